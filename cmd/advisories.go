@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	enrichAdvisories       bool
+	nvdAPIURL              string
+	githubAdvisoriesAPIURL string
+	advisoryCacheDir       string
+)
+
+func addAdvisoryFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&enrichAdvisories, "enrich-advisories", false, "complete the missing or truncated descriptions, the references, the published and modified dates and the exploit references of the vulnerabilities from their NVD advisory, or their GitHub advisory for the GHSA vulnerabilities. The advisories of the distinct vulnerabilities are looked up once the images are scanned, cached for a week in --advisory-cache-dir and requested within the rate limits of the APIs, raised by the NVD_API_KEY and GITHUB_TOKEN environment variables")
+	cmd.Flags().StringVar(&advisoryCacheDir, "advisory-cache-dir", ".advisorycache", "directory the advisories are cached to, for instance a persistent volume shared by the runs")
+	cmd.Flags().StringVar(&nvdAPIURL, "nvd-api-url", scanner.DefaultNVDAPIURL, "URL of the NVD CVE API the advisories are requested from, for instance a mirror")
+	cmd.Flags().StringVar(&githubAdvisoriesAPIURL, "github-advisories-api-url", scanner.DefaultGitHubAdvisoriesAPIURL, "URL of the GitHub Security Advisories API the GHSA advisories are requested from, for instance of GitHub Enterprise")
+}
+
+// advisoryDatabase returns the advisories the vulnerabilities are enriched with, nil when they are not enriched
+func advisoryDatabase() *scanner.AdvisoryDatabase {
+	if !enrichAdvisories {
+		return nil
+	}
+	return scanner.NewAdvisoryDatabase(scanner.AdvisoryConfig{
+		NVDAPIURL:              nvdAPIURL,
+		NVDAPIKey:              os.Getenv("NVD_API_KEY"),
+		GitHubAdvisoriesAPIURL: githubAdvisoriesAPIURL,
+		GitHubToken:            os.Getenv("GITHUB_TOKEN"),
+		CacheDir:               advisoryCacheDir,
+		MaxAge:                 7 * 24 * time.Hour,
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/annotations"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ciAnnotationsAuto detects the CI from its environment variables
+const ciAnnotationsAuto = "auto"
+
+var ciAnnotations string
+
+func addCIAnnotationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&ciAnnotations, "ci-annotations", "", "write the breaches of the thresholds the command fails on, the exceeded severity budgets and the remediation SLA breaches as CI annotations, one of github for GitHub Actions workflow commands, azure-devops for Azure DevOps logging commands, or auto to detect the CI from its environment variables. No annotation is written unless this option is specified")
+}
+
+// ciAnnotationFormat returns the annotation format of --ci-annotations, empty when no annotation is written
+func ciAnnotationFormat() string {
+	switch ciAnnotations {
+	case "":
+		return ""
+	case ciAnnotationsAuto:
+		return annotations.DetectFormat(os.Getenv)
+	}
+	if err := annotations.ValidateFormat(ciAnnotations); err != nil {
+		logr.Fatalf("Invalid --ci-annotations: %v", err)
+	}
+	return ciAnnotations
+}
+
+// validateCIAnnotationFlags fails the command before the scan when --ci-annotations is unknown
+func validateCIAnnotationFlags() {
+	ciAnnotationFormat()
+}
+
+// writeCIAnnotations writes the annotations of the report to the standard output, read by the CI, or to the standard
+// error with --quiet as the standard output is kept for the report
+func writeCIAnnotations(imageScanReport *scanner.VulnerabilityReport) {
+	format := ciAnnotationFormat()
+	if format == "" || imageScanReport == nil {
+		return
+	}
+	output := os.Stdout
+	if quiet {
+		output = os.Stderr
+	}
+	thresholds := annotations.Thresholds{
+		FailOnSeverity:       minSeverity("fail-on-severity", failOnSeverity),
+		FailOnKnownExploited: failOnKnownExploited,
+		MaxScanErrorRate:     maxScanErrorRate,
+	}
+	if err := annotations.Write(output, format, annotations.ForReport(imageScanReport, thresholds)); err != nil {
+		logr.Errorf("Error writing the CI annotations: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var severityBudgetsFile string
+
+func addBudgetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&severityBudgetsFile, "severity-budgets", "", "yaml file of the maximum CRITICAL and HIGH vulnerabilities per area or team. A budget with an area but no team limits the vulnerabilities of all the teams of the area. The report shows the budget consumption of each area and team and the command exits with an error once the reports are generated when a budget is exceeded")
+}
+
+// severityBudgets returns the severity budgets, nil when no budgets file is specified
+func severityBudgets() *scanner.SeverityBudgets {
+	if severityBudgetsFile == "" {
+		return nil
+	}
+	budgets, err := scanner.LoadSeverityBudgets(severityBudgetsFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	budgets.FixableOnly = fixableOnly
+	return budgets
+}
+
+// exitIfBudgetExceeded fails the command when an area or a team exceeds its severity budget
+func exitIfBudgetExceeded(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil {
+		return
+	}
+	if exceeded := imageScanReport.ExceededBudgets(); len(exceeded) > 0 {
+		for _, budget := range exceeded {
+			logr.Error(budget)
+		}
+		logr.Fatalf("%d severity budgets exceeded", len(exceeded))
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/releases"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/trivyoperator"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Will run readiness checks against the workloads of a cluster",
+		Run:   readinessChecks,
+	}
+	selectedChecks          []string
+	targetKubernetesVersion string
+
+	// availableChecks creates the readiness checks by name, including the checks of the compile-time plugins
+	availableChecks = withRegisteredChecks(map[string]checks.CheckFactory{
+		checks.NetworkPolicyCheckName:    checks.NewNetworkPolicyCheck,
+		checks.WorkloadSecurityCheckName: checks.NewWorkloadSecurityCheck,
+		checks.ServiceAccountsCheckName:  checks.NewServiceAccountsCheck,
+		checks.DisruptionBudgetCheckName: checks.NewDisruptionBudgetCheck,
+		checks.AvailabilityCheckName:     checks.NewAvailabilityCheck,
+		checks.TopologySpreadCheckName:   checks.NewTopologySpreadCheck,
+		checks.GracefulShutdownCheckName: checks.NewGracefulShutdownCheck,
+		checks.ProbesCheckName:           checks.NewProbesCheck,
+		checks.ImageTagsCheckName:        checks.NewImageTagsCheck,
+		checks.WorkloadRiskCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewWorkloadRiskCheck(safeCapabilities, writableRootFilesystem)
+		},
+		checks.DeprecatedAPICheckName: func(kubernetesClient k8s.KubernetesClient) checks.Check {
+			return checks.NewDeprecatedAPICheck(kubernetesClient, targetKubernetesVersion)
+		},
+		checks.MisconfigurationCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewMisconfigurationCheck(kubeContext, kubeconfigPath, trivyPath(), trivyDB(), strings.Fields(trivyExtraArgs), regoPolicies())
+		},
+		checks.ConfigAuditCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewConfigAuditCheck(trivyoperator.NewClient(k8s.KubernetesConfig(kubeContext, kubeconfigPath)))
+		},
+		checks.ImageSignaturesCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewImageSignaturesCheck(signaturePolicy())
+		},
+		checks.ImageProvenanceCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewImageProvenanceCheck(signaturePolicy(), provenancePolicy())
+		},
+		checks.ApprovedRegistriesCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewApprovedRegistriesCheck(approvedRegistries)
+		},
+		checks.ApprovedDigestsCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewApprovedDigestsCheck(digestAllowlist())
+		},
+		checks.ImageStalenessCheckName: func(_ k8s.KubernetesClient) checks.Check {
+			return checks.NewImageStalenessCheck(maxImageAge())
+		},
+		checks.ComponentVersionsCheckName: func(kubernetesClient k8s.KubernetesClient) checks.Check {
+			return checks.NewComponentVersionsCheck(kubernetesClient, releases.NewClient(os.Getenv("GITHUB_TOKEN")))
+		},
+	})
+
+	// optInChecks are only run when selected with --checks, as they need trivy, Trivy Operator, cosign, docker or the
+	// release feeds of Kubernetes and GitHub, and take longer to run, or need a policy such as the approved registries or
+	// the digest allowlist. The workload-risk check overlaps the workload-security check run by default
+	optInChecks = map[string]bool{
+		checks.WorkloadRiskCheckName:       true,
+		checks.MisconfigurationCheckName:   true,
+		checks.ConfigAuditCheckName:        true,
+		checks.ImageSignaturesCheckName:    true,
+		checks.ImageProvenanceCheckName:    true,
+		checks.ApprovedRegistriesCheckName: true,
+		checks.ApprovedDigestsCheckName:    true,
+		checks.ImageStalenessCheckName:     true,
+		checks.ComponentVersionsCheckName:  true,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "kubeconfig file to use if connecting from outside a cluster")
+	checkCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
+	addNamespaceFlags(checkCmd)
+	checkCmd.Flags().StringVar(&areaLabel, "area-labels", "", "comma-separated pod or namespace labels allowing to split per area the findings, the first label set being used")
+	checkCmd.Flags().StringVar(&teamLabels, "teams-labels", "", "comma-separated pod or namespace labels allowing to split per team the findings, the first label set being used")
+	checkCmd.Flags().StringVar(&filterLabels, "filters-labels", "", "string allowing to filter the namespaces string separated by comma")
+	checkCmd.Flags().StringSliceVar(&selectedChecks, "checks", defaultCheckNames(), fmt.Sprintf("List of readiness checks to run, among %v. If not specified all are run except %v", checkNames(), optInCheckNames()))
+	checkCmd.Flags().StringVar(&targetKubernetesVersion, "target-kubernetes-version", "", "Kubernetes version the deprecated API usage is evaluated against, for instance 1.26. The cluster version is used when not specified")
+	checkCmd.Flags().StringVar(&failOnSeverity, "fail-on-severity", "", "exit with an error once the reports are generated when a finding of at least this severity is found, for instance HIGH to gate a CI pipeline on the CRITICAL and HIGH findings only")
+	addReportDirectoryFlags(checkCmd)
+	checkCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	addSignatureFlags(checkCmd)
+	addProvenanceFlags(checkCmd)
+	addApprovedRegistryFlags(checkCmd)
+	addWorkloadRiskFlags(checkCmd)
+	addDigestAllowlistFlags(checkCmd)
+	addImageStalenessFlags(checkCmd)
+	addPDFFlags(checkCmd)
+	addRecordFlags(checkCmd)
+	addTrivyFlags(checkCmd)
+	addTrivyArgsFlags(checkCmd)
+	addNetworkFlags(checkCmd)
+	addPolicyFlags(checkCmd)
+	addReadinessScoreFlags(checkCmd)
+	addCheckPluginFlags(checkCmd)
+}
+
+func readinessChecks(_ *cobra.Command, _ []string) {
+	validateRecordFlags()
+	minSeverity("fail-on-severity", failOnSeverity)
+	checksReport, err := runChecks(newKubernetesClient(), nil)
+	if err != nil {
+		logr.Fatal(err)
+	}
+
+	fullReport := &FullReport{
+		Checks:          checksReport,
+		ReadinessScores: readinessScores(nil, checksReport),
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-checks.html.tmpl", reportDir, "report-checks.html")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-checks.md.tmpl", reportDir, "report-checks.md")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	convertToPDF("report-checks.html")
+
+	if jsonReportFile != "" {
+		err = r.SaveReport(fullReport, jsonReportFile)
+		if err != nil {
+			logr.Fatal(err)
+		}
+	}
+	writeQuietReport(fullReport)
+	writeReadinessScores(fullReport.ReadinessScores)
+	exitIfMissingProvenance(checksReport)
+	exitIfFindingsFound(checksReport)
+}
+
+// exitIfFindingsFound fails the command when requested and findings of at least the severity are found
+func exitIfFindingsFound(checksReport *checks.ReadinessReport) {
+	if failOnSeverity == "" {
+		return
+	}
+	severity := minSeverity("fail-on-severity", failOnSeverity)
+	if findings := checksReport.FindingsWithMinSeverity(severity); len(findings) > 0 {
+		logr.Fatalf("%d findings of severity %s or higher found", len(findings), severity)
+	}
+}
+
+// runChecks runs the selected checks and the checks of the executable plugins, the image scan being given to the checks
+// inspecting it, nil when the images were not scanned
+func runChecks(kubernetesClient k8s.KubernetesClient, imageScan *scanner.VulnerabilityReport) (*checks.ReadinessReport, error) {
+	var toRun []checks.Check
+	for _, name := range selectedChecks {
+		newCheck, ok := availableChecks[name]
+		if !ok {
+			logr.Infof("Unrecognised check: %s. Skipping.... (permitted values: %v))", name, checkNames())
+			continue
+		}
+		toRun = append(toRun, newCheck(kubernetesClient))
+	}
+	toRun = append(toRun, pluginChecks()...)
+
+	config := &checks.Config{
+		AreaLabels:   areaLabel,
+		TeamsLabels:  teamLabels,
+		FilterLabels: namespaceFilterLabels(),
+		ImageScan:    imageScan,
+	}
+	return checks.New(kubernetesClient, config, toRun...).Run()
+}
+
+func checkNames() []string {
+	var names []string
+	for name := range availableChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultCheckNames returns the checks run when --checks is not specified
+func defaultCheckNames() []string {
+	var names []string
+	for _, name := range checkNames() {
+		if !optInChecks[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func optInCheckNames() []string {
+	var names []string
+	for _, name := range checkNames() {
+		if optInChecks[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkpointFile string
+	resume         bool
+)
+
+func addCheckpointFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&checkpointFile, "checkpoint-file", "", "file each scanned image is recorded to as soon as its scan finishes, removed once the scan completes, for instance .scancheckpoint/checkpoint.jsonl. The scans are not checkpointed when not specified")
+	cmd.Flags().BoolVar(&resume, "resume", false, "resume an interrupted scan from --checkpoint-file, only scanning the images it does not record")
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"strings"
 	"time"
 
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/kubebench"
 	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
 	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
 	logr "github.com/sirupsen/logrus"
@@ -12,54 +15,129 @@ import (
 var (
 	cisScanCmd = &cobra.Command{
 		Use:   "cis-scan",
-		Short: "Scan cluster with CIS, NSA and PSS-Restricted security benchmarks",
+		Short: "Scan cluster with CIS, NSA and PSS security benchmarks",
 		Run:   cisScan,
 	}
-	benchmarks        []string
-	defaultBenchmarks = []string{"k8s-cis", "k8s-nsa", "k8s-pss-restricted"}
+	benchmarks                         []string
+	defaultBenchmarks                  = []string{"k8s-cis", "k8s-nsa", "k8s-pss-restricted"}
+	runKubeBench                       bool
+	kubeBenchImage, kubeBenchNamespace string
+	kubeBenchWorkers                   int
+	kubeBenchTimeout                   time.Duration
 )
 
 func init() {
 	rootCmd.AddCommand(cisScanCmd)
 	cisScanCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "path to kubeconfig file if connecting from outside a cluster")
-	cisScanCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use if connecting from outside a cluster")
+	cisScanCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
 	cisScanCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
-	cisScanCmd.Flags().StringSliceVar(&benchmarks, "benchmarks", defaultBenchmarks, "List of security benchmarks to run. If not specified all are run (permitted values: k8s-cis,k8s-nsa,k8s-pss-restricted)")
+	cisScanCmd.Flags().StringSliceVar(&benchmarks, "benchmarks", defaultBenchmarks, "List of security benchmarks to run, the results are combined in a single report. The 'k8s-' prefix can be omitted (permitted values: k8s-cis,k8s-nsa,k8s-pss-baseline,k8s-pss-restricted)")
 	cisScanCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 60*time.Minute, "timeout for the Kubernetes cluster scan")
+	cisScanCmd.Flags().BoolVar(&runKubeBench, "kube-bench", false, "run kube-bench on each node to add the node and control plane checks to the report")
+	cisScanCmd.Flags().StringVar(&kubeBenchImage, "kube-bench-image", kubebench.DefaultImage, "kube-bench image used by the jobs")
+	cisScanCmd.Flags().StringVar(&kubeBenchNamespace, "kube-bench-namespace", "kube-system", "namespace where the kube-bench jobs are created")
+	cisScanCmd.Flags().IntVar(&kubeBenchWorkers, "kube-bench-workers", 5, "number of nodes kube-bench is run on in parallel")
+	cisScanCmd.Flags().DurationVar(&kubeBenchTimeout, "kube-bench-timeout", 5*time.Minute, "timeout for the kube-bench job on each node")
+	cisScanCmd.Flags().StringVar(&failOnSeverity, "fail-on-severity", "", "exit with an error once the reports are generated when a control of at least this severity fails, for instance HIGH to gate a CI pipeline on the CRITICAL and HIGH controls only")
+	addReportDirectoryFlags(cisScanCmd)
+	cisScanCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	addPDFFlags(cisScanCmd)
+	addTrivyFlags(cisScanCmd)
+	addNetworkFlags(cisScanCmd)
+	addTrivyArgsFlags(cisScanCmd)
+	addPolicyFlags(cisScanCmd)
 }
 
 func cisScan(_ *cobra.Command, _ []string) {
+	minSeverity("fail-on-severity", failOnSeverity)
+	selectedBenchmarks := selectBenchmarks(benchmarks)
+	if len(selectedBenchmarks) == 0 {
+		logr.Fatalf("No security benchmark to run (permitted values: %v)", scanner.SupportedBenchmarks)
+	}
 
-	t := scanner.NewTrivyClient(severity, scanTimeout)
+	config := &scanner.Config{
+		LogLevel:          logLevel,
+		Severity:          severity,
+		ScanImageTimeout:  scanTimeout,
+		TrivyPath:         trivyPath(),
+		TrivyExtraArgs:    strings.Fields(trivyExtraArgs),
+		TrivyCisExtraArgs: strings.Fields(trivyCisExtraArgs),
+		TrivyDB:           trivyDB(),
+		Policies:          regoPolicies(),
+	}
+	s := scanner.New(nil, config)
 
-	for _, benchmark := range benchmarks {
-		if !contains(defaultBenchmarks, benchmark) {
-			logr.Infof("Unrecognised benchmark: %s. Skipping.... (permitted values: %v))", benchmark, defaultBenchmarks)
-			continue
-		}
+	complianceReport, err := s.CisScan(selectedBenchmarks)
+	if err != nil {
+		logr.Fatal(err)
+	}
 
-		logr.Infof("Running %s security benchmark. Please wait...", benchmark)
-		cisScanReport, err := t.CisScan(benchmark)
+	if runKubeBench {
+		kubeBenchConfig := &kubebench.Config{
+			Image:     kubeBenchImage,
+			Namespace: kubeBenchNamespace,
+			Workers:   kubeBenchWorkers,
+			Timeout:   kubeBenchTimeout,
+		}
+		kubeBenchReport, err := kubebench.New(k8s.NewKubernetesClient(kubeContext, kubeconfigPath), kubeBenchConfig).Run()
 		if err != nil {
-			logr.Fatalf("Error running %s security benchmark: %v", benchmark, err)
+			logr.Errorf("Error running kube-bench, node checks are not included in the report: %v", err)
+		} else {
+			complianceReport.Add(kubeBenchReport)
 		}
+	}
 
-		fullReport := &FullReport{
-			CisScan: cisScanReport,
-		}
-		logr.Infof("Generating %s security benchmark report", benchmark)
-		err = r.GenerateReportFromTemplate(fullReport, "templates/report-cisScan.html.tmpl", reportDir, "report-CIS-"+benchmark+".html")
+	fullReport := &FullReport{
+		CisScan: complianceReport,
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-cisScan.html.tmpl", reportDir, "report-CIS.html")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-cisScan.md.tmpl", reportDir, "report-CIS.md")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	convertToPDF("report-CIS.html")
+
+	if jsonReportFile != "" {
+		err = r.SaveReport(fullReport, jsonReportFile)
 		if err != nil {
 			logr.Fatal(err)
 		}
+	}
+	writeQuietReport(fullReport)
+	exitIfControlsFailed(complianceReport)
+}
+
+// exitIfControlsFailed fails the command when requested and controls of at least the severity fail
+func exitIfControlsFailed(complianceReport *scanner.CombinedComplianceReport) {
+	if failOnSeverity == "" {
+		return
+	}
+	severity := minSeverity("fail-on-severity", failOnSeverity)
+	if failed := complianceReport.FailedControlsWithMinSeverity(severity); len(failed) > 0 {
+		logr.Fatalf("%d controls of severity %s or higher failed", len(failed), severity)
+	}
+}
 
-		if jsonReportFile != "" {
-			err = r.SaveReport(fullReport, jsonReportFile)
-			if err != nil {
-				logr.Fatal(err)
-			}
+// selectBenchmarks returns the supported benchmarks once each, adding the 'k8s-' prefix when omitted
+func selectBenchmarks(requested []string) []string {
+	var selected []string
+	for _, benchmark := range requested {
+		benchmark = strings.TrimSpace(benchmark)
+		if !strings.HasPrefix(benchmark, "k8s-") {
+			benchmark = "k8s-" + benchmark
+		}
+		if !contains(scanner.SupportedBenchmarks, benchmark) {
+			logr.Infof("Unrecognised benchmark: %s. Skipping.... (permitted values: %v))", benchmark, scanner.SupportedBenchmarks)
+			continue
+		}
+		if !contains(selected, benchmark) {
+			selected = append(selected, benchmark)
 		}
 	}
+	return selected
 }
 
 func contains(elems []string, v string) bool {
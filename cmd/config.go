@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/configfile"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manages the config file setting the flags of the commands",
+	}
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validates the config file and the " + configfile.EnvPrefix + "* environment variables against the flags of the commands",
+		Run:   validateConfig,
+		// the flags are not completed from the config file so that its invalid values are reported by the validation
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+			setLogLevel(logLevel)
+		},
+	}
+	configFile string
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func addConfigFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "YAML config file setting the flags by name, for instance 'scan-workers: 20'. The flags given on the command line take precedence over the "+configfile.EnvPrefix+"* environment variables, for instance "+configfile.EnvVar("scan-workers")+", which take precedence over the config file")
+}
+
+// applyConfig sets the flags of the command not given on the command line from the environment variables and the config file
+func applyConfig(cmd *cobra.Command) {
+	values, err := loadConfigFile(cmd)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	if err := configfile.Apply(cmd.Flags(), values, os.LookupEnv); err != nil {
+		logr.Fatal(err)
+	}
+}
+
+// loadConfigFile loads the config file of the --config flag, or else of its environment variable, if any
+func loadConfigFile(cmd *cobra.Command) (configfile.Values, error) {
+	path := configFile
+	if value, ok := os.LookupEnv(configfile.EnvVar("config")); ok && !cmd.Flags().Changed("config") {
+		path = value
+	}
+	if path == "" {
+		return configfile.Values{}, nil
+	}
+	return configfile.Load(path)
+}
+
+func validateConfig(cmd *cobra.Command, _ []string) {
+	values, err := loadConfigFile(cmd)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	// the config flag is set from its environment variable before the validation
+	delete(values, "config")
+	errs := configfile.Validate(values, os.Environ(), commandFlagSets(rootCmd))
+	for _, err := range errs {
+		logr.Error(err)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("The config file and the environment variables are valid")
+}
+
+// commandFlagSets returns the flags of the command and of its sub commands
+func commandFlagSets(cmd *cobra.Command) []*pflag.FlagSet {
+	flagSets := []*pflag.FlagSet{cmd.PersistentFlags(), cmd.LocalFlags()}
+	for _, subCommand := range cmd.Commands() {
+		flagSets = append(flagSets, commandFlagSets(subCommand)...)
+	}
+	return flagSets
+}
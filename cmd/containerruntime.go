@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	containerRuntimeName string
+	keepImages           bool
+)
+
+func addContainerRuntimeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&containerRuntimeName, "container-runtime", scanner.DockerRuntime, "container runtime the images are pulled and removed with: "+scanner.DockerRuntime+" or "+scanner.PodmanRuntime+" for the hosts where docker is not installed")
+	cmd.Flags().BoolVar(&keepImages, "keep-images", false, "keep the pulled images once scanned instead of removing them. The images present on the host before the scan are never removed")
+}
+
+// containerRuntime returns the container runtime the images are pulled with
+func containerRuntime() string {
+	if err := scanner.ValidateContainerRuntime(containerRuntimeName); err != nil {
+		logr.Fatal(err)
+	}
+	return containerRuntimeName
+}
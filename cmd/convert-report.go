@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/reportschema"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertReportCmd = &cobra.Command{
+		Use:   "convert-report <report.json>",
+		Short: "Will upgrade a json report saved by a previous version to the current report schema version",
+		Args:  cobra.ExactArgs(1),
+		Run:   convertReport,
+	}
+	convertedReportFile string
+)
+
+func init() {
+	rootCmd.AddCommand(convertReportCmd)
+	convertReportCmd.Flags().StringVar(&convertedReportFile, "output", "", "output filename of the upgraded report. The report is upgraded in place when not specified")
+}
+
+func convertReport(_ *cobra.Command, args []string) {
+	content, err := os.ReadFile(args[0])
+	if err != nil {
+		logr.Fatalf("Could not read report file %s: %v", args[0], err)
+	}
+	version, err := reportschema.Version(content)
+	if err != nil {
+		logr.Fatalf("Could not read report file %s: %v", args[0], err)
+	}
+	upgraded, err := reportschema.Upgrade(content)
+	if err != nil {
+		logr.Fatalf("Could not upgrade report file %s: %v", args[0], err)
+	}
+
+	output := convertedReportFile
+	if output == "" {
+		output = args[0]
+	}
+	if err := os.WriteFile(output, upgraded, 0644); err != nil {
+		logr.Fatalf("Could not write report file %s: %v", output, err)
+	}
+	logr.Infof("Report %s upgraded from schema version %d to %d into %s", args[0], version, reportschema.CurrentVersion, output)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+// podScratchDirShare is the share of the ephemeral storage limit of the pod the scratch directory is limited to with
+// --pod-limits, leaving room for the logs and the reports so that the pod is not evicted
+const podScratchDirShare = 0.8
+
+var (
+	podLimits bool
+	jobEvents bool
+
+	currentPodOnce sync.Once
+	currentPodOf   *k8s.CurrentPod
+)
+
+func addCronJobFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&podLimits, "pod-limits", false, "when running in a pod, size the number of workers from the CPU and memory limits of the pod and from its ephemeral storage limit, --scan-workers being the maximum, and limit the --scratch-dir to 80% of the ephemeral storage limit unless --scratch-dir-max-size is specified, so that the pod is not evicted. The pod needs the permission to get its own pod")
+	cmd.Flags().BoolVar(&jobEvents, "job-events", false, "when running in a pod of a Job, such as the Jobs of a CronJob, record a Kubernetes Event on the Job summarising the outcome of the run, the images scanned, the vulnerabilities per severity and the failed scans. The pod needs the permissions to get its own pod and to create events")
+}
+
+// currentPod returns the pod the scanner runs in, nil when not running in a pod or when it cannot be looked up
+func currentPod() *k8s.CurrentPod {
+	currentPodOnce.Do(func() {
+		pod, err := k8s.NewCurrentPod()
+		if err != nil {
+			logr.Warnf("Unable to look up the pod of the scanner: %v", err)
+		}
+		currentPodOf = pod
+	})
+	return currentPodOf
+}
+
+// podResources limits the disk space of the resources to the ephemeral storage limit of the pod, if any. The CPUs and
+// the memory are already limited by the cgroup of the pod
+func podResources(resources scanner.Resources) scanner.Resources {
+	limit := podEphemeralStorageLimit()
+	if limit > 0 && (resources.Disk == 0 || limit < resources.Disk) {
+		resources.Disk = limit
+	}
+	return resources
+}
+
+// podEphemeralStorageLimit returns the ephemeral storage limit of the pod with --pod-limits, 0 when there is none
+func podEphemeralStorageLimit() int64 {
+	if !podLimits {
+		return 0
+	}
+	pod := currentPod()
+	if pod == nil {
+		logr.Warn("--pod-limits has no effect outside of a pod")
+		return 0
+	}
+	limit, err := pod.EphemeralStorageLimit()
+	if err != nil {
+		logr.Warnf("Unable to read the ephemeral storage limit of the pod: %v", err)
+	}
+	return limit
+}
+
+// recordJobEvent records the outcome of the run on the Job of the scanner pod with --job-events
+func recordJobEvent(report *scanner.VulnerabilityReport, duration time.Duration, runErr error) {
+	if !jobEvents {
+		return
+	}
+	pod := currentPod()
+	if pod == nil {
+		logr.Warn("--job-events has no effect outside of a pod")
+		return
+	}
+	eventType, reason, message := jobEventOf(report, duration.Round(time.Second), runErr)
+	if err := pod.RecordJobEvent(eventType, reason, message); err != nil {
+		logr.Error(err)
+		return
+	}
+	logr.Infof("Recorded the %s event on the job of pod %s/%s", reason, pod.Namespace, pod.Name)
+}
+
+// jobEventOf returns the type, reason and message of the event of the outcome of the run
+func jobEventOf(report *scanner.VulnerabilityReport, duration time.Duration, runErr error) (string, string, string) {
+	switch {
+	case runErr != nil || report == nil:
+		return v1.EventTypeWarning, "ScanFailed", fmt.Sprintf("Scan failed after %v: %v", duration, runErr)
+	case report.Metadata.FailFastFinding != "":
+		return v1.EventTypeWarning, "ScanStopped", fmt.Sprintf("Scan stopped on %s after %v: %s", report.Metadata.FailFastFinding, duration, report.OutcomeSummary())
+	case report.Metadata.Incomplete:
+		return v1.EventTypeWarning, "ScanIncomplete", fmt.Sprintf("Scan interrupted after %v: %s", duration, report.OutcomeSummary())
+	default:
+		return v1.EventTypeNormal, "ScanCompleted", fmt.Sprintf("Scan completed in %v: %s", duration, report.OutcomeSummary())
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	minCVSSScore float64
+	sortByCVSS   bool
+)
+
+func addCVSSFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64Var(&minCVSSScore, "min-cvss-score", 0, "minimum CVSS score of the vulnerabilities to be reported, for instance 7.0. The vulnerabilities without CVSS score are reported whatever the minimum score")
+	cmd.Flags().BoolVar(&sortByCVSS, "sort-by-cvss", false, "sort the vulnerabilities of each image by decreasing CVSS score rather than by severity")
+}
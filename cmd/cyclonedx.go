@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/cyclonedx"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cycloneDXReportFile string
+
+func addCycloneDXFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cycloneDXReportFile, "report-output-filename-cyclonedx", "", "output filename where the images and their vulnerabilities will be saved as a CycloneDX Vulnerability Disclosure Report, the packages of the images being read from their SBOM with --sbom-dir. No CycloneDX report will be created unless this option is specified")
+}
+
+// saveCycloneDXReport saves the CycloneDX Vulnerability Disclosure Report of the images when --report-output-filename-cyclonedx is set
+func saveCycloneDXReport(report *scanner.VulnerabilityReport) {
+	if cycloneDXReportFile == "" || report == nil {
+		return
+	}
+	if err := cyclonedx.Save(cyclonedx.NewVDR(report), cycloneDXReportFile); err != nil {
+		logr.Fatal(err)
+	}
+}
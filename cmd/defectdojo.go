@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/defectdojo"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var defectDojoURL, defectDojoProductType, defectDojoTeamProducts string
+
+func addDefectDojoFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&defectDojoURL, "defectdojo-url", "", "DefectDojo base url the findings are imported to, with one product per team and one engagement per image repository. No finding is exported unless this option is specified. The API key is read from the DEFECTDOJO_API_KEY environment variable")
+	cmd.Flags().StringVar(&defectDojoProductType, "defectdojo-product-type", "Production Readiness", "DefectDojo product type of the products created for the teams")
+	cmd.Flags().StringVar(&defectDojoTeamProducts, "defectdojo-team-products", "", "DefectDojo product per team, format: 'team1=product1,team2=product2'. The products are named after the teams otherwise")
+}
+
+func exportToDefectDojo(report *scanner.VulnerabilityReport) {
+	if defectDojoURL == "" || report == nil {
+		return
+	}
+	exporter := defectdojo.New(&defectdojo.Config{
+		URL:          defectDojoURL,
+		APIKey:       os.Getenv("DEFECTDOJO_API_KEY"),
+		ProductType:  defectDojoProductType,
+		TeamProducts: parseKeyValues(defectDojoTeamProducts),
+	})
+	if err := exporter.Export(report); err != nil {
+		logr.Error(err)
+	}
+}
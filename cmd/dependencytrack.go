@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/dependencytrack"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var dependencyTrackURL, dependencyTrackParentProject string
+
+func addDependencyTrackFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&dependencyTrackURL, "dependency-track-url", "", "Dependency-Track base url the SBOMs of the images are uploaded to, with one project per image repository tagged with the cluster and the teams. No SBOM is uploaded unless this option is specified. The API key is read from the DEPENDENCY_TRACK_API_KEY environment variable")
+	cmd.Flags().StringVar(&dependencyTrackParentProject, "dependency-track-parent-project", "", "Dependency-Track project the image projects are created under")
+}
+
+func exportToDependencyTrack(report *scanner.VulnerabilityReport) {
+	if dependencyTrackURL == "" || report == nil {
+		return
+	}
+	exporter := dependencytrack.New(&dependencytrack.Config{
+		URL:           dependencyTrackURL,
+		APIKey:        os.Getenv("DEPENDENCY_TRACK_API_KEY"),
+		ParentProject: dependencyTrackParentProject,
+	})
+	if err := exporter.Export(report); err != nil {
+		logr.Error(err)
+	}
+}
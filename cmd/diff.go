@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffCmd = &cobra.Command{
+		Use:   "diff <old-report.json> <new-report.json>",
+		Short: "Will compare two json reports, listing the new and removed images and the new and fixed vulnerabilities",
+		Args:  cobra.ExactArgs(2),
+		Run:   diffReports,
+	}
+	diffJSONFile                       string
+	oldInventoryFile, newInventoryFile string
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&diffJSONFile, "output-json", "", "output filename where the json representation of the delta will be saved. No json representation will be created unless this option is specified")
+	diffCmd.Flags().StringVar(&oldInventoryFile, "old-inventory", "", "inventory saved with --inventory-output alongside the old report, explaining with --new-inventory whether the vulnerabilities were added or fixed by a deployment or by a vulnerability data update, and listing the workloads whose images changed")
+	diffCmd.Flags().StringVar(&newInventoryFile, "new-inventory", "", "inventory saved with --inventory-output alongside the new report, see --old-inventory")
+}
+
+func diffReports(_ *cobra.Command, args []string) {
+	oldReport, err := scanner.LoadVulnerabilityReport(args[0])
+	if err != nil {
+		logr.Fatal(err)
+	}
+	newReport, err := scanner.LoadVulnerabilityReport(args[1])
+	if err != nil {
+		logr.Fatal(err)
+	}
+	diff := scanner.DiffReports(oldReport, newReport)
+	if oldInventoryFile != "" || newInventoryFile != "" {
+		explainDiff(diff)
+	}
+	if quiet {
+		writeQuietReport(diff)
+	} else if err := diff.WriteText(os.Stdout); err != nil {
+		logr.Fatal(err)
+	}
+	if diffJSONFile != "" {
+		if err := r.SaveReport(diff, diffJSONFile); err != nil {
+			logr.Fatal(err)
+		}
+	}
+}
+
+// explainDiff explains the diff with the inventories of the reports
+func explainDiff(diff *scanner.ReportDiff) {
+	if oldInventoryFile == "" || newInventoryFile == "" {
+		logr.Fatal("--old-inventory and --new-inventory must be specified together")
+	}
+	oldInventory, err := scanner.LoadInventory(oldInventoryFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	newInventory, err := scanner.LoadInventory(newInventoryFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	diff.Explain(oldInventory, newInventory)
+}
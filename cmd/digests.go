@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/signing"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var digestAllowlistFile, digestAllowlistSignature, digestAllowlistKey, digestAllowlistCosignKey string
+
+func addDigestAllowlistFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&digestAllowlistFile, "digest-allowlist", "", "signed file listing the image digests approved to run with the "+checks.ApprovedDigestsCheckName+" check, one digest per line such as sha256:4ff3ca91..., or <repository>@<digest> to approve a digest for a single repository")
+	cmd.Flags().StringVar(&digestAllowlistSignature, "digest-allowlist-signature", "", "signature of the --digest-allowlist file, the allowlist file with the "+signing.SignatureExtension+" extension by default")
+	cmd.Flags().StringVar(&digestAllowlistKey, "digest-allowlist-key", "", "PEM public key the signature of the --digest-allowlist file is verified against")
+	cmd.Flags().StringVar(&digestAllowlistCosignKey, "digest-allowlist-cosign-key", "", "cosign public key the signature of the --digest-allowlist file is verified against by cosign verify-blob, as a path, url or KMS URI")
+}
+
+// digestAllowlist returns the signed allowlist of the approved image digests
+func digestAllowlist() checks.DigestAllowlist {
+	allowlist := checks.DigestAllowlist{File: digestAllowlistFile, SignatureFile: digestAllowlistSignature}
+	switch {
+	case digestAllowlistKey != "" && digestAllowlistCosignKey != "":
+		logr.Fatal("--digest-allowlist-key and --digest-allowlist-cosign-key are mutually exclusive")
+	case digestAllowlistKey != "":
+		verifier, err := signing.NewKeyVerifier(digestAllowlistKey)
+		if err != nil {
+			logr.Fatalf("Invalid --digest-allowlist-key: %v", err)
+		}
+		allowlist.Verifier = verifier
+	case digestAllowlistCosignKey != "":
+		allowlist.Verifier = signing.NewCosignVerifier(digestAllowlistCosignKey)
+	}
+	return allowlist
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// discoveryWorkers defaults to the default workers for the commands without discovery flags, such as check
+	discoveryWorkers  = k8s.DefaultDiscoveryWorkers
+	discoveryPageSize int64
+	// contextAnnotations are the workload annotations recorded per image in the report
+	contextAnnotations []string
+)
+
+func addDiscoveryFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&discoveryWorkers, "discovery-workers", k8s.DefaultDiscoveryWorkers, "number of namespaces whose pods and workloads are listed concurrently when discovering the containers to scan")
+	cmd.Flags().Int64Var(&discoveryPageSize, "discovery-page-size", 0, "maximum number of namespaces or pods returned by each list request of the container discovery, the lists being paginated. The lists are not paginated when 0")
+	cmd.Flags().StringSliceVar(&contextAnnotations, "context-annotations", nil, "annotations of the pods, of their workload or else of their namespace recorded per image in the report to prioritise the findings by business criticality, for instance 'data-classification,tier'")
+}
+
+// discoveryOptions returns the options of the container discovery, exiting when they are invalid
+func discoveryOptions() k8s.DiscoveryOptions {
+	if discoveryWorkers < 1 {
+		logr.Fatalf("Invalid --discovery-workers %d, it must be at least 1", discoveryWorkers)
+	}
+	if discoveryPageSize < 0 {
+		logr.Fatalf("Invalid --discovery-page-size %d, it must be positive or 0", discoveryPageSize)
+	}
+	return k8s.DiscoveryOptions{Workers: discoveryWorkers, PageSize: discoveryPageSize, ContextAnnotations: contextAnnotations}
+}
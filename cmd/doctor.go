@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/doctor"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/trivybinary"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Will validate the environment of the scans end-to-end, the cluster access and permissions, the container runtime and containerd, trivy and its db, the registries and the disk space, printing the actionable failures before a long scan is attempted",
+		Args:  cobra.NoArgs,
+		Run:   runDoctor,
+	}
+	doctorSkipCluster  bool
+	doctorRegistries   []string
+	doctorMaxDBAge     time.Duration
+	doctorMinFreeDisk  string
+	doctorCheckTimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "kubeconfig file to use if connecting from outside a cluster")
+	doctorCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
+	addNamespaceFlags(doctorCmd)
+	doctorCmd.Flags().StringVar(&filterLabels, "filters-labels", "", "string allowing to filter the namespaces string separated by comma")
+	doctorCmd.Flags().BoolVar(&doctorSkipCluster, "skip-cluster", false, "do not check the access to the cluster, for the scans of --image-list or of a registry")
+	doctorCmd.Flags().StringSliceVar(&doctorRegistries, "registries", nil, "comma-separated registries checked to be reachable, for instance registry.example.com,docker.io. The registries of the cluster images are checked when not specified")
+	doctorCmd.Flags().DurationVar(&doctorMaxDBAge, "max-db-age", 7*24*time.Hour, "age of the trivy vulnerability db above which it is reported out of date")
+	doctorCmd.Flags().StringVar(&doctorMinFreeDisk, "min-free-disk", "10Gi", "free disk space of the container runtime storage root, the trivy cache and the temporary directories below which the scans are expected to fail, for instance 20Gi")
+	doctorCmd.Flags().DurationVar(&doctorCheckTimeout, "check-timeout", doctor.DefaultCheckTimeout, "time each check is given to complete, the trivy db download included")
+	addContainerRuntimeFlags(doctorCmd)
+	addTrivyFlags(doctorCmd)
+	addTrivyArgsFlags(doctorCmd)
+	addNetworkFlags(doctorCmd)
+	doctorCmd.Flags().StringVar(&scratchDir, "scratch-dir", "", "directory trivy writes its temporary files and its cache to during the scans, checked for free disk space instead of the trivy cache and the system temporary directory")
+}
+
+func runDoctor(_ *cobra.Command, _ []string) {
+	minFreeDisk, err := resource.ParseQuantity(doctorMinFreeDisk)
+	if err != nil {
+		logr.Fatalf("Invalid --min-free-disk %q: %v", doctorMinFreeDisk, err)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var kubernetesClient k8s.KubernetesClient
+	clusterClient := func() k8s.KubernetesClient {
+		if kubernetesClient == nil {
+			kubernetesClient = newKubernetesClient()
+		}
+		return kubernetesClient
+	}
+	var checks []doctor.Check
+	if !doctorSkipCluster {
+		checks = append(checks, doctor.NewKubernetesCheck(kubeContext, kubeconfigPath, clusterClient, doctor.ScanPermissions))
+	}
+	trivy := &doctor.Trivy{Resolve: resolveTrivyPath, NewClient: func(path string) scanner.TrivyClient {
+		config := &scanner.Config{
+			TrivyPath:          path,
+			TrivyExtraArgs:     strings.Fields(trivyExtraArgs),
+			TrivyCisExtraArgs:  strings.Fields(trivyCisExtraArgs),
+			TrivyDB:            trivyDB(),
+			ScratchDir:         scratchDir,
+			InsecureRegistries: insecureRegistries,
+			ContainerRuntime:   containerRuntime(),
+		}
+		return config.NewTrivyClient()
+	}}
+	containerdAddress := os.Getenv("CONTAINERD_ADDRESS")
+	if containerdAddress == "" {
+		containerdAddress = doctor.DefaultContainerdAddress
+	}
+	runner := execCmd.NewCommandRunner()
+	checks = append(checks,
+		doctor.NewContainerRuntimeCheck(containerRuntime(), runner),
+		doctor.NewContainerdCheck(containerdAddress),
+		doctor.NewTrivyCheck(trivy),
+		doctor.NewTrivyDBCheck(trivy, doctorMaxDBAge),
+		doctor.NewRegistriesCheck(func() ([]string, error) {
+			if len(doctorRegistries) > 0 || doctorSkipCluster {
+				return doctorRegistries, nil
+			}
+			return clusterRegistries(clusterClient)
+		}, &http.Client{Timeout: 30 * time.Second}, insecureRegistries),
+		doctor.NewDiskSpaceCheck(doctorDirs(), containerRuntime(), runner, minFreeDisk.Value()),
+	)
+
+	results := doctor.Run(ctx, doctorCheckTimeout, checks...)
+	if err := doctor.Write(os.Stdout, results); err != nil {
+		logr.Fatal(err)
+	}
+	exitIfInterrupted(ctx)
+	if failures := doctor.Failures(results); failures > 0 {
+		logr.Fatalf("%d of %d checks failed, the scans are expected to fail", failures, len(results))
+	}
+}
+
+// resolveTrivyPath returns the trivy binary the scans run, as trivyPath does but returning the errors
+func resolveTrivyPath() (string, error) {
+	installer := trivybinary.NewInstaller(trivyVersion, trivyCacheDir)
+	if trivyPathFlag != "" {
+		return trivyPathFlag, installer.Validate(trivyPathFlag)
+	}
+	return installer.Resolve()
+}
+
+// clusterRegistries returns the registries of the images of the cluster containers, once the kube config is loaded
+func clusterRegistries(clusterClient func() k8s.KubernetesClient) ([]string, error) {
+	if err := k8s.ValidateKubernetesConfig(kubeContext, kubeconfigPath); err != nil {
+		return nil, err
+	}
+	containers, err := clusterClient().GetContainersInNamespaces(namespaceFilterLabels())
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, container := range containers {
+		images = append(images, container.Image)
+	}
+	return doctor.ImageRegistries(images), nil
+}
+
+// doctorDirs returns the directories the scans write to, the trivy cache and the temporary directory
+func doctorDirs() []string {
+	if scratchDir != "" {
+		return []string{scratchDir}
+	}
+	cacheDir := dbCacheDir
+	if cacheDir == "" {
+		if userCacheDir, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(userCacheDir, "trivy")
+		}
+	}
+	dirs := []string{os.TempDir()}
+	if cacheDir != "" {
+		dirs = append([]string{cacheDir}, dirs...)
+	}
+	return dirs
+}
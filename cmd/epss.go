@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scoreEPSS   bool
+	epssDataset string
+)
+
+func addEPSSFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&scoreEPSS, "epss", false, "score the vulnerabilities with their EPSS probability of being exploited in the next 30 days")
+	cmd.Flags().StringVar(&epssDataset, "epss-dataset", scanner.DefaultEPSSDatasetURL, "URL the EPSS scores dataset is downloaded from, or path of a local copy of the dataset for offline scans. The downloaded dataset is cached for a day in .epsscache/")
+}
+
+// loadEPSSDataset returns the EPSS scores dataset, nil when the vulnerabilities are not scored
+func loadEPSSDataset() *scanner.EPSSDataset {
+	if !scoreEPSS {
+		return nil
+	}
+	dataset, err := scanner.LoadEPSSDataset(&scanner.DatasetConfig{
+		Source:    epssDataset,
+		CacheFile: ".epsscache/epss_scores-current.csv.gz",
+		MaxAge:    24 * time.Hour,
+	})
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return dataset
+}
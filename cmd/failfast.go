@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var failFast bool
+
+func addFailFastFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "stop the scan and exit with an error as soon as an image has a vulnerability failing --fail-on-severity or --fail-on-known-exploited, rather than scanning all the images first. The reports only hold the images scanned before")
+}
+
+// failurePolicy returns the policy the scan stops on with --fail-fast, nil to scan all the images
+func failurePolicy() *scanner.FailurePolicy {
+	if !failFast {
+		return nil
+	}
+	if failOnSeverity == "" && !failOnKnownExploited {
+		logr.Fatal("--fail-fast requires --fail-on-severity or --fail-on-known-exploited to know which vulnerabilities fail the scan")
+	}
+	return &scanner.FailurePolicy{MinSeverity: minSeverity("fail-on-severity", failOnSeverity), KnownExploited: failOnKnownExploited, FindingsState: findingsState(), FixableOnly: fixableOnly}
+}
+
+// exitIfFailedFast fails the command once the partial reports are written when the scan stopped on a vulnerability,
+// skipping the notifications that would report the findings of an incomplete scan
+func exitIfFailedFast(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport != nil && imageScanReport.Metadata.FailFastFinding != "" {
+		logr.Fatalf("Scan stopped on %s, the reports generated are incomplete", imageScanReport.Metadata.FailFastFinding)
+	}
+}
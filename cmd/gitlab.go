@@ -0,0 +1,26 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/gitlab"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var gitlabReportFile string
+
+func addGitLabFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&gitlabReportFile, "report-output-filename-gitlab", "", "output filename where the vulnerabilities will be saved in the GitLab container scanning report format, to attach to the pipeline as artifacts:reports:container_scanning. No GitLab report will be created unless this option is specified")
+}
+
+// saveGitLabReport saves the GitLab container scanning report of the vulnerabilities when --report-output-filename-gitlab is set
+func saveGitLabReport(report *scanner.VulnerabilityReport) {
+	if gitlabReportFile == "" || report == nil {
+		return
+	}
+	if err := gitlab.NewContainerScanningReport(report, time.Now().UTC()).Save(gitlabReportFile); err != nil {
+		logr.Fatal(err)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var groupBy string
+
+func addGroupByFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&groupBy, "group-by", scanner.GroupByLabels, fmt.Sprintf("grouping of the images in the report: '%s' groups them per area and team using --area-labels and --teams-labels, '%s' per namespace and '%s' per namespace and workload, the workload being the Deployment, StatefulSet, DaemonSet or CronJob running the containers",
+		scanner.GroupByLabels, scanner.GroupByNamespace, scanner.GroupByWorkload))
+}
+
+// groupByMode returns the grouping mode of the images in the report, exiting when it is not supported
+func groupByMode() string {
+	switch groupBy {
+	case scanner.GroupByLabels, scanner.GroupByNamespace, scanner.GroupByWorkload:
+		return groupBy
+	}
+	logr.Fatalf("Unsupported --group-by %q, permitted values: %s, %s, %s", groupBy, scanner.GroupByLabels, scanner.GroupByNamespace, scanner.GroupByWorkload)
+	return ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/grpcapi"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/grpcapi/scanpb"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	grpcPort        int
+	grpcAddress     string
+	grpcTLSCertFile string
+	grpcTLSKeyFile  string
+)
+
+func addGRPCFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&grpcPort, "grpc-port", 0, "port of the gRPC API the scans are started from, their images being streamed as they are scanned. The command serves the API rather than scanning once when specified")
+	cmd.Flags().StringVar(&grpcAddress, "grpc-address", "127.0.0.1", "address the gRPC API listens to, only reachable from the host by default. The API is only served on another address when the SCAN_TRIGGER_TOKEN environment variable is set, the requests holding it as bearer token")
+	cmd.Flags().StringVar(&grpcTLSCertFile, "grpc-tls-cert-file", "", "PEM certificate file the gRPC API is served with over TLS, with --grpc-tls-key-file")
+	cmd.Flags().StringVar(&grpcTLSKeyFile, "grpc-tls-key-file", "", "PEM private key file of --grpc-tls-cert-file")
+}
+
+// grpcServerOptions returns the TLS and the SCAN_TRIGGER_TOKEN authentication options of the gRPC server. The API
+// is only served without token on a loopback address
+func grpcServerOptions() []grpc.ServerOption {
+	var options []grpc.ServerOption
+	if (grpcTLSCertFile == "") != (grpcTLSKeyFile == "") {
+		logr.Fatal("--grpc-tls-cert-file and --grpc-tls-key-file are required together")
+	}
+	if grpcTLSCertFile != "" {
+		transportCredentials, err := credentials.NewServerTLSFromFile(grpcTLSCertFile, grpcTLSKeyFile)
+		if err != nil {
+			logr.Fatalf("Could not load the gRPC TLS certificate: %v", err)
+		}
+		options = append(options, grpc.Creds(transportCredentials))
+	}
+
+	token := os.Getenv("SCAN_TRIGGER_TOKEN")
+	loopback := grpcAddress == "localhost" || net.ParseIP(grpcAddress).IsLoopback()
+	switch {
+	case token != "":
+		options = append(options, grpcapi.TokenAuth(token)...)
+		if grpcTLSCertFile == "" && !loopback {
+			logr.Warn("The gRPC API is served without TLS, the SCAN_TRIGGER_TOKEN bearer token being sent in clear text, set --grpc-tls-cert-file and --grpc-tls-key-file")
+		}
+	case loopback:
+		logr.Warn("The gRPC API is served without authentication, SCAN_TRIGGER_TOKEN is not set")
+	default:
+		logr.Fatalf("SCAN_TRIGGER_TOKEN is required to serve the gRPC API on --grpc-address %s, which is not a loopback address", grpcAddress)
+	}
+	return options
+}
+
+// serveScans serves the gRPC API running the scans of the cluster, or of the image list, until the context is done
+func serveScans(ctx context.Context, kubernetesClient k8s.KubernetesClient, stream io.Writer) {
+	options := grpcServerOptions()
+	address := net.JoinHostPort(grpcAddress, strconv.Itoa(grpcPort))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		logr.Fatalf("Could not listen to the gRPC address %s: %v", address, err)
+	}
+	grpcServer := grpc.NewServer(options...)
+	scanpb.RegisterScanServiceServer(grpcServer, grpcapi.NewServer(ctx, func(ctx context.Context, imageStream io.Writer) (*scanner.VulnerabilityReport, error) {
+		if stream != nil {
+			imageStream = io.MultiWriter(imageStream, stream)
+		}
+		// the config is created for each scan so that the datasets such as the KEV catalog are refreshed
+		return scanAndReport(ctx, kubernetesClient, newScanConfig(imageStream))
+	}))
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	logr.Infof("Serving the gRPC scan API at: %s", address)
+	if err := grpcServer.Serve(listener); err != nil {
+		logr.Fatalf("Unexpected failure when serving the gRPC API: %v", err)
+	}
+	logr.Info("Shut down complete")
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/harbor"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	harborURL      string
+	harborUsername string
+)
+
+func addHarborFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&harborURL, "harbor-url", "", "Harbor url, for instance https://harbor.example.com, whose vulnerability reports are reused for the images it hosts rather than pulling and scanning them. The images Harbor has not scanned are scanned with trivy. The password is read from the HARBOR_PASSWORD environment variable")
+	cmd.Flags().StringVar(&harborUsername, "harbor-username", "", "Harbor user or robot account used to read the vulnerability reports, anonymous when not specified")
+}
+
+// harborScans returns the source of the Harbor vulnerability reports, nil when Harbor is not configured
+func harborScans() scanner.ImageScanSource {
+	if harborURL == "" {
+		return nil
+	}
+	client, err := harbor.NewClient(harborURL, harborUsername, os.Getenv("HARBOR_PASSWORD"))
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return client
+}
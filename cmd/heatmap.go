@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var heatmapFile string
+
+func addHeatmapFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&heatmapFile, "heatmap-output", "", "output filename where the vulnerability counts of the teams and of the namespaces by severity will be saved, as matrices shaped for the heatmap visualisations. The heatmap is saved as csv when the filename ends with .csv and as json otherwise. No heatmap will be saved unless this option is specified")
+}
+
+// saveHeatmap saves the heatmap of the report when --heatmap-output is set
+func saveHeatmap(report *scanner.VulnerabilityReport) {
+	if heatmapFile == "" || report == nil {
+		return
+	}
+	if err := scanner.NewHeatmap(report).Save(heatmapFile); err != nil {
+		logr.Fatal(err)
+	}
+	logr.Infof("Heatmap saved into: %s", heatmapFile)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vulnerabilityHistoryFile string
+	remediationSLAs          string
+	fixLatencyDays           int
+	fixLatencyOutput         string
+)
+
+func addHistoryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&vulnerabilityHistoryFile, "vulnerability-history", "", "json file recording when each vulnerability was first found in each image, updated after each scan so that the report shows the age of the vulnerabilities")
+	cmd.Flags().StringVar(&remediationSLAs, "remediation-slas", "", "maximum number of days the vulnerabilities of each severity may remain in an image, format: 'CRITICAL=7,HIGH=30'. The report lists the vulnerabilities of each team past their SLA, requires --vulnerability-history")
+	cmd.Flags().IntVar(&fixLatencyDays, "fix-latency-days", 90, "number of days of the fixes the mean and median time to fix the vulnerabilities of the report are computed over, per team and per severity, requires --vulnerability-history")
+	cmd.Flags().StringVar(&fixLatencyOutput, "fix-latency-output", "", "file the mean and median time to fix the vulnerabilities per team and per severity is written to as a table, '-' for the standard output, requires --vulnerability-history")
+}
+
+// trackVulnerabilityAges sets the first scan time of the vulnerabilities of the report from the vulnerability history,
+// saves the updated history and reports the vulnerabilities past their remediation SLA
+func trackVulnerabilityAges(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil || vulnerabilityHistoryFile == "" {
+		if remediationSLAs != "" {
+			logr.Warn("--remediation-slas requires --vulnerability-history to know the age of the vulnerabilities")
+		}
+		if fixLatencyOutput != "" {
+			logr.Warn("--fix-latency-output requires --vulnerability-history to know when the vulnerabilities were fixed")
+		}
+		return
+	}
+	slas, err := scanner.ParseRemediationSLAs(remediationSLAs)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	history, err := scanner.LoadVulnerabilityHistory(vulnerabilityHistoryFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	history.Track(imageScanReport)
+	if err := history.Save(vulnerabilityHistoryFile); err != nil {
+		logr.Errorf("Unable to save the vulnerability history: %v", err)
+	}
+	if fixLatencyDays <= 0 {
+		logr.Fatalf("Invalid --fix-latency-days %d, it must be positive", fixLatencyDays)
+	}
+	imageScanReport.FixLatencies = history.FixLatencies(imageScanReport.Metadata.ScanTime.AddDate(0, 0, -fixLatencyDays))
+	imageScanReport.ApplyRemediationSLAs(slas)
+	for _, team := range imageScanReport.SLABreaches() {
+		logr.Warnf("Team %s of area %s has %d vulnerabilities past their remediation SLA", team.Team, team.Area, len(team.Breaches))
+	}
+}
+
+// writeFixLatencies writes the time to fix the vulnerabilities of the report to --fix-latency-output if specified
+func writeFixLatencies(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil || fixLatencyOutput == "" || vulnerabilityHistoryFile == "" {
+		return
+	}
+	w, closeOutput := openOutput(fixLatencyOutput, "fix latency")
+	defer closeOutput()
+	if err := scanner.WriteFixLatencies(w, imageScanReport.FixLatencies); err != nil {
+		logr.Errorf("Unable to write the fix latencies: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	maxImageSize          string
+	scanOversizedImages   bool
+	oversizedImageWorkers int
+)
+
+func addImageSizeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&maxImageSize, "max-image-size", "", "compressed image size above which images are skipped rather than pulled and scanned, for instance 2Gi. There is no maximum size unless this option is specified")
+	cmd.Flags().BoolVar(&scanOversizedImages, "scan-oversized-images", false, "scan the images larger than --max-image-size once all the other images are scanned, rather than skipping them")
+	cmd.Flags().IntVar(&oversizedImageWorkers, "oversized-image-workers", 1, "number of worker to process the oversized images scan in parallel")
+}
+
+// maxImageSizeBytes parses the maximum image size, 0 when not specified
+func maxImageSizeBytes() int64 {
+	if maxImageSize == "" {
+		return 0
+	}
+	size, err := resource.ParseQuantity(maxImageSize)
+	if err != nil {
+		logr.Fatalf("Invalid maximum image size %q: %v", maxImageSize, err)
+	}
+	return size.Value()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// interruptContext returns a context cancelled on SIGINT or SIGTERM, so that the scans in progress are stopped
+// and a partial report is written. A second signal terminates the process immediately
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			logr.Warnf("Received %v, stopping the scans in progress. Send it again to exit immediately", sig)
+			// restore the default behaviour for the next signal
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// exitIfInterrupted stops the command once the partial reports are written, skipping the notifications
+// that would report the findings of an incomplete scan
+func exitIfInterrupted(ctx context.Context) {
+	if ctx.Err() != nil {
+		logr.Fatal("Scan interrupted, the reports generated are incomplete")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var inventoryFile string
+
+func addInventoryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&inventoryFile, "inventory-output", "", "output filename where the inventory of the namespaces, workloads, images and digests captured at scan time will be saved as json, to audit the report and to explain its differences with the diff command. No inventory will be saved unless this option is specified")
+}
+
+// saveInventory saves the inventory of the scanned cluster when --inventory-output is set
+func saveInventory(report *scanner.VulnerabilityReport) {
+	if inventoryFile == "" || report == nil {
+		return
+	}
+	if err := r.SaveReport(scanner.NewInventory(report), inventoryFile); err != nil {
+		logr.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkKnownExploited  bool
+	kevCatalog           string
+	failOnKnownExploited bool
+)
+
+func addKEVFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&checkKnownExploited, "check-known-exploited", false, "cross-reference the vulnerabilities with the CISA Known Exploited Vulnerabilities catalog to flag the vulnerabilities exploited in the wild")
+	cmd.Flags().StringVar(&kevCatalog, "kev-catalog", scanner.DefaultKEVCatalogURL, "URL the CISA Known Exploited Vulnerabilities catalog is downloaded from, or path of a local copy of the catalog for offline scans. The downloaded catalog is cached for a day in .kevcache/")
+	cmd.Flags().BoolVar(&failOnKnownExploited, "fail-on-known-exploited", false, "exit with an error once the reports are generated when a known exploited vulnerability is found, implies --check-known-exploited")
+}
+
+// loadKEVCatalog returns the known exploited vulnerabilities catalog, nil when the vulnerabilities are not cross-referenced
+func loadKEVCatalog() *scanner.KEVCatalog {
+	if !checkKnownExploited && !failOnKnownExploited {
+		return nil
+	}
+	catalog, err := scanner.LoadKEVCatalog(&scanner.DatasetConfig{
+		Source:    kevCatalog,
+		CacheFile: ".kevcache/known_exploited_vulnerabilities.json",
+		MaxAge:    24 * time.Hour,
+	})
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return catalog
+}
+
+// exitIfKnownExploited fails the command when requested and known exploited vulnerabilities are found, except those
+// triaged as false positives or accepted, and those without fixed version with --fixable-only
+func exitIfKnownExploited(imageScanReport *scanner.VulnerabilityReport) {
+	if !failOnKnownExploited || imageScanReport == nil {
+		return
+	}
+	var findings []scanner.VulnerabilityFinding
+	for _, finding := range policyFindings(imageScanReport.KnownExploitedVulnerabilities()) {
+		if !finding.Vulnerability.Triage.Dismissed() {
+			findings = append(findings, finding)
+		}
+	}
+	if len(findings) > 0 {
+		for _, finding := range findings {
+			logr.Errorf("Known exploited vulnerability %s (%s) in %s", finding.Vulnerability.VulnerabilityID, finding.Vulnerability.PkgName, finding.ImageName)
+		}
+		logr.Fatalf("%d known exploited vulnerabilities found", len(findings))
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeQPS     float32
+	kubeBurst   int
+	kubeTimeout time.Duration
+)
+
+func addKubeClientFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Float32Var(&kubeQPS, "kube-qps", 50, "maximum sustained rate of the requests to the Kubernetes API, per second, the client-go default of 5 throttling the container discovery of big clusters")
+	cmd.PersistentFlags().IntVar(&kubeBurst, "kube-burst", 100, "maximum burst of requests to the Kubernetes API above --kube-qps")
+	cmd.PersistentFlags().DurationVar(&kubeTimeout, "kube-timeout", 0, "timeout of each request to the Kubernetes API, for instance 1m, the requests not timing out when 0. The watches of --watch are established again once timed out")
+}
+
+// applyKubeClientFlags sets the rate limiting and the timeout of the Kubernetes clients, exiting when they are invalid
+func applyKubeClientFlags() {
+	if kubeQPS <= 0 || kubeBurst <= 0 {
+		logr.Fatalf("Invalid --kube-qps %v or --kube-burst %d, they must be positive", kubeQPS, kubeBurst)
+	}
+	if kubeTimeout < 0 {
+		logr.Fatalf("Invalid --kube-timeout %s, it must be positive or 0", kubeTimeout)
+	}
+	k8s.SetClientOptions(k8s.ClientOptions{QPS: kubeQPS, Burst: kubeBurst, Timeout: kubeTimeout})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/spf13/cobra"
+)
+
+// kubectlPluginBinary is the name of the binary kubectl runs for kubectl prod-readiness, the dash of the plugin name
+// being replaced with an underscore as kubectl splits the binary names on dashes to find the subcommands
+const kubectlPluginBinary = "kubectl-prod_readiness"
+
+var kubeNamespace string
+
+func init() {
+	if isKubectlPlugin(os.Args[0]) {
+		rootCmd.Use = "prod-readiness"
+		rootCmd.Short = "kubectl plugin to analyse the workloads and the images of a cluster"
+	}
+}
+
+// isKubectlPlugin returns true when the binary is run by kubectl as the prod-readiness plugin, as installed by krew
+func isKubectlPlugin(binary string) bool {
+	name := strings.TrimSuffix(filepath.Base(binary), ".exe")
+	return name == kubectlPluginBinary
+}
+
+func addNamespaceFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&kubeNamespace, "namespace", "n", "", "namespace to restrict the command to, as kubectl --namespace. All the namespaces matching --filters-labels are used when not specified")
+}
+
+// namespaceFilterLabels returns the namespace label selector of --filters-labels, restricted to the --namespace namespace
+func namespaceFilterLabels() string {
+	return k8s.NamespaceSelector(filterLabels, kubeNamespace)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var reportLabelsFile string
+
+func addReportLabelsFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&reportLabelsFile, "report-labels", "", "yaml file of the labels the headings and the severities of the HTML and Markdown reports are rendered with, for instance to localise the reports or to name the severities P1, P2... The headings and severities without label are rendered as is")
+}
+
+// applyReportLabels sets the labels of the reports when a labels file is specified
+func applyReportLabels() {
+	if reportLabelsFile == "" {
+		return
+	}
+	labels, err := r.LoadReportLabels(reportLabelsFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	r.SetReportLabels(labels)
+}
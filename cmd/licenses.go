@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanLicenses    bool
+	allowedLicenses []string
+	deniedLicenses  []string
+)
+
+func addLicenseFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&scanLicenses, "scan-licenses", false, "also run the trivy license scanner on the images and report the package licenses violating the license policy")
+	cmd.Flags().StringSliceVar(&allowedLicenses, "allowed-licenses", nil, "licenses allowed by the license policy, any other license is reported as a violation, for instance 'MIT,Apache-2.0,BSD-*'. All licenses are allowed unless this option is specified")
+	cmd.Flags().StringSliceVar(&deniedLicenses, "denied-licenses", nil, "licenses denied by the license policy, for instance 'AGPL-*,SSPL-1.0'. The licenses trivy classifies as forbidden are denied when neither allowed nor denied licenses are specified")
+}
+
+// licensePolicy returns the license policy the package licenses are classified against
+func licensePolicy() *scanner.LicensePolicy {
+	return &scanner.LicensePolicy{
+		Allowed: allowedLicenses,
+		Denied:  deniedLicenses,
+	}
+}
@@ -19,7 +19,7 @@ var (
 func init() {
 	rootCmd.AddCommand(linuxBenchCmd)
 	linuxBenchCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "kubeconfig file to use if connecting from outside a cluster")
-	linuxBenchCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use if connecting from outside a cluster")
+	linuxBenchCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
 	linuxBenchCmd.Flags().IntVar(&workersLinuxBench, "workers-linux-bench", 5, "number of worker to process linux-bench in parallel")
 }
 
@@ -30,6 +30,7 @@ var (
 )
 
 func main() {
+	addSubsystemCommands()
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -41,18 +42,32 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&enableImageScanning, "scan-image", false, "Enable image scanning")
 	rootCmd.PersistentFlags().StringVar(&image, "image", "", "Name of the image to scan.")
 
+	addConfigFlags(rootCmd)
+	addQuietFlags(rootCmd)
+	addReportLabelsFlags(rootCmd)
+	addReportRunbooksFlags(rootCmd)
+	addKubeClientFlags(rootCmd)
+
 	// _ = rootCmd.MarkPersistentFlagRequired("admin-port")
-	cobra.OnInitialize(onInitialise)
+	rootCmd.PersistentPreRun = onInitialise
 }
 
-func onInitialise() {
+// onInitialise completes the flags of the command with the environment variables and the config file before setting
+// the log level
+func onInitialise(cmd *cobra.Command, _ []string) {
+	applyConfig(cmd)
 	setLogLevel(logLevel)
+	applyQuiet()
+	applyNetworkFlags()
+	applyKubeClientFlags()
+	applyReportLabels()
+	applyReportRunbooks()
 }
 
 func runCheck(_ *cobra.Command, _ []string) {
 	doneCh := make(chan bool, 1)
 
-	startServer(serverAdminPort)
+	startServer(serverAdminPort, nil)
 	if enableImageScanning {
 		cmd := "trivy"
 		args := []string{"image", "-f", "json", image}
@@ -66,7 +81,8 @@ func runCheck(_ *cobra.Command, _ []string) {
 	logr.Info("Shut down complete")
 }
 
-func startServer(adminPort int) *http.Server {
+// startServer starts the admin server serving the metrics, the health and the additional handlers per path
+func startServer(adminPort int, handlers map[string]http.Handler) *http.Server {
 	serverMux := http.NewServeMux()
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", adminPort),
@@ -76,6 +92,9 @@ func startServer(adminPort int) *http.Server {
 	serverMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
 	})
+	for path, handler := range handlers {
+		serverMux.Handle(path, handler)
+	}
 
 	go func() {
 		logr.Infof("Starting to listen at: http://0.0.0.0%s", server.Addr)
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// markdownReportTemplate is the template of the Markdown report, with a summary table of the teams and a collapsible
+// section of details per team
+const markdownReportTemplate = "templates/report-pr.md.tmpl"
+
+var markdownReportFile string
+
+func addMarkdownFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&markdownReportFile, "report-output-filename-markdown", "", "output filename where the vulnerabilities will be saved as a Markdown report with a summary table per team and collapsible details per team, to post as a pull request comment or to publish to a wiki. No Markdown report will be created unless this option is specified")
+}
+
+// saveMarkdownReport saves the Markdown report of the vulnerabilities when --report-output-filename-markdown is set
+func saveMarkdownReport(report *scanner.VulnerabilityReport) {
+	if markdownReportFile == "" || report == nil {
+		return
+	}
+	err := r.GenerateReport(&FullReport{ImageScan: report}, markdownReportTemplate, r.TextEngine, filepath.Dir(markdownReportFile)+"/", filepath.Base(markdownReportFile))
+	if err != nil {
+		logr.Fatal(err)
+	}
+}
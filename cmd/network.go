@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	httpProxy          string
+	httpsProxy         string
+	noProxy            string
+	caBundle           string
+	insecureRegistries []string
+)
+
+func addNetworkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&httpProxy, "http-proxy", "", "proxy of the HTTP requests of the tool, docker and trivy, the HTTP_PROXY environment variable when not specified")
+	cmd.Flags().StringVar(&httpsProxy, "https-proxy", "", "proxy of the HTTPS requests of the tool, docker and trivy, the HTTPS_PROXY environment variable when not specified")
+	cmd.Flags().StringVar(&noProxy, "no-proxy", "", "comma-separated hosts, domains and CIDRs reached without proxy, for instance the Kubernetes API server, the NO_PROXY environment variable when not specified")
+	cmd.Flags().StringVar(&caBundle, "ca-bundle", "", "PEM file of the certificate authorities trusted by the tool, docker and trivy instead of the system ones, for instance the CA of a TLS intercepting proxy")
+	cmd.Flags().StringSliceVar(&insecureRegistries, "insecure-registries", nil, "comma-separated registries the images are read from without TLS verification or over plain HTTP, for instance registry.internal:5000")
+}
+
+// applyNetworkFlags exports the proxies and the CA bundle to the environment of the tool, inherited by the docker and
+// trivy commands it runs
+func applyNetworkFlags() {
+	for name, value := range map[string]string{"HTTP_PROXY": httpProxy, "HTTPS_PROXY": httpsProxy, "NO_PROXY": noProxy} {
+		if value != "" {
+			_ = os.Setenv(name, value)
+		}
+	}
+	if caBundle != "" {
+		if _, err := os.Stat(caBundle); err != nil {
+			logr.Fatalf("Invalid --ca-bundle: %v", err)
+		}
+		_ = os.Setenv("SSL_CERT_FILE", caBundle)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/nodeagent"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodeAgentCmd = &cobra.Command{
+		Use:   "node-agent",
+		Short: "Will scan the images of the containers running on the node from the node container runtime storage, without pulling them, and report the scans to the node collector",
+		Run:   runNodeAgent,
+	}
+	nodeCollectorCmd = &cobra.Command{
+		Use:   "node-collector",
+		Short: "Will collect the image scans of the node agents so that the scan command reports them with --source node-agent",
+		Run:   runNodeCollector,
+	}
+	nodeName          string
+	collectorURL      string
+	nodeScanInterval  time.Duration
+	nodeImageSource   string
+	nodeCollectorPort int
+)
+
+// nodeCollectorTokenEnv is the environment variable holding the bearer token the node scans are reported to and read
+// from the node collector with, shared by the collector, the node agents and the scan command
+const nodeCollectorTokenEnv = "NODE_COLLECTOR_TOKEN"
+
+func init() {
+	rootCmd.AddCommand(nodeAgentCmd)
+	nodeAgentCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "kubeconfig file to use if connecting from outside a cluster")
+	nodeAgentCmd.Flags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
+	nodeAgentCmd.Flags().StringVar(&nodeName, "node-name", os.Getenv("NODE_NAME"), "node the agent runs on, the NODE_NAME environment variable by default so that the DaemonSet sets it from spec.nodeName")
+	nodeAgentCmd.Flags().StringVar(&collectorURL, "collector-url", "", "url of the node collector the scans are reported to, for instance http://node-collector:8080")
+	nodeAgentCmd.Flags().DurationVar(&nodeScanInterval, "scan-interval", 6*time.Hour, "interval the images of the node are rescanned at")
+	nodeAgentCmd.Flags().StringVar(&nodeImageSource, "image-src", "containerd", "trivy image source the images are read from, the container runtime of the node such as containerd, docker or podman")
+	nodeAgentCmd.Flags().StringVar(&filterLabels, "filters-labels", "", "string allowing to filter the namespaces string separated by comma")
+	nodeAgentCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
+	nodeAgentCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for each container image scan")
+	addTrivyFlags(nodeAgentCmd)
+	addNetworkFlags(nodeAgentCmd)
+	addTrivyArgsFlags(nodeAgentCmd)
+	addIgnoreUnfixedFlags(nodeAgentCmd)
+	_ = nodeAgentCmd.MarkFlagRequired("collector-url")
+
+	rootCmd.AddCommand(nodeCollectorCmd)
+	nodeCollectorCmd.Flags().IntVar(&nodeCollectorPort, "port", 8080, "port the collector listens to")
+}
+
+func runNodeAgent(_ *cobra.Command, _ []string) {
+	if nodeName == "" {
+		logr.Fatal("--node-name or the NODE_NAME environment variable is required")
+	}
+	token := os.Getenv(nodeCollectorTokenEnv)
+	if token == "" {
+		logr.Fatalf("the %s environment variable is required to report the scans to the collector", nodeCollectorTokenEnv)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	config := &scanner.Config{
+		Severity:            severity,
+		ScanImageTimeout:    scanTimeout,
+		IgnoreUnfixed:       ignoreUnfixed,
+		TrivyPath:           trivyPath(),
+		TrivyExtraArgs:      strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs: strings.Fields(trivyImageExtraArgs),
+		TrivyDB:             trivyDB(),
+		TrivyImageSource:    nodeImageSource,
+		InsecureRegistries:  insecureRegistries,
+	}
+	kubernetesClient := k8s.NewKubernetesClientWithDiscovery(kubeContext, kubeconfigPath, k8s.DiscoveryOptions{NodeName: nodeName})
+	agent := nodeagent.NewAgent(nodeName, collectorURL, token, filterLabels, kubernetesClient, config.NewTrivyClient())
+	logr.Infof("Scanning the images of node %s every %v", nodeName, nodeScanInterval)
+	agent.Run(ctx, nodeScanInterval)
+	logr.Info("Node agent stopped")
+}
+
+func runNodeCollector(_ *cobra.Command, _ []string) {
+	token := os.Getenv(nodeCollectorTokenEnv)
+	if token == "" {
+		logr.Fatalf("the %s environment variable is required, the node scans being reported and read with it as bearer token", nodeCollectorTokenEnv)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", nodeCollectorPort),
+		Handler:           nodeagent.NewCollector(token).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelShutdown()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logr.Infof("Collecting the node scans at: http://0.0.0.0%s/api/v1/node-scans", httpServer.Addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logr.Fatalf("Unexpected failure when starting the collector: %v", err)
+	}
+	logr.Info("Shut down complete")
+}
+
+// nodeAgentImageScans reads the scans of the node agents from the collector of --node-collector-url
+func nodeAgentImageScans(ctx context.Context) (scanner.ImageScanSource, error) {
+	if nodeCollectorURL == "" {
+		logr.Fatalf("--node-collector-url is required with --source %s", sourceNodeAgent)
+	}
+	token := os.Getenv(nodeCollectorTokenEnv)
+	if token == "" {
+		logr.Fatalf("the %s environment variable is required with --source %s", nodeCollectorTokenEnv, sourceNodeAgent)
+	}
+	nodeScans, err := nodeagent.FetchNodeScans(ctx, nodeCollectorURL, token)
+	if err != nil {
+		return nil, err
+	}
+	logr.Infof("Read the image scans of %d nodes", len(nodeScans))
+	return nodeagent.NewImageScanSource(nodeScans)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var pauseOnNodePressure bool
+
+func addNodePressureFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&pauseOnNodePressure, "pause-on-node-pressure", false, "when running in a pod of the scanned cluster, pause the image pulls while the node hosting the scanner is under disk or memory pressure and resume once it recovers, so that the pulls do not get the workloads of small nodes evicted. The node is read from the NODE_NAME environment variable, set from spec.nodeName")
+}
+
+// scannerNodeName returns the node hosting the scanner whose pressure pauses the image pulls, empty unless
+// --pause-on-node-pressure is set
+func scannerNodeName() string {
+	if !pauseOnNodePressure {
+		return ""
+	}
+	name := os.Getenv("NODE_NAME")
+	if name == "" {
+		logr.Fatal("--pause-on-node-pressure requires the NODE_NAME environment variable, set from spec.nodeName with the downward API")
+	}
+	return name
+}
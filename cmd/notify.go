@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/github"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/jira"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/notifier"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/webhook"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	baselineReportFile, slackWebhookURL, slackTeamChannels              string
+	jiraURL, jiraUsername, jiraProject, jiraTeamProjects, jiraIssueType string
+	githubAPIURL, githubRepository, githubTeamRepositories              string
+	webhookURL, webhookMode                                             string
+	msTeamsWebhookURL, msTeamsTeamWebhookURLs, msTeamsAreaWebhookURLs   string
+	smtpHost, smtpUsername, emailFrom, emailTeamRecipients              string
+	emailAreaRecipients                                                 string
+	slackChannels, emailTo, enabledNotifiers                            []string
+	notifyPerTeam, notifyPerArea                                        bool
+	notifyTopImages, smtpPort                                           int
+)
+
+// the notification channels selected with --notifiers
+const (
+	notifierSlack   = "slack"
+	notifierMSTeams = "msteams"
+	notifierEmail   = "email"
+)
+
+func addNotificationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&baselineReportFile, "baseline-report", "", "json report of a previous scan used to identify new vulnerabilities")
+	cmd.Flags().StringVar(&slackWebhookURL, "slack-webhook-url", "", "Slack incoming webhook url used to post the scan summary. No notification is sent unless this option is specified")
+	cmd.Flags().StringSliceVar(&slackChannels, "slack-channels", nil, "Slack channels to post the scan summary to (comma separated). The webhook default channel is used when not specified")
+	cmd.Flags().StringVar(&slackTeamChannels, "slack-team-channels", "", "Slack channel per team used with --notify-per-team, format: 'team1=#channel1,team2=#channel2'")
+	cmd.Flags().BoolVar(&notifyPerTeam, "notify-per-team", false, "send one notification per team based on the team label instead of a single summary")
+	cmd.Flags().BoolVar(&notifyPerArea, "notify-per-area", false, "send one notification per area based on the area label instead of a single summary, ignored with --notify-per-team")
+	cmd.Flags().StringSliceVar(&enabledNotifiers, "notifiers", nil, "notification channels the summaries are sent to (permitted values: slack, msteams, email), all the configured ones when not specified")
+	cmd.Flags().StringVar(&msTeamsWebhookURL, "msteams-webhook-url", "", "Microsoft Teams incoming webhook url used to post the scan summary")
+	cmd.Flags().StringVar(&msTeamsTeamWebhookURLs, "msteams-team-webhook-urls", "", "Microsoft Teams webhook url per team used with --notify-per-team, format: 'team1=<url1>,team2=<url2>'")
+	cmd.Flags().StringVar(&msTeamsAreaWebhookURLs, "msteams-area-webhook-urls", "", "Microsoft Teams webhook url per area used with --notify-per-area, and --notify-per-team for the teams without webhook, format: 'area1=<url1>,area2=<url2>'")
+	cmd.Flags().StringVar(&smtpHost, "smtp-host", "", "SMTP server used to email the scan summary. No email is sent unless this option is specified. The password is read from the SMTP_PASSWORD environment variable")
+	cmd.Flags().IntVar(&smtpPort, "smtp-port", 587, "port of the SMTP server")
+	cmd.Flags().StringVar(&smtpUsername, "smtp-username", "", "user authenticating with the SMTP server, no authentication being used when not specified")
+	cmd.Flags().StringVar(&emailFrom, "email-from", "", "sender address of the summary emails")
+	cmd.Flags().StringSliceVar(&emailTo, "email-to", nil, "recipients of the summary emails (comma separated)")
+	cmd.Flags().StringVar(&emailTeamRecipients, "email-team-recipients", "", "recipients per team used with --notify-per-team, separated with semicolons, format: 'team1=a@example.com;b@example.com,team2=c@example.com'")
+	cmd.Flags().StringVar(&emailAreaRecipients, "email-area-recipients", "", "recipients per area used with --notify-per-area, and --notify-per-team for the teams without recipient, format: 'area1=a@example.com,area2=b@example.com'")
+	cmd.Flags().IntVar(&notifyTopImages, "notify-top-images", 5, "number of most vulnerable images listed in the notifications")
+	cmd.Flags().BoolVar(&fixableOnly, "notify-fixable-only", false, "only notify and open jira tickets and github issues for the new critical vulnerabilities with a fixed version, as the teams cannot act on the other ones")
+	_ = cmd.Flags().MarkDeprecated("notify-fixable-only", "use --fixable-only, which also applies to the severity budgets and the failure flags")
+	cmd.Flags().StringVar(&jiraURL, "jira-url", "", "Jira base url used to open tickets for new critical vulnerabilities. No ticket is created unless this option is specified. The API token is read from the JIRA_API_TOKEN environment variable")
+	cmd.Flags().StringVar(&jiraUsername, "jira-username", "", "Jira user used to authenticate with the API token")
+	cmd.Flags().StringVar(&jiraProject, "jira-project", "", "Jira project key used for the teams not listed in --jira-team-projects")
+	cmd.Flags().StringVar(&jiraTeamProjects, "jira-team-projects", "", "Jira project key per team, format: 'team1=PROJ1,team2=PROJ2'")
+	cmd.Flags().StringVar(&jiraIssueType, "jira-issue-type", "Bug", "Jira issue type of the created tickets")
+	cmd.Flags().StringVar(&githubRepository, "github-repository", "", "GitHub repository (owner/name) the issues of the new high and critical vulnerabilities are opened in for the teams not listed in --github-team-repositories. The token is read from the GITHUB_TOKEN environment variable")
+	cmd.Flags().StringVar(&githubTeamRepositories, "github-team-repositories", "", "GitHub repository per team, format: 'team1=org/repo1,team2=org/repo2'. No issue is opened unless this option or --github-repository is specified")
+	cmd.Flags().StringVar(&githubAPIURL, "github-api-url", github.DefaultAPIURL, "GitHub API url, https://<host>/api/v3 for GitHub Enterprise Server")
+	cmd.Flags().StringVar(&webhookURL, "webhook-url", "", "url the json report is posted to. Requests are signed with HMAC-SHA256 when the WEBHOOK_SECRET environment variable is set")
+	cmd.Flags().StringVar(&webhookMode, "webhook-mode", webhook.ModeReport, "whether to post the whole report or one event per scanned image (permitted values: report, image)")
+}
+
+func loadBaselineReport() *scanner.VulnerabilityReport {
+	if baselineReportFile == "" {
+		return nil
+	}
+	baseline, err := scanner.LoadVulnerabilityReport(baselineReportFile)
+	if err != nil {
+		logr.Errorf("Unable to load baseline report, all vulnerabilities will be considered new: %v", err)
+		return nil
+	}
+	return baseline
+}
+
+func notifiers() []notifier.Notifier {
+	var n []notifier.Notifier
+	if slackWebhookURL != "" && notifierEnabled(notifierSlack) {
+		n = append(n, notifier.NewSlackNotifier(&notifier.SlackConfig{
+			WebhookURL:   slackWebhookURL,
+			Channels:     slackChannels,
+			TeamChannels: parseKeyValues(slackTeamChannels),
+		}))
+	}
+	if (msTeamsWebhookURL != "" || msTeamsTeamWebhookURLs != "" || msTeamsAreaWebhookURLs != "") && notifierEnabled(notifierMSTeams) {
+		n = append(n, notifier.NewMSTeamsNotifier(&notifier.MSTeamsConfig{
+			WebhookURL:      msTeamsWebhookURL,
+			TeamWebhookURLs: parseKeyValues(msTeamsTeamWebhookURLs),
+			AreaWebhookURLs: parseKeyValues(msTeamsAreaWebhookURLs),
+		}))
+	}
+	if smtpHost != "" && notifierEnabled(notifierEmail) {
+		if emailFrom == "" {
+			logr.Fatal("--email-from is required with --smtp-host")
+		}
+		n = append(n, notifier.NewEmailNotifier(&notifier.EmailConfig{
+			Host:           smtpHost,
+			Port:           smtpPort,
+			Username:       smtpUsername,
+			Password:       os.Getenv("SMTP_PASSWORD"),
+			From:           emailFrom,
+			To:             emailTo,
+			TeamRecipients: parseKeyValues(emailTeamRecipients),
+			AreaRecipients: parseKeyValues(emailAreaRecipients),
+		}))
+	}
+	return n
+}
+
+// notifierEnabled returns whether the notification channel is selected with --notifiers, all the channels being
+// selected when the option is not specified
+func notifierEnabled(name string) bool {
+	if len(enabledNotifiers) == 0 {
+		return true
+	}
+	for _, enabled := range enabledNotifiers {
+		switch enabled {
+		case notifierSlack, notifierMSTeams, notifierEmail:
+		default:
+			logr.Fatalf("Invalid notifier %q, permitted values: %s, %s, %s", enabled, notifierSlack, notifierMSTeams, notifierEmail)
+		}
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sendNotifications(report, baseline *scanner.VulnerabilityReport) {
+	n := notifiers()
+	if len(n) == 0 || report == nil {
+		return
+	}
+	summaries := notifier.NewSummaries(report.WithMinSeverity(minSeverity("notify-min-severity", notifyMinSeverity)), baseline, &notifier.Config{
+		TopImages:   notifyTopImages,
+		PerTeam:     notifyPerTeam,
+		PerArea:     notifyPerArea,
+		FixableOnly: fixableOnly,
+	})
+	if err := notifier.NotifyAll(n, summaries); err != nil {
+		logr.Error(err)
+	}
+}
+
+func createJiraTickets(report, baseline *scanner.VulnerabilityReport) {
+	if jiraURL == "" || report == nil {
+		return
+	}
+	client := jira.NewClient(jiraURL, jiraUsername, os.Getenv("JIRA_API_TOKEN"))
+	ticketer := jira.New(client, &jira.Config{
+		DefaultProject: jiraProject,
+		TeamProjects:   parseKeyValues(jiraTeamProjects),
+		IssueType:      jiraIssueType,
+		FixableOnly:    fixableOnly,
+	})
+	if err := ticketer.CreateTickets(report, baseline); err != nil {
+		logr.Error(err)
+	}
+}
+
+func syncGitHubIssues(report, baseline *scanner.VulnerabilityReport) {
+	if (githubRepository == "" && githubTeamRepositories == "") || report == nil {
+		return
+	}
+	client := github.NewClient(githubAPIURL, os.Getenv("GITHUB_TOKEN"))
+	issuer := github.New(client, &github.Config{
+		DefaultRepository: githubRepository,
+		TeamRepositories:  parseKeyValues(githubTeamRepositories),
+		FixableOnly:       fixableOnly,
+	})
+	if err := issuer.SyncIssues(report, baseline); err != nil {
+		logr.Error(err)
+	}
+}
+
+func deliverWebhook(fullReport interface{}, imageScanReport *scanner.VulnerabilityReport) {
+	if webhookURL == "" {
+		return
+	}
+	sink, err := webhook.New(&webhook.Config{
+		URL:    webhookURL,
+		Secret: os.Getenv("WEBHOOK_SECRET"),
+		Mode:   webhookMode,
+	})
+	if err != nil {
+		logr.Error(err)
+		return
+	}
+	if err := sink.Deliver(fullReport, imageScanReport); err != nil {
+		logr.Error(err)
+	}
+}
+
+// parseKeyValues parses a string with the format 'key1=value1,key2=value2'
+func parseKeyValues(value string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		items := strings.SplitN(pair, "=", 2)
+		if len(items) != 2 {
+			logr.Warnf("Ignoring invalid key/value pair %q, expected format 'key=value'", pair)
+			continue
+		}
+		result[strings.TrimSpace(items[0])] = strings.TrimSpace(items[1])
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/ocsf"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var ocsfReportFile string
+
+func addOCSFFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&ocsfReportFile, "report-output-filename-ocsf", "", "output filename where the vulnerabilities will be saved as OCSF Vulnerability Finding events, one json event per line, to load into security data lakes such as Amazon Security Lake. No OCSF findings will be created unless this option is specified")
+}
+
+// saveOCSFFindings saves the OCSF Vulnerability Finding events of the vulnerabilities when --report-output-filename-ocsf is set
+func saveOCSFFindings(report *scanner.VulnerabilityReport) {
+	if ocsfReportFile == "" || report == nil {
+		return
+	}
+	if err := ocsf.Save(ocsf.NewVulnerabilityFindings(report), ocsfReportFile); err != nil {
+		logr.Fatal(err)
+	}
+}
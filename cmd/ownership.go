@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var ownershipMappingFile string
+
+func addOwnershipFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&ownershipMappingFile, "ownership-mapping", "", "yaml file attributing the images of a namespace or with an image prefix to an area and team when their --area-labels or --teams-labels are missing")
+}
+
+// ownershipMapping returns the ownership mapping, nil when no mapping file is specified
+func ownershipMapping() *scanner.OwnershipMapping {
+	if ownershipMappingFile == "" {
+		return nil
+	}
+	mapping, err := scanner.LoadOwnershipMapping(ownershipMappingFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return mapping
+}
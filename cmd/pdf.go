@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pdfReport          bool
+	pdfConverterBinary string
+)
+
+func addPDFFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&pdfReport, "report-output-pdf", false, "also render the HTML reports as PDF documents, next to the HTML reports")
+	cmd.Flags().StringVar(&pdfConverterBinary, "pdf-converter", r.DefaultPDFConverterCommand, "wkhtmltopdf compatible command used to render the HTML reports as PDF")
+}
+
+// convertToPDF renders the HTML reports of the report directory as PDF when requested
+func convertToPDF(htmlReportFiles ...string) {
+	if !pdfReport {
+		return
+	}
+	converter := r.NewPDFConverter(pdfConverterBinary)
+	for _, htmlReportFile := range htmlReportFiles {
+		if _, err := converter.Convert(reportDir + htmlReportFile); err != nil {
+			logr.Fatal(err)
+		}
+	}
+}
+
+// convertHTMLReportToPDF renders the report as PDF when requested and generated from an HTML template
+func convertHTMLReportToPDF(reportFile string) {
+	if pdfReport && !strings.HasSuffix(reportFile, ".html") {
+		logr.Warnf("Report %s is not an HTML report, skipping PDF rendering", reportFile)
+		return
+	}
+	convertToPDF(reportFile)
+}
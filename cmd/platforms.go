@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var platformSelection string
+
+func addPlatformFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&platformSelection, "platforms", scanner.PlatformsHost, "platforms of the multi-platform images to scan: "+scanner.PlatformsHost+" for the platform docker pulls on the scanning host, "+scanner.PlatformsNodes+" for the platforms of the nodes running the images or "+scanner.PlatformsAll+" for every platform. The scanned platforms are recorded in the results")
+}
+
+// platforms returns the platform selection mode of the multi-platform images
+func platforms() string {
+	if err := scanner.ValidatePlatforms(platformSelection); err != nil {
+		logr.Fatal(err)
+	}
+	return platformSelection
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	// The compile-time check plugins are packages registering their checks with checks.Register in their init
+	// function, added to the build by importing them here, for instance:
+	// _ "example.com/platform/readiness-checks"
+)
+
+var (
+	checkPlugins       []string
+	checkPluginTimeout time.Duration
+)
+
+func addCheckPluginFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&checkPlugins, "check-plugins", nil, "comma-separated readiness checks run by executable plugins, given as name=command, for instance cost-centre=/usr/local/bin/cost-centre-check. The command is run with the path of a json file holding the workloads and the image scan results, and writes the findings as json to its standard output. The plugins are run in addition to the checks selected with --checks")
+	cmd.Flags().DurationVar(&checkPluginTimeout, "check-plugin-timeout", 5*time.Minute, "time each plugin of --check-plugins is given to write its findings before being killed, never killed when 0")
+}
+
+// withRegisteredChecks adds the checks registered by the compile-time plugins to the available checks
+func withRegisteredChecks(available map[string]checks.CheckFactory) map[string]checks.CheckFactory {
+	for name, factory := range checks.RegisteredChecks() {
+		available[name] = factory
+	}
+	return available
+}
+
+// pluginChecks returns the checks run by the executable plugins
+func pluginChecks() []checks.Check {
+	pluginChecks, err := checks.ParseExecChecks(checkPlugins, checkPluginTimeout)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return pluginChecks
+}
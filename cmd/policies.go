@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	policyDirs       []string
+	policyNamespaces []string
+)
+
+func addPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&policyDirs, "policy-dir", nil, "directories of custom Rego policy bundles the Kubernetes resources are evaluated against in addition to the trivy built-in checks")
+	cmd.Flags().StringSliceVar(&policyNamespaces, "policy-namespaces", []string{scanner.DefaultPolicyNamespace}, "Rego package prefixes of the custom policies of --policy-dir, for instance 'user' for 'package user.kubernetes.ORG001'")
+}
+
+// regoPolicies returns the custom Rego policies of the flags, stopping the command when a policy directory is missing
+func regoPolicies() scanner.RegoPolicies {
+	policies := scanner.RegoPolicies{Dirs: policyDirs, Namespaces: policyNamespaces}
+	if err := policies.Validate(); err != nil {
+		logr.Fatalf("Invalid --policy-dir: %v", err)
+	}
+	return policies
+}
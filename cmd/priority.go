@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var scanPriorityWeights string
+
+func addScanPriorityFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scanPriorityWeights, "scan-priority", "", "weights of the priority the images are scanned and streamed by, for instance 'exposure=3,criticality=2,previous-score=1'. The criticality is read from the "+k8s.CriticalityAnnotation+" annotation of the pods, workloads or namespaces and the previous score from the --baseline-report. The images are scanned by decreasing exposure when empty")
+}
+
+// scanPriority returns the priority the images are scanned by, nil to scan them by decreasing exposure
+func scanPriority() *scanner.ScanPriority {
+	if scanPriorityWeights == "" {
+		return nil
+	}
+	priority, err := scanner.ParseScanPriority(scanPriorityWeights)
+	if err != nil {
+		logr.Fatalf("Invalid --scan-priority: %v", err)
+	}
+	if priority.PreviousScoreWeight > 0 {
+		if baselineReportFile == "" {
+			logr.Warnf("No --baseline-report, the previous score of the images is not weighted in their scan priority")
+		}
+		priority.PreviousReport = loadBaselineReport()
+	}
+	return priority
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	provenanceBuilders, provenanceSourceRepositories []string
+	failOnMissingProvenance                          bool
+)
+
+func addProvenanceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&provenanceBuilders, "provenance-builders", nil, "ids of the builders trusted by the "+checks.ImageProvenanceCheckName+" check, matched as prefixes, for instance https://github.com/slsa-framework/slsa-github-generator/ (comma separated). Any builder is trusted when not specified")
+	cmd.Flags().StringSliceVar(&provenanceSourceRepositories, "provenance-source-repos", nil, "source repositories trusted by the "+checks.ImageProvenanceCheckName+" check, matched as prefixes, for instance https://github.com/example/ (comma separated). Any repository is trusted when not specified")
+	cmd.Flags().BoolVar(&failOnMissingProvenance, "fail-on-missing-provenance", false, "exit with an error once the reports are generated when the "+checks.ImageProvenanceCheckName+" check finds images without SLSA provenance attestation")
+}
+
+func provenancePolicy() *checks.ProvenancePolicy {
+	return &checks.ProvenancePolicy{Builders: provenanceBuilders, SourceRepositories: provenanceSourceRepositories}
+}
+
+// exitIfMissingProvenance fails the command when requested and images without provenance attestation are found
+func exitIfMissingProvenance(checksReport *checks.ReadinessReport) {
+	if !failOnMissingProvenance || checksReport == nil {
+		return
+	}
+	if findings := checksReport.MissingProvenance(); len(findings) > 0 {
+		for _, finding := range findings {
+			logr.Errorf("No provenance attestation for container %s of %s %s/%s: %s", finding.Container, finding.Kind, finding.Namespace, finding.Workload, finding.Message)
+		}
+		logr.Fatalf("%d containers run images without provenance attestation", len(findings))
+	}
+}
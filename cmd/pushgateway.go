@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/pushgateway"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pushgatewayURL, pushgatewayJob, pushgatewayGrouping, pushgatewayUsername string
+)
+
+func addPushgatewayFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&pushgatewayURL, "pushgateway-url", "", "Prometheus Pushgateway url the metrics of the run are pushed to once finished, such as the vulnerabilities per severity and team, the scan duration and the failed scans, so that the runs of a CronJob are observable. The basic auth password is read from the PUSHGATEWAY_PASSWORD environment variable")
+	cmd.Flags().StringVar(&pushgatewayJob, "pushgateway-job", pushgateway.DefaultJob, "job the pushed metrics are grouped by")
+	cmd.Flags().StringVar(&pushgatewayGrouping, "pushgateway-grouping", "", "labels the pushed metrics are grouped by in addition to the job, format: 'cluster=prod,region=eu'")
+	cmd.Flags().StringVar(&pushgatewayUsername, "pushgateway-username", "", "user authenticating with basic auth to the Pushgateway")
+}
+
+// pushMetrics pushes the metrics of the run to the Pushgateway of --pushgateway-url, if any
+func pushMetrics(report *scanner.VulnerabilityReport, duration time.Duration, runErr error) {
+	if pushgatewayURL == "" {
+		return
+	}
+	err := pushgateway.Push(&pushgateway.Config{
+		URL:      pushgatewayURL,
+		Job:      pushgatewayJob,
+		Grouping: parseKeyValues(pushgatewayGrouping),
+		Username: pushgatewayUsername,
+		Password: os.Getenv("PUSHGATEWAY_PASSWORD"),
+	}, report, duration, runErr)
+	if err != nil {
+		logr.Error(err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// quiet only logs the errors and writes the json report to the standard output, for instance to pipe the scan to jq
+var quiet bool
+
+func addQuietFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "only log the errors, to the standard error, and write only the json representation of the report to the standard output, for instance to pipe it to jq. The report files are still generated")
+}
+
+// applyQuiet raises the log level to error when --quiet is set, so that the errors and the reason the command fails are
+// still logged to the standard error while the standard output is kept for the report
+func applyQuiet() {
+	if !quiet {
+		return
+	}
+	if err := quietOutputConflict(); err != nil {
+		logr.Fatal(err)
+	}
+	if logr.GetLevel() > logr.ErrorLevel {
+		logr.SetLevel(logr.ErrorLevel)
+	}
+}
+
+// quietOutputConflict returns an error when an output is written to the standard output, where --quiet writes the report
+func quietOutputConflict() error {
+	outputs := []struct{ flag, filename string }{
+		{"stream-output", streamOutput},
+		{"scoreboard-output", scoreboardOutput},
+		{"fix-latency-output", fixLatencyOutput},
+		{"readiness-score-output", readinessScoreOutput},
+	}
+	for _, output := range outputs {
+		if output.filename == "-" {
+			return fmt.Errorf("--quiet writes the report to the standard output, it cannot be combined with --%s -", output.flag)
+		}
+	}
+	return nil
+}
+
+// openOutput opens the file an output is written to, '-' being the standard output, returning the function closing it
+func openOutput(filename, description string) (io.Writer, func()) {
+	if filename == "-" {
+		return os.Stdout, func() {}
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		logr.Fatalf("Could not create %s file %s: %v", description, filename, err)
+	}
+	return file, func() { _ = file.Close() }
+}
+
+// writeQuietReport writes the json representation of the report to the standard output when --quiet is set
+func writeQuietReport(report interface{}) {
+	if !quiet {
+		return
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+		logr.Fatalf("Error writing the report to the standard output: %v", err)
+	}
+}
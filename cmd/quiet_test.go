@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommands(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Commands Suite")
+}
+
+var _ = Describe("Quiet output", func() {
+
+	BeforeEach(func() {
+		streamOutput, scoreboardOutput, fixLatencyOutput, readinessScoreOutput = "", "", "", ""
+		DeferCleanup(func() {
+			streamOutput, scoreboardOutput, fixLatencyOutput, readinessScoreOutput = "", "", "", ""
+		})
+	})
+
+	It("rejects the outputs written to the standard output along with the report", func() {
+		for flag, output := range map[string]*string{
+			"stream-output":          &streamOutput,
+			"scoreboard-output":      &scoreboardOutput,
+			"fix-latency-output":     &fixLatencyOutput,
+			"readiness-score-output": &readinessScoreOutput,
+		} {
+			*output = "-"
+			Expect(quietOutputConflict()).To(MatchError("--quiet writes the report to the standard output, it cannot be combined with --" + flag + " -"))
+			*output = ""
+		}
+	})
+
+	It("accepts the outputs written to files", func() {
+		streamOutput, scoreboardOutput, fixLatencyOutput, readinessScoreOutput = "stream.jsonl", "scoreboard.txt", "latency.txt", "scores.txt"
+
+		Expect(quietOutputConflict()).To(Succeed())
+	})
+
+	It("opens the output file, or the standard output for '-'", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "scoreboard.txt")
+
+		w, closeOutput := openOutput(filename, "scoreboard")
+		_, err := w.Write([]byte("team-a 12\n"))
+		closeOutput()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(filename)).To(Equal([]byte("team-a 12\n")))
+		w, _ = openOutput("-", "scoreboard")
+		Expect(w).To(Equal(os.Stdout))
+	})
+})
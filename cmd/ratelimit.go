@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strconv"
+
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var registryRateLimits, registryWorkerLimits string
+
+func addRateLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&registryRateLimits, "registry-rate-limits", "", "maximum number of image pulls per minute per registry, format: 'docker.io=10,quay.io=60'. Images without registry host are pulled from docker.io, registries not listed are not limited")
+	cmd.Flags().StringVar(&registryWorkerLimits, "registry-workers", "", "maximum number of images of each registry scanned in parallel within the --scan-workers, format: 'docker.io=2,registry.example.com=10'. Images without registry host are pulled from docker.io, registries not listed are only limited by the --scan-workers")
+}
+
+// registryPullsPerMinute parses the registry rate limits
+func registryPullsPerMinute() map[string]int {
+	result := make(map[string]int)
+	for registry, value := range parseKeyValues(registryRateLimits) {
+		pulls, err := strconv.Atoi(value)
+		if err != nil || pulls <= 0 {
+			logr.Fatalf("Invalid rate limit %q for registry %s, expected a positive number of pulls per minute", value, registry)
+		}
+		result[registry] = pulls
+	}
+	return result
+}
+
+// registryWorkers parses the registry worker limits
+func registryWorkers() map[string]int {
+	result := make(map[string]int)
+	for registry, value := range parseKeyValues(registryWorkerLimits) {
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers <= 0 {
+			logr.Fatalf("Invalid worker limit %q for registry %s, expected a positive number of workers", value, registry)
+		}
+		result[registry] = workers
+	}
+	return result
+}
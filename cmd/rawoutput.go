@@ -0,0 +1,11 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var rawTrivyOutputDir string
+
+func addRawTrivyOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&rawTrivyOutputDir, "raw-trivy-output-dir", "", "directory the raw trivy json output of each scanned image is written to, compressed with gzip and organised by digest, for instance <dir>/sha256/<digest>.json.gz. The images of unknown digest are written to <dir>/no-digest by name")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	readinessScoreWeights []string
+	readinessScoreOutput  string
+)
+
+func addReadinessScoreFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&readinessScoreWeights, "readiness-score-weights", checks.DefaultReadinessScoreWeights, "weights of the vulnerabilities, the misconfigurations and the workload checks in the readiness score of each team, the percentage of its images and workloads without CRITICAL or HIGH finding. The readiness scores are recorded in the json report")
+	cmd.Flags().StringVar(&readinessScoreOutput, "readiness-score-output", "", "file the readiness scores of the teams are written to as a table, the teams with the highest score first. '-' for the standard output")
+}
+
+// readinessScores returns the readiness scores of the teams, exiting when the weights are invalid
+func readinessScores(imageScanReport *scanner.VulnerabilityReport, checksReport *checks.ReadinessReport) []checks.TeamReadinessScore {
+	weights, err := checks.ParseReadinessScoreWeights(readinessScoreWeights)
+	if err != nil {
+		logr.Fatalf("Invalid --readiness-score-weights: %v", err)
+	}
+	return checks.ReadinessScores(imageScanReport, checksReport, weights)
+}
+
+// writeReadinessScores writes the readiness scores of the teams to --readiness-score-output if specified
+func writeReadinessScores(scores []checks.TeamReadinessScore) {
+	if readinessScoreOutput == "" {
+		return
+	}
+	w, closeOutput := openOutput(readinessScoreOutput, "readiness score")
+	defer closeOutput()
+	if err := checks.WriteReadinessScores(w, scores); err != nil {
+		logr.Errorf("Unable to write the readiness scores: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/recording"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recordDir string
+	replayDir string
+)
+
+func addRecordFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&recordDir, "record", "", "directory the responses of the cluster, trivy and docker are recorded to, so that the reports can be generated again with --replay")
+	cmd.Flags().StringVar(&replayDir, "replay", "", "directory of the responses recorded with --record, replayed instead of accessing the cluster and running trivy and docker")
+}
+
+// validateRecordFlags stops the command when both recording and replaying
+func validateRecordFlags() {
+	if recordDir != "" && replayDir != "" {
+		logr.Fatal("--record and --replay cannot be combined")
+	}
+}
+
+// newKubernetesClient creates the client of the cluster, recording or replaying its responses with --record and --replay
+func newKubernetesClient() k8s.KubernetesClient {
+	switch {
+	case replayDir != "":
+		logr.Infof("Replaying the cluster responses recorded in %s", replayDir)
+		return recording.NewReplayer(replayDir).KubernetesClient()
+	case recordDir != "":
+		logr.Infof("Recording the cluster responses to %s", recordDir)
+		return recording.NewRecorder(recordDir).KubernetesClient(k8s.NewKubernetesClientWithDiscovery(kubeContext, kubeconfigPath, discoveryOptions()))
+	}
+	return k8s.NewKubernetesClientWithDiscovery(kubeContext, kubeconfigPath, discoveryOptions())
+}
+
+// clusterName returns the name of the cluster of the kubeconfig, or the recorded one with --replay
+func clusterName() string {
+	switch {
+	case replayDir != "":
+		return recording.NewReplayer(replayDir).ClusterName()
+	case recordDir != "":
+		return recording.NewRecorder(recordDir).ClusterName(k8s.ClusterName(kubeContext, kubeconfigPath))
+	}
+	return k8s.ClusterName(kubeContext, kubeconfigPath)
+}
+
+// newScanner creates the scanner of the images, recording or replaying the responses of trivy and docker with
+// --record and --replay
+func newScanner(kubernetesClient k8s.KubernetesClient, config *scanner.Config) *scanner.Scanner {
+	switch {
+	case replayDir != "":
+		replayer := recording.NewReplayer(replayDir)
+		return scanner.NewWithClients(kubernetesClient, replayer.DockerClient(), replayer.TrivyClient(), config)
+	case recordDir != "":
+		recorder := recording.NewRecorder(recordDir)
+		return scanner.NewWithClients(kubernetesClient, recorder.DockerClient(config.NewDockerClient()), recorder.TrivyClient(config.NewTrivyClient()), config)
+	}
+	return scanner.New(kubernetesClient, config)
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/spf13/cobra"
+)
+
+var approvedRegistries []string
+
+func addApprovedRegistryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&approvedRegistries, "approved-registries", nil, "registries the images may be pulled from with the "+checks.ApprovedRegistriesCheckName+" check, as registry hosts or repository prefixes, for instance registry.example.com,ghcr.io/example (comma separated). The Docker Hub images are hosted by docker.io")
+}
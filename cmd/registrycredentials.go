@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/secretstore"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var registryCredentials string
+
+func addRegistryCredentialsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&registryCredentials, "registry-credentials", "", "secrets holding the credentials the images of each registry are pulled with, read from Vault, AWS Secrets Manager or GCP Secret Manager with the vault, aws and gcloud CLIs, format: 'ghcr.io=vault://secret/ghcr,docker.io=awssm://dockerhub,europe-docker.pkg.dev=gcpsm://projects/platform/secrets/registry'. A secret holds a JSON object with a username and a password, or a docker config. Registries not listed are pulled with the credentials of the container runtime")
+}
+
+// registryCredentialSource returns the secret store of the registry credentials, nil when no secret is configured
+func registryCredentialSource() scanner.RegistryCredentialSource {
+	secrets := parseKeyValues(registryCredentials)
+	if len(secrets) == 0 {
+		return nil
+	}
+	store, err := secretstore.NewStore(secrets)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return store
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
 	"github.com/coreeng/production-readiness/production-readiness/pkg/linuxbench"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/reportschema"
 	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
 	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
 	logr "github.com/sirupsen/logrus"
@@ -20,79 +25,241 @@ var (
 	kubeContext, kubeconfigPath, imageNameReplacement, areaLabel, teamLabels, filterLabels, severity, jsonReportFile, reportDir, reportFile, reportTemplate string
 	scanWorkers, workersLinuxBench                                                                                                                          int
 	scanTimeout                                                                                                                                             time.Duration
+	reportTemplateEngine                                                                                                                                    string
 )
 
 func init() {
 	rootCmd.AddCommand(reportCmd)
 	reportCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "kubeconfig file to use if connecting from outside a cluster")
-	reportCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use if connecting from outside a cluster")
+	reportCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
+	addNamespaceFlags(reportCmd)
 	reportCmd.Flags().StringVar(&imageNameReplacement, "image-name-replacement", "", "string replacement to replace name into the image name for ex: registry url, format: 'registry-mirror:5000|registry.com,registry-second:5000|registry-second.com' list separated by comma, matching and replacement string are seperated by a pipe '|'")
 	reportCmd.Flags().IntVar(&scanWorkers, "scan-workers", 10, "number of worker to process images scan in parallel")
 	reportCmd.Flags().IntVar(&workersLinuxBench, "workers-linux-bench", 5, "number of worker to process linux-bench in parallel")
-	reportCmd.Flags().StringVar(&areaLabel, "area-labels", "", "string allowing to split per area the image scan")
-	reportCmd.Flags().StringVar(&teamLabels, "teams-labels", "", "string allowing to split per team the image scan")
+	reportCmd.Flags().StringVar(&areaLabel, "area-labels", "", "comma-separated pod or namespace labels allowing to split per area the image scan, the first label set being used")
+	reportCmd.Flags().StringVar(&teamLabels, "teams-labels", "", "comma-separated pod or namespace labels allowing to split per team the image scan, the first label set being used")
 	reportCmd.Flags().StringVar(&filterLabels, "filters-labels", "", "string allowing to filter the namespaces string separated by comma")
 	reportCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
+	reportCmd.Flags().StringSliceVar(&selectedChecks, "checks", defaultCheckNames(), fmt.Sprintf("List of readiness checks to run, among %v. If not specified all are run except %v", checkNames(), optInCheckNames()))
+	addSignatureFlags(reportCmd)
+	addProvenanceFlags(reportCmd)
+	addApprovedRegistryFlags(reportCmd)
+	addWorkloadRiskFlags(reportCmd)
+	addDigestAllowlistFlags(reportCmd)
+	addImageStalenessFlags(reportCmd)
 	reportCmd.Flags().StringVar(&reportTemplate, "report-input-template", "templates/report.md.tmpl", "input filename that will be used as report template")
-	reportCmd.Flags().StringVar(&reportDir, "report-output-directory", "audit-report/", "output directory that will contain the generated report")
+	reportCmd.Flags().StringVar(&reportTemplateEngine, "report-template-engine", string(r.HTMLEngine), "Go template package used to execute the report template, 'html' escapes the report data for HTML while 'text' writes it as is")
+	addReportDirectoryFlags(reportCmd)
 	reportCmd.Flags().StringVar(&reportFile, "report-output-filename", "report.md", "output filename that will contain the generated report based on the report-template")
 	reportCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	addGitLabFlags(reportCmd)
+	addOCSFFlags(reportCmd)
+	addCycloneDXFlags(reportCmd)
+	addMarkdownFlags(reportCmd)
+	addInventoryFlags(reportCmd)
+	addHeatmapFlags(reportCmd)
+	addCIAnnotationFlags(reportCmd)
+	addReportSigningFlags(reportCmd)
 	reportCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for the container image scan")
+	addNotificationFlags(reportCmd)
+	addDefectDojoFlags(reportCmd)
+	addDependencyTrackFlags(reportCmd)
+	addPDFFlags(reportCmd)
+	addTracingFlags(reportCmd)
+	addRetryFlags(reportCmd)
+	addTrivyFlags(reportCmd)
+	addNetworkFlags(reportCmd)
+	addRegistryCredentialsFlags(reportCmd)
+	addTrivyArgsFlags(reportCmd)
+	addTrivySharedCacheFlags(reportCmd)
+	addRateLimitFlags(reportCmd)
+	addImageSizeFlags(reportCmd)
+	addAutoScanWorkersFlags(reportCmd)
+	addPlatformFlags(reportCmd)
+	addContainerRuntimeFlags(reportCmd)
+	addSecretFlags(reportCmd)
+	addLicenseFlags(reportCmd)
+	addCVSSFlags(reportCmd)
+	addIgnoreUnfixedFlags(reportCmd)
+	addFixableOnlyFlags(reportCmd)
+	addKEVFlags(reportCmd)
+	addEPSSFlags(reportCmd)
+	addAdvisoryFlags(reportCmd)
+	addSeverityOverrideFlags(reportCmd)
+	addGroupByFlags(reportCmd)
+	addDiscoveryFlags(reportCmd)
+	addScoringFlags(reportCmd)
+	addOwnershipFlags(reportCmd)
+	addBudgetFlags(reportCmd)
+	addHistoryFlags(reportCmd)
+	addScoreboardFlags(reportCmd)
+	addFindingsStateFlags(reportCmd)
+	addSeverityFloorFlags(reportCmd)
+	addScanErrorFlags(reportCmd)
+	addCheckpointFlags(reportCmd)
+	addSpillFlags(reportCmd)
+	addScratchFlags(reportCmd)
+	addResultAgeFlags(reportCmd)
+	addNodePressureFlags(reportCmd)
+	addSourceFlags(reportCmd)
+	addHarborFlags(reportCmd)
+	addSBOMFlags(reportCmd)
+	addRawTrivyOutputFlags(reportCmd)
+	addFailFastFlags(reportCmd)
+	addScanPriorityFlags(reportCmd)
+	addReadinessScoreFlags(reportCmd)
+	addCheckPluginFlags(reportCmd)
+	addCronJobFlags(reportCmd)
 }
 
 // FullReport - FullReport
 type FullReport struct {
 	ImageScan *scanner.VulnerabilityReport
 	LinuxCIS  *linuxbench.LinuxReport
-	CisScan   *scanner.CisOutput
+	CisScan   *scanner.CombinedComplianceReport
+	Checks    *checks.ReadinessReport
+	// ReadinessScores are the readiness scores of the teams combining the image scan and the readiness checks
+	ReadinessScores []checks.TeamReadinessScore `json:",omitempty"`
+}
+
+// MarshalJSON adds the schema version to the json report so that older reports can be upgraded when read
+func (f FullReport) MarshalJSON() ([]byte, error) {
+	type fullReport FullReport
+	return json.Marshal(&struct {
+		SchemaVersion int `json:"schemaVersion"`
+		fullReport
+	}{reportschema.CurrentVersion, fullReport(f)})
 }
 
 func report(cmd *cobra.Command, str []string) {
+	validateMaxScanErrorRate()
+	validateCIAnnotationFlags()
+	validateReportSigningFlags()
+	validateSeverityFloorFlags()
+	ctx, cancel := interruptContext()
+	defer cancel()
+	defer closeSpilledReport()
 	kubeconfig := k8s.KubernetesConfig(kubeContext, kubeconfigPath)
 	clientset := k8s.KubernetesClientset(kubeconfig)
 
 	config := &scanner.Config{
-		LogLevel:             logLevel,
-		Workers:              scanWorkers,
-		ImageNameReplacement: imageNameReplacement,
-		AreaLabels:           areaLabel,
-		TeamsLabels:          teamLabels,
-		FilterLabels:         filterLabels,
-		Severity:             severity,
-		ScanImageTimeout:     scanTimeout,
+		LogLevel:               logLevel,
+		Workers:                workers(),
+		ImageNameReplacement:   imageNameReplacement,
+		AreaLabels:             areaLabel,
+		TeamsLabels:            teamLabels,
+		GroupBy:                groupByMode(),
+		ScoringMode:            scoringMode(),
+		Ownership:              ownershipMapping(),
+		CheckpointFile:         checkpointFile,
+		Resume:                 resume,
+		SpillDir:               spillDir,
+		ScratchDir:             scratchDir,
+		ScratchDirMaxSize:      scratchDirMaxSizeBytes(),
+		MaxResultAge:           maxResultAge,
+		NodeName:               scannerNodeName(),
+		FilterLabels:           namespaceFilterLabels(),
+		Severity:               severity,
+		ScanImageTimeout:       scanTimeout,
+		ClusterName:            k8s.ClusterName(kubeContext, kubeconfigPath),
+		Tracer:                 newTracer(),
+		Retries:                retries,
+		RetryBackoff:           retryBackoff,
+		RegistryPullsPerMinute: registryPullsPerMinute(),
+		RegistryWorkers:        registryWorkers(),
+		MaxImageSize:           maxImageSizeBytes(),
+		ScanOversizedImages:    scanOversizedImages,
+		OversizedImageWorkers:  oversizedImageWorkers,
+		WeightScansBySize:      autoScanWorkers,
+		ScanSecrets:            scanSecrets,
+		ScanLicenses:           scanLicenses,
+		LicensePolicy:          licensePolicy(),
+		MinCVSSScore:           minCVSSScore,
+		IgnoreUnfixed:          ignoreUnfixed,
+		SortByCVSS:             sortByCVSS,
+		KEVCatalog:             loadKEVCatalog(),
+		EPSSDataset:            loadEPSSDataset(),
+		Advisories:             advisoryDatabase(),
+		SeverityOverrides:      severityOverrides(),
+		UnknownSeverity:        unknownSeverity(),
+		RegistryScans:          harborScans(),
+		SBOMDir:                sbomDir(),
+		SBOMVulnerabilities:    sbomVulnerabilities,
+		SeverityBudgets:        severityBudgets(),
+		TrivyPath:              trivyPath(),
+		TrivyExtraArgs:         strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs:    strings.Fields(trivyImageExtraArgs),
+		TrivySBOMExtraArgs:     strings.Fields(trivySBOMExtraArgs),
+		TrivyCisExtraArgs:      strings.Fields(trivyCisExtraArgs),
+		TrivyDB:                trivyDB(),
+		TrivySharedCache:       trivySharedCache,
+		RawTrivyOutputDir:      rawTrivyOutputDir,
+		FailFast:               failurePolicy(),
+		ScanPriority:           scanPriority(),
+		Platforms:              platforms(),
+		ContainerRuntime:       containerRuntime(),
+		KeepImages:             keepImages,
+		InsecureRegistries:     insecureRegistries,
+		RegistryCredentials:    registryCredentialSource(),
 	}
 
-	t := scanner.New(k8s.NewKubernetesClientWith(clientset), config)
-	imageScanReport, err := t.ScanImages()
+	kubernetesClient := k8s.NewKubernetesClientWithDiscovery(kubeContext, kubeconfigPath, discoveryOptions())
+	start := time.Now()
+	imageScanReport, err := scanClusterImages(ctx, kubernetesClient, config)
+	keepSpilledResults(imageScanReport)
+	shutdownTracer(config.Tracer)
+	scanErr := err
 	if err != nil {
 		logr.Errorf("Error scanning images with config %v: %v", config, err)
 	}
 
-	cisScan(cmd, str)
+	var (
+		checksReport *checks.ReadinessReport
+		linuxReport  *linuxbench.LinuxReport
+	)
+	if ctx.Err() != nil || imageScanReport != nil && imageScanReport.Metadata.FailFastFinding != "" {
+		logr.Warnf("Scan interrupted, skipping the readiness checks and the compliance scans")
+	} else {
+		checksReport, err = runChecks(kubernetesClient, imageScanReport)
+		if err != nil {
+			logr.Errorf("Error running readiness checks: %v", err)
+		}
 
-	l := linuxbench.New(kubeconfig, clientset)
+		cisScan(cmd, str)
 
-	configLinux := &linuxbench.Config{
-		LogLevel: logLevel,
-		Workers:  workersLinuxBench,
-		Template: "linux-bench-node.yaml.tmpl",
-	}
+		l := linuxbench.New(kubeconfig, clientset)
 
-	linuxReport, err := l.Run(configLinux)
-	if err != nil {
-		logr.Errorf("Error scanning images with config %v: %v", configLinux, err)
+		configLinux := &linuxbench.Config{
+			LogLevel: logLevel,
+			Workers:  workersLinuxBench,
+			Template: "linux-bench-node.yaml.tmpl",
+		}
+
+		linuxReport, err = l.Run(configLinux)
+		if err != nil {
+			logr.Errorf("Error scanning images with config %v: %v", configLinux, err)
+		}
+		logr.Infof("linuxReport %v, %v", linuxReport, err)
 	}
-	logr.Infof("linuxReport %v, %v", linuxReport, err)
 
+	trackVulnerabilityAges(imageScanReport)
+	applyTriage(imageScanReport)
 	fullReport := &FullReport{
-		ImageScan: imageScanReport,
+		ImageScan: imageScanReport.WithMinSeverity(minSeverity("report-min-severity", reportMinSeverity)),
 		LinuxCIS:  linuxReport,
+		Checks:    checksReport,
 	}
+	fullReport.ReadinessScores = readinessScores(fullReport.ImageScan, checksReport)
 	err = r.GenerateReportFromTemplate(fullReport, "templates/report-linuxCIS.html.tmpl", reportDir, "report-linuxCIS.html")
+	if err == nil {
+		convertToPDF("report-linuxCIS.html")
+	}
 
-	err = r.GenerateReportFromTemplate(fullReport, reportTemplate, reportDir, reportFile)
+	err = r.GenerateReport(fullReport, reportTemplate, r.Engine(reportTemplateEngine), reportDir, reportFile)
 	if err != nil {
 		logr.Error(err)
+	} else {
+		convertHTMLReportToPDF(reportFile)
 	}
 
 	if jsonReportFile != "" {
@@ -101,4 +268,33 @@ func report(cmd *cobra.Command, str []string) {
 			logr.Error(err)
 		}
 	}
+	saveGitLabReport(fullReport.ImageScan)
+	saveOCSFFindings(fullReport.ImageScan)
+	saveCycloneDXReport(fullReport.ImageScan)
+	saveMarkdownReport(fullReport.ImageScan)
+	saveInventory(fullReport.ImageScan)
+	saveHeatmap(fullReport.ImageScan)
+	signReportFiles(append(renderedReportFiles(reportFile), jsonReportFile, gitlabReportFile, ocsfReportFile, cycloneDXReportFile, markdownReportFile, inventoryFile, heatmapFile)...)
+	writeQuietReport(fullReport)
+	printSummaryTable(imageScanReport)
+	writeScoreboard(imageScanReport)
+	writeFixLatencies(imageScanReport)
+	writeReadinessScores(fullReport.ReadinessScores)
+	recordJobEvent(imageScanReport, time.Since(start), scanErr)
+
+	exitIfInterrupted(ctx)
+	exitIfFailedFast(imageScanReport)
+	baseline := loadBaselineReport()
+	sendNotifications(imageScanReport, baseline)
+	createJiraTickets(imageScanReport, baseline)
+	syncGitHubIssues(imageScanReport, baseline)
+	deliverWebhook(fullReport, imageScanReport)
+	exportToDefectDojo(imageScanReport)
+	exportToDependencyTrack(imageScanReport)
+	writeCIAnnotations(imageScanReport)
+	exitIfKnownExploited(imageScanReport)
+	exitIfBudgetExceeded(imageScanReport)
+	exitIfSeverityFound(imageScanReport)
+	exitIfMissingProvenance(checksReport)
+	exitIfScanErrorRateExceeded(imageScanReport)
 }
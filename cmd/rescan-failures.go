@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/reportschema"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rescanFailuresCmd = &cobra.Command{
+		Use:   "rescan-failures <report.json>",
+		Short: "Will scan again the images whose scan failed in a json report and merge their new results into the report, instead of scanning the whole cluster again",
+		Args:  cobra.ExactArgs(1),
+		Run:   rescanFailures,
+	}
+	rescanInPlace bool
+)
+
+func init() {
+	rootCmd.AddCommand(rescanFailuresCmd)
+	rescanFailuresCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
+	rescanFailuresCmd.Flags().StringVar(&reportTemplate, "report-input-template", "templates/report-imageScan.html.tmpl", "input filename that will be used as report template")
+	rescanFailuresCmd.Flags().StringVar(&reportTemplateEngine, "report-template-engine", string(r.HTMLEngine), "Go template package used to execute the report template, 'html' escapes the report data for HTML while 'text' writes it as is")
+	rescanFailuresCmd.Flags().StringVar(&reportFile, "report-output-filename", "report-imageScan.html", "output filename where that will contain the generated report based on the report-template")
+	rescanFailuresCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the merged report will be saved, <report>-rescanned.json next to the report when not specified")
+	rescanFailuresCmd.Flags().BoolVar(&rescanInPlace, "in-place", false, "save the merged report over the report given as argument instead of a separate file")
+	rescanFailuresCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for each container image scan")
+	rescanFailuresCmd.Flags().IntVar(&scanWorkers, "scan-workers", 10, "number of worker to process images scan in parallel")
+	addTracingFlags(rescanFailuresCmd)
+	addRetryFlags(rescanFailuresCmd)
+	addTrivyFlags(rescanFailuresCmd)
+	addNetworkFlags(rescanFailuresCmd)
+	addRegistryCredentialsFlags(rescanFailuresCmd)
+	addTrivyArgsFlags(rescanFailuresCmd)
+	addTrivySharedCacheFlags(rescanFailuresCmd)
+	addRateLimitFlags(rescanFailuresCmd)
+	addContainerRuntimeFlags(rescanFailuresCmd)
+	addSecretFlags(rescanFailuresCmd)
+	addLicenseFlags(rescanFailuresCmd)
+	addCVSSFlags(rescanFailuresCmd)
+	addIgnoreUnfixedFlags(rescanFailuresCmd)
+	addKEVFlags(rescanFailuresCmd)
+	addEPSSFlags(rescanFailuresCmd)
+	addAdvisoryFlags(rescanFailuresCmd)
+	addSeverityOverrideFlags(rescanFailuresCmd)
+	addScoringFlags(rescanFailuresCmd)
+	addFindingsStateFlags(rescanFailuresCmd)
+	addScanErrorFlags(rescanFailuresCmd)
+	addScratchFlags(rescanFailuresCmd)
+	addRawTrivyOutputFlags(rescanFailuresCmd)
+}
+
+func rescanFailures(_ *cobra.Command, args []string) {
+	validateMaxScanErrorRate()
+	if rescanInPlace && jsonReportFile != "" {
+		logr.Fatal("--in-place and --report-output-filename-json cannot be used together")
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	fullReport, err := loadFullReport(args[0])
+	if err != nil {
+		logr.Fatal(err)
+	}
+
+	config := &scanner.Config{
+		LogLevel:               logLevel,
+		Workers:                scanWorkers,
+		ScoringMode:            scoringMode(),
+		Severity:               severity,
+		ScanImageTimeout:       scanTimeout,
+		ScratchDir:             scratchDir,
+		ScratchDirMaxSize:      scratchDirMaxSizeBytes(),
+		Tracer:                 newTracer(),
+		Retries:                retries,
+		RetryBackoff:           retryBackoff,
+		RegistryPullsPerMinute: registryPullsPerMinute(),
+		RegistryWorkers:        registryWorkers(),
+		ScanSecrets:            scanSecrets,
+		ScanLicenses:           scanLicenses,
+		LicensePolicy:          licensePolicy(),
+		MinCVSSScore:           minCVSSScore,
+		IgnoreUnfixed:          ignoreUnfixed,
+		SortByCVSS:             sortByCVSS,
+		KEVCatalog:             loadKEVCatalog(),
+		EPSSDataset:            loadEPSSDataset(),
+		Advisories:             advisoryDatabase(),
+		SeverityOverrides:      severityOverrides(),
+		UnknownSeverity:        unknownSeverity(),
+		TrivyPath:              trivyPath(),
+		TrivyExtraArgs:         strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs:    strings.Fields(trivyImageExtraArgs),
+		TrivySBOMExtraArgs:     strings.Fields(trivySBOMExtraArgs),
+		TrivyCisExtraArgs:      strings.Fields(trivyCisExtraArgs),
+		TrivyDB:                trivyDB(),
+		TrivySharedCache:       trivySharedCache,
+		RawTrivyOutputDir:      rawTrivyOutputDir,
+		ContainerRuntime:       containerRuntime(),
+		KeepImages:             keepImages,
+		InsecureRegistries:     insecureRegistries,
+		RegistryCredentials:    registryCredentialSource(),
+	}
+	imageScanReport, err := scanner.New(nil, config).RescanFailures(ctx, fullReport.ImageScan)
+	shutdownTracer(config.Tracer)
+	if err != nil {
+		logr.Fatalf("Error scanning the failed images of report %s: %v", args[0], err)
+	}
+	applyTriage(imageScanReport)
+
+	// the other sections of the report, for instance the readiness checks, are kept as is
+	fullReport.ImageScan = imageScanReport
+	err = r.GenerateReport(fullReport, reportTemplate, r.Engine(reportTemplateEngine), reportDir, reportFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	output := rescanOutput(args[0])
+	if err := r.SaveReport(fullReport, output); err != nil {
+		logr.Fatal(err)
+	}
+	logr.Infof("Report %s merged with the images scanned again into %s", args[0], output)
+	printSummaryTable(imageScanReport)
+	exitIfInterrupted(ctx)
+	exitIfScanErrorRateExceeded(imageScanReport)
+}
+
+// rescanOutput returns the file the merged report is saved to: the report itself with --in-place, otherwise the
+// --report-output-filename-json file or <report>-rescanned.json next to the report, so that the report is kept
+func rescanOutput(report string) string {
+	if rescanInPlace {
+		return report
+	}
+	if jsonReportFile != "" {
+		return jsonReportFile
+	}
+	return strings.TrimSuffix(report, filepath.Ext(report)) + "-rescanned.json"
+}
+
+// loadFullReport reads all the sections of a json report previously saved with the report-output-filename-json option,
+// upgraded to the current report schema version
+func loadFullReport(filename string) (*FullReport, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read report file %s: %v", filename, err)
+	}
+	content, err = reportschema.Upgrade(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not upgrade report file %s: %v", filename, err)
+	}
+	var fullReport FullReport
+	if err := json.Unmarshal(content, &fullReport); err != nil {
+		return nil, fmt.Errorf("error while decoding report file %s: %v", filename, err)
+	}
+	if fullReport.ImageScan == nil {
+		return nil, fmt.Errorf("report file %s does not contain an image scan", filename)
+	}
+	return &fullReport, nil
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var maxResultAge time.Duration
+
+func addResultAgeFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&maxResultAge, "max-result-age", 0, "maximum age of the scan results of an image, for instance 72h. Older results are flagged as stale in the report, and rescanned when read from --checkpoint-file, the --watch report or the registry. Results never expire when 0")
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	retries      int
+	retryBackoff time.Duration
+)
+
+func addRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&retries, "retries", 2, "number of times a failing image pull or scan is retried before reporting the scan error, for instance when throttled by a registry")
+	cmd.Flags().DurationVar(&retryBackoff, "retry-backoff", 5*time.Second, "delay before retrying a failing image pull or scan, doubled after each retry and randomised to spread the retries")
+}
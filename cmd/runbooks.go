@@ -0,0 +1,25 @@
+package main
+
+import (
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var reportRunbooksFile string
+
+func addReportRunbooksFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&reportRunbooksFile, "report-runbooks", "", "yaml file mapping the check names, the compliance control ids and classes of vulnerabilities to the URLs of internal runbooks, linked from the HTML and Markdown reports for remediation guidance")
+}
+
+// applyReportRunbooks sets the runbooks of the reports when a runbooks file is specified
+func applyReportRunbooks() {
+	if reportRunbooksFile == "" {
+		return
+	}
+	runbooks, err := r.LoadReportRunbooks(reportRunbooksFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	r.SetReportRunbooks(runbooks)
+}
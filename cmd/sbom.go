@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// defaultSBOMDir receives the SBOMs uploaded to Dependency-Track when no SBOM directory is specified
+const defaultSBOMDir = ".sbom"
+
+var (
+	sbomDirectory       string
+	sbomVulnerabilities bool
+)
+
+func addSBOMFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sbomDirectory, "sbom-dir", "", "directory the CycloneDX SBOM of each pulled and scanned image is written to. No SBOM is generated unless this option or --dependency-track-url is specified, the SBOMs being written to "+defaultSBOMDir+" with the latter")
+	cmd.Flags().BoolVar(&sbomVulnerabilities, "sbom-vulnerabilities", false, "also record the vulnerabilities trivy found in the components of the SBOMs")
+}
+
+// sbomDir returns the directory the SBOMs are written to, empty when no SBOM is generated
+func sbomDir() string {
+	if sbomDirectory == "" && dependencyTrackURL != "" {
+		return defaultSBOMDir
+	}
+	return sbomDirectory
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanImageCmd = &cobra.Command{
+		Use:   "scan-image <ref>",
+		Short: "Will scan a single docker image outside of any cluster, for instance a locally built image before pushing it",
+		Args:  cobra.ExactArgs(1),
+		Run:   scanImage,
+	}
+	noReportFiles bool
+)
+
+func init() {
+	rootCmd.AddCommand(scanImageCmd)
+	scanImageCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
+	scanImageCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for the image scan")
+	scanImageCmd.Flags().BoolVar(&noReportFiles, "no-report-files", false, "only print the vulnerabilities without generating report-imageScan.html and report-imageScan.md")
+	scanImageCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	addGitLabFlags(scanImageCmd)
+	addOCSFFlags(scanImageCmd)
+	addCycloneDXFlags(scanImageCmd)
+	addMarkdownFlags(scanImageCmd)
+	addCIAnnotationFlags(scanImageCmd)
+	addReportSigningFlags(scanImageCmd)
+	addTracingFlags(scanImageCmd)
+	addRetryFlags(scanImageCmd)
+	addTrivyFlags(scanImageCmd)
+	addNetworkFlags(scanImageCmd)
+	addRegistryCredentialsFlags(scanImageCmd)
+	addTrivyArgsFlags(scanImageCmd)
+	addSecretFlags(scanImageCmd)
+	addLicenseFlags(scanImageCmd)
+	addCVSSFlags(scanImageCmd)
+	addIgnoreUnfixedFlags(scanImageCmd)
+	addFixableOnlyFlags(scanImageCmd)
+	addKEVFlags(scanImageCmd)
+	addEPSSFlags(scanImageCmd)
+	addAdvisoryFlags(scanImageCmd)
+	addSeverityOverrideFlags(scanImageCmd)
+	addRawTrivyOutputFlags(scanImageCmd)
+}
+
+func scanImage(_ *cobra.Command, args []string) {
+	validateCIAnnotationFlags()
+	validateReportSigningFlags()
+	ctx, cancel := interruptContext()
+	defer cancel()
+	config := &scanner.Config{
+		LogLevel:            logLevel,
+		Severity:            severity,
+		ScanImageTimeout:    scanTimeout,
+		Tracer:              newTracer(),
+		Retries:             retries,
+		RetryBackoff:        retryBackoff,
+		ScanSecrets:         scanSecrets,
+		ScanLicenses:        scanLicenses,
+		LicensePolicy:       licensePolicy(),
+		MinCVSSScore:        minCVSSScore,
+		IgnoreUnfixed:       ignoreUnfixed,
+		SortByCVSS:          sortByCVSS,
+		KEVCatalog:          loadKEVCatalog(),
+		EPSSDataset:         loadEPSSDataset(),
+		Advisories:          advisoryDatabase(),
+		SeverityOverrides:   severityOverrides(),
+		UnknownSeverity:     unknownSeverity(),
+		TrivyPath:           trivyPath(),
+		TrivyExtraArgs:      strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs: strings.Fields(trivyImageExtraArgs),
+		TrivyDB:             trivyDB(),
+		TrivySBOMExtraArgs:  strings.Fields(trivySBOMExtraArgs),
+		InsecureRegistries:  insecureRegistries,
+		RegistryCredentials: registryCredentialSource(),
+		RawTrivyOutputDir:   rawTrivyOutputDir,
+	}
+	imageScanReport, err := scanner.New(nil, config).ScanImage(ctx, args[0])
+	shutdownTracer(config.Tracer)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	if !quiet {
+		printScannedImages(imageScanReport.ScannedImages)
+	}
+
+	fullReport := &FullReport{
+		ImageScan: imageScanReport,
+	}
+	if !noReportFiles {
+		err = r.GenerateReportFromTemplate(fullReport, "templates/report-imageScan.html.tmpl", reportDir, "report-imageScan.html")
+		if err != nil {
+			logr.Fatal(err)
+		}
+		err = r.GenerateReportFromTemplate(fullReport, "templates/report-imageScan.md.tmpl", reportDir, "report-imageScan.md")
+		if err != nil {
+			logr.Fatal(err)
+		}
+		signReportFiles(reportDir+"report-imageScan.html", reportDir+"report-imageScan.md")
+	}
+
+	if jsonReportFile != "" {
+		err = r.SaveReport(fullReport, jsonReportFile)
+		if err != nil {
+			logr.Fatal(err)
+		}
+	}
+	saveGitLabReport(imageScanReport)
+	saveOCSFFindings(imageScanReport)
+	saveCycloneDXReport(imageScanReport)
+	saveMarkdownReport(imageScanReport)
+	signReportFiles(jsonReportFile, gitlabReportFile, ocsfReportFile, cycloneDXReportFile, markdownReportFile)
+	writeQuietReport(fullReport)
+	writeCIAnnotations(imageScanReport)
+	exitIfKnownExploited(imageScanReport)
+}
+
+// printScannedImages prints the vulnerability count per severity, the known exploited vulnerability count, the end-of-life
+// operating system, the vulnerabilities, the secrets and the license violations of the images
+func printScannedImages(scannedImages []scanner.ScannedImage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	for _, image := range scannedImages {
+		summary := image.VulnerabilitySummary.TotalVulnerabilityBySeverity
+		fmt.Fprintf(w, "%s\tCRITICAL: %d\tHIGH: %d\tMEDIUM: %d\tLOW: %d\tUNKNOWN: %d\n", image.ImageName,
+			summary["CRITICAL"], summary["HIGH"], summary["MEDIUM"], summary["LOW"], summary["UNKNOWN"])
+		if image.VulnerabilitySummary.KnownExploitedCount > 0 {
+			fmt.Fprintf(w, "KNOWN EXPLOITED\t%d vulnerabilities exploited in the wild\n", image.VulnerabilitySummary.KnownExploitedCount)
+		}
+		if image.EndOfLife() {
+			fmt.Fprintf(w, "END OF LIFE OS\t%s %s\n", image.OS.Family, image.OS.Name)
+		}
+		for _, result := range image.Results() {
+			for _, vulnerability := range result.Vulnerabilities {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", vulnerability.VulnerabilityID, vulnerability.Severity,
+					vulnerability.PkgName, vulnerability.InstalledVersion, vulnerability.FixedVersion)
+			}
+			for _, secret := range result.Secrets {
+				fmt.Fprintf(w, "%s\t%s\t%s:%d\t%s\n", secret.RuleID, secret.Severity, result.Target, secret.StartLine, secret.Title)
+			}
+			for _, license := range result.Licenses {
+				if license.Violation != "" {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", license.Name, license.Severity, license.PkgName, license.Violation)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/manifest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanManifestsCmd = &cobra.Command{
+		Use:   "scan-manifests <path>",
+		Short: "Will scan the images and run readiness checks against the workloads of manifests or a Helm chart before they are deployed",
+		Args:  cobra.ExactArgs(1),
+		Run:   scanManifests,
+	}
+	manifestsNamespace string
+	helmReleaseName    string
+	helmValues         []string
+)
+
+func init() {
+	rootCmd.AddCommand(scanManifestsCmd)
+	scanManifestsCmd.Flags().StringVar(&manifestsNamespace, "namespace", "default", "namespace of the resources defined without namespace")
+	scanManifestsCmd.Flags().StringVar(&helmReleaseName, "helm-release-name", "release", "release name used to render the Helm chart")
+	scanManifestsCmd.Flags().StringSliceVar(&helmValues, "helm-values", nil, "values files used to render the Helm chart")
+	scanManifestsCmd.Flags().StringVar(&imageNameReplacement, "image-name-replacement", "", "string replacement to replace name into the image name for ex: registry url, format: 'registry-mirror:5000|registry.com,registry-second:5000|registry-second.com' list separated by comma, matching and replacement string are seperated by a pipe '|'")
+	scanManifestsCmd.Flags().StringVar(&areaLabel, "area-labels", "", "comma-separated labels allowing to split per area the image scan and findings, taken from the pod templates or else from the Namespace manifests")
+	scanManifestsCmd.Flags().StringVar(&teamLabels, "teams-labels", "", "comma-separated labels allowing to split per team the image scan and findings, taken from the pod templates or else from the Namespace manifests")
+	scanManifestsCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
+	scanManifestsCmd.Flags().StringSliceVar(&selectedChecks, "checks", defaultCheckNames(), "List of readiness checks to run. If not specified all are run")
+	addSignatureFlags(scanManifestsCmd)
+	addProvenanceFlags(scanManifestsCmd)
+	addApprovedRegistryFlags(scanManifestsCmd)
+	addWorkloadRiskFlags(scanManifestsCmd)
+	addDigestAllowlistFlags(scanManifestsCmd)
+	addImageStalenessFlags(scanManifestsCmd)
+	scanManifestsCmd.Flags().StringVar(&targetKubernetesVersion, "target-kubernetes-version", "", "Kubernetes version the deprecated API usage is evaluated against, for instance 1.26. The deprecated-api check is skipped when not specified")
+	scanManifestsCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	addGitLabFlags(scanManifestsCmd)
+	addOCSFFlags(scanManifestsCmd)
+	addCycloneDXFlags(scanManifestsCmd)
+	addMarkdownFlags(scanManifestsCmd)
+	addInventoryFlags(scanManifestsCmd)
+	addHeatmapFlags(scanManifestsCmd)
+	addCIAnnotationFlags(scanManifestsCmd)
+	addReportSigningFlags(scanManifestsCmd)
+	scanManifestsCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for each container image scan")
+	scanManifestsCmd.Flags().IntVar(&scanWorkers, "scan-workers", 10, "number of worker to process images scan in parallel")
+	addRetryFlags(scanManifestsCmd)
+	addTrivyFlags(scanManifestsCmd)
+	addNetworkFlags(scanManifestsCmd)
+	addRegistryCredentialsFlags(scanManifestsCmd)
+	addTrivyArgsFlags(scanManifestsCmd)
+	addTrivySharedCacheFlags(scanManifestsCmd)
+	addRateLimitFlags(scanManifestsCmd)
+	addImageSizeFlags(scanManifestsCmd)
+	addAutoScanWorkersFlags(scanManifestsCmd)
+	addPlatformFlags(scanManifestsCmd)
+	addContainerRuntimeFlags(scanManifestsCmd)
+	addSecretFlags(scanManifestsCmd)
+	addLicenseFlags(scanManifestsCmd)
+	addCVSSFlags(scanManifestsCmd)
+	addIgnoreUnfixedFlags(scanManifestsCmd)
+	addFixableOnlyFlags(scanManifestsCmd)
+	addKEVFlags(scanManifestsCmd)
+	addScanErrorFlags(scanManifestsCmd)
+	addEPSSFlags(scanManifestsCmd)
+	addAdvisoryFlags(scanManifestsCmd)
+	addSeverityOverrideFlags(scanManifestsCmd)
+	addGroupByFlags(scanManifestsCmd)
+	addScoringFlags(scanManifestsCmd)
+	addOwnershipFlags(scanManifestsCmd)
+	addCheckpointFlags(scanManifestsCmd)
+	addSpillFlags(scanManifestsCmd)
+	addScratchFlags(scanManifestsCmd)
+	addResultAgeFlags(scanManifestsCmd)
+	addRawTrivyOutputFlags(scanManifestsCmd)
+	addFailOnSeverityFlags(scanManifestsCmd)
+	addFailFastFlags(scanManifestsCmd)
+	addCheckPluginFlags(scanManifestsCmd)
+}
+
+func scanManifests(_ *cobra.Command, args []string) {
+	validateMaxScanErrorRate()
+	minSeverity("fail-on-severity", failOnSeverity)
+	validateCIAnnotationFlags()
+	validateReportSigningFlags()
+	ctx, cancel := interruptContext()
+	defer cancel()
+	defer closeSpilledReport()
+	manifests, err := manifest.Load(&manifest.Config{
+		Path:             args[0],
+		DefaultNamespace: manifestsNamespace,
+		HelmReleaseName:  helmReleaseName,
+		HelmValues:       helmValues,
+	})
+	if err != nil {
+		logr.Fatalf("Error loading manifests from %s: %v", args[0], err)
+	}
+
+	config := &scanner.Config{
+		LogLevel:               logLevel,
+		Workers:                workers(),
+		ImageNameReplacement:   imageNameReplacement,
+		AreaLabels:             areaLabel,
+		TeamsLabels:            teamLabels,
+		GroupBy:                groupByMode(),
+		ScoringMode:            scoringMode(),
+		Ownership:              ownershipMapping(),
+		CheckpointFile:         checkpointFile,
+		Resume:                 resume,
+		SpillDir:               spillDir,
+		ScratchDir:             scratchDir,
+		ScratchDirMaxSize:      scratchDirMaxSizeBytes(),
+		MaxResultAge:           maxResultAge,
+		Severity:               severity,
+		ScanImageTimeout:       scanTimeout,
+		Retries:                retries,
+		RetryBackoff:           retryBackoff,
+		RegistryPullsPerMinute: registryPullsPerMinute(),
+		RegistryWorkers:        registryWorkers(),
+		MaxImageSize:           maxImageSizeBytes(),
+		ScanOversizedImages:    scanOversizedImages,
+		OversizedImageWorkers:  oversizedImageWorkers,
+		WeightScansBySize:      autoScanWorkers,
+		ScanSecrets:            scanSecrets,
+		ScanLicenses:           scanLicenses,
+		LicensePolicy:          licensePolicy(),
+		MinCVSSScore:           minCVSSScore,
+		IgnoreUnfixed:          ignoreUnfixed,
+		SortByCVSS:             sortByCVSS,
+		KEVCatalog:             loadKEVCatalog(),
+		EPSSDataset:            loadEPSSDataset(),
+		Advisories:             advisoryDatabase(),
+		SeverityOverrides:      severityOverrides(),
+		UnknownSeverity:        unknownSeverity(),
+		TrivyPath:              trivyPath(),
+		TrivyExtraArgs:         strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs:    strings.Fields(trivyImageExtraArgs),
+		TrivySBOMExtraArgs:     strings.Fields(trivySBOMExtraArgs),
+		TrivyCisExtraArgs:      strings.Fields(trivyCisExtraArgs),
+		TrivyDB:                trivyDB(),
+		TrivySharedCache:       trivySharedCache,
+		RawTrivyOutputDir:      rawTrivyOutputDir,
+		FailFast:               failurePolicy(),
+		Platforms:              platforms(),
+		ContainerRuntime:       containerRuntime(),
+		KeepImages:             keepImages,
+		InsecureRegistries:     insecureRegistries,
+		RegistryCredentials:    registryCredentialSource(),
+	}
+	imageScanReport, err := scanner.New(manifests, config).ScanImages(ctx)
+	keepSpilledResults(imageScanReport)
+	if err != nil {
+		logr.Fatalf("Error scanning images with config %v: %v", config, err)
+	}
+
+	if targetKubernetesVersion == "" {
+		// manifests are not bound to a cluster the deprecated API usage could be evaluated against
+		selectedChecks = withoutCheck(selectedChecks, checks.DeprecatedAPICheckName, "no target Kubernetes version is specified")
+	}
+	selectedChecks = withoutCheck(selectedChecks, checks.MisconfigurationCheckName, "it scans the live cluster resources")
+	selectedChecks = withoutCheck(selectedChecks, checks.ConfigAuditCheckName, "it reads the Trivy Operator reports of the live cluster resources")
+	checksReport, err := runChecks(manifests, imageScanReport)
+	if err != nil {
+		logr.Fatal(err)
+	}
+
+	fullReport := &FullReport{
+		ImageScan: imageScanReport,
+		Checks:    checksReport,
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-imageScan.html.tmpl", reportDir, "report-imageScan.html")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-imageScan.md.tmpl", reportDir, "report-imageScan.md")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-checks.html.tmpl", reportDir, "report-checks.html")
+	if err != nil {
+		logr.Fatal(err)
+	}
+	err = r.GenerateReportFromTemplate(fullReport, "templates/report-checks.md.tmpl", reportDir, "report-checks.md")
+	if err != nil {
+		logr.Fatal(err)
+	}
+
+	if jsonReportFile != "" {
+		err = r.SaveReport(fullReport, jsonReportFile)
+		if err != nil {
+			logr.Fatal(err)
+		}
+	}
+	saveGitLabReport(imageScanReport)
+	saveOCSFFindings(imageScanReport)
+	saveCycloneDXReport(imageScanReport)
+	saveMarkdownReport(imageScanReport)
+	saveInventory(imageScanReport)
+	saveHeatmap(imageScanReport)
+	signReportFiles(reportDir+"report-imageScan.html", reportDir+"report-imageScan.md", reportDir+"report-checks.html", reportDir+"report-checks.md", jsonReportFile, gitlabReportFile, ocsfReportFile, cycloneDXReportFile, markdownReportFile, inventoryFile, heatmapFile)
+	writeQuietReport(fullReport)
+	exitIfInterrupted(ctx)
+	exitIfFailedFast(imageScanReport)
+	writeCIAnnotations(imageScanReport)
+	exitIfKnownExploited(imageScanReport)
+	exitIfSeverityFound(imageScanReport)
+	exitIfMissingProvenance(checksReport)
+	exitIfScanErrorRateExceeded(imageScanReport)
+}
+
+func withoutCheck(names []string, name, reason string) []string {
+	var result []string
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	if len(result) != len(names) {
+		logr.Warnf("Skipping %s check as %s", name, reason)
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/registry"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanRegistryCmd = &cobra.Command{
+		Use:   "scan-registry <registry-url>",
+		Short: "Will scan the tagged images listed from the catalog of a registry rather than the images deployed to a cluster, for instance https://registry.example.com",
+		Args:  cobra.ExactArgs(1),
+		Run:   scanRegistry,
+	}
+	registryIncludes []string
+	registryUsername string
+)
+
+func init() {
+	rootCmd.AddCommand(scanRegistryCmd)
+	scanRegistryCmd.Flags().StringSliceVar(&registryIncludes, "include", nil, "patterns of the repositories, optionally followed by a tag pattern, whose images are scanned, for instance 'payments/*,platform/ingress:v1.*'. All the tagged images of the catalog are scanned unless this option is specified")
+	scanRegistryCmd.Flags().StringVar(&registryUsername, "registry-username", "", "user or robot account used to read the catalog of the registry, anonymous when not specified. The password is read from the REGISTRY_PASSWORD environment variable")
+	scanRegistryCmd.Flags().IntVar(&scanWorkers, "scan-workers", 10, "number of worker to process images scan in parallel")
+	scanRegistryCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
+	scanRegistryCmd.Flags().StringVar(&reportTemplate, "report-input-template", "templates/report-imageScan.html.tmpl", "input filename that will be used as report template")
+	scanRegistryCmd.Flags().StringVar(&reportTemplateEngine, "report-template-engine", string(r.HTMLEngine), "Go template package used to execute the report template, 'html' escapes the report data for HTML while 'text' writes it as is")
+	scanRegistryCmd.Flags().StringVar(&reportFile, "report-output-filename", "report-imageScan.html", "output filename where that will contain the generated report based on the report-template")
+	scanRegistryCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	scanRegistryCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for each container image scan")
+	addTracingFlags(scanRegistryCmd)
+	addRetryFlags(scanRegistryCmd)
+	addTrivyFlags(scanRegistryCmd)
+	addNetworkFlags(scanRegistryCmd)
+	addRegistryCredentialsFlags(scanRegistryCmd)
+	addTrivyArgsFlags(scanRegistryCmd)
+	addTrivySharedCacheFlags(scanRegistryCmd)
+	addRateLimitFlags(scanRegistryCmd)
+	addContainerRuntimeFlags(scanRegistryCmd)
+	addSecretFlags(scanRegistryCmd)
+	addLicenseFlags(scanRegistryCmd)
+	addCVSSFlags(scanRegistryCmd)
+	addIgnoreUnfixedFlags(scanRegistryCmd)
+	addFixableOnlyFlags(scanRegistryCmd)
+	addKEVFlags(scanRegistryCmd)
+	addEPSSFlags(scanRegistryCmd)
+	addAdvisoryFlags(scanRegistryCmd)
+	addSeverityOverrideFlags(scanRegistryCmd)
+	addScoringFlags(scanRegistryCmd)
+	addScanErrorFlags(scanRegistryCmd)
+	addCheckpointFlags(scanRegistryCmd)
+	addSpillFlags(scanRegistryCmd)
+	addScratchFlags(scanRegistryCmd)
+	addRawTrivyOutputFlags(scanRegistryCmd)
+	addFailOnSeverityFlags(scanRegistryCmd)
+	addFailFastFlags(scanRegistryCmd)
+}
+
+func scanRegistry(_ *cobra.Command, args []string) {
+	validateMaxScanErrorRate()
+	minSeverity("fail-on-severity", failOnSeverity)
+	if err := registry.ValidatePatterns(registryIncludes); err != nil {
+		logr.Fatal(err)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	defer closeSpilledReport()
+	client, err := registry.NewClient(args[0], registryUsername, os.Getenv("REGISTRY_PASSWORD"))
+	if err != nil {
+		logr.Fatal(err)
+	}
+	images, err := client.Images(registryIncludes)
+	if err != nil {
+		logr.Fatalf("Error listing the images of registry %s: %v", args[0], err)
+	}
+	logr.Infof("Found %d images matching %v in registry %s", len(images), registryIncludes, client.Host())
+
+	config := &scanner.Config{
+		LogLevel:               logLevel,
+		Workers:                scanWorkers,
+		ScoringMode:            scoringMode(),
+		CheckpointFile:         checkpointFile,
+		Resume:                 resume,
+		SpillDir:               spillDir,
+		ScratchDir:             scratchDir,
+		ScratchDirMaxSize:      scratchDirMaxSizeBytes(),
+		Severity:               severity,
+		ScanImageTimeout:       scanTimeout,
+		Tracer:                 newTracer(),
+		Retries:                retries,
+		RetryBackoff:           retryBackoff,
+		RegistryPullsPerMinute: registryPullsPerMinute(),
+		RegistryWorkers:        registryWorkers(),
+		ScanSecrets:            scanSecrets,
+		ScanLicenses:           scanLicenses,
+		LicensePolicy:          licensePolicy(),
+		MinCVSSScore:           minCVSSScore,
+		IgnoreUnfixed:          ignoreUnfixed,
+		SortByCVSS:             sortByCVSS,
+		KEVCatalog:             loadKEVCatalog(),
+		EPSSDataset:            loadEPSSDataset(),
+		Advisories:             advisoryDatabase(),
+		SeverityOverrides:      severityOverrides(),
+		UnknownSeverity:        unknownSeverity(),
+		TrivyPath:              trivyPath(),
+		TrivyExtraArgs:         strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs:    strings.Fields(trivyImageExtraArgs),
+		TrivySBOMExtraArgs:     strings.Fields(trivySBOMExtraArgs),
+		TrivyCisExtraArgs:      strings.Fields(trivyCisExtraArgs),
+		TrivyDB:                trivyDB(),
+		TrivySharedCache:       trivySharedCache,
+		RawTrivyOutputDir:      rawTrivyOutputDir,
+		FailFast:               failurePolicy(),
+		ContainerRuntime:       containerRuntime(),
+		KeepImages:             keepImages,
+		InsecureRegistries:     insecureRegistries,
+		RegistryCredentials:    registryCredentialSource(),
+	}
+	imageScanReport, err := scanner.New(nil, config).ScanRegistryImages(ctx, images)
+	keepSpilledResults(imageScanReport)
+	shutdownTracer(config.Tracer)
+	if err != nil {
+		logr.Fatalf("Error scanning the images of registry %s: %v", args[0], err)
+	}
+	applyTriage(imageScanReport)
+	writeImageScanReports(imageScanReport)
+	printSummaryTable(imageScanReport)
+	exitIfInterrupted(ctx)
+	exitIfFailedFast(imageScanReport)
+	exitIfKnownExploited(imageScanReport)
+	exitIfSeverityFound(imageScanReport)
+	exitIfScanErrorRateExceeded(imageScanReport)
+}
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
@@ -16,49 +22,310 @@ var (
 		Short: "Will gather all the docker images available in a cluster and scan the image to check vulnerabilities",
 		Run:   scan,
 	}
+	imageList     string
+	reportPerTeam bool
+	streamOutput  string
 )
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
 	scanCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "kubeconfig file to use if connecting from outside a cluster")
-	scanCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use if connecting from outside a cluster")
+	scanCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use, the current context of the kubeconfig when not specified")
+	addNamespaceFlags(scanCmd)
 	scanCmd.Flags().StringVar(&imageNameReplacement, "image-name-replacement", "", "string replacement to replace name into the image name for ex: registry url, format: 'registry-mirror:5000|registry.com,registry-second:5000|registry-second.com' list separated by comma, matching and replacement string are seperated by a pipe '|'")
-	scanCmd.Flags().StringVar(&areaLabel, "area-labels", "", "string allowing to split per area the image scan")
-	scanCmd.Flags().StringVar(&teamLabels, "teams-labels", "", "string allowing to split per team the image scan")
+	scanCmd.Flags().StringVar(&areaLabel, "area-labels", "", "comma-separated pod or namespace labels allowing to split per area the image scan, the first label set being used")
+	scanCmd.Flags().StringVar(&teamLabels, "teams-labels", "", "comma-separated pod or namespace labels allowing to split per team the image scan, the first label set being used")
 	scanCmd.Flags().StringVar(&filterLabels, "filters-labels", "", "string allowing to filter the namespaces string separated by comma")
 	scanCmd.Flags().StringVar(&severity, "severity", "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL", "severities of vulnerabilities to be reported (comma separated) ")
 	scanCmd.Flags().StringVar(&reportTemplate, "report-input-template", "templates/report-imageScan.html.tmpl", "input filename that will be used as report template")
+	scanCmd.Flags().StringVar(&reportTemplateEngine, "report-template-engine", string(r.HTMLEngine), "Go template package used to execute the report template, 'html' escapes the report data for HTML while 'text' writes it as is")
 	scanCmd.Flags().StringVar(&reportFile, "report-output-filename", "report-imageScan.html", "output filename where that will contain the generated report based on the report-template")
+	addReportDirectoryFlags(scanCmd)
 	scanCmd.Flags().StringVar(&jsonReportFile, "report-output-filename-json", "", "output filename where the json representation of the report will be saved. No json representation will be created unless this option is specified")
+	addGitLabFlags(scanCmd)
+	addOCSFFlags(scanCmd)
+	addCycloneDXFlags(scanCmd)
+	addMarkdownFlags(scanCmd)
+	addInventoryFlags(scanCmd)
+	addHeatmapFlags(scanCmd)
+	addCIAnnotationFlags(scanCmd)
+	addReportSigningFlags(scanCmd)
 	scanCmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "timeout for each container image scan")
 	scanCmd.Flags().IntVar(&scanWorkers, "scan-workers", 10, "number of worker to process images scan in parallel")
+	scanCmd.Flags().BoolVar(&reportPerTeam, "report-per-team", false, "also generate one report per team, named after the report output filenames suffixed by the team name")
+	scanCmd.Flags().StringVar(&streamOutput, "stream-output", "", "file each scanned image is written to as a json line (NDJSON) as soon as its scan finishes, '-' for the standard output")
+	scanCmd.Flags().StringVar(&imageList, "image-list", "", "file listing the images to scan instead of the cluster images, one image per line optionally followed by 'area=<area> team=<team>' annotations")
+	addNotificationFlags(scanCmd)
+	addDefectDojoFlags(scanCmd)
+	addDependencyTrackFlags(scanCmd)
+	addPDFFlags(scanCmd)
+	addTracingFlags(scanCmd)
+	addRetryFlags(scanCmd)
+	addTrivyFlags(scanCmd)
+	addNetworkFlags(scanCmd)
+	addRegistryCredentialsFlags(scanCmd)
+	addTrivyArgsFlags(scanCmd)
+	addTrivySharedCacheFlags(scanCmd)
+	addRateLimitFlags(scanCmd)
+	addImageSizeFlags(scanCmd)
+	addAutoScanWorkersFlags(scanCmd)
+	addPlatformFlags(scanCmd)
+	addContainerRuntimeFlags(scanCmd)
+	addSecretFlags(scanCmd)
+	addLicenseFlags(scanCmd)
+	addCVSSFlags(scanCmd)
+	addIgnoreUnfixedFlags(scanCmd)
+	addFixableOnlyFlags(scanCmd)
+	addKEVFlags(scanCmd)
+	addEPSSFlags(scanCmd)
+	addAdvisoryFlags(scanCmd)
+	addSeverityOverrideFlags(scanCmd)
+	addGroupByFlags(scanCmd)
+	addDiscoveryFlags(scanCmd)
+	addScoringFlags(scanCmd)
+	addOwnershipFlags(scanCmd)
+	addBudgetFlags(scanCmd)
+	addHistoryFlags(scanCmd)
+	addScoreboardFlags(scanCmd)
+	addFindingsStateFlags(scanCmd)
+	addSeverityFloorFlags(scanCmd)
+	addScanErrorFlags(scanCmd)
+	addCheckpointFlags(scanCmd)
+	addSpillFlags(scanCmd)
+	addScratchFlags(scanCmd)
+	addResultAgeFlags(scanCmd)
+	addSinceFlags(scanCmd)
+	addNodePressureFlags(scanCmd)
+	addSourceFlags(scanCmd)
+	addHarborFlags(scanCmd)
+	addSBOMFlags(scanCmd)
+	addRawTrivyOutputFlags(scanCmd)
+	addFailFastFlags(scanCmd)
+	addScanPriorityFlags(scanCmd)
+	addWatchFlags(scanCmd)
+	addScheduleFlags(scanCmd)
+	addGRPCFlags(scanCmd)
+	addRecordFlags(scanCmd)
+	addPushgatewayFlags(scanCmd)
+	addCronJobFlags(scanCmd)
 }
 
 func scan(_ *cobra.Command, _ []string) {
+	if imageList != "" && imageScanSource != sourceTrivy {
+		logr.Fatalf("--image-list images are scanned with trivy, --source %s is not supported", imageScanSource)
+	}
+	if watch && (imageList != "" || imageScanSource != sourceTrivy) {
+		logr.Fatalf("--watch only watches the cluster images scanned with trivy, it cannot be used with --image-list or --source %s", imageScanSource)
+	}
+	if watch && scanSchedule != "" {
+		logr.Fatal("--watch and --schedule cannot be combined, use --full-rescan-interval to rescan the watched cluster")
+	}
+	if grpcPort != 0 && (watch || scanSchedule != "") {
+		logr.Fatal("--grpc-port cannot be combined with --watch or --schedule, the scans being started from the gRPC API")
+	}
+	if grpcPort != 0 && (scanWindows != "" || scanBlackouts != "") {
+		logr.Fatal("--scan-windows and --scan-blackouts cannot be combined with --grpc-port, only the scans started by the command, on --schedule or with --watch being restricted to the windows")
+	}
+	if spillDir != "" && (watch || grpcPort != 0) {
+		logr.Fatal("--spill-dir cannot be combined with --watch or --grpc-port, their reports being kept for the whole run")
+	}
+	if failFast && (watch || scanSchedule != "" || grpcPort != 0) {
+		logr.Fatal("--fail-fast cannot be combined with --watch, --schedule or --grpc-port, it stops a single scan")
+	}
+	validateRecordFlags()
+	validateSinceFlags()
+	validateMaxScanErrorRate()
+	validateCIAnnotationFlags()
+	validateReportSigningFlags()
+	validateSeverityFloorFlags()
+	if (recordDir != "" || replayDir != "") && imageScanSource != sourceTrivy {
+		logr.Fatalf("--record and --replay only record the images scanned with trivy, --source %s is not supported", imageScanSource)
+	}
+	if replayDir != "" && watch {
+		logr.Fatal("--watch cannot be used with --replay as the recorded cluster cannot be watched")
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	defer closeSpilledReport()
+	var stream io.Writer
+	if streamOutput != "" {
+		var closeStream func()
+		stream, closeStream = openOutput(streamOutput, "stream output")
+		defer closeStream()
+	}
+	var kubernetesClient k8s.KubernetesClient
+	if imageList == "" {
+		kubernetesClient = newKubernetesClient()
+	}
+
+	if scanSchedule != "" {
+		// the config is created for each scan so that the datasets such as the KEV catalog are refreshed
+		scheduleScans(ctx, func(ctx context.Context) error {
+			_, err := scanAndReport(ctx, kubernetesClient, newScanConfig(stream))
+			return err
+		})
+		return
+	}
+	if grpcPort != 0 {
+		serveScans(ctx, kubernetesClient, stream)
+		return
+	}
+	windows := scanWindowsOf()
+	scanCtx, cancelWindow := waitForScanWindow(ctx, windows)
+	defer cancelWindow()
+	config := newScanConfig(stream)
+	start := time.Now()
+	imageScanReport, err := scanAndReport(scanCtx, kubernetesClient, config)
+	if !watch {
+		pushMetrics(imageScanReport, time.Since(start), err)
+		recordJobEvent(imageScanReport, time.Since(start), err)
+	}
+	if err != nil {
+		logr.Fatal(err)
+	}
+	if watch {
+		// the images the initial scan did not reach before its window closed are scanned by the watch
+		exitIfInterrupted(ctx)
+		if windows != nil {
+			config.ScanWindows = windows
+		}
+		watchClusterImages(ctx, kubernetesClient, config, imageScanReport)
+		return
+	}
+	exitIfInterrupted(scanCtx)
+	exitIfFailedFast(imageScanReport)
+	writeCIAnnotations(imageScanReport)
+	exitIfKnownExploited(imageScanReport)
+	exitIfBudgetExceeded(imageScanReport)
+	exitIfSeverityFound(imageScanReport)
+	exitIfScanErrorRateExceeded(imageScanReport)
+}
+
+// newScanConfig creates the scanner config of the command flags, the scanned images being streamed to the stream if set
+func newScanConfig(stream io.Writer) *scanner.Config {
 	config := &scanner.Config{
-		LogLevel:             logLevel,
-		Workers:              scanWorkers,
-		ImageNameReplacement: imageNameReplacement,
-		AreaLabels:           areaLabel,
-		TeamsLabels:          teamLabels,
-		FilterLabels:         filterLabels,
-		Severity:             severity,
-		ScanImageTimeout:     scanTimeout,
-	}
-	t := scanner.New(k8s.NewKubernetesClient(kubeContext, kubeconfigPath), config)
-
-	imageScanReport, err := t.ScanImages()
+		LogLevel:               logLevel,
+		Workers:                workers(),
+		ImageNameReplacement:   imageNameReplacement,
+		AreaLabels:             areaLabel,
+		TeamsLabels:            teamLabels,
+		GroupBy:                groupByMode(),
+		ScoringMode:            scoringMode(),
+		Ownership:              ownershipMapping(),
+		CheckpointFile:         checkpointFile,
+		Resume:                 resume,
+		SpillDir:               spillDir,
+		ScratchDir:             scratchDir,
+		ScratchDirMaxSize:      scratchDirMaxSizeBytes(),
+		MaxResultAge:           maxResultAge,
+		Since:                  since,
+		NodeName:               scannerNodeName(),
+		FullRescanInterval:     fullRescanInterval,
+		FilterLabels:           namespaceFilterLabels(),
+		Severity:               severity,
+		ScanImageTimeout:       scanTimeout,
+		Tracer:                 newTracer(),
+		Retries:                retries,
+		RetryBackoff:           retryBackoff,
+		RegistryPullsPerMinute: registryPullsPerMinute(),
+		RegistryWorkers:        registryWorkers(),
+		MaxImageSize:           maxImageSizeBytes(),
+		ScanOversizedImages:    scanOversizedImages,
+		OversizedImageWorkers:  oversizedImageWorkers,
+		WeightScansBySize:      autoScanWorkers,
+		ScanSecrets:            scanSecrets,
+		ScanLicenses:           scanLicenses,
+		LicensePolicy:          licensePolicy(),
+		MinCVSSScore:           minCVSSScore,
+		IgnoreUnfixed:          ignoreUnfixed,
+		SortByCVSS:             sortByCVSS,
+		KEVCatalog:             loadKEVCatalog(),
+		EPSSDataset:            loadEPSSDataset(),
+		Advisories:             advisoryDatabase(),
+		SeverityOverrides:      severityOverrides(),
+		UnknownSeverity:        unknownSeverity(),
+		RegistryScans:          harborScans(),
+		SBOMDir:                sbomDir(),
+		SBOMVulnerabilities:    sbomVulnerabilities,
+		SeverityBudgets:        severityBudgets(),
+		TrivyPath:              trivyPath(),
+		TrivyExtraArgs:         strings.Fields(trivyExtraArgs),
+		TrivyImageExtraArgs:    strings.Fields(trivyImageExtraArgs),
+		TrivySBOMExtraArgs:     strings.Fields(trivySBOMExtraArgs),
+		TrivyCisExtraArgs:      strings.Fields(trivyCisExtraArgs),
+		TrivyDB:                trivyDB(),
+		TrivySharedCache:       trivySharedCache,
+		RawTrivyOutputDir:      rawTrivyOutputDir,
+		FailFast:               failurePolicy(),
+		ScanPriority:           scanPriority(),
+		Platforms:              platforms(),
+		ContainerRuntime:       containerRuntime(),
+		KeepImages:             keepImages,
+		InsecureRegistries:     insecureRegistries,
+		RegistryCredentials:    registryCredentialSource(),
+	}
+	if stream != nil {
+		config.Stream = stream
+	}
+	if imageList == "" {
+		config.ClusterName = clusterName()
+	}
+	return config
+}
+
+// scanAndReport scans the images of the image list or of the cluster, generates the reports and sends the notifications,
+// unless the scan was interrupted or stopped on a failing vulnerability
+func scanAndReport(ctx context.Context, kubernetesClient k8s.KubernetesClient, config *scanner.Config) (*scanner.VulnerabilityReport, error) {
+	var (
+		imageScanReport *scanner.VulnerabilityReport
+		err             error
+	)
+	if imageList != "" {
+		imageScanReport, err = newScanner(nil, config).ScanImageList(ctx, imageList)
+	} else {
+		imageScanReport, err = scanClusterImages(ctx, kubernetesClient, config)
+	}
+	keepSpilledResults(imageScanReport)
+	shutdownTracer(config.Tracer)
 	if err != nil {
-		logr.Fatalf("Error scanning images with config %v: %v", config, err)
+		return nil, fmt.Errorf("error scanning images with config %v: %v", config, err)
 	}
 
+	trackVulnerabilityAges(imageScanReport)
+	applyTriage(imageScanReport)
+	rotateReportFiles()
+	fullReport := writeImageScanReports(imageScanReport)
+	printSummaryTable(imageScanReport)
+	writeScoreboard(imageScanReport)
+	writeFixLatencies(imageScanReport)
+	if ctx.Err() != nil || imageScanReport.Metadata.FailFastFinding != "" {
+		// the notifications would report the findings of an incomplete scan, the callers exit once the outcome of the
+		// run is recorded, see exitIfInterrupted and exitIfFailedFast
+		return imageScanReport, nil
+	}
+
+	baseline := loadBaselineReport()
+	sendNotifications(imageScanReport, baseline)
+	createJiraTickets(imageScanReport, baseline)
+	syncGitHubIssues(imageScanReport, baseline)
+	deliverWebhook(fullReport, imageScanReport)
+	exportToDefectDojo(imageScanReport)
+	exportToDependencyTrack(imageScanReport)
+	return imageScanReport, nil
+}
+
+// writeImageScanReports generates the image scan reports, the json report and the team reports if enabled, without the
+// vulnerabilities below --report-min-severity
+func writeImageScanReports(imageScanReport *scanner.VulnerabilityReport) *FullReport {
+	imageScanReport = imageScanReport.WithMinSeverity(minSeverity("report-min-severity", reportMinSeverity))
 	fullReport := &FullReport{
 		ImageScan: imageScanReport,
 	}
-	err = r.GenerateReportFromTemplate(fullReport, reportTemplate, reportDir, reportFile)
+	err := r.GenerateReport(fullReport, reportTemplate, r.Engine(reportTemplateEngine), reportDir, reportFile)
 	if err != nil {
 		logr.Fatal(err)
 	}
+	convertHTMLReportToPDF(reportFile)
 
 	if jsonReportFile != "" {
 		err = r.SaveReport(fullReport, jsonReportFile)
@@ -66,5 +333,47 @@ func scan(_ *cobra.Command, _ []string) {
 			logr.Fatal(err)
 		}
 	}
+	saveGitLabReport(imageScanReport)
+	saveOCSFFindings(imageScanReport)
+	saveCycloneDXReport(imageScanReport)
+	saveMarkdownReport(imageScanReport)
+	saveInventory(imageScanReport)
+	saveHeatmap(imageScanReport)
+	signReportFiles(append(renderedReportFiles(reportFile), jsonReportFile, gitlabReportFile, ocsfReportFile, cycloneDXReportFile, markdownReportFile, inventoryFile, heatmapFile)...)
+
+	if reportPerTeam {
+		generateTeamReports(imageScanReport)
+	}
+	writeQuietReport(fullReport)
+	return fullReport
+}
+
+// generateTeamReports generates the report of each team next to the aggregated report
+func generateTeamReports(imageScanReport *scanner.VulnerabilityReport) {
+	for team, teamReport := range imageScanReport.SplitByTeam() {
+		teamFullReport := &FullReport{
+			ImageScan: teamReport,
+		}
+		err := r.GenerateReport(teamFullReport, reportTemplate, r.Engine(reportTemplateEngine), reportDir, teamFilename(reportFile, team))
+		if err != nil {
+			logr.Fatal(err)
+		}
+		convertHTMLReportToPDF(teamFilename(reportFile, team))
+		signReportFiles(renderedReportFiles(teamFilename(reportFile, team))...)
+		if jsonReportFile != "" {
+			err = r.SaveReport(teamFullReport, teamFilename(jsonReportFile, team))
+			if err != nil {
+				logr.Fatal(err)
+			}
+			signReportFiles(teamFilename(jsonReportFile, team))
+		}
+	}
+}
+
+var unsafeFilenameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
 
+// teamFilename suffixes the filename with the team name, for instance report-imageScan-payments.html
+func teamFilename(filename, team string) string {
+	extension := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filename, extension), unsafeFilenameCharacters.ReplaceAllString(team, "_"), extension)
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// scanErrorsExitCode is the exit code of the commands whose scan error rate exceeds --max-scan-error-rate, distinct
+// from the exit code 1 of the failures and of the findings so that the pipelines can retry the scan
+const scanErrorsExitCode = 3
+
+var maxScanErrorRate float64
+
+func addScanErrorFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64Var(&maxScanErrorRate, "max-scan-error-rate", 100, fmt.Sprintf("maximum percentage of the images whose scan fails, the command exits with the code %d once the reports are generated when it is exceeded", scanErrorsExitCode))
+}
+
+// validateMaxScanErrorRate fails the command when --max-scan-error-rate is not a percentage
+func validateMaxScanErrorRate() {
+	if maxScanErrorRate < 0 || maxScanErrorRate > 100 {
+		logr.Fatalf("Invalid --max-scan-error-rate %v, expecting a percentage between 0 and 100", maxScanErrorRate)
+	}
+}
+
+// exitIfScanErrorRateExceeded prints the summary of the scan errors and exits with scanErrorsExitCode when the
+// percentage of the failed scans exceeds --max-scan-error-rate
+func exitIfScanErrorRateExceeded(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil {
+		return
+	}
+	scanErrors := imageScanReport.ScanErrors()
+	if len(scanErrors) == 0 {
+		return
+	}
+	errorsByStage := make(map[string]int)
+	for _, scanError := range scanErrors {
+		errorsByStage[scanError.Stage]++
+	}
+	rate := imageScanReport.ScanErrorRate()
+	logr.Warnf("Scan errors: %d of %d image(s) failed to scan (%.1f%%), %d pull error(s), %d removal error(s)",
+		imageScanReport.FailedScanCount(), len(imageScanReport.ScannedImages), rate, errorsByStage[scanner.ScanStagePull], errorsByStage[scanner.ScanStageRemove])
+	if rate > maxScanErrorRate {
+		logr.Errorf("The scan error rate of %.1f%% exceeds the maximum of %.1f%%", rate, maxScanErrorRate)
+		// the exit handlers remove the results spilled to disk
+		logr.Exit(scanErrorsExitCode)
+	}
+}
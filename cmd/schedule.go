@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/schedule"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/server"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanSchedule        string
+	keepReports         int
+	scanWindows         string
+	scanBlackouts       string
+	scanWindowsTimezone string
+)
+
+func addScheduleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scanSchedule, "schedule", "", "cron schedule of the scans, for instance '0 2 * * *' for every day at 02:00. The command keeps running and scans on the schedule until interrupted, serving the status of the last scan on the --admin-port /metrics and /api/v1/status endpoints, POST /api/v1/scans starting a scan out of the schedule when the SCAN_TRIGGER_TOKEN environment variable is set, the requests holding it as bearer token")
+	cmd.Flags().IntVar(&keepReports, "keep-reports", 7, "number of previous reports kept with --schedule, the reports of the previous scans being renamed with a numbered suffix, for instance report-imageScan.1.html")
+	cmd.Flags().StringVar(&scanWindows, "scan-windows", "", "comma-separated daily windows the scans are allowed in, for instance '01:00-05:00,Sat,Sun 22:00-06:00'. The scans of --schedule are postponed to the next window and a scan without --schedule waits for it, the scans still in progress when their window closes being cancelled. The scans of --watch due outside of the windows are postponed to the next window")
+	cmd.Flags().StringVar(&scanBlackouts, "scan-blackouts", "", "comma-separated windows the scans are never run in, in the --scan-windows format, for instance 'Mon-Fri 08:00-20:00'")
+	cmd.Flags().StringVar(&scanWindowsTimezone, "scan-windows-timezone", "Local", "time zone of the --scan-windows and --scan-blackouts times, for instance Europe/London")
+}
+
+// scanWindowsOf returns the --scan-windows and --scan-blackouts windows, nil when the scans may run at any time
+func scanWindowsOf() *schedule.Windows {
+	if scanWindows == "" && scanBlackouts == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(scanWindowsTimezone)
+	if err != nil {
+		logr.Fatalf("Invalid --scan-windows-timezone %s: %v", scanWindowsTimezone, err)
+	}
+	windows, err := schedule.ParseWindows(scanWindows, scanBlackouts, location)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return windows
+}
+
+// waitForScanWindow waits for the next scan window to open, and returns a context cancelled when the window closes.
+// The context is returned as is without scan windows
+func waitForScanWindow(ctx context.Context, windows *schedule.Windows) (context.Context, context.CancelFunc) {
+	if windows == nil {
+		return context.WithCancel(ctx)
+	}
+	if next := windows.Next(time.Now()); time.Until(next) > 0 {
+		logr.Infof("Waiting for the next scan window %s at %s", windows, next.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return context.WithCancel(ctx)
+		case <-time.After(time.Until(next)):
+		}
+	}
+	return windows.Context(ctx, time.Now())
+}
+
+// scheduleScans runs the scan on the --schedule cron schedule until interrupted. The admin server serves the status of
+// the scans, and the Report API of the json report when --report-output-filename-json is set
+func scheduleScans(ctx context.Context, scan func(ctx context.Context) error) {
+	cronSchedule, err := schedule.Parse(scanSchedule)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	scheduler := schedule.NewScheduler(cronSchedule, scan)
+	if windows := scanWindowsOf(); windows != nil {
+		scheduler = schedule.NewSchedulerWithWindows(cronSchedule, windows, scan)
+		logr.Infof("Scanning within the scan windows %s", windows)
+	}
+	handlers := map[string]http.Handler{"/api/v1/status": scheduler.StatusHandler()}
+	if token := os.Getenv("SCAN_TRIGGER_TOKEN"); token != "" {
+		handlers["/api/v1/scans"] = scheduler.TriggerHandler(token)
+	} else {
+		logr.Info("POST /api/v1/scans is disabled, SCAN_TRIGGER_TOKEN is not set")
+	}
+	if jsonReportFile != "" {
+		handlers["/api/"] = server.New(jsonReportFile).Handler()
+	}
+	adminServer := startServer(serverAdminPort, handlers)
+
+	logr.Infof("Scanning on schedule %s", scanSchedule)
+	scheduler.Run(ctx)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+	_ = adminServer.Shutdown(shutdownCtx)
+	logr.Info("Shut down complete")
+}
+
+// rotateReportFiles keeps the reports of the previous scheduled scans, the reports are overwritten without --schedule
+func rotateReportFiles() {
+	if scanSchedule == "" {
+		return
+	}
+	if err := schedule.RotateFiles(keepReports, reportDir+reportFile, jsonReportFile); err != nil {
+		logr.Warnf("Unable to keep the previous reports: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var scoreboardOutput string
+
+func addScoreboardFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scoreboardOutput, "scoreboard-output", "", "file the scoreboard of the teams is written to, ranking the teams by severity score with the percentage of their images without CRITICAL vulnerability and, with --vulnerability-history, the change of their score week over week. '-' for the standard output")
+}
+
+// writeScoreboard writes the scoreboard of the teams to --scoreboard-output if specified
+func writeScoreboard(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil || scoreboardOutput == "" {
+		return
+	}
+	w, closeOutput := openOutput(scoreboardOutput, "scoreboard")
+	defer closeOutput()
+	if err := imageScanReport.WriteScoreboard(w); err != nil {
+		logr.Errorf("Unable to write the scoreboard: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var scoring string
+
+func addScoringFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scoring, "scoring-mode", scanner.ScoringImages, fmt.Sprintf("weighting of the severity score the images are ranked by: '%s' scores each image once, '%s' weights the score by the number of containers running the image and '%s' by the number of distinct workloads running it, so that a DaemonSet image counts once whatever the number of nodes. The container and workload weighted scores are both recorded in the json report",
+		scanner.ScoringImages, scanner.ScoringContainers, scanner.ScoringWorkloads))
+}
+
+// scoringMode returns the weighting of the severity score of the images, exiting when it is not supported
+func scoringMode() string {
+	switch scoring {
+	case scanner.ScoringImages, scanner.ScoringContainers, scanner.ScoringWorkloads:
+		return scoring
+	}
+	logr.Fatalf("Unsupported --scoring-mode %q, permitted values: %s, %s, %s", scoring, scanner.ScoringImages, scanner.ScoringContainers, scanner.ScoringWorkloads)
+	return ""
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	scratchDir        string
+	scratchDirMaxSize string
+)
+
+func addScratchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scratchDir, "scratch-dir", "", "directory trivy writes its temporary files and its cache to, for instance a volume mounted in the scanner pod rather than the root disk of the node. The temporary files and the layer cache are removed once the scan completes. The system temporary directory and the trivy cache are used when not specified")
+	cmd.Flags().StringVar(&scratchDirMaxSize, "scratch-dir-max-size", "", "size of the --scratch-dir above which the scans wait for the scans in progress to complete, for instance 20Gi. There is no maximum size unless this option is specified")
+}
+
+// scratchDirMaxSizeBytes parses the maximum size of the scratch directory, 0 when not specified. With --pod-limits it
+// defaults to a share of the ephemeral storage limit of the pod
+func scratchDirMaxSizeBytes() int64 {
+	if scratchDirMaxSize == "" {
+		if scratchDir == "" {
+			return 0
+		}
+		return int64(float64(podEphemeralStorageLimit()) * podScratchDirShare)
+	}
+	if scratchDir == "" {
+		logr.Fatal("--scratch-dir-max-size requires --scratch-dir")
+	}
+	size, err := resource.ParseQuantity(scratchDirMaxSize)
+	if err != nil {
+		logr.Fatalf("Invalid maximum scratch directory size %q: %v", scratchDirMaxSize, err)
+	}
+	return size.Value()
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var scanSecrets bool
+
+func addSecretFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&scanSecrets, "scan-secrets", false, "also run the trivy secret scanner on the images and report the credentials found in their files, such as AWS access keys, tokens or private keys")
+}
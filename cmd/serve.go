@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/server"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Will serve the latest json report over a REST API so that dashboards can query it",
+		Run:   serve,
+	}
+	serveReportFile string
+	serveSeriesFile string
+	servePort       int
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveReportFile, "report-file", "", "json report saved by the scan with --report-output-filename-json, reloaded whenever the scan saves a new one")
+	serveCmd.Flags().StringVar(&serveSeriesFile, "series-file", "", "json file keeping the vulnerability counts by severity of the reports served, so that the Grafana time series survive the restarts of the server. The counts are only kept in memory when not specified")
+	serveCmd.Flags().IntVar(&servePort, "port", 8080, "port the API listens to")
+	_ = serveCmd.MarkFlagRequired("report-file")
+}
+
+func serve(_ *cobra.Command, _ []string) {
+	ctx, cancel := interruptContext()
+	defer cancel()
+	reportServer, err := server.NewWithSeriesFile(serveReportFile, serveSeriesFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", servePort),
+		Handler:           reportServer.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelShutdown()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logr.Infof("Serving report %s at: http://0.0.0.0%s/api/v1/report", serveReportFile, httpServer.Addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logr.Fatalf("Unexpected failure when starting the server: %v", err)
+	}
+	logr.Info("Shut down complete")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	severityOverridesFile string
+	unknownSeverityPolicy string
+)
+
+func addSeverityOverrideFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&severityOverridesFile, "severity-overrides", "", "yaml file overriding the severity of vulnerabilities or packages with a justification, applied before the vulnerabilities are counted")
+	cmd.Flags().StringVar(&unknownSeverityPolicy, "unknown-severity", scanner.UnknownSeverityKeep, "policy of the vulnerabilities of UNKNOWN severity, the unscored CVEs: keep them as UNKNOWN, count them as low or medium, or exclude them except the known exploited vulnerabilities, applied after the --severity-overrides so that the summaries, the scores and the thresholds count them consistently. One of keep, low, medium or exclude")
+}
+
+// severityOverrides returns the severity overrides, nil when no overrides file is specified
+func severityOverrides() *scanner.SeverityOverrides {
+	if severityOverridesFile == "" {
+		return nil
+	}
+	overrides, err := scanner.LoadSeverityOverrides(severityOverridesFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return overrides
+}
+
+// unknownSeverity returns the --unknown-severity policy
+func unknownSeverity() string {
+	if err := scanner.ValidateUnknownSeverityPolicy(unknownSeverityPolicy); err != nil {
+		logr.Fatal(err)
+	}
+	return unknownSeverityPolicy
+}
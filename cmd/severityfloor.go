@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportMinSeverity  string
+	summaryMinSeverity string
+	notifyMinSeverity  string
+	failOnSeverity     string
+)
+
+func addSeverityFloorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportMinSeverity, "report-min-severity", "", "minimum severity of the vulnerabilities of the html, markdown and json reports, for instance MEDIUM, all the --severity severities being reported when empty")
+	cmd.Flags().StringVar(&summaryMinSeverity, "summary-min-severity", "", "minimum severity of the vulnerabilities of the summary table printed to the standard output, all the --severity severities being counted when empty")
+	cmd.Flags().StringVar(&notifyMinSeverity, "notify-min-severity", "", "minimum severity of the vulnerabilities of the notifications, all the --severity severities being notified when empty")
+	addFailOnSeverityFlags(cmd)
+}
+
+// addFailOnSeverityFlags registers --fail-on-severity alone, for the commands without the other severity floors
+func addFailOnSeverityFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&failOnSeverity, "fail-on-severity", "", "exit with an error once the reports are generated when a vulnerability of at least this severity is found, for instance HIGH to gate a CI pipeline on the CRITICAL and HIGH vulnerabilities only")
+}
+
+// minSeverity returns the upper-cased minimum severity of the flag, empty for all the severities
+func minSeverity(flag, value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if err := scanner.ValidateMinSeverity(value); err != nil {
+		logr.Fatalf("Invalid --%s: %v", flag, err)
+	}
+	return value
+}
+
+// validateSeverityFloorFlags fails the command before the scan when a minimum severity is unknown
+func validateSeverityFloorFlags() {
+	minSeverity("report-min-severity", reportMinSeverity)
+	minSeverity("summary-min-severity", summaryMinSeverity)
+	minSeverity("notify-min-severity", notifyMinSeverity)
+	minSeverity("fail-on-severity", failOnSeverity)
+}
+
+// exitIfSeverityFound fails the command when requested and vulnerabilities of at least the severity are found
+func exitIfSeverityFound(imageScanReport *scanner.VulnerabilityReport) {
+	if failOnSeverity == "" || imageScanReport == nil {
+		return
+	}
+	severity := minSeverity("fail-on-severity", failOnSeverity)
+	if findings := policyFindings(imageScanReport.FailingVulnerabilities(severity)); len(findings) > 0 {
+		logr.Fatalf("%d vulnerabilities of severity %s or higher found", len(findings), severity)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cosignKeys, cosignIdentities []string
+
+func addSignatureFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&cosignKeys, "cosign-keys", nil, "cosign public keys the image signatures are verified against with the "+checks.ImageSignaturesCheckName+" check, as paths, urls or KMS URIs (comma separated)")
+	cmd.Flags().StringSliceVar(&cosignIdentities, "cosign-identities", nil, "keyless signing identities the image signatures are verified against with the "+checks.ImageSignaturesCheckName+" check, format: '<oidc issuer>=<certificate identity regexp>' (comma separated)")
+}
+
+// signaturePolicy returns the keys and identities the image signatures are verified against
+func signaturePolicy() *checks.SignaturePolicy {
+	policy := &checks.SignaturePolicy{PublicKeys: cosignKeys}
+	for _, value := range cosignIdentities {
+		identity, err := checks.ParseKeylessIdentity(value)
+		if err != nil {
+			logr.Fatal(err)
+		}
+		policy.Identities = append(policy.Identities, identity)
+	}
+	return policy
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/signing"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var reportSigningKey, reportCosignKey string
+
+func addReportSigningFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportSigningKey, "report-signing-key", "", "unencrypted PEM private key, ECDSA, Ed25519 or RSA, the generated reports are signed with, each signature being written next to its report with the "+signing.SignatureExtension+" extension. The reports are not signed unless this option or --report-cosign-key is specified")
+	cmd.Flags().StringVar(&reportCosignKey, "report-cosign-key", "", "cosign key the generated reports are signed with by cosign sign-blob, as a path or KMS URI, the password of an encrypted key being read from the COSIGN_PASSWORD environment variable")
+}
+
+// reportSigner returns the signer of the generated reports, nil when the reports are not signed
+func reportSigner() signing.Signer {
+	switch {
+	case reportSigningKey != "" && reportCosignKey != "":
+		logr.Fatal("--report-signing-key and --report-cosign-key are mutually exclusive")
+	case reportSigningKey != "":
+		signer, err := signing.NewKeySigner(reportSigningKey)
+		if err != nil {
+			logr.Fatalf("Invalid --report-signing-key: %v", err)
+		}
+		return signer
+	case reportCosignKey != "":
+		return signing.NewCosignSigner(reportCosignKey)
+	}
+	return nil
+}
+
+// validateReportSigningFlags fails the command before the scan when the signing key cannot be read
+func validateReportSigningFlags() {
+	reportSigner()
+}
+
+// signReportFiles signs the generated report files when requested, the empty filenames being ignored
+func signReportFiles(filenames ...string) {
+	signer := reportSigner()
+	if signer == nil {
+		return
+	}
+	for _, filename := range filenames {
+		if filename == "" {
+			continue
+		}
+		signatureFilename, err := signer.Sign(filename)
+		if err != nil {
+			logr.Fatal(err)
+		}
+		logr.Infof("Report %s signed into: %s", filename, signatureFilename)
+	}
+}
+
+// renderedReportFiles returns the report file of the report directory and its PDF rendering when requested
+func renderedReportFiles(reportFile string) []string {
+	files := []string{reportDir + reportFile}
+	if pdfReport && strings.HasSuffix(reportFile, ".html") {
+		files = append(files, reportDir+strings.TrimSuffix(reportFile, filepath.Ext(reportFile))+".pdf")
+	}
+	return files
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var since time.Duration
+
+func addSinceFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&since, "since", 0, "only scan the images of the pods created or updated within this duration, for instance 24h, for fast incremental scans between full scans. All the cluster images are scanned when 0")
+}
+
+// validateSinceFlags rejects the incremental scans of the images that are not run by the pods of the cluster, and of
+// the watched clusters whose reports hold all the images
+func validateSinceFlags() {
+	if since < 0 {
+		logr.Fatalf("Invalid --since %s, expected a positive duration such as 24h", since)
+	}
+	if since > 0 && (imageList != "" || watch) {
+		logr.Fatal("--since only restricts the scan of the cluster images, it cannot be used with --image-list or --watch")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	spillDir string
+
+	// spilledReport is the report of the last scan whose results may be spilled to --spill-dir, closed once the
+	// command exits, including when it fails
+	spilledReport *scanner.VulnerabilityReport
+)
+
+func init() {
+	logr.RegisterExitHandler(closeSpilledReport)
+}
+
+func addSpillFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&spillDir, "spill-dir", "", "directory the results of the scanned images are kept in rather than in memory, for the runners with little memory. The results are read back one image at a time when the reports are generated and removed once the command exits. Empty to keep them in memory")
+}
+
+// keepSpilledResults keeps the results the report spilled to disk until the command exits, the results of the report
+// of the previous scheduled scan being removed
+func keepSpilledResults(imageScanReport *scanner.VulnerabilityReport) {
+	closeSpilledReport()
+	spilledReport = imageScanReport
+}
+
+// closeSpilledReport removes the results spilled to disk once the command no longer reads them
+func closeSpilledReport() {
+	spilledReport.Close()
+	spilledReport = nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/spf13/cobra"
+)
+
+var maxImageAgeDays int
+
+func addImageStalenessFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&maxImageAgeDays, "max-image-age-days", 90, "maximum age in days of the images, based on their creation time in the registry, before the "+checks.ImageStalenessCheckName+" check reports them as not rebuilt against patched base images")
+}
+
+func maxImageAge() time.Duration {
+	return time.Duration(maxImageAgeDays) * 24 * time.Hour
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func addReportDirectoryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reportDir, "report-output-directory", "audit-report/", "output directory that will contain the generated report")
+}
+
+// addSubsystemCommands adds a subcommand per subsystem, so that the vulnerability scans, the compliance scans and the
+// workload checks are run on their own with scan images, scan compliance and check workloads, or composed with scan
+// all. They are added once the flags of all the commands are registered, as each runs an existing command with its flags
+func addSubsystemCommands() {
+	scanCmd.AddCommand(
+		subsystemCommand("images", "Scan the images run in a cluster for vulnerabilities, as the scan command", scanCmd),
+		subsystemCommand("compliance", "Scan a cluster with the CIS, NSA and PSS security benchmarks, as the cis-scan command", cisScanCmd),
+		subsystemCommand("all", "Scan the images and the compliance of a cluster and run the workload checks in a single report, as the report command", reportCmd),
+	)
+	checkCmd.AddCommand(
+		subsystemCommand("workloads", "Run the readiness checks against the workloads of a cluster, as the check command", checkCmd),
+	)
+}
+
+// subsystemCommand returns a command running the command of the subsystem, with the same flags so that it honours
+// the same output and threshold options
+func subsystemCommand(use, short string, subsystem *cobra.Command) *cobra.Command {
+	command := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		Run:   subsystem.Run,
+	}
+	command.Flags().AddFlagSet(subsystem.LocalFlags())
+	return command
+}
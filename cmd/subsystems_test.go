@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Subsystem commands", func() {
+
+	// subcommand returns the subcommand of the command, nil when there is none
+	subcommand := func(command *cobra.Command, use string) *cobra.Command {
+		for _, c := range command.Commands() {
+			if c.Name() == use {
+				return c
+			}
+		}
+		return nil
+	}
+
+	It("run the command of their subsystem with the same flags", func() {
+		addSubsystemCommands()
+		DeferCleanup(func() {
+			scanCmd.RemoveCommand(subcommand(scanCmd, "images"), subcommand(scanCmd, "compliance"), subcommand(scanCmd, "all"))
+			checkCmd.RemoveCommand(subcommand(checkCmd, "workloads"))
+		})
+
+		for _, subsystem := range []struct {
+			parent, standalone *cobra.Command
+			use                string
+		}{
+			{scanCmd, scanCmd, "images"},
+			{scanCmd, cisScanCmd, "compliance"},
+			{scanCmd, reportCmd, "all"},
+			{checkCmd, checkCmd, "workloads"},
+		} {
+			command := subcommand(subsystem.parent, subsystem.use)
+			Expect(command).NotTo(BeNil(), subsystem.use)
+			Expect(reflect.ValueOf(command.Run).Pointer()).To(Equal(reflect.ValueOf(subsystem.standalone.Run).Pointer()), subsystem.use)
+			subsystem.standalone.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+				Expect(command.Flags().Lookup(flag.Name)).To(BeIdenticalTo(flag), "--%s of %s", flag.Name, command.CommandPath())
+			})
+			Expect(command.Flags().HasFlags()).To(Equal(subsystem.standalone.LocalFlags().HasFlags()))
+		}
+	})
+
+	It("sets the flags of the standalone command", func() {
+		var (
+			severity string
+			ran      bool
+		)
+		standalone := &cobra.Command{Use: "standalone", Run: func(_ *cobra.Command, _ []string) { ran = true }}
+		standalone.Flags().StringVar(&severity, "fail-on-severity", "", "")
+		parent := &cobra.Command{Use: "parent"}
+		parent.SetOut(GinkgoWriter)
+		parent.SetErr(GinkgoWriter)
+		parent.AddCommand(subsystemCommand("sub", "Run as standalone", standalone))
+
+		parent.SetArgs([]string{"sub", "--fail-on-severity", "HIGH"})
+		Expect(parent.Execute()).To(Succeed())
+
+		Expect(ran).To(BeTrue())
+		Expect(severity).To(Equal("HIGH"))
+		parent.SetArgs([]string{"sub", "extra"})
+		Expect(parent.Execute()).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// printSummaryTable prints the summary table of the image scan to the standard output whatever the report format,
+// unless the scanned images are streamed to it or the report is written to it with --quiet. The table is colored when the standard output is a terminal and
+// NO_COLOR is not set
+func printSummaryTable(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil || streamOutput == "-" || quiet {
+		return
+	}
+	if err := imageScanReport.WithMinSeverity(minSeverity("summary-min-severity", summaryMinSeverity)).WriteSummaryTable(os.Stdout, isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""); err != nil {
+		logr.Warnf("Unable to print the summary table: %v", err)
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
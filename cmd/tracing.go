@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/tracing"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	otlpEndpoint, otlpHeaders string
+
+	// activeTracer is the tracer of the latest scan, whose spans are exported once the command exits, including when
+	// it fails with logr.Fatal
+	activeTracer *tracing.Tracer
+)
+
+func init() {
+	logr.RegisterExitHandler(func() {
+		shutdownTracer(activeTracer)
+	})
+}
+
+func addTracingFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OpenTelemetry collector url the scan traces are exported to with OTLP/HTTP, for instance http://localhost:4318. Defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable, no trace is recorded when empty")
+	cmd.Flags().StringVar(&otlpHeaders, "otlp-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "headers added to the trace export requests, format: 'key1=value1,key2=value2'. Defaults to the OTEL_EXPORTER_OTLP_HEADERS environment variable")
+}
+
+// newTracer creates the tracer of the scan pipeline, nil when no collector is configured
+func newTracer() *tracing.Tracer {
+	if otlpEndpoint == "" {
+		return nil
+	}
+	activeTracer = tracing.New(tracing.NewOTLPExporter(&tracing.OTLPConfig{
+		Endpoint: otlpEndpoint,
+		Headers:  parseKeyValues(otlpHeaders),
+	}))
+	return activeTracer
+}
+
+// shutdownTracer exports the remaining spans. Tracing is best effort so that errors are logged rather than failing the command
+func shutdownTracer(tracer *tracing.Tracer) {
+	if err := tracer.Shutdown(); err != nil {
+		logr.Warnf("Unable to export trace spans: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	triageCmd = &cobra.Command{
+		Use:   "triage",
+		Short: "Will mark a vulnerability of an image digest as a false positive, accepted or being fixed in the findings state file, the following reports carrying the triage forward. Lists the triaged findings when no vulnerability is specified",
+		Args:  cobra.NoArgs,
+		Run:   triageFinding,
+	}
+	findingsStateFile                                            string
+	triageVulnerability, triageDigest, triageImage, triageReport string
+	triageState, triageReason                                    string
+	triageClear                                                  bool
+)
+
+func init() {
+	rootCmd.AddCommand(triageCmd)
+	addFindingsStateFlags(triageCmd)
+	triageCmd.Flags().StringVar(&triageVulnerability, "vulnerability", "", "id of the vulnerability to triage, for instance CVE-2023-4911")
+	triageCmd.Flags().StringVar(&triageDigest, "digest", "", "digest of the image the vulnerability is triaged for, for instance sha256:4ff3ca91...")
+	triageCmd.Flags().StringVar(&triageImage, "image", "", "name of the image the vulnerability is triaged for, its digest being read from the --report json report, as an alternative to --digest")
+	triageCmd.Flags().StringVar(&triageReport, "report", "", "json report saved with --report-output-filename-json holding the digest of the --image image")
+	triageCmd.Flags().StringVar(&triageState, "state", "", "triage state of the finding, one of: "+strings.Join(scanner.TriageStates, ", "))
+	triageCmd.Flags().StringVar(&triageReason, "reason", "", "reason of the triage, shown in the reports")
+	triageCmd.Flags().BoolVar(&triageClear, "clear", false, "remove the triage of the finding rather than setting it")
+}
+
+func addFindingsStateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&findingsStateFile, "findings-state", "", "json file recording the triage of the findings with the triage command, by vulnerability and image digest. The reports show the triage state of the vulnerabilities and the false positives and accepted vulnerabilities are not notified")
+}
+
+func triageFinding(_ *cobra.Command, _ []string) {
+	if findingsStateFile == "" {
+		logr.Fatal("--findings-state is required")
+	}
+	state, err := scanner.LoadFindingsState(findingsStateFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	if triageVulnerability == "" {
+		if err := state.WriteText(os.Stdout); err != nil {
+			logr.Fatal(err)
+		}
+		return
+	}
+
+	digest := triageDigest
+	if triageImage != "" {
+		if digest != "" || triageReport == "" {
+			logr.Fatal("--image requires --report and cannot be combined with --digest")
+		}
+		report, err := scanner.LoadVulnerabilityReport(triageReport)
+		if err != nil {
+			logr.Fatal(err)
+		}
+		if digest = report.ImageDigest(triageImage); digest == "" {
+			logr.Fatalf("The digest of image %s is not known from report %s", triageImage, triageReport)
+		}
+	}
+	if digest == "" {
+		logr.Fatal("--digest or --image is required to triage a vulnerability")
+	}
+
+	if triageClear {
+		if !state.Clear(triageVulnerability, digest) {
+			logr.Warnf("Vulnerability %s of digest %s is not triaged", triageVulnerability, digest)
+			return
+		}
+		logr.Infof("Cleared the triage of vulnerability %s of digest %s", triageVulnerability, digest)
+	} else {
+		err := state.Triage(scanner.FindingTriage{
+			VulnerabilityID: triageVulnerability,
+			Digest:          digest,
+			State:           triageState,
+			Reason:          triageReason,
+			TriagedAt:       time.Now().UTC().Truncate(time.Second),
+		})
+		if err != nil {
+			logr.Fatal(err)
+		}
+		logr.Infof("Triaged vulnerability %s of digest %s as %s", triageVulnerability, digest, triageState)
+	}
+	if err := state.Save(findingsStateFile); err != nil {
+		logr.Fatal(err)
+	}
+}
+
+// findingsState returns the findings state of --findings-state, nil when not specified
+func findingsState() *scanner.FindingsState {
+	if findingsStateFile == "" {
+		return nil
+	}
+	state, err := scanner.LoadFindingsState(findingsStateFile)
+	if err != nil {
+		logr.Fatal(err)
+	}
+	return state
+}
+
+// applyTriage sets the triage state of the vulnerabilities of the report from the findings state file
+func applyTriage(imageScanReport *scanner.VulnerabilityReport) {
+	if imageScanReport == nil || findingsStateFile == "" {
+		return
+	}
+	findingsState().Apply(imageScanReport)
+}
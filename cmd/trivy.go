@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/trivybinary"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trivyPathFlag     string
+	trivyVersion      string
+	trivyCacheDir     string
+	resolvedTrivyPath string
+
+	trivyExtraArgs      string
+	trivyImageExtraArgs string
+	trivySBOMExtraArgs  string
+	trivyCisExtraArgs   string
+
+	skipDBUpdate     bool
+	dbRepository     string
+	dbCacheDir       string
+	javaDB           string
+	javaDBRepository string
+
+	trivySharedCache bool
+)
+
+func addTrivyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&trivyPathFlag, "trivy-path", "", "trivy binary to run. When not specified, the trivy of the PATH is run if compatible, otherwise --trivy-version is downloaded")
+	cmd.Flags().StringVar(&trivyVersion, "trivy-version", trivybinary.PinnedVersion, "trivy release downloaded, verified against its published checksums and cached when no compatible trivy is on the PATH")
+	cmd.Flags().StringVar(&trivyCacheDir, "trivy-cache-dir", trivybinary.DefaultCacheDir(), "directory the downloaded trivy releases are cached to")
+}
+
+func addTrivyArgsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&trivyExtraArgs, "trivy-args", "", "space-separated arguments passed through to every trivy invocation, for instance '--offline-scan --db-repository mirror.example.com/aquasecurity/trivy-db'")
+	cmd.Flags().StringVar(&trivyImageExtraArgs, "trivy-image-args", "", "space-separated arguments passed through to the trivy image scans only, for instance '--vuln-type os'")
+	cmd.Flags().StringVar(&trivySBOMExtraArgs, "trivy-sbom-args", "", "space-separated arguments passed through to the trivy SBOM generations only")
+	cmd.Flags().StringVar(&trivyCisExtraArgs, "trivy-cis-args", "", "space-separated arguments passed through to the trivy compliance scans only")
+	cmd.Flags().BoolVar(&skipDBUpdate, "skip-db-update", false, "scan with the trivy vulnerability db already present in the cache directory instead of downloading or updating it, for the air-gapped clusters")
+	cmd.Flags().StringVar(&dbRepository, "db-repository", "", "OCI repository the trivy vulnerability db is downloaded from instead of ghcr.io/aquasecurity/trivy-db, for instance a mirror of an internal registry")
+	cmd.Flags().StringVar(&dbCacheDir, "db-cache-dir", "", "trivy cache directory holding the vulnerability db in its db subdirectory, for instance a copy of the cache of a connected host. The trivy default cache directory is used when not specified")
+	cmd.Flags().StringVar(&javaDB, "java-db", scanner.JavaDBOnDemand, "when the trivy Java index db, the only language db trivy downloads, is downloaded: "+scanner.JavaDBPrefetch+" before the scans start so that the concurrent scans do not race to download it, "+scanner.JavaDBSkip+" never, using the one of the cache directory, or "+scanner.JavaDBOnDemand+" by the scans of the images holding jar files")
+	cmd.Flags().StringVar(&javaDBRepository, "java-db-repository", "", "OCI repository the trivy Java index db is downloaded from instead of ghcr.io/aquasecurity/trivy-java-db")
+}
+
+func addTrivySharedCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&trivySharedCache, "trivy-shared-cache", false, "lock the trivy cache directory shared by the scan workers and by the other runs using it, for instance the runs of a CronJob mounting the --db-cache-dir volume: exclusively while the vulnerability db and the Java index db are downloaded once before the scans, and shared while the images are scanned, so that no run updates the dbs under the scans of another. The Java index db is prefetched unless --java-db skip")
+}
+
+// trivyDB returns the location of the trivy vulnerability and Java index dbs of the flags
+func trivyDB() scanner.TrivyDBConfig {
+	if err := scanner.ValidateJavaDB(javaDB); err != nil {
+		logr.Fatal(err)
+	}
+	return scanner.TrivyDBConfig{
+		SkipUpdate:       skipDBUpdate,
+		Repository:       dbRepository,
+		CacheDir:         dbCacheDir,
+		JavaDB:           javaDB,
+		JavaDBRepository: javaDBRepository,
+	}
+}
+
+// trivyPath returns the trivy binary to run, the --trivy-path one, the compatible trivy of the PATH or else the
+// downloaded pinned release. It is resolved once per command, and not at all when the trivy responses are replayed
+func trivyPath() string {
+	if replayDir != "" {
+		return ""
+	}
+	if resolvedTrivyPath != "" {
+		return resolvedTrivyPath
+	}
+	installer := trivybinary.NewInstaller(trivyVersion, trivyCacheDir)
+	if trivyPathFlag != "" {
+		if err := installer.Validate(trivyPathFlag); err != nil {
+			logr.Fatalf("Unsupported --trivy-path %s: %v", trivyPathFlag, err)
+		}
+		resolvedTrivyPath = trivyPathFlag
+		return resolvedTrivyPath
+	}
+	path, err := installer.Resolve()
+	if err != nil {
+		logr.Fatalf("Unable to find or download trivy: %v", err)
+	}
+	resolvedTrivyPath = path
+	return resolvedTrivyPath
+}
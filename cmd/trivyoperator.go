@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/trivyoperator"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Sources of the image scan results, see --source
+const (
+	sourceTrivy         = "trivy"
+	sourceTrivyOperator = "trivy-operator"
+	sourceNodeAgent     = "node-agent"
+)
+
+var (
+	imageScanSource  string
+	nodeCollectorURL string
+)
+
+func addSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&imageScanSource, "source", sourceTrivy, fmt.Sprintf("source of the image scan results: '%s' pulls and scans the images, '%s' reads the VulnerabilityReports Trivy Operator already produced in the cluster, '%s' reads the scans of the node agents from --node-collector-url",
+		sourceTrivy, sourceTrivyOperator, sourceNodeAgent))
+	cmd.Flags().StringVar(&nodeCollectorURL, "node-collector-url", "", "url of the node collector the node agents report their scans to, for instance http://node-collector:8080, with --source "+sourceNodeAgent+", the scans being read with the "+nodeCollectorTokenEnv+" environment variable as bearer token")
+}
+
+// scanClusterImages scans the images of the cluster, or reports them with the Trivy Operator vulnerability reports or
+// the scans of the node agents
+func scanClusterImages(ctx context.Context, kubernetesClient k8s.KubernetesClient, config *scanner.Config) (*scanner.VulnerabilityReport, error) {
+	switch imageScanSource {
+	case sourceTrivy:
+		return newScanner(kubernetesClient, config).ScanImages(ctx)
+	case sourceTrivyOperator:
+		reports, err := trivyoperator.NewClient(k8s.KubernetesConfig(kubeContext, kubeconfigPath)).VulnerabilityReports(kubeNamespace)
+		if err != nil {
+			return nil, err
+		}
+		logr.Infof("Read %d Trivy Operator vulnerability reports", len(reports))
+		return scanner.New(kubernetesClient, config).ReportImageScans(ctx, trivyoperator.NewImageScanSource(reports))
+	case sourceNodeAgent:
+		source, err := nodeAgentImageScans(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return scanner.New(kubernetesClient, config).ReportImageScans(ctx, source)
+	}
+	logr.Fatalf("Unsupported --source %q, permitted values: %s, %s, %s", imageScanSource, sourceTrivy, sourceTrivyOperator, sourceNodeAgent)
+	return nil, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/spf13/cobra"
+)
+
+var ignoreUnfixed, fixableOnly bool
+
+func addIgnoreUnfixedFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&ignoreUnfixed, "ignore-unfixed", false, "only report the vulnerabilities with a fixed version, the unfixed ones being neither scanned by trivy nor counted in the summaries and scores, whatever the image scan source")
+}
+
+func addFixableOnlyFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&fixableOnly, "fixable-only", false, "only count the vulnerabilities with a fixed version towards the severity budgets, --fail-on-severity, --fail-on-known-exploited, --fail-fast, the notifications, jira tickets and github issues, as the teams cannot act on the other ones. Unlike --ignore-unfixed, the reports still list the unfixed vulnerabilities")
+}
+
+// policyFindings returns the findings failing the command, only the fixable ones with --fixable-only
+func policyFindings(findings []scanner.VulnerabilityFinding) []scanner.VulnerabilityFinding {
+	if !fixableOnly {
+		return findings
+	}
+	return scanner.FixableFindings(findings)
+}
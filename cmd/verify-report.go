@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/signing"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyReportCmd = &cobra.Command{
+		Use:   "verify-report <report>...",
+		Short: "Will verify the signatures of reports signed with --report-signing-key or --report-cosign-key, failing when a report was modified since it was signed",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   verifyReport,
+	}
+	verifyPublicKey, verifyCosignKey, verifySignatureFile string
+)
+
+func init() {
+	rootCmd.AddCommand(verifyReportCmd)
+	verifyReportCmd.Flags().StringVar(&verifyPublicKey, "key", "", "PEM public key of the --report-signing-key private key, or of the --report-cosign-key cosign key, the signatures are verified against")
+	verifyReportCmd.Flags().StringVar(&verifyCosignKey, "cosign-key", "", "cosign public key the signatures are verified against by cosign verify-blob, as a path, url or KMS URI")
+	verifyReportCmd.Flags().StringVar(&verifySignatureFile, "signature", "", "signature file of the report when a single report is verified, the report file with the "+signing.SignatureExtension+" extension by default")
+}
+
+func verifyReport(_ *cobra.Command, args []string) {
+	var verifier signing.Verifier
+	switch {
+	case (verifyPublicKey == "") == (verifyCosignKey == ""):
+		logr.Fatal("exactly one of --key or --cosign-key is required")
+	case verifyPublicKey != "":
+		var err error
+		if verifier, err = signing.NewKeyVerifier(verifyPublicKey); err != nil {
+			logr.Fatal(err)
+		}
+	default:
+		verifier = signing.NewCosignVerifier(verifyCosignKey)
+	}
+	if verifySignatureFile != "" && len(args) > 1 {
+		logr.Fatal("--signature can only be specified to verify a single report")
+	}
+
+	var failures int
+	for _, report := range args {
+		signatureFile := verifySignatureFile
+		if signatureFile == "" {
+			signatureFile = signing.SignatureFilename(report)
+		}
+		if err := verifier.Verify(report, signatureFile); err != nil {
+			logr.Errorf("Report %s: %v", report, err)
+			failures++
+			continue
+		}
+		logr.Infof("Report %s: signature verified", report)
+	}
+	if failures > 0 {
+		logr.Fatalf("%d of %d reports failed the signature verification", failures, len(args))
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watch              bool
+	fullRescanInterval time.Duration
+)
+
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&watch, "watch", false, "keep running after the scan, watching the pods of the cluster to scan the images not in the report yet and regenerating the reports after each scan, until interrupted")
+	cmd.Flags().DurationVar(&fullRescanInterval, "full-rescan-interval", 24*time.Hour, "interval of the full rescans of the cluster with --watch, refreshing the vulnerabilities of the images already scanned. The images already scanned are never rescanned when 0")
+}
+
+// watchClusterImages keeps the reports fresh by scanning the images appearing in the cluster until interrupted.
+// The notifications, Jira tickets and webhook are only sent for the initial scan
+func watchClusterImages(ctx context.Context, kubernetesClient k8s.KubernetesClient, config *scanner.Config, imageScanReport *scanner.VulnerabilityReport) {
+	err := newScanner(kubernetesClient, config).Watch(ctx, imageScanReport, func(updated *scanner.VulnerabilityReport) {
+		logr.Infof("Regenerating the reports with %d image(s)", len(updated.ScannedImages))
+		trackVulnerabilityAges(updated)
+		applyTriage(updated)
+		writeImageScanReports(updated)
+	})
+	shutdownTracer(config.Tracer)
+	if err != nil {
+		logr.Fatalf("Error watching the cluster images: %v", err)
+	}
+	logr.Info("Watch stopped")
+}
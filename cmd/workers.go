@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var autoScanWorkers bool
+
+func addAutoScanWorkersFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&autoScanWorkers, "auto-scan-workers", false, "size the number of workers from the CPUs, memory and free disk space of the host, --scan-workers being the maximum, and scan the larger images on more worker slots so that fewer of them are scanned concurrently")
+}
+
+// workers returns the number of scan workers, sized from the resources of the host with --auto-scan-workers, or of the
+// pod with --pod-limits
+func workers() int {
+	if !autoScanWorkers && !podLimits {
+		return scanWorkers
+	}
+	resources := scanner.DetectResources(scanDiskDir())
+	if podLimits {
+		resources = podResources(resources)
+	}
+	workers := resources.Workers(scanWorkers)
+	logr.Infof("Sized %d scan workers from %d CPUs, %d bytes of memory and %d bytes of free disk space", workers, resources.CPUs, resources.Memory, resources.Disk)
+	return workers
+}
+
+// scanDiskDir returns the directory the free disk space of the scans is measured in: the scratch directory, else the
+// trivy cache the image layers are cached to, or its closest existing parent when not created yet
+func scanDiskDir() string {
+	dir := doctorDirs()[0]
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return os.TempDir()
+		}
+		dir = parent
+	}
+}
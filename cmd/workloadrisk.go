@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	safeCapabilities       []string
+	writableRootFilesystem bool
+)
+
+func addWorkloadRiskFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&safeCapabilities, "safe-capabilities", checks.DefaultSafeCapabilities, "Linux capabilities the containers may add without being reported by the "+checks.WorkloadRiskCheckName+" check (comma separated)")
+	cmd.Flags().BoolVar(&writableRootFilesystem, "report-writable-root-filesystem", false, "report the containers whose root filesystem is not read-only with the "+checks.WorkloadRiskCheckName+" check, as LOW findings. Off by default as most workloads write to their root filesystem")
+}
@@ -0,0 +1,144 @@
+package annotations
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+const (
+	// FormatGitHub writes GitHub Actions workflow commands, for instance ::error title=...::...
+	FormatGitHub = "github"
+	// FormatAzureDevOps writes Azure DevOps logging commands, for instance ##vso[task.logissue type=error]...
+	FormatAzureDevOps = "azure-devops"
+
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Annotation is a finding surfaced inline by the CI, an error for the breaches of the thresholds failing the command
+type Annotation struct {
+	Level   string
+	Title   string
+	Message string
+}
+
+// Thresholds are the thresholds the command fails on, so that their breaches are annotated as errors
+type Thresholds struct {
+	// FailOnSeverity is the minimum severity of the vulnerabilities failing the command, empty when not failing on any
+	FailOnSeverity       string
+	FailOnKnownExploited bool
+	// MaxScanErrorRate is the maximum percentage of the images whose scan fails
+	MaxScanErrorRate float64
+}
+
+// ValidateFormat checks that the format is a supported annotation format
+func ValidateFormat(format string) error {
+	if format != FormatGitHub && format != FormatAzureDevOps {
+		return fmt.Errorf("unknown annotation format %q, must be %s or %s", format, FormatGitHub, FormatAzureDevOps)
+	}
+	return nil
+}
+
+// DetectFormat returns the annotation format of the CI the command runs in from its environment variables, empty when
+// the CI is not detected
+func DetectFormat(getenv func(string) string) string {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return FormatGitHub
+	case strings.EqualFold(getenv("TF_BUILD"), "true"):
+		return FormatAzureDevOps
+	default:
+		return ""
+	}
+}
+
+// ForReport returns the annotations of the breaches of the thresholds and of the severity budgets, as errors, and of
+// the remediation SLAs, as warnings
+func ForReport(report *scanner.VulnerabilityReport, thresholds Thresholds) []Annotation {
+	var annotations []Annotation
+	if thresholds.FailOnKnownExploited {
+		for _, finding := range report.KnownExploitedVulnerabilities() {
+			annotations = append(annotations, Annotation{
+				Level:   LevelError,
+				Title:   "Known exploited vulnerability",
+				Message: fmt.Sprintf("%s (%s) in %s is exploited in the wild", finding.Vulnerability.VulnerabilityID, finding.Vulnerability.PkgName, finding.ImageName),
+			})
+		}
+	}
+	for _, budget := range report.ExceededBudgets() {
+		annotations = append(annotations, Annotation{
+			Level:   LevelError,
+			Title:   "Severity budget exceeded",
+			Message: budget.String(),
+		})
+	}
+	if thresholds.FailOnSeverity != "" {
+		for _, image := range report.ScannedImages {
+			if count := image.CountWithMinSeverity(thresholds.FailOnSeverity); count > 0 {
+				annotations = append(annotations, Annotation{
+					Level:   LevelError,
+					Title:   fmt.Sprintf("%s vulnerabilities", thresholds.FailOnSeverity),
+					Message: fmt.Sprintf("%s has %d vulnerabilities of severity %s or higher", image.ImageName, count, thresholds.FailOnSeverity),
+				})
+			}
+		}
+	}
+	if rate := report.ScanErrorRate(); rate > thresholds.MaxScanErrorRate {
+		annotations = append(annotations, Annotation{
+			Level:   LevelError,
+			Title:   "Scan error rate exceeded",
+			Message: fmt.Sprintf("%d of %d images failed to scan (%.1f%%), exceeding the maximum of %.1f%%", report.FailedScanCount(), len(report.ScannedImages), rate, thresholds.MaxScanErrorRate),
+		})
+	}
+	for _, team := range report.SLABreaches() {
+		for _, breach := range team.Breaches {
+			annotations = append(annotations, Annotation{
+				Level: LevelWarning,
+				Title: "Remediation SLA breached",
+				Message: fmt.Sprintf("%s %s (%s) in %s of team %s found %d days ago, exceeding its SLA of %d days",
+					breach.Vulnerability.Severity, breach.Vulnerability.VulnerabilityID, breach.Vulnerability.PkgName, breach.ImageName, team.Team, breach.AgeDays, breach.SLADays),
+			})
+		}
+	}
+	return annotations
+}
+
+// Write writes the annotations as the commands of the CI of the format, one per line
+func Write(w io.Writer, format string, annotations []Annotation) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+	for _, annotation := range annotations {
+		var line string
+		if format == FormatGitHub {
+			line = fmt.Sprintf("::%s title=%s::%s\n", annotation.Level, escapeGitHubProperty(annotation.Title), escapeGitHubData(annotation.Message))
+		} else {
+			line = fmt.Sprintf("##vso[task.logissue type=%s]%s\n", annotation.Level, escapeAzureDevOps(annotation.Title+": "+annotation.Message))
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	gitHubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	gitHubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	azureDevOpsEscaper    = strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", ";", "%3B", "]", "%5D")
+)
+
+func escapeGitHubData(value string) string {
+	return gitHubDataEscaper.Replace(value)
+}
+
+func escapeGitHubProperty(value string) string {
+	return gitHubPropertyEscaper.Replace(value)
+}
+
+func escapeAzureDevOps(value string) string {
+	return azureDevOpsEscaper.Replace(value)
+}
@@ -0,0 +1,85 @@
+package annotations
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAnnotations(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Annotations Suite")
+}
+
+var _ = Describe("CI annotations", func() {
+
+	var report *scanner.VulnerabilityReport
+
+	BeforeEach(func() {
+		report = &scanner.VulnerabilityReport{
+			ScannedImages: []scanner.ScannedImage{
+				{
+					ImageName: "api:1",
+					TrivyOutputResults: []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{
+						{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL", KnownExploited: &scanner.KnownExploitedVulnerability{}},
+						{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "MEDIUM"},
+					}}},
+					VulnerabilitySummary: scanner.VulnerabilitySummary{TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": 1, "MEDIUM": 1}},
+				},
+				{
+					ImageName:            "web:1",
+					VulnerabilitySummary: scanner.VulnerabilitySummary{TotalVulnerabilityBySeverity: map[string]int{"LOW": 3}},
+				},
+				{ImageName: "db:1", ScanError: errors.New("timeout")},
+			},
+		}
+	})
+
+	It("annotates the breaches of the thresholds as errors", func() {
+		annotations := ForReport(report, Thresholds{FailOnSeverity: "HIGH", FailOnKnownExploited: true, MaxScanErrorRate: 10})
+
+		Expect(annotations).To(Equal([]Annotation{
+			{Level: LevelError, Title: "Known exploited vulnerability", Message: "CVE-1 (openssl) in api:1 is exploited in the wild"},
+			{Level: LevelError, Title: "HIGH vulnerabilities", Message: "api:1 has 1 vulnerabilities of severity HIGH or higher"},
+			{Level: LevelError, Title: "Scan error rate exceeded", Message: "1 of 3 images failed to scan (33.3%), exceeding the maximum of 10.0%"},
+		}))
+	})
+
+	It("does not annotate the thresholds the command does not fail on", func() {
+		Expect(ForReport(report, Thresholds{MaxScanErrorRate: 100})).To(BeEmpty())
+	})
+
+	It("writes GitHub Actions workflow commands", func() {
+		var output bytes.Buffer
+
+		Expect(Write(&output, FormatGitHub, []Annotation{
+			{Level: LevelError, Title: "Budget: exceeded, 100%", Message: "line 1\nline 2"},
+			{Level: LevelWarning, Title: "SLA", Message: "late"},
+		})).To(Succeed())
+
+		Expect(output.String()).To(Equal("::error title=Budget%3A exceeded%2C 100%25::line 1%0Aline 2\n::warning title=SLA::late\n"))
+	})
+
+	It("writes Azure DevOps logging commands", func() {
+		var output bytes.Buffer
+
+		Expect(Write(&output, FormatAzureDevOps, []Annotation{{Level: LevelError, Title: "Budget", Message: "100%; [x]"}})).To(Succeed())
+
+		Expect(output.String()).To(Equal("##vso[task.logissue type=error]Budget: 100%AZP25%3B [x%5D\n"))
+	})
+
+	It("detects the format of the CI from its environment", func() {
+		env := func(values map[string]string) func(string) string {
+			return func(name string) string { return values[name] }
+		}
+		Expect(DetectFormat(env(map[string]string{"GITHUB_ACTIONS": "true"}))).To(Equal(FormatGitHub))
+		Expect(DetectFormat(env(map[string]string{"TF_BUILD": "True"}))).To(Equal(FormatAzureDevOps))
+		Expect(DetectFormat(env(nil))).To(BeEmpty())
+		Expect(Write(&bytes.Buffer{}, "gitlab", nil)).To(MatchError(ContainSubstring("unknown annotation format")))
+	})
+})
@@ -0,0 +1,113 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// AvailabilityCheckName is the name of the replica count and autoscaling check
+const AvailabilityCheckName = "availability"
+
+// AutoscalingAnnotation is the pod annotation declaring that a workload is expected to scale with its load, so to be
+// the target of a HorizontalPodAutoscaler
+const AutoscalingAnnotation = "production-readiness/autoscaling"
+
+// Classes of availability issue, recorded in the status of the findings
+const (
+	AvailabilitySingleReplica = "single-replica"
+	AvailabilityNoAutoscaler  = "no-autoscaler"
+)
+
+type availabilityCheck struct {
+	kubernetesClient k8s.KubernetesClient
+}
+
+type namespaceAvailability struct {
+	services    []v1.Service
+	autoscalers []autoscalingv2.HorizontalPodAutoscaler
+}
+
+// NewAvailabilityCheck creates a check reporting the Deployments running a single pod behind a Service, unavailable
+// whenever their pod restarts, and the workloads annotated with AutoscalingAnnotation targeted by no
+// HorizontalPodAutoscaler
+func NewAvailabilityCheck(kubernetesClient k8s.KubernetesClient) Check {
+	return &availabilityCheck{kubernetesClient: kubernetesClient}
+}
+
+func (c *availabilityCheck) Name() string {
+	return AvailabilityCheckName
+}
+
+func (c *availabilityCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	availabilityByNamespace := make(map[string]*namespaceAvailability)
+	var findings []Finding
+	for _, workload := range workloads {
+		if workload.Kind != "Deployment" && workload.Kind != "StatefulSet" {
+			continue
+		}
+		availability, ok := availabilityByNamespace[workload.Namespace]
+		if !ok {
+			var err error
+			availability, err = c.listNamespaceAvailability(workload.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			availabilityByNamespace[workload.Namespace] = availability
+		}
+
+		if workload.Kind == "Deployment" && workload.PodCount == 1 {
+			if service := selectingService(workload, availability.services); service != "" {
+				finding := workloadFinding(workload, "MEDIUM", fmt.Sprintf("single replica serving the traffic of Service %s, the service is unavailable whenever the pod restarts or its node is drained", service))
+				finding.Status = AvailabilitySingleReplica
+				findings = append(findings, finding)
+			}
+		}
+		if workload.PodAnnotations[AutoscalingAnnotation] == "true" && !isAutoscaled(workload, availability.autoscalers) {
+			finding := workloadFinding(workload, "MEDIUM", fmt.Sprintf("annotated with %s but not targeted by any HorizontalPodAutoscaler, the replicas do not follow the load", AutoscalingAnnotation))
+			finding.Status = AvailabilityNoAutoscaler
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+func (c *availabilityCheck) listNamespaceAvailability(namespace string) (*namespaceAvailability, error) {
+	services, err := c.kubernetesClient.GetServices(namespace)
+	if err != nil {
+		return nil, err
+	}
+	autoscalers, err := c.kubernetesClient.GetHorizontalPodAutoscalers(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &namespaceAvailability{services: services, autoscalers: autoscalers}, nil
+}
+
+// selectingService returns the name of the first Service selecting the pods of the workload. The ExternalName
+// services and the services without selector, whose endpoints are managed separately, are ignored
+func selectingService(workload k8s.Workload, services []v1.Service) string {
+	for _, service := range services {
+		if service.Spec.Type == v1.ServiceTypeExternalName || len(service.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(workload.PodLabels)) {
+			return service.Name
+		}
+	}
+	return ""
+}
+
+// isAutoscaled returns true when a HorizontalPodAutoscaler targets the workload
+func isAutoscaled(workload k8s.Workload, autoscalers []autoscalingv2.HorizontalPodAutoscaler) bool {
+	for _, autoscaler := range autoscalers {
+		target := autoscaler.Spec.ScaleTargetRef
+		if target.Kind == workload.Kind && target.Name == workload.Name {
+			return true
+		}
+	}
+	return false
+}
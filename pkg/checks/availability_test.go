@@ -0,0 +1,77 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Availability check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		check                Check
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		check = NewAvailabilityCheck(mockKubernetesClient)
+	})
+
+	It("reports the single replica Deployments selected by a Service", func() {
+		mockKubernetesClient.On("GetServices", "shop").Return([]v1.Service{
+			{ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "shop"}, Spec: v1.ServiceSpec{Type: v1.ServiceTypeExternalName, Selector: map[string]string{"app": "worker"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "headless", Namespace: "shop"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}, Spec: v1.ServiceSpec{Selector: map[string]string{"app": "api"}}},
+		}, nil).Once()
+		mockKubernetesClient.On("GetHorizontalPodAutoscalers", "shop").Return([]autoscalingv2.HorizontalPodAutoscaler{}, nil).Once()
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "shop", PodCount: 1, PodLabels: map[string]string{"app": "api", "tier": "web"}},
+			{Kind: "Deployment", Name: "web", Namespace: "shop", PodCount: 2, PodLabels: map[string]string{"app": "api"}},
+			{Kind: "Deployment", Name: "worker", Namespace: "shop", PodCount: 1, PodLabels: map[string]string{"app": "worker"}},
+			{Kind: "DaemonSet", Name: "agent", Namespace: "shop", PodCount: 1, PodLabels: map[string]string{"app": "api"}},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{{
+			Severity:  "MEDIUM",
+			Namespace: "shop",
+			Kind:      "Deployment",
+			Workload:  "api",
+			Status:    AvailabilitySingleReplica,
+			Message:   "single replica serving the traffic of Service api, the service is unavailable whenever the pod restarts or its node is drained",
+		}}))
+	})
+
+	It("reports the workloads annotated to autoscale targeted by no HorizontalPodAutoscaler", func() {
+		mockKubernetesClient.On("GetServices", "shop").Return([]v1.Service{}, nil)
+		mockKubernetesClient.On("GetHorizontalPodAutoscalers", "shop").Return([]autoscalingv2.HorizontalPodAutoscaler{
+			{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}, Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "api"},
+			}},
+		}, nil)
+		autoscaling := map[string]string{AutoscalingAnnotation: "true"}
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "shop", PodCount: 2, PodAnnotations: autoscaling},
+			{Kind: "StatefulSet", Name: "api", Namespace: "shop", PodCount: 2, PodAnnotations: autoscaling},
+			{Kind: "Deployment", Name: "web", Namespace: "shop", PodCount: 2},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{{
+			Severity:  "MEDIUM",
+			Namespace: "shop",
+			Kind:      "StatefulSet",
+			Workload:  "api",
+			Status:    AvailabilityNoAutoscaler,
+			Message:   "annotated with production-readiness/autoscaling but not targeted by any HorizontalPodAutoscaler, the replicas do not follow the load",
+		}}))
+	})
+})
@@ -0,0 +1,131 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// Finding is a readiness issue found on a workload
+type Finding struct {
+	Check     string
+	Severity  string
+	Area      string
+	Team      string
+	Namespace string
+	Kind      string
+	Workload  string
+	// Container is empty when the finding applies to the whole workload
+	Container string
+	Message   string
+	// Status qualifies the findings of the checks reporting several classes of failure, for instance unsigned or
+	// invalid-signature for the image-signatures check. It is empty for the other checks
+	Status string `json:",omitempty"`
+}
+
+// Check inspects the cluster workloads and reports the readiness issues found
+type Check interface {
+	// Name identifies the check in the report
+	Name() string
+	// Run returns the findings for the workloads, the area and team of the findings are set by the Runner
+	Run(workloads []k8s.Workload) ([]Finding, error)
+}
+
+// Config is the config used to run the readiness checks
+type Config struct {
+	AreaLabels   string
+	TeamsLabels  string
+	FilterLabels string
+	// ImageScan is the result of the image scan given to the checks implementing ImageScanCheck, nil when the images
+	// were not scanned
+	ImageScan *scanner.VulnerabilityReport
+}
+
+// Runner runs the readiness checks against the cluster workloads
+type Runner struct {
+	config           *Config
+	kubernetesClient k8s.KubernetesClient
+	checks           []Check
+}
+
+// New creates a Runner for the checks
+func New(kubernetesClient k8s.KubernetesClient, config *Config, checks ...Check) *Runner {
+	return &Runner{
+		config:           config,
+		kubernetesClient: kubernetesClient,
+		checks:           checks,
+	}
+}
+
+// Run runs all the checks and groups the findings by area and team
+func (r *Runner) Run() (*ReadinessReport, error) {
+	workloads, err := r.kubernetesClient.GetWorkloadsInNamespaces(r.config.FilterLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceLabels := make(map[string]map[string]string)
+	namespaceSuppressions := make(map[string][]k8s.Suppression)
+	podLabels := make(map[string]map[string]string)
+	suppressions := k8s.NewSuppressionsInEffect(time.Now())
+	for _, workload := range workloads {
+		namespaceLabels[workload.Namespace] = workload.NamespaceLabels
+		namespaceSuppressions[workload.Namespace] = workload.Suppressions
+		podLabels[workload.Namespace+"/"+workload.Kind+"/"+workload.Name] = workload.PodLabels
+		suppressions.Declare(workload.Suppressions)
+	}
+
+	report := &ReadinessReport{WorkloadCount: len(workloads), WorkloadCountByTeam: make(map[string]int), CheckCategories: make(map[string]string)}
+	for _, workload := range workloads {
+		area := labelValue(r.config.AreaLabels, workload.PodLabels, workload.NamespaceLabels)
+		team := labelValue(r.config.TeamsLabels, workload.PodLabels, workload.NamespaceLabels)
+		report.WorkloadCountByTeam[area+"/"+team]++
+	}
+	for _, check := range r.checks {
+		logr.Infof("Running %s check", check.Name())
+		if imageScanCheck, ok := check.(ImageScanCheck); ok {
+			imageScanCheck.UseImageScan(r.config.ImageScan)
+		}
+		findings, err := check.Run(workloads)
+		if err != nil {
+			return nil, fmt.Errorf("error running %s check: %v", check.Name(), err)
+		}
+		for _, finding := range findings {
+			finding.Check = check.Name()
+			if suppression, ok := k8s.MatchingSuppression(namespaceSuppressions[finding.Namespace], finding.Check, time.Now()); ok {
+				suppressions.Count(suppression)
+				continue
+			}
+			labels := podLabels[finding.Namespace+"/"+finding.Kind+"/"+finding.Workload]
+			finding.Area = labelValue(r.config.AreaLabels, labels, namespaceLabels[finding.Namespace])
+			finding.Team = labelValue(r.config.TeamsLabels, labels, namespaceLabels[finding.Namespace])
+			report.Findings = append(report.Findings, finding)
+		}
+		report.Checks = append(report.Checks, check.Name())
+		report.CheckCategories[check.Name()] = checkCategory(check)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severityScores[report.Findings[i].Severity] > severityScores[report.Findings[j].Severity]
+	})
+	report.AreaSummary = groupFindingsByArea(report.Findings)
+	report.Suppressions = suppressions.List()
+	return report, nil
+}
+
+// labelValue returns the value of the first label set on the workload pods or else on the namespace, see
+// k8s.LabelValue, or 'all' when not set as done for the image scan
+func labelValue(labelNames string, podLabels, namespaceLabels map[string]string) string {
+	if value := k8s.LabelValue(labelNames, podLabels, namespaceLabels); value != "" {
+		return value
+	}
+	return "all"
+}
+
+var severityScores = map[string]int{
+	"CRITICAL": 5, "HIGH": 4, "MEDIUM": 3, "LOW": 2, "UNKNOWN": 1,
+}
@@ -0,0 +1,111 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestChecks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Checks Suite")
+}
+
+var _ = Describe("Runner", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		workloads            []k8s.Workload
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		workloads = []k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "team-a", NamespaceLabels: map[string]string{"area": "payments", "team": "a"}},
+			{Kind: "Deployment", Name: "web", Namespace: "team-b", NamespaceLabels: map[string]string{"area": "payments"}},
+		}
+		mockKubernetesClient.On("GetWorkloadsInNamespaces", "env=prod").Return(workloads, nil)
+	})
+
+	It("attributes the findings to the area and team of the workload namespace", func() {
+		runner := New(mockKubernetesClient, &Config{AreaLabels: "area", TeamsLabels: "team", FilterLabels: "env=prod"},
+			&fakeCheck{name: "fake", findings: []Finding{
+				{Namespace: "team-a", Workload: "api", Severity: "LOW"},
+				{Namespace: "team-b", Workload: "web", Severity: "HIGH"},
+			}})
+
+		report, err := runner.Run()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Checks).To(Equal([]string{"fake"}))
+		Expect(report.WorkloadCount).To(Equal(2))
+		Expect(report.WorkloadCountByTeam).To(Equal(map[string]int{"payments/a": 1, "payments/all": 1}))
+		Expect(report.Findings).To(HaveLen(2))
+		Expect(report.Findings[0].Workload).To(Equal("web"))
+		Expect(report.Findings[0].Check).To(Equal("fake"))
+		Expect(report.AreaSummary).To(HaveLen(1))
+		Expect(report.AreaSummary["payments"].FindingCountByCheck["fake"]).To(Equal(2))
+		Expect(report.AreaSummary["payments"].Teams["a"].Findings[0].Workload).To(Equal("api"))
+		Expect(report.AreaSummary["payments"].Teams["all"].FindingsFor("fake")).To(HaveLen(1))
+	})
+
+	It("attributes the findings to the first label set on the workload pods or else on the namespace", func() {
+		workloads[0].PodLabels = map[string]string{"app.kubernetes.io/team": "api-team"}
+		runner := New(mockKubernetesClient, &Config{AreaLabels: "area", TeamsLabels: "team,app.kubernetes.io/team", FilterLabels: "env=prod"},
+			&fakeCheck{name: "fake", findings: []Finding{
+				{Namespace: "team-a", Kind: "Deployment", Workload: "api", Severity: "LOW"},
+				{Namespace: "team-a", Kind: "Service", Workload: "api", Severity: "LOW"},
+			}})
+
+		report, err := runner.Run()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Findings[0].Team).To(Equal("api-team"))
+		Expect(report.Findings[1].Team).To(Equal("a"))
+		Expect(report.Findings[1].Area).To(Equal("payments"))
+	})
+
+	It("leaves out the findings of the checks the namespace suppresses and lists the suppressions", func() {
+		workloads[0].Suppressions = k8s.NamespaceSuppressions("team-a", map[string]string{k8s.SuppressAnnotation: "fake, image-*"})
+		runner := New(mockKubernetesClient, &Config{AreaLabels: "area", TeamsLabels: "team", FilterLabels: "env=prod"},
+			&fakeCheck{name: "fake", findings: []Finding{
+				{Namespace: "team-a", Workload: "api", Severity: "LOW"},
+				{Namespace: "team-b", Workload: "web", Severity: "HIGH"},
+			}})
+
+		report, err := runner.Run()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Findings).To(HaveLen(1))
+		Expect(report.Findings[0].Namespace).To(Equal("team-b"))
+		Expect(report.Suppressions).To(HaveLen(2))
+		Expect(report.Suppressions[0].Pattern).To(Equal("fake"))
+		Expect(report.Suppressions[0].SuppressedCount).To(Equal(1))
+		Expect(report.Suppressions[1].Pattern).To(Equal("image-*"))
+		Expect(report.Suppressions[1].SuppressedCount).To(Equal(0))
+	})
+
+	It("returns the findings of at least the minimum severity", func() {
+		report := &ReadinessReport{Findings: []Finding{{Workload: "api", Severity: "LOW"}, {Workload: "web", Severity: "CRITICAL"}, {Workload: "db", Severity: "HIGH"}}}
+
+		Expect(report.FindingsWithMinSeverity("HIGH")).To(Equal([]Finding{report.Findings[1], report.Findings[2]}))
+		Expect(report.FindingsWithMinSeverity("")).To(HaveLen(3))
+	})
+})
+
+type fakeCheck struct {
+	name     string
+	findings []Finding
+}
+
+func (c *fakeCheck) Name() string {
+	return c.name
+}
+
+func (c *fakeCheck) Run(_ []k8s.Workload) ([]Finding, error) {
+	return c.findings, nil
+}
@@ -0,0 +1,132 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/releases"
+	logr "github.com/sirupsen/logrus"
+)
+
+// ComponentVersionsCheckName is the name of the cluster component version currency check
+const ComponentVersionsCheckName = "component-versions"
+
+// addOn is a cluster add-on recognised by the repository of its image, and released on GitHub
+type addOn struct {
+	name, gitHubRepository, tagPrefix string
+}
+
+// addOns are the add-ons by image repository suffix, the registry of the images varying with the installation
+var addOns = map[string]addOn{
+	"/coredns/coredns":          {"CoreDNS", "coredns/coredns", "v"},
+	"/ingress-nginx/controller": {"ingress-nginx controller", "kubernetes/ingress-nginx", "controller-v"},
+	"/calico/node":              {"Calico", "projectcalico/calico", "v"},
+	"/cilium/cilium":            {"Cilium", "cilium/cilium", "v"},
+	"/flannel/flannel":          {"Flannel", "flannel-io/flannel", "v"},
+}
+
+// latestReleases looks up the latest patch releases, see releases.Client
+type latestReleases interface {
+	LatestKubernetesPatch(version releases.Version) (releases.Version, error)
+	LatestGitHubPatch(repository, tagPrefix string, version releases.Version) (releases.Version, error)
+}
+
+type componentVersionsCheck struct {
+	kubernetesClient k8s.KubernetesClient
+	releases         latestReleases
+}
+
+// NewComponentVersionsCheck creates a check reporting the control plane, the kubelets and the key add-ons, CoreDNS,
+// ingress-nginx and the Calico, Cilium or Flannel CNI, running an older patch release than the latest one of their
+// release line. The add-ons are looked up in all the namespaces of the cluster. The components whose latest release
+// cannot be looked up are skipped
+func NewComponentVersionsCheck(kubernetesClient k8s.KubernetesClient, releases *releases.Client) Check {
+	return &componentVersionsCheck{kubernetesClient: kubernetesClient, releases: releases}
+}
+
+func (c *componentVersionsCheck) Name() string {
+	return ComponentVersionsCheckName
+}
+
+func (c *componentVersionsCheck) Run(_ []k8s.Workload) ([]Finding, error) {
+	var findings []Finding
+	if serverVersion, err := c.kubernetesClient.GetServerVersion(); err != nil {
+		logr.Warnf("Unable to check the version of the control plane: %v", err)
+	} else if message, outdated := c.outdatedKubernetes("Kubernetes control plane runs "+serverVersion, serverVersion); outdated {
+		findings = append(findings, Finding{Severity: "MEDIUM", Kind: "Cluster", Workload: "control-plane", Message: message})
+	}
+
+	nodes, err := c.kubernetesClient.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	nodeCounts := make(map[string]int)
+	for _, node := range nodes {
+		nodeCounts[node.Status.NodeInfo.KubeletVersion]++
+	}
+	var kubeletVersions []string
+	for version := range nodeCounts {
+		kubeletVersions = append(kubeletVersions, version)
+	}
+	sort.Strings(kubeletVersions)
+	for _, version := range kubeletVersions {
+		subject := fmt.Sprintf("kubelet runs %s on %d nodes", version, nodeCounts[version])
+		if message, outdated := c.outdatedKubernetes(subject, version); outdated {
+			findings = append(findings, Finding{Severity: "MEDIUM", Kind: "Node", Message: message})
+		}
+	}
+
+	workloads, err := c.kubernetesClient.GetWorkloadsInNamespaces("")
+	if err != nil {
+		return nil, err
+	}
+	return append(findings, imageFindings(workloads, c.outdatedAddOn)...), nil
+}
+
+// outdatedKubernetes returns the message of the finding when the Kubernetes version is not the latest patch release
+func (c *componentVersionsCheck) outdatedKubernetes(subject, version string) (string, bool) {
+	current, err := releases.ParseVersion(version)
+	if err != nil {
+		logr.Warnf("Unable to check the version of %s: %v", subject, err)
+		return "", false
+	}
+	latest, err := c.releases.LatestKubernetesPatch(current)
+	if err != nil {
+		logr.Warnf("Unable to check the version of %s: %v", subject, err)
+		return "", false
+	}
+	if current.Patch >= latest.Patch {
+		return "", false
+	}
+	return fmt.Sprintf("%s, older than the latest patch release %s of Kubernetes %s", subject, latest, current.MinorRelease()), true
+}
+
+// outdatedAddOn returns the finding when the image is the image of an add-on older than its latest patch release
+func (c *componentVersionsCheck) outdatedAddOn(image string) (Finding, bool) {
+	repository := imageRepositoryPath(image)
+	for suffix, addOn := range addOns {
+		if !strings.HasSuffix(repository, suffix) {
+			continue
+		}
+		name, _, _ := strings.Cut(image, "@")
+		tag := imageTag(name)
+		current, err := releases.ParseVersion(tag)
+		if err != nil {
+			logr.Warnf("Unable to check the version of %s image %s: %v", addOn.name, image, err)
+			return Finding{}, false
+		}
+		latest, err := c.releases.LatestGitHubPatch(addOn.gitHubRepository, addOn.tagPrefix, current)
+		if err != nil {
+			logr.Warnf("Unable to check the version of %s image %s: %v", addOn.name, image, err)
+			return Finding{}, false
+		}
+		if current.Patch >= latest.Patch {
+			return Finding{}, false
+		}
+		return Finding{Severity: "MEDIUM", Message: fmt.Sprintf("%s runs %s, older than the latest patch release %s of %s %s",
+			addOn.name, tag, latest, addOn.name, current.MinorRelease())}, true
+	}
+	return Finding{}, false
+}
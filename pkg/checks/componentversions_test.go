@@ -0,0 +1,104 @@
+package checks
+
+import (
+	"errors"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/releases"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Component versions check", func() {
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		latest               *fakeReleases
+		check                Check
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		latest = &fakeReleases{latest: map[string]releases.Version{
+			"kubernetes/1.27":                          {Major: 1, Minor: 27, Patch: 16},
+			"kubernetes/1.26":                          {Major: 1, Minor: 26, Patch: 15},
+			"coredns/coredns/1.10":                     {Major: 1, Minor: 10, Patch: 1},
+			"kubernetes/ingress-nginx/controller-v1.8": {Major: 1, Minor: 8, Patch: 5},
+		}}
+		check = &componentVersionsCheck{kubernetesClient: mockKubernetesClient, releases: latest}
+		mockKubernetesClient.On("GetWorkloadsInNamespaces", "").Return([]k8s.Workload{
+			{Kind: "Deployment", Name: "coredns", Namespace: "kube-system", PodSpec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "coredns", Image: "registry.k8s.io/coredns/coredns:v1.10.1"},
+			}}},
+			{Kind: "Deployment", Name: "ingress-nginx-controller", Namespace: "ingress-nginx", PodSpec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "controller", Image: "registry.k8s.io/ingress-nginx/controller:v1.8.1@sha256:e5c4824e"},
+			}}},
+			{Kind: "DaemonSet", Name: "cilium", Namespace: "kube-system", PodSpec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "cilium-agent", Image: "quay.io/cilium/cilium:v1.14.2"},
+			}}},
+			{Kind: "Deployment", Name: "api", Namespace: "payments", PodSpec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "api", Image: "registry.example.com/payments/api:1.2.0"},
+			}}},
+		}, nil)
+	})
+
+	It("reports the control plane, kubelets and add-ons older than the latest patch release", func() {
+		mockKubernetesClient.On("GetServerVersion").Return("v1.27.3-gke.100", nil)
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{
+			node("node-a", "v1.27.3-gke.100"), node("node-b", "v1.26.15"), node("node-c", "v1.27.3-gke.100"),
+		}, nil)
+
+		findings, err := check.Run(nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "MEDIUM", Kind: "Cluster", Workload: "control-plane", Message: "Kubernetes control plane runs v1.27.3-gke.100, older than the latest patch release v1.27.16 of Kubernetes 1.27"},
+			{Severity: "MEDIUM", Kind: "Node", Message: "kubelet runs v1.27.3-gke.100 on 2 nodes, older than the latest patch release v1.27.16 of Kubernetes 1.27"},
+			{Severity: "MEDIUM", Namespace: "ingress-nginx", Kind: "Deployment", Workload: "ingress-nginx-controller", Container: "controller", Message: "ingress-nginx controller runs v1.8.1, older than the latest patch release v1.8.5 of ingress-nginx controller 1.8"},
+		}))
+	})
+
+	It("skips the control plane when its version is not available", func() {
+		mockKubernetesClient.On("GetServerVersion").Return("", errors.New("no cluster version for manifests"))
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{}, nil)
+
+		findings, err := check.Run(nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Workload).To(Equal("ingress-nginx-controller"))
+	})
+})
+
+func node(name, kubeletVersion string) v1.Node {
+	return v1.Node{
+		ObjectMeta: metaV1.ObjectMeta{Name: name},
+		Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KubeletVersion: kubeletVersion}},
+	}
+}
+
+// fakeReleases returns the latest releases by release line, the current version when unknown
+type fakeReleases struct {
+	latest map[string]releases.Version
+}
+
+func (f *fakeReleases) LatestKubernetesPatch(version releases.Version) (releases.Version, error) {
+	return f.lookup("kubernetes/"+version.MinorRelease(), version)
+}
+
+func (f *fakeReleases) LatestGitHubPatch(repository, tagPrefix string, version releases.Version) (releases.Version, error) {
+	return f.lookup(repository+"/"+tagPrefix+version.MinorRelease(), version)
+}
+
+func (f *fakeReleases) lookup(key string, version releases.Version) (releases.Version, error) {
+	if latest, ok := f.latest[key]; ok {
+		return latest, nil
+	}
+	if key == "cilium/cilium/v1.14" {
+		return releases.Version{}, errors.New("rate limit exceeded")
+	}
+	return version, nil
+}
@@ -0,0 +1,72 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/trivyoperator"
+)
+
+// ConfigAuditCheckName is the name of the Trivy Operator config audit check
+const ConfigAuditCheckName = "config-audit"
+
+// configAuditReports lists the config audit reports of a namespace, see trivyoperator.Client
+type configAuditReports interface {
+	ConfigAuditReports(namespace string) ([]trivyoperator.ConfigAuditReport, error)
+}
+
+type configAuditCheck struct {
+	client configAuditReports
+}
+
+// NewConfigAuditCheck creates a check reporting the failed checks of the config audit reports Trivy Operator produced
+// for the cluster resources, the counterpart of the misconfiguration check for the clusters running Trivy Operator.
+// Only the resources of the namespaces of the workloads are reported
+func NewConfigAuditCheck(client *trivyoperator.Client) Check {
+	return &configAuditCheck{client: client}
+}
+
+func (c *configAuditCheck) Name() string {
+	return ConfigAuditCheckName
+}
+
+func (c *configAuditCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	namespaces := make(map[string]bool)
+	for _, workload := range workloads {
+		namespaces[workload.Namespace] = true
+	}
+	var sortedNamespaces []string
+	for namespace := range namespaces {
+		sortedNamespaces = append(sortedNamespaces, namespace)
+	}
+	sort.Strings(sortedNamespaces)
+
+	var findings []Finding
+	for _, namespace := range sortedNamespaces {
+		reports, err := c.client.ConfigAuditReports(namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, report := range reports {
+			for _, check := range report.Report.Checks {
+				if check.Success {
+					continue
+				}
+				message := fmt.Sprintf("%s %s", check.CheckID, check.Title)
+				if len(check.Messages) > 0 {
+					message += ": " + strings.Join(check.Messages, ", ")
+				}
+				findings = append(findings, Finding{
+					Severity:  check.Severity,
+					Namespace: namespace,
+					Kind:      report.Labels[trivyoperator.ResourceKindLabel],
+					Workload:  report.Labels[trivyoperator.ResourceNameLabel],
+					Message:   message,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,63 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/trivyoperator"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeConfigAuditReports map[string][]trivyoperator.ConfigAuditReport
+
+func (f fakeConfigAuditReports) ConfigAuditReports(namespace string) ([]trivyoperator.ConfigAuditReport, error) {
+	if namespace == "broken" {
+		return nil, fmt.Errorf("configauditreports is forbidden")
+	}
+	return f[namespace], nil
+}
+
+var _ = Describe("Config audit check", func() {
+
+	configAuditReport := func(kind, name string, checks ...trivyoperator.ConfigAuditCheck) trivyoperator.ConfigAuditReport {
+		report := trivyoperator.ConfigAuditReport{ObjectMeta: metaV1.ObjectMeta{Labels: map[string]string{
+			trivyoperator.ResourceKindLabel: kind,
+			trivyoperator.ResourceNameLabel: name,
+		}}}
+		report.Report.Checks = checks
+		return report
+	}
+
+	It("reports the failed checks of the namespaces of the workloads", func() {
+		check := &configAuditCheck{client: fakeConfigAuditReports{
+			"payments": {configAuditReport("ReplicaSet", "api-7c9b",
+				trivyoperator.ConfigAuditCheck{CheckID: "KSV001", Title: "Process can elevate its own privileges", Severity: "MEDIUM",
+					Messages: []string{"Container 'api' should set 'securityContext.allowPrivilegeEscalation' to false"}},
+				trivyoperator.ConfigAuditCheck{CheckID: "KSV003", Title: "Default capabilities not dropped", Severity: "LOW", Success: true},
+			)},
+			"kube-system": {configAuditReport("DaemonSet", "kube-proxy", trivyoperator.ConfigAuditCheck{CheckID: "KSV009", Severity: "HIGH"})},
+		}}
+
+		findings, err := check.Run([]k8s.Workload{{Namespace: "payments", Kind: "Deployment", Name: "api"}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{{
+			Severity:  "MEDIUM",
+			Namespace: "payments",
+			Kind:      "ReplicaSet",
+			Workload:  "api-7c9b",
+			Message:   "KSV001 Process can elevate its own privileges: Container 'api' should set 'securityContext.allowPrivilegeEscalation' to false",
+		}}))
+	})
+
+	It("returns the error of the reports that cannot be listed", func() {
+		check := &configAuditCheck{client: fakeConfigAuditReports{}}
+
+		_, err := check.Run([]k8s.Workload{{Namespace: "broken", Kind: "Deployment", Name: "api"}})
+
+		Expect(err).To(MatchError("configauditreports is forbidden"))
+	})
+})
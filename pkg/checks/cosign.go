@@ -0,0 +1,122 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+// SignaturePolicy holds the cosign public keys and keyless identities the image signatures are verified against,
+// an image being verified when one of them verifies one of its signatures
+type SignaturePolicy struct {
+	// PublicKeys are the paths, urls or KMS URIs of the cosign public keys, for instance cosign.pub or awskms:///alias/signing
+	PublicKeys []string
+	Identities []KeylessIdentity
+}
+
+// KeylessIdentity is the identity of the keyless signatures, the Fulcio certificate identity and its OIDC issuer
+type KeylessIdentity struct {
+	// Issuer is the OIDC issuer of the identity, for instance https://token.actions.githubusercontent.com
+	Issuer string
+	// Identity is the regular expression the certificate identity must match, for instance the workflows of a repository
+	Identity string
+}
+
+// ParseKeylessIdentity parses a keyless identity with the format '<issuer>=<identity regexp>', for instance
+// https://token.actions.githubusercontent.com=^https://github.com/example/.*
+func ParseKeylessIdentity(value string) (KeylessIdentity, error) {
+	issuer, identity, found := strings.Cut(value, "=")
+	if !found || issuer == "" || identity == "" {
+		return KeylessIdentity{}, fmt.Errorf("invalid keyless identity %q, expected format '<issuer>=<identity regexp>'", value)
+	}
+	return KeylessIdentity{Issuer: issuer, Identity: identity}, nil
+}
+
+// cosignVerifier verifies the signatures, or the signed attestations, of the images with cosign against the keys and
+// identities of a policy
+type cosignVerifier struct {
+	policy        *SignaturePolicy
+	commandRunner execCmd.CommandRunner
+	lookPath      func(file string) (string, error)
+}
+
+func newCosignVerifier(policy *SignaturePolicy) *cosignVerifier {
+	return &cosignVerifier{policy: policy, commandRunner: execCmd.NewCommandRunner(), lookPath: exec.LookPath}
+}
+
+// Outcomes of a cosign verification
+const (
+	cosignVerified = iota
+	// cosignMissing is the outcome of the images without any signature or attestation to verify
+	cosignMissing
+	// cosignInvalid is the outcome of the images whose signatures are not verified by any key or identity of the policy
+	cosignInvalid
+)
+
+// cosignResult is the outcome of the verification of an image, with the output of cosign when verified, and the
+// cosign error of the last failed verification otherwise
+type cosignResult struct {
+	outcome int
+	output  []byte
+	reason  string
+}
+
+// missingMarkers are the cosign errors of the images without signature or attestation
+var missingMarkers = []string{"no signatures found", "no attestations found", "none of the attestations matched the predicate type"}
+
+// validate fails when the policy has nothing to verify the images against or the cosign binary is not installed, so
+// that a misconfiguration is reported once rather than as a finding per image
+func (v *cosignVerifier) validate() error {
+	if len(v.policy.PublicKeys) == 0 && len(v.policy.Identities) == 0 {
+		return errors.New("no cosign public key nor keyless identity to verify the images against")
+	}
+	if _, err := v.lookPath("cosign"); err != nil {
+		return fmt.Errorf("cosign is required to verify the images: %v", err)
+	}
+	return nil
+}
+
+// verify runs the cosign command, for instance verify, with each key and identity of the policy until one verifies the image
+func (v *cosignVerifier) verify(image string, command ...string) cosignResult {
+	var reason string
+	for _, args := range v.verifyArgs() {
+		args = append(append(append([]string{}, command...), args...), image)
+		output, errOutput, err := v.commandRunner.Execute("cosign", args)
+		if err == nil {
+			return cosignResult{outcome: cosignVerified, output: output}
+		}
+		reason = lastLine(utils.ConvertByteToString(errOutput))
+		for _, marker := range missingMarkers {
+			if strings.Contains(reason, marker) {
+				// the other keys and identities would not find any either
+				return cosignResult{outcome: cosignMissing, reason: reason}
+			}
+		}
+		if reason == "" {
+			reason = err.Error()
+		}
+	}
+	return cosignResult{outcome: cosignInvalid, reason: reason}
+}
+
+// verifyArgs returns the cosign arguments of each key and identity of the policy
+func (v *cosignVerifier) verifyArgs() [][]string {
+	var args [][]string
+	for _, key := range v.policy.PublicKeys {
+		args = append(args, []string{"--key", key})
+	}
+	for _, identity := range v.policy.Identities {
+		args = append(args, []string{"--certificate-oidc-issuer", identity.Issuer, "--certificate-identity-regexp", identity.Identity})
+	}
+	return args
+}
+
+// lastLine returns the last non-empty line of the output, cosign printing the cause of the failure last
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,117 @@
+package checks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// DeprecatedAPICheckName is the name of the deprecated API usage check
+const DeprecatedAPICheckName = "deprecated-api"
+
+// deprecatedAPI describes an API version deprecated or removed in a Kubernetes release
+type deprecatedAPI struct {
+	deprecatedIn, removedIn, replacement string
+}
+
+// deprecatedAPIs are the deprecated API versions by apiVersion and kind,
+// see https://kubernetes.io/docs/reference/using-api/deprecation-guide/
+var deprecatedAPIs = map[string]deprecatedAPI{
+	"extensions/v1beta1/Deployment":                   {"1.9", "1.16", "apps/v1"},
+	"extensions/v1beta1/DaemonSet":                    {"1.9", "1.16", "apps/v1"},
+	"extensions/v1beta1/NetworkPolicy":                {"1.9", "1.16", "networking.k8s.io/v1"},
+	"extensions/v1beta1/Ingress":                      {"1.14", "1.22", "networking.k8s.io/v1"},
+	"apps/v1beta1/Deployment":                         {"1.9", "1.16", "apps/v1"},
+	"apps/v1beta1/StatefulSet":                        {"1.9", "1.16", "apps/v1"},
+	"apps/v1beta2/Deployment":                         {"1.9", "1.16", "apps/v1"},
+	"apps/v1beta2/StatefulSet":                        {"1.9", "1.16", "apps/v1"},
+	"apps/v1beta2/DaemonSet":                          {"1.9", "1.16", "apps/v1"},
+	"networking.k8s.io/v1beta1/Ingress":               {"1.19", "1.22", "networking.k8s.io/v1"},
+	"batch/v1beta1/CronJob":                           {"1.21", "1.25", "batch/v1"},
+	"policy/v1beta1/PodDisruptionBudget":              {"1.21", "1.25", "policy/v1"},
+	"autoscaling/v2beta1/HorizontalPodAutoscaler":     {"1.22", "1.25", "autoscaling/v2"},
+	"autoscaling/v2beta2/HorizontalPodAutoscaler":     {"1.23", "1.26", "autoscaling/v2"},
+	"flowcontrol.apiserver.k8s.io/v1beta1/FlowSchema": {"1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+}
+
+type deprecatedAPICheck struct {
+	kubernetesClient k8s.KubernetesClient
+	targetVersion    string
+}
+
+// NewDeprecatedAPICheck creates a check reporting the resources managed with deprecated or removed API versions.
+// Removals are evaluated against the target version when set, the cluster version otherwise
+func NewDeprecatedAPICheck(kubernetesClient k8s.KubernetesClient, targetVersion string) Check {
+	return &deprecatedAPICheck{kubernetesClient: kubernetesClient, targetVersion: targetVersion}
+}
+
+func (c *deprecatedAPICheck) Name() string {
+	return DeprecatedAPICheckName
+}
+
+func (c *deprecatedAPICheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	version := c.targetVersion
+	if version == "" {
+		var err error
+		version, err = c.kubernetesClient.GetServerVersion()
+		if err != nil {
+			return nil, err
+		}
+	}
+	target, err := parseMinorVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	checked := make(map[string]bool)
+	for _, workload := range workloads {
+		if checked[workload.Namespace] {
+			continue
+		}
+		checked[workload.Namespace] = true
+
+		resources, err := c.kubernetesClient.GetResourceAPIVersions(workload.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			for _, apiVersion := range resource.APIVersions {
+				api, ok := deprecatedAPIs[apiVersion+"/"+resource.Kind]
+				if !ok {
+					continue
+				}
+				finding := Finding{
+					Namespace: resource.Namespace,
+					Kind:      resource.Kind,
+					Workload:  resource.Name,
+				}
+				removedIn, _ := parseMinorVersion(api.removedIn)
+				if removedIn <= target {
+					finding.Severity = "HIGH"
+					finding.Message = fmt.Sprintf("%s is removed in %s, migrate to %s", apiVersion, api.removedIn, api.replacement)
+				} else {
+					finding.Severity = "MEDIUM"
+					finding.Message = fmt.Sprintf("%s is deprecated since %s and removed in %s, migrate to %s", apiVersion, api.deprecatedIn, api.removedIn, api.replacement)
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// parseMinorVersion returns the minor version of a 1.x Kubernetes version, for instance 25 for v1.25.3-gke.1
+func parseMinorVersion(version string) (int, error) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, fmt.Errorf("unsupported Kubernetes version %q", version)
+	}
+	minor, err := strconv.Atoi(strings.TrimRight(parts[1], "+"))
+	if err != nil {
+		return 0, fmt.Errorf("unsupported Kubernetes version %q: %v", version, err)
+	}
+	return minor, nil
+}
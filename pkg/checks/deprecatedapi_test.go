@@ -0,0 +1,58 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deprecated API check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		workloads            []k8s.Workload
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		workloads = []k8s.Workload{
+			{Name: "api", Namespace: "ns"},
+			{Name: "worker", Namespace: "ns"},
+		}
+		mockKubernetesClient.On("GetResourceAPIVersions", "ns").Return([]k8s.ResourceAPIVersions{
+			{Kind: "CronJob", Name: "cleanup", Namespace: "ns", APIVersions: []string{"batch/v1beta1", "batch/v1"}},
+			{Kind: "HorizontalPodAutoscaler", Name: "api", Namespace: "ns", APIVersions: []string{"autoscaling/v2beta2"}},
+			{Kind: "Deployment", Name: "api", Namespace: "ns", APIVersions: []string{"apps/v1"}},
+		}, nil).Once()
+	})
+
+	It("reports the resources using APIs removed or deprecated in the cluster version", func() {
+		mockKubernetesClient.On("GetServerVersion").Return("v1.25.3-gke.100", nil)
+
+		findings, err := NewDeprecatedAPICheck(mockKubernetesClient, "").Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Namespace: "ns", Kind: "CronJob", Workload: "cleanup", Message: "batch/v1beta1 is removed in 1.25, migrate to batch/v1"},
+			{Severity: "MEDIUM", Namespace: "ns", Kind: "HorizontalPodAutoscaler", Workload: "api", Message: "autoscaling/v2beta2 is deprecated since 1.23 and removed in 1.26, migrate to autoscaling/v2"},
+		}))
+		mockKubernetesClient.AssertNumberOfCalls(GinkgoT(), "GetResourceAPIVersions", 1)
+	})
+
+	It("evaluates the removals against the target version when set", func() {
+		findings, err := NewDeprecatedAPICheck(mockKubernetesClient, "1.26").Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(2))
+		Expect(findings[1].Severity).To(Equal("HIGH"))
+		mockKubernetesClient.AssertNotCalled(GinkgoT(), "GetServerVersion")
+	})
+
+	It("rejects invalid target versions", func() {
+		_, err := NewDeprecatedAPICheck(mockKubernetesClient, "latest").Run(workloads)
+
+		Expect(err).To(MatchError(ContainSubstring(`unsupported Kubernetes version "latest"`)))
+	})
+})
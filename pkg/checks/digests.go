@@ -0,0 +1,152 @@
+package checks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/signing"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ApprovedDigestsCheckName is the name of the digest allowlist check
+const ApprovedDigestsCheckName = "approved-digests"
+
+// Statuses of the approved-digests findings
+const (
+	// DigestUnapproved is the status of the containers running an image digest missing from the allowlist
+	DigestUnapproved = "unapproved-digest"
+	// DigestUnknown is the status of the containers whose image digest is not known, their pods not running yet or
+	// the image of the manifests being referenced by tag
+	DigestUnknown = "unknown-digest"
+)
+
+// digestPattern matches the digests of the OCI image spec, algorithm and encoded hash
+var digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+// DigestAllowlist is the signed allowlist of the image digests approved to run, for instance produced by the build
+// system. Each line holds a digest, for instance sha256:4ff3ca91..., approved for any repository, or a digest approved
+// for a single repository, for instance registry.example.com/payments/api@sha256:4ff3ca91..., the empty lines and the
+// lines starting with # being ignored
+type DigestAllowlist struct {
+	File string
+	// SignatureFile is the signature of the allowlist file, verified before the allowlist is read
+	SignatureFile string
+	Verifier      signing.Verifier
+}
+
+type approvedDigestsCheck struct {
+	allowlist DigestAllowlist
+}
+
+// NewApprovedDigestsCheck creates a check reporting the containers running an image digest missing from the signed
+// allowlist, as such images were not approved by the build system, or whose running digest is not known
+func NewApprovedDigestsCheck(allowlist DigestAllowlist) Check {
+	return &approvedDigestsCheck{allowlist: allowlist}
+}
+
+func (c *approvedDigestsCheck) Name() string {
+	return ApprovedDigestsCheckName
+}
+
+func (c *approvedDigestsCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	approved, err := c.allowlist.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, workload := range workloads {
+		containers := append([]v1.Container{}, workload.PodSpec.InitContainers...)
+		for _, container := range append(containers, workload.PodSpec.Containers...) {
+			digests := workload.ImageDigests[container.Name]
+			if _, digest, found := strings.Cut(container.Image, "@"); found && len(digests) == 0 {
+				digests = []string{digest}
+			}
+			if len(digests) == 0 {
+				finding := workloadFinding(workload, "MEDIUM", fmt.Sprintf("image %s has no known digest to check against the digest allowlist", container.Image))
+				finding.Status = DigestUnknown
+				finding.Container = container.Name
+				findings = append(findings, finding)
+				continue
+			}
+			for _, digest := range digests {
+				if approved.contains(container.Image, digest) {
+					continue
+				}
+				finding := workloadFinding(workload, "HIGH", fmt.Sprintf("image %s runs digest %s, which is not in the digest allowlist", container.Image, digest))
+				finding.Status = DigestUnapproved
+				finding.Container = container.Name
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// approvedDigests holds the digests approved for any repository and the digests approved per repository
+type approvedDigests struct {
+	anyRepository map[string]bool
+	byRepository  map[string]map[string]bool
+}
+
+// contains returns true when the digest is approved for the repository of the image
+func (a approvedDigests) contains(image, digest string) bool {
+	return a.anyRepository[digest] || a.byRepository[imageRepositoryPath(image)][digest]
+}
+
+// load verifies the signature of the allowlist and reads its digests
+func (l DigestAllowlist) load() (approvedDigests, error) {
+	if l.File == "" {
+		return approvedDigests{}, errors.New("no digest allowlist to check the images against")
+	}
+	if l.Verifier == nil {
+		return approvedDigests{}, errors.New("no key to verify the signature of the digest allowlist")
+	}
+	signatureFile := l.SignatureFile
+	if signatureFile == "" {
+		signatureFile = signing.SignatureFilename(l.File)
+	}
+	if err := l.Verifier.Verify(l.File, signatureFile); err != nil {
+		return approvedDigests{}, fmt.Errorf("digest allowlist %s: %v", l.File, err)
+	}
+
+	file, err := os.Open(l.File)
+	if err != nil {
+		return approvedDigests{}, fmt.Errorf("unable to read the digest allowlist: %v", err)
+	}
+	defer file.Close()
+
+	approved := approvedDigests{anyRepository: make(map[string]bool), byRepository: make(map[string]map[string]bool)}
+	lines := bufio.NewScanner(file)
+	for lineNumber := 1; lines.Scan(); lineNumber++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		repository, digest, found := strings.Cut(line, "@")
+		if !found {
+			repository, digest = "", line
+		}
+		if !digestPattern.MatchString(digest) {
+			return approvedDigests{}, fmt.Errorf("invalid digest allowlist %s, line %d: expected a digest such as sha256:4ff3ca91... but got %q", l.File, lineNumber, line)
+		}
+		if repository == "" {
+			approved.anyRepository[digest] = true
+			continue
+		}
+		repository = imageRepositoryPath(repository)
+		if approved.byRepository[repository] == nil {
+			approved.byRepository[repository] = make(map[string]bool)
+		}
+		approved.byRepository[repository][digest] = true
+	}
+	if err := lines.Err(); err != nil {
+		return approvedDigests{}, fmt.Errorf("unable to read the digest allowlist: %v", err)
+	}
+	return approved, nil
+}
@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Approved digests check", func() {
+
+	var (
+		allowlist DigestAllowlist
+		verifier  *fakeVerifier
+	)
+
+	BeforeEach(func() {
+		verifier = &fakeVerifier{}
+		allowlist = DigestAllowlist{File: filepath.Join(GinkgoT().TempDir(), "digests.txt"), Verifier: verifier}
+		Expect(os.WriteFile(allowlist.File, []byte(`# digests built by the pipeline
+sha256:aaa
+registry.example.com/payments/api@sha256:bbb
+
+nginx@sha256:ccc
+`), 0644)).To(Succeed())
+	})
+
+	It("reports the containers running a digest missing from the allowlist", func() {
+		workloads := []k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "payments",
+				PodSpec: v1.PodSpec{
+					InitContainers: []v1.Container{{Name: "migrate", Image: "registry.example.com/payments/migrate:1.0"}},
+					Containers: []v1.Container{
+						{Name: "api", Image: "registry.example.com/payments/api:1.2"},
+						{Name: "proxy", Image: "nginx:1.25"},
+						{Name: "sidecar", Image: "registry.example.com/payments/sidecar:0.1"},
+					},
+				},
+				ImageDigests: map[string][]string{"migrate": {"sha256:bbb"}, "api": {"sha256:bbb", "sha256:ddd"}, "proxy": {"sha256:ccc"}},
+			},
+			{Kind: "Deployment", Name: "web", Namespace: "shop", PodSpec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "web", Image: "registry.example.com/shop/web@sha256:aaa"},
+				{Name: "cache", Image: "redis@sha256:eee"},
+			}}},
+		}
+
+		findings, err := NewApprovedDigestsCheck(allowlist).Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.verified).To(Equal([]string{allowlist.File, allowlist.File + ".sig"}))
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Status: DigestUnapproved, Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "migrate", Message: "image registry.example.com/payments/migrate:1.0 runs digest sha256:bbb, which is not in the digest allowlist"},
+			{Severity: "HIGH", Status: DigestUnapproved, Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "api", Message: "image registry.example.com/payments/api:1.2 runs digest sha256:ddd, which is not in the digest allowlist"},
+			{Severity: "MEDIUM", Status: DigestUnknown, Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "sidecar", Message: "image registry.example.com/payments/sidecar:0.1 has no known digest to check against the digest allowlist"},
+			{Severity: "HIGH", Status: DigestUnapproved, Namespace: "shop", Kind: "Deployment", Workload: "web", Container: "cache", Message: "image redis@sha256:eee runs digest sha256:eee, which is not in the digest allowlist"},
+		}))
+	})
+
+	It("fails when the signature of the allowlist is not verified", func() {
+		verifier.err = errors.New("invalid signature")
+		allowlist.SignatureFile = "digests.txt.signature"
+
+		_, err := NewApprovedDigestsCheck(allowlist).Run(nil)
+
+		Expect(err).To(MatchError(ContainSubstring("invalid signature")))
+		Expect(verifier.verified).To(Equal([]string{allowlist.File, "digests.txt.signature"}))
+	})
+
+	It("fails when the allowlist holds an invalid line", func() {
+		Expect(os.WriteFile(allowlist.File, []byte("registry.example.com/payments/api:1.2\n"), 0644)).To(Succeed())
+
+		_, err := NewApprovedDigestsCheck(allowlist).Run(nil)
+
+		Expect(err).To(MatchError(ContainSubstring("line 1")))
+	})
+
+	It("fails without allowlist or key", func() {
+		_, err := NewApprovedDigestsCheck(DigestAllowlist{}).Run(nil)
+		Expect(err).To(HaveOccurred())
+
+		_, err = NewApprovedDigestsCheck(DigestAllowlist{File: allowlist.File}).Run(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+type fakeVerifier struct {
+	verified []string
+	err      error
+}
+
+func (v *fakeVerifier) Verify(filename, signatureFilename string) error {
+	v.verified = append(v.verified, filename, signatureFilename)
+	return v.err
+}
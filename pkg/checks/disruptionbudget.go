@@ -0,0 +1,73 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DisruptionBudgetCheckName is the name of the PodDisruptionBudget coverage check
+const DisruptionBudgetCheckName = "disruption-budget"
+
+type disruptionBudgetCheck struct {
+	kubernetesClient k8s.KubernetesClient
+}
+
+// NewDisruptionBudgetCheck creates a check reporting the Deployments and StatefulSets running more than one pod not
+// selected by any PodDisruptionBudget, all their pods may be evicted at once when the nodes are drained
+func NewDisruptionBudgetCheck(kubernetesClient k8s.KubernetesClient) Check {
+	return &disruptionBudgetCheck{kubernetesClient: kubernetesClient}
+}
+
+func (c *disruptionBudgetCheck) Name() string {
+	return DisruptionBudgetCheckName
+}
+
+func (c *disruptionBudgetCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	budgetsByNamespace := make(map[string][]policyv1.PodDisruptionBudget)
+	var findings []Finding
+	for _, workload := range workloads {
+		if (workload.Kind != "Deployment" && workload.Kind != "StatefulSet") || workload.PodCount < 2 {
+			continue
+		}
+		budgets, ok := budgetsByNamespace[workload.Namespace]
+		if !ok {
+			var err error
+			budgets, err = c.kubernetesClient.GetPodDisruptionBudgets(workload.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			budgetsByNamespace[workload.Namespace] = budgets
+		}
+
+		selected, err := isSelectedByDisruptionBudget(workload, budgets)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			findings = append(findings, workloadFinding(workload, "MEDIUM", fmt.Sprintf("%d replicas without PodDisruptionBudget, all of them may be evicted at once when the nodes are drained", workload.PodCount)))
+		}
+	}
+	return findings, nil
+}
+
+// isSelectedByDisruptionBudget returns true when a budget selects the pods of the workload. The budgets without
+// selector select no pod and the budgets with an empty selector select all the pods of the namespace
+func isSelectedByDisruptionBudget(workload k8s.Workload, budgets []policyv1.PodDisruptionBudget) (bool, error) {
+	for _, budget := range budgets {
+		if budget.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid selector in PodDisruptionBudget %s/%s: %v", budget.Namespace, budget.Name, err)
+		}
+		if selector.Matches(labels.Set(workload.PodLabels)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
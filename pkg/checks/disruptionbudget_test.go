@@ -0,0 +1,60 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Disruption budget check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		check                Check
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		check = NewDisruptionBudgetCheck(mockKubernetesClient)
+	})
+
+	It("reports the replicated Deployments and StatefulSets not selected by any PodDisruptionBudget", func() {
+		mockKubernetesClient.On("GetPodDisruptionBudgets", "shop").Return([]policyv1.PodDisruptionBudget{
+			{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}, Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "none", Namespace: "shop"}},
+		}, nil).Once()
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "shop", PodCount: 3, PodLabels: map[string]string{"app": "api"}},
+			{Kind: "StatefulSet", Name: "db", Namespace: "shop", PodCount: 2, PodLabels: map[string]string{"app": "db"}},
+			{Kind: "Deployment", Name: "worker", Namespace: "shop", PodCount: 1, PodLabels: map[string]string{"app": "worker"}},
+			{Kind: "DaemonSet", Name: "agent", Namespace: "shop", PodCount: 5},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{{
+			Severity:  "MEDIUM",
+			Namespace: "shop",
+			Kind:      "StatefulSet",
+			Workload:  "db",
+			Message:   "2 replicas without PodDisruptionBudget, all of them may be evicted at once when the nodes are drained",
+		}}))
+	})
+
+	It("considers the pods of the namespace selected by the budgets with an empty selector", func() {
+		mockKubernetesClient.On("GetPodDisruptionBudgets", "shop").Return([]policyv1.PodDisruptionBudget{
+			{ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "shop"}, Spec: policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}}},
+		}, nil)
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "shop", PodCount: 2}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+})
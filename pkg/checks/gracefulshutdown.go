@@ -0,0 +1,94 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// GracefulShutdownCheckName is the name of the graceful shutdown check
+const GracefulShutdownCheckName = "graceful-shutdown"
+
+type gracefulShutdownCheck struct {
+	kubernetesClient k8s.KubernetesClient
+}
+
+// NewGracefulShutdownCheck creates a check reporting the containers of the workloads behind an Ingress without preStop
+// hook whose pods keep the default termination grace period or set it to zero. Such containers are stopped while the
+// ingress controller still routes requests to them, dropping the in-flight requests during rollouts.
+// Jobs and CronJobs are ignored as their pods run to completion
+func NewGracefulShutdownCheck(kubernetesClient k8s.KubernetesClient) Check {
+	return &gracefulShutdownCheck{kubernetesClient: kubernetesClient}
+}
+
+func (c *gracefulShutdownCheck) Name() string {
+	return GracefulShutdownCheckName
+}
+
+func (c *gracefulShutdownCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	ingressServicesByNamespace := make(map[string][]v1.Service)
+	var findings []Finding
+	for _, workload := range workloads {
+		if workload.Kind == "Job" || workload.Kind == "CronJob" {
+			continue
+		}
+		ingressServices, ok := ingressServicesByNamespace[workload.Namespace]
+		if !ok {
+			var err error
+			ingressServices, err = c.listIngressServices(workload.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			ingressServicesByNamespace[workload.Namespace] = ingressServices
+		}
+		service := selectingService(workload, ingressServices)
+		if service == "" {
+			continue
+		}
+
+		severity, gracePeriod := "MEDIUM", "the default termination grace period"
+		if period := workload.PodSpec.TerminationGracePeriodSeconds; period != nil && *period == 0 {
+			severity, gracePeriod = "HIGH", "a zero termination grace period"
+		} else if period != nil && *period != v1.DefaultTerminationGracePeriodSeconds {
+			continue
+		}
+		for _, container := range workload.PodSpec.Containers {
+			if container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+				continue
+			}
+			finding := workloadFinding(workload, severity, fmt.Sprintf("no preStop hook and %s behind the Ingress of Service %s, in-flight requests are dropped when the pod stops during rollouts", gracePeriod, service))
+			finding.Container = container.Name
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// listIngressServices returns the Services of the namespace its Ingresses route to
+func (c *gracefulShutdownCheck) listIngressServices(namespace string) ([]v1.Service, error) {
+	ingresses, err := c.kubernetesClient.GetIngresses(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(ingresses) == 0 {
+		return nil, nil
+	}
+	backends := make(map[string]bool)
+	for _, ingress := range ingresses {
+		for _, name := range k8s.IngressServiceNames(ingress) {
+			backends[name] = true
+		}
+	}
+	services, err := c.kubernetesClient.GetServices(namespace)
+	if err != nil {
+		return nil, err
+	}
+	var ingressServices []v1.Service
+	for _, service := range services {
+		if backends[service.Name] {
+			ingressServices = append(ingressServices, service)
+		}
+	}
+	return ingressServices, nil
+}
@@ -0,0 +1,86 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Graceful shutdown check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		check                Check
+	)
+
+	int64Ptr := func(i int64) *int64 { return &i }
+	podSpec := func(gracePeriod *int64, lifecycle *v1.Lifecycle) v1.PodSpec {
+		return v1.PodSpec{TerminationGracePeriodSeconds: gracePeriod, Containers: []v1.Container{{Name: "main", Lifecycle: lifecycle}}}
+	}
+	preStop := &v1.Lifecycle{PreStop: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"sleep", "10"}}}}
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		check = NewGracefulShutdownCheck(mockKubernetesClient)
+	})
+
+	It("reports the containers behind an Ingress without preStop hook and with the default or zero grace period", func() {
+		mockKubernetesClient.On("GetIngresses", "shop").Return([]networkingv1.Ingress{
+			{ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"}, Spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "web"}},
+				Rules: []networkingv1.IngressRule{{IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Path: "/api", Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "api"}}}},
+				}}}},
+			}},
+		}, nil).Once()
+		mockKubernetesClient.On("GetServices", "shop").Return([]v1.Service{
+			{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}, Spec: v1.ServiceSpec{Selector: map[string]string{"app": "api"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}, Spec: v1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "shop"}, Spec: v1.ServiceSpec{Selector: map[string]string{"app": "internal"}}},
+		}, nil).Once()
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "shop", PodLabels: map[string]string{"app": "api"}, PodSpec: podSpec(int64Ptr(30), nil)},
+			{Kind: "Deployment", Name: "web", Namespace: "shop", PodLabels: map[string]string{"app": "web"}, PodSpec: podSpec(int64Ptr(0), nil)},
+			{Kind: "Deployment", Name: "hooked", Namespace: "shop", PodLabels: map[string]string{"app": "api"}, PodSpec: podSpec(nil, preStop)},
+			{Kind: "Deployment", Name: "patient", Namespace: "shop", PodLabels: map[string]string{"app": "api"}, PodSpec: podSpec(int64Ptr(60), nil)},
+			{Kind: "Deployment", Name: "internal", Namespace: "shop", PodLabels: map[string]string{"app": "internal"}, PodSpec: podSpec(nil, nil)},
+			{Kind: "Job", Name: "migrate", Namespace: "shop", PodLabels: map[string]string{"app": "api"}, PodSpec: podSpec(nil, nil)},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{
+				Severity:  "MEDIUM",
+				Namespace: "shop",
+				Kind:      "Deployment",
+				Workload:  "api",
+				Container: "main",
+				Message:   "no preStop hook and the default termination grace period behind the Ingress of Service api, in-flight requests are dropped when the pod stops during rollouts",
+			},
+			{
+				Severity:  "HIGH",
+				Namespace: "shop",
+				Kind:      "Deployment",
+				Workload:  "web",
+				Container: "main",
+				Message:   "no preStop hook and a zero termination grace period behind the Ingress of Service web, in-flight requests are dropped when the pod stops during rollouts",
+			},
+		}))
+	})
+
+	It("does not list the services of the namespaces without Ingress", func() {
+		mockKubernetesClient.On("GetIngresses", "shop").Return([]networkingv1.Ingress{}, nil)
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "shop", PodSpec: podSpec(nil, nil)}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+		mockKubernetesClient.AssertNotCalled(GinkgoT(), "GetServices", "shop")
+	})
+})
@@ -0,0 +1,63 @@
+package checks
+
+import (
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// ImageTagsCheckName is the name of the image reference check
+const ImageTagsCheckName = "image-tags"
+
+type imageTagsCheck struct{}
+
+// NewImageTagsCheck creates a check reporting the containers running images by mutable tags.
+// Mutable tags undermine rollbacks and provenance as the image behind the tag can change
+func NewImageTagsCheck(_ k8s.KubernetesClient) Check {
+	return &imageTagsCheck{}
+}
+
+func (c *imageTagsCheck) Name() string {
+	return ImageTagsCheckName
+}
+
+func (c *imageTagsCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	var findings []Finding
+	for _, workload := range workloads {
+		for _, container := range workload.PodSpec.Containers {
+			severity, message := imageReferenceIssue(container.Image)
+			if message == "" {
+				continue
+			}
+			finding := workloadFinding(workload, severity, message)
+			finding.Container = container.Name
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// imageReferenceIssue returns the severity and description of the issue with the image reference, or an empty message when pinned by digest
+func imageReferenceIssue(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return "", ""
+	}
+	tag := imageTag(image)
+	switch tag {
+	case "":
+		return "HIGH", "image " + image + " has no tag, the latest tag is used"
+	case "latest":
+		return "HIGH", "image " + image + " uses the latest tag"
+	default:
+		return "LOW", "image " + image + " is deployed by tag rather than digest"
+	}
+}
+
+// imageTag returns the tag of the image reference, the registry port is not mistaken for a tag
+func imageTag(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
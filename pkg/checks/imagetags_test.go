@@ -0,0 +1,41 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image tags check", func() {
+
+	var (
+		check Check
+	)
+
+	BeforeEach(func() {
+		check = NewImageTagsCheck(nil)
+	})
+
+	It("reports images deployed by mutable tags", func() {
+		spec := v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "untagged", Image: "registry:5000/team/app"},
+				{Name: "latest", Image: "alpine:latest"},
+				{Name: "tagged", Image: "registry:5000/team/app:1.2.3"},
+				{Name: "digest", Image: "alpine@sha256:c0e9560cda118f9ec63ddefb4a173a2b2a0347082d7dff7dc14272e7841a5b5a"},
+				{Name: "tagged-digest", Image: "alpine:3.18@sha256:c0e9560cda118f9ec63ddefb4a173a2b2a0347082d7dff7dc14272e7841a5b5a"},
+			},
+		}
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "ns", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "untagged", Message: "image registry:5000/team/app has no tag, the latest tag is used"},
+			{Severity: "HIGH", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "latest", Message: "image alpine:latest uses the latest tag"},
+			{Severity: "LOW", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "tagged", Message: "image registry:5000/team/app:1.2.3 is deployed by tag rather than digest"},
+		}))
+	})
+})
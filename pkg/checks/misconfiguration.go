@@ -0,0 +1,104 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+// MisconfigurationCheckName is the name of the trivy misconfiguration check
+const MisconfigurationCheckName = "misconfiguration"
+
+type misconfigurationCheck struct {
+	command        string
+	kubeContext    string
+	kubeconfigPath string
+	db             scanner.TrivyDBConfig
+	extraArgs      []string
+	policies       scanner.RegoPolicies
+	commandRunner  execCmd.CommandRunner
+}
+
+// trivyKubernetesOutput is an object representation of the trivy kubernetes scan json report
+type trivyKubernetesOutput struct {
+	Resources []struct {
+		Namespace string
+		Kind      string
+		Name      string
+		Results   []struct {
+			Misconfigurations []struct {
+				ID       string
+				Title    string
+				Message  string
+				Severity string
+				Status   string
+			}
+		}
+	}
+}
+
+// NewMisconfigurationCheck creates a check reporting the misconfigurations trivy finds in the live cluster resources,
+// such as Deployments, Services or Ingresses, running the trivy binary at the trivy path with the db and the extra
+// arguments of the image scans. The resources are evaluated against the custom Rego policies in addition to the trivy
+// built-in checks. Only the resources of the namespaces of the workloads are reported
+func NewMisconfigurationCheck(kubeContext, kubeconfigPath, trivyPath string, db scanner.TrivyDBConfig, extraArgs []string, policies scanner.RegoPolicies) Check {
+	return &misconfigurationCheck{command: trivyPath, kubeContext: kubeContext, kubeconfigPath: kubeconfigPath, db: db, extraArgs: extraArgs, policies: policies, commandRunner: execCmd.NewCommandRunner()}
+}
+
+func (c *misconfigurationCheck) Name() string {
+	return MisconfigurationCheckName
+}
+
+func (c *misconfigurationCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	namespaces := make(map[string]bool)
+	for _, workload := range workloads {
+		namespaces[workload.Namespace] = true
+	}
+	if len(namespaces) == 0 {
+		return nil, nil
+	}
+
+	args := c.db.KubernetesArgs("--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--scanners", "misconfig", "--report", "all")
+	if c.kubeContext != "" {
+		args = append(args, "--context", c.kubeContext)
+	}
+	if c.kubeconfigPath != "" {
+		args = append(args, "--kubeconfig", c.kubeconfigPath)
+	}
+	args = append(append(append(args, c.policies.TrivyArgs()...), c.extraArgs...), "cluster")
+	output, errOutput, err := c.commandRunner.Execute(c.command, args)
+	if err != nil {
+		return nil, fmt.Errorf("error while running trivy kubernetes scan. Error output: %s, Error: %v", utils.ConvertByteToString(errOutput), err)
+	}
+
+	var trivyOutput trivyKubernetesOutput
+	if err := json.Unmarshal(output, &trivyOutput); err != nil {
+		return nil, fmt.Errorf("error while decoding trivy kubernetes scan output: %v", err)
+	}
+
+	var findings []Finding
+	for _, resource := range trivyOutput.Resources {
+		if !namespaces[resource.Namespace] {
+			continue
+		}
+		for _, result := range resource.Results {
+			for _, misconfiguration := range result.Misconfigurations {
+				if misconfiguration.Status != "FAIL" {
+					continue
+				}
+				findings = append(findings, Finding{
+					Severity:  misconfiguration.Severity,
+					Namespace: resource.Namespace,
+					Kind:      resource.Kind,
+					Workload:  resource.Name,
+					Message:   fmt.Sprintf("%s %s: %s", misconfiguration.ID, misconfiguration.Title, misconfiguration.Message),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,111 @@
+package checks
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Misconfiguration check", func() {
+
+	var (
+		mockRunner *mockCommandRunner
+		check      *misconfigurationCheck
+		workloads  []k8s.Workload
+		trivyArgs  []string
+	)
+
+	BeforeEach(func() {
+		mockRunner = &mockCommandRunner{}
+		check = &misconfigurationCheck{command: "trivy", kubeContext: "sandbox", commandRunner: mockRunner}
+		workloads = []k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "payments"}}
+		trivyArgs = []string{"--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--scanners", "misconfig", "--report", "all", "--skip-java-db-update", "--context", "sandbox", "cluster"}
+	})
+
+	It("reports the failed misconfigurations of the resources in the workload namespaces", func() {
+		output := `{"ClusterName":"sandbox","Resources":[
+			{"Namespace":"payments","Kind":"Deployment","Name":"api","Results":[{"Misconfigurations":[
+				{"ID":"KSV001","Title":"Process can elevate its own privileges","Message":"Container 'api' should set 'securityContext.allowPrivilegeEscalation' to false","Severity":"MEDIUM","Status":"FAIL"},
+				{"ID":"KSV003","Title":"Default capabilities not dropped","Message":"Container 'api' should add 'ALL' to 'securityContext.capabilities.drop'","Severity":"LOW","Status":"PASS"}
+			]}]},
+			{"Namespace":"payments","Kind":"Service","Name":"api","Results":[{"Misconfigurations":[
+				{"ID":"KSV108","Title":"Service of type NodePort","Message":"Service 'api' is of type NodePort","Severity":"HIGH","Status":"FAIL"}
+			]}]},
+			{"Namespace":"kube-system","Kind":"DaemonSet","Name":"kube-proxy","Results":[{"Misconfigurations":[
+				{"ID":"KSV017","Title":"Privileged container","Message":"Container 'kube-proxy' should set 'securityContext.privileged' to false","Severity":"HIGH","Status":"FAIL"}
+			]}]}
+		]}`
+		mockRunner.On("Execute", "trivy", trivyArgs).Return([]byte(output), []byte{}, nil)
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "MEDIUM", Namespace: "payments", Kind: "Deployment", Workload: "api", Message: "KSV001 Process can elevate its own privileges: Container 'api' should set 'securityContext.allowPrivilegeEscalation' to false"},
+			{Severity: "HIGH", Namespace: "payments", Kind: "Service", Workload: "api", Message: "KSV108 Service of type NodePort: Service 'api' is of type NodePort"},
+		}))
+	})
+
+	It("reports the failures of the custom Rego policies", func() {
+		check.policies = scanner.RegoPolicies{Dirs: []string{"policies"}, Namespaces: []string{"user"}}
+		output := `{"Resources":[{"Namespace":"payments","Kind":"Deployment","Name":"api","Results":[{"Misconfigurations":[
+			{"ID":"ORG001","Title":"Missing cost centre label","Message":"Deployment 'api' should set the 'cost-centre' label","Severity":"LOW","Status":"FAIL"}
+		]}]}]}`
+		mockRunner.On("Execute", "trivy", []string{"--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--scanners", "misconfig", "--report", "all", "--skip-java-db-update", "--context", "sandbox", "--config-policy", "policies", "--policy-namespaces", "user", "cluster"}).
+			Return([]byte(output), []byte{}, nil)
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "LOW", Namespace: "payments", Kind: "Deployment", Workload: "api", Message: "ORG001 Missing cost centre label: Deployment 'api' should set the 'cost-centre' label"},
+		}))
+	})
+
+	It("scans with the db and the extra arguments of the image scans", func() {
+		check.db = scanner.TrivyDBConfig{SkipUpdate: true, CacheDir: "/var/lib/trivy", Repository: "mirror.example.com/aquasecurity/trivy-db"}
+		check.extraArgs = []string{"--offline-scan"}
+		mockRunner.On("Execute", "trivy", []string{"--cache-dir", "/var/lib/trivy", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--scanners", "misconfig", "--report", "all", "--skip-java-db-update", "--skip-db-update", "--skip-policy-update", "--db-repository", "mirror.example.com/aquasecurity/trivy-db", "--context", "sandbox", "--offline-scan", "cluster"}).
+			Return([]byte(`{"Resources":[]}`), []byte{}, nil)
+
+		_, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockRunner.AssertExpectations(GinkgoT())
+	})
+
+	It("does not run trivy without workloads", func() {
+		findings, err := check.Run(nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+		mockRunner.AssertNotCalled(GinkgoT(), "Execute", mock.Anything, mock.Anything)
+	})
+
+	It("returns the error when trivy fails", func() {
+		mockRunner.On("Execute", "trivy", trivyArgs).Return([]byte{}, []byte("unable to connect"), errors.New("exit status 1"))
+
+		_, err := check.Run(workloads)
+
+		Expect(err).To(MatchError(ContainSubstring("unable to connect")))
+	})
+})
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
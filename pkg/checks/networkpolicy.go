@@ -0,0 +1,95 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NetworkPolicyCheckName is the name of the NetworkPolicy coverage check
+const NetworkPolicyCheckName = "network-policy"
+
+type networkPolicyCheck struct {
+	kubernetesClient k8s.KubernetesClient
+}
+
+// NewNetworkPolicyCheck creates a check reporting the workloads not selected by any ingress NetworkPolicy,
+// these workloads accept traffic from any source
+func NewNetworkPolicyCheck(kubernetesClient k8s.KubernetesClient) Check {
+	return &networkPolicyCheck{kubernetesClient: kubernetesClient}
+}
+
+func (c *networkPolicyCheck) Name() string {
+	return NetworkPolicyCheckName
+}
+
+func (c *networkPolicyCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	policiesByNamespace := make(map[string][]networkingv1.NetworkPolicy)
+	var findings []Finding
+	for _, workload := range workloads {
+		policies, ok := policiesByNamespace[workload.Namespace]
+		if !ok {
+			var err error
+			policies, err = c.kubernetesClient.GetNetworkPolicies(workload.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			policiesByNamespace[workload.Namespace] = policies
+		}
+
+		if len(policies) == 0 {
+			findings = append(findings, workloadFinding(workload, "HIGH", "no NetworkPolicy in the namespace, the workload accepts traffic from any source"))
+			continue
+		}
+		selected, err := isSelectedByIngressPolicy(workload, policies)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			findings = append(findings, workloadFinding(workload, "MEDIUM", "not selected by any ingress NetworkPolicy, the workload accepts traffic from any source"))
+		}
+	}
+	return findings, nil
+}
+
+func isSelectedByIngressPolicy(workload k8s.Workload, policies []networkingv1.NetworkPolicy) (bool, error) {
+	for _, policy := range policies {
+		if !isIngressPolicy(policy) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid pod selector in NetworkPolicy %s/%s: %v", policy.Namespace, policy.Name, err)
+		}
+		if selector.Matches(labels.Set(workload.PodLabels)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isIngressPolicy returns true when the policy restricts ingress traffic. Policies without policy types always apply to ingress
+func isIngressPolicy(policy networkingv1.NetworkPolicy) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		if policyType == networkingv1.PolicyTypeIngress {
+			return true
+		}
+	}
+	return false
+}
+
+func workloadFinding(workload k8s.Workload, severity, message string) Finding {
+	return Finding{
+		Severity:  severity,
+		Namespace: workload.Namespace,
+		Kind:      workload.Kind,
+		Workload:  workload.Name,
+		Message:   message,
+	}
+}
@@ -0,0 +1,85 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NetworkPolicy check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		check                Check
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		check = NewNetworkPolicyCheck(mockKubernetesClient)
+	})
+
+	It("reports the workloads of namespaces without NetworkPolicy", func() {
+		mockKubernetesClient.On("GetNetworkPolicies", "open").Return([]networkingv1.NetworkPolicy{}, nil).Once()
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "open"},
+			{Kind: "StatefulSet", Name: "db", Namespace: "open"},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(2))
+		Expect(findings[0]).To(Equal(Finding{
+			Severity:  "HIGH",
+			Namespace: "open",
+			Kind:      "Deployment",
+			Workload:  "api",
+			Message:   "no NetworkPolicy in the namespace, the workload accepts traffic from any source",
+		}))
+		mockKubernetesClient.AssertNumberOfCalls(GinkgoT(), "GetNetworkPolicies", 1)
+	})
+
+	It("reports the workloads not selected by an ingress NetworkPolicy", func() {
+		mockKubernetesClient.On("GetNetworkPolicies", "restricted").Return([]networkingv1.NetworkPolicy{
+			networkPolicy(map[string]string{"app": "api"}, networkingv1.PolicyTypeIngress),
+			networkPolicy(map[string]string{"app": "worker"}, networkingv1.PolicyTypeEgress),
+			networkPolicy(map[string]string{"app": "db"}),
+		}, nil)
+
+		findings, err := check.Run([]k8s.Workload{
+			{Name: "api", Namespace: "restricted", PodLabels: map[string]string{"app": "api"}},
+			{Name: "worker", Namespace: "restricted", PodLabels: map[string]string{"app": "worker"}},
+			{Name: "db", Namespace: "restricted", PodLabels: map[string]string{"app": "db"}},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Workload).To(Equal("worker"))
+		Expect(findings[0].Severity).To(Equal("MEDIUM"))
+	})
+
+	It("considers a policy with an empty pod selector to select all the workloads", func() {
+		mockKubernetesClient.On("GetNetworkPolicies", "default-deny").Return([]networkingv1.NetworkPolicy{
+			networkPolicy(nil, networkingv1.PolicyTypeIngress),
+		}, nil)
+
+		findings, err := check.Run([]k8s.Workload{
+			{Name: "api", Namespace: "default-deny", PodLabels: map[string]string{"app": "api"}},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+})
+
+func networkPolicy(podLabels map[string]string, policyTypes ...networkingv1.PolicyType) networkingv1.NetworkPolicy {
+	return networkingv1.NetworkPolicy{
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			PolicyTypes: policyTypes,
+		},
+	}
+}
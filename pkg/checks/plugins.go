@@ -0,0 +1,194 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+	logr "github.com/sirupsen/logrus"
+)
+
+const (
+	// MisconfigurationCategory and WorkloadCategory are the categories of the checks, the findings of the former
+	// counting as misconfigurations in the readiness score and those of the latter as workload check findings
+	MisconfigurationCategory = "misconfiguration"
+	WorkloadCategory         = "workload"
+)
+
+// CategorizedCheck is a check declaring its category, MisconfigurationCategory or WorkloadCategory. The checks not
+// implementing it are workload checks, except the built-in misconfiguration checks
+type CategorizedCheck interface {
+	Check
+	// Category is the category of the check, read once the check has run
+	Category() string
+}
+
+// ImageScanCheck is a check also inspecting the results of the image scan, given by the Runner before the check runs
+type ImageScanCheck interface {
+	Check
+	// UseImageScan gives the results of the image scan to the check, nil when the images were not scanned
+	UseImageScan(imageScan *scanner.VulnerabilityReport)
+}
+
+// CheckFactory creates a check for the client of the cluster checked
+type CheckFactory func(kubernetesClient k8s.KubernetesClient) Check
+
+var registeredChecks = make(map[string]CheckFactory)
+
+// Register makes a check available under its name, so that organisations can add their own readiness checks by
+// importing the package registering them in its init function, as done for the database/sql drivers. It panics when a
+// check is registered twice under the same name
+func Register(name string, factory CheckFactory) {
+	if _, ok := registeredChecks[name]; ok {
+		panic(fmt.Sprintf("check %s is already registered", name))
+	}
+	registeredChecks[name] = factory
+}
+
+// RegisteredChecks returns the checks made available with Register, by name
+func RegisteredChecks() map[string]CheckFactory {
+	checks := make(map[string]CheckFactory, len(registeredChecks))
+	for name, factory := range registeredChecks {
+		checks[name] = factory
+	}
+	return checks
+}
+
+// checkCategory returns the category of the check
+func checkCategory(check Check) string {
+	if categorized, ok := check.(CategorizedCheck); ok {
+		if categorized.Category() == MisconfigurationCategory {
+			return MisconfigurationCategory
+		}
+		return WorkloadCategory
+	}
+	if misconfigurationChecks[check.Name()] {
+		return MisconfigurationCategory
+	}
+	return WorkloadCategory
+}
+
+// PluginInput is the json document given to the exec plugins, see NewExecCheck
+type PluginInput struct {
+	Check     string
+	Workloads []k8s.Workload
+	// ImageScan is the result of the image scan, nil when the images were not scanned
+	ImageScan *scanner.VulnerabilityReport
+}
+
+// PluginOutput is the json document the exec plugins write to their standard output, see NewExecCheck
+type PluginOutput struct {
+	// Category is the category of the check, WorkloadCategory when empty
+	Category string
+	// Findings are the findings of the check, their check, area and team being set by the Runner
+	Findings []Finding
+}
+
+type execCheck struct {
+	name          string
+	command       string
+	category      string
+	timeout       time.Duration
+	imageScan     *scanner.VulnerabilityReport
+	commandRunner execCmd.CommandRunner
+}
+
+// NewExecCheck creates a check running an executable plugin, so that organisations can add their own readiness checks
+// in any language. The command is run with the path of a json file holding the PluginInput as argument and writes the
+// PluginOutput as json to its standard output. The severity of the findings is one of CRITICAL, HIGH, MEDIUM, LOW or
+// UNKNOWN. The command is killed once running for longer than the timeout, never when 0
+func NewExecCheck(name, command string, timeout time.Duration) Check {
+	return &execCheck{name: name, command: command, category: WorkloadCategory, timeout: timeout, commandRunner: execCmd.NewCommandRunner()}
+}
+
+func (c *execCheck) Name() string {
+	return c.name
+}
+
+func (c *execCheck) Category() string {
+	return c.category
+}
+
+func (c *execCheck) UseImageScan(imageScan *scanner.VulnerabilityReport) {
+	c.imageScan = imageScan
+}
+
+func (c *execCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	input, err := json.Marshal(PluginInput{Check: c.name, Workloads: workloads, ImageScan: c.imageScan})
+	if err != nil {
+		return nil, fmt.Errorf("error while encoding the input of plugin %s: %v", c.command, err)
+	}
+	inputFile, err := os.CreateTemp("", "check-plugin-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the input file of plugin %s: %v", c.command, err)
+	}
+	defer os.Remove(inputFile.Name())
+	_, err = inputFile.Write(input)
+	if closeErr := inputFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while writing the input file of plugin %s: %v", c.command, err)
+	}
+
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	output, errOutput, err := c.commandRunner.ExecuteContext(ctx, c.command, []string{inputFile.Name()})
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("plugin %s timed out after %s", c.command, c.timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while running plugin %s. Error output: %s, Error: %v", c.command, utils.ConvertByteToString(errOutput), err)
+	}
+	var pluginOutput PluginOutput
+	if err := json.Unmarshal(output, &pluginOutput); err != nil {
+		return nil, fmt.Errorf("error while decoding the output of plugin %s: %v", c.command, err)
+	}
+	switch pluginOutput.Category {
+	case "", WorkloadCategory:
+	case MisconfigurationCategory:
+		c.category = MisconfigurationCategory
+	default:
+		logr.Warnf("Unknown category %q of plugin %s, its findings count as %s findings", pluginOutput.Category, c.command, WorkloadCategory)
+	}
+	for i := range pluginOutput.Findings {
+		finding := &pluginOutput.Findings[i]
+		finding.Severity = strings.ToUpper(finding.Severity)
+		if _, ok := severityScores[finding.Severity]; !ok {
+			logr.Warnf("Unknown severity %q of a finding of plugin %s, reporting it as UNKNOWN", finding.Severity, c.command)
+			finding.Severity = "UNKNOWN"
+		}
+	}
+	return pluginOutput.Findings, nil
+}
+
+// ParseExecChecks parses the exec plugins given as name=command, each of them being run with the timeout, see
+// NewExecCheck
+func ParseExecChecks(plugins []string, timeout time.Duration) ([]Check, error) {
+	var checks []Check
+	names := make(map[string]bool)
+	for _, plugin := range plugins {
+		name, command, ok := strings.Cut(plugin, "=")
+		name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+		if !ok || name == "" || command == "" {
+			return nil, fmt.Errorf("invalid check plugin %q, expecting name=command such as cost-centre=/usr/local/bin/cost-centre-check", plugin)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("check plugin %s is declared twice", name)
+		}
+		names[name] = true
+		checks = append(checks, NewExecCheck(name, command, timeout))
+	}
+	return checks, nil
+}
@@ -0,0 +1,122 @@
+package checks
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check plugins", func() {
+
+	var (
+		mockRunner *mockCommandRunner
+		check      *execCheck
+		workloads  []k8s.Workload
+		imageScan  *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		mockRunner = &mockCommandRunner{}
+		check = NewExecCheck("cost-centre", "/usr/local/bin/cost-centre-check", time.Minute).(*execCheck)
+		check.commandRunner = mockRunner
+		workloads = []k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "team-a"}}
+		imageScan = &scanner.VulnerabilityReport{ScannedImages: []scanner.ScannedImage{{ImageName: "api:1"}}}
+	})
+
+	// inputOf matches the arguments of the plugin when the input file holds the check, the workloads and the image scan
+	inputOf := func(args []string) bool {
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return false
+		}
+		var input PluginInput
+		return json.Unmarshal(content, &input) == nil && input.Check == "cost-centre" &&
+			len(input.Workloads) == 1 && input.ImageScan != nil && len(input.ImageScan.ScannedImages) == 1
+	}
+
+	It("runs the executable with the workloads and the image scan and reads its findings", func() {
+		output := `{"category":"misconfiguration","findings":[
+			{"severity":"high","namespace":"team-a","kind":"Deployment","workload":"api","message":"no cost-centre label"},
+			{"severity":"severe","namespace":"team-a","kind":"Deployment","workload":"api","message":"unknown cost centre"}]}`
+		mockRunner.On("Execute", "/usr/local/bin/cost-centre-check", mock.MatchedBy(inputOf)).Return([]byte(output), []byte{}, nil)
+		check.UseImageScan(imageScan)
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(2))
+		Expect(findings[0]).To(Equal(Finding{Severity: "HIGH", Namespace: "team-a", Kind: "Deployment", Workload: "api", Message: "no cost-centre label"}))
+		Expect(findings[1].Severity).To(Equal("UNKNOWN"))
+		Expect(check.Category()).To(Equal(MisconfigurationCategory))
+	})
+
+	It("fails when the executable fails", func() {
+		mockRunner.On("Execute", "/usr/local/bin/cost-centre-check", mock.Anything).Return([]byte{}, []byte("no such cluster"), errors.New("exit status 2"))
+
+		_, err := check.Run(workloads)
+
+		Expect(err).To(MatchError(ContainSubstring("no such cluster")))
+	})
+
+	It("kills the executable running for longer than the timeout", func() {
+		command := filepath.Join(GinkgoT().TempDir(), "slow-check")
+		Expect(os.WriteFile(command, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755)).To(Succeed())
+		slowCheck := NewExecCheck("slow", command, 100*time.Millisecond)
+
+		start := time.Now()
+		_, err := slowCheck.Run(workloads)
+
+		Expect(err).To(MatchError(ContainSubstring("timed out after 100ms")))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	It("gives the image scan to the checks and records their category in the report", func() {
+		mockKubernetesClient := &k8stest.KubernetesClient{}
+		mockKubernetesClient.On("GetWorkloadsInNamespaces", "").Return(workloads, nil)
+		mockRunner.On("Execute", "/usr/local/bin/cost-centre-check", mock.MatchedBy(inputOf)).
+			Return([]byte(`{"Category":"misconfiguration","Findings":[{"Severity":"HIGH","Namespace":"team-a","Kind":"Deployment","Workload":"api"}]}`), []byte{}, nil)
+
+		report, err := New(mockKubernetesClient, &Config{ImageScan: imageScan}, check, &fakeCheck{name: "fake"}).Run()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Findings).To(HaveLen(1))
+		Expect(report.Findings[0].Check).To(Equal("cost-centre"))
+		Expect(report.CheckCategories).To(Equal(map[string]string{"cost-centre": MisconfigurationCategory, "fake": WorkloadCategory}))
+		Expect(report.IsMisconfigurationCheck("cost-centre")).To(BeTrue())
+		Expect(report.IsMisconfigurationCheck("fake")).To(BeFalse())
+		Expect((&ReadinessReport{}).IsMisconfigurationCheck(ConfigAuditCheckName)).To(BeTrue())
+	})
+
+	It("parses the executable plugins given as name=command", func() {
+		plugins, err := ParseExecChecks([]string{"cost-centre=/usr/local/bin/cost-centre-check", "owners = ./owners-check"}, time.Minute)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(HaveLen(2))
+		Expect(plugins[1].Name()).To(Equal("owners"))
+		Expect(plugins[1].(*execCheck).command).To(Equal("./owners-check"))
+		Expect(plugins[1].(*execCheck).timeout).To(Equal(time.Minute))
+		_, err = ParseExecChecks([]string{"/usr/local/bin/cost-centre-check"}, time.Minute)
+		Expect(err).To(MatchError(ContainSubstring("expecting name=command")))
+		_, err = ParseExecChecks([]string{"owners=./a", "owners=./b"}, time.Minute)
+		Expect(err).To(MatchError(ContainSubstring("declared twice")))
+	})
+
+	It("makes the registered checks available by name", func() {
+		Register("plugin-test", func(_ k8s.KubernetesClient) Check { return &fakeCheck{name: "plugin-test"} })
+		DeferCleanup(func() { delete(registeredChecks, "plugin-test") })
+
+		Expect(RegisteredChecks()).To(HaveKey("plugin-test"))
+		Expect(RegisteredChecks()["plugin-test"](nil).Name()).To(Equal("plugin-test"))
+		Expect(func() { Register("plugin-test", nil) }).To(Panic())
+	})
+})
@@ -0,0 +1,180 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// WorkloadSecurityCheckName is the name of the Pod Security Standards conformance check
+const WorkloadSecurityCheckName = "workload-security"
+
+// Pod Security Standards profiles, see https://kubernetes.io/docs/concepts/security/pod-security-standards/
+const (
+	PSSBaseline   = "baseline"
+	PSSRestricted = "restricted"
+)
+
+// capabilities which can be added to containers without violating the baseline profile
+var baselineCapabilities = map[v1.Capability]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true, "KILL": true, "MKNOD": true,
+	"NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+type workloadSecurityCheck struct{}
+
+// NewWorkloadSecurityCheck creates a check reporting the workloads violating the Pod Security Standards.
+// Baseline violations are reported as HIGH and restricted violations as MEDIUM
+func NewWorkloadSecurityCheck(_ k8s.KubernetesClient) Check {
+	return &workloadSecurityCheck{}
+}
+
+func (c *workloadSecurityCheck) Name() string {
+	return WorkloadSecurityCheckName
+}
+
+func (c *workloadSecurityCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	var findings []Finding
+	for _, workload := range workloads {
+		for _, v := range podSecurityViolations(workload.PodSpec) {
+			severity := "MEDIUM"
+			if v.profile == PSSBaseline {
+				severity = "HIGH"
+			}
+			finding := workloadFinding(workload, severity, fmt.Sprintf("PSS %s: %s", v.profile, v.message))
+			finding.Container = v.container
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+type podSecurityViolation struct {
+	profile, container, message string
+}
+
+// podSecurityViolations evaluates the pod spec against the baseline and restricted profiles
+func podSecurityViolations(spec v1.PodSpec) []podSecurityViolation {
+	var violations []podSecurityViolation
+	baseline := func(container, format string, args ...interface{}) {
+		violations = append(violations, podSecurityViolation{PSSBaseline, container, fmt.Sprintf(format, args...)})
+	}
+	restricted := func(container, format string, args ...interface{}) {
+		violations = append(violations, podSecurityViolation{PSSRestricted, container, fmt.Sprintf(format, args...)})
+	}
+
+	if spec.HostNetwork {
+		baseline("", "host network namespace is shared")
+	}
+	if spec.HostPID {
+		baseline("", "host PID namespace is shared")
+	}
+	if spec.HostIPC {
+		baseline("", "host IPC namespace is shared")
+	}
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			baseline("", "hostPath volume %s mounts %s", volume.Name, volume.HostPath.Path)
+		} else if !isRestrictedVolume(volume) {
+			restricted("", "volume %s has a type not allowed by the restricted profile", volume.Name)
+		}
+	}
+
+	podSecurityContext := spec.SecurityContext
+	if podSecurityContext == nil {
+		podSecurityContext = &v1.PodSecurityContext{}
+	}
+	if isUnconfined(podSecurityContext.SeccompProfile) {
+		baseline("", "seccomp profile is Unconfined")
+	}
+
+	var containers []v1.Container
+	containers = append(containers, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, container := range containers {
+		securityContext := container.SecurityContext
+		if securityContext == nil {
+			securityContext = &v1.SecurityContext{}
+		}
+
+		// baseline
+		if securityContext.Privileged != nil && *securityContext.Privileged {
+			baseline(container.Name, "container is privileged")
+		}
+		if securityContext.Capabilities != nil {
+			for _, capability := range securityContext.Capabilities.Add {
+				if !baselineCapabilities[capability] {
+					baseline(container.Name, "capability %s is added", capability)
+				}
+			}
+		}
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				baseline(container.Name, "host port %d is used", port.HostPort)
+			}
+		}
+		if securityContext.ProcMount != nil && *securityContext.ProcMount == v1.UnmaskedProcMount {
+			baseline(container.Name, "/proc is unmasked")
+		}
+		if isUnconfined(securityContext.SeccompProfile) {
+			baseline(container.Name, "seccomp profile is Unconfined")
+		}
+
+		// restricted
+		if securityContext.AllowPrivilegeEscalation == nil || *securityContext.AllowPrivilegeEscalation {
+			restricted(container.Name, "allowPrivilegeEscalation is not set to false")
+		}
+		if !runsAsNonRoot(podSecurityContext, securityContext) {
+			restricted(container.Name, "runAsNonRoot is not set to true")
+		}
+		if (securityContext.RunAsUser != nil && *securityContext.RunAsUser == 0) ||
+			(securityContext.RunAsUser == nil && podSecurityContext.RunAsUser != nil && *podSecurityContext.RunAsUser == 0) {
+			restricted(container.Name, "container runs as root user")
+		}
+		if securityContext.SeccompProfile == nil && podSecurityContext.SeccompProfile == nil {
+			restricted(container.Name, "seccomp profile is not set to RuntimeDefault or Localhost")
+		}
+		if !dropsAllCapabilities(securityContext.Capabilities) {
+			restricted(container.Name, "capabilities do not drop ALL")
+		}
+		if securityContext.Capabilities != nil {
+			for _, capability := range securityContext.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" && baselineCapabilities[capability] {
+					restricted(container.Name, "capability %s is added", capability)
+				}
+			}
+		}
+	}
+	return violations
+}
+
+func isRestrictedVolume(volume v1.Volume) bool {
+	source := volume.VolumeSource
+	return source.ConfigMap != nil || source.CSI != nil || source.DownwardAPI != nil || source.EmptyDir != nil ||
+		source.Ephemeral != nil || source.PersistentVolumeClaim != nil || source.Projected != nil || source.Secret != nil
+}
+
+func isUnconfined(profile *v1.SeccompProfile) bool {
+	return profile != nil && profile.Type == v1.SeccompProfileTypeUnconfined
+}
+
+func runsAsNonRoot(podSecurityContext *v1.PodSecurityContext, securityContext *v1.SecurityContext) bool {
+	if securityContext.RunAsNonRoot != nil {
+		return *securityContext.RunAsNonRoot
+	}
+	return podSecurityContext.RunAsNonRoot != nil && *podSecurityContext.RunAsNonRoot
+}
+
+func dropsAllCapabilities(capabilities *v1.Capabilities) bool {
+	if capabilities == nil {
+		return false
+	}
+	for _, capability := range capabilities.Drop {
+		if strings.EqualFold(string(capability), "ALL") {
+			return true
+		}
+	}
+	return false
+}
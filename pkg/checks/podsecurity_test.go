@@ -0,0 +1,119 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Workload security check", func() {
+
+	var (
+		check Check
+	)
+
+	BeforeEach(func() {
+		check = NewWorkloadSecurityCheck(nil)
+	})
+
+	It("does not report workloads conforming to the restricted profile", func() {
+		findings, err := check.Run([]k8s.Workload{{Name: "api", PodSpec: restrictedPodSpec()}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+
+	It("reports the baseline violations as HIGH", func() {
+		spec := restrictedPodSpec()
+		spec.HostNetwork = true
+		spec.Volumes = []v1.Volume{{Name: "docker", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}}}
+		spec.Containers[0].SecurityContext.Privileged = boolPtr(true)
+		spec.Containers[0].SecurityContext.Capabilities.Add = []v1.Capability{"SYS_ADMIN"}
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "DaemonSet", Name: "agent", Namespace: "ns", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages(findings)).To(ConsistOf(
+			"PSS baseline: host network namespace is shared",
+			"PSS baseline: hostPath volume docker mounts /var/run/docker.sock",
+			"PSS baseline: container is privileged",
+			"PSS baseline: capability SYS_ADMIN is added",
+		))
+		Expect(findings[0].Severity).To(Equal("HIGH"))
+		Expect(findings[2].Container).To(Equal("app"))
+		Expect(findings[2].Workload).To(Equal("agent"))
+	})
+
+	It("reports the restricted violations as MEDIUM", func() {
+		spec := v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "init"}},
+			Containers:     []v1.Container{{Name: "app"}},
+		}
+
+		findings, err := check.Run([]k8s.Workload{{Name: "api", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(8))
+		Expect(findings[0]).To(Equal(Finding{
+			Severity:  "MEDIUM",
+			Workload:  "api",
+			Container: "init",
+			Message:   "PSS restricted: allowPrivilegeEscalation is not set to false",
+		}))
+		Expect(messages(findings)).To(ContainElements(
+			"PSS restricted: runAsNonRoot is not set to true",
+			"PSS restricted: seccomp profile is not set to RuntimeDefault or Localhost",
+			"PSS restricted: capabilities do not drop ALL",
+		))
+	})
+
+	It("uses the pod security context when the container one is not set", func() {
+		spec := restrictedPodSpec()
+		spec.Containers[0].SecurityContext.RunAsNonRoot = nil
+		spec.SecurityContext.RunAsNonRoot = boolPtr(true)
+		spec.SecurityContext.RunAsUser = int64Ptr(0)
+
+		findings, err := check.Run([]k8s.Workload{{Name: "api", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages(findings)).To(ConsistOf("PSS restricted: container runs as root user"))
+	})
+})
+
+func restrictedPodSpec() v1.PodSpec {
+	return v1.PodSpec{
+		SecurityContext: &v1.PodSecurityContext{
+			SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+		},
+		Volumes: []v1.Volume{{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}}}},
+		Containers: []v1.Container{{
+			Name: "app",
+			SecurityContext: &v1.SecurityContext{
+				AllowPrivilegeEscalation: boolPtr(false),
+				RunAsNonRoot:             boolPtr(true),
+				Capabilities: &v1.Capabilities{
+					Drop: []v1.Capability{"ALL"},
+					Add:  []v1.Capability{"NET_BIND_SERVICE"},
+				},
+			},
+		}},
+	}
+}
+
+func messages(findings []Finding) []string {
+	var result []string
+	for _, finding := range findings {
+		result = append(result, finding.Message)
+	}
+	return result
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
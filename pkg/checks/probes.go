@@ -0,0 +1,47 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// ProbesCheckName is the name of the probe coverage check
+const ProbesCheckName = "probes"
+
+type probesCheck struct{}
+
+// NewProbesCheck creates a check reporting the containers without liveness or readiness probe.
+// Jobs are ignored as their pods run to completion and are not probed
+func NewProbesCheck(_ k8s.KubernetesClient) Check {
+	return &probesCheck{}
+}
+
+func (c *probesCheck) Name() string {
+	return ProbesCheckName
+}
+
+func (c *probesCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	var findings []Finding
+	for _, workload := range workloads {
+		if workload.Kind == "Job" {
+			continue
+		}
+		for _, container := range workload.PodSpec.Containers {
+			if container.ReadinessProbe == nil {
+				finding := workloadFinding(workload, "MEDIUM", "no readiness probe, traffic is sent to the container before it is ready")
+				finding.Container = container.Name
+				findings = append(findings, finding)
+			}
+			if container.LivenessProbe == nil {
+				finding := workloadFinding(workload, "LOW", "no liveness probe, the container is not restarted when it stops responding")
+				finding.Container = container.Name
+				findings = append(findings, finding)
+			}
+			if container.StartupProbe == nil && container.LivenessProbe != nil && container.LivenessProbe.InitialDelaySeconds > 60 {
+				finding := workloadFinding(workload, "LOW", "long liveness probe initial delay without startup probe, failures are detected late")
+				finding.Container = container.Name
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,59 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Probes check", func() {
+
+	var (
+		check Check
+	)
+
+	BeforeEach(func() {
+		check = NewProbesCheck(nil)
+	})
+
+	It("reports the containers missing liveness or readiness probes", func() {
+		spec := v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "app", LivenessProbe: &v1.Probe{}, ReadinessProbe: &v1.Probe{}},
+				{Name: "sidecar"},
+			},
+		}
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "ns", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "MEDIUM", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "sidecar", Message: "no readiness probe, traffic is sent to the container before it is ready"},
+			{Severity: "LOW", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "sidecar", Message: "no liveness probe, the container is not restarted when it stops responding"},
+		}))
+	})
+
+	It("reports long liveness initial delays without startup probe", func() {
+		spec := v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "app", LivenessProbe: &v1.Probe{InitialDelaySeconds: 120}, ReadinessProbe: &v1.Probe{}},
+			},
+		}
+
+		findings, err := check.Run([]k8s.Workload{{Name: "api", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages(findings)).To(ConsistOf("long liveness probe initial delay without startup probe, failures are detected late"))
+	})
+
+	It("ignores jobs", func() {
+		spec := v1.PodSpec{Containers: []v1.Container{{Name: "migration"}}}
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "Job", Name: "migration", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+})
@@ -0,0 +1,213 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// ImageProvenanceCheckName is the name of the SLSA provenance verification check
+const ImageProvenanceCheckName = "image-provenance"
+
+// Statuses of the image-provenance findings
+const (
+	// ProvenanceMissing is the status of the images without SLSA provenance attestation
+	ProvenanceMissing = "missing-provenance"
+	// ProvenanceInvalid is the status of the images whose provenance attestations are not verified by any key or
+	// identity of the signature policy
+	ProvenanceInvalid = "invalid-provenance"
+	// ProvenanceUntrustedBuilder is the status of the images built by a builder the provenance policy does not trust
+	ProvenanceUntrustedBuilder = "untrusted-builder"
+	// ProvenanceUntrustedSource is the status of the images built from a repository the provenance policy does not trust
+	ProvenanceUntrustedSource = "untrusted-source"
+)
+
+// provenanceTypes are the cosign predicate types of the SLSA provenance attestations, v0.2 and v1
+var provenanceTypes = []string{"slsaprovenance", "slsaprovenance1"}
+
+// ProvenancePolicy holds the builders and source repositories the images must be built by and from, any builder or
+// repository being trusted when empty. They are matched as prefixes, for instance https://github.com/example/ trusts all
+// the repositories of the organisation
+type ProvenancePolicy struct {
+	Builders           []string
+	SourceRepositories []string
+}
+
+// Provenance is the builder and the source repository recorded by a SLSA provenance attestation
+type Provenance struct {
+	BuilderID        string
+	SourceRepository string
+}
+
+type imageProvenanceCheck struct {
+	verifier *cosignVerifier
+	policy   *ProvenancePolicy
+}
+
+// NewImageProvenanceCheck creates a check reporting the containers running images without SLSA provenance attestation
+// verified by the keys or identities of the signature policy, or built by an untrusted builder or from an untrusted
+// source repository according to the provenance policy
+func NewImageProvenanceCheck(signaturePolicy *SignaturePolicy, provenancePolicy *ProvenancePolicy) Check {
+	return &imageProvenanceCheck{
+		verifier: newCosignVerifier(signaturePolicy),
+		policy:   provenancePolicy,
+	}
+}
+
+func (c *imageProvenanceCheck) Name() string {
+	return ImageProvenanceCheckName
+}
+
+func (c *imageProvenanceCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	if err := c.verifier.validate(); err != nil {
+		return nil, err
+	}
+	return imageFindings(workloads, c.imageIssue), nil
+}
+
+func (c *imageProvenanceCheck) imageIssue(image string) (Finding, bool) {
+	var result cosignResult
+	for _, provenanceType := range provenanceTypes {
+		typeResult := c.verifier.verify(image, "verify-attestation", "--type", provenanceType)
+		// an invalid attestation of one type is not hidden by a missing attestation of the other type
+		if typeResult.outcome == cosignVerified || result.outcome != cosignInvalid {
+			result = typeResult
+		}
+		if result.outcome == cosignVerified {
+			break
+		}
+	}
+	switch result.outcome {
+	case cosignMissing:
+		return Finding{Severity: "HIGH", Status: ProvenanceMissing, Message: "image " + image + " has no SLSA provenance attestation"}, true
+	case cosignInvalid:
+		return Finding{Severity: "CRITICAL", Status: ProvenanceInvalid, Message: fmt.Sprintf("image %s has no SLSA provenance attestation verified by the configured keys or identities: %s", image, result.reason)}, true
+	}
+
+	provenances, err := parseProvenances(result.output)
+	if err != nil || len(provenances) == 0 {
+		return Finding{Severity: "CRITICAL", Status: ProvenanceInvalid, Message: fmt.Sprintf("image %s has an unreadable SLSA provenance attestation: %v", image, err)}, true
+	}
+	var untrusted Finding
+	for _, provenance := range provenances {
+		switch {
+		case !matchesPrefix(provenance.BuilderID, c.policy.Builders):
+			untrusted = Finding{Severity: "CRITICAL", Status: ProvenanceUntrustedBuilder, Message: fmt.Sprintf("image %s was built by the untrusted builder %s", image, provenance.BuilderID)}
+		case !matchesPrefix(provenance.SourceRepository, c.policy.SourceRepositories):
+			untrusted = Finding{Severity: "HIGH", Status: ProvenanceUntrustedSource, Message: fmt.Sprintf("image %s was built from the untrusted source repository %s", image, provenance.SourceRepository)}
+		default:
+			// one trusted provenance is enough
+			return Finding{}, false
+		}
+	}
+	return untrusted, true
+}
+
+// parseProvenances decodes the provenances of the in-toto attestations cosign verify-attestation prints, one DSSE
+// envelope per line
+func parseProvenances(output []byte) ([]Provenance, error) {
+	var provenances []Provenance
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var envelope struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.Unmarshal(line, &envelope); err != nil {
+			return nil, fmt.Errorf("error while decoding the attestation: %v", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("error while decoding the attestation payload: %v", err)
+		}
+		var statement inTotoStatement
+		if err := json.Unmarshal(payload, &statement); err != nil {
+			return nil, fmt.Errorf("error while decoding the in-toto statement: %v", err)
+		}
+		provenances = append(provenances, statement.provenance())
+	}
+	return provenances, scanner.Err()
+}
+
+// inTotoStatement is the object representation of an in-toto statement with a SLSA provenance predicate, holding the
+// fields of the builder and of the source repository of both the v0.2 and v1 predicates
+type inTotoStatement struct {
+	PredicateType string `json:"predicateType"`
+	Predicate     struct {
+		// v0.2
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Invocation struct {
+			ConfigSource struct {
+				URI string `json:"uri"`
+			} `json:"configSource"`
+		} `json:"invocation"`
+		// v1
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"`
+		BuildDefinition struct {
+			ResolvedDependencies []struct {
+				URI string `json:"uri"`
+			} `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+	} `json:"predicate"`
+}
+
+func (s inTotoStatement) provenance() Provenance {
+	if strings.HasPrefix(s.PredicateType, "https://slsa.dev/provenance/v1") {
+		provenance := Provenance{BuilderID: s.Predicate.RunDetails.Builder.ID}
+		// the first resolved dependency is the source of the build by convention
+		if dependencies := s.Predicate.BuildDefinition.ResolvedDependencies; len(dependencies) > 0 {
+			provenance.SourceRepository = sourceRepository(dependencies[0].URI)
+		}
+		return provenance
+	}
+	return Provenance{BuilderID: s.Predicate.Builder.ID, SourceRepository: sourceRepository(s.Predicate.Invocation.ConfigSource.URI)}
+}
+
+// sourceRepository returns the repository url of a source uri, for instance https://github.com/example/api for
+// git+https://github.com/example/api@refs/heads/main
+func sourceRepository(uri string) string {
+	uri = strings.TrimPrefix(uri, "git+")
+	if index := strings.LastIndex(uri, "@"); index > strings.Index(uri, "://")+3 {
+		uri = uri[:index]
+	}
+	return strings.TrimSuffix(uri, ".git")
+}
+
+// matchesPrefix returns true when the value starts with one of the prefixes, or when there is no prefix
+func matchesPrefix(value string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingProvenance returns the findings of the images without SLSA provenance attestation
+func (r *ReadinessReport) MissingProvenance() []Finding {
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if finding.Check == ImageProvenanceCheckName && finding.Status == ProvenanceMissing {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,104 @@
+package checks
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image provenance check", func() {
+
+	const builder = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.9.0"
+
+	var (
+		mockRunner *mockCommandRunner
+		check      *imageProvenanceCheck
+	)
+
+	attestation := func(statement string) []byte {
+		return []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"` + base64.StdEncoding.EncodeToString([]byte(statement)) + `","signatures":[{"sig":"MEUC"}]}` + "\n")
+	}
+	v02Attestation := func(builderID, source string) []byte {
+		return attestation(`{"_type":"https://in-toto.io/Statement/v0.1","predicateType":"https://slsa.dev/provenance/v0.2","predicate":{"builder":{"id":"` + builderID + `"},"invocation":{"configSource":{"uri":"` + source + `"}}}}`)
+	}
+	verifyArgs := func(provenanceType, image string) []string {
+		return []string{"verify-attestation", "--type", provenanceType, "--key", "cosign.pub", image}
+	}
+	workloads := func(images ...string) []k8s.Workload {
+		var containers []v1.Container
+		for _, image := range images {
+			containers = append(containers, v1.Container{Name: "app", Image: image})
+		}
+		return []k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "payments", PodSpec: v1.PodSpec{Containers: containers}}}
+	}
+
+	BeforeEach(func() {
+		mockRunner = &mockCommandRunner{}
+		check = &imageProvenanceCheck{
+			verifier: &cosignVerifier{policy: &SignaturePolicy{PublicKeys: []string{"cosign.pub"}}, commandRunner: mockRunner,
+				lookPath: func(string) (string, error) { return "/usr/local/bin/cosign", nil }},
+			policy: &ProvenancePolicy{
+				Builders:           []string{"https://github.com/slsa-framework/slsa-github-generator/"},
+				SourceRepositories: []string{"https://github.com/example/"},
+			},
+		}
+	})
+
+	It("accepts the images built by a trusted builder from a trusted source repository", func() {
+		mockRunner.On("Execute", "cosign", verifyArgs("slsaprovenance", "registry/api:1.2")).
+			Return(v02Attestation(builder, "git+https://github.com/example/api@refs/heads/main"), []byte{}, nil)
+
+		findings, err := check.Run(workloads("registry/api:1.2"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+
+	It("reads the builder and the source repository of the SLSA v1 provenance", func() {
+		noV02 := []byte("Error: none of the attestations matched the predicate type: slsaprovenance\n")
+		mockRunner.
+			On("Execute", "cosign", verifyArgs("slsaprovenance", "registry/api:1.2")).Return([]byte{}, noV02, errors.New("exit status 1")).
+			On("Execute", "cosign", verifyArgs("slsaprovenance1", "registry/api:1.2")).
+			Return(attestation(`{"predicateType":"https://slsa.dev/provenance/v1","predicate":{"runDetails":{"builder":{"id":"https://ci.example.com/builder"}},"buildDefinition":{"resolvedDependencies":[{"uri":"git+https://github.com/example/api.git@refs/heads/main"}]}}}`), []byte{}, nil)
+
+		findings, err := check.Run(workloads("registry/api:1.2"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{{Severity: "CRITICAL", Status: ProvenanceUntrustedBuilder, Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "app",
+			Message: "image registry/api:1.2 was built by the untrusted builder https://ci.example.com/builder"}}))
+	})
+
+	It("reports the images without provenance, with invalid provenance or built from an untrusted source", func() {
+		missing := []byte("Error: no matching attestations: no attestations found\n")
+		invalid := []byte("Error: no matching attestations: invalid signature when validating ASN.1 encoded signature\n")
+		for _, provenanceType := range []string{"slsaprovenance", "slsaprovenance1"} {
+			mockRunner.
+				On("Execute", "cosign", verifyArgs(provenanceType, "registry/batch:2.0")).Return([]byte{}, missing, errors.New("exit status 1")).
+				On("Execute", "cosign", verifyArgs(provenanceType, "registry/web:3.1")).Return([]byte{}, invalid, errors.New("exit status 1"))
+		}
+		mockRunner.On("Execute", "cosign", verifyArgs("slsaprovenance", "registry/fork:1.0")).
+			Return(v02Attestation(builder, "git+https://github.com/someone/api@refs/heads/main"), []byte{}, nil)
+
+		findings, err := check.Run(workloads("registry/batch:2.0", "registry/web:3.1", "registry/fork:1.0"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(3))
+		Expect(findings[0].Status).To(Equal(ProvenanceMissing))
+		Expect(findings[0].Severity).To(Equal("HIGH"))
+		Expect(findings[1].Status).To(Equal(ProvenanceInvalid))
+		Expect(findings[1].Message).To(ContainSubstring("invalid signature"))
+		Expect(findings[2].Status).To(Equal(ProvenanceUntrustedSource))
+		Expect(findings[2].Message).To(HaveSuffix("untrusted source repository https://github.com/someone/api"))
+
+		report := &ReadinessReport{Findings: findings}
+		for i := range report.Findings {
+			report.Findings[i].Check = ImageProvenanceCheckName
+		}
+		Expect(report.MissingProvenance()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,223 @@
+package checks
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+const (
+	// VulnerabilitiesWeight, MisconfigurationsWeight and WorkloadChecksWeight name the components of the readiness
+	// score in the weights given to ParseReadinessScoreWeights
+	VulnerabilitiesWeight   = "vulnerabilities"
+	MisconfigurationsWeight = "misconfigurations"
+	WorkloadChecksWeight    = "workload-checks"
+)
+
+// DefaultReadinessScoreWeights are the weights of the readiness score when none are configured
+var DefaultReadinessScoreWeights = []string{VulnerabilitiesWeight + "=50", MisconfigurationsWeight + "=25", WorkloadChecksWeight + "=25"}
+
+// misconfigurationChecks are the built-in checks whose findings count as misconfigurations in the readiness score, the
+// findings of the other checks counting as workload check findings unless they declare their category, see
+// CategorizedCheck
+var misconfigurationChecks = map[string]bool{MisconfigurationCheckName: true, ConfigAuditCheckName: true}
+
+// ReadinessScoreWeights are the relative weights of the vulnerabilities, the misconfigurations and the workload checks
+// in the readiness score of the teams
+type ReadinessScoreWeights struct {
+	Vulnerabilities   float64
+	Misconfigurations float64
+	WorkloadChecks    float64
+}
+
+// ParseReadinessScoreWeights parses weights such as vulnerabilities=50,misconfigurations=25,workload-checks=25. The
+// components not given are weighted 0
+func ParseReadinessScoreWeights(weights []string) (ReadinessScoreWeights, error) {
+	var parsed ReadinessScoreWeights
+	for _, weight := range weights {
+		name, value, _ := strings.Cut(weight, "=")
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || number < 0 {
+			return parsed, fmt.Errorf("invalid readiness score weight %q, expecting a positive number such as %s=50", weight, VulnerabilitiesWeight)
+		}
+		switch name {
+		case VulnerabilitiesWeight:
+			parsed.Vulnerabilities = number
+		case MisconfigurationsWeight:
+			parsed.Misconfigurations = number
+		case WorkloadChecksWeight:
+			parsed.WorkloadChecks = number
+		default:
+			return parsed, fmt.Errorf("unknown readiness score weight %q, permitted values: %s, %s, %s", name, VulnerabilitiesWeight, MisconfigurationsWeight, WorkloadChecksWeight)
+		}
+	}
+	if parsed.Vulnerabilities+parsed.Misconfigurations+parsed.WorkloadChecks == 0 {
+		return parsed, fmt.Errorf("the readiness score weights %v are all 0", weights)
+	}
+	return parsed, nil
+}
+
+// TeamReadinessScore is the readiness score of a team, see ReadinessScores
+type TeamReadinessScore struct {
+	Area string
+	Team string
+	// Score is the weighted average of the scores of the components, from 0 to 100, 100 when the team has neither
+	// CRITICAL nor HIGH finding
+	Score float64
+	// VulnerabilityScore is the percentage of the scanned images of the team without CRITICAL or HIGH vulnerability,
+	// MisconfigurationScore and WorkloadCheckScore the percentage of its workloads without CRITICAL or HIGH
+	// misconfiguration or workload check finding. Each is nil when the team has no image or workload for it, or when
+	// its checks were not run, and is then left out of the score
+	VulnerabilityScore    *float64 `json:",omitempty"`
+	MisconfigurationScore *float64 `json:",omitempty"`
+	WorkloadCheckScore    *float64 `json:",omitempty"`
+}
+
+// ReadinessScores combines the vulnerabilities of the image scan with the misconfigurations and the workload check
+// findings of the readiness checks into a single readiness score per team, so that the teams can be compared on one
+// number. Only the CRITICAL and HIGH findings lower the score. Either report can be nil. The teams are sorted by
+// decreasing score
+func ReadinessScores(imageScan *scanner.VulnerabilityReport, readiness *ReadinessReport, weights ReadinessScoreWeights) []TeamReadinessScore {
+	scores := make(map[string]*TeamReadinessScore)
+	scoreOf := func(area, team string) *TeamReadinessScore {
+		key := area + "/" + team
+		if _, ok := scores[key]; !ok {
+			scores[key] = &TeamReadinessScore{Area: area, Team: team}
+		}
+		return scores[key]
+	}
+
+	if imageScan != nil {
+		for _, area := range imageScan.AreaSummary {
+			for _, team := range area.Teams {
+				scanned, clean := 0, 0
+				for _, image := range team.Images {
+					if image.ScanError != nil || image.Skipped {
+						continue
+					}
+					scanned++
+					counts := image.VulnerabilitySummary.TotalVulnerabilityBySeverity
+					if counts["CRITICAL"]+counts["HIGH"] == 0 {
+						clean++
+					}
+				}
+				if scanned > 0 {
+					scoreOf(area.Name, team.Name).VulnerabilityScore = percentage(clean, scanned)
+				}
+			}
+		}
+	}
+
+	if readiness != nil {
+		ranMisconfigurationChecks, ranWorkloadChecks := false, false
+		for _, check := range readiness.Checks {
+			if readiness.IsMisconfigurationCheck(check) {
+				ranMisconfigurationChecks = true
+			} else {
+				ranWorkloadChecks = true
+			}
+		}
+		misconfigured := make(map[string]map[string]bool)
+		failing := make(map[string]map[string]bool)
+		for _, finding := range readiness.Findings {
+			if finding.Severity != "CRITICAL" && finding.Severity != "HIGH" {
+				continue
+			}
+			failed := failing
+			if readiness.IsMisconfigurationCheck(finding.Check) {
+				failed = misconfigured
+			}
+			key := finding.Area + "/" + finding.Team
+			if failed[key] == nil {
+				failed[key] = make(map[string]bool)
+			}
+			failed[key][finding.Namespace+"/"+finding.Kind+"/"+finding.Workload] = true
+		}
+		for key, workloadCount := range readiness.WorkloadCountByTeam {
+			if workloadCount == 0 {
+				continue
+			}
+			area, team, _ := strings.Cut(key, "/")
+			score := scoreOf(area, team)
+			if ranMisconfigurationChecks {
+				score.MisconfigurationScore = percentage(workloadCount-minInt(len(misconfigured[key]), workloadCount), workloadCount)
+			}
+			if ranWorkloadChecks {
+				score.WorkloadCheckScore = percentage(workloadCount-minInt(len(failing[key]), workloadCount), workloadCount)
+			}
+		}
+	}
+
+	var teamScores []TeamReadinessScore
+	for _, score := range scores {
+		total, totalWeight := 0.0, 0.0
+		for _, component := range []struct {
+			score  *float64
+			weight float64
+		}{
+			{score.VulnerabilityScore, weights.Vulnerabilities},
+			{score.MisconfigurationScore, weights.Misconfigurations},
+			{score.WorkloadCheckScore, weights.WorkloadChecks},
+		} {
+			if component.score != nil && component.weight > 0 {
+				total += *component.score * component.weight
+				totalWeight += component.weight
+			}
+		}
+		if totalWeight == 0 {
+			continue
+		}
+		score.Score = total / totalWeight
+		teamScores = append(teamScores, *score)
+	}
+	sort.Slice(teamScores, func(i, j int) bool {
+		switch {
+		case teamScores[i].Score != teamScores[j].Score:
+			return teamScores[i].Score > teamScores[j].Score
+		case teamScores[i].Area != teamScores[j].Area:
+			return teamScores[i].Area < teamScores[j].Area
+		}
+		return teamScores[i].Team < teamScores[j].Team
+	})
+	return teamScores
+}
+
+// WriteReadinessScores writes the readiness scores of the teams as a table, the components not scored being shown as -
+func WriteReadinessScores(w io.Writer, scores []TeamReadinessScore) error {
+	var out strings.Builder
+	table := tabwriter.NewWriter(&out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(table, "TEAM\tAREA\tSCORE\tVULNERABILITIES\tMISCONFIGURATIONS\tWORKLOAD CHECKS\t\n")
+	for _, score := range scores {
+		fmt.Fprintf(table, "%s\t%s\t%.0f\t%s\t%s\t%s\t\n", score.Team, score.Area, score.Score,
+			formatPercentage(score.VulnerabilityScore), formatPercentage(score.MisconfigurationScore), formatPercentage(score.WorkloadCheckScore))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func percentage(count, total int) *float64 {
+	value := 100 * float64(count) / float64(total)
+	return &value
+}
+
+func formatPercentage(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", *value)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
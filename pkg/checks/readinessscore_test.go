@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Readiness scores", func() {
+
+	image := func(critical, high int) scanner.ScannedImage {
+		return scanner.ScannedImage{VulnerabilitySummary: scanner.VulnerabilitySummary{
+			TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": critical, "HIGH": high, "MEDIUM": 3},
+		}}
+	}
+
+	var (
+		imageScan *scanner.VulnerabilityReport
+		readiness *ReadinessReport
+		weights   ReadinessScoreWeights
+	)
+
+	BeforeEach(func() {
+		failedScan := scanner.ScannedImage{ScanError: errors.New("timeout")}
+		imageScan = &scanner.VulnerabilityReport{AreaSummary: map[string]*scanner.AreaSummary{
+			"payments": {Name: "payments", Teams: map[string]*scanner.TeamSummary{
+				"api": {Name: "api", Images: []scanner.ScannedImage{image(0, 0), image(1, 0), image(0, 2), image(0, 0), failedScan}},
+				"web": {Name: "web", Images: []scanner.ScannedImage{image(0, 0)}},
+			}},
+		}}
+		readiness = &ReadinessReport{
+			Checks: []string{MisconfigurationCheckName, ProbesCheckName},
+			Findings: []Finding{
+				{Check: MisconfigurationCheckName, Severity: "HIGH", Area: "payments", Team: "api", Namespace: "api", Kind: "Deployment", Workload: "server"},
+				{Check: ProbesCheckName, Severity: "CRITICAL", Area: "payments", Team: "api", Namespace: "api", Kind: "Deployment", Workload: "server"},
+				{Check: ProbesCheckName, Severity: "HIGH", Area: "payments", Team: "api", Namespace: "api", Kind: "Deployment", Workload: "server"},
+				{Check: ProbesCheckName, Severity: "LOW", Area: "payments", Team: "api", Namespace: "api", Kind: "Deployment", Workload: "worker"},
+				{Check: ProbesCheckName, Severity: "HIGH", Area: "payments", Team: "ledger", Namespace: "ledger", Kind: "StatefulSet", Workload: "db"},
+			},
+			WorkloadCountByTeam: map[string]int{"payments/api": 2, "payments/web": 1, "payments/ledger": 1},
+		}
+		var err error
+		weights, err = ParseReadinessScoreWeights(DefaultReadinessScoreWeights)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("weights the percentages of images and workloads without CRITICAL or HIGH finding", func() {
+		scores := ReadinessScores(imageScan, readiness, weights)
+
+		Expect(scores).To(HaveLen(3))
+		Expect(scores[0].Team).To(Equal("web"))
+		Expect(scores[0].Score).To(BeNumerically("==", 100))
+		Expect(scores[1].Team).To(Equal("api"))
+		Expect(*scores[1].VulnerabilityScore).To(BeNumerically("==", 50))
+		Expect(*scores[1].MisconfigurationScore).To(BeNumerically("==", 50))
+		Expect(*scores[1].WorkloadCheckScore).To(BeNumerically("==", 50))
+		Expect(scores[1].Score).To(BeNumerically("==", 50))
+		// the ledger team runs no scanned image, its score only weighs its workloads
+		Expect(scores[2].Team).To(Equal("ledger"))
+		Expect(scores[2].VulnerabilityScore).To(BeNil())
+		Expect(scores[2].Score).To(BeNumerically("==", 50))
+	})
+
+	It("leaves out the components whose checks were not run or whose weight is 0", func() {
+		readiness.Checks = []string{ProbesCheckName}
+		weights.Vulnerabilities = 0
+
+		scores := ReadinessScores(imageScan, readiness, weights)
+
+		Expect(scores[1].Team).To(Equal("api"))
+		Expect(scores[1].MisconfigurationScore).To(BeNil())
+		Expect(scores[1].Score).To(BeNumerically("==", 50))
+		Expect(ReadinessScores(nil, nil, weights)).To(BeEmpty())
+	})
+
+	It("writes the scores as a table", func() {
+		var out strings.Builder
+
+		Expect(WriteReadinessScores(&out, ReadinessScores(imageScan, nil, weights))).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring("TEAM"))
+		Expect(strings.Fields(strings.Split(out.String(), "\n")[2])).To(Equal([]string{"api", "payments", "50", "50%", "-", "-"}))
+	})
+
+	It("rejects the unknown, negative and all zero weights", func() {
+		_, err := ParseReadinessScoreWeights([]string{"cis=10"})
+		Expect(err).To(MatchError(ContainSubstring("unknown readiness score weight")))
+		_, err = ParseReadinessScoreWeights([]string{"vulnerabilities=-1"})
+		Expect(err).To(HaveOccurred())
+		_, err = ParseReadinessScoreWeights([]string{"vulnerabilities=0"})
+		Expect(err).To(HaveOccurred())
+	})
+})
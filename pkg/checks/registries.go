@@ -0,0 +1,71 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+// ApprovedRegistriesCheckName is the name of the approved registries check
+const ApprovedRegistriesCheckName = "approved-registries"
+
+type approvedRegistriesCheck struct {
+	registries []string
+}
+
+// NewApprovedRegistriesCheck creates a check reporting the containers pulling their image from a registry outside the
+// approved registries, as images of unvetted registries escape the organisation controls. An approved registry is a
+// registry host, for instance registry.example.com, or a repository prefix, for instance ghcr.io/example, the Docker Hub
+// images being hosted by docker.io
+func NewApprovedRegistriesCheck(registries []string) Check {
+	return &approvedRegistriesCheck{registries: registries}
+}
+
+func (c *approvedRegistriesCheck) Name() string {
+	return ApprovedRegistriesCheckName
+}
+
+func (c *approvedRegistriesCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	if len(c.registries) == 0 {
+		return nil, errors.New("no approved registry to check the images against")
+	}
+	return imageFindings(workloads, func(image string) (Finding, bool) {
+		if c.approved(image) {
+			return Finding{}, false
+		}
+		return Finding{Severity: "HIGH", Message: fmt.Sprintf("image %s is pulled from %s, which is not an approved registry", image, scanner.ImageRegistry(image))}, true
+	}), nil
+}
+
+// approved returns true when the image is hosted by an approved registry or under an approved repository prefix
+func (c *approvedRegistriesCheck) approved(image string) bool {
+	repository := imageRepositoryPath(image)
+	for _, registry := range c.registries {
+		registry = strings.TrimSuffix(registry, "/")
+		if repository == registry || strings.HasPrefix(repository, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRepositoryPath returns the registry and the repository of the image without tag nor digest, for instance
+// docker.io/library/nginx for nginx:1.25
+func imageRepositoryPath(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		name = name[:tagIndex]
+	}
+	registry := scanner.ImageRegistry(name)
+	path := name
+	if host, rest, found := strings.Cut(name, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		path = rest
+	}
+	if registry == scanner.DockerHubRegistry && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	return registry + "/" + path
+}
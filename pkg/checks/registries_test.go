@@ -0,0 +1,41 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Approved registries check", func() {
+
+	It("reports the containers pulling their image from a registry outside the approved registries", func() {
+		check := NewApprovedRegistriesCheck([]string{"registry.example.com", "ghcr.io/example/", "docker.io/library"})
+		workloads := []k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "payments", PodSpec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "migrate", Image: "quay.io/someone/migrate:1.0"}},
+			Containers: []v1.Container{
+				{Name: "api", Image: "registry.example.com/payments/api:1.2"},
+				{Name: "sidecar", Image: "ghcr.io/example/sidecar@sha256:4ff3ca91"},
+				{Name: "proxy", Image: "nginx:1.25"},
+				{Name: "exporter", Image: "ghcr.io/examples/exporter:0.3"},
+				{Name: "cache", Image: "bitnami/redis:7.2"},
+			},
+		}}}
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "migrate", Message: "image quay.io/someone/migrate:1.0 is pulled from quay.io, which is not an approved registry"},
+			{Severity: "HIGH", Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "exporter", Message: "image ghcr.io/examples/exporter:0.3 is pulled from ghcr.io, which is not an approved registry"},
+			{Severity: "HIGH", Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "cache", Message: "image bitnami/redis:7.2 is pulled from docker.io, which is not an approved registry"},
+		}))
+	})
+
+	It("fails without approved registry", func() {
+		_, err := NewApprovedRegistriesCheck(nil).Run(nil)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,91 @@
+package checks
+
+import "github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+// ReadinessReport is top level structure holding the results of the readiness checks
+type ReadinessReport struct {
+	Checks        []string
+	WorkloadCount int
+	Findings      []Finding
+	AreaSummary   map[string]*AreaSummary
+	// WorkloadCountByTeam is the number of workloads checked per area and team, keyed by area/team, see ReadinessScores
+	WorkloadCountByTeam map[string]int `json:",omitempty"`
+	// CheckCategories are the categories of the checks run, MisconfigurationCategory or WorkloadCategory, by check name
+	CheckCategories map[string]string `json:",omitempty"`
+	// Suppressions are the suppressions declared by the namespaces with the number of findings each suppressed, listed
+	// so that the suppressions remain visible
+	Suppressions []k8s.SuppressionInEffect `json:",omitempty"`
+}
+
+// AreaSummary holds the findings of the teams of an area
+type AreaSummary struct {
+	Name                string
+	Teams               map[string]*TeamSummary
+	FindingCountByCheck map[string]int
+}
+
+// TeamSummary holds the findings of a team
+type TeamSummary struct {
+	Name                string
+	Findings            []Finding
+	FindingCountByCheck map[string]int
+}
+
+// FindingsFor returns the findings of the check
+func (t *TeamSummary) FindingsFor(check string) []Finding {
+	var findings []Finding
+	for _, finding := range t.Findings {
+		if finding.Check == check {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func groupFindingsByArea(findings []Finding) map[string]*AreaSummary {
+	summaryByArea := make(map[string]*AreaSummary)
+	for _, finding := range findings {
+		area, ok := summaryByArea[finding.Area]
+		if !ok {
+			area = &AreaSummary{
+				Name:                finding.Area,
+				Teams:               make(map[string]*TeamSummary),
+				FindingCountByCheck: make(map[string]int),
+			}
+			summaryByArea[finding.Area] = area
+		}
+		team, ok := area.Teams[finding.Team]
+		if !ok {
+			team = &TeamSummary{
+				Name:                finding.Team,
+				FindingCountByCheck: make(map[string]int),
+			}
+			area.Teams[finding.Team] = team
+		}
+		team.Findings = append(team.Findings, finding)
+		team.FindingCountByCheck[finding.Check]++
+		area.FindingCountByCheck[finding.Check]++
+	}
+	return summaryByArea
+}
+
+// IsMisconfigurationCheck returns true when the findings of the check count as misconfigurations, see CheckCategories.
+// The reports recorded before the checks had categories only count the built-in misconfiguration checks
+func (r *ReadinessReport) IsMisconfigurationCheck(check string) bool {
+	if category, ok := r.CheckCategories[check]; ok {
+		return category == MisconfigurationCategory
+	}
+	return misconfigurationChecks[check]
+}
+
+// FindingsWithMinSeverity returns the findings of at least the minimum severity, for instance HIGH for the CRITICAL
+// and HIGH findings
+func (r *ReadinessReport) FindingsWithMinSeverity(minSeverity string) []Finding {
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if severityScores[finding.Severity] >= severityScores[minSeverity] {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,151 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	rbacV1 "k8s.io/api/rbac/v1"
+)
+
+// ServiceAccountsCheckName is the name of the check of the service account tokens mounted in the workloads
+const ServiceAccountsCheckName = "service-accounts"
+
+// Statuses of the service accounts findings
+const (
+	// TokenAutomounted qualifies the workloads mounting the token of a service account bound to no role
+	TokenAutomounted = "token-automounted"
+	// PowerfulRole qualifies the workloads whose service account is bound to a powerful role
+	PowerfulRole = "powerful-role"
+)
+
+type serviceAccountsCheck struct {
+	kubernetesClient k8s.KubernetesClient
+}
+
+// NewServiceAccountsCheck creates a check reporting the workloads automounting the token of a service account bound to
+// no role, so not needing API access (LOW), and the workloads whose service account is bound to a powerful role:
+// cluster-admin (CRITICAL), or a role granting all the verbs or resources, access to the secrets, the creation of pods
+// or the escalate, bind or impersonate verbs (HIGH)
+func NewServiceAccountsCheck(kubernetesClient k8s.KubernetesClient) Check {
+	return &serviceAccountsCheck{kubernetesClient: kubernetesClient}
+}
+
+func (c *serviceAccountsCheck) Name() string {
+	return ServiceAccountsCheckName
+}
+
+// namespaceServiceAccounts holds the service accounts and role bindings of a namespace
+type namespaceServiceAccounts struct {
+	serviceAccounts map[string]v1.ServiceAccount
+	bindings        []k8s.RoleBinding
+}
+
+func (c *serviceAccountsCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	namespaces := make(map[string]*namespaceServiceAccounts)
+	var findings []Finding
+	for _, workload := range workloads {
+		namespace, ok := namespaces[workload.Namespace]
+		if !ok {
+			var err error
+			namespace, err = c.namespaceServiceAccounts(workload.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			namespaces[workload.Namespace] = namespace
+		}
+
+		serviceAccountName := workload.PodSpec.ServiceAccountName
+		if serviceAccountName == "" {
+			serviceAccountName = "default"
+		}
+		var bindings []k8s.RoleBinding
+		for _, binding := range namespace.bindings {
+			if binding.BindsServiceAccount(workload.Namespace, serviceAccountName) {
+				bindings = append(bindings, binding)
+			}
+		}
+		if len(bindings) == 0 && tokenAutomounted(workload.PodSpec, namespace.serviceAccounts[serviceAccountName]) {
+			finding := workloadFinding(workload, "LOW", fmt.Sprintf("token of service account %s is automounted but the service account is bound to no role, set automountServiceAccountToken to false", serviceAccountName))
+			finding.Status = TokenAutomounted
+			findings = append(findings, finding)
+		}
+		for _, binding := range bindings {
+			severity, reason := powerfulRole(binding)
+			if severity == "" {
+				continue
+			}
+			finding := workloadFinding(workload, severity, fmt.Sprintf("service account %s is bound to %s %s by %s %s, which %s",
+				serviceAccountName, binding.RoleRef.Kind, binding.RoleRef.Name, binding.Kind, binding.Name, reason))
+			finding.Status = PowerfulRole
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+func (c *serviceAccountsCheck) namespaceServiceAccounts(namespace string) (*namespaceServiceAccounts, error) {
+	serviceAccounts, err := c.kubernetesClient.GetServiceAccounts(namespace)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := c.kubernetesClient.GetRoleBindings(namespace)
+	if err != nil {
+		return nil, err
+	}
+	accounts := &namespaceServiceAccounts{serviceAccounts: make(map[string]v1.ServiceAccount), bindings: bindings}
+	for _, serviceAccount := range serviceAccounts {
+		accounts.serviceAccounts[serviceAccount.Name] = serviceAccount
+	}
+	return accounts, nil
+}
+
+// tokenAutomounted returns true when the token of the service account is mounted in the pods, the pod spec setting
+// overriding the service account one. Tokens are mounted unless disabled
+func tokenAutomounted(spec v1.PodSpec, serviceAccount v1.ServiceAccount) bool {
+	if spec.AutomountServiceAccountToken != nil {
+		return *spec.AutomountServiceAccountToken
+	}
+	return serviceAccount.AutomountServiceAccountToken == nil || *serviceAccount.AutomountServiceAccountToken
+}
+
+// powerfulRole returns the severity and the reason why the bound role is powerful, an empty severity when it is not
+func powerfulRole(binding k8s.RoleBinding) (string, string) {
+	if binding.RoleRef.Kind == "ClusterRole" && binding.RoleRef.Name == "cluster-admin" {
+		if binding.Kind == "ClusterRoleBinding" {
+			return "CRITICAL", "grants full control of the cluster"
+		}
+		return "HIGH", "grants full control of the namespace"
+	}
+	scope := "in the namespace"
+	if binding.Kind == "ClusterRoleBinding" {
+		scope = "in the cluster"
+	}
+	for _, rule := range binding.Rules {
+		switch {
+		case contains(rule.Verbs, rbacV1.VerbAll) && contains(rule.Resources, rbacV1.ResourceAll):
+			return "HIGH", "grants all the verbs on all the resources " + scope
+		case contains(rule.Verbs, rbacV1.VerbAll):
+			return "HIGH", fmt.Sprintf("grants all the verbs on %s %s", strings.Join(rule.Resources, ", "), scope)
+		case contains(rule.Resources, rbacV1.ResourceAll):
+			return "HIGH", fmt.Sprintf("grants %s on all the resources %s", strings.Join(rule.Verbs, ", "), scope)
+		case contains(rule.Resources, "secrets") && (contains(rule.Verbs, "get") || contains(rule.Verbs, "list") || contains(rule.Verbs, "watch")):
+			return "HIGH", "grants access to the secrets " + scope
+		case contains(rule.Resources, "pods") && contains(rule.Verbs, "create"):
+			return "HIGH", "grants the creation of pods " + scope
+		case contains(rule.Verbs, "escalate") || contains(rule.Verbs, "bind") || contains(rule.Verbs, "impersonate"):
+			return "HIGH", "grants privilege escalation " + scope
+		}
+	}
+	return "", ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	v1 "k8s.io/api/core/v1"
+	rbacV1 "k8s.io/api/rbac/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Service accounts check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		check                Check
+	)
+
+	serviceAccount := func(name string, automount *bool) v1.ServiceAccount {
+		return v1.ServiceAccount{ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: "shop"}, AutomountServiceAccountToken: automount}
+	}
+	binding := func(kind, name, roleKind, roleName, serviceAccountName string, rules ...rbacV1.PolicyRule) k8s.RoleBinding {
+		return k8s.RoleBinding{
+			Kind: kind, Name: name, Namespace: "shop",
+			RoleRef:  rbacV1.RoleRef{Kind: roleKind, Name: roleName},
+			Subjects: []rbacV1.Subject{{Kind: rbacV1.ServiceAccountKind, Name: serviceAccountName, Namespace: "shop"}},
+			Rules:    rules,
+		}
+	}
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		check = NewServiceAccountsCheck(mockKubernetesClient)
+	})
+
+	It("reports the workloads automounting the token of a service account bound to no role", func() {
+		mockKubernetesClient.On("GetServiceAccounts", "shop").Return([]v1.ServiceAccount{
+			serviceAccount("default", nil),
+			serviceAccount("web", boolPtr(false)),
+			serviceAccount("api", nil),
+		}, nil).Once()
+		mockKubernetesClient.On("GetRoleBindings", "shop").Return([]k8s.RoleBinding{
+			binding("RoleBinding", "api-reader", "Role", "reader", "api", rbacV1.PolicyRule{Verbs: []string{"get"}, Resources: []string{"configmaps"}}),
+		}, nil).Once()
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "cart", Namespace: "shop"},
+			{Kind: "Deployment", Name: "web", Namespace: "shop", PodSpec: v1.PodSpec{ServiceAccountName: "web"}},
+			{Kind: "Deployment", Name: "web-debug", Namespace: "shop", PodSpec: v1.PodSpec{ServiceAccountName: "web", AutomountServiceAccountToken: boolPtr(true)}},
+			{Kind: "Deployment", Name: "api", Namespace: "shop", PodSpec: v1.PodSpec{ServiceAccountName: "api"}},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "LOW", Namespace: "shop", Kind: "Deployment", Workload: "cart", Status: TokenAutomounted,
+				Message: "token of service account default is automounted but the service account is bound to no role, set automountServiceAccountToken to false"},
+			{Severity: "LOW", Namespace: "shop", Kind: "Deployment", Workload: "web-debug", Status: TokenAutomounted,
+				Message: "token of service account web is automounted but the service account is bound to no role, set automountServiceAccountToken to false"},
+		}))
+	})
+
+	It("reports the workloads whose service account is bound to a powerful role", func() {
+		mockKubernetesClient.On("GetServiceAccounts", "shop").Return([]v1.ServiceAccount{}, nil)
+		mockKubernetesClient.On("GetRoleBindings", "shop").Return([]k8s.RoleBinding{
+			binding("ClusterRoleBinding", "operator-admin", "ClusterRole", "cluster-admin", "operator"),
+			binding("RoleBinding", "ci-secrets", "Role", "secret-reader", "ci", rbacV1.PolicyRule{Verbs: []string{"list"}, Resources: []string{"secrets"}}),
+			binding("RoleBinding", "ci-reader", "Role", "reader", "ci", rbacV1.PolicyRule{Verbs: []string{"get"}, Resources: []string{"configmaps"}}),
+		}, nil)
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "operator", Namespace: "shop", PodSpec: v1.PodSpec{ServiceAccountName: "operator"}},
+			{Kind: "CronJob", Name: "deploy", Namespace: "shop", PodSpec: v1.PodSpec{ServiceAccountName: "ci"}},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages(findings)).To(Equal([]string{
+			"service account operator is bound to ClusterRole cluster-admin by ClusterRoleBinding operator-admin, which grants full control of the cluster",
+			"service account ci is bound to Role secret-reader by RoleBinding ci-secrets, which grants access to the secrets in the namespace",
+		}))
+		Expect(findings[0].Severity).To(Equal("CRITICAL"))
+		Expect(findings[1].Severity).To(Equal("HIGH"))
+		Expect(findings[1].Status).To(Equal(PowerfulRole))
+	})
+})
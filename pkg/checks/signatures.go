@@ -0,0 +1,79 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ImageSignaturesCheckName is the name of the image signature verification check
+const ImageSignaturesCheckName = "image-signatures"
+
+// Statuses of the image-signatures findings
+const (
+	// SignatureMissing is the status of the images without any cosign signature
+	SignatureMissing = "unsigned"
+	// SignatureInvalid is the status of the images whose signatures are not verified by any key or identity of the policy
+	SignatureInvalid = "invalid-signature"
+)
+
+type imageSignaturesCheck struct {
+	verifier *cosignVerifier
+}
+
+// NewImageSignaturesCheck creates a check reporting the containers running images that are unsigned, or whose cosign
+// signatures are not verified by the keys or identities of the policy, as the origin of those images is not established
+func NewImageSignaturesCheck(policy *SignaturePolicy) Check {
+	return &imageSignaturesCheck{verifier: newCosignVerifier(policy)}
+}
+
+func (c *imageSignaturesCheck) Name() string {
+	return ImageSignaturesCheckName
+}
+
+func (c *imageSignaturesCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	if err := c.verifier.validate(); err != nil {
+		return nil, err
+	}
+	return imageFindings(workloads, func(image string) (Finding, bool) {
+		result := c.verifier.verify(image, "verify")
+		switch result.outcome {
+		case cosignMissing:
+			return Finding{Severity: "HIGH", Status: SignatureMissing, Message: "image " + image + " is not signed"}, true
+		case cosignInvalid:
+			return Finding{Severity: "CRITICAL", Status: SignatureInvalid, Message: fmt.Sprintf("image %s has no signature verified by the configured keys or identities: %s", image, result.reason)}, true
+		}
+		return Finding{}, false
+	}), nil
+}
+
+// imageFindings returns the finding of each container of the workloads whose image has an issue. The images run by
+// several containers are inspected once
+func imageFindings(workloads []k8s.Workload, imageIssue func(image string) (Finding, bool)) []Finding {
+	type issue struct {
+		finding Finding
+		found   bool
+	}
+	issues := make(map[string]issue)
+	var findings []Finding
+	for _, workload := range workloads {
+		containers := append([]v1.Container{}, workload.PodSpec.InitContainers...)
+		for _, container := range append(containers, workload.PodSpec.Containers...) {
+			known, ok := issues[container.Image]
+			if !ok {
+				finding, found := imageIssue(container.Image)
+				known = issue{finding: finding, found: found}
+				issues[container.Image] = known
+			}
+			if !known.found {
+				continue
+			}
+			finding := workloadFinding(workload, known.finding.Severity, known.finding.Message)
+			finding.Status = known.finding.Status
+			finding.Container = container.Name
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
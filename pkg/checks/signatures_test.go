@@ -0,0 +1,101 @@
+package checks
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image signatures check", func() {
+
+	var (
+		mockRunner *mockCommandRunner
+		check      *imageSignaturesCheck
+		workloads  []k8s.Workload
+	)
+
+	const issuer = "https://token.actions.githubusercontent.com"
+
+	keyArgs := func(image string) []string {
+		return []string{"verify", "--key", "cosign.pub", image}
+	}
+	identityArgs := func(image string) []string {
+		return []string{"verify", "--certificate-oidc-issuer", issuer, "--certificate-identity-regexp", "^https://github.com/example/", image}
+	}
+
+	BeforeEach(func() {
+		mockRunner = &mockCommandRunner{}
+		check = &imageSignaturesCheck{verifier: &cosignVerifier{
+			policy: &SignaturePolicy{
+				PublicKeys: []string{"cosign.pub"},
+				Identities: []KeylessIdentity{{Issuer: issuer, Identity: "^https://github.com/example/"}},
+			},
+			commandRunner: mockRunner,
+			lookPath:      func(string) (string, error) { return "/usr/local/bin/cosign", nil },
+		}}
+		workloads = []k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "payments", PodSpec: v1.PodSpec{
+				InitContainers: []v1.Container{{Name: "migrate", Image: "registry/payments/api:1.2"}},
+				Containers:     []v1.Container{{Name: "api", Image: "registry/payments/api:1.2"}, {Name: "proxy", Image: "registry/proxy:2.0"}},
+			}},
+			{Kind: "Deployment", Name: "web", Namespace: "payments", PodSpec: v1.PodSpec{
+				Containers: []v1.Container{{Name: "web", Image: "registry/payments/web:3.1"}},
+			}},
+		}
+	})
+
+	It("reports the unsigned images and the images whose signatures are not verified by any key or identity", func() {
+		mockRunner.
+			On("Execute", "cosign", keyArgs("registry/payments/api:1.2")).Return([]byte{}, []byte("Error: no matching signatures: invalid signature when validating ASN.1 encoded signature\n"), errors.New("exit status 1")).
+			On("Execute", "cosign", identityArgs("registry/payments/api:1.2")).Return([]byte("[{}]"), []byte{}, nil).
+			On("Execute", "cosign", keyArgs("registry/proxy:2.0")).Return([]byte{}, []byte("Error: no signatures found\nmain.go:74: error during command execution: no signatures found\n"), errors.New("exit status 1")).
+			On("Execute", "cosign", keyArgs("registry/payments/web:3.1")).Return([]byte{}, []byte("Error: no matching signatures: invalid signature\n"), errors.New("exit status 1")).
+			On("Execute", "cosign", identityArgs("registry/payments/web:3.1")).Return([]byte{}, []byte("Error: no matching signatures: none of the expected identities matched what was in the certificate\n"), errors.New("exit status 1"))
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "proxy", Status: SignatureMissing, Message: "image registry/proxy:2.0 is not signed"},
+			{Severity: "CRITICAL", Namespace: "payments", Kind: "Deployment", Workload: "web", Container: "web", Status: SignatureInvalid, Message: "image registry/payments/web:3.1 has no signature verified by the configured keys or identities: Error: no matching signatures: none of the expected identities matched what was in the certificate"},
+		}))
+		// the images are verified once, and are not verified against the identities once found unsigned
+		mockRunner.AssertNumberOfCalls(GinkgoT(), "Execute", 5)
+	})
+
+	It("fails without key nor identity to verify the signatures against", func() {
+		check.verifier.policy = &SignaturePolicy{}
+
+		_, err := check.Run(workloads)
+
+		Expect(err).To(HaveOccurred())
+		mockRunner.AssertNotCalled(GinkgoT(), "Execute", mock.Anything, mock.Anything)
+	})
+
+	It("fails once without running cosign when cosign is not installed", func() {
+		check.verifier.lookPath = func(file string) (string, error) {
+			return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+		}
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).To(MatchError(ContainSubstring("cosign is required to verify the images")))
+		Expect(findings).To(BeEmpty())
+		mockRunner.AssertNotCalled(GinkgoT(), "Execute", mock.Anything, mock.Anything)
+	})
+
+	It("parses the keyless identities", func() {
+		identity, err := ParseKeylessIdentity(issuer + "=^https://github.com/example/.*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity).To(Equal(KeylessIdentity{Issuer: issuer, Identity: "^https://github.com/example/.*"}))
+
+		_, err = ParseKeylessIdentity(issuer)
+		Expect(err).To(HaveOccurred())
+	})
+})
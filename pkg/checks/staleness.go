@@ -0,0 +1,78 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// ImageStalenessCheckName is the name of the image staleness check
+const ImageStalenessCheckName = "image-staleness"
+
+// imageInspectTimeout bounds the lookup of the image config blob in the registry
+const imageInspectTimeout = time.Minute
+
+// reproducibleBuildCutoff is the creation time before which the image is considered built reproducibly, for instance
+// with the epoch timestamp of ko or Bazel, so without a meaningful creation time
+var reproducibleBuildCutoff = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type imageStalenessCheck struct {
+	maxAge       time.Duration
+	dockerClient scanner.DockerClient
+	now          func() time.Time
+}
+
+// NewImageStalenessCheck creates a check reporting the containers running an image built longer than maxAge ago, as
+// such workloads have not been rebuilt against patched base images. The creation time is read from the image config
+// blob in the registry, without pulling the image
+func NewImageStalenessCheck(maxAge time.Duration) Check {
+	return &imageStalenessCheck{maxAge: maxAge, dockerClient: scanner.NewDockerClient(), now: time.Now}
+}
+
+func (c *imageStalenessCheck) Name() string {
+	return ImageStalenessCheckName
+}
+
+func (c *imageStalenessCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	if c.maxAge <= 0 {
+		return nil, errors.New("the maximum image age must be positive")
+	}
+	now := c.now()
+	return imageFindings(workloads, func(image string) (Finding, bool) {
+		created, ok := c.imageCreated(image)
+		if !ok {
+			return Finding{}, false
+		}
+		age := now.Sub(created)
+		if age <= c.maxAge {
+			return Finding{}, false
+		}
+		return Finding{Severity: "MEDIUM", Message: fmt.Sprintf("image %s was built on %s, %d days ago, older than the maximum age of %d days",
+			image, created.Format("2006-01-02"), days(age), days(c.maxAge))}, true
+	}), nil
+}
+
+// imageCreated returns the creation time of the image, false when it cannot be determined
+func (c *imageStalenessCheck) imageCreated(image string) (time.Time, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), imageInspectTimeout)
+	defer cancel()
+	created, err := c.dockerClient.ImageCreated(ctx, image)
+	if err != nil {
+		logr.Warnf("Unable to determine the creation time of image %s, skipping it: %v", image, err)
+		return time.Time{}, false
+	}
+	if created.Before(reproducibleBuildCutoff) {
+		logr.Debugf("Image %s has no meaningful creation time (%s), skipping it", image, created.Format(time.RFC3339))
+		return time.Time{}, false
+	}
+	return created, true
+}
+
+func days(duration time.Duration) int {
+	return int(duration.Hours() / 24)
+}
@@ -0,0 +1,61 @@
+package checks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner/scannertest"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image staleness check", func() {
+	var (
+		dockerClient *scannertest.DockerClient
+		check        *imageStalenessCheck
+		workloads    []k8s.Workload
+	)
+
+	BeforeEach(func() {
+		dockerClient = &scannertest.DockerClient{}
+		check = &imageStalenessCheck{
+			maxAge:       90 * 24 * time.Hour,
+			dockerClient: dockerClient,
+			now:          func() time.Time { return time.Date(2023, time.June, 30, 12, 0, 0, 0, time.UTC) },
+		}
+		workloads = []k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "payments", PodSpec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "migrate", Image: "registry.example.com/payments/migrate:1.0"}},
+			Containers: []v1.Container{
+				{Name: "api", Image: "registry.example.com/payments/api:1.2"},
+				{Name: "proxy", Image: "nginx:1.25"},
+				{Name: "tool", Image: "ko.local/tool:0.1"},
+				{Name: "cache", Image: "redis:7.2"},
+			},
+		}}}
+	})
+
+	It("reports the containers running an image older than the maximum age", func() {
+		dockerClient.On("ImageCreated", "registry.example.com/payments/migrate:1.0").Return(time.Date(2023, time.January, 10, 8, 0, 0, 0, time.UTC), nil)
+		dockerClient.On("ImageCreated", "registry.example.com/payments/api:1.2").Return(time.Date(2023, time.June, 1, 8, 0, 0, 0, time.UTC), nil)
+		dockerClient.On("ImageCreated", "nginx:1.25").Return(time.Date(2023, time.March, 31, 8, 0, 0, 0, time.UTC), nil)
+		dockerClient.On("ImageCreated", "ko.local/tool:0.1").Return(time.Unix(0, 0), nil)
+		dockerClient.On("ImageCreated", "redis:7.2").Return(time.Time{}, errors.New("manifest unknown"))
+
+		findings, err := check.Run(workloads)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "MEDIUM", Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "migrate", Message: "image registry.example.com/payments/migrate:1.0 was built on 2023-01-10, 171 days ago, older than the maximum age of 90 days"},
+			{Severity: "MEDIUM", Namespace: "payments", Kind: "Deployment", Workload: "api", Container: "proxy", Message: "image nginx:1.25 was built on 2023-03-31, 91 days ago, older than the maximum age of 90 days"},
+		}))
+	})
+
+	It("fails without a positive maximum age", func() {
+		_, err := NewImageStalenessCheck(0).Run(workloads)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,100 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// TopologySpreadCheckName is the name of the check of the spread of the replicas across nodes and zones
+const TopologySpreadCheckName = "topology-spread"
+
+type topologySpreadCheck struct {
+	kubernetesClient k8s.KubernetesClient
+}
+
+// NewTopologySpreadCheck creates a check reporting the Deployments and StatefulSets running more than one pod without
+// pod anti-affinity or topologySpreadConstraints whose pods all run on the same node or in the same zone, a single
+// node or zone failure taking all of them out. The workloads of manifests, not scheduled yet, are reported as their
+// replicas may all be scheduled onto the same node
+func NewTopologySpreadCheck(kubernetesClient k8s.KubernetesClient) Check {
+	return &topologySpreadCheck{kubernetesClient: kubernetesClient}
+}
+
+func (c *topologySpreadCheck) Name() string {
+	return TopologySpreadCheckName
+}
+
+func (c *topologySpreadCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	var zones map[string]string
+	var findings []Finding
+	for _, workload := range workloads {
+		if (workload.Kind != "Deployment" && workload.Kind != "StatefulSet") || workload.PodCount < 2 || isSpread(workload.PodSpec) {
+			continue
+		}
+		if len(workload.NodeNames) == 0 {
+			findings = append(findings, workloadFinding(workload, "MEDIUM", fmt.Sprintf("%d replicas without pod anti-affinity or topologySpreadConstraints, they may all be scheduled onto the same node", workload.PodCount)))
+			continue
+		}
+		if len(workload.NodeNames) < 2 {
+			continue
+		}
+		if node, ok := sameValue(workload.NodeNames, func(nodeName string) string { return nodeName }); ok {
+			findings = append(findings, workloadFinding(workload, "HIGH", fmt.Sprintf("all the %d scheduled pods run on node %s without pod anti-affinity or topologySpreadConstraints, a single node failure takes all of them out", len(workload.NodeNames), node)))
+			continue
+		}
+		if zones == nil {
+			var err error
+			zones, err = c.nodeZones()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if zone, ok := sameValue(workload.NodeNames, func(nodeName string) string { return zones[nodeName] }); ok && zone != "" {
+			findings = append(findings, workloadFinding(workload, "MEDIUM", fmt.Sprintf("all the %d scheduled pods run in zone %s without pod anti-affinity or topologySpreadConstraints, a single zone failure takes all of them out", len(workload.NodeNames), zone)))
+		}
+	}
+	return findings, nil
+}
+
+// nodeZones returns the zone of each node of the cluster by node name, from the well-known zone label of the nodes
+func (c *topologySpreadCheck) nodeZones() (map[string]string, error) {
+	nodes, err := c.kubernetesClient.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	zones := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		zone, ok := node.Labels[v1.LabelTopologyZone]
+		if !ok {
+			zone = node.Labels[v1.LabelFailureDomainBetaZone]
+		}
+		zones[node.Name] = zone
+	}
+	return zones, nil
+}
+
+// isSpread returns true when the pods declare a pod anti-affinity or topologySpreadConstraints, so that the scheduler
+// spreads them across nodes or zones
+func isSpread(spec v1.PodSpec) bool {
+	if len(spec.TopologySpreadConstraints) > 0 {
+		return true
+	}
+	if spec.Affinity == nil || spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	antiAffinity := spec.Affinity.PodAntiAffinity
+	return len(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 || len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0
+}
+
+// sameValue returns the value of the node names when all of them have the same value
+func sameValue(nodeNames []string, valueOf func(nodeName string) string) (string, bool) {
+	value := valueOf(nodeNames[0])
+	for _, nodeName := range nodeNames[1:] {
+		if valueOf(nodeName) != value {
+			return "", false
+		}
+	}
+	return value, true
+}
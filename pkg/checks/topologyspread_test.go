@@ -0,0 +1,69 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Topology spread check", func() {
+
+	var (
+		mockKubernetesClient *k8stest.KubernetesClient
+		check                Check
+	)
+
+	node := func(name, zone string) v1.Node {
+		return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelTopologyZone: zone}}}
+	}
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		check = NewTopologySpreadCheck(mockKubernetesClient)
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{node("node-1", "zone-a"), node("node-2", "zone-a"), node("node-3", "zone-b")}, nil).Once()
+	})
+
+	It("reports the replicated workloads whose pods run on the same node or in the same zone", func() {
+		spread := v1.PodSpec{TopologySpreadConstraints: []v1.TopologySpreadConstraint{{TopologyKey: v1.LabelHostname}}}
+		antiAffinity := v1.PodSpec{Affinity: &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{{Weight: 100}},
+		}}}
+
+		findings, err := check.Run([]k8s.Workload{
+			{Kind: "Deployment", Name: "api", Namespace: "shop", PodCount: 2, NodeNames: []string{"node-1", "node-1"}},
+			{Kind: "StatefulSet", Name: "db", Namespace: "shop", PodCount: 3, NodeNames: []string{"node-1", "node-2", "node-2"}},
+			{Kind: "Deployment", Name: "web", Namespace: "shop", PodCount: 2, NodeNames: []string{"node-1", "node-3"}},
+			{Kind: "Deployment", Name: "spread", Namespace: "shop", PodCount: 2, NodeNames: []string{"node-1", "node-1"}, PodSpec: spread},
+			{Kind: "Deployment", Name: "anti-affinity", Namespace: "shop", PodCount: 2, NodeNames: []string{"node-1", "node-1"}, PodSpec: antiAffinity},
+			{Kind: "Deployment", Name: "pending", Namespace: "shop", PodCount: 2, NodeNames: []string{"node-1"}},
+			{Kind: "DaemonSet", Name: "agent", Namespace: "shop", PodCount: 2, NodeNames: []string{"node-1", "node-1"}},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages(findings)).To(Equal([]string{
+			"all the 2 scheduled pods run on node node-1 without pod anti-affinity or topologySpreadConstraints, a single node failure takes all of them out",
+			"all the 3 scheduled pods run in zone zone-a without pod anti-affinity or topologySpreadConstraints, a single zone failure takes all of them out",
+		}))
+		Expect(findings[0].Severity).To(Equal("HIGH"))
+		Expect(findings[1].Severity).To(Equal("MEDIUM"))
+		Expect(findings[1].Workload).To(Equal("db"))
+	})
+
+	It("reports the replicated workloads of manifests without anti-affinity or topologySpreadConstraints", func() {
+		findings, err := check.Run([]k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "shop", PodCount: 3}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{{
+			Severity:  "MEDIUM",
+			Namespace: "shop",
+			Kind:      "Deployment",
+			Workload:  "api",
+			Message:   "3 replicas without pod anti-affinity or topologySpreadConstraints, they may all be scheduled onto the same node",
+		}}))
+		mockKubernetesClient.AssertNotCalled(GinkgoT(), "GetNodes")
+	})
+})
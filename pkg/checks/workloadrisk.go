@@ -0,0 +1,153 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+)
+
+// WorkloadRiskCheckName is the name of the check of the workloads able to take over their node
+const WorkloadRiskCheckName = "workload-risk"
+
+// Statuses of the workload risk findings
+const (
+	RiskPrivileged             = "privileged"
+	RiskHostNamespace          = "host-namespace"
+	RiskHostPath               = "host-path"
+	RiskPrivilegeEscalation    = "privilege-escalation"
+	RiskAddedCapability        = "added-capability"
+	RiskWritableRootFilesystem = "writable-root-filesystem"
+)
+
+// DefaultSafeCapabilities are the capabilities the containers may add without being reported by default
+var DefaultSafeCapabilities = []string{"NET_BIND_SERVICE"}
+
+// criticalCapabilities are the capabilities giving control of the node when added, as they allow to mount
+// filesystems, load kernel modules, trace the other processes or access the raw devices
+var criticalCapabilities = map[string]bool{
+	"ALL": true, "SYS_ADMIN": true, "SYS_MODULE": true, "SYS_PTRACE": true, "SYS_RAWIO": true, "DAC_READ_SEARCH": true, "BPF": true,
+}
+
+// criticalHostPaths are the host paths giving control of the node or of its containers when mounted
+var criticalHostPaths = []string{"/", "/etc", "/proc", "/root", "/var/lib/kubelet", "/var/run/docker.sock", "/run/containerd/containerd.sock", "/var/run/crio/crio.sock"}
+
+type workloadRiskCheck struct {
+	safeCapabilities map[string]bool
+	// writableRootFilesystem reports the containers running with a writable root filesystem, off by default as most
+	// workloads do
+	writableRootFilesystem bool
+}
+
+// NewWorkloadRiskCheck creates a check reporting the containers running privileged, the pods sharing the host network,
+// PID or IPC namespace and the containers mounting hostPath volumes. The privileged containers and the mounts of
+// critical host paths, such as the container runtime socket, are reported as CRITICAL and the others as HIGH.
+// The runtime privileges are reported too: the containers allowing privilege escalation (HIGH), adding capabilities
+// beyond the safe capabilities (HIGH, CRITICAL for the capabilities giving control of the node) and, with
+// writableRootFilesystem, running with a writable root filesystem (LOW)
+func NewWorkloadRiskCheck(safeCapabilities []string, writableRootFilesystem bool) Check {
+	safe := make(map[string]bool)
+	for _, capability := range safeCapabilities {
+		safe[capabilityName(v1.Capability(capability))] = true
+	}
+	return &workloadRiskCheck{safeCapabilities: safe, writableRootFilesystem: writableRootFilesystem}
+}
+
+func (c *workloadRiskCheck) Name() string {
+	return WorkloadRiskCheckName
+}
+
+func (c *workloadRiskCheck) Run(workloads []k8s.Workload) ([]Finding, error) {
+	var findings []Finding
+	for _, workload := range workloads {
+		spec := workload.PodSpec
+		addFinding := func(severity, status, container, message string) {
+			finding := workloadFinding(workload, severity, message)
+			finding.Container = container
+			finding.Status = status
+			findings = append(findings, finding)
+		}
+		if spec.HostNetwork {
+			addFinding("HIGH", RiskHostNamespace, "", "host network namespace is shared, the pod reaches the node services")
+		}
+		if spec.HostPID {
+			addFinding("HIGH", RiskHostNamespace, "", "host PID namespace is shared, the pod sees the node processes")
+		}
+		if spec.HostIPC {
+			addFinding("HIGH", RiskHostNamespace, "", "host IPC namespace is shared")
+		}
+
+		hostPaths := make(map[string]string)
+		for _, volume := range spec.Volumes {
+			if volume.HostPath != nil {
+				hostPaths[volume.Name] = volume.HostPath.Path
+			}
+		}
+		var containers []v1.Container
+		containers = append(containers, spec.InitContainers...)
+		containers = append(containers, spec.Containers...)
+		for _, container := range containers {
+			securityContext := container.SecurityContext
+			if securityContext == nil {
+				securityContext = &v1.SecurityContext{}
+			}
+			privileged := securityContext.Privileged != nil && *securityContext.Privileged
+			if privileged {
+				addFinding("CRITICAL", RiskPrivileged, container.Name, "container is privileged, it has full access to the node")
+			} else if securityContext.AllowPrivilegeEscalation != nil && *securityContext.AllowPrivilegeEscalation {
+				addFinding("HIGH", RiskPrivilegeEscalation, container.Name, "allowPrivilegeEscalation is true, the processes may gain more privileges than their parent, for instance through setuid binaries")
+			}
+			if securityContext.Capabilities != nil {
+				for _, capability := range securityContext.Capabilities.Add {
+					name := capabilityName(capability)
+					if c.safeCapabilities[name] {
+						continue
+					}
+					severity := "HIGH"
+					if criticalCapabilities[name] {
+						severity = "CRITICAL"
+					}
+					addFinding(severity, RiskAddedCapability, container.Name, fmt.Sprintf("capability %s is added beyond the safe capabilities", name))
+				}
+			}
+			if c.writableRootFilesystem && (securityContext.ReadOnlyRootFilesystem == nil || !*securityContext.ReadOnlyRootFilesystem) {
+				addFinding("LOW", RiskWritableRootFilesystem, container.Name, "root filesystem is writable, readOnlyRootFilesystem is not set to true")
+			}
+			for _, mount := range container.VolumeMounts {
+				path, ok := hostPaths[mount.Name]
+				if !ok {
+					continue
+				}
+				severity := "HIGH"
+				if isCriticalHostPath(path) {
+					severity = "CRITICAL"
+				}
+				access := "read-write"
+				if mount.ReadOnly {
+					access = "read-only"
+				}
+				addFinding(severity, RiskHostPath, container.Name, fmt.Sprintf("hostPath %s is mounted %s at %s", path, access, mount.MountPath))
+			}
+		}
+	}
+	return findings, nil
+}
+
+// capabilityName returns the capability without the CAP_ prefix, in upper case, for instance SYS_ADMIN for cap_sys_admin
+func capabilityName(capability v1.Capability) string {
+	return strings.TrimPrefix(strings.ToUpper(string(capability)), "CAP_")
+}
+
+func isCriticalHostPath(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return true
+	}
+	for _, critical := range criticalHostPaths {
+		if path == critical {
+			return true
+		}
+	}
+	return false
+}
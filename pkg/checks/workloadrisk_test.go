@@ -0,0 +1,111 @@
+package checks
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Workload risk check", func() {
+
+	var (
+		check Check
+	)
+
+	BeforeEach(func() {
+		check = NewWorkloadRiskCheck(DefaultSafeCapabilities, true)
+	})
+
+	hardenedPodSpec := func() v1.PodSpec {
+		spec := restrictedPodSpec()
+		spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem = boolPtr(true)
+		return spec
+	}
+
+	It("does not report the hardened workloads without privileged container, host namespace nor hostPath volume", func() {
+		findings, err := check.Run([]k8s.Workload{{Name: "api", PodSpec: hardenedPodSpec()}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+
+	It("reports the privileged containers, the host namespaces and the hostPath volumes", func() {
+		spec := hardenedPodSpec()
+		spec.HostNetwork = true
+		spec.HostPID = true
+		spec.Volumes = []v1.Volume{
+			{Name: "runtime", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/run/containerd/containerd.sock"}}},
+			{Name: "logs", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}}},
+			{Name: "config", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		}
+		spec.Containers[0].SecurityContext.Privileged = boolPtr(true)
+		spec.Containers[0].VolumeMounts = []v1.VolumeMount{
+			{Name: "runtime", MountPath: "/run/containerd/containerd.sock"},
+			{Name: "logs", MountPath: "/host/logs", ReadOnly: true},
+			{Name: "config", MountPath: "/config"},
+		}
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "DaemonSet", Name: "agent", Namespace: "ns", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Namespace: "ns", Kind: "DaemonSet", Workload: "agent", Status: RiskHostNamespace,
+				Message: "host network namespace is shared, the pod reaches the node services"},
+			{Severity: "HIGH", Namespace: "ns", Kind: "DaemonSet", Workload: "agent", Status: RiskHostNamespace,
+				Message: "host PID namespace is shared, the pod sees the node processes"},
+			{Severity: "CRITICAL", Namespace: "ns", Kind: "DaemonSet", Workload: "agent", Container: "app", Status: RiskPrivileged,
+				Message: "container is privileged, it has full access to the node"},
+			{Severity: "CRITICAL", Namespace: "ns", Kind: "DaemonSet", Workload: "agent", Container: "app", Status: RiskHostPath,
+				Message: "hostPath /run/containerd/containerd.sock is mounted read-write at /run/containerd/containerd.sock"},
+			{Severity: "HIGH", Namespace: "ns", Kind: "DaemonSet", Workload: "agent", Container: "app", Status: RiskHostPath,
+				Message: "hostPath /var/log is mounted read-only at /host/logs"},
+		}))
+	})
+
+	It("reports the privilege escalation, the added capabilities beyond the safe ones and the writable root filesystems", func() {
+		spec := hardenedPodSpec()
+		spec.InitContainers = []v1.Container{{Name: "setup", SecurityContext: &v1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(true),
+			ReadOnlyRootFilesystem:   boolPtr(true),
+		}}}
+		spec.Containers[0].SecurityContext.Capabilities.Add = []v1.Capability{"NET_BIND_SERVICE", "CAP_NET_RAW", "sys_admin"}
+		spec.Containers = append(spec.Containers, v1.Container{Name: "sidecar"})
+
+		findings, err := check.Run([]k8s.Workload{{Kind: "Deployment", Name: "api", Namespace: "ns", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(Equal([]Finding{
+			{Severity: "HIGH", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "setup", Status: RiskPrivilegeEscalation,
+				Message: "allowPrivilegeEscalation is true, the processes may gain more privileges than their parent, for instance through setuid binaries"},
+			{Severity: "HIGH", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "app", Status: RiskAddedCapability,
+				Message: "capability NET_RAW is added beyond the safe capabilities"},
+			{Severity: "CRITICAL", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "app", Status: RiskAddedCapability,
+				Message: "capability SYS_ADMIN is added beyond the safe capabilities"},
+			{Severity: "LOW", Namespace: "ns", Kind: "Deployment", Workload: "api", Container: "sidecar", Status: RiskWritableRootFilesystem,
+				Message: "root filesystem is writable, readOnlyRootFilesystem is not set to true"},
+		}))
+	})
+
+	It("does not report the writable root filesystems unless enabled", func() {
+		spec := hardenedPodSpec()
+		spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem = nil
+
+		findings, err := NewWorkloadRiskCheck(DefaultSafeCapabilities, false).Run([]k8s.Workload{{Name: "api", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+
+	It("does not report the capabilities of the safe capabilities", func() {
+		spec := hardenedPodSpec()
+		spec.Containers[0].SecurityContext.Capabilities.Add = []v1.Capability{"NET_BIND_SERVICE", "NET_RAW"}
+
+		findings, err := NewWorkloadRiskCheck([]string{"cap_net_raw"}, true).Run([]k8s.Workload{{Name: "api", PodSpec: spec}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Message).To(Equal("capability NET_BIND_SERVICE is added beyond the safe capabilities"))
+	})
+})
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
 )
@@ -10,10 +12,14 @@ import (
 // CommandRunner defines how to run commands
 type CommandRunner interface {
 	Execute(cmd string, arg []string) (output []byte, erroutput []byte, err error)
+	// ExecuteContext executes the command, killing it when the context is done
+	ExecuteContext(ctx context.Context, cmd string, arg []string) (output []byte, erroutput []byte, err error)
 }
 
 // ExecCommandRunner is a thin wrapper around exec.Command
 type ExecCommandRunner struct {
+	// env are the environment variables set on top of the environment of the process, for instance TMPDIR
+	env []string
 }
 
 // NewCommandRunner creates a new CommandRunner
@@ -21,10 +27,24 @@ func NewCommandRunner() CommandRunner {
 	return &ExecCommandRunner{}
 }
 
+// NewCommandRunnerWithEnv creates a new CommandRunner running the commands with the environment variables, given as
+// key=value, on top of the environment of the process
+func NewCommandRunnerWithEnv(env []string) CommandRunner {
+	return &ExecCommandRunner{env: env}
+}
+
 // Execute will execute command
 func (c *ExecCommandRunner) Execute(cmd string, arg []string) (output []byte, erroutput []byte, err error) {
+	return c.ExecuteContext(context.Background(), cmd, arg)
+}
+
+// ExecuteContext will execute command until the context is done
+func (c *ExecCommandRunner) ExecuteContext(ctx context.Context, cmd string, arg []string) (output []byte, erroutput []byte, err error) {
 	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
-		cmd := exec.Command(cmd, arg...)
+		cmd := exec.CommandContext(ctx, cmd, arg...)
+		if len(c.env) > 0 {
+			cmd.Env = append(os.Environ(), c.env...)
+		}
 
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
@@ -0,0 +1,161 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// EnvPrefix is the prefix of the environment variables setting the flags, for instance PROD_READINESS_SCAN_WORKERS
+// for --scan-workers
+const EnvPrefix = "PROD_READINESS_"
+
+// Values are the flag values of a config file by flag name
+type Values map[string]string
+
+// EnvVar returns the environment variable setting the flag, for instance PROD_READINESS_SCAN_WORKERS for scan-workers
+func EnvVar(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Load reads the YAML config file, a mapping of the flag names to their value. The lists are set as comma separated
+// values and the mappings as comma separated key=value pairs, as they are given on the command line
+func Load(path string) (Values, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the config file %s: %v", path, err)
+	}
+	values, err := parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return values, nil
+}
+
+func parse(content []byte) (Values, error) {
+	content, err := yaml.ToJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// the numbers are kept as written so that large integers are not rounded
+	decoder.UseNumber()
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("expecting a mapping of the flag names to their value: %v", err)
+	}
+	values := make(Values)
+	for name, setting := range settings {
+		if setting == nil {
+			continue
+		}
+		value, err := flagValue(setting)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %v", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// flagValue returns the command line representation of the setting
+func flagValue(setting interface{}) (string, error) {
+	switch setting := setting.(type) {
+	case []interface{}:
+		var items []string
+		for _, item := range setting {
+			value, err := flagValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		var pairs []string
+		for key, item := range setting {
+			value, err := flagValue(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+"="+value)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(setting), nil
+	}
+}
+
+// Apply sets the flags not given on the command line from their environment variable, or else from the config file
+// values, so that the flags take precedence over the environment variables, which take precedence over the config file
+func Apply(flags *pflag.FlagSet, values Values, lookupEnv func(string) (string, bool)) error {
+	var errs []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			return
+		}
+		source := "environment variable " + EnvVar(flag.Name)
+		value, ok := lookupEnv(EnvVar(flag.Name))
+		if !ok {
+			source = "config file setting " + flag.Name
+			value, ok = values[flag.Name]
+		}
+		if !ok {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid value %q of the %s: %v", value, source, err))
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Validate returns the errors of the config file values and of the PROD_READINESS_ environment variables of environ:
+// the settings that are not a flag of the flag sets, and the values the flags reject. The flags are set to the values
+// to check them, Validate is meant to be run instead of the commands
+func Validate(values Values, environ []string, flagSets []*pflag.FlagSet) []error {
+	var errs []error
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := validate(name, values[name], flagSets); err != nil {
+			errs = append(errs, fmt.Errorf("config file setting %s: %v", name, err))
+		}
+	}
+
+	for _, variable := range environ {
+		key, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(key, EnvPrefix), "_", "-"))
+		if err := validate(name, value, flagSets); err != nil {
+			errs = append(errs, fmt.Errorf("environment variable %s: %v", key, err))
+		}
+	}
+	return errs
+}
+
+func validate(name, value string, flagSets []*pflag.FlagSet) error {
+	for _, flags := range flagSets {
+		if flags.Lookup(name) != nil {
+			return flags.Set(name, value)
+		}
+	}
+	return fmt.Errorf("unknown flag %s", name)
+}
@@ -0,0 +1,125 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestConfigFile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Config File Suite")
+}
+
+const configFile = `
+scan-workers: 20
+severity: [HIGH, CRITICAL]
+scan-timeout: 10m
+report-per-team: true
+otlp-headers:
+  x-api-key: secret
+  x-team: platform
+min-cvss-score: 7.5
+kubeconfig: null
+`
+
+var _ = Describe("Config file", func() {
+
+	var (
+		flags          *pflag.FlagSet
+		scanWorkers    int
+		severity       []string
+		scanTimeout    time.Duration
+		reportPerTeam  bool
+		otlpHeaders    string
+		minCVSSScore   float64
+		reportTemplate string
+		env            map[string]string
+	)
+
+	BeforeEach(func() {
+		flags = pflag.NewFlagSet("scan", pflag.ContinueOnError)
+		flags.IntVar(&scanWorkers, "scan-workers", 10, "")
+		flags.StringSliceVar(&severity, "severity", []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}, "")
+		flags.DurationVar(&scanTimeout, "scan-timeout", 5*time.Minute, "")
+		flags.BoolVar(&reportPerTeam, "report-per-team", false, "")
+		flags.StringVar(&otlpHeaders, "otlp-headers", "", "")
+		flags.Float64Var(&minCVSSScore, "min-cvss-score", 0, "")
+		flags.StringVar(&reportTemplate, "report-input-template", "templates/report.md.tmpl", "")
+		env = map[string]string{}
+	})
+
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	load := func(content string) Values {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		values, err := Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		return values
+	}
+
+	It("reads the flag values of the YAML config file as given on the command line", func() {
+		Expect(load(configFile)).To(Equal(Values{
+			"scan-workers":    "20",
+			"severity":        "HIGH,CRITICAL",
+			"scan-timeout":    "10m",
+			"report-per-team": "true",
+			"otlp-headers":    "x-api-key=secret,x-team=platform",
+			"min-cvss-score":  "7.5",
+		}))
+	})
+
+	It("fails when the config file is not a mapping", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte("- scan-workers"), 0644)).To(Succeed())
+
+		_, err := Load(path)
+
+		Expect(err).To(MatchError(ContainSubstring("expecting a mapping")))
+	})
+
+	It("gives precedence to the flags over the environment variables over the config file", func() {
+		Expect(flags.Parse([]string{"--scan-workers", "5"})).To(Succeed())
+		env["PROD_READINESS_SCAN_WORKERS"] = "8"
+		env["PROD_READINESS_SEVERITY"] = "CRITICAL"
+		env["PROD_READINESS_REPORT_INPUT_TEMPLATE"] = "templates/report.html.tmpl"
+
+		Expect(Apply(flags, load(configFile), lookupEnv)).To(Succeed())
+
+		Expect(scanWorkers).To(Equal(5))
+		Expect(severity).To(Equal([]string{"CRITICAL"}))
+		Expect(reportTemplate).To(Equal("templates/report.html.tmpl"))
+		Expect(scanTimeout).To(Equal(10 * time.Minute))
+		Expect(reportPerTeam).To(BeTrue())
+		Expect(otlpHeaders).To(Equal("x-api-key=secret,x-team=platform"))
+		Expect(minCVSSScore).To(Equal(7.5))
+	})
+
+	It("reports the invalid values", func() {
+		env["PROD_READINESS_SCAN_WORKERS"] = "many"
+
+		err := Apply(flags, Values{}, lookupEnv)
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid value "many" of the environment variable PROD_READINESS_SCAN_WORKERS`)))
+	})
+
+	It("validates the config file settings and the environment variables against the flags", func() {
+		errs := Validate(Values{"scan-workers": "20", "scan-timout": "10m", "report-per-team": "maybe"},
+			[]string{"PROD_READINESS_SEVERITY=HIGH", "PROD_READINESS_WORKERS=5", "HOME=/root"}, []*pflag.FlagSet{flags})
+
+		Expect(errs).To(HaveLen(3))
+		Expect(errs[0]).To(MatchError(ContainSubstring("config file setting report-per-team: ")))
+		Expect(errs[1]).To(MatchError("config file setting scan-timout: unknown flag scan-timout"))
+		Expect(errs[2]).To(MatchError("environment variable PROD_READINESS_WORKERS: unknown flag workers"))
+	})
+})
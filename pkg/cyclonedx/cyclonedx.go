@@ -0,0 +1,414 @@
+package cyclonedx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+	logr "github.com/sirupsen/logrus"
+)
+
+// specVersion is the version of the CycloneDX specification the VDR is generated with
+const specVersion = "1.5"
+
+// BOM is a CycloneDX Vulnerability Disclosure Report: the scanned images as container components holding their
+// packages, and the vulnerabilities found in the packages
+type BOM struct {
+	BOMFormat       string          `json:"bomFormat"`
+	SpecVersion     string          `json:"specVersion"`
+	SerialNumber    string          `json:"serialNumber"`
+	Version         int             `json:"version"`
+	Metadata        Metadata        `json:"metadata"`
+	Components      []Component     `json:"components"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Metadata describes when and by which tool the report was produced, and the cluster it describes
+type Metadata struct {
+	Timestamp  string     `json:"timestamp"`
+	Tools      Tools      `json:"tools"`
+	Component  *Component `json:"component,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Tools are the tools that produced the report
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is a scanned image, a package of an image or a tool
+type Component struct {
+	BOMRef     string      `json:"bom-ref,omitempty"`
+	Type       string      `json:"type"`
+	Group      string      `json:"group,omitempty"`
+	Name       string      `json:"name"`
+	Version    string      `json:"version,omitempty"`
+	PURL       string      `json:"purl,omitempty"`
+	Hashes     []Hash      `json:"hashes,omitempty"`
+	Properties []Property  `json:"properties,omitempty"`
+	Components []Component `json:"components,omitempty"`
+}
+
+// Hash is the digest of an image
+type Hash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+// Property is a name-value pair of the production-readiness namespace, for instance the team running an image
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Vulnerability is a vulnerability found in packages of an image
+type Vulnerability struct {
+	BOMRef         string     `json:"bom-ref"`
+	ID             string     `json:"id"`
+	Source         *Source    `json:"source,omitempty"`
+	Ratings        []Rating   `json:"ratings,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Recommendation string     `json:"recommendation,omitempty"`
+	Advisories     []Advisory `json:"advisories,omitempty"`
+	Published      string     `json:"published,omitempty"`
+	Updated        string     `json:"updated,omitempty"`
+	Analysis       *Analysis  `json:"analysis,omitempty"`
+	Affects        []Affect   `json:"affects"`
+	Properties     []Property `json:"properties,omitempty"`
+}
+
+// Source is the database the vulnerability is published in
+type Source struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Rating is the severity of the vulnerability with its CVSS score when known
+type Rating struct {
+	Source   *Source `json:"source,omitempty"`
+	Score    float64 `json:"score,omitempty"`
+	Severity string  `json:"severity"`
+	Method   string  `json:"method,omitempty"`
+	Vector   string  `json:"vector,omitempty"`
+}
+
+// Advisory is a reference of the vulnerability
+type Advisory struct {
+	URL string `json:"url"`
+}
+
+// Analysis is the triage of the vulnerability in the image, see scanner.FindingTriage
+type Analysis struct {
+	State    string   `json:"state"`
+	Response []string `json:"response,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Affect is a package the vulnerability is found in, referenced by its bom-ref
+type Affect struct {
+	Ref string `json:"ref"`
+}
+
+// sbom is the part of the CycloneDX SBOMs generated by trivy read to list the packages of the images
+type sbom struct {
+	Components []Component `json:"components"`
+}
+
+// NewVDR converts the report to a CycloneDX Vulnerability Disclosure Report. The packages of each image are read from
+// its SBOM when generated, see scanner.Config.SBOMDir, the vulnerable packages only being listed otherwise. The images
+// whose scan failed are listed without package
+func NewVDR(report *scanner.VulnerabilityReport) *BOM {
+	scanTime := report.Metadata.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now().UTC()
+	}
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  specVersion,
+		SerialNumber: "urn:uuid:" + utils.NameUUID(report.Metadata.ClusterName, scanTime.Format(time.RFC3339Nano)),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: scanTime.UTC().Format(time.RFC3339),
+			Tools: Tools{Components: []Component{
+				{Type: "application", Group: "CECG", Name: "production-readiness"},
+				{Type: "application", Group: "Aqua Security", Name: "trivy", Version: report.Metadata.TrivyVersion},
+			}},
+		},
+		Components:      []Component{},
+		Vulnerabilities: []Vulnerability{},
+	}
+	if report.Metadata.ClusterName != "" {
+		bom.Metadata.Component = &Component{BOMRef: "cluster:" + report.Metadata.ClusterName, Type: "platform", Name: report.Metadata.ClusterName}
+	}
+	if report.Metadata.Incomplete {
+		bom.Metadata.Properties = append(bom.Metadata.Properties, Property{Name: "production-readiness:incomplete", Value: "true"})
+	}
+	teams := imageTeams(report)
+
+	for _, image := range report.ScannedImages {
+		component, packageRefs := imageComponent(image, teams[image.ImageName])
+		var vulnerabilityIDs []string
+		affected := make(map[string][]affectedPackage)
+		for _, target := range image.Results() {
+			for _, v := range target.Vulnerabilities {
+				packageRef := packageRefs[packageKey(v)]
+				if packageRef == "" {
+					packageRef = component.BOMRef + "/" + v.PkgName + "@" + v.InstalledVersion
+					packageRefs[packageKey(v)] = packageRef
+					packageComponent := Component{BOMRef: packageRef, Type: "library", Name: v.PkgName, Version: v.InstalledVersion}
+					if v.PkgIdentifier != nil {
+						packageComponent.PURL = v.PkgIdentifier.PURL
+					}
+					component.Components = append(component.Components, packageComponent)
+				}
+				if _, ok := affected[v.VulnerabilityID]; !ok {
+					vulnerabilityIDs = append(vulnerabilityIDs, v.VulnerabilityID)
+				}
+				affected[v.VulnerabilityID] = append(affected[v.VulnerabilityID], affectedPackage{ref: packageRef, vulnerability: v})
+			}
+		}
+		for _, vulnerabilityID := range vulnerabilityIDs {
+			bom.Vulnerabilities = append(bom.Vulnerabilities, newVulnerability(component.BOMRef, affected[vulnerabilityID]))
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	return bom
+}
+
+// affectedPackage is a package of an image a vulnerability is found in, with the vulnerability as found in the package
+type affectedPackage struct {
+	ref           string
+	vulnerability scanner.Vulnerabilities
+}
+
+// packageKey returns the key the vulnerable package is matched with the packages of the SBOM on: its PURL when trivy
+// reports it, as the names and versions of the packages of different ecosystems may clash, and its name@version otherwise
+func packageKey(v scanner.Vulnerabilities) string {
+	if v.PkgIdentifier != nil && v.PkgIdentifier.PURL != "" {
+		return v.PkgIdentifier.PURL
+	}
+	return v.PkgName + "@" + v.InstalledVersion
+}
+
+// imageComponent returns the container component of the image holding the packages of its SBOM, and the bom-ref of
+// the packages by PURL and by name@version, see packageKey
+func imageComponent(image scanner.ScannedImage, teams []Property) (Component, map[string]string) {
+	component := Component{BOMRef: image.ImageName, Type: "container", Name: image.ImageName, Properties: teams}
+	if digest := image.Digest(); digest != "" {
+		component.BOMRef += "@" + digest
+		component.Version = digest
+		if hex, ok := strings.CutPrefix(digest, "sha256:"); ok {
+			component.Hashes = []Hash{{Algorithm: "SHA-256", Content: hex}}
+		}
+	}
+	if image.ScanError != nil {
+		component.Properties = append(component.Properties, Property{Name: "production-readiness:scan-error", Value: image.ScanError.Error()})
+	}
+	packageRefs := make(map[string]string)
+	if image.SBOMFile == "" {
+		return component, packageRefs
+	}
+	content, err := os.ReadFile(image.SBOMFile)
+	var packages sbom
+	if err == nil {
+		err = json.Unmarshal(content, &packages)
+	}
+	if err != nil {
+		logr.Warnf("Unable to read the SBOM %s of image %s, only its vulnerable packages are listed: %v", image.SBOMFile, image.ImageName, err)
+		return component, packageRefs
+	}
+	for _, p := range packages.Components {
+		ref := p.BOMRef
+		if p.PURL != "" {
+			ref = p.PURL
+		}
+		if ref == "" {
+			ref = p.Name + "@" + p.Version
+		}
+		p.BOMRef = component.BOMRef + "/" + ref
+		// the nested components of the packages are not referenced by the vulnerabilities
+		p.Components = nil
+		if p.PURL != "" {
+			packageRefs[p.PURL] = p.BOMRef
+		}
+		if _, ok := packageRefs[p.Name+"@"+p.Version]; !ok {
+			packageRefs[p.Name+"@"+p.Version] = p.BOMRef
+		}
+		component.Components = append(component.Components, p)
+	}
+	return component, packageRefs
+}
+
+// newVulnerability returns the vulnerability of the image affecting the packages. The analysis is only set when all
+// the packages share the triage, and the recommendation lists the upgrades of all the fixable packages
+func newVulnerability(imageRef string, affected []affectedPackage) Vulnerability {
+	v := affected[0].vulnerability
+	vulnerability := Vulnerability{
+		BOMRef:      imageRef + "/" + v.VulnerabilityID,
+		ID:          v.VulnerabilityID,
+		Source:      source(v.VulnerabilityID),
+		Ratings:     []Rating{rating(v)},
+		Description: v.Description,
+		Analysis:    analysis(v.Triage),
+	}
+	var upgrades []string
+	for _, a := range affected {
+		if !containsAffect(vulnerability.Affects, a.ref) {
+			vulnerability.Affects = append(vulnerability.Affects, Affect{Ref: a.ref})
+		}
+		if !sameTriage(a.vulnerability.Triage, v.Triage) {
+			vulnerability.Analysis = nil
+		}
+		if upgrade := fmt.Sprintf("%s to %s", a.vulnerability.PkgName, a.vulnerability.FixedVersion); a.vulnerability.Fixable() && !containsString(upgrades, upgrade) {
+			upgrades = append(upgrades, upgrade)
+		}
+	}
+	if len(upgrades) > 0 {
+		vulnerability.Recommendation = "Upgrade " + strings.Join(upgrades, ", ")
+	}
+	if v.Description == "" {
+		vulnerability.Description = v.Title
+	}
+	for _, reference := range v.References {
+		vulnerability.Advisories = append(vulnerability.Advisories, Advisory{URL: reference})
+	}
+	if v.PublishedDate != nil {
+		vulnerability.Published = v.PublishedDate.UTC().Format(time.RFC3339)
+	}
+	if v.LastModifiedDate != nil {
+		vulnerability.Updated = v.LastModifiedDate.UTC().Format(time.RFC3339)
+	}
+	if v.KnownExploited != nil || len(v.ExploitReferences) > 0 {
+		vulnerability.Properties = append(vulnerability.Properties, Property{Name: "production-readiness:known-exploited", Value: "true"})
+	}
+	if v.EPSS != nil {
+		vulnerability.Properties = append(vulnerability.Properties, Property{Name: "production-readiness:epss", Value: fmt.Sprintf("%g", v.EPSS.Score)})
+	}
+	return vulnerability
+}
+
+// sameTriage returns true when the triages have the same state and reason
+func sameTriage(a, b *scanner.FindingTriage) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.State == b.State && a.Reason == b.Reason
+}
+
+func containsAffect(affects []Affect, ref string) bool {
+	for _, affect := range affects {
+		if affect.Ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// source returns the database the vulnerability is published in, nil when unknown
+func source(vulnerabilityID string) *Source {
+	switch {
+	case strings.HasPrefix(vulnerabilityID, "CVE-"):
+		return &Source{Name: "NVD", URL: "https://nvd.nist.gov/vuln/detail/" + vulnerabilityID}
+	case strings.HasPrefix(vulnerabilityID, "GHSA-"):
+		return &Source{Name: "GitHub", URL: "https://github.com/advisories/" + vulnerabilityID}
+	}
+	return nil
+}
+
+// rating returns the severity of the vulnerability with its CVSS score, see scanner.Vulnerabilities.CVSSScore
+func rating(v scanner.Vulnerabilities) Rating {
+	rating := Rating{Severity: strings.ToLower(v.Severity)}
+	switch rating.Severity {
+	case "critical", "high", "medium", "low":
+	default:
+		rating.Severity = "unknown"
+	}
+	if v.SeveritySource != "" {
+		rating.Source = &Source{Name: v.SeveritySource}
+	}
+	if score := v.CVSSScore(); score > 0 {
+		rating.Score, rating.Vector = score, v.CVSSVector()
+		switch {
+		case strings.HasPrefix(rating.Vector, "CVSS:3.1/"):
+			rating.Method = "CVSSv31"
+		case strings.HasPrefix(rating.Vector, "CVSS:3"):
+			rating.Method = "CVSSv3"
+		case strings.HasPrefix(rating.Vector, "CVSS:4"):
+			rating.Method = "CVSSv4"
+		default:
+			rating.Method = "CVSSv2"
+		}
+	}
+	return rating
+}
+
+// analysis returns the CycloneDX analysis of the triage, nil when the vulnerability is not triaged
+func analysis(triage *scanner.FindingTriage) *Analysis {
+	if triage == nil {
+		return nil
+	}
+	switch triage.State {
+	case scanner.TriageFalsePositive:
+		return &Analysis{State: "false_positive", Detail: triage.Reason}
+	case scanner.TriageAccepted:
+		return &Analysis{State: "exploitable", Response: []string{"will_not_fix"}, Detail: triage.Reason}
+	case scanner.TriageFixInProgress:
+		return &Analysis{State: "exploitable", Response: []string{"update"}, Detail: triage.Reason}
+	}
+	return &Analysis{State: "in_triage", Detail: triage.Reason}
+}
+
+// imageTeams returns the area and team properties of the images by image name, sorted
+func imageTeams(report *scanner.VulnerabilityReport) map[string][]Property {
+	teams := make(map[string][]Property)
+	for areaName, area := range report.AreaSummary {
+		for teamName, team := range area.Teams {
+			for _, image := range team.Images {
+				teams[image.ImageName] = append(teams[image.ImageName],
+					Property{Name: "production-readiness:area", Value: areaName}, Property{Name: "production-readiness:team", Value: teamName})
+			}
+		}
+	}
+	for imageName, properties := range teams {
+		sort.SliceStable(properties, func(i, j int) bool {
+			if properties[i].Name != properties[j].Name {
+				return properties[i].Name < properties[j].Name
+			}
+			return properties[i].Value < properties[j].Value
+		})
+		var deduped []Property
+		for i, property := range properties {
+			if i == 0 || property != properties[i-1] {
+				deduped = append(deduped, property)
+			}
+		}
+		teams[imageName] = deduped
+	}
+	return teams
+}
+
+// Save writes the report to the file as json
+func Save(bom *BOM, filename string) error {
+	content, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode CycloneDX VDR %s: %v", filename, err)
+	}
+	if err := os.WriteFile(filename, content, 0644); err != nil {
+		return fmt.Errorf("could not write CycloneDX VDR %s: %v", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package cyclonedx
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCycloneDX(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CycloneDX Suite")
+}
+
+var _ = Describe("CycloneDX vulnerability disclosure report", func() {
+
+	var (
+		scanTime = time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+		report   *scanner.VulnerabilityReport
+		sbomFile string
+	)
+
+	BeforeEach(func() {
+		sbomFile = filepath.Join(GinkgoT().TempDir(), "api.cdx.json")
+		Expect(os.WriteFile(sbomFile, []byte(`{"bomFormat":"CycloneDX","components":[
+			{"bom-ref":"pkg:deb/debian/openssl@3.0.0","type":"library","name":"openssl","version":"3.0.0","purl":"pkg:deb/debian/openssl@3.0.0"},
+			{"bom-ref":"pkg:deb/debian/zlib@1.2","type":"library","name":"zlib","version":"1.2","purl":"pkg:deb/debian/zlib@1.2"}]}`), 0644)).To(Succeed())
+		api := scanner.ScannedImage{
+			ImageName:  "registry/api:1",
+			SBOMFile:   sbomFile,
+			Containers: []k8s.ContainerSummary{{Image: "registry/api:1", ImageDigest: "sha256:a1"}},
+			TrivyOutputResults: []scanner.TrivyOutputResults{{Target: "registry/api:1 (debian 11.7)", Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-0001", PkgName: "openssl", InstalledVersion: "3.0.0", FixedVersion: "3.0.1", Severity: "CRITICAL", SeveritySource: "nvd",
+					Title: "openssl: buffer overflow", Description: "A buffer overflow", References: []string{"https://nvd.nist.gov/vuln/detail/CVE-2023-0001"},
+					CVSS:           map[string]scanner.CVSS{"nvd": {V3Score: 9.8, V3Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+					KnownExploited: &scanner.KnownExploitedVulnerability{CveID: "CVE-2023-0001"}},
+			}}},
+		}
+		web := scanner.ScannedImage{
+			ImageName: "registry/web:2",
+			TrivyOutputResults: []scanner.TrivyOutputResults{{Target: "app.jar", Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "GHSA-abcd-efgh-ijkl", PkgName: "log4j", InstalledVersion: "2.14", FixedVersion: "2.17.1", Severity: "UNKNOWN",
+					Triage: &scanner.FindingTriage{State: scanner.TriageFalsePositive, Reason: "not reachable"}},
+				{VulnerabilityID: "GHSA-abcd-efgh-ijkl", PkgName: "log4j-core", InstalledVersion: "2.14", FixedVersion: "2.17.1", Severity: "UNKNOWN"},
+			}}},
+		}
+		report = &scanner.VulnerabilityReport{
+			Metadata:      scanner.ReportMetadata{ClusterName: "prod", ScanTime: scanTime, TrivyVersion: "0.45.0"},
+			ScannedImages: []scanner.ScannedImage{api, web, {ImageName: "registry/db:3", ScanError: errors.New("timeout")}},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"finance": {Name: "finance", Teams: map[string]*scanner.TeamSummary{"payments": {Name: "payments", Images: []scanner.ScannedImage{api}}}},
+			},
+		}
+	})
+
+	It("lists the images with the packages of their SBOM and the vulnerabilities affecting the packages", func() {
+		bom := NewVDR(report)
+
+		Expect(bom.BOMFormat).To(Equal("CycloneDX"))
+		Expect(bom.SpecVersion).To(Equal("1.5"))
+		Expect(bom.SerialNumber).To(MatchRegexp(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(bom.Metadata.Timestamp).To(Equal("2023-09-01T10:00:00Z"))
+		Expect(bom.Metadata.Component).To(Equal(&Component{BOMRef: "cluster:prod", Type: "platform", Name: "prod"}))
+		Expect(bom.Components).To(HaveLen(3))
+		Expect(bom.Components[0]).To(Equal(Component{BOMRef: "registry/api:1@sha256:a1", Type: "container", Name: "registry/api:1", Version: "sha256:a1",
+			Hashes:     []Hash{{Algorithm: "SHA-256", Content: "a1"}},
+			Properties: []Property{{Name: "production-readiness:area", Value: "finance"}, {Name: "production-readiness:team", Value: "payments"}},
+			Components: []Component{
+				{BOMRef: "registry/api:1@sha256:a1/pkg:deb/debian/openssl@3.0.0", Type: "library", Name: "openssl", Version: "3.0.0", PURL: "pkg:deb/debian/openssl@3.0.0"},
+				{BOMRef: "registry/api:1@sha256:a1/pkg:deb/debian/zlib@1.2", Type: "library", Name: "zlib", Version: "1.2", PURL: "pkg:deb/debian/zlib@1.2"},
+			}}))
+		Expect(bom.Components[1].Components).To(Equal([]Component{
+			{BOMRef: "registry/web:2/log4j@2.14", Type: "library", Name: "log4j", Version: "2.14"},
+			{BOMRef: "registry/web:2/log4j-core@2.14", Type: "library", Name: "log4j-core", Version: "2.14"},
+		}))
+		Expect(bom.Components[2].Properties).To(Equal([]Property{{Name: "production-readiness:scan-error", Value: "timeout"}}))
+
+		Expect(bom.Vulnerabilities).To(Equal([]Vulnerability{
+			{
+				BOMRef:         "registry/api:1@sha256:a1/CVE-2023-0001",
+				ID:             "CVE-2023-0001",
+				Source:         &Source{Name: "NVD", URL: "https://nvd.nist.gov/vuln/detail/CVE-2023-0001"},
+				Ratings:        []Rating{{Source: &Source{Name: "nvd"}, Score: 9.8, Severity: "critical", Method: "CVSSv31", Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+				Description:    "A buffer overflow",
+				Recommendation: "Upgrade openssl to 3.0.1",
+				Advisories:     []Advisory{{URL: "https://nvd.nist.gov/vuln/detail/CVE-2023-0001"}},
+				Affects:        []Affect{{Ref: "registry/api:1@sha256:a1/pkg:deb/debian/openssl@3.0.0"}},
+				Properties:     []Property{{Name: "production-readiness:known-exploited", Value: "true"}},
+			},
+			{
+				BOMRef:  "registry/web:2/GHSA-abcd-efgh-ijkl",
+				ID:      "GHSA-abcd-efgh-ijkl",
+				Source:  &Source{Name: "GitHub", URL: "https://github.com/advisories/GHSA-abcd-efgh-ijkl"},
+				Ratings: []Rating{{Severity: "unknown"}},
+				// the packages do not share the triage
+				Recommendation: "Upgrade log4j to 2.17.1, log4j-core to 2.17.1",
+				Affects:        []Affect{{Ref: "registry/web:2/log4j@2.14"}, {Ref: "registry/web:2/log4j-core@2.14"}},
+			},
+		}))
+	})
+
+	It("sets the analysis of the vulnerabilities triaged the same way in all their packages", func() {
+		triage := &scanner.FindingTriage{State: scanner.TriageFalsePositive, Reason: "not reachable"}
+		report.ScannedImages[1].TrivyOutputResults[0].Vulnerabilities[1].Triage = triage
+
+		bom := NewVDR(report)
+
+		Expect(bom.Vulnerabilities[1].Analysis).To(Equal(&Analysis{State: "false_positive", Detail: "not reachable"}))
+	})
+
+	It("matches the vulnerable packages with the packages of the SBOM on their PURL", func() {
+		Expect(os.WriteFile(sbomFile, []byte(`{"bomFormat":"CycloneDX","components":[
+			{"bom-ref":"pkg:maven/org.json/json@1.0","type":"library","name":"json","version":"1.0","purl":"pkg:maven/org.json/json@1.0"},
+			{"bom-ref":"pkg:npm/json@1.0","type":"library","name":"json","version":"1.0","purl":"pkg:npm/json@1.0"}]}`), 0644)).To(Succeed())
+		report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities = []scanner.Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-0002", PkgName: "json", InstalledVersion: "1.0", Severity: "HIGH", PkgIdentifier: &scanner.PkgIdentifier{PURL: "pkg:npm/json@1.0"}},
+		}
+
+		bom := NewVDR(report)
+
+		Expect(bom.Components[0].Components).To(HaveLen(2))
+		Expect(bom.Vulnerabilities[0].Affects).To(Equal([]Affect{{Ref: "registry/api:1@sha256:a1/pkg:npm/json@1.0"}}))
+	})
+
+	It("saves the report as json", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "vdr.cdx.json")
+
+		Expect(Save(NewVDR(report), filename)).To(Succeed())
+
+		content, err := os.ReadFile(filename)
+		Expect(err).NotTo(HaveOccurred())
+		var saved map[string]interface{}
+		Expect(json.Unmarshal(content, &saved)).To(Succeed())
+		Expect(saved["bomFormat"]).To(Equal("CycloneDX"))
+		Expect(saved["vulnerabilities"]).To(HaveLen(2))
+		Expect(saved["components"].([]interface{})[0].(map[string]interface{})["bom-ref"]).To(Equal("registry/api:1@sha256:a1"))
+	})
+})
@@ -0,0 +1,197 @@
+package defectdojo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// scanType is the DefectDojo parser of the imported findings, the images being exported in the trivy json format
+const scanType = "Trivy Scan"
+
+// Config is the config used to export the findings to DefectDojo
+type Config struct {
+	URL    string
+	APIKey string
+	// ProductType is the product type of the products created for the teams
+	ProductType string
+	// TeamProducts maps a team name to its DefectDojo product, the product being named after the team otherwise
+	TeamProducts map[string]string
+	Timeout      time.Duration
+}
+
+// Exporter imports the findings of the scanned images into DefectDojo, with one product per team and one engagement
+// per image repository, so that the security teams track the remediation in their existing workflow
+type Exporter struct {
+	config     *Config
+	baseURL    string
+	httpClient *http.Client
+}
+
+// trivyReport is the trivy json report of an image, as parsed by the DefectDojo Trivy Scan parser
+type trivyReport struct {
+	SchemaVersion int
+	ArtifactName  string
+	ArtifactType  string
+	Results       []scanner.TrivyOutputResults
+}
+
+// New creates an Exporter
+func New(config *Config) *Exporter {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	return &Exporter{
+		config:     config,
+		baseURL:    strings.TrimSuffix(config.URL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Export reimports the findings of each image of each team into the engagement of the image repository of the team
+// product. The products and engagements are created when missing, and the findings no longer found are closed.
+// The images skipped or whose scan failed or timed out are not exported, so that their findings are not closed
+func (e *Exporter) Export(report *scanner.VulnerabilityReport) error {
+	var areaNames []string
+	for areaName := range report.AreaSummary {
+		areaNames = append(areaNames, areaName)
+	}
+	sort.Strings(areaNames)
+
+	var exported, failures int
+	for _, areaName := range areaNames {
+		area := report.AreaSummary[areaName]
+		var teamNames []string
+		for teamName := range area.Teams {
+			teamNames = append(teamNames, teamName)
+		}
+		sort.Strings(teamNames)
+		for _, teamName := range teamNames {
+			product := e.productFor(teamName)
+			for _, image := range area.Teams[teamName].Images {
+				if image.Skipped || image.ScanError != nil {
+					logr.Debugf("Not exporting image %s to DefectDojo, it was not fully scanned", image.ImageName)
+					continue
+				}
+				if err := e.reimport(product, teamName, report.Metadata, image); err != nil {
+					logr.Error(err)
+					failures++
+					continue
+				}
+				exported++
+			}
+		}
+	}
+	logr.Infof("Exported the findings of %d images to DefectDojo", exported)
+	if failures > 0 {
+		return fmt.Errorf("the findings of %d image(s) could not be exported to DefectDojo", failures)
+	}
+	return nil
+}
+
+func (e *Exporter) productFor(team string) string {
+	if product, ok := e.config.TeamProducts[team]; ok {
+		return product
+	}
+	return team
+}
+
+// reimport posts the image findings to the reimport-scan API, which updates the test of the image repository
+func (e *Exporter) reimport(product, team string, metadata scanner.ReportMetadata, image scanner.ScannedImage) error {
+	repository := imageRepository(image.ImageName)
+	scan, err := json.Marshal(&trivyReport{
+		SchemaVersion: 2,
+		ArtifactName:  image.ImageName,
+		ArtifactType:  "container_image",
+		Results:       image.Results(),
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding the DefectDojo findings of image %s: %v", image.ImageName, err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"scan_type", scanType},
+		{"product_type_name", e.config.ProductType},
+		{"product_name", product},
+		{"engagement_name", repository},
+		{"test_title", repository},
+		{"version", image.ImageName},
+		{"auto_create_context", "true"},
+		{"close_old_findings", "true"},
+		{"active", "true"},
+		{"verified", "false"},
+		{"scan_date", metadata.ScanTime.Format("2006-01-02")},
+	}
+	for _, tag := range tags(team, metadata) {
+		fields = append(fields, [2]string{"tags", tag})
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	file, err := writer.CreateFormFile("file", "trivy.json")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(scan); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.baseURL+"/api/v2/reimport-scan/", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+e.config.APIKey)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error exporting the findings of image %s to DefectDojo: %v", image.ImageName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error exporting the findings of image %s to DefectDojo: status code %d: %s", image.ImageName, resp.StatusCode, string(respBody))
+	}
+	logr.Debugf("Exported the findings of image %s to DefectDojo product %s", image.ImageName, product)
+	return nil
+}
+
+// tags returns the tags of the imported test, identifying the team and the cluster
+func tags(team string, metadata scanner.ReportMetadata) []string {
+	tags := []string{"prod-readiness", "team:" + team}
+	if metadata.ClusterName != "" {
+		tags = append(tags, "cluster:"+metadata.ClusterName)
+	}
+	return tags
+}
+
+// imageRepository returns the image name without tag nor digest, for instance registry/payments/web for
+// registry/payments/web:1.2, so that the successive versions of an image are tracked in the same engagement
+func imageRepository(imageName string) string {
+	name, _, _ := strings.Cut(imageName, "@")
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		name = name[:tagIndex]
+	}
+	return name
+}
@@ -0,0 +1,113 @@
+package defectdojo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDefectDojo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DefectDojo Suite")
+}
+
+type importRequest struct {
+	authorization string
+	fields        map[string][]string
+	scan          trivyReport
+}
+
+var _ = Describe("DefectDojo exporter", func() {
+
+	var (
+		server   *httptest.Server
+		received []importRequest
+		status   int
+		report   *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		received = nil
+		status = http.StatusCreated
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/api/v2/reimport-scan/"))
+			Expect(r.ParseMultipartForm(1 << 20)).To(Succeed())
+			file, _, err := r.FormFile("file")
+			Expect(err).NotTo(HaveOccurred())
+			content, err := io.ReadAll(file)
+			Expect(err).NotTo(HaveOccurred())
+			request := importRequest{authorization: r.Header.Get("Authorization"), fields: r.MultipartForm.Value}
+			Expect(json.Unmarshal(content, &request.scan)).To(Succeed())
+			received = append(received, request)
+			w.WriteHeader(status)
+		}))
+
+		results := []scanner.TrivyOutputResults{{Target: "debian", Vulnerabilities: []scanner.Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-0001", PkgName: "openssl", Severity: "CRITICAL"},
+		}}}
+		report = &scanner.VulnerabilityReport{
+			Metadata: scanner.ReportMetadata{ClusterName: "sandbox", ScanTime: time.Date(2023, 9, 5, 10, 0, 0, 0, time.UTC)},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"retail": {Teams: map[string]*scanner.TeamSummary{
+					"payments": {Images: []scanner.ScannedImage{
+						{ImageName: "registry/payments/web:1.2", TrivyOutputResults: results},
+						{ImageName: "registry/payments/batch:2.0", ScanError: errors.New("error executing trivy")},
+						{ImageName: "registry/payments/ml:3.1", Skipped: true},
+					}},
+					"orders": {Images: []scanner.ScannedImage{
+						{ImageName: "registry/orders/api@sha256:4ff3ca91"},
+					}},
+				}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reimports the findings of each fully scanned image into the engagement of its repository in the team product", func() {
+		exporter := New(&Config{URL: server.URL + "/", APIKey: "key", ProductType: "Production Readiness", TeamProducts: map[string]string{"orders": "Orders Platform"}})
+
+		err := exporter.Export(report)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(HaveLen(2))
+		orders, payments := received[0], received[1]
+		Expect(orders.authorization).To(Equal("Token key"))
+		Expect(orders.fields["product_name"]).To(Equal([]string{"Orders Platform"}))
+		Expect(orders.fields["engagement_name"]).To(Equal([]string{"registry/orders/api"}))
+		Expect(payments.fields["scan_type"]).To(Equal([]string{"Trivy Scan"}))
+		Expect(payments.fields["product_type_name"]).To(Equal([]string{"Production Readiness"}))
+		Expect(payments.fields["product_name"]).To(Equal([]string{"payments"}))
+		Expect(payments.fields["engagement_name"]).To(Equal([]string{"registry/payments/web"}))
+		Expect(payments.fields["version"]).To(Equal([]string{"registry/payments/web:1.2"}))
+		Expect(payments.fields["close_old_findings"]).To(Equal([]string{"true"}))
+		Expect(payments.fields["scan_date"]).To(Equal([]string{"2023-09-05"}))
+		Expect(payments.fields["tags"]).To(Equal([]string{"prod-readiness", "team:payments", "cluster:sandbox"}))
+		Expect(payments.scan.ArtifactName).To(Equal("registry/payments/web:1.2"))
+		Expect(payments.scan.Results[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2023-0001"))
+	})
+
+	It("returns an error when findings could not be exported", func() {
+		status = http.StatusBadRequest
+
+		err := New(&Config{URL: server.URL}).Export(report)
+
+		Expect(err).To(MatchError(ContainSubstring("the findings of 2 image(s) could not be exported")))
+	})
+
+	It("strips the tag and the digest of the image names", func() {
+		Expect(imageRepository("registry:5000/payments/web:1.2@sha256:4ff3")).To(Equal("registry:5000/payments/web"))
+		Expect(imageRepository("nginx")).To(Equal("nginx"))
+	})
+})
@@ -0,0 +1,163 @@
+package dependencytrack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// Config is the config used to upload the SBOMs to Dependency-Track
+type Config struct {
+	URL    string
+	APIKey string
+	// ParentProject is the name of the project the image projects are created under, they have no parent when empty
+	ParentProject string
+	Timeout       time.Duration
+}
+
+// Exporter uploads the SBOMs of the scanned images to Dependency-Track, with one project per image repository and
+// one project version per image tag or digest, tagged with the cluster and the teams running the image
+type Exporter struct {
+	config     *Config
+	baseURL    string
+	httpClient *http.Client
+}
+
+// bomSubmitRequest is the request of the Dependency-Track BOM upload API
+type bomSubmitRequest struct {
+	ProjectName    string       `json:"projectName"`
+	ProjectVersion string       `json:"projectVersion"`
+	ParentName     string       `json:"parentName,omitempty"`
+	AutoCreate     bool         `json:"autoCreate"`
+	ProjectTags    []projectTag `json:"projectTags,omitempty"`
+	BOM            string       `json:"bom"`
+}
+
+type projectTag struct {
+	Name string `json:"name"`
+}
+
+// New creates an Exporter
+func New(config *Config) *Exporter {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	return &Exporter{
+		config:     config,
+		baseURL:    strings.TrimSuffix(config.URL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Export uploads the SBOM of each image of the report that has one, see scanner.Config.SBOMDir. The projects are
+// created when missing, Dependency-Track then analysing the components of the SBOMs
+func (e *Exporter) Export(report *scanner.VulnerabilityReport) error {
+	images := make(map[string]scanner.ScannedImage)
+	teams := make(map[string][]string)
+	for _, area := range report.AreaSummary {
+		for teamName, team := range area.Teams {
+			for _, image := range team.Images {
+				if image.SBOMFile == "" {
+					continue
+				}
+				images[image.ImageName] = image
+				teams[image.ImageName] = append(teams[image.ImageName], teamName)
+			}
+		}
+	}
+	var imageNames []string
+	for imageName := range images {
+		imageNames = append(imageNames, imageName)
+	}
+	sort.Strings(imageNames)
+
+	var failures int
+	for _, imageName := range imageNames {
+		if err := e.upload(images[imageName], projectTags(report.Metadata.ClusterName, teams[imageName])); err != nil {
+			logr.Error(err)
+			failures++
+		}
+	}
+	logr.Infof("Uploaded the SBOMs of %d images to Dependency-Track", len(imageNames)-failures)
+	if failures > 0 {
+		return fmt.Errorf("the SBOMs of %d image(s) could not be uploaded to Dependency-Track", failures)
+	}
+	return nil
+}
+
+func (e *Exporter) upload(image scanner.ScannedImage, tags []projectTag) error {
+	sbom, err := os.ReadFile(image.SBOMFile)
+	if err != nil {
+		return fmt.Errorf("could not read the SBOM of image %s: %v", image.ImageName, err)
+	}
+	name, version := projectNameAndVersion(image.ImageName)
+	body, err := json.Marshal(&bomSubmitRequest{
+		ProjectName:    name,
+		ProjectVersion: version,
+		ParentName:     e.config.ParentProject,
+		AutoCreate:     true,
+		ProjectTags:    tags,
+		BOM:            base64.StdEncoding.EncodeToString(sbom),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, e.baseURL+"/api/v1/bom", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Api-Key", e.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading the SBOM of image %s to Dependency-Track: %v", image.ImageName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error uploading the SBOM of image %s to Dependency-Track: status code %d: %s", image.ImageName, resp.StatusCode, string(respBody))
+	}
+	logr.Debugf("Uploaded the SBOM of image %s to Dependency-Track project %s %s", image.ImageName, name, version)
+	return nil
+}
+
+// projectTags returns the tags of the project of an image, identifying the cluster and the teams running the image
+func projectTags(clusterName string, teams []string) []projectTag {
+	sort.Strings(teams)
+	var tags []projectTag
+	if clusterName != "" {
+		tags = append(tags, projectTag{Name: "cluster:" + clusterName})
+	}
+	for _, team := range teams {
+		tags = append(tags, projectTag{Name: "team:" + team})
+	}
+	return tags
+}
+
+// projectNameAndVersion splits the image name into its repository, the project name, and its digest or else its tag,
+// the project version, for instance registry/payments/web and 1.2 for registry/payments/web:1.2
+func projectNameAndVersion(imageName string) (string, string) {
+	name, digest, hasDigest := strings.Cut(imageName, "@")
+	version := "latest"
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		name, version = name[:tagIndex], name[tagIndex+1:]
+	}
+	if hasDigest {
+		version = digest
+	}
+	return name, version
+}
@@ -0,0 +1,95 @@
+package dependencytrack
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDependencyTrack(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dependency-Track Suite")
+}
+
+var _ = Describe("Dependency-Track exporter", func() {
+
+	var (
+		server   *httptest.Server
+		received []bomSubmitRequest
+		apiKeys  []string
+		status   int
+		report   *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		received, apiKeys = nil, nil
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPut))
+			Expect(r.URL.Path).To(Equal("/api/v1/bom"))
+			var request bomSubmitRequest
+			Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+			received = append(received, request)
+			apiKeys = append(apiKeys, r.Header.Get("X-Api-Key"))
+			w.WriteHeader(status)
+		}))
+
+		sbomFile := filepath.Join(GinkgoT().TempDir(), "web.cdx.json")
+		Expect(os.WriteFile(sbomFile, []byte(`{"bomFormat":"CycloneDX"}`), 0644)).To(Succeed())
+		web := scanner.ScannedImage{ImageName: "registry/payments/web:1.2", SBOMFile: sbomFile}
+		report = &scanner.VulnerabilityReport{
+			Metadata: scanner.ReportMetadata{ClusterName: "sandbox"},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"retail": {Teams: map[string]*scanner.TeamSummary{
+					"payments": {Images: []scanner.ScannedImage{web, {ImageName: "registry/payments/batch:2.0"}}},
+					"checkout": {Images: []scanner.ScannedImage{web}},
+				}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("uploads the SBOM of each image once, tagged with the cluster and the teams running it", func() {
+		err := New(&Config{URL: server.URL + "/", APIKey: "key", ParentProject: "sandbox"}).Export(report)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(HaveLen(1))
+		Expect(apiKeys[0]).To(Equal("key"))
+		Expect(received[0].ProjectName).To(Equal("registry/payments/web"))
+		Expect(received[0].ProjectVersion).To(Equal("1.2"))
+		Expect(received[0].ParentName).To(Equal("sandbox"))
+		Expect(received[0].AutoCreate).To(BeTrue())
+		Expect(received[0].ProjectTags).To(Equal([]projectTag{{Name: "cluster:sandbox"}, {Name: "team:checkout"}, {Name: "team:payments"}}))
+		Expect(base64.StdEncoding.DecodeString(received[0].BOM)).To(Equal([]byte(`{"bomFormat":"CycloneDX"}`)))
+	})
+
+	It("returns an error when SBOMs could not be uploaded", func() {
+		status = http.StatusUnauthorized
+
+		err := New(&Config{URL: server.URL}).Export(report)
+
+		Expect(err).To(MatchError(ContainSubstring("the SBOMs of 1 image(s) could not be uploaded")))
+	})
+
+	It("uses the digest or else the tag of the images as project version", func() {
+		for imageName, expected := range map[string][]string{
+			"registry:5000/payments/web":                {"registry:5000/payments/web", "latest"},
+			"registry/payments/web:1.2@sha256:4ff3ca91": {"registry/payments/web", "sha256:4ff3ca91"},
+		} {
+			name, version := projectNameAndVersion(imageName)
+			Expect([]string{name, version}).To(Equal(expected), imageName)
+		}
+	})
+})
@@ -0,0 +1,384 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+// ScanPermissions are the permissions the discovery of the cluster containers and workloads needs in all the namespaces,
+// and on the nodes the platforms of the images and the node checks are read from
+var ScanPermissions = []k8s.Permission{
+	{Verb: "get", Resource: "nodes"},
+	{Verb: "list", Resource: "nodes"},
+	{Verb: "list", Resource: "namespaces"},
+	{Verb: "list", Resource: "pods"},
+	{Verb: "list", Resource: "services"},
+	{Verb: "list", Group: "networking.k8s.io", Resource: "ingresses"},
+	{Verb: "list", Group: "apps", Resource: "deployments"},
+	{Verb: "list", Group: "apps", Resource: "replicasets"},
+	{Verb: "list", Group: "apps", Resource: "statefulsets"},
+	{Verb: "list", Group: "apps", Resource: "daemonsets"},
+	{Verb: "list", Group: "batch", Resource: "jobs"},
+	{Verb: "list", Group: "batch", Resource: "cronjobs"},
+}
+
+type kubernetesCheck struct {
+	kubeContext    string
+	kubeconfigPath string
+	client         func() k8s.KubernetesClient
+	permissions    []k8s.Permission
+}
+
+// NewKubernetesCheck creates a check loading the kube config of the context, reaching the cluster with the client and
+// reviewing the permissions of its user. The client is only created once the kube config is loaded
+func NewKubernetesCheck(kubeContext, kubeconfigPath string, client func() k8s.KubernetesClient, permissions []k8s.Permission) Check {
+	return &kubernetesCheck{kubeContext: kubeContext, kubeconfigPath: kubeconfigPath, client: client, permissions: permissions}
+}
+
+func (c *kubernetesCheck) Name() string {
+	return "kubernetes"
+}
+
+func (c *kubernetesCheck) Run(_ context.Context) Result {
+	if err := k8s.ValidateKubernetesConfig(c.kubeContext, c.kubeconfigPath); err != nil {
+		return failed("check --kubeconfig and --context, or the KUBECONFIG environment variable", "unable to load the kube config: %v", err)
+	}
+	client := c.client()
+	version, err := client.GetServerVersion()
+	if err != nil {
+		return failed("check the API server is reachable from this host, through --https-proxy or --no-proxy if needed, and that the credentials of the kube config have not expired", "%v", err)
+	}
+	var denied []string
+	for _, permission := range c.permissions {
+		allowed, err := client.IsAllowed(permission)
+		if err != nil {
+			return failed("grant the user of the kube config the permission to create selfsubjectaccessreviews.authorization.k8s.io", "cluster %s reachable but %v", version, err)
+		}
+		if !allowed {
+			denied = append(denied, permission.String())
+		}
+	}
+	if len(denied) > 0 {
+		return failed("grant the denied permissions to the user of the kube config in all the namespaces, for instance with the ClusterRole of node-agent.yaml",
+			"cluster %s reachable but %d of %d permissions denied: %s", version, len(denied), len(c.permissions), strings.Join(denied, ", "))
+	}
+	return passed("cluster %s reachable, %d permissions granted", version, len(c.permissions))
+}
+
+type containerRuntimeCheck struct {
+	runtime string
+	runner  execCmd.CommandRunner
+}
+
+// NewContainerRuntimeCheck creates a check running the container runtime the images are pulled with, docker or podman
+func NewContainerRuntimeCheck(runtime string, runner execCmd.CommandRunner) Check {
+	return &containerRuntimeCheck{runtime: runtime, runner: runner}
+}
+
+func (c *containerRuntimeCheck) Name() string {
+	return "container-runtime"
+}
+
+func (c *containerRuntimeCheck) Run(ctx context.Context) Result {
+	// the docker server version requires the daemon to be running
+	args, remedy := []string{"version", "--format", "{{.Server.Version}}"}, "install docker and start its daemon, check the user is allowed to run docker ps, or use --container-runtime podman"
+	if c.runtime == scanner.PodmanRuntime {
+		args, remedy = []string{"version", "--format", "{{.Client.Version}}"}, "install podman or use --container-runtime docker"
+	}
+	output, errOutput, err := c.runner.ExecuteContext(ctx, c.runtime, args)
+	if err != nil {
+		return failed(remedy, "unable to run %s: %s", c.runtime, strings.TrimSpace(fmt.Sprintf("%v %s", err, utils.ConvertByteToString(errOutput))))
+	}
+	return passed("%s %s", c.runtime, strings.TrimSpace(utils.ConvertByteToString(output)))
+}
+
+// DefaultContainerdAddress is the containerd socket trivy reads the images from when CONTAINERD_ADDRESS is not set
+const DefaultContainerdAddress = "/run/containerd/containerd.sock"
+
+type containerdCheck struct {
+	address string
+}
+
+// NewContainerdCheck creates a check connecting to the containerd socket the images are read from with the containerd
+// image source of trivy, as the node agents do. The check is skipped when containerd is not installed on the host
+func NewContainerdCheck(address string) Check {
+	return &containerdCheck{address: address}
+}
+
+func (c *containerdCheck) Name() string {
+	return "containerd"
+}
+
+func (c *containerdCheck) Run(ctx context.Context) Result {
+	if _, err := os.Stat(c.address); os.IsNotExist(err) {
+		return skipped("no containerd socket at %s", c.address)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.address)
+	if err != nil {
+		return failed("start containerd, check the user is allowed to read and write its socket, or set CONTAINERD_ADDRESS to its socket", "unable to connect to containerd: %v", err)
+	}
+	_ = conn.Close()
+	return passed("containerd reachable at %s", c.address)
+}
+
+// storageRoot returns the directory the container runtime stores the pulled images in, when it is on this host. The root
+// of a remote daemon, or of a daemon running in a virtual machine, is not returned
+func storageRoot(ctx context.Context, runtime string, runner execCmd.CommandRunner) (string, bool) {
+	args := []string{"info", "--format", "{{.DockerRootDir}}"}
+	if runtime == scanner.PodmanRuntime {
+		args = []string{"info", "--format", "{{.Store.GraphRoot}}"}
+	}
+	output, _, err := runner.ExecuteContext(ctx, runtime, args)
+	if err != nil {
+		return "", false
+	}
+	root := strings.TrimSpace(utils.ConvertByteToString(output))
+	if root == "" {
+		return "", false
+	}
+	if _, err := os.Stat(root); err != nil {
+		return "", false
+	}
+	return root, true
+}
+
+// Trivy resolves the trivy binary once for the trivy and trivy-db checks
+type Trivy struct {
+	// Resolve returns the path of the trivy binary, downloading it when needed
+	Resolve func() (string, error)
+	// NewClient creates the trivy client of the binary
+	NewClient func(path string) scanner.TrivyClient
+
+	once   sync.Once
+	path   string
+	client scanner.TrivyClient
+	err    error
+}
+
+func (t *Trivy) resolve() (scanner.TrivyClient, string, error) {
+	t.once.Do(func() {
+		if t.path, t.err = t.Resolve(); t.err == nil {
+			t.client = t.NewClient(t.path)
+		}
+	})
+	return t.client, t.path, t.err
+}
+
+type trivyCheck struct {
+	trivy *Trivy
+}
+
+// NewTrivyCheck creates a check resolving the trivy binary and running it
+func NewTrivyCheck(trivy *Trivy) Check {
+	return &trivyCheck{trivy: trivy}
+}
+
+func (c *trivyCheck) Name() string {
+	return "trivy"
+}
+
+func (c *trivyCheck) Run(_ context.Context) Result {
+	client, path, err := c.trivy.resolve()
+	if err != nil {
+		return failed("install the trivy release of --trivy-version on the PATH, pass a compatible one with --trivy-path, or allow the download of the trivy releases from github.com", "%v", err)
+	}
+	version, err := client.Version()
+	if err != nil {
+		return failed("reinstall trivy or pass a working binary with --trivy-path", "unable to run %s: %v", path, err)
+	}
+	return passed("trivy %s at %s", version.Version, path)
+}
+
+type trivyDBCheck struct {
+	trivy  *Trivy
+	maxAge time.Duration
+}
+
+// NewTrivyDBCheck creates a check downloading or updating the trivy vulnerability db as the scans do, the db being
+// reported out of date when it was updated more than maxAge ago
+func NewTrivyDBCheck(trivy *Trivy, maxAge time.Duration) Check {
+	return &trivyDBCheck{trivy: trivy, maxAge: maxAge}
+}
+
+func (c *trivyDBCheck) Name() string {
+	return "trivy-db"
+}
+
+func (c *trivyDBCheck) Run(ctx context.Context) Result {
+	client, _, err := c.trivy.resolve()
+	if err != nil {
+		return skipped("trivy is not available")
+	}
+	if err := client.DownloadDatabase(ctx, "image"); err != nil {
+		return failed("allow the access to ghcr.io, mirror the db with --db-repository, or copy the trivy cache of a connected host to --db-cache-dir and use --skip-db-update", "%v", err)
+	}
+	version, err := client.Version()
+	if err != nil {
+		return failed("reinstall trivy or pass a working binary with --trivy-path", "%v", err)
+	}
+	updatedAt := version.VulnerabilityDB.UpdatedAt
+	if updatedAt.IsZero() {
+		return failed("download the db without --skip-db-update, or copy the trivy cache of a connected host to --db-cache-dir", "no vulnerability db found")
+	}
+	age := time.Since(updatedAt)
+	if c.maxAge > 0 && age > c.maxAge {
+		return warning("update the db without --skip-db-update, or refresh the copy of --db-cache-dir, so that the recent vulnerabilities are found",
+			"vulnerability db v%d updated %s, %s ago", version.VulnerabilityDB.Version, updatedAt.UTC().Format(time.RFC3339), age.Round(time.Hour))
+	}
+	return passed("vulnerability db v%d updated %s", version.VulnerabilityDB.Version, updatedAt.UTC().Format(time.RFC3339))
+}
+
+type registriesCheck struct {
+	registries         func() ([]string, error)
+	httpClient         *http.Client
+	insecureRegistries []string
+}
+
+// NewRegistriesCheck creates a check reaching the registry API of the registries, the registries requiring
+// authentication being reachable. The insecure registries are reached without TLS verification, or over plain HTTP
+func NewRegistriesCheck(registries func() ([]string, error), httpClient *http.Client, insecureRegistries []string) Check {
+	return &registriesCheck{registries: registries, httpClient: httpClient, insecureRegistries: insecureRegistries}
+}
+
+func (c *registriesCheck) Name() string {
+	return "registries"
+}
+
+func (c *registriesCheck) Run(ctx context.Context) Result {
+	registries, err := c.registries()
+	if err != nil {
+		return warning("pass the registries to check with --registries", "unable to list the registries of the images: %v", err)
+	}
+	if len(registries) == 0 {
+		return skipped("no registry to check")
+	}
+	var unreachable []string
+	for _, registry := range registries {
+		if err := c.reach(ctx, registry); err != nil {
+			unreachable = append(unreachable, fmt.Sprintf("%s (%v)", registry, err))
+		}
+	}
+	if len(unreachable) > 0 {
+		return failed("allow the access to the registries from this host, through --https-proxy or --no-proxy if needed, trust their certificate authority with --ca-bundle or list them in --insecure-registries",
+			"%d of %d registries unreachable: %s", len(unreachable), len(registries), strings.Join(unreachable, ", "))
+	}
+	return passed("%d registries reachable: %s", len(registries), strings.Join(registries, ", "))
+}
+
+// reach requests the version check endpoint of the registry API, answered with 401 by the registries requiring
+// authentication
+func (c *registriesCheck) reach(ctx context.Context, registry string) error {
+	host, client := registry, c.httpClient
+	if host == scanner.DockerHubRegistry {
+		host = "registry-1.docker.io"
+	}
+	schemes := []string{"https"}
+	for _, insecure := range c.insecureRegistries {
+		if insecure == registry {
+			schemes = append(schemes, "http")
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+			client = &http.Client{Transport: transport, Timeout: c.httpClient.Timeout}
+		}
+	}
+	var err error
+	for _, scheme := range schemes {
+		var request *http.Request
+		if request, err = http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/v2/", nil); err != nil {
+			return err
+		}
+		var response *http.Response
+		if response, err = client.Do(request); err != nil {
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode == http.StatusOK || response.StatusCode == http.StatusUnauthorized {
+			return nil
+		}
+		err = fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return err
+}
+
+// ImageRegistries returns the registries of the images, in order of first appearance
+func ImageRegistries(images []string) []string {
+	var registries []string
+	seen := make(map[string]bool)
+	for _, image := range images {
+		if registry := scanner.ImageRegistry(image); !seen[registry] {
+			seen[registry] = true
+			registries = append(registries, registry)
+		}
+	}
+	return registries
+}
+
+type diskSpaceCheck struct {
+	dirs    []string
+	runtime string
+	runner  execCmd.CommandRunner
+	minFree int64
+}
+
+// NewDiskSpaceCheck creates a check of the free disk space of the storage root of the container runtime the images are
+// pulled with, and of the directories they are saved and scanned in, failing below minFree bytes. The directories not
+// created yet are checked on their closest existing parent
+func NewDiskSpaceCheck(dirs []string, runtime string, runner execCmd.CommandRunner, minFree int64) Check {
+	return &diskSpaceCheck{dirs: dirs, runtime: runtime, runner: runner, minFree: minFree}
+}
+
+func (c *diskSpaceCheck) Name() string {
+	return "disk-space"
+}
+
+func (c *diskSpaceCheck) Run(ctx context.Context) Result {
+	dirs := c.dirs
+	if root, ok := storageRoot(ctx, c.runtime, c.runner); ok {
+		dirs = append([]string{root}, dirs...)
+	}
+	var low, free []string
+	for _, dir := range dirs {
+		existing := existingParent(dir)
+		space := scanner.DetectResources(existing).Disk
+		if space == 0 {
+			return skipped("the free disk space of %s is unknown on this platform", existing)
+		}
+		if space < c.minFree {
+			low = append(low, fmt.Sprintf("%s has %s free", dir, gibibytes(space)))
+			continue
+		}
+		free = append(free, fmt.Sprintf("%s %s", dir, gibibytes(space)))
+	}
+	if len(low) > 0 {
+		return failed("free disk space, prune the images of the container runtime, or point --scratch-dir and --db-cache-dir to a larger volume", "%s, below %s", strings.Join(low, ", "), gibibytes(c.minFree))
+	}
+	return passed("free disk space: %s", strings.Join(free, ", "))
+}
+
+// existingParent returns the directory or its closest existing parent
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			return dir
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func gibibytes(size int64) string {
+	return fmt.Sprintf("%.1f GiB", float64(size)/(1<<30))
+}
@@ -0,0 +1,111 @@
+// Package doctor validates the environment of the scans end-to-end, reporting the actionable failures before a long
+// scan is attempted
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// Passed is the status of the checks the scans can rely on
+	Passed Status = "OK"
+	// Warning is the status of the checks the scans can run despite, possibly degraded
+	Warning Status = "WARN"
+	// Failed is the status of the checks failing the scans
+	Failed Status = "FAIL"
+	// Skipped is the status of the checks depending on a failed check
+	Skipped Status = "SKIP"
+)
+
+// Result is the outcome of a check
+type Result struct {
+	Check   string
+	Status  Status
+	Message string
+	// Remedy is the action fixing the failure or the warning, empty for the passed and skipped checks
+	Remedy string `json:",omitempty"`
+}
+
+// Check validates a prerequisite of the scans
+type Check interface {
+	// Name identifies the check in the results
+	Name() string
+	// Run returns the outcome of the check, stopping when the context is done
+	Run(ctx context.Context) Result
+}
+
+// DefaultCheckTimeout is the time each check is given to complete by default, the trivy db download included
+const DefaultCheckTimeout = 5 * time.Minute
+
+// Run runs the checks in order, each with the timeout, and returns their results
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) []Result {
+	var results []Result
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		result := check.Run(checkCtx)
+		if checkCtx.Err() == context.DeadlineExceeded && result.Status == Failed {
+			result.Message = fmt.Sprintf("%s (timed out after %v)", result.Message, timeout)
+		}
+		cancel()
+		result.Check = check.Name()
+		results = append(results, result)
+	}
+	return results
+}
+
+// Failures returns the number of failed checks
+func Failures(results []Result) int {
+	failures := 0
+	for _, result := range results {
+		if result.Status == Failed {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Write writes the results as a table followed by the remedies of the failures and warnings
+func Write(w io.Writer, results []Result) error {
+	var out strings.Builder
+	table := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(table, "CHECK\tSTATUS\tMESSAGE\n")
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", result.Check, result.Status, result.Message)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	separator := "\n"
+	for _, result := range results {
+		if result.Remedy != "" {
+			fmt.Fprintf(&out, "%s%s %s: %s\n", separator, result.Status, result.Check, result.Remedy)
+			separator = ""
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func passed(format string, args ...interface{}) Result {
+	return Result{Status: Passed, Message: fmt.Sprintf(format, args...)}
+}
+
+func warning(remedy, format string, args ...interface{}) Result {
+	return Result{Status: Warning, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+func failed(remedy, format string, args ...interface{}) Result {
+	return Result{Status: Failed, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+func skipped(format string, args ...interface{}) Result {
+	return Result{Status: Skipped, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,238 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner/scannertest"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDoctor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Doctor Suite")
+}
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: secret
+`
+
+var _ = Describe("Doctor", func() {
+
+	It("writes the results with the remedies of the failures and warnings", func() {
+		results := Run(context.Background(), time.Minute,
+			staticCheck{"trivy", passed("trivy 0.50.1 at /usr/bin/trivy")},
+			staticCheck{"trivy-db", warning("update the db", "vulnerability db v2 updated 10 days ago")},
+			staticCheck{"registries", failed("allow the access", "1 of 1 registries unreachable")},
+		)
+
+		var out strings.Builder
+		Expect(Write(&out, results)).To(Succeed())
+
+		Expect(Failures(results)).To(Equal(1))
+		Expect(out.String()).To(Equal(`CHECK       STATUS  MESSAGE
+trivy       OK      trivy 0.50.1 at /usr/bin/trivy
+trivy-db    WARN    vulnerability db v2 updated 10 days ago
+registries  FAIL    1 of 1 registries unreachable
+
+WARN trivy-db: update the db
+FAIL registries: allow the access
+`))
+	})
+
+	Context("kubernetes", func() {
+		var kubeconfigPath string
+
+		BeforeEach(func() {
+			kubeconfigPath = filepath.Join(GinkgoT().TempDir(), "kubeconfig")
+			Expect(os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600)).To(Succeed())
+		})
+
+		It("lists the permissions denied to the user of the kube config", func() {
+			client := &k8stest.KubernetesClient{}
+			client.On("GetServerVersion").Return("v1.27.3", nil)
+			client.On("IsAllowed", k8s.Permission{Verb: "list", Resource: "pods"}).Return(true, nil)
+			client.On("IsAllowed", k8s.Permission{Verb: "list", Group: "apps", Resource: "deployments"}).Return(false, nil)
+			check := NewKubernetesCheck("", kubeconfigPath, func() k8s.KubernetesClient { return client }, []k8s.Permission{
+				{Verb: "list", Resource: "pods"}, {Verb: "list", Group: "apps", Resource: "deployments"},
+			})
+
+			result := check.Run(context.Background())
+
+			Expect(result.Status).To(Equal(Failed))
+			Expect(result.Message).To(Equal("cluster v1.27.3 reachable but 1 of 2 permissions denied: list deployments.apps"))
+			Expect(result.Remedy).To(ContainSubstring("grant the denied permissions"))
+		})
+
+		It("reviews the permissions on the nodes the scans read", func() {
+			Expect(ScanPermissions).To(ContainElements(k8s.Permission{Verb: "get", Resource: "nodes"}, k8s.Permission{Verb: "list", Resource: "nodes"}))
+		})
+
+		It("fails without creating the client when the kube config cannot be loaded", func() {
+			check := NewKubernetesCheck("missing", kubeconfigPath, func() k8s.KubernetesClient {
+				Fail("the client should not be created")
+				return nil
+			}, ScanPermissions)
+
+			result := check.Run(context.Background())
+
+			Expect(result.Status).To(Equal(Failed))
+			Expect(result.Message).To(ContainSubstring(`unable to load the kube config: context "missing" does not exist`))
+		})
+	})
+
+	Context("trivy", func() {
+		It("skips the db check when trivy cannot be resolved", func() {
+			trivy := &Trivy{Resolve: func() (string, error) { return "", errors.New("no trivy on the PATH") }}
+
+			results := Run(context.Background(), time.Minute, NewTrivyCheck(trivy), NewTrivyDBCheck(trivy, time.Hour))
+
+			Expect(results[0]).To(Equal(Result{Check: "trivy", Status: Failed, Message: "no trivy on the PATH", Remedy: results[0].Remedy}))
+			Expect(results[1]).To(Equal(Result{Check: "trivy-db", Status: Skipped, Message: "trivy is not available"}))
+		})
+
+		It("downloads the db and reports it out of date", func() {
+			client := &scannertest.TrivyClient{}
+			version := &scanner.TrivyVersion{Version: "0.50.1"}
+			version.VulnerabilityDB.Version = 2
+			version.VulnerabilityDB.UpdatedAt = time.Now().Add(-10 * 24 * time.Hour)
+			client.On("DownloadDatabase", "image").Return(nil)
+			client.On("Version").Return(version, nil)
+			trivy := &Trivy{Resolve: func() (string, error) { return "/usr/bin/trivy", nil }, NewClient: func(path string) scanner.TrivyClient { return client }}
+
+			results := Run(context.Background(), time.Minute, NewTrivyCheck(trivy), NewTrivyDBCheck(trivy, 7*24*time.Hour))
+
+			Expect(results[0].Status).To(Equal(Passed))
+			Expect(results[0].Message).To(Equal("trivy 0.50.1 at /usr/bin/trivy"))
+			Expect(results[1].Status).To(Equal(Warning))
+			Expect(results[1].Message).To(HaveSuffix(", 240h0m0s ago"))
+			client.AssertNumberOfCalls(GinkgoT(), "DownloadDatabase", 1)
+		})
+	})
+
+	It("reports the registries not answering the registry API", func() {
+		registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer registry.Close()
+		notRegistry := httptest.NewTLSServer(http.NotFoundHandler())
+		defer notRegistry.Close()
+		reachable, unreachable := strings.TrimPrefix(registry.URL, "https://"), strings.TrimPrefix(notRegistry.URL, "https://")
+		httpClient := registry.Client()
+		httpClient.Transport.(*http.Transport).TLSClientConfig.RootCAs.AddCert(notRegistry.Certificate())
+
+		result := NewRegistriesCheck(func() ([]string, error) { return []string{reachable, unreachable}, nil }, httpClient, nil).Run(context.Background())
+
+		Expect(result.Status).To(Equal(Failed))
+		Expect(result.Message).To(Equal("1 of 2 registries unreachable: " + unreachable + " (unexpected status 404 Not Found)"))
+	})
+
+	It("lists the registries of the images", func() {
+		Expect(ImageRegistries([]string{"nginx:1.25", "registry.example.com/api:1.0", "docker.io/library/redis:7", "registry.example.com/web:2.0"})).
+			To(Equal([]string{"docker.io", "registry.example.com"}))
+	})
+
+	It("checks the free disk space of the closest existing parent of the directories", func() {
+		dir := GinkgoT().TempDir()
+		runner := &mockCommandRunner{}
+		runner.On("Execute", "docker", []string{"info", "--format", "{{.DockerRootDir}}"}).Return([]byte(nil), []byte("Cannot connect to the Docker daemon\n"), errors.New("exit status 1"))
+
+		Expect(NewDiskSpaceCheck([]string{filepath.Join(dir, "not", "created")}, scanner.DockerRuntime, runner, 1).Run(context.Background()).Status).To(Equal(Passed))
+		result := NewDiskSpaceCheck([]string{dir}, scanner.DockerRuntime, runner, 1<<62).Run(context.Background())
+		Expect(result.Status).To(Equal(Failed))
+		Expect(result.Message).To(MatchRegexp(`^.+ has [0-9.]+ GiB free, below 4294967296.0 GiB$`))
+	})
+
+	It("checks the free disk space of the storage root of the container runtime on this host", func() {
+		root, dir := GinkgoT().TempDir(), GinkgoT().TempDir()
+		runner := &mockCommandRunner{}
+		runner.On("Execute", "podman", []string{"info", "--format", "{{.Store.GraphRoot}}"}).Return([]byte(root+"\n"), []byte(nil), nil).Once()
+		runner.On("Execute", "podman", []string{"info", "--format", "{{.Store.GraphRoot}}"}).Return([]byte("/var/lib/containers/not/on/this/host\n"), []byte(nil), nil).Once()
+
+		result := NewDiskSpaceCheck([]string{dir}, scanner.PodmanRuntime, runner, 1).Run(context.Background())
+		Expect(result.Status).To(Equal(Passed))
+		Expect(result.Message).To(MatchRegexp(`^free disk space: ` + root + ` [0-9.]+ GiB, ` + dir + ` [0-9.]+ GiB$`))
+
+		result = NewDiskSpaceCheck([]string{dir}, scanner.PodmanRuntime, runner, 1).Run(context.Background())
+		Expect(result.Message).To(MatchRegexp(`^free disk space: ` + dir + ` [0-9.]+ GiB$`))
+	})
+
+	It("connects to the containerd socket when containerd is installed", func() {
+		address := filepath.Join(GinkgoT().TempDir(), "containerd.sock")
+		Expect(NewContainerdCheck(address).Run(context.Background())).To(Equal(Result{Status: Skipped, Message: "no containerd socket at " + address}))
+
+		listener, err := net.Listen("unix", address)
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		Expect(NewContainerdCheck(address).Run(context.Background())).To(Equal(Result{Status: Passed, Message: "containerd reachable at " + address}))
+
+		Expect(listener.Close()).To(Succeed())
+		Expect(os.WriteFile(address, nil, 0o600)).To(Succeed())
+		result := NewContainerdCheck(address).Run(context.Background())
+		Expect(result.Status).To(Equal(Failed))
+		Expect(result.Message).To(HavePrefix("unable to connect to containerd: "))
+	})
+
+	It("reports the container runtime that cannot be run", func() {
+		runner := &mockCommandRunner{}
+		runner.On("Execute", "podman", []string{"version", "--format", "{{.Client.Version}}"}).Return([]byte(nil), []byte("permission denied\n"), errors.New("exit status 125"))
+
+		result := NewContainerRuntimeCheck(scanner.PodmanRuntime, runner).Run(context.Background())
+
+		Expect(result).To(Equal(Result{Status: Failed, Message: "unable to run podman: exit status 125 permission denied", Remedy: "install podman or use --container-runtime docker"}))
+	})
+})
+
+type staticCheck struct {
+	name   string
+	result Result
+}
+
+func (c staticCheck) Name() string {
+	return c.name
+}
+
+func (c staticCheck) Run(_ context.Context) Result {
+	return c.result
+}
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
@@ -0,0 +1,150 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the url of the GitHub REST API, GitHub Enterprise Server exposing it at https://<host>/api/v3
+const DefaultAPIURL = "https://api.github.com"
+
+// Client is a thin client for the GitHub issues REST API, the repositories being given as owner/name
+type Client interface {
+	// ListIssues returns the open issues of the repository with the given label, without the pull requests
+	ListIssues(repository, label string) ([]Issue, error)
+	// CreateIssue creates the issue and returns its number
+	CreateIssue(repository string, issue *Issue) (int, error)
+	AddComment(repository string, number int, comment string) error
+	CloseIssue(repository string, number int) error
+}
+
+// Issue holds the fields of a GitHub issue
+type Issue struct {
+	Number int      `json:"number,omitempty"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+type client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client authenticating with the token, the public GitHub API being used when no url is given
+func NewClient(baseURL, token string) Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *client) ListIssues(repository, label string) ([]Issue, error) {
+	var issues []Issue
+	for page := 1; ; page++ {
+		var result []struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+			Body   string `json:"body"`
+			Labels []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+			PullRequest *struct{} `json:"pull_request"`
+		}
+		path := fmt.Sprintf("/repos/%s/issues?state=open&per_page=100&page=%d&labels=%s", repository, page, url.QueryEscape(label))
+		if err := c.do(http.MethodGet, path, nil, &result); err != nil {
+			return nil, fmt.Errorf("error listing github issues of repository %s: %v", repository, err)
+		}
+		for _, item := range result {
+			if item.PullRequest != nil {
+				continue
+			}
+			issue := Issue{Number: item.Number, Title: item.Title, Body: item.Body}
+			for _, l := range item.Labels {
+				issue.Labels = append(issue.Labels, l.Name)
+			}
+			issues = append(issues, issue)
+		}
+		if len(result) < 100 {
+			return issues, nil
+		}
+	}
+}
+
+func (c *client) CreateIssue(repository string, issue *Issue) (int, error) {
+	var result struct {
+		Number int `json:"number"`
+	}
+	err := c.do(http.MethodPost, "/repos/"+repository+"/issues", issue, &result)
+	if err != nil {
+		return 0, fmt.Errorf("error creating github issue in repository %s: %v", repository, err)
+	}
+	return result.Number, nil
+}
+
+func (c *client) AddComment(repository string, number int, comment string) error {
+	err := c.do(http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", repository, number), map[string]string{"body": comment}, nil)
+	if err != nil {
+		return fmt.Errorf("error commenting github issue %s#%d: %v", repository, number, err)
+	}
+	return nil
+}
+
+func (c *client) CloseIssue(repository string, number int) error {
+	request := map[string]string{"state": "closed", "state_reason": "completed"}
+	err := c.do(http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repository, number), request, nil)
+	if err != nil {
+		return fmt.Errorf("error closing github issue %s#%d: %v", repository, number, err)
+	}
+	return nil
+}
+
+func (c *client) do(method, path string, requestBody, responseBody interface{}) error {
+	var body io.Reader
+	if requestBody != nil {
+		content, err := json.Marshal(requestBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if requestBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("github returned status %d: %s", resp.StatusCode, string(content))
+	}
+	if responseBody != nil {
+		return json.Unmarshal(content, responseBody)
+	}
+	return nil
+}
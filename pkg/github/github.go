@@ -0,0 +1,271 @@
+package github
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+const issueLabel = "prod-readiness"
+
+// issueSeverities are the severities of the vulnerabilities an issue is opened for
+var issueSeverities = []string{"CRITICAL", "HIGH"}
+
+// markerPattern matches the hidden marker identifying the team, image and vulnerability of an issue in its body
+var markerPattern = regexp.MustCompile(`<!-- prod-readiness team=(\S+) finding=([0-9a-f]+) -->`)
+
+// imagePattern matches the hidden marker of the image name of an issue in its body, the issues opened before it was
+// added holding the image name at the end of their title only
+var imagePattern = regexp.MustCompile(`<!-- prod-readiness image=(\S+) -->`)
+
+// Config is the config used to sync the GitHub issues
+type Config struct {
+	// DefaultRepository is the owner/name repository used for the teams without a repository in TeamRepositories
+	DefaultRepository string
+	// TeamRepositories maps a team name to its owner/name repository
+	TeamRepositories map[string]string
+	// FixableOnly restricts the issues to the vulnerabilities with a fixed version
+	FixableOnly bool
+}
+
+// Issuer opens, updates and closes GitHub issues for the high and critical vulnerabilities of the team images
+type Issuer struct {
+	config *Config
+	client Client
+}
+
+// New creates an Issuer
+func New(client Client, config *Config) *Issuer {
+	return &Issuer{
+		config: config,
+		client: client,
+	}
+}
+
+// repositoryFindings holds the current findings of the teams sharing a repository, keyed by issue marker
+type repositoryFindings struct {
+	teams    map[string]bool
+	findings map[string]scanner.VulnerabilityFinding
+}
+
+// SyncIssues opens an issue in the team repository for each new high or critical vulnerability of the team images,
+// or comments the issue already open for the same image and vulnerability. The open issues of the vulnerabilities
+// no longer found in the team images are closed, so that the repositories reflect the latest scan. The issues of the
+// images whose scan failed or timed out, and of the images missing from a partial report, are left open, see
+// scanner.ReportMetadata.Partial
+func (i *Issuer) SyncIssues(report, baseline *scanner.VulnerabilityReport) error {
+	newFindings := make(map[string]bool)
+	for _, finding := range i.findings(report, baseline) {
+		newFindings[findingID(finding)] = true
+	}
+	current := i.findings(report, nil)
+
+	repositories := make(map[string]*repositoryFindings)
+	for _, area := range report.AreaSummary {
+		for _, team := range area.Teams {
+			repository := i.repositoryFor(team.Name)
+			findings := team.Findings(current)
+			if repository == "" {
+				if len(findings) > 0 {
+					logr.Warnf("No github repository configured for team %s, skipping %d high or critical vulnerabilities", team.Name, len(findings))
+				}
+				continue
+			}
+			r, ok := repositories[repository]
+			if !ok {
+				r = &repositoryFindings{teams: make(map[string]bool), findings: make(map[string]scanner.VulnerabilityFinding)}
+				repositories[repository] = r
+			}
+			r.teams[team.Name] = true
+			for _, finding := range findings {
+				r.findings[marker(team.Name, finding)] = finding
+			}
+		}
+	}
+
+	names := make([]string, 0, len(repositories))
+	for repository := range repositories {
+		names = append(names, repository)
+	}
+	sort.Strings(names)
+	scanned := make(map[string]bool)
+	for _, image := range report.ScannedImages {
+		scanned[image.ImageName] = image.ScanError == nil && !image.TimedOut
+	}
+	closable := func(imageName string) bool {
+		if complete, ok := scanned[imageName]; ok {
+			return complete
+		}
+		return !report.Metadata.Partial()
+	}
+	var failures int
+	for _, repository := range names {
+		failures += i.syncRepository(repository, repositories[repository], newFindings, closable)
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d github issue(s) could not be created, updated or closed", failures)
+	}
+	return nil
+}
+
+func (i *Issuer) findings(report, baseline *scanner.VulnerabilityReport) []scanner.VulnerabilityFinding {
+	var findings []scanner.VulnerabilityFinding
+	for _, severity := range issueSeverities {
+		findings = append(findings, report.NewVulnerabilities(baseline, severity)...)
+	}
+	if i.config.FixableOnly {
+		findings = scanner.FixableFindings(findings)
+	}
+	return findings
+}
+
+func (i *Issuer) repositoryFor(team string) string {
+	if repository, ok := i.config.TeamRepositories[team]; ok {
+		return repository
+	}
+	return i.config.DefaultRepository
+}
+
+// syncRepository creates or comments the issues of the new findings of the repository and closes the issues of the
+// fixed ones, only the issues of the teams of the report whose image is closable being closed. It returns the number
+// of failures
+func (i *Issuer) syncRepository(repository string, r *repositoryFindings, newFindings map[string]bool, closable func(imageName string) bool) int {
+	issues, err := i.client.ListIssues(repository, issueLabel)
+	if err != nil {
+		logr.Error(err)
+		return 1
+	}
+	open := make(map[string]Issue)
+	for _, issue := range issues {
+		if m := markerPattern.FindString(issue.Body); m != "" {
+			open[m] = issue
+		}
+	}
+
+	markers := make([]string, 0, len(r.findings))
+	for m := range r.findings {
+		markers = append(markers, m)
+	}
+	sort.Strings(markers)
+	var failures int
+	for _, m := range markers {
+		finding := r.findings[m]
+		if !newFindings[findingID(finding)] {
+			continue
+		}
+		if err := i.createOrUpdate(repository, m, finding, open); err != nil {
+			logr.Error(err)
+			failures++
+		}
+	}
+
+	for _, issue := range issues {
+		m := markerPattern.FindString(issue.Body)
+		if m == "" {
+			continue
+		}
+		team := markerPattern.FindStringSubmatch(m)[1]
+		if _, found := r.findings[m]; found || !r.teams[team] || !closable(issueImage(issue)) {
+			continue
+		}
+		logr.Infof("Closing github issue %s#%d, the vulnerability is no longer found", repository, issue.Number)
+		if err := i.client.AddComment(repository, issue.Number, "Vulnerability no longer found in the latest scan, closing the issue."); err != nil {
+			logr.Error(err)
+			failures++
+			continue
+		}
+		if err := i.client.CloseIssue(repository, issue.Number); err != nil {
+			logr.Error(err)
+			failures++
+		}
+	}
+	return failures
+}
+
+func (i *Issuer) createOrUpdate(repository, m string, finding scanner.VulnerabilityFinding, open map[string]Issue) error {
+	v := finding.Vulnerability
+	if issue, ok := open[m]; ok {
+		logr.Infof("Updating github issue %s#%d for %s in %s", repository, issue.Number, v.VulnerabilityID, finding.ImageName)
+		return i.client.AddComment(repository, issue.Number, "Vulnerability still present in the latest scan.\n\n"+affectedWorkloads(finding))
+	}
+
+	number, err := i.client.CreateIssue(repository, &Issue{
+		Title:  fmt.Sprintf("[%s] %s (%s) in %s", v.Severity, v.VulnerabilityID, v.PkgName, finding.ImageName),
+		Body:   body(finding) + "\n" + m + "\n" + imageMarker(finding.ImageName) + "\n",
+		Labels: []string{issueLabel},
+	})
+	if err != nil {
+		return err
+	}
+	logr.Infof("Created github issue %s#%d for %s in %s", repository, number, v.VulnerabilityID, finding.ImageName)
+	return nil
+}
+
+// findingID returns a stable id of the image and vulnerability
+func findingID(finding scanner.VulnerabilityFinding) string {
+	hash := sha1.Sum([]byte(finding.ImageName + "|" + finding.Vulnerability.VulnerabilityID + "|" + finding.Vulnerability.PkgName))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// marker returns the hidden marker of the issue of the team finding, used to find the existing issues
+func marker(team string, finding scanner.VulnerabilityFinding) string {
+	return fmt.Sprintf("<!-- prod-readiness team=%s finding=%s -->", team, findingID(finding))
+}
+
+// imageMarker returns the hidden marker of the image name of an issue, used to close the issues of the scanned images only
+func imageMarker(imageName string) string {
+	return fmt.Sprintf("<!-- prod-readiness image=%s -->", imageName)
+}
+
+// issueImage returns the image name of the issue, from its image marker or else from the end of its title, empty when
+// unknown
+func issueImage(issue Issue) string {
+	if m := imagePattern.FindStringSubmatch(issue.Body); m != nil {
+		return m[1]
+	}
+	if i := strings.LastIndex(issue.Title, " in "); i >= 0 {
+		return issue.Title[i+len(" in "):]
+	}
+	return ""
+}
+
+func body(finding scanner.VulnerabilityFinding) string {
+	v := finding.Vulnerability
+	fixedVersion := v.FixedVersion
+	if fixedVersion == "" {
+		fixedVersion = "no fix available"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "A new %s vulnerability has been found by the production readiness image scan.\n\n", strings.ToLower(v.Severity))
+	fmt.Fprintf(&b, "**Image:** %s\n", finding.ImageName)
+	fmt.Fprintf(&b, "**Vulnerability:** [%s](https://nvd.nist.gov/vuln/detail/%s)\n", v.VulnerabilityID, v.VulnerabilityID)
+	fmt.Fprintf(&b, "**Package:** %s %s\n", v.PkgName, v.InstalledVersion)
+	fmt.Fprintf(&b, "**Fixed version:** %s\n", fixedVersion)
+	if v.Title != "" {
+		fmt.Fprintf(&b, "**Title:** %s\n", v.Title)
+	}
+	b.WriteString("\n")
+	b.WriteString(affectedWorkloads(finding))
+	return b.String()
+}
+
+func affectedWorkloads(finding scanner.VulnerabilityFinding) string {
+	var b strings.Builder
+	b.WriteString("**Affected workloads:**\n")
+	for _, c := range finding.Containers {
+		if c.Type != k8s.RegularContainer {
+			fmt.Fprintf(&b, "* %s/%s (%s container %s)\n", c.Namespace, c.PodName, c.Type, c.ContainerName)
+			continue
+		}
+		fmt.Fprintf(&b, "* %s/%s (container %s)\n", c.Namespace, c.PodName, c.ContainerName)
+	}
+	return b.String()
+}
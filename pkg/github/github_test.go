@@ -0,0 +1,190 @@
+package github
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitHub(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GitHub Suite")
+}
+
+var _ = Describe("GitHub issues", func() {
+
+	var (
+		mockClient *mockGitHubClient
+		report     *scanner.VulnerabilityReport
+		image      scanner.ScannedImage
+	)
+
+	finding := func(vulnerabilityID, pkgName string) scanner.VulnerabilityFinding {
+		return scanner.VulnerabilityFinding{ImageName: "image:1.0", Vulnerability: scanner.Vulnerabilities{VulnerabilityID: vulnerabilityID, PkgName: pkgName}}
+	}
+
+	BeforeEach(func() {
+		mockClient = &mockGitHubClient{}
+		image = scanner.NewScannedImage("image:1.0", []k8s.ContainerSummary{{Namespace: "ns1", PodName: "pod1", ContainerName: "app"}}, []scanner.TrivyOutputResults{
+			{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", InstalledVersion: "1.1.1", FixedVersion: "1.1.2", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-3", PkgName: "zlib", Severity: "MEDIUM"},
+			}},
+		}, nil)
+		report = &scanner.VulnerabilityReport{
+			ScannedImages: []scanner.ScannedImage{image},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"area1": {Name: "area1", Teams: map[string]*scanner.TeamSummary{
+					"team1": {Name: "team1", Images: []scanner.ScannedImage{image}},
+				}},
+			},
+		}
+	})
+
+	It("creates an issue in the team repository for each new high or critical vulnerability", func() {
+		issuer := New(mockClient, &Config{DefaultRepository: "org/security", TeamRepositories: map[string]string{"team1": "org/team1"}})
+		mockClient.On("ListIssues", "org/team1", "prod-readiness").Return([]Issue(nil), nil)
+		mockClient.On("CreateIssue", "org/team1", mock.MatchedBy(func(issue *Issue) bool {
+			return issue.Title == "[CRITICAL] CVE-1 (openssl) in image:1.0" &&
+				strings.Contains(issue.Body, "**Fixed version:** 1.1.2") &&
+				strings.Contains(issue.Body, "ns1/pod1 (container app)") &&
+				strings.Contains(issue.Body, marker("team1", finding("CVE-1", "openssl"))) &&
+				strings.Contains(issue.Body, imageMarker("image:1.0")) &&
+				reflect.DeepEqual(issue.Labels, []string{"prod-readiness"})
+		})).Return(1, nil)
+		mockClient.On("CreateIssue", "org/team1", mock.MatchedBy(func(issue *Issue) bool {
+			return issue.Title == "[HIGH] CVE-2 (curl) in image:1.0" &&
+				strings.Contains(issue.Body, "**Fixed version:** no fix available")
+		})).Return(2, nil)
+
+		err := issuer.SyncIssues(report, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+		mockClient.AssertNumberOfCalls(GinkgoT(), "CreateIssue", 2)
+	})
+
+	It("comments the existing issue of the vulnerability", func() {
+		issuer := New(mockClient, &Config{DefaultRepository: "org/security", FixableOnly: true})
+		mockClient.On("ListIssues", "org/security", "prod-readiness").Return([]Issue{
+			{Number: 12, Body: "description\n" + marker("team1", finding("CVE-1", "openssl")) + "\n"},
+		}, nil)
+		mockClient.On("AddComment", "org/security", 12, mock.MatchedBy(func(comment string) bool {
+			return strings.HasPrefix(comment, "Vulnerability still present in the latest scan.")
+		})).Return(nil)
+
+		err := issuer.SyncIssues(report, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+		mockClient.AssertNotCalled(GinkgoT(), "CreateIssue", mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(GinkgoT(), "CloseIssue", mock.Anything, mock.Anything)
+	})
+
+	It("closes the issues of the vulnerabilities fixed since the previous scan", func() {
+		issuer := New(mockClient, &Config{DefaultRepository: "org/security"})
+		mockClient.On("ListIssues", "org/security", "prod-readiness").Return([]Issue{
+			{Number: 12, Body: marker("team1", finding("CVE-1", "openssl"))},
+			{Number: 13, Body: marker("team1", finding("CVE-9", "bash"))},
+			{Number: 14, Body: marker("team2", finding("CVE-9", "bash"))},
+			{Number: 15, Body: "opened by hand"},
+		}, nil)
+		mockClient.On("AddComment", "org/security", 13, mock.Anything).Return(nil)
+		mockClient.On("CloseIssue", "org/security", 13).Return(nil)
+
+		err := issuer.SyncIssues(report, &scanner.VulnerabilityReport{ScannedImages: []scanner.ScannedImage{image}})
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+		mockClient.AssertNumberOfCalls(GinkgoT(), "CloseIssue", 1)
+		mockClient.AssertNotCalled(GinkgoT(), "CreateIssue", mock.Anything, mock.Anything)
+	})
+
+	It("leaves open the issues of the images missing from a partial report", func() {
+		issuer := New(mockClient, &Config{DefaultRepository: "org/security"})
+		mockClient.On("ListIssues", "org/security", "prod-readiness").Return([]Issue{
+			{Number: 12, Title: "[HIGH] CVE-9 (bash) in image:1.0", Body: marker("team1", finding("CVE-9", "bash"))},
+			{Number: 13, Body: marker("team1", scanner.VulnerabilityFinding{ImageName: "other:1.0", Vulnerability: scanner.Vulnerabilities{VulnerabilityID: "CVE-9", PkgName: "bash"}}) + "\n" + imageMarker("other:1.0")},
+		}, nil)
+		mockClient.On("AddComment", "org/security", 12, mock.Anything).Return(nil)
+		mockClient.On("CloseIssue", "org/security", 12).Return(nil)
+		report.Metadata.Incomplete = true
+
+		err := issuer.SyncIssues(report, report)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+		mockClient.AssertNumberOfCalls(GinkgoT(), "CloseIssue", 1)
+	})
+
+	It("leaves open the issues of the images whose scan failed", func() {
+		issuer := New(mockClient, &Config{DefaultRepository: "org/security"})
+		mockClient.On("ListIssues", "org/security", "prod-readiness").Return([]Issue{
+			{Number: 12, Body: marker("team1", finding("CVE-1", "openssl")) + "\n" + imageMarker("image:1.0")},
+		}, nil)
+		failed := scanner.NewScannedImage("image:1.0", image.Containers, nil, errors.New("unable to pull image"))
+		report.ScannedImages = []scanner.ScannedImage{failed}
+		report.AreaSummary["area1"].Teams["team1"].Images = []scanner.ScannedImage{failed}
+
+		err := issuer.SyncIssues(report, report)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+		mockClient.AssertNotCalled(GinkgoT(), "AddComment", mock.Anything, mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(GinkgoT(), "CloseIssue", mock.Anything, mock.Anything)
+	})
+
+	It("skips the teams without a repository", func() {
+		issuer := New(mockClient, &Config{})
+
+		err := issuer.SyncIssues(report, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertNotCalled(GinkgoT(), "ListIssues", mock.Anything, mock.Anything)
+	})
+
+	It("reports the issues that could not be synced", func() {
+		issuer := New(mockClient, &Config{DefaultRepository: "org/security"})
+		mockClient.On("ListIssues", "org/security", "prod-readiness").Return([]Issue(nil), nil)
+		mockClient.On("CreateIssue", "org/security", mock.Anything).Return(0, errors.New("github returned status 410: issues are disabled"))
+
+		err := issuer.SyncIssues(report, nil)
+
+		Expect(err).To(MatchError("2 github issue(s) could not be created, updated or closed"))
+	})
+})
+
+type mockGitHubClient struct {
+	mock.Mock
+}
+
+var _ Client = &mockGitHubClient{}
+
+func (c *mockGitHubClient) ListIssues(repository, label string) ([]Issue, error) {
+	args := c.Called(repository, label)
+	return args.Get(0).([]Issue), args.Error(1)
+}
+
+func (c *mockGitHubClient) CreateIssue(repository string, issue *Issue) (int, error) {
+	args := c.Called(repository, issue)
+	return args.Int(0), args.Error(1)
+}
+
+func (c *mockGitHubClient) AddComment(repository string, number int, comment string) error {
+	args := c.Called(repository, number, comment)
+	return args.Error(0)
+}
+
+func (c *mockGitHubClient) CloseIssue(repository string, number int) error {
+	args := c.Called(repository, number)
+	return args.Error(0)
+}
@@ -0,0 +1,212 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+const (
+	// schemaVersion is the version of the GitLab security report schema the reports are generated with
+	schemaVersion = "15.0.7"
+	// timeFormat is the format of the scan times of the GitLab security reports, without time zone
+	timeFormat = "2006-01-02T15:04:05"
+)
+
+// ContainerScanningReport is a GitLab container scanning report, attached to the pipelines as
+// artifacts:reports:container_scanning so that the vulnerabilities are shown in the merge request security widget and
+// the vulnerability report of the project
+type ContainerScanningReport struct {
+	Version         string          `json:"version"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	Scan            Scan            `json:"scan"`
+}
+
+// Vulnerability is a vulnerability of a package of an image
+type Vulnerability struct {
+	// ID identifies the vulnerability of the package of the image across the reports
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Severity    string       `json:"severity"`
+	Solution    string       `json:"solution,omitempty"`
+	Identifiers []Identifier `json:"identifiers"`
+	Links       []Link       `json:"links,omitempty"`
+	Location    Location     `json:"location"`
+}
+
+// Identifier is an identifier of a vulnerability, for instance its CVE
+type Identifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Link is a reference url of a vulnerability
+type Link struct {
+	URL string `json:"url"`
+}
+
+// Location is the package of the image the vulnerability is found in
+type Location struct {
+	Dependency      Dependency `json:"dependency"`
+	OperatingSystem string     `json:"operating_system"`
+	Image           string     `json:"image"`
+}
+
+// Dependency is a package at its installed version
+type Dependency struct {
+	Package Package `json:"package"`
+	Version string  `json:"version"`
+}
+
+// Package is a package name
+type Package struct {
+	Name string `json:"name"`
+}
+
+// Scan describes the scan the vulnerabilities were found by
+type Scan struct {
+	Analyzer  Tool      `json:"analyzer"`
+	Scanner   Tool      `json:"scanner"`
+	Type      string    `json:"type"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Status    string    `json:"status"`
+	Messages  []Message `json:"messages,omitempty"`
+}
+
+// Tool is the analyzer or the scanner of a scan
+type Tool struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	Version string `json:"version"`
+	Vendor  Vendor `json:"vendor"`
+}
+
+// Vendor is the vendor of a tool
+type Vendor struct {
+	Name string `json:"name"`
+}
+
+// Message is a message of a scan, shown in the pipeline security tab
+type Message struct {
+	// Level is info, warn or fatal, the levels of the GitLab security report schema
+	Level string `json:"level"`
+	Value string `json:"value"`
+}
+
+// NewContainerScanningReport converts the vulnerabilities of the scanned images of the report to a GitLab container
+// scanning report of a scan ended at endTime. The images whose scan failed are listed in the messages of the scan,
+// which fails when the report is incomplete
+func NewContainerScanningReport(report *scanner.VulnerabilityReport, endTime time.Time) *ContainerScanningReport {
+	trivyVersion := report.Metadata.TrivyVersion
+	if trivyVersion == "" {
+		trivyVersion = "unknown"
+	}
+	startTime := report.Metadata.ScanTime
+	if startTime.IsZero() {
+		startTime = endTime
+	}
+	gitlabReport := &ContainerScanningReport{
+		Version:         schemaVersion,
+		Vulnerabilities: []Vulnerability{},
+		Scan: Scan{
+			// the analyzer is not versioned on its own, it is versioned as the trivy it runs
+			Analyzer:  Tool{ID: "production-readiness", Name: "Production Readiness", URL: "https://github.com/coreeng/production-readiness", Version: trivyVersion, Vendor: Vendor{Name: "CECG"}},
+			Scanner:   Tool{ID: "trivy", Name: "Trivy", URL: "https://github.com/aquasecurity/trivy", Version: trivyVersion, Vendor: Vendor{Name: "Aqua Security"}},
+			Type:      "container_scanning",
+			StartTime: startTime.UTC().Format(timeFormat),
+			EndTime:   endTime.UTC().Format(timeFormat),
+			Status:    "success",
+		},
+	}
+	if report.Metadata.Incomplete {
+		gitlabReport.Scan.Status = "failure"
+		message := "The scan was interrupted, the report only holds the images scanned before"
+		if report.Metadata.FailFastFinding != "" {
+			message = fmt.Sprintf("The scan stopped on %s, the report only holds the images scanned before", report.Metadata.FailFastFinding)
+		}
+		gitlabReport.Scan.Messages = append(gitlabReport.Scan.Messages, Message{Level: "fatal", Value: message})
+	}
+
+	for _, image := range report.ScannedImages {
+		if image.ScanError != nil {
+			gitlabReport.Scan.Messages = append(gitlabReport.Scan.Messages, Message{Level: "warn", Value: fmt.Sprintf("Could not scan image %s: %v", image.ImageName, image.ScanError)})
+			continue
+		}
+		operatingSystem := "Unknown"
+		if image.OS != nil {
+			operatingSystem = strings.TrimSpace(image.OS.Family + " " + image.OS.Name)
+		}
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				gitlabReport.Vulnerabilities = append(gitlabReport.Vulnerabilities, convert(image.ImageName, operatingSystem, target.Target, vulnerability))
+			}
+		}
+	}
+	return gitlabReport
+}
+
+func convert(imageName, operatingSystem, target string, v scanner.Vulnerabilities) Vulnerability {
+	name := v.Title
+	if name == "" {
+		name = v.VulnerabilityID
+	}
+	vulnerability := Vulnerability{
+		ID:          utils.NameUUID(imageName, target, v.VulnerabilityID, v.PkgName, v.InstalledVersion),
+		Name:        name,
+		Description: v.Description,
+		Severity:    severity(v.Severity),
+		Identifiers: []Identifier{{Type: identifierType(v.VulnerabilityID), Name: v.VulnerabilityID, Value: v.VulnerabilityID}},
+		Location: Location{
+			Dependency:      Dependency{Package: Package{Name: v.PkgName}, Version: v.InstalledVersion},
+			OperatingSystem: operatingSystem,
+			Image:           imageName,
+		},
+	}
+	if v.Fixable() {
+		vulnerability.Solution = fmt.Sprintf("Upgrade %s to %s", v.PkgName, v.FixedVersion)
+	}
+	for _, reference := range v.References {
+		vulnerability.Links = append(vulnerability.Links, Link{URL: reference})
+	}
+	return vulnerability
+}
+
+// severity returns the GitLab severity of a trivy severity, for instance Critical for CRITICAL
+func severity(trivySeverity string) string {
+	switch trivySeverity {
+	case "CRITICAL", "HIGH", "MEDIUM", "LOW":
+		return trivySeverity[:1] + strings.ToLower(trivySeverity[1:])
+	default:
+		return "Unknown"
+	}
+}
+
+// identifierType returns the type of a vulnerability identifier from its prefix, for instance cve for CVE-2023-1234
+// or ghsa for GHSA-xxxx-xxxx-xxxx
+func identifierType(vulnerabilityID string) string {
+	if prefix, _, found := strings.Cut(vulnerabilityID, "-"); found && prefix != "" {
+		return strings.ToLower(prefix)
+	}
+	return "trivy"
+}
+
+// Save writes the report to the file
+func (r *ContainerScanningReport) Save(filename string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, content, 0644); err != nil {
+		return fmt.Errorf("could not write GitLab container scanning report %s: %v", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitLab(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GitLab Suite")
+}
+
+var _ = Describe("GitLab container scanning report", func() {
+
+	var (
+		scanTime = time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+		endTime  = scanTime.Add(5 * time.Minute)
+		report   *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		report = &scanner.VulnerabilityReport{
+			Metadata: scanner.ReportMetadata{ScanTime: scanTime, TrivyVersion: "0.45.0"},
+			ScannedImages: []scanner.ScannedImage{
+				{
+					ImageName: "registry/api:1",
+					OS:        &scanner.OS{Family: "debian", Name: "11.7"},
+					TrivyOutputResults: []scanner.TrivyOutputResults{{Target: "registry/api:1 (debian 11.7)", Vulnerabilities: []scanner.Vulnerabilities{
+						{VulnerabilityID: "CVE-2023-0001", PkgName: "openssl", InstalledVersion: "3.0.0", FixedVersion: "3.0.1", Severity: "CRITICAL", Title: "openssl: buffer overflow", Description: "A buffer overflow", References: []string{"https://nvd.nist.gov/vuln/detail/CVE-2023-0001"}},
+					}}},
+				},
+				{
+					ImageName:          "registry/web:2",
+					TrivyOutputResults: []scanner.TrivyOutputResults{{Target: "app.jar", Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: "GHSA-abcd-efgh-ijkl", PkgName: "log4j", InstalledVersion: "2.14", Severity: "UNKNOWN"}}}},
+				},
+				{ImageName: "registry/db:3", ScanError: errors.New("timeout")},
+			},
+		}
+	})
+
+	It("converts the vulnerabilities of the images", func() {
+		gitlabReport := NewContainerScanningReport(report, endTime)
+
+		Expect(gitlabReport.Version).To(Equal(schemaVersion))
+		Expect(gitlabReport.Vulnerabilities).To(HaveLen(2))
+		vulnerability := gitlabReport.Vulnerabilities[0]
+		Expect(vulnerability.ID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(vulnerability.Name).To(Equal("openssl: buffer overflow"))
+		Expect(vulnerability.Description).To(Equal("A buffer overflow"))
+		Expect(vulnerability.Severity).To(Equal("Critical"))
+		Expect(vulnerability.Solution).To(Equal("Upgrade openssl to 3.0.1"))
+		Expect(vulnerability.Identifiers).To(Equal([]Identifier{{Type: "cve", Name: "CVE-2023-0001", Value: "CVE-2023-0001"}}))
+		Expect(vulnerability.Links).To(Equal([]Link{{URL: "https://nvd.nist.gov/vuln/detail/CVE-2023-0001"}}))
+		Expect(vulnerability.Location).To(Equal(Location{
+			Dependency:      Dependency{Package: Package{Name: "openssl"}, Version: "3.0.0"},
+			OperatingSystem: "debian 11.7",
+			Image:           "registry/api:1",
+		}))
+
+		vulnerability = gitlabReport.Vulnerabilities[1]
+		Expect(vulnerability.Name).To(Equal("GHSA-abcd-efgh-ijkl"))
+		Expect(vulnerability.Severity).To(Equal("Unknown"))
+		Expect(vulnerability.Solution).To(BeEmpty())
+		Expect(vulnerability.Identifiers[0].Type).To(Equal("ghsa"))
+		Expect(vulnerability.Location.OperatingSystem).To(Equal("Unknown"))
+	})
+
+	It("keeps the ids of the vulnerabilities across the reports", func() {
+		first := NewContainerScanningReport(report, endTime)
+		second := NewContainerScanningReport(report, endTime.Add(time.Hour))
+
+		Expect(second.Vulnerabilities[0].ID).To(Equal(first.Vulnerabilities[0].ID))
+		Expect(second.Vulnerabilities[1].ID).NotTo(Equal(first.Vulnerabilities[0].ID))
+	})
+
+	It("describes the scan and lists the images whose scan failed", func() {
+		scan := NewContainerScanningReport(report, endTime).Scan
+
+		Expect(scan.Type).To(Equal("container_scanning"))
+		Expect(scan.StartTime).To(Equal("2023-09-01T10:00:00"))
+		Expect(scan.EndTime).To(Equal("2023-09-01T10:05:00"))
+		Expect(scan.Status).To(Equal("success"))
+		Expect(scan.Scanner.ID).To(Equal("trivy"))
+		Expect(scan.Scanner.Version).To(Equal("0.45.0"))
+		Expect(scan.Messages).To(Equal([]Message{{Level: "warn", Value: "Could not scan image registry/db:3: timeout"}}))
+	})
+
+	It("fails the scan when the report is incomplete", func() {
+		report.Metadata.Incomplete = true
+
+		scan := NewContainerScanningReport(report, endTime).Scan
+
+		Expect(scan.Status).To(Equal("failure"))
+		Expect(scan.Messages[0].Level).To(Equal("fatal"))
+	})
+
+	It("saves the report with an empty vulnerability list when none is found", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "gl-container-scanning-report.json")
+
+		Expect(NewContainerScanningReport(&scanner.VulnerabilityReport{}, endTime).Save(filename)).To(Succeed())
+
+		content, err := os.ReadFile(filename)
+		Expect(err).NotTo(HaveOccurred())
+		var saved map[string]interface{}
+		Expect(json.Unmarshal(content, &saved)).To(Succeed())
+		Expect(saved["vulnerabilities"]).To(Equal([]interface{}{}))
+		Expect(saved["scan"].(map[string]interface{})["start_time"]).To(Equal("2023-09-01T10:05:00"))
+	})
+})
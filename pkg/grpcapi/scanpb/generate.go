@@ -0,0 +1,5 @@
+// Package scanpb holds the gRPC API of the scans generated from scan.proto with protoc and the protoc-gen-go and
+// protoc-gen-go-grpc plugins of tools.go
+package scanpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative scan.proto
@@ -0,0 +1,269 @@
+package grpcapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/grpcapi/scanpb"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxScans is the number of scans whose results are kept, the oldest ended scans being forgotten first
+const maxScans = 10
+
+// ScanFunc scans the images, writing each scanned image as a json line to the stream as soon as it is scanned, see
+// scanner.Config.Stream, and returns the vulnerability report of the scan
+type ScanFunc func(ctx context.Context, stream io.Writer) (*scanner.VulnerabilityReport, error)
+
+// Server implements the gRPC ScanService, running the scans one at a time with the scan function
+type Server struct {
+	scanpb.UnimplementedScanServiceServer
+	// ctx is the context of the scans, so that they outlive the StartScan requests
+	ctx  context.Context
+	scan ScanFunc
+	// guards the scans, each scan update closing the updated channel of the scan
+	lock    sync.Mutex
+	scans   map[string]*scanState
+	scanIDs []string
+	nextID  int
+}
+
+type scanState struct {
+	id        string
+	status    scanpb.ScanStatus
+	startTime time.Time
+	endTime   time.Time
+	images    []*scanpb.ImageResult
+	report    []byte
+	err       string
+	updated   chan struct{}
+}
+
+// NewServer creates a Server running the scans with the scan function until the context is done
+func NewServer(ctx context.Context, scan ScanFunc) *Server {
+	return &Server{ctx: ctx, scan: scan, scans: make(map[string]*scanState)}
+}
+
+// StartScan starts a scan, unless another scan is running
+func (s *Server) StartScan(_ context.Context, _ *scanpb.StartScanRequest) (*scanpb.StartScanResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if latest := s.latestScan(); latest != nil && latest.status == scanpb.ScanStatus_SCAN_STATUS_RUNNING {
+		return nil, status.Errorf(codes.FailedPrecondition, "scan %s is already running", latest.id)
+	}
+	s.nextID++
+	state := &scanState{
+		id:        fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405Z"), s.nextID),
+		status:    scanpb.ScanStatus_SCAN_STATUS_RUNNING,
+		startTime: time.Now().UTC(),
+		updated:   make(chan struct{}),
+	}
+	s.scans[state.id] = state
+	s.scanIDs = append(s.scanIDs, state.id)
+	if len(s.scanIDs) > maxScans {
+		delete(s.scans, s.scanIDs[0])
+		s.scanIDs = s.scanIDs[1:]
+	}
+
+	logr.Infof("Starting scan %s", state.id)
+	go s.run(state)
+	return &scanpb.StartScanResponse{ScanId: state.id}, nil
+}
+
+// run runs the scan and records its images as they are scanned, then its report
+func (s *Server) run(state *scanState) {
+	report, err := s.scan(s.ctx, &imageStream{server: s, state: state})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	state.endTime = time.Now().UTC()
+	if err == nil {
+		state.report, err = json.Marshal(report)
+	}
+	if err != nil {
+		logr.Errorf("Scan %s failed: %v", state.id, err)
+		state.status = scanpb.ScanStatus_SCAN_STATUS_FAILED
+		state.err = err.Error()
+	} else {
+		logr.Infof("Scan %s succeeded", state.id)
+		state.status = scanpb.ScanStatus_SCAN_STATUS_SUCCEEDED
+	}
+	s.notify(state)
+}
+
+// notify wakes up the streams of the scan, the lock being held
+func (s *Server) notify(state *scanState) {
+	close(state.updated)
+	state.updated = make(chan struct{})
+}
+
+// StreamResults streams the images already scanned, then each image as soon as it is scanned until the scan ends
+func (s *Server) StreamResults(request *scanpb.StreamResultsRequest, stream scanpb.ScanService_StreamResultsServer) error {
+	s.lock.Lock()
+	state, err := s.findScan(request.ScanId)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for {
+		s.lock.Lock()
+		images := state.images[sent:]
+		ended := state.status != scanpb.ScanStatus_SCAN_STATUS_RUNNING
+		updated := state.updated
+		s.lock.Unlock()
+
+		for _, image := range images {
+			if err := stream.Send(image); err != nil {
+				return err
+			}
+		}
+		sent += len(images)
+		if ended {
+			return nil
+		}
+		select {
+		case <-updated:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// GetReport returns the status of the scan and its report once it succeeded
+func (s *Server) GetReport(_ context.Context, request *scanpb.GetReportRequest) (*scanpb.GetReportResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	state, err := s.findScan(request.ScanId)
+	if err != nil {
+		return nil, err
+	}
+	response := &scanpb.GetReportResponse{
+		ScanId:     state.id,
+		Status:     state.status,
+		StartTime:  timestamppb.New(state.startTime),
+		Error:      state.err,
+		ReportJson: state.report,
+	}
+	if !state.endTime.IsZero() {
+		response.EndTime = timestamppb.New(state.endTime)
+	}
+	return response, nil
+}
+
+// findScan returns the scan of the id, the latest scan when empty, the lock being held
+func (s *Server) findScan(scanID string) (*scanState, error) {
+	if scanID == "" {
+		if latest := s.latestScan(); latest != nil {
+			return latest, nil
+		}
+		return nil, status.Error(codes.NotFound, "no scan started yet")
+	}
+	state, ok := s.scans[scanID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "scan %s not found", scanID)
+	}
+	return state, nil
+}
+
+func (s *Server) latestScan() *scanState {
+	if len(s.scanIDs) == 0 {
+		return nil
+	}
+	return s.scans[s.scanIDs[len(s.scanIDs)-1]]
+}
+
+// imageStream records the scanned images the scanner streams as json lines
+type imageStream struct {
+	server *Server
+	state  *scanState
+}
+
+func (w *imageStream) Write(p []byte) (int, error) {
+	var results []*scanpb.ImageResult
+	lines := bufio.NewScanner(bytes.NewReader(p))
+	lines.Buffer(nil, len(p)+1)
+	for lines.Scan() {
+		line := lines.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var image scanner.ScannedImage
+		if err := json.Unmarshal(line, &image); err != nil {
+			return 0, fmt.Errorf("could not decode the scanned image: %v", err)
+		}
+		results = append(results, imageResult(w.state.id, image, line))
+	}
+
+	w.server.lock.Lock()
+	defer w.server.lock.Unlock()
+	w.state.images = append(w.state.images, results...)
+	w.server.notify(w.state)
+	return len(p), nil
+}
+
+func imageResult(scanID string, image scanner.ScannedImage, imageJSON []byte) *scanpb.ImageResult {
+	result := &scanpb.ImageResult{
+		ScanId:                    scanID,
+		ImageName:                 image.ImageName,
+		VulnerabilitiesBySeverity: make(map[string]int32),
+		ScannedImageJson:          append([]byte{}, imageJSON...),
+	}
+	for severity, count := range image.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+		result.VulnerabilitiesBySeverity[severity] = int32(count)
+	}
+	if image.ScanError != nil {
+		result.ScanError = image.ScanError.Error()
+	}
+	return result
+}
+
+// TokenAuth returns the server options authorising only the requests holding the token as bearer token in their
+// authorization metadata, the other requests failing with Unauthenticated. No request is authorised with an empty token
+func TokenAuth(token string) []grpc.ServerOption {
+	authorise := func(ctx context.Context) error {
+		if !authorised(ctx, token) {
+			return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+		}
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, request interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := authorise(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, request)
+		}),
+		grpc.StreamInterceptor(func(server interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorise(stream.Context()); err != nil {
+				return err
+			}
+			return handler(server, stream)
+		}),
+	}
+}
+
+// authorised returns true when the request metadata holds the token as bearer token, compared in constant time
+func authorised(ctx context.Context, token string) bool {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) != 1 {
+		return false
+	}
+	value, ok := strings.CutPrefix(values[0], "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
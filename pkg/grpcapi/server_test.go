@@ -0,0 +1,191 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/grpcapi/scanpb"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGRPCAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gRPC API Suite")
+}
+
+var _ = Describe("gRPC scan service", func() {
+
+	var (
+		client scanpb.ScanServiceClient
+		// scanned receives the images the scan streams, the scan ending with the report or error of done
+		scanned chan scanner.ScannedImage
+		done    chan error
+	)
+
+	BeforeEach(func() {
+		scanned = make(chan scanner.ScannedImage)
+		done = make(chan error)
+		scan := func(ctx context.Context, stream io.Writer) (*scanner.VulnerabilityReport, error) {
+			report := &scanner.VulnerabilityReport{}
+			for {
+				select {
+				case image := <-scanned:
+					Expect(json.NewEncoder(stream).Encode(image)).To(Succeed())
+					report.ScannedImages = append(report.ScannedImages, image)
+				case err := <-done:
+					if err != nil {
+						return nil, err
+					}
+					return report, nil
+				}
+			}
+		}
+
+		listener := bufconn.Listen(1 << 20)
+		grpcServer := grpc.NewServer()
+		scanpb.RegisterScanServiceServer(grpcServer, NewServer(context.Background(), scan))
+		go func() { _ = grpcServer.Serve(listener) }()
+		DeferCleanup(grpcServer.Stop)
+
+		conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+		client = scanpb.NewScanServiceClient(conn)
+	})
+
+	image := func(name string, critical int) scanner.ScannedImage {
+		return scanner.ScannedImage{
+			ImageName:            name,
+			VulnerabilitySummary: scanner.VulnerabilitySummary{TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": critical}},
+		}
+	}
+
+	It("streams the results of the images as they are scanned, then returns the report", func() {
+		started, err := client.StartScan(context.Background(), &scanpb.StartScanRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		scanned <- image("api:1", 2)
+
+		stream, err := client.StreamResults(context.Background(), &scanpb.StreamResultsRequest{ScanId: started.ScanId})
+		Expect(err).NotTo(HaveOccurred())
+		result, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.ScanId).To(Equal(started.ScanId))
+		Expect(result.ImageName).To(Equal("api:1"))
+		Expect(result.VulnerabilitiesBySeverity).To(Equal(map[string]int32{"CRITICAL": 2}))
+
+		scanned <- image("web:1", 0)
+		result, err = stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.ImageName).To(Equal("web:1"))
+		var scannedImage scanner.ScannedImage
+		Expect(json.Unmarshal(result.ScannedImageJson, &scannedImage)).To(Succeed())
+		Expect(scannedImage.ImageName).To(Equal("web:1"))
+
+		running, err := client.GetReport(context.Background(), &scanpb.GetReportRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(running.Status).To(Equal(scanpb.ScanStatus_SCAN_STATUS_RUNNING))
+		Expect(running.EndTime).To(BeNil())
+
+		done <- nil
+		_, err = stream.Recv()
+		Expect(err).To(Equal(io.EOF))
+
+		response, err := client.GetReport(context.Background(), &scanpb.GetReportRequest{ScanId: started.ScanId})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Status).To(Equal(scanpb.ScanStatus_SCAN_STATUS_SUCCEEDED))
+		Expect(response.EndTime).NotTo(BeNil())
+		var report scanner.VulnerabilityReport
+		Expect(json.Unmarshal(response.ReportJson, &report)).To(Succeed())
+		Expect(report.ScannedImages).To(HaveLen(2))
+	})
+
+	It("runs a single scan at a time", func() {
+		started, err := client.StartScan(context.Background(), &scanpb.StartScanRequest{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.StartScan(context.Background(), &scanpb.StartScanRequest{})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		done <- errors.New("could not list the pods")
+		Eventually(func() scanpb.ScanStatus {
+			response, err := client.GetReport(context.Background(), &scanpb.GetReportRequest{ScanId: started.ScanId})
+			Expect(err).NotTo(HaveOccurred())
+			return response.Status
+		}).Should(Equal(scanpb.ScanStatus_SCAN_STATUS_FAILED))
+		response, err := client.GetReport(context.Background(), &scanpb.GetReportRequest{ScanId: started.ScanId})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Error).To(Equal("could not list the pods"))
+		Expect(response.ReportJson).To(BeEmpty())
+
+		_, err = client.StartScan(context.Background(), &scanpb.StartScanRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		done <- nil
+	})
+
+	It("fails when the scan is unknown", func() {
+		_, err := client.GetReport(context.Background(), &scanpb.GetReportRequest{})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		stream, err := client.StreamResults(context.Background(), &scanpb.StreamResultsRequest{ScanId: "unknown"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+})
+
+var _ = Describe("gRPC token authentication", func() {
+
+	dial := func(token string) scanpb.ScanServiceClient {
+		listener := bufconn.Listen(1 << 20)
+		grpcServer := grpc.NewServer(TokenAuth(token)...)
+		scanpb.RegisterScanServiceServer(grpcServer, NewServer(context.Background(), nil))
+		go func() { _ = grpcServer.Serve(listener) }()
+		DeferCleanup(grpcServer.Stop)
+
+		conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+		return scanpb.NewScanServiceClient(conn)
+	}
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+
+	It("only authorises the requests holding the token as bearer token", func() {
+		client := dial("secret")
+
+		_, err := client.GetReport(withToken("secret"), &scanpb.GetReportRequest{})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		_, err = client.GetReport(withToken("wrong"), &scanpb.GetReportRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		_, err = client.GetReport(context.Background(), &scanpb.GetReportRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		stream, err := client.StreamResults(withToken("wrong"), &scanpb.StreamResultsRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+	})
+
+	It("authorises no request with an empty token", func() {
+		_, err := dial("").GetReport(withToken(""), &scanpb.GetReportRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+	})
+})
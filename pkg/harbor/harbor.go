@@ -0,0 +1,217 @@
+package harbor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+// vulnerabilityReportMimeTypes are the report formats accepted from the Harbor scanners, the native report format of
+// Harbor and the format of the Trivy adapter
+const vulnerabilityReportMimeTypes = "application/vnd.security.vulnerability.report; version=1.1, application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0"
+
+// cvssSource is the source the preferred CVSS scores of the vulnerabilities are recorded under when the scanner reports
+// no score per source
+const cvssSource = "harbor"
+
+// VulnerabilityReport is the object representation of the vulnerability report Harbor holds for an artifact
+type VulnerabilityReport struct {
+	GeneratedAt string `json:"generated_at"`
+	Scanner     struct {
+		Name    string `json:"name"`
+		Vendor  string `json:"vendor"`
+		Version string `json:"version"`
+	} `json:"scanner"`
+	Severity        string          `json:"severity"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Vulnerability is a vulnerability of a VulnerabilityReport
+type Vulnerability struct {
+	ID string `json:"id"`
+	// Package is the name of the vulnerable package
+	Package       string   `json:"package"`
+	Version       string   `json:"version"`
+	FixVersion    string   `json:"fix_version"`
+	Severity      string   `json:"severity"`
+	Description   string   `json:"description"`
+	Links         []string `json:"links"`
+	PreferredCVSS *struct {
+		ScoreV3  float64 `json:"score_v3"`
+		ScoreV2  float64 `json:"score_v2"`
+		VectorV3 string  `json:"vector_v3"`
+		VectorV2 string  `json:"vector_v2"`
+	} `json:"preferred_cvss"`
+	VendorAttributes struct {
+		// CVSS holds the scores per source the Trivy adapter reports, for instance nvd or redhat
+		CVSS map[string]scanner.CVSS `json:"CVSS"`
+	} `json:"vendor_attributes"`
+}
+
+// Client reads the vulnerability reports of the artifacts hosted by Harbor with its v2 API
+type Client struct {
+	baseURL    string
+	host       string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client for the Harbor instance of the url, for instance https://harbor.example.com,
+// authenticating with the username and password of a user or robot account allowed to read the artifacts
+func NewClient(harborURL, username, password string) (*Client, error) {
+	parsed, err := url.Parse(harborURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Harbor url %q, expecting for instance https://harbor.example.com", harborURL)
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(harborURL, "/"),
+		host:       parsed.Host,
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// VulnerabilityReport returns the vulnerability report of the artifact of the repository of the project, the reference
+// being a tag or a digest. It returns an error when the artifact does not exist or was not scanned yet
+func (c *Client) VulnerabilityReport(project, repository, reference string) (*VulnerabilityReport, error) {
+	artifact := fmt.Sprintf("%s/%s@%s", project, repository, reference)
+	// the slashes of the repository name are encoded twice, as Harbor decodes the path before routing it
+	path := fmt.Sprintf("/api/v2.0/projects/%s/repositories/%s/artifacts/%s/additions/vulnerabilities",
+		url.PathEscape(project), url.PathEscape(url.PathEscape(repository)), url.PathEscape(reference))
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Accept-Vulnerabilities", vulnerabilityReportMimeTypes)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the Harbor vulnerability report of %s: %v", artifact, err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the Harbor vulnerability report of %s: %v", artifact, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("artifact %s not found in Harbor", artifact)
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("error reading the Harbor vulnerability report of %s: status code %d: %s", artifact, resp.StatusCode, string(content))
+	}
+
+	// the reports are keyed by mime type, Harbor returning the report of the first format the scanner supports
+	var reports map[string]*VulnerabilityReport
+	if err := json.Unmarshal(content, &reports); err != nil {
+		return nil, fmt.Errorf("error while decoding the Harbor vulnerability report of %s: %v", artifact, err)
+	}
+	for _, report := range reports {
+		if report != nil {
+			return report, nil
+		}
+	}
+	return nil, fmt.Errorf("artifact %s not scanned by Harbor yet", artifact)
+}
+
+// ImageScan returns the trivy output of the Harbor vulnerability report of the image, the artifact being referenced by
+// the digest of the running containers when known. It returns an error for the images Harbor does not host
+func (c *Client) ImageScan(imageName string, containers []k8s.ContainerSummary) (*scanner.TrivyOutput, error) {
+	if scanner.ImageRegistry(imageName) != c.host {
+		return nil, fmt.Errorf("image %s not hosted by Harbor %s", imageName, c.host)
+	}
+	project, repository, reference, err := parseImageName(imageName)
+	if err != nil {
+		return nil, err
+	}
+	for _, container := range containers {
+		if container.ImageDigest != "" {
+			reference = container.ImageDigest
+			break
+		}
+	}
+	report, err := c.VulnerabilityReport(project, repository, reference)
+	if err != nil {
+		return nil, err
+	}
+	return trivyOutput(imageName, report), nil
+}
+
+// Version returns an empty version, as each artifact may have been scanned by a different scanner of Harbor
+func (c *Client) Version() string {
+	return ""
+}
+
+// parseImageName splits the name of an image hosted by Harbor, such as harbor.example.com/payments/api/web:1.2, into its
+// project, its repository and the reference of its artifact, the digest or else the tag
+func parseImageName(imageName string) (project, repository, reference string, err error) {
+	_, path, _ := strings.Cut(imageName, "/")
+	name, digest, hasDigest := strings.Cut(path, "@")
+	reference = "latest"
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		name, reference = name[:tagIndex], name[tagIndex+1:]
+	}
+	if hasDigest {
+		reference = digest
+	}
+	project, repository, found := strings.Cut(name, "/")
+	if !found || project == "" || repository == "" {
+		return "", "", "", fmt.Errorf("invalid Harbor image %s, expecting <harbor host>/<project>/<repository>", imageName)
+	}
+	return project, repository, reference, nil
+}
+
+// trivyOutput converts the vulnerability report to the trivy output of an image scan, with a single result as Harbor
+// does not report the target of the vulnerabilities
+func trivyOutput(imageName string, report *VulnerabilityReport) *scanner.TrivyOutput {
+	result := scanner.TrivyOutputResults{Target: imageName}
+	for _, vulnerability := range report.Vulnerabilities {
+		converted := scanner.Vulnerabilities{
+			VulnerabilityID:  vulnerability.ID,
+			PkgName:          vulnerability.Package,
+			InstalledVersion: vulnerability.Version,
+			FixedVersion:     vulnerability.FixVersion,
+			Severity:         severity(vulnerability.Severity),
+			Description:      vulnerability.Description,
+			References:       vulnerability.Links,
+			CVSS:             vulnerability.VendorAttributes.CVSS,
+		}
+		if len(converted.CVSS) == 0 && vulnerability.PreferredCVSS != nil {
+			preferred := vulnerability.PreferredCVSS
+			converted.CVSS = map[string]scanner.CVSS{cvssSource: {
+				V2Vector: preferred.VectorV2,
+				V3Vector: preferred.VectorV3,
+				V2Score:  preferred.ScoreV2,
+				V3Score:  preferred.ScoreV3,
+			}}
+		}
+		result.Vulnerabilities = append(result.Vulnerabilities, converted)
+	}
+	output := &scanner.TrivyOutput{Results: []scanner.TrivyOutputResults{result}}
+	// the scan time is informative, the output being undated when it is invalid
+	output.CreatedAt, _ = time.Parse(time.RFC3339, report.GeneratedAt)
+	return output
+}
+
+// severity converts a Harbor severity, for instance High, to the trivy severity, Negligible being reported as LOW
+func severity(harborSeverity string) string {
+	switch strings.ToUpper(harborSeverity) {
+	case "CRITICAL", "HIGH", "MEDIUM", "LOW":
+		return strings.ToUpper(harborSeverity)
+	case "NEGLIGIBLE":
+		return "LOW"
+	}
+	return "UNKNOWN"
+}
@@ -0,0 +1,131 @@
+package harbor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHarbor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Harbor Suite")
+}
+
+const vulnerabilityReport = `{
+  "application/vnd.security.vulnerability.report; version=1.1": {
+    "generated_at": "2023-09-05T10:00:00Z",
+    "scanner": {"name": "Trivy", "vendor": "Aqua Security", "version": "v0.45.1"},
+    "severity": "Critical",
+    "vulnerabilities": [
+      {
+        "id": "CVE-2023-0001", "package": "openssl", "version": "3.0.9", "fix_version": "3.0.10", "severity": "Critical",
+        "links": ["https://avd.aquasec.com/nvd/cve-2023-0001"],
+        "preferred_cvss": {"score_v3": 9.8, "vector_v3": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+        "vendor_attributes": {"CVSS": {"nvd": {"V3Score": 9.8, "V3Vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}}}
+      },
+      {"id": "CVE-2023-0002", "package": "zlib", "version": "1.2.13", "severity": "Negligible", "preferred_cvss": {"score_v3": 3.1}}
+    ]
+  }
+}`
+
+var _ = Describe("Harbor vulnerability reports", func() {
+
+	var (
+		server   *httptest.Server
+		client   *Client
+		host     string
+		requests []*http.Request
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			switch {
+			case strings.Contains(r.URL.EscapedPath(), "/repositories/api%252Fweb/artifacts/"):
+				_, _ = w.Write([]byte(vulnerabilityReport))
+			case strings.Contains(r.URL.Path, "/repositories/pending/"):
+				_, _ = w.Write([]byte(`{}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		var err error
+		client, err = NewClient(server.URL, "robot$scanner", "secret")
+		Expect(err).NotTo(HaveOccurred())
+		host = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("converts the vulnerability report of the image artifact to a trivy output", func() {
+		output, err := client.ImageScan(host+"/payments/api/web:1.2", nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.EscapedPath()).To(Equal("/api/v2.0/projects/payments/repositories/api%252Fweb/artifacts/1.2/additions/vulnerabilities"))
+		Expect(requests[0].Header.Get("X-Accept-Vulnerabilities")).To(ContainSubstring("application/vnd.security.vulnerability.report; version=1.1"))
+		username, password, _ := requests[0].BasicAuth()
+		Expect(username).To(Equal("robot$scanner"))
+		Expect(password).To(Equal("secret"))
+
+		Expect(output.CreatedAt).To(Equal(time.Date(2023, 9, 5, 10, 0, 0, 0, time.UTC)))
+		Expect(output.Results).To(HaveLen(1))
+		Expect(output.Results[0].Target).To(Equal(host + "/payments/api/web:1.2"))
+		vulnerabilities := output.Results[0].Vulnerabilities
+		Expect(vulnerabilities).To(HaveLen(2))
+		Expect(vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2023-0001"))
+		Expect(vulnerabilities[0].PkgName).To(Equal("openssl"))
+		Expect(vulnerabilities[0].FixedVersion).To(Equal("3.0.10"))
+		Expect(vulnerabilities[0].Severity).To(Equal("CRITICAL"))
+		Expect(vulnerabilities[0].CVSS["nvd"].V3Score).To(Equal(9.8))
+		Expect(vulnerabilities[1].Severity).To(Equal("LOW"))
+		Expect(vulnerabilities[1].CVSS[cvssSource].V3Score).To(Equal(3.1))
+	})
+
+	It("references the artifact by the digest of the running containers", func() {
+		_, err := client.ImageScan(host+"/payments/api/web:1.2", []k8s.ContainerSummary{{ImageDigest: "sha256:4ff3ca91"}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests[0].URL.Path).To(HaveSuffix("/artifacts/sha256:4ff3ca91/additions/vulnerabilities"))
+	})
+
+	It("returns an error for the images Harbor does not host", func() {
+		_, err := client.ImageScan("docker.io/library/nginx:1.25", nil)
+
+		Expect(err).To(MatchError(ContainSubstring("not hosted by Harbor")))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("returns an error for the artifacts not found or not scanned yet", func() {
+		_, err := client.ImageScan(host+"/payments/unknown:1.0", nil)
+		Expect(err).To(MatchError(ContainSubstring("not found in Harbor")))
+
+		_, err = client.ImageScan(host+"/payments/pending:1.0", nil)
+		Expect(err).To(MatchError(ContainSubstring("not scanned by Harbor yet")))
+	})
+
+	It("parses the project, repository and reference of the image names", func() {
+		for imageName, expected := range map[string][]string{
+			"harbor.example.com/payments/web":                    {"payments", "web", "latest"},
+			"harbor.example.com/payments/api/web:1.2":            {"payments", "api/web", "1.2"},
+			"harbor.example.com:8443/payments/web@sha256:4ff3":   {"payments", "web", "sha256:4ff3"},
+			"harbor.example.com/payments/web:1.2@sha256:4ff3ca9": {"payments", "web", "sha256:4ff3ca9"},
+		} {
+			project, repository, reference, err := parseImageName(imageName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect([]string{project, repository, reference}).To(Equal(expected), imageName)
+		}
+		_, _, _, err := parseImageName("harbor.example.com/web:1.2")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,131 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client is a thin client for the Jira REST API
+type Client interface {
+	// FindIssue returns the key of the open issue with the given label in the project, or an empty string if there is none
+	FindIssue(project, label string) (string, error)
+	// CreateIssue creates the issue and returns its key
+	CreateIssue(issue *Issue) (string, error)
+	AddComment(issueKey, comment string) error
+}
+
+// Issue holds the fields of a Jira issue
+type Issue struct {
+	Project     string
+	IssueType   string
+	Summary     string
+	Description string
+	Labels      []string
+}
+
+type client struct {
+	baseURL    string
+	username   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new Client authenticating with the username and API token
+func NewClient(baseURL, username, token string) Client {
+	return &client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *client) FindIssue(project, label string) (string, error) {
+	jql := fmt.Sprintf("project = %q AND labels = %q AND statusCategory != Done", project, label)
+	var result struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	err := c.do(http.MethodGet, "/rest/api/2/search?fields=key&maxResults=1&jql="+url.QueryEscape(jql), nil, &result)
+	if err != nil {
+		return "", fmt.Errorf("error searching jira issues in project %s: %v", project, err)
+	}
+	if len(result.Issues) == 0 {
+		return "", nil
+	}
+	return result.Issues[0].Key, nil
+}
+
+func (c *client) CreateIssue(issue *Issue) (string, error) {
+	request := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": issue.Project},
+			"issuetype":   map[string]string{"name": issue.IssueType},
+			"summary":     issue.Summary,
+			"description": issue.Description,
+			"labels":      issue.Labels,
+		},
+	}
+	var result struct {
+		Key string `json:"key"`
+	}
+	err := c.do(http.MethodPost, "/rest/api/2/issue", request, &result)
+	if err != nil {
+		return "", fmt.Errorf("error creating jira issue in project %s: %v", issue.Project, err)
+	}
+	return result.Key, nil
+}
+
+func (c *client) AddComment(issueKey, comment string) error {
+	err := c.do(http.MethodPost, "/rest/api/2/issue/"+issueKey+"/comment", map[string]string{"body": comment}, nil)
+	if err != nil {
+		return fmt.Errorf("error commenting jira issue %s: %v", issueKey, err)
+	}
+	return nil
+}
+
+func (c *client) do(method, path string, requestBody, responseBody interface{}) error {
+	var body io.Reader
+	if requestBody != nil {
+		content, err := json.Marshal(requestBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.token)
+	req.Header.Set("Accept", "application/json")
+	if requestBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("jira returned status %d: %s", resp.StatusCode, string(content))
+	}
+	if responseBody != nil {
+		return json.Unmarshal(content, responseBody)
+	}
+	return nil
+}
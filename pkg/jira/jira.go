@@ -0,0 +1,153 @@
+package jira
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+const ticketLabel = "prod-readiness"
+
+// Config is the config used to create the Jira tickets
+type Config struct {
+	// DefaultProject is the project used for the teams without a project in TeamProjects
+	DefaultProject string
+	// TeamProjects maps a team name to its Jira project key
+	TeamProjects map[string]string
+	IssueType    string
+	// FixableOnly restricts the tickets to the new critical vulnerabilities with a fixed version
+	FixableOnly bool
+}
+
+// Ticketer opens or updates Jira tickets for new critical vulnerabilities
+type Ticketer struct {
+	config *Config
+	client Client
+}
+
+// New creates a Ticketer
+func New(client Client, config *Config) *Ticketer {
+	return &Ticketer{
+		config: config,
+		client: client,
+	}
+}
+
+// CreateTickets opens a ticket in the team project for each new critical vulnerability of the team images,
+// or comments the existing ticket when one is already open for the same image and vulnerability
+func (t *Ticketer) CreateTickets(report, baseline *scanner.VulnerabilityReport) error {
+	newCriticals := report.NewVulnerabilities(baseline, "CRITICAL")
+	if t.config.FixableOnly {
+		newCriticals = scanner.FixableFindings(newCriticals)
+	}
+	if len(newCriticals) == 0 {
+		logr.Info("No new critical vulnerabilities, no jira ticket to create")
+		return nil
+	}
+
+	var failures int
+	for _, area := range report.AreaSummary {
+		for _, team := range area.Teams {
+			project := t.projectFor(team.Name)
+			findings := team.Findings(newCriticals)
+			if project == "" {
+				if len(findings) > 0 {
+					logr.Warnf("No jira project configured for team %s, skipping %d new critical vulnerabilities", team.Name, len(findings))
+				}
+				continue
+			}
+			for _, finding := range findings {
+				if err := t.createOrUpdate(project, finding); err != nil {
+					logr.Error(err)
+					failures++
+				}
+			}
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d jira ticket(s) could not be created or updated", failures)
+	}
+	return nil
+}
+
+func (t *Ticketer) projectFor(team string) string {
+	if project, ok := t.config.TeamProjects[team]; ok {
+		return project
+	}
+	return t.config.DefaultProject
+}
+
+func (t *Ticketer) createOrUpdate(project string, finding scanner.VulnerabilityFinding) error {
+	label := findingLabel(finding)
+	issueKey, err := t.client.FindIssue(project, label)
+	if err != nil {
+		return err
+	}
+
+	if issueKey != "" {
+		logr.Infof("Updating jira issue %s for %s in %s", issueKey, finding.Vulnerability.VulnerabilityID, finding.ImageName)
+		return t.client.AddComment(issueKey, "Vulnerability still present in the latest scan.\n\n"+affectedWorkloads(finding))
+	}
+
+	issueType := t.config.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+	issueKey, err = t.client.CreateIssue(&Issue{
+		Project:     project,
+		IssueType:   issueType,
+		Summary:     fmt.Sprintf("%s (%s) in %s", finding.Vulnerability.VulnerabilityID, finding.Vulnerability.PkgName, finding.ImageName),
+		Description: description(finding),
+		Labels:      []string{ticketLabel, label},
+	})
+	if err != nil {
+		return err
+	}
+	logr.Infof("Created jira issue %s for %s in %s", issueKey, finding.Vulnerability.VulnerabilityID, finding.ImageName)
+	return nil
+}
+
+// findingLabel returns a stable label identifying the image and vulnerability, used to find existing tickets
+func findingLabel(finding scanner.VulnerabilityFinding) string {
+	hash := sha1.Sum([]byte(finding.ImageName + "|" + finding.Vulnerability.VulnerabilityID + "|" + finding.Vulnerability.PkgName))
+	return ticketLabel + "-" + hex.EncodeToString(hash[:])[:12]
+}
+
+func description(finding scanner.VulnerabilityFinding) string {
+	v := finding.Vulnerability
+	fixedVersion := v.FixedVersion
+	if fixedVersion == "" {
+		fixedVersion = "no fix available"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "A new critical vulnerability has been found by the production readiness image scan.\n\n")
+	fmt.Fprintf(&b, "*Image:* %s\n", finding.ImageName)
+	fmt.Fprintf(&b, "*Vulnerability:* [%s|https://nvd.nist.gov/vuln/detail/%s]\n", v.VulnerabilityID, v.VulnerabilityID)
+	fmt.Fprintf(&b, "*Package:* %s %s\n", v.PkgName, v.InstalledVersion)
+	fmt.Fprintf(&b, "*Fixed version:* %s\n", fixedVersion)
+	if v.Title != "" {
+		fmt.Fprintf(&b, "*Title:* %s\n", v.Title)
+	}
+	b.WriteString("\n")
+	b.WriteString(affectedWorkloads(finding))
+	return b.String()
+}
+
+func affectedWorkloads(finding scanner.VulnerabilityFinding) string {
+	var b strings.Builder
+	b.WriteString("*Affected workloads:*\n")
+	for _, c := range finding.Containers {
+		if c.Type != k8s.RegularContainer {
+			fmt.Fprintf(&b, "* %s/%s (%s container %s)\n", c.Namespace, c.PodName, c.Type, c.ContainerName)
+			continue
+		}
+		fmt.Fprintf(&b, "* %s/%s (container %s)\n", c.Namespace, c.PodName, c.ContainerName)
+	}
+	return b.String()
+}
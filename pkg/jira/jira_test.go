@@ -0,0 +1,116 @@
+package jira
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestJira(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Jira Suite")
+}
+
+var _ = Describe("Jira tickets", func() {
+
+	var (
+		mockClient *mockJiraClient
+		report     *scanner.VulnerabilityReport
+		image      scanner.ScannedImage
+	)
+
+	BeforeEach(func() {
+		mockClient = &mockJiraClient{}
+		image = scanner.NewScannedImage("image:1.0", []k8s.ContainerSummary{{Namespace: "ns1", PodName: "pod1", ContainerName: "app"}}, []scanner.TrivyOutputResults{
+			{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", InstalledVersion: "1.1.1", FixedVersion: "1.1.2", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"},
+			}},
+		}, nil)
+		report = &scanner.VulnerabilityReport{
+			ScannedImages: []scanner.ScannedImage{image},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"area1": {Name: "area1", Teams: map[string]*scanner.TeamSummary{
+					"team1": {Name: "team1", Images: []scanner.ScannedImage{image}},
+				}},
+			},
+		}
+	})
+
+	It("creates a ticket in the team project for each new critical vulnerability", func() {
+		ticketer := New(mockClient, &Config{DefaultProject: "SEC", TeamProjects: map[string]string{"team1": "TEAM1"}})
+		label := findingLabel(scanner.VulnerabilityFinding{ImageName: "image:1.0", Vulnerability: scanner.Vulnerabilities{VulnerabilityID: "CVE-1", PkgName: "openssl"}})
+		mockClient.On("FindIssue", "TEAM1", label).Return("", nil)
+		mockClient.On("CreateIssue", mock.MatchedBy(func(issue *Issue) bool {
+			return issue.Project == "TEAM1" &&
+				issue.IssueType == "Bug" &&
+				issue.Summary == "CVE-1 (openssl) in image:1.0" &&
+				strings.Contains(issue.Description, "*Fixed version:* 1.1.2") &&
+				strings.Contains(issue.Description, "ns1/pod1 (container app)") &&
+				reflect.DeepEqual(issue.Labels, []string{"prod-readiness", label})
+		})).Return("TEAM1-1", nil)
+
+		err := ticketer.CreateTickets(report, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+	})
+
+	It("comments the existing ticket of the vulnerability", func() {
+		ticketer := New(mockClient, &Config{DefaultProject: "SEC"})
+		mockClient.On("FindIssue", "SEC", mock.Anything).Return("SEC-12", nil)
+		mockClient.On("AddComment", "SEC-12", mock.Anything).Return(nil)
+
+		err := ticketer.CreateTickets(report, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertExpectations(GinkgoT())
+		mockClient.AssertNotCalled(GinkgoT(), "CreateIssue", mock.Anything)
+	})
+
+	It("ignores the vulnerabilities present in the baseline", func() {
+		ticketer := New(mockClient, &Config{DefaultProject: "SEC"})
+
+		err := ticketer.CreateTickets(report, &scanner.VulnerabilityReport{ScannedImages: []scanner.ScannedImage{image}})
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertNotCalled(GinkgoT(), "FindIssue", mock.Anything, mock.Anything)
+	})
+
+	It("skips the teams without a project", func() {
+		ticketer := New(mockClient, &Config{})
+
+		err := ticketer.CreateTickets(report, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		mockClient.AssertNotCalled(GinkgoT(), "FindIssue", mock.Anything, mock.Anything)
+	})
+})
+
+type mockJiraClient struct {
+	mock.Mock
+}
+
+var _ Client = &mockJiraClient{}
+
+func (c *mockJiraClient) FindIssue(project, label string) (string, error) {
+	args := c.Called(project, label)
+	return args.String(0), args.Error(1)
+}
+
+func (c *mockJiraClient) CreateIssue(issue *Issue) (string, error) {
+	args := c.Called(issue)
+	return args.String(0), args.Error(1)
+}
+
+func (c *mockJiraClient) AddComment(issueKey, comment string) error {
+	args := c.Called(issueKey, comment)
+	return args.Error(0)
+}
@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"context"
+
+	logr "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Exposure levels of the containers, from the most to the least reachable. The containers not selected by any
+// Service are not exposed, their exposure being empty
+const (
+	// ExposureInternet is the exposure of the containers selected by a LoadBalancer Service, a Service with external
+	// IPs or a Service backing an Ingress
+	ExposureInternet = "internet"
+	// ExposureNode is the exposure of the containers selected by a NodePort Service, reachable on the node addresses
+	ExposureNode = "node"
+	// ExposureCluster is the exposure of the containers only selected by ClusterIP Services
+	ExposureCluster = "cluster"
+)
+
+var exposureRanks = map[string]int{ExposureInternet: 3, ExposureNode: 2, ExposureCluster: 1}
+
+// ExposureRank orders the exposure levels, the higher the rank the more reachable the containers. It is 0 when the
+// containers are not exposed
+func ExposureRank(exposure string) int {
+	return exposureRanks[exposure]
+}
+
+// HighestExposure returns the highest exposure of the containers, empty when none is exposed
+func HighestExposure(containers []ContainerSummary) string {
+	exposure := ""
+	for _, container := range containers {
+		if ExposureRank(container.Exposure) > ExposureRank(exposure) {
+			exposure = container.Exposure
+		}
+	}
+	return exposure
+}
+
+// namespaceExposure holds the Services of a namespace and the names of the Services backing its Ingresses
+type namespaceExposure struct {
+	services        []v1.Service
+	ingressServices map[string]bool
+}
+
+// listNamespaceExposure lists the Services and Ingresses of the namespace. A kind that cannot be listed is logged and
+// ignored, the containers being reported without its exposure
+func (k *kubernetesClient) listNamespaceExposure(namespace string) namespaceExposure {
+	ctx := context.Background()
+	options := metaV1.ListOptions{}
+	exposure := namespaceExposure{ingressServices: make(map[string]bool)}
+	if list, err := k.clientset.CoreV1().Services(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list Service in namespace %s: %v", namespace, err)
+	} else {
+		exposure.services = list.Items
+	}
+	if list, err := k.clientset.NetworkingV1().Ingresses(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list Ingress in namespace %s: %v", namespace, err)
+	} else {
+		for _, ingress := range list.Items {
+			for _, name := range IngressServiceNames(ingress) {
+				exposure.ingressServices[name] = true
+			}
+		}
+	}
+	return exposure
+}
+
+// IngressServiceNames returns the names of the Services the default backend and the rules of the Ingress route to
+func IngressServiceNames(ingress networkingV1.Ingress) []string {
+	var names []string
+	addBackend := func(backend *networkingV1.IngressBackend) {
+		if backend != nil && backend.Service != nil {
+			names = append(names, backend.Service.Name)
+		}
+	}
+	addBackend(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			addBackend(&path.Backend)
+		}
+	}
+	return names
+}
+
+// exposureOf returns the highest exposure of the Services selecting the pod labels, empty when no Service selects them
+func (e namespaceExposure) exposureOf(podLabels map[string]string) string {
+	exposure := ""
+	for _, service := range e.services {
+		if len(service.Spec.Selector) == 0 || !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(podLabels)) {
+			continue
+		}
+		serviceExposure := ExposureCluster
+		switch {
+		case service.Spec.Type == v1.ServiceTypeLoadBalancer || len(service.Spec.ExternalIPs) > 0 || e.ingressServices[service.Name]:
+			serviceExposure = ExposureInternet
+		case service.Spec.Type == v1.ServiceTypeNodePort:
+			serviceExposure = ExposureNode
+		case service.Spec.Type == v1.ServiceTypeExternalName:
+			continue
+		}
+		if ExposureRank(serviceExposure) > ExposureRank(exposure) {
+			exposure = serviceExposure
+		}
+	}
+	return exposure
+}
@@ -2,7 +2,7 @@ package k8s
 
 import (
 	"os"
-	"path/filepath"
+	"time"
 
 	logr "github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
@@ -10,36 +10,73 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// KubernetesConfig returns k8s client config
-func KubernetesConfig(kubeContext string, kubeconfigPath string) *rest.Config {
-	var config *rest.Config
-	var err error
-
-	if kubeContext != "" {
-		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-			&clientcmd.ClientConfigLoadingRules{ExplicitPath: GetOrDefaultKubeConfigPath(kubeconfigPath)},
-			&clientcmd.ConfigOverrides{
-				CurrentContext: kubeContext,
-			}).ClientConfig()
-	} else {
-		config, err = rest.InClusterConfig()
+// ClientOptions tune the client side rate limiting and the timeout of the requests to the Kubernetes API, the
+// client-go defaults throttling the discovery of the containers of clusters with tens of thousands of pods
+type ClientOptions struct {
+	// QPS and Burst are the sustained and the burst rates of the requests, the client-go defaults of 5 and 10 being
+	// used when 0
+	QPS   float32
+	Burst int
+	// Timeout is the timeout of each request, the requests not timing out when 0
+	Timeout time.Duration
+}
+
+// clientOptions are the options of the configs returned by KubernetesConfig, see SetClientOptions
+var clientOptions ClientOptions
+
+// SetClientOptions sets the options of the Kubernetes clients created afterwards
+func SetClientOptions(options ClientOptions) {
+	clientOptions = options
+}
+
+// applyClientOptions sets the client options on the config, the options that are 0 leaving the config as is
+func applyClientOptions(config *rest.Config, options ClientOptions) {
+	if options.QPS > 0 {
+		config.QPS = options.QPS
 	}
+	if options.Burst > 0 {
+		config.Burst = options.Burst
+	}
+	if options.Timeout > 0 {
+		config.Timeout = options.Timeout
+	}
+}
+
+// KubernetesConfig returns k8s client config. The kubeconfig is resolved as kubectl does: kubeconfigPath, or else the
+// files of the KUBECONFIG environment variable merged, or else ~/.kube/config, the current context being used when
+// kubeContext is empty. The in-cluster config is used when running inside a cluster without kubeContext nor kubeconfigPath.
+// The options set with SetClientOptions are applied to the config
+func KubernetesConfig(kubeContext string, kubeconfigPath string) *rest.Config {
+	config, err := loadKubernetesConfig(kubeContext, kubeconfigPath)
 	if err != nil {
 		logr.Fatalf("Unable to obtain kube config: %v", err)
 	}
+	applyClientOptions(config, clientOptions)
 	return config
 }
 
-// GetOrDefaultKubeConfigPath returns kubeconifg path
-func GetOrDefaultKubeConfigPath(path string) string {
-	kubeconfigPath := path
-	if kubeconfigPath == "" {
-		kubeconfigPath = os.Getenv("KUBECONFIG")
-		if kubeconfigPath == "" {
-			kubeconfigPath = filepath.Join(os.Getenv("HOME"), ".kube", "config")
-		}
+// ValidateKubernetesConfig returns the error loading the kube config of the context, nil when it can be loaded
+func ValidateKubernetesConfig(kubeContext string, kubeconfigPath string) error {
+	_, err := loadKubernetesConfig(kubeContext, kubeconfigPath)
+	return err
+}
+
+func loadKubernetesConfig(kubeContext string, kubeconfigPath string) (*rest.Config, error) {
+	if kubeContext == "" && kubeconfigPath == "" && inCluster() {
+		return rest.InClusterConfig()
 	}
-	return kubeconfigPath
+	return clientConfig(kubeContext, kubeconfigPath).ClientConfig()
+}
+
+func clientConfig(kubeContext string, kubeconfigPath string) clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+}
+
+// inCluster returns true when running in a pod, the service account of the pod being used to access the cluster
+func inCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
 }
 
 // KubernetesClientset returns kubernetes clientset
@@ -50,3 +87,23 @@ func KubernetesClientset(config *rest.Config) *kubernetes.Clientset {
 	}
 	return clientset
 }
+
+// ClusterName returns the cluster name of the kubeconfig context, the current context when kubeContext is empty, or the
+// context name when the cluster cannot be looked up. It is empty when running inside a cluster as the cluster name is not known
+func ClusterName(kubeContext string, kubeconfigPath string) string {
+	if kubeContext == "" && kubeconfigPath == "" && inCluster() {
+		return ""
+	}
+	config, err := clientConfig(kubeContext, kubeconfigPath).RawConfig()
+	if err != nil {
+		logr.Warnf("Unable to load kube config to find the cluster name: %v", err)
+		return kubeContext
+	}
+	if kubeContext == "" {
+		kubeContext = config.CurrentContext
+	}
+	if context, ok := config.Contexts[kubeContext]; ok && context.Cluster != "" {
+		return context.Cluster
+	}
+	return kubeContext
+}
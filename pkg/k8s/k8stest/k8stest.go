@@ -0,0 +1,115 @@
+// Package k8stest provides a mock of k8s.KubernetesClient to unit test the code using the cluster without a cluster
+package k8stest
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/stretchr/testify/mock"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+// KubernetesClient is a testify mock of k8s.KubernetesClient, the expectations being set on the method names with
+// the arguments other than the context and the callbacks
+type KubernetesClient struct {
+	mock.Mock
+}
+
+// force implementation of k8s.KubernetesClient at compilation time
+var _ k8s.KubernetesClient = &KubernetesClient{}
+
+func (k *KubernetesClient) GetContainersInNamespaces(labelSelector string) ([]k8s.ContainerSummary, error) {
+	args := k.Called(labelSelector)
+	return args.Get(0).([]k8s.ContainerSummary), args.Error(1)
+}
+
+func (k *KubernetesClient) GetWorkloadsInNamespaces(labelSelector string) ([]k8s.Workload, error) {
+	args := k.Called(labelSelector)
+	return args.Get(0).([]k8s.Workload), args.Error(1)
+}
+
+func (k *KubernetesClient) GetNetworkPolicies(namespace string) ([]networkingv1.NetworkPolicy, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]networkingv1.NetworkPolicy), args.Error(1)
+}
+
+func (k *KubernetesClient) GetPodDisruptionBudgets(namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]policyv1.PodDisruptionBudget), args.Error(1)
+}
+
+func (k *KubernetesClient) GetServices(namespace string) ([]v1.Service, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]v1.Service), args.Error(1)
+}
+
+func (k *KubernetesClient) GetIngresses(namespace string) ([]networkingv1.Ingress, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]networkingv1.Ingress), args.Error(1)
+}
+
+func (k *KubernetesClient) GetHorizontalPodAutoscalers(namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]autoscalingv2.HorizontalPodAutoscaler), args.Error(1)
+}
+
+func (k *KubernetesClient) GetServerVersion() (string, error) {
+	args := k.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (k *KubernetesClient) GetResourceAPIVersions(namespace string) ([]k8s.ResourceAPIVersions, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]k8s.ResourceAPIVersions), args.Error(1)
+}
+
+func (k *KubernetesClient) GetNodes() ([]v1.Node, error) {
+	args := k.Called()
+	return args.Get(0).([]v1.Node), args.Error(1)
+}
+
+func (k *KubernetesClient) GetNode(name string) (*v1.Node, error) {
+	args := k.Called(name)
+	return args.Get(0).(*v1.Node), args.Error(1)
+}
+
+func (k *KubernetesClient) GetServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]v1.ServiceAccount), args.Error(1)
+}
+
+func (k *KubernetesClient) GetRoleBindings(namespace string) ([]k8s.RoleBinding, error) {
+	args := k.Called(namespace)
+	return args.Get(0).([]k8s.RoleBinding), args.Error(1)
+}
+
+func (k *KubernetesClient) IsAllowed(permission k8s.Permission) (bool, error) {
+	args := k.Called(permission)
+	return args.Bool(0), args.Error(1)
+}
+
+func (k *KubernetesClient) RunJob(job *batchv1.Job, timeout time.Duration) ([]byte, error) {
+	args := k.Called(job, timeout)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+// WatchContainers calls onContainers with the containers of each returned event, a [][]k8s.ContainerSummary, and
+// returns once the context is done, or immediately with the returned error when it is set
+func (k *KubernetesClient) WatchContainers(ctx context.Context, labelSelector string, onContainers func([]k8s.ContainerSummary)) error {
+	args := k.Called(labelSelector)
+	if args.Error(1) != nil {
+		return args.Error(1)
+	}
+	if events, ok := args.Get(0).([][]k8s.ContainerSummary); ok {
+		for _, containers := range events {
+			onContainers(containers)
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
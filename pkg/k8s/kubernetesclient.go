@@ -2,47 +2,243 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/gammazero/workerpool"
 	logr "github.com/sirupsen/logrus"
+	appsV1 "k8s.io/api/apps/v1"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	batchV1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	policyV1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 // KubernetesClient is a thin client to access the Kubernetes cluster
 type KubernetesClient interface {
 	// GetContainersInNamespaces returns the containers for all the pods in the namespaces that match the labelSelector
 	GetContainersInNamespaces(labelSelector string) ([]ContainerSummary, error)
+	// GetWorkloadsInNamespaces returns the workloads running in the namespaces that match the labelSelector
+	GetWorkloadsInNamespaces(labelSelector string) ([]Workload, error)
+	// GetNetworkPolicies returns the network policies of the namespace
+	GetNetworkPolicies(namespace string) ([]networkingV1.NetworkPolicy, error)
+	// GetPodDisruptionBudgets returns the pod disruption budgets of the namespace
+	GetPodDisruptionBudgets(namespace string) ([]policyV1.PodDisruptionBudget, error)
+	// GetServices returns the services of the namespace
+	GetServices(namespace string) ([]v1.Service, error)
+	// GetIngresses returns the ingresses of the namespace
+	GetIngresses(namespace string) ([]networkingV1.Ingress, error)
+	// GetHorizontalPodAutoscalers returns the horizontal pod autoscalers of the namespace
+	GetHorizontalPodAutoscalers(namespace string) ([]autoscalingV2.HorizontalPodAutoscaler, error)
+	// GetServerVersion returns the Kubernetes version of the cluster, for instance v1.25.3
+	GetServerVersion() (string, error)
+	// GetResourceAPIVersions returns the API versions used to manage the resources of the namespace
+	GetResourceAPIVersions(namespace string) ([]ResourceAPIVersions, error)
+	// GetNodes returns the nodes of the cluster
+	GetNodes() ([]v1.Node, error)
+	// GetNode returns the node of the cluster of that name
+	GetNode(name string) (*v1.Node, error)
+	// GetServiceAccounts returns the service accounts of the namespace
+	GetServiceAccounts(namespace string) ([]v1.ServiceAccount, error)
+	// GetRoleBindings returns the role bindings of the namespace and the cluster role bindings, with the rules of
+	// the roles they bind
+	GetRoleBindings(namespace string) ([]RoleBinding, error)
+	// IsAllowed returns true when the user of the client is allowed the permission in all the namespaces
+	IsAllowed(permission Permission) (bool, error)
+	// RunJob creates the job, waits for its completion and returns the logs of its pod. The job is deleted once finished
+	RunJob(job *batchV1.Job, timeout time.Duration) ([]byte, error)
+	// WatchContainers calls onContainers with the containers of the pods running, created or updated in the namespaces
+	// that match the labelSelector, until the context is done
+	WatchContainers(ctx context.Context, labelSelector string, onContainers func([]ContainerSummary)) error
 }
 
 // ContainerSummary holds details of the docker container
 type ContainerSummary struct {
-	Image           string
-	ContainerName   string
+	Image         string
+	ContainerName string
+	// PodName is the name of the pod, or the kind and name of the workload for the containers of a workload
+	// without pod, for instance cronjob/backup
 	PodName         string
 	Namespace       string
 	NamespaceLabels map[string]string
+	// PodLabels are the labels of the pod, or of the pod template for the containers of a workload without pod
+	PodLabels map[string]string
+	// Workload is the kind and name of the workload running the container, for instance deployment/web,
+	// the ownerReferences of the pod being walked up to its Deployment or CronJob. It is pod/<name> for the pods without controller
+	Workload string
+	// ImageDigest is the digest of the image run by the container, for instance sha256:4ff3ca91..., empty when unknown
+	ImageDigest string
+	Type        ContainerType
+	// NodeName is the node the pod of the container is scheduled on, empty for the pods not scheduled yet and the
+	// containers of a workload without pod
+	NodeName string `json:",omitempty"`
+	// Exposure is the exposure level of the container through the Services and Ingresses selecting its pod, for
+	// instance ExposureInternet, empty when not exposed or when the Services cannot be listed
+	Exposure string `json:",omitempty"`
+	// SkipScanReason is the reason the pod or the namespace of the container opted out of the image scans with the
+	// SkipScanAnnotation, empty when the container is scanned
+	SkipScanReason string `json:",omitempty"`
+	// ChangedAt is the time the pod of the container was created or its spec last updated, for instance by an
+	// ephemeral container or an image change, nil for the containers of a workload without pod
+	ChangedAt *time.Time `json:",omitempty"`
+	// Annotations are the context annotations of the pod, of its workload or else of its namespace, for instance
+	// data-classification or tier, so that the findings can be prioritised by business criticality. Only the
+	// annotations of DiscoveryOptions.ContextAnnotations are recorded
+	Annotations map[string]string `json:",omitempty"`
+	// Criticality is the CriticalityAnnotation of the pod, of its workload or else of its namespace, empty when none
+	// declares it. It is only read to prioritise the scans and is not reported
+	Criticality string `json:"-"`
+	// Suppressions are the findings suppressed in the namespace of the container, see SuppressAnnotation
+	Suppressions []Suppression `json:",omitempty"`
+}
+
+// SkipScanAnnotation opts a pod, or all the pods of a namespace, out of the image scans. Its value is the reason of
+// the opt-out, for instance "vendor appliance scanned by the vendor", listed in the report so that opt-outs remain visible
+const SkipScanAnnotation = "prod-readiness/skip-scan"
+
+// CriticalityAnnotation declares the business criticality of a pod, of its workload or of its namespace, one of
+// critical, high, medium or low, see ContainerSummary.Criticality
+const CriticalityAnnotation = "prod-readiness/criticality"
+
+// criticality returns the CriticalityAnnotation of the first annotations declaring it, empty when none does
+func criticality(annotations ...map[string]string) string {
+	for _, a := range annotations {
+		if value, ok := a[CriticalityAnnotation]; ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// SkipScanReason returns the reason of the scan opt-out of the first annotations having the SkipScanAnnotation,
+// for instance the pod annotations then the namespace annotations, empty when none opts out of the scans
+func SkipScanReason(annotations ...map[string]string) string {
+	for _, a := range annotations {
+		if reason, ok := a[SkipScanAnnotation]; ok {
+			if reason = strings.TrimSpace(reason); reason == "" {
+				return "no reason given"
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+// ContainerType distinguishes the init and ephemeral containers from the regular containers of a pod
+type ContainerType string
+
+const (
+	// RegularContainer is the type of the containers of the pod spec containers
+	RegularContainer ContainerType = ""
+	// InitContainer is the type of the containers run before the regular containers start
+	InitContainer ContainerType = "init"
+	// EphemeralContainer is the type of the containers added to a running pod, for instance with kubectl debug
+	EphemeralContainer ContainerType = "ephemeral"
+)
+
+// Workload is a set of pods managed by the same controller, or a single pod without controller
+type Workload struct {
+	Kind            string
+	Name            string
+	Namespace       string
+	NamespaceLabels map[string]string
+	PodLabels       map[string]string
+	// PodAnnotations are the annotations of the pods, or of the pod template of the workloads of manifests
+	PodAnnotations map[string]string
+	PodSpec        v1.PodSpec
+	// PodCount is the number of pods of the workload, the replicas of the workloads of manifests
+	PodCount int
+	// NodeNames are the nodes the scheduled pods of the workload run on, one per pod, empty for the workloads of
+	// manifests
+	NodeNames []string `json:",omitempty"`
+	// ImageDigests are the distinct digests of the images the pods run, by container name, several digests being
+	// running during a rollout. Empty for the workloads of manifests
+	ImageDigests map[string][]string `json:",omitempty"`
+	// Suppressions are the findings suppressed in the namespace of the workload, see SuppressAnnotation
+	Suppressions []Suppression `json:",omitempty"`
+}
+
+// ResourceAPIVersions holds the API versions a resource was applied or updated with
+type ResourceAPIVersions struct {
+	Kind        string
+	Name        string
+	Namespace   string
+	APIVersions []string
+}
+
+// DefaultDiscoveryWorkers is the number of namespaces whose containers are discovered concurrently by default
+const DefaultDiscoveryWorkers = 10
+
+// DiscoveryOptions tune the discovery of the containers of clusters with thousands of namespaces
+type DiscoveryOptions struct {
+	// Workers is the number of namespaces whose containers are discovered concurrently, DefaultDiscoveryWorkers when 0
+	Workers int
+	// PageSize is the maximum number of namespaces or pods returned by each list request, the lists being paginated
+	// with continue tokens. The lists are not paginated when 0
+	PageSize int64
+	// ContextAnnotations are the annotations recorded on the containers, see ContainerSummary.Annotations
+	ContextAnnotations []string
+	// NodeName restricts the discovery to the pods scheduled on the node, listed with a spec.nodeName field selector,
+	// the workloads without pods being skipped. All the pods are discovered when empty
+	NodeName string
+}
+
+// contextAnnotations adds the context annotations of the sources the annotations do not have yet, the first source
+// having an annotation taking precedence. It returns the annotations as is when no context annotation is configured
+func contextAnnotations(annotations map[string]string, keys []string, sources ...map[string]string) map[string]string {
+	for _, key := range keys {
+		if _, ok := annotations[key]; ok {
+			continue
+		}
+		for _, source := range sources {
+			if value, ok := source[key]; ok {
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				annotations[key] = value
+				break
+			}
+		}
+	}
+	return annotations
 }
 
 type kubernetesClient struct {
 	config    *rest.Config
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
+	discovery DiscoveryOptions
 }
 
 // NewKubernetesClient creates a new KubernetesClient
 func NewKubernetesClient(kubeContext, kubeconfigPath string) KubernetesClient {
+	return NewKubernetesClientWithDiscovery(kubeContext, kubeconfigPath, DiscoveryOptions{})
+}
+
+// NewKubernetesClientWithDiscovery creates a new KubernetesClient discovering the containers with the discovery options
+func NewKubernetesClientWithDiscovery(kubeContext, kubeconfigPath string, discovery DiscoveryOptions) KubernetesClient {
 	config := KubernetesConfig(kubeContext, kubeconfigPath)
 	clientset := KubernetesClientset(config)
 	return &kubernetesClient{
 		config:    config,
 		clientset: clientset,
+		discovery: discovery,
 	}
 }
 
 // NewKubernetesClientWith creates a new KubernetesClient using the provided clientset
-func NewKubernetesClientWith(clientset *kubernetes.Clientset) KubernetesClient {
+func NewKubernetesClientWith(clientset kubernetes.Interface) KubernetesClient {
 	return &kubernetesClient{
 		clientset: clientset,
 	}
@@ -54,51 +250,804 @@ func (k *kubernetesClient) GetContainersInNamespaces(labelSelector string) ([]Co
 		return nil, fmt.Errorf("unable to list namespaces: %v", err)
 	}
 
-	// get all pods running for now
-	// then we could get all the deployment and statefulset, job, cronjob, to gather all the images which are not running during the scan
-	// pod manifest should be available in the kube-system namespace
+	// the static pods manifests should be available in the kube-system namespace
 	return k.getAllPodContainersInNamespaces(namespaceList)
 }
 
+// getAllPodContainersInNamespaces discovers the containers of the namespaces with a bounded pool of workers, the
+// containers being returned in the order of the namespaces. The progress of the discovery is logged
 func (k *kubernetesClient) getAllPodContainersInNamespaces(namespaceList *v1.NamespaceList) ([]ContainerSummary, error) {
+	workers := k.discovery.Workers
+	if workers <= 0 {
+		workers = DefaultDiscoveryWorkers
+	}
+	namespaces := namespaceList.Items
+	total := len(namespaces)
+	logr.Infof("Discovering the containers of %d namespaces with %d workers", total, workers)
+	progressInterval := total / 10
+	if progressInterval == 0 {
+		progressInterval = 1
+	}
+
+	containersByNamespace := make([][]ContainerSummary, total)
+	var (
+		// guards the progress and the first error of the workers
+		lock     sync.Mutex
+		done     int
+		firstErr error
+	)
+	wp := workerpool.New(workers)
+	for i := range namespaces {
+		index := i
+		wp.Submit(func() {
+			containers, err := k.getNamespaceContainers(namespaces[index])
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			containersByNamespace[index] = containers
+			done++
+			if done%progressInterval == 0 || done == total {
+				logr.Infof("Discovered the containers of %d/%d namespaces", done, total)
+			}
+		})
+	}
+	wp.StopWait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
 	var containers []ContainerSummary
-	for _, namespace := range namespaceList.Items {
-		logr.Infof("Getting pods from namespace %s", namespace.Name)
-		podList, err := k.clientset.CoreV1().Pods(namespace.Name).List(context.Background(), metaV1.ListOptions{})
+	for _, namespaceContainers := range containersByNamespace {
+		containers = append(containers, namespaceContainers...)
+	}
+	return containers, nil
+}
+
+// getNamespaceContainers lists the containers of the pods of the namespace, and of its workloads without pods
+func (k *kubernetesClient) getNamespaceContainers(namespace v1.Namespace) ([]ContainerSummary, error) {
+	logr.Debugf("Getting pods from namespace %s", namespace.Name)
+	pods, err := k.listPods(namespace.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find pods in namespace %s %v", namespace.Name, err)
+	}
+
+	if len(pods) == 0 && k.discovery.NodeName == "" {
+		logr.Warnf("no pods found in namespace: %s", namespace.Name)
+		// continue as some namespaces may have scaled down deployments
+	}
+
+	var containers []ContainerSummary
+	controllers := k.listWorkloadControllers(namespace.Name)
+	exposure := k.listNamespaceExposure(namespace.Name)
+	suppressions := NamespaceSuppressions(namespace.Name, namespace.Annotations)
+	for _, pod := range pods {
+		logr.Debugf("pod %s in namespace %s", pod.Name, pod.Namespace)
+		workload := controllers.workloadOf(pod)
+		podExposure := exposure.exposureOf(pod.Labels)
+		skipScanReason := SkipScanReason(pod.Annotations, namespace.Annotations)
+		for _, container := range podContainers(pod) {
+			container.NamespaceLabels = namespace.Labels
+			container.PodLabels = pod.Labels
+			container.Workload = workload
+			container.Exposure = podExposure
+			container.SkipScanReason = skipScanReason
+			container.Annotations = contextAnnotations(nil, k.discovery.ContextAnnotations, pod.Annotations, controllers.annotationsOf(workload), namespace.Annotations)
+			container.Criticality = criticality(pod.Annotations, controllers.annotationsOf(workload), namespace.Annotations)
+			container.Suppressions = suppressions
+			containers = append(containers, container)
+		}
+	}
+
+	// the workloads without pods, such as cron jobs between two runs or scaled down deployments,
+	// are scanned from their pod template so that their images are reported as well. They run on no node
+	if k.discovery.NodeName != "" {
+		return containers, nil
+	}
+	for _, container := range controllers.containersWithoutPods(pods, k.discovery.ContextAnnotations) {
+		container.NamespaceLabels = namespace.Labels
+		container.Exposure = exposure.exposureOf(container.PodLabels)
+		if container.SkipScanReason == "" {
+			container.SkipScanReason = SkipScanReason(namespace.Annotations)
+		}
+		container.Annotations = contextAnnotations(container.Annotations, k.discovery.ContextAnnotations, namespace.Annotations)
+		if container.Criticality == "" {
+			container.Criticality = criticality(namespace.Annotations)
+		}
+		container.Suppressions = suppressions
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// listPods lists the pods of the namespace, one page of the discovery page size at a time, only the pods of the
+// discovery node when set
+func (k *kubernetesClient) listPods(namespace string) ([]v1.Pod, error) {
+	var pods []v1.Pod
+	options := metaV1.ListOptions{Limit: k.discovery.PageSize}
+	if k.discovery.NodeName != "" {
+		options.FieldSelector = "spec.nodeName=" + k.discovery.NodeName
+	}
+	for {
+		podList, err := k.clientset.CoreV1().Pods(namespace).List(context.Background(), options)
 		if err != nil {
-			return nil, fmt.Errorf("unable to find pods in namespace %s %v", namespace.Namespace, err)
+			return nil, err
+		}
+		pods = append(pods, podList.Items...)
+		if podList.Continue == "" {
+			return pods, nil
+		}
+		options.Continue = podList.Continue
+	}
+}
+
+// WatchContainers watches the pods of all the namespaces, the pods of the namespaces not matching the labelSelector
+// being ignored. As the controllers are not listed for each pod, the workload of the pods is only known from their
+// owner references, for instance deployment/web for the pods of the ReplicaSet web-5d8f. The controllers of a namespace
+// are only listed for the context annotations and the criticality of the workloads
+func (k *kubernetesClient) WatchContainers(ctx context.Context, labelSelector string, onContainers func([]ContainerSummary)) error {
+	namespaces := &watchedNamespaces{client: k, labelSelector: labelSelector, matching: make(map[string]v1.Namespace), listed: make(map[string]bool)}
+	workloads := &watchedWorkloads{client: k, controllers: make(map[string]workloadControllers)}
+	onPod := func(object interface{}) {
+		pod, ok := object.(*v1.Pod)
+		if !ok {
+			return
+		}
+		namespace, ok := namespaces.get(pod.Namespace)
+		if !ok {
+			return
 		}
+		workload := workloadControllers{}.workloadOf(*pod)
+		workloadAnnotations := workloads.annotationsOf(pod.Namespace, workload)
+		var containers []ContainerSummary
+		for _, container := range podContainers(*pod) {
+			container.NamespaceLabels = namespace.Labels
+			container.PodLabels = pod.Labels
+			container.Workload = workload
+			container.SkipScanReason = SkipScanReason(pod.Annotations, namespace.Annotations)
+			container.Annotations = contextAnnotations(nil, k.discovery.ContextAnnotations, pod.Annotations, workloadAnnotations, namespace.Annotations)
+			container.Criticality = criticality(pod.Annotations, workloadAnnotations, namespace.Annotations)
+			container.Suppressions = NamespaceSuppressions(namespace.Name, namespace.Annotations)
+			containers = append(containers, container)
+		}
+		onContainers(containers)
+	}
+
+	factory := informers.NewSharedInformerFactory(k.clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    onPod,
+		UpdateFunc: func(_, object interface{}) { onPod(object) },
+	}); err != nil {
+		return fmt.Errorf("unable to watch pods: %v", err)
+	}
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+		return ctx.Err()
+	}
+	logr.Infof("Watching the pods of the namespaces matching %q", labelSelector)
+	<-ctx.Done()
+	return nil
+}
+
+// watchedNamespaces caches the namespaces matching the label selector of a watch. The namespaces are listed again
+// when a pod of an unknown namespace is seen, so that the namespaces created during the watch are watched too.
+// The label changes of the namespaces already seen are ignored until the next full scan
+type watchedNamespaces struct {
+	client        *kubernetesClient
+	labelSelector string
+	lock          sync.Mutex
+	matching      map[string]v1.Namespace
+	listed        map[string]bool
+}
+
+func (w *watchedNamespaces) get(name string) (v1.Namespace, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if namespace, ok := w.matching[name]; ok || w.listed[name] {
+		return namespace, ok
+	}
+	namespaceList, err := w.client.getNamespaces(w.labelSelector)
+	if err != nil {
+		logr.Warnf("Unable to list the namespaces of the watched pods: %v", err)
+		return v1.Namespace{}, false
+	}
+	for _, namespace := range namespaceList.Items {
+		w.matching[namespace.Name] = namespace
+	}
+	// the pods of the namespaces not matching the selector are ignored without listing the namespaces again
+	w.listed[name] = true
+	namespace, ok := w.matching[name]
+	return namespace, ok
+}
+
+// watchedWorkloads caches the controllers of the namespaces of a watch, for the annotations of the workloads of the
+// pods. The controllers of a namespace are listed again when a pod of an unknown workload is seen, so that the workloads
+// created during the watch are known too. The annotation changes of the workloads already seen are ignored until the
+// next full scan
+type watchedWorkloads struct {
+	client      *kubernetesClient
+	lock        sync.Mutex
+	controllers map[string]workloadControllers
+}
+
+// annotationsOf returns the annotations of the workload of the namespace, for instance deployment/web, nil when the
+// workload is a bare pod or is unknown
+func (w *watchedWorkloads) annotationsOf(namespace, workload string) map[string]string {
+	if strings.HasPrefix(workload, "pod/") {
+		return nil
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	controllers, listed := w.controllers[namespace]
+	annotations, found := controllers.lookupAnnotations(workload)
+	if !listed || !found {
+		controllers = w.client.listWorkloadControllers(namespace)
+		w.controllers[namespace] = controllers
+		annotations, _ = controllers.lookupAnnotations(workload)
+	}
+	return annotations
+}
+
+// listWorkloadControllers lists the replica sets, deployments, stateful sets, daemon sets, cron jobs and jobs of the
+// namespace.
+// A workload kind that cannot be listed is logged and ignored
+func (k *kubernetesClient) listWorkloadControllers(namespace string) workloadControllers {
+	ctx := context.Background()
+	options := metaV1.ListOptions{}
+	var controllers workloadControllers
+	if list, err := k.clientset.AppsV1().ReplicaSets(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list ReplicaSet in namespace %s: %v", namespace, err)
+	} else {
+		controllers.replicaSets = list.Items
+	}
+	if list, err := k.clientset.AppsV1().Deployments(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list Deployment in namespace %s: %v", namespace, err)
+	} else {
+		controllers.deployments = list.Items
+	}
+	if list, err := k.clientset.AppsV1().StatefulSets(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list StatefulSet in namespace %s: %v", namespace, err)
+	} else {
+		controllers.statefulSets = list.Items
+	}
+	if list, err := k.clientset.AppsV1().DaemonSets(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list DaemonSet in namespace %s: %v", namespace, err)
+	} else {
+		controllers.daemonSets = list.Items
+	}
+	if list, err := k.clientset.BatchV1().CronJobs(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list CronJob in namespace %s: %v", namespace, err)
+	} else {
+		controllers.cronJobs = list.Items
+	}
+	if list, err := k.clientset.BatchV1().Jobs(namespace).List(ctx, options); err != nil {
+		logr.Warnf("Unable to list Job in namespace %s: %v", namespace, err)
+	} else {
+		controllers.jobs = list.Items
+	}
+	return controllers
+}
 
-		if len(podList.Items) == 0 {
-			logr.Warnf("no pods found in namespace: %s %v", namespace.Name, err)
-			// continue as some namespaces may have scaled down deployments
+// workloadControllers holds the controllers of a namespace the pods are attributed to, and which may have no pod
+// when the scan runs
+type workloadControllers struct {
+	replicaSets  []appsV1.ReplicaSet
+	deployments  []appsV1.Deployment
+	statefulSets []appsV1.StatefulSet
+	daemonSets   []appsV1.DaemonSet
+	cronJobs     []batchV1.CronJob
+	jobs         []batchV1.Job
+}
+
+// workloadOf returns the kind and name of the workload of the pod, for instance deployment/web. The ownerReferences
+// of the pod ReplicaSet or Job are walked up to their Deployment or CronJob
+func (w workloadControllers) workloadOf(pod v1.Pod) string {
+	owners := make(map[string]*metaV1.OwnerReference)
+	for i := range w.replicaSets {
+		owners["ReplicaSet/"+w.replicaSets[i].Name] = metaV1.GetControllerOf(&w.replicaSets[i])
+	}
+	for i := range w.jobs {
+		owners["Job/"+w.jobs[i].Name] = metaV1.GetControllerOf(&w.jobs[i])
+	}
+
+	kind, name := "Pod", pod.Name
+	if owner := metaV1.GetControllerOf(&pod); owner != nil {
+		kind, name = owner.Kind, owner.Name
+	}
+	for depth := 0; depth < 2; depth++ {
+		owner := owners[kind+"/"+name]
+		if owner == nil {
+			break
 		}
+		kind, name = owner.Kind, owner.Name
+	}
+	if kind == "ReplicaSet" {
+		// the ReplicaSet could not be listed, the pod-template-hash label tells its Deployment
+		kind, name = podController(pod)
+	}
+	return strings.ToLower(kind) + "/" + name
+}
+
+// annotationsOf returns the annotations of the workload, for instance deployment/web, nil when the workload is unknown
+func (w workloadControllers) annotationsOf(workload string) map[string]string {
+	annotations, _ := w.lookupAnnotations(workload)
+	return annotations
+}
 
-		for _, pod := range podList.Items {
-			logr.Infof("pod %s in namespace %s", pod.Name, pod.Namespace)
-			for _, container := range pod.Spec.Containers {
-				containers = append(containers, ContainerSummary{
-					Namespace:       pod.Namespace,
-					NamespaceLabels: namespace.Labels,
-					PodName:         pod.Name,
-					ContainerName:   container.Name,
-					Image:           container.Image,
-				})
+// lookupAnnotations returns the annotations of the workload, false when the workload is unknown
+func (w workloadControllers) lookupAnnotations(workload string) (map[string]string, bool) {
+	kind, name, _ := strings.Cut(workload, "/")
+	switch kind {
+	case "deployment":
+		for _, deployment := range w.deployments {
+			if deployment.Name == name {
+				return deployment.Annotations, true
+			}
+		}
+	case "statefulset":
+		for _, statefulSet := range w.statefulSets {
+			if statefulSet.Name == name {
+				return statefulSet.Annotations, true
+			}
+		}
+	case "daemonset":
+		for _, daemonSet := range w.daemonSets {
+			if daemonSet.Name == name {
+				return daemonSet.Annotations, true
+			}
+		}
+	case "cronjob":
+		for _, cronJob := range w.cronJobs {
+			if cronJob.Name == name {
+				return cronJob.Annotations, true
+			}
+		}
+	case "job":
+		for _, job := range w.jobs {
+			if job.Name == name {
+				return job.Annotations, true
 			}
 		}
 	}
-	return containers, nil
+	return nil, false
+}
+
+// containersWithoutPods returns the template containers of the workloads none of the pods belongs to.
+// The jobs created by a cron job are covered by the cron job template, and a pod of any of these jobs
+// counts as a pod of the cron job
+func (w workloadControllers) containersWithoutPods(pods []v1.Pod, annotationKeys []string) []ContainerSummary {
+	cronJobOfJob := make(map[string]string)
+	for _, job := range w.jobs {
+		if owner := metaV1.GetControllerOf(&job); owner != nil && owner.Kind == "CronJob" {
+			cronJobOfJob[job.Name] = owner.Name
+		}
+	}
+	withPods := make(map[string]bool)
+	for _, pod := range pods {
+		kind, name := podController(pod)
+		withPods[kind+"/"+name] = true
+		if cronJob, ok := cronJobOfJob[name]; ok && kind == "Job" {
+			withPods["CronJob/"+cronJob] = true
+		}
+	}
+
+	var containers []ContainerSummary
+	add := func(kind string, object metaV1.ObjectMeta, template v1.PodTemplateSpec) {
+		if withPods[kind+"/"+object.Name] {
+			return
+		}
+		logr.Infof("%s %s in namespace %s has no pod, scanning its pod template", kind, object.Name, object.Namespace)
+		workload := strings.ToLower(kind) + "/" + object.Name
+		for _, container := range specContainers(object.Namespace, workload, template.Spec, nil) {
+			container.PodLabels = template.Labels
+			container.Workload = workload
+			container.SkipScanReason = SkipScanReason(template.Annotations, object.Annotations)
+			container.Annotations = contextAnnotations(nil, annotationKeys, template.Annotations, object.Annotations)
+			container.Criticality = criticality(template.Annotations, object.Annotations)
+			containers = append(containers, container)
+		}
+	}
+	for _, deployment := range w.deployments {
+		add("Deployment", deployment.ObjectMeta, deployment.Spec.Template)
+	}
+	for _, statefulSet := range w.statefulSets {
+		add("StatefulSet", statefulSet.ObjectMeta, statefulSet.Spec.Template)
+	}
+	for _, cronJob := range w.cronJobs {
+		add("CronJob", cronJob.ObjectMeta, cronJob.Spec.JobTemplate.Spec.Template)
+	}
+	for _, job := range w.jobs {
+		if _, ok := cronJobOfJob[job.Name]; ok {
+			continue
+		}
+		add("Job", job.ObjectMeta, job.Spec.Template)
+	}
+	return containers
 }
 
+// getNamespaces lists the namespaces matching the label selector, one page of the discovery page size at a time
 func (k *kubernetesClient) getNamespaces(labelSelector string) (*v1.NamespaceList, error) {
-	options := metaV1.ListOptions{}
+	options := metaV1.ListOptions{Limit: k.discovery.PageSize}
 	if labelSelector != "" {
 		options.LabelSelector = labelSelector
 	}
 
-	namespaceList, err := k.clientset.CoreV1().Namespaces().List(context.Background(), options)
+	namespaces := &v1.NamespaceList{}
+	for {
+		namespaceList, err := k.clientset.CoreV1().Namespaces().List(context.Background(), options)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find namespaces: %v", err)
+		}
+		namespaces.Items = append(namespaces.Items, namespaceList.Items...)
+		if namespaceList.Continue == "" {
+			return namespaces, nil
+		}
+		options.Continue = namespaceList.Continue
+	}
+}
+
+func (k *kubernetesClient) GetNodes() ([]v1.Node, error) {
+	nodeList, err := k.clientset.CoreV1().Nodes().List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find nodes: %v", err)
+	}
+	return nodeList.Items, nil
+}
+
+func (k *kubernetesClient) GetNode(name string) (*v1.Node, error) {
+	node, err := k.clientset.CoreV1().Nodes().Get(context.Background(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get node %s: %v", name, err)
+	}
+	return node, nil
+}
+
+func (k *kubernetesClient) RunJob(job *batchV1.Job, timeout time.Duration) ([]byte, error) {
+	jobsClient := k.clientset.BatchV1().Jobs(job.Namespace)
+	created, err := jobsClient.Create(context.Background(), job, metaV1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create job %s/%s: %v", job.Namespace, job.Name, err)
+	}
+	defer func() {
+		deletePolicy := metaV1.DeletePropagationForeground
+		if err := jobsClient.Delete(context.Background(), created.Name, metaV1.DeleteOptions{PropagationPolicy: &deletePolicy}); err != nil {
+			logr.Errorf("Unable to delete job %s/%s: %v", created.Namespace, created.Name, err)
+		}
+	}()
+
+	logr.Infof("Waiting for job %s/%s to complete", created.Namespace, created.Name)
+	err = wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		current, err := jobsClient.Get(context.Background(), created.Name, metaV1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if current.Status.Failed > 0 {
+			return false, fmt.Errorf("job failed")
+		}
+		return current.Status.Succeeded > 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for job %s/%s: %v", created.Namespace, created.Name, err)
+	}
+
+	podList, err := k.clientset.CoreV1().Pods(created.Namespace).List(context.Background(), metaV1.ListOptions{LabelSelector: "job-name=" + created.Name})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find pods of job %s/%s: %v", created.Namespace, created.Name, err)
+	}
+	if len(podList.Items) == 0 {
+		return nil, fmt.Errorf("no pod found for job %s/%s", created.Namespace, created.Name)
+	}
+	logs, err := k.clientset.CoreV1().Pods(created.Namespace).GetLogs(podList.Items[0].Name, &v1.PodLogOptions{}).Do(context.Background()).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get logs of job %s/%s: %v", created.Namespace, created.Name, err)
+	}
+	return logs, nil
+}
+
+func (k *kubernetesClient) GetWorkloadsInNamespaces(labelSelector string) ([]Workload, error) {
+	namespaceList, err := k.getNamespaces(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list namespaces: %v", err)
+	}
+
+	var workloads []Workload
+	for _, namespace := range namespaceList.Items {
+		podList, err := k.clientset.CoreV1().Pods(namespace.Name).List(context.Background(), metaV1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to find pods in namespace %s %v", namespace.Name, err)
+		}
+		workloads = append(workloads, groupPodsByWorkload(namespace, podList.Items)...)
+	}
+	return workloads, nil
+}
+
+// groupPodsByWorkload returns one workload per pod controller, in the order the pods are listed
+func groupPodsByWorkload(namespace v1.Namespace, pods []v1.Pod) []Workload {
+	var workloads []Workload
+	index := make(map[string]int)
+	suppressions := NamespaceSuppressions(namespace.Name, namespace.Annotations)
+	for _, pod := range pods {
+		kind, name := podController(pod)
+		key := kind + "/" + name
+		if i, ok := index[key]; ok {
+			workloads[i].PodCount++
+			workloads[i].NodeNames = appendNodeName(workloads[i].NodeNames, pod)
+			addImageDigests(workloads[i].ImageDigests, pod)
+			continue
+		}
+		index[key] = len(workloads)
+		workloads = append(workloads, Workload{
+			Kind:            kind,
+			Name:            name,
+			Namespace:       pod.Namespace,
+			NamespaceLabels: namespace.Labels,
+			PodLabels:       pod.Labels,
+			PodAnnotations:  pod.Annotations,
+			PodSpec:         pod.Spec,
+			PodCount:        1,
+			NodeNames:       appendNodeName(nil, pod),
+			ImageDigests:    addImageDigests(make(map[string][]string), pod),
+			Suppressions:    suppressions,
+		})
+	}
+	return workloads
+}
+
+// addImageDigests adds the digests of the images the containers of the pod run to the digests by container name
+func addImageDigests(imageDigests map[string][]string, pod v1.Pod) map[string][]string {
+	for _, container := range podContainers(pod) {
+		if container.ImageDigest == "" || containsString(imageDigests[container.ContainerName], container.ImageDigest) {
+			continue
+		}
+		imageDigests[container.ContainerName] = append(imageDigests[container.ContainerName], container.ImageDigest)
+	}
+	return imageDigests
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// appendNodeName appends the node of the pod to the node names when the pod is scheduled
+func appendNodeName(nodeNames []string, pod v1.Pod) []string {
+	if pod.Spec.NodeName == "" {
+		return nodeNames
+	}
+	return append(nodeNames, pod.Spec.NodeName)
+}
+
+// podController returns the kind and name of the controller managing the pod.
+// Pods created by a ReplicaSet are attributed to its Deployment using the pod-template-hash label
+func podController(pod v1.Pod) (string, string) {
+	owner := metaV1.GetControllerOf(&pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if hash, ok := pod.Labels["pod-template-hash"]; ok && owner.Kind == "ReplicaSet" && strings.HasSuffix(owner.Name, "-"+hash) {
+		return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+	}
+	return owner.Kind, owner.Name
+}
+
+func (k *kubernetesClient) GetNetworkPolicies(namespace string) ([]networkingV1.NetworkPolicy, error) {
+	policyList, err := k.clientset.NetworkingV1().NetworkPolicies(namespace).List(context.Background(), metaV1.ListOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("unable to find namespaces: %v", err)
+		return nil, fmt.Errorf("unable to find network policies in namespace %s: %v", namespace, err)
+	}
+	return policyList.Items, nil
+}
+
+func (k *kubernetesClient) GetPodDisruptionBudgets(namespace string) ([]policyV1.PodDisruptionBudget, error) {
+	budgetList, err := k.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find pod disruption budgets in namespace %s: %v", namespace, err)
+	}
+	return budgetList.Items, nil
+}
+
+func (k *kubernetesClient) GetServices(namespace string) ([]v1.Service, error) {
+	serviceList, err := k.clientset.CoreV1().Services(namespace).List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find services in namespace %s: %v", namespace, err)
+	}
+	return serviceList.Items, nil
+}
+
+func (k *kubernetesClient) GetIngresses(namespace string) ([]networkingV1.Ingress, error) {
+	ingressList, err := k.clientset.NetworkingV1().Ingresses(namespace).List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find ingresses in namespace %s: %v", namespace, err)
+	}
+	return ingressList.Items, nil
+}
+
+func (k *kubernetesClient) GetHorizontalPodAutoscalers(namespace string) ([]autoscalingV2.HorizontalPodAutoscaler, error) {
+	autoscalerList, err := k.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find horizontal pod autoscalers in namespace %s: %v", namespace, err)
+	}
+	return autoscalerList.Items, nil
+}
+
+func (k *kubernetesClient) GetServerVersion() (string, error) {
+	version, err := k.clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("unable to get the server version: %v", err)
+	}
+	return version.GitVersion, nil
+}
+
+// GetResourceAPIVersions reads the API versions from the last applied configuration and managed fields of the resources,
+// objects are always returned in the requested version by the API server
+func (k *kubernetesClient) GetResourceAPIVersions(namespace string) ([]ResourceAPIVersions, error) {
+	ctx := context.Background()
+	options := metaV1.ListOptions{}
+	lists := []struct {
+		kind string
+		list func() (runtime.Object, error)
+	}{
+		{"Deployment", func() (runtime.Object, error) {
+			return k.clientset.AppsV1().Deployments(namespace).List(ctx, options)
+		}},
+		{"StatefulSet", func() (runtime.Object, error) {
+			return k.clientset.AppsV1().StatefulSets(namespace).List(ctx, options)
+		}},
+		{"DaemonSet", func() (runtime.Object, error) {
+			return k.clientset.AppsV1().DaemonSets(namespace).List(ctx, options)
+		}},
+		{"CronJob", func() (runtime.Object, error) {
+			return k.clientset.BatchV1().CronJobs(namespace).List(ctx, options)
+		}},
+		{"Ingress", func() (runtime.Object, error) {
+			return k.clientset.NetworkingV1().Ingresses(namespace).List(ctx, options)
+		}},
+		{"NetworkPolicy", func() (runtime.Object, error) {
+			return k.clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, options)
+		}},
+		{"PodDisruptionBudget", func() (runtime.Object, error) {
+			return k.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, options)
+		}},
+		{"HorizontalPodAutoscaler", func() (runtime.Object, error) {
+			return k.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, options)
+		}},
+	}
+
+	var resources []ResourceAPIVersions
+	for _, l := range lists {
+		list, err := l.list()
+		if err != nil {
+			// the resource may not be served in this version by older clusters
+			logr.Warnf("Unable to list %s in namespace %s: %v", l.kind, namespace, err)
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s list in namespace %s: %v", l.kind, namespace, err)
+		}
+		for _, item := range items {
+			object, err := meta.Accessor(item)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s metadata in namespace %s: %v", l.kind, namespace, err)
+			}
+			resources = append(resources, ResourceAPIVersions{
+				Kind:        l.kind,
+				Name:        object.GetName(),
+				Namespace:   object.GetNamespace(),
+				APIVersions: apiVersions(object),
+			})
+		}
+	}
+	return resources, nil
+}
+
+// apiVersions returns the distinct API versions found in the last applied configuration and managed fields
+func apiVersions(object metaV1.Object) []string {
+	var versions []string
+	add := func(version string) {
+		for _, v := range versions {
+			if v == version {
+				return
+			}
+		}
+		if version != "" {
+			versions = append(versions, version)
+		}
+	}
+
+	if lastApplied, ok := object.GetAnnotations()[v1.LastAppliedConfigAnnotation]; ok {
+		var applied struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if err := json.Unmarshal([]byte(lastApplied), &applied); err == nil {
+			add(applied.APIVersion)
+		}
+	}
+	for _, field := range object.GetManagedFields() {
+		add(field.APIVersion)
+	}
+	return versions
+}
+
+// podContainers lists the regular, init and ephemeral containers of the pod
+func podContainers(pod v1.Pod) []ContainerSummary {
+	imageDigests := make(map[string]string)
+	var statuses []v1.ContainerStatus
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+	for _, status := range statuses {
+		imageDigests[status.Name] = ImageDigest(status.ImageID)
+	}
+	containers := specContainers(pod.Namespace, pod.Name, pod.Spec, imageDigests)
+	changedAt := podChangeTime(pod)
+	for i := range containers {
+		containers[i].NodeName = pod.Spec.NodeName
+		containers[i].ChangedAt = changedAt
+	}
+	return containers
+}
+
+// podChangeTime returns the time the pod was created or its spec last updated according to its managed fields, the
+// updates of its status by the kubelet being ignored. It is nil when the pod has no creation time
+func podChangeTime(pod v1.Pod) *time.Time {
+	if pod.CreationTimestamp.IsZero() {
+		return nil
+	}
+	changedAt := pod.CreationTimestamp.Time
+	for _, field := range pod.ManagedFields {
+		if field.Subresource == "" && field.Time != nil && field.Time.After(changedAt) {
+			changedAt = field.Time.Time
+		}
+	}
+	return &changedAt
+}
+
+// specContainers lists the regular, init and ephemeral containers of the pod spec, with the image digests
+// of imageDigests indexed by container name
+func specContainers(namespace, podName string, spec v1.PodSpec, imageDigests map[string]string) []ContainerSummary {
+	var containers []ContainerSummary
+	addContainer := func(name, image string, containerType ContainerType) {
+		containers = append(containers, ContainerSummary{
+			Namespace:     namespace,
+			PodName:       podName,
+			ContainerName: name,
+			Image:         image,
+			ImageDigest:   imageDigests[name],
+			Type:          containerType,
+		})
+	}
+	for _, container := range spec.Containers {
+		addContainer(container.Name, container.Image, RegularContainer)
+	}
+	for _, container := range spec.InitContainers {
+		addContainer(container.Name, container.Image, InitContainer)
+	}
+	for _, container := range spec.EphemeralContainers {
+		addContainer(container.Name, container.Image, EphemeralContainer)
+	}
+	return containers
+}
+
+// ImageDigest extracts the digest from the image id of a container status, for instance
+// docker-pullable://nginx@sha256:4ff3ca91... or sha256:4ff3ca91... It is empty when the image id holds no digest
+func ImageDigest(imageID string) string {
+	if index := strings.LastIndex(imageID, "@"); index >= 0 {
+		imageID = imageID[index+1:]
+	}
+	imageID = strings.TrimPrefix(imageID, "docker://")
+	if !strings.HasPrefix(imageID, "sha256:") {
+		return ""
 	}
-	return namespaceList, nil
+	return imageID
 }
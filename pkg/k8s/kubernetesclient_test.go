@@ -0,0 +1,696 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	rbacV1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestK8s(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "K8s Suite")
+}
+
+var _ = Describe("Idle workloads", func() {
+
+	podSpec := func(image string) v1.PodSpec {
+		return v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: image}}}
+	}
+	objectMeta := func(name string, owner *metaV1.OwnerReference) metaV1.ObjectMeta {
+		meta := metaV1.ObjectMeta{Name: name, Namespace: "ns"}
+		if owner != nil {
+			meta.OwnerReferences = []metaV1.OwnerReference{*owner}
+		}
+		return meta
+	}
+	controller := func(kind, name string) *metaV1.OwnerReference {
+		isController := true
+		return &metaV1.OwnerReference{Kind: kind, Name: name, Controller: &isController}
+	}
+
+	var workloads workloadControllers
+
+	BeforeEach(func() {
+		var replicas int32
+		workloads = workloadControllers{
+			deployments: []appsV1.Deployment{
+				{ObjectMeta: objectMeta("web", nil), Spec: appsV1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: podSpec("web:1")}}},
+				{ObjectMeta: objectMeta("legacy", nil), Spec: appsV1.DeploymentSpec{Replicas: &replicas, Template: v1.PodTemplateSpec{Spec: podSpec("legacy:1")}}},
+			},
+			cronJobs: []batchV1.CronJob{
+				{ObjectMeta: objectMeta("backup", nil), Spec: batchV1.CronJobSpec{JobTemplate: batchV1.JobTemplateSpec{Spec: batchV1.JobSpec{Template: v1.PodTemplateSpec{Spec: podSpec("backup:1")}}}}},
+				{ObjectMeta: objectMeta("report", nil), Spec: batchV1.CronJobSpec{JobTemplate: batchV1.JobTemplateSpec{Spec: batchV1.JobSpec{Template: v1.PodTemplateSpec{Spec: podSpec("report:1")}}}}},
+			},
+			jobs: []batchV1.Job{
+				{ObjectMeta: objectMeta("report-28100", controller("CronJob", "report")), Spec: batchV1.JobSpec{Template: v1.PodTemplateSpec{Spec: podSpec("report:1")}}},
+				{ObjectMeta: objectMeta("migrate", nil), Spec: batchV1.JobSpec{Template: v1.PodTemplateSpec{Spec: podSpec("migrate:1")}}},
+			},
+		}
+	})
+
+	It("returns the template containers of the workloads without pods", func() {
+		pods := []v1.Pod{
+			{ObjectMeta: metaV1.ObjectMeta{Name: "web-5d8f-x2k9z", Namespace: "ns", Labels: map[string]string{"pod-template-hash": "5d8f"},
+				OwnerReferences: []metaV1.OwnerReference{*controller("ReplicaSet", "web-5d8f")}}, Spec: podSpec("web:1")},
+			{ObjectMeta: objectMeta("report-28100-abcde", controller("Job", "report-28100")), Spec: podSpec("report:1")},
+		}
+
+		Expect(workloads.containersWithoutPods(pods, nil)).To(Equal([]ContainerSummary{
+			{Namespace: "ns", PodName: "deployment/legacy", Workload: "deployment/legacy", ContainerName: "main", Image: "legacy:1"},
+			{Namespace: "ns", PodName: "cronjob/backup", Workload: "cronjob/backup", ContainerName: "main", Image: "backup:1"},
+			{Namespace: "ns", PodName: "job/migrate", Workload: "job/migrate", ContainerName: "main", Image: "migrate:1"},
+		}))
+	})
+
+	It("covers the jobs of a cron job with the cron job template", func() {
+		containers := workloads.containersWithoutPods(nil, nil)
+
+		var podNames []string
+		for _, container := range containers {
+			podNames = append(podNames, container.PodName)
+		}
+		Expect(podNames).To(Equal([]string{"deployment/web", "deployment/legacy", "cronjob/backup", "cronjob/report", "job/migrate"}))
+	})
+
+	It("lists the init containers of the pod templates", func() {
+		workloads = workloadControllers{jobs: []batchV1.Job{{ObjectMeta: objectMeta("seed", nil), Spec: batchV1.JobSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "wait", Image: "busybox:1"}},
+			Containers:     []v1.Container{{Name: "seed", Image: "seed:1"}},
+		}}}}}}
+
+		Expect(workloads.containersWithoutPods(nil, nil)).To(Equal([]ContainerSummary{
+			{Namespace: "ns", PodName: "job/seed", Workload: "job/seed", ContainerName: "seed", Image: "seed:1"},
+			{Namespace: "ns", PodName: "job/seed", Workload: "job/seed", ContainerName: "wait", Image: "busybox:1", Type: InitContainer},
+		}))
+	})
+
+	It("labels the containers with the pod template labels", func() {
+		workloads = workloadControllers{statefulSets: []appsV1.StatefulSet{{ObjectMeta: objectMeta("db", nil), Spec: appsV1.StatefulSetSpec{Template: v1.PodTemplateSpec{
+			ObjectMeta: metaV1.ObjectMeta{Labels: map[string]string{"team": "data"}},
+			Spec:       podSpec("postgres:15"),
+		}}}}}
+
+		Expect(workloads.containersWithoutPods(nil, nil)).To(Equal([]ContainerSummary{
+			{Namespace: "ns", PodName: "statefulset/db", Workload: "statefulset/db", PodLabels: map[string]string{"team": "data"}, ContainerName: "main", Image: "postgres:15"},
+		}))
+	})
+
+	It("attributes the pods to the workload owning their controller", func() {
+		workloads.replicaSets = []appsV1.ReplicaSet{{ObjectMeta: objectMeta("web-5d8f", controller("Deployment", "web"))}}
+
+		Expect(workloads.workloadOf(v1.Pod{ObjectMeta: objectMeta("web-5d8f-x2k9z", controller("ReplicaSet", "web-5d8f"))})).To(Equal("deployment/web"))
+		Expect(workloads.workloadOf(v1.Pod{ObjectMeta: objectMeta("report-28100-abcde", controller("Job", "report-28100"))})).To(Equal("cronjob/report"))
+		Expect(workloads.workloadOf(v1.Pod{ObjectMeta: objectMeta("migrate-fghij", controller("Job", "migrate"))})).To(Equal("job/migrate"))
+		Expect(workloads.workloadOf(v1.Pod{ObjectMeta: objectMeta("node-exporter-q7x2p", controller("DaemonSet", "node-exporter"))})).To(Equal("daemonset/node-exporter"))
+		Expect(workloads.workloadOf(v1.Pod{ObjectMeta: objectMeta("debug", nil)})).To(Equal("pod/debug"))
+	})
+
+	It("attributes the pods to the deployment of their pod-template-hash when the replica sets are not listed", func() {
+		pod := v1.Pod{ObjectMeta: metaV1.ObjectMeta{Name: "api-7c9b-k2m4p", Namespace: "ns", Labels: map[string]string{"pod-template-hash": "7c9b"},
+			OwnerReferences: []metaV1.OwnerReference{*controller("ReplicaSet", "api-7c9b")}}}
+
+		Expect(workloads.workloadOf(pod)).To(Equal("deployment/api"))
+	})
+})
+
+var _ = Describe("LabelValue", func() {
+	It("returns the first label set on the pod, or else on the namespace", func() {
+		podLabels := map[string]string{"app.kubernetes.io/team": "api-team"}
+		namespaceLabels := map[string]string{"team": "platform", "owner": "sre"}
+
+		Expect(LabelValue("team, app.kubernetes.io/team", podLabels, namespaceLabels)).To(Equal("api-team"))
+		Expect(LabelValue("owner,team", nil, namespaceLabels)).To(Equal("sre"))
+		Expect(LabelValue("squad,,team", podLabels, namespaceLabels)).To(Equal("platform"))
+		Expect(LabelValue("squad", podLabels, namespaceLabels)).To(BeEmpty())
+		Expect(LabelValue("", podLabels, namespaceLabels)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Pod change time", func() {
+	It("sets the time the pod was created or its spec last updated on the containers, ignoring the status updates", func() {
+		created := metaV1.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+		updated := metaV1.Date(2023, 9, 5, 10, 0, 0, 0, time.UTC)
+		statusUpdated := metaV1.Date(2023, 9, 7, 10, 0, 0, 0, time.UTC)
+		pod := v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Name: "api-1", Namespace: "ns", CreationTimestamp: created, ManagedFields: []metaV1.ManagedFieldsEntry{
+				{Manager: "kube-controller-manager", Time: &created},
+				{Manager: "kubectl", Time: &updated},
+				{Manager: "kubelet", Subresource: "status", Time: &statusUpdated},
+			}},
+			Spec: v1.PodSpec{Containers: []v1.Container{{Name: "api", Image: "api:1"}, {Name: "proxy", Image: "envoy:1"}}},
+		}
+
+		containers := podContainers(pod)
+
+		Expect(containers).To(HaveLen(2))
+		Expect(*containers[0].ChangedAt).To(Equal(updated.Time))
+		Expect(*containers[1].ChangedAt).To(Equal(updated.Time))
+
+		pod.ManagedFields = nil
+		Expect(*podChangeTime(pod)).To(Equal(created.Time))
+		Expect(podChangeTime(v1.Pod{})).To(BeNil())
+	})
+})
+
+var _ = Describe("NamespaceSelector", func() {
+	It("restricts the label selector to the namespace", func() {
+		Expect(NamespaceSelector("area=payments", "api")).To(Equal("area=payments,kubernetes.io/metadata.name=api"))
+		Expect(NamespaceSelector("", "api")).To(Equal("kubernetes.io/metadata.name=api"))
+		Expect(NamespaceSelector("area=payments", "")).To(Equal("area=payments"))
+	})
+})
+
+var _ = Describe("Kubeconfig resolution", func() {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+- name: prod
+  context:
+    cluster: prod-cluster
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+`
+	var kubeconfigPath string
+
+	BeforeEach(func() {
+		kubeconfigPath = filepath.Join(GinkgoT().TempDir(), "config")
+		Expect(os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600)).To(Succeed())
+		GinkgoT().Setenv("KUBERNETES_SERVICE_HOST", "")
+	})
+
+	It("uses the current context when no context is specified", func() {
+		Expect(KubernetesConfig("", kubeconfigPath).Host).To(Equal("https://dev.example.com"))
+		Expect(ClusterName("", kubeconfigPath)).To(Equal("dev-cluster"))
+	})
+
+	It("uses the specified context", func() {
+		Expect(KubernetesConfig("prod", kubeconfigPath).Host).To(Equal("https://prod.example.com"))
+		Expect(ClusterName("prod", kubeconfigPath)).To(Equal("prod-cluster"))
+	})
+
+	It("merges the kubeconfig files of the KUBECONFIG environment variable", func() {
+		emptyKubeconfigPath := filepath.Join(GinkgoT().TempDir(), "empty")
+		Expect(os.WriteFile(emptyKubeconfigPath, []byte("apiVersion: v1\nkind: Config\n"), 0600)).To(Succeed())
+		GinkgoT().Setenv("KUBECONFIG", emptyKubeconfigPath+string(os.PathListSeparator)+kubeconfigPath)
+
+		Expect(KubernetesConfig("prod", "").Host).To(Equal("https://prod.example.com"))
+		Expect(ClusterName("", "")).To(Equal("dev-cluster"))
+	})
+
+	It("applies the client options to the config", func() {
+		SetClientOptions(ClientOptions{QPS: 50, Burst: 100, Timeout: 30 * time.Second})
+		DeferCleanup(SetClientOptions, ClientOptions{})
+
+		config := KubernetesConfig("", kubeconfigPath)
+
+		Expect(config.QPS).To(BeNumerically("==", 50))
+		Expect(config.Burst).To(Equal(100))
+		Expect(config.Timeout).To(Equal(30 * time.Second))
+	})
+})
+
+var _ = Describe("WatchContainers", func() {
+	pod := func(namespace, name, image string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"team": "api"}},
+			Spec:       v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{{Name: "main", Image: image}}},
+		}
+	}
+
+	It("reports the containers of the pods running and created in the namespaces matching the selector", func() {
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "payments", Labels: map[string]string{"area": "payments"}}},
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "kube-system"}},
+			pod("kube-system", "coredns", "coredns:1.10"),
+			pod("payments", "api-1", "api:1.0"),
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watched := make(chan []ContainerSummary, 10)
+		done := make(chan error, 1)
+
+		go func() {
+			done <- NewKubernetesClientWith(clientset).WatchContainers(ctx, "area=payments", func(containers []ContainerSummary) {
+				watched <- containers
+			})
+		}()
+
+		var containers []ContainerSummary
+		Eventually(watched).Should(Receive(&containers))
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].Image).To(Equal("api:1.0"))
+		Expect(containers[0].Workload).To(Equal("pod/api-1"))
+		Expect(containers[0].PodLabels).To(Equal(map[string]string{"team": "api"}))
+		Expect(containers[0].NamespaceLabels).To(Equal(map[string]string{"area": "payments"}))
+		Expect(containers[0].NodeName).To(Equal("node-1"))
+
+		_, err := clientset.CoreV1().Pods("payments").Create(context.Background(), pod("payments", "web-1", "web:2.0"), metaV1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(watched).Should(Receive(&containers))
+		Expect(containers[0].Image).To(Equal("web:2.0"))
+		Consistently(watched, "100ms").ShouldNot(Receive())
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("records the context annotations of the workloads of the pods, including those created during the watch", func() {
+		isController := true
+		ownedPod := func(name, image, kind, owner string) *v1.Pod {
+			p := pod("payments", name, image)
+			p.OwnerReferences = []metaV1.OwnerReference{{Kind: kind, Name: owner, Controller: &isController}}
+			return p
+		}
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "payments", Labels: map[string]string{"area": "payments"}, Annotations: map[string]string{"tier": "2"}}},
+			&appsV1.DaemonSet{ObjectMeta: metaV1.ObjectMeta{Name: "agent", Namespace: "payments", Annotations: map[string]string{"tier": "0"}}},
+			ownedPod("agent-x8v2k", "agent:1.0", "DaemonSet", "agent"),
+		)
+		client := &kubernetesClient{clientset: clientset, discovery: DiscoveryOptions{ContextAnnotations: []string{"tier"}}}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watched := make(chan []ContainerSummary, 10)
+		done := make(chan error, 1)
+
+		go func() {
+			done <- client.WatchContainers(ctx, "area=payments", func(containers []ContainerSummary) {
+				watched <- containers
+			})
+		}()
+
+		var containers []ContainerSummary
+		Eventually(watched).Should(Receive(&containers))
+		Expect(containers[0].Annotations).To(Equal(map[string]string{"tier": "0"}))
+
+		_, err := clientset.AppsV1().StatefulSets("payments").Create(context.Background(),
+			&appsV1.StatefulSet{ObjectMeta: metaV1.ObjectMeta{Name: "db", Namespace: "payments", Annotations: map[string]string{"tier": "1"}}}, metaV1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = clientset.CoreV1().Pods("payments").Create(context.Background(), ownedPod("db-0", "postgres:15", "StatefulSet", "db"), metaV1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(watched).Should(Receive(&containers))
+		Expect(containers[0].Workload).To(Equal("statefulset/db"))
+		Expect(containers[0].Annotations).To(Equal(map[string]string{"tier": "1"}))
+		_, err = clientset.CoreV1().Pods("payments").Create(context.Background(), pod("payments", "debug", "busybox:1.36"), metaV1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(watched).Should(Receive(&containers))
+		Expect(containers[0].Annotations).To(Equal(map[string]string{"tier": "2"}))
+		Consistently(watched, "100ms").ShouldNot(Receive())
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
+
+var _ = Describe("Exposure", func() {
+	service := func(name string, serviceType v1.ServiceType, selector map[string]string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec:       v1.ServiceSpec{Type: serviceType, Selector: selector},
+		}
+	}
+	pod := func(name string, labels map[string]string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: "shop", Labels: labels},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: name + ":1.0"}}},
+		}
+	}
+
+	It("marks the containers with the highest exposure of the Services and Ingresses selecting their pod", func() {
+		pathType := networkingV1.PathTypePrefix
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "shop"}},
+			pod("web", map[string]string{"app": "web"}),
+			pod("admin", map[string]string{"app": "admin"}),
+			pod("api", map[string]string{"app": "api"}),
+			pod("db", map[string]string{"app": "db"}),
+			pod("batch", map[string]string{"app": "batch"}),
+			service("web", v1.ServiceTypeClusterIP, map[string]string{"app": "web"}),
+			service("admin", v1.ServiceTypeNodePort, map[string]string{"app": "admin"}),
+			service("api", v1.ServiceTypeLoadBalancer, map[string]string{"app": "api"}),
+			service("api-internal", v1.ServiceTypeClusterIP, map[string]string{"app": "api"}),
+			service("db", v1.ServiceTypeClusterIP, map[string]string{"app": "db"}),
+			&networkingV1.Ingress{
+				ObjectMeta: metaV1.ObjectMeta{Name: "web", Namespace: "shop"},
+				Spec: networkingV1.IngressSpec{Rules: []networkingV1.IngressRule{{
+					IngressRuleValue: networkingV1.IngressRuleValue{HTTP: &networkingV1.HTTPIngressRuleValue{Paths: []networkingV1.HTTPIngressPath{{
+						Path: "/", PathType: &pathType, Backend: networkingV1.IngressBackend{Service: &networkingV1.IngressServiceBackend{Name: "web"}},
+					}}}},
+				}}},
+			},
+		)
+
+		containers, err := NewKubernetesClientWith(clientset).GetContainersInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		exposures := make(map[string]string)
+		for _, container := range containers {
+			exposures[container.Image] = container.Exposure
+		}
+		Expect(exposures).To(Equal(map[string]string{
+			"web:1.0":   ExposureInternet,
+			"admin:1.0": ExposureNode,
+			"api:1.0":   ExposureInternet,
+			"db:1.0":    ExposureCluster,
+			"batch:1.0": "",
+		}))
+		Expect(HighestExposure(containers)).To(Equal(ExposureInternet))
+	})
+})
+
+var _ = Describe("Scan opt-outs", func() {
+	It("sets the skip scan reason of the pod, or else of the namespace, on the containers", func() {
+		pod := func(name, namespace string, annotations map[string]string) *v1.Pod {
+			return &v1.Pod{
+				ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: name + ":1.0"}}},
+			}
+		}
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "shop"}},
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "vendor", Annotations: map[string]string{SkipScanAnnotation: "scanned by the vendor"}}},
+			pod("web", "shop", nil),
+			pod("legacy", "shop", map[string]string{SkipScanAnnotation: ""}),
+			pod("appliance", "vendor", nil),
+			pod("agent", "vendor", map[string]string{SkipScanAnnotation: "image mirrored from the vendor registry"}),
+		)
+
+		containers, err := NewKubernetesClientWith(clientset).GetContainersInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		reasons := make(map[string]string)
+		for _, container := range containers {
+			reasons[container.Image] = container.SkipScanReason
+		}
+		Expect(reasons).To(Equal(map[string]string{
+			"web:1.0":       "",
+			"legacy:1.0":    "no reason given",
+			"appliance:1.0": "scanned by the vendor",
+			"agent:1.0":     "image mirrored from the vendor registry",
+		}))
+	})
+})
+
+var _ = Describe("Context annotations", func() {
+	It("records the configured annotations of the pod, or else of its workload, or else of its namespace, on the containers", func() {
+		isController := true
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "shop", Annotations: map[string]string{"tier": "2", "owner": "shop-team"}}},
+			&appsV1.Deployment{
+				ObjectMeta: metaV1.ObjectMeta{Name: "web", Namespace: "shop", Annotations: map[string]string{"data-classification": "pii", CriticalityAnnotation: " high "}},
+				Spec:       appsV1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "web:1.0"}}}}},
+			},
+			&appsV1.Deployment{
+				ObjectMeta: metaV1.ObjectMeta{Name: "batch", Namespace: "shop"},
+				Spec: appsV1.DeploymentSpec{Template: v1.PodTemplateSpec{
+					ObjectMeta: metaV1.ObjectMeta{Annotations: map[string]string{"tier": "3", CriticalityAnnotation: "low"}},
+					Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "batch:1.0"}}},
+				}},
+			},
+			&v1.Pod{
+				ObjectMeta: metaV1.ObjectMeta{Name: "web-7c9b-k2m4p", Namespace: "shop", Labels: map[string]string{"pod-template-hash": "7c9b"},
+					Annotations:     map[string]string{"tier": "1"},
+					OwnerReferences: []metaV1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7c9b", Controller: &isController}}},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "web:1.0"}}},
+			},
+			&appsV1.DaemonSet{ObjectMeta: metaV1.ObjectMeta{Name: "log-shipper", Namespace: "shop", Annotations: map[string]string{"tier": "0"}}},
+			&v1.Pod{
+				ObjectMeta: metaV1.ObjectMeta{Name: "log-shipper-x8v2k", Namespace: "shop",
+					OwnerReferences: []metaV1.OwnerReference{{Kind: "DaemonSet", Name: "log-shipper", Controller: &isController}}},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "fluent-bit:2.1"}}},
+			},
+		)
+		client := &kubernetesClient{clientset: clientset, discovery: DiscoveryOptions{ContextAnnotations: []string{"data-classification", "tier"}}}
+
+		containers, err := client.GetContainersInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		annotations := make(map[string]map[string]string)
+		for _, container := range containers {
+			annotations[container.Image] = container.Annotations
+		}
+		Expect(annotations).To(Equal(map[string]map[string]string{
+			"web:1.0":        {"data-classification": "pii", "tier": "1"},
+			"batch:1.0":      {"tier": "3"},
+			"fluent-bit:2.1": {"tier": "0"},
+		}))
+		for _, container := range containers {
+			Expect(container.Criticality).To(Equal(map[string]string{"web:1.0": "high", "batch:1.0": "low"}[container.Image]), container.Image)
+		}
+	})
+})
+
+var _ = Describe("Namespace suppressions", func() {
+	It("parses the suppressed patterns with their expiry date, ignoring the invalid entries", func() {
+		suppressions := NamespaceSuppressions("payments", map[string]string{
+			SuppressAnnotation:       "CVE-2023-*@2024-03-31, probes\nCVE-[@2024-01-01,CVE-2024-0001@31/03/2024",
+			SuppressReasonAnnotation: "waiting for the vendor fix",
+		})
+
+		Expect(suppressions).To(HaveLen(2))
+		Expect(suppressions[0].Pattern).To(Equal("CVE-2023-*"))
+		Expect(suppressions[0].ExpiryDate()).To(Equal("2024-03-31"))
+		Expect(suppressions[0].Reason).To(Equal("waiting for the vendor fix"))
+		Expect(suppressions[1].Pattern).To(Equal("probes"))
+		Expect(suppressions[1].Expires).To(BeNil())
+		Expect(NamespaceSuppressions("orders", nil)).To(BeEmpty())
+	})
+
+	It("matches the findings until the end of the expiry date, whatever their case", func() {
+		suppression := NamespaceSuppressions("payments", map[string]string{SuppressAnnotation: "cve-2023-*@2024-03-31"})[0]
+
+		Expect(suppression.Matches("CVE-2023-4911", time.Date(2024, 3, 31, 23, 59, 0, 0, time.UTC))).To(BeTrue())
+		Expect(suppression.Matches("CVE-2024-0001", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))).To(BeFalse())
+		Expect(suppression.Matches("CVE-2023-4911", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))).To(BeFalse())
+	})
+})
+
+var _ = Describe("GetRoleBindings", func() {
+	It("returns the role bindings of the namespace and the cluster role bindings with the rules of their role", func() {
+		rules := []rbacV1.PolicyRule{{Verbs: []string{"get"}, Resources: []string{"secrets"}}}
+		subjects := []rbacV1.Subject{{Kind: rbacV1.ServiceAccountKind, Name: "api"}}
+		clientset := fake.NewSimpleClientset(
+			&rbacV1.Role{ObjectMeta: metaV1.ObjectMeta{Name: "secret-reader", Namespace: "shop"}, Rules: rules},
+			&rbacV1.ClusterRole{ObjectMeta: metaV1.ObjectMeta{Name: "cluster-admin"}, Rules: []rbacV1.PolicyRule{{Verbs: []string{"*"}, Resources: []string{"*"}}}},
+			&rbacV1.RoleBinding{ObjectMeta: metaV1.ObjectMeta{Name: "api", Namespace: "shop"}, Subjects: subjects, RoleRef: rbacV1.RoleRef{Kind: "Role", Name: "secret-reader"}},
+			&rbacV1.RoleBinding{ObjectMeta: metaV1.ObjectMeta{Name: "other", Namespace: "other"}, Subjects: subjects, RoleRef: rbacV1.RoleRef{Kind: "Role", Name: "secret-reader"}},
+			&rbacV1.ClusterRoleBinding{ObjectMeta: metaV1.ObjectMeta{Name: "operator"}, RoleRef: rbacV1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+				Subjects: []rbacV1.Subject{{Kind: rbacV1.ServiceAccountKind, Name: "operator", Namespace: "shop"}}},
+		)
+
+		bindings, err := NewKubernetesClientWith(clientset).GetRoleBindings("shop")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bindings).To(HaveLen(2))
+		Expect(bindings[0].Kind).To(Equal("RoleBinding"))
+		Expect(bindings[0].Rules).To(Equal(rules))
+		Expect(bindings[0].BindsServiceAccount("shop", "api")).To(BeTrue())
+		Expect(bindings[0].BindsServiceAccount("other", "api")).To(BeFalse())
+		Expect(bindings[1].Kind).To(Equal("ClusterRoleBinding"))
+		Expect(bindings[1].Rules[0].Verbs).To(Equal([]string{"*"}))
+		Expect(bindings[1].BindsServiceAccount("shop", "operator")).To(BeTrue())
+	})
+
+	It("binds the service accounts through the groups of all the service accounts and of the service accounts of a namespace", func() {
+		group := func(name string) RoleBinding {
+			return RoleBinding{Kind: "ClusterRoleBinding", Subjects: []rbacV1.Subject{{Kind: rbacV1.GroupKind, APIGroup: rbacV1.GroupName, Name: name}}}
+		}
+
+		Expect(group("system:serviceaccounts").BindsServiceAccount("shop", "api")).To(BeTrue())
+		Expect(group("system:serviceaccounts:shop").BindsServiceAccount("shop", "api")).To(BeTrue())
+		Expect(group("system:serviceaccounts:other").BindsServiceAccount("shop", "api")).To(BeFalse())
+		Expect(group("system:authenticated").BindsServiceAccount("shop", "api")).To(BeFalse())
+	})
+})
+
+var _ = Describe("GetWorkloadsInNamespaces", func() {
+	It("groups the pods per controller with the nodes of the scheduled pods and the digests of their images", func() {
+		isController := true
+		replicaSet := []metaV1.OwnerReference{{Kind: "ReplicaSet", Name: "api-5d8f", Controller: &isController}}
+		pod := func(name, nodeName, imageID string) *v1.Pod {
+			return &v1.Pod{
+				ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"pod-template-hash": "5d8f"}, OwnerReferences: replicaSet},
+				Spec:       v1.PodSpec{NodeName: nodeName, Containers: []v1.Container{{Name: "api", Image: "api:1.0"}}},
+				Status:     v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{Name: "api", ImageID: imageID}}},
+			}
+		}
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "shop"}},
+			pod("api-5d8f-a", "node-1", "docker.io/library/api@sha256:aaa"), pod("api-5d8f-b", "node-2", "sha256:bbb"), pod("api-5d8f-c", "", "sha256:aaa"),
+		)
+
+		workloads, err := NewKubernetesClientWith(clientset).GetWorkloadsInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workloads).To(HaveLen(1))
+		Expect(workloads[0].Kind).To(Equal("Deployment"))
+		Expect(workloads[0].Name).To(Equal("api"))
+		Expect(workloads[0].PodCount).To(Equal(3))
+		Expect(workloads[0].NodeNames).To(Equal([]string{"node-1", "node-2"}))
+		Expect(workloads[0].ImageDigests).To(Equal(map[string][]string{"api": {"sha256:aaa", "sha256:bbb"}}))
+	})
+})
+
+var _ = Describe("Container discovery", func() {
+	pod := func(namespace, name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: namespace + ":1.0"}}},
+		}
+	}
+
+	It("discovers the containers of the namespaces concurrently", func() {
+		var objects []runtime.Object
+		var expectedImages []string
+		for i := 0; i < 25; i++ {
+			namespace := fmt.Sprintf("team-%02d", i)
+			objects = append(objects, &v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: namespace}}, pod(namespace, "api"))
+			expectedImages = append(expectedImages, namespace+":1.0")
+		}
+		client := &kubernetesClient{clientset: fake.NewSimpleClientset(objects...), discovery: DiscoveryOptions{Workers: 4}}
+
+		containers, err := client.GetContainersInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		var images []string
+		for _, container := range containers {
+			images = append(images, container.Image)
+		}
+		Expect(images).To(ConsistOf(expectedImages))
+	})
+
+	It("lists the namespaces and the pods one page at a time", func() {
+		clientset := fake.NewSimpleClientset()
+		pages := map[string]int{}
+		paginate := func(resource string, firstPage, lastPage runtime.Object) {
+			clientset.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+				pages[resource]++
+				if pages[resource] == 1 {
+					return true, firstPage, nil
+				}
+				return true, lastPage, nil
+			})
+		}
+		paginate("namespaces",
+			&v1.NamespaceList{ListMeta: metaV1.ListMeta{Continue: "next"}, Items: []v1.Namespace{{ObjectMeta: metaV1.ObjectMeta{Name: "shop"}}}},
+			&v1.NamespaceList{Items: []v1.Namespace{{ObjectMeta: metaV1.ObjectMeta{Name: "payments"}}}})
+		paginate("pods",
+			&v1.PodList{ListMeta: metaV1.ListMeta{Continue: "next"}, Items: []v1.Pod{*pod("shop", "api")}},
+			&v1.PodList{Items: []v1.Pod{*pod("shop", "web")}})
+		client := &kubernetesClient{clientset: clientset, discovery: DiscoveryOptions{Workers: 1, PageSize: 1}}
+
+		containers, err := client.GetContainersInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal(map[string]int{"namespaces": 2, "pods": 3}))
+		var pods []string
+		for _, container := range containers {
+			pods = append(pods, container.PodName)
+		}
+		Expect(pods).To(Equal([]string{"api", "web", "web"}))
+	})
+
+	It("lists only the pods of the node, without the workloads without pods", func() {
+		replicas := int32(1)
+		clientset := fake.NewSimpleClientset(
+			&v1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "shop"}},
+			&appsV1.Deployment{
+				ObjectMeta: metaV1.ObjectMeta{Name: "worker", Namespace: "shop"},
+				Spec: appsV1.DeploymentSpec{Replicas: &replicas, Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "worker:1.0"}}},
+				}},
+			},
+		)
+		var fieldSelectors []string
+		clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			fieldSelectors = append(fieldSelectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+			return true, &v1.PodList{Items: []v1.Pod{*pod("shop", "api")}}, nil
+		})
+		client := &kubernetesClient{clientset: clientset, discovery: DiscoveryOptions{NodeName: "node-1"}}
+
+		containers, err := client.GetContainersInNamespaces("")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fieldSelectors).To(Equal([]string{"spec.nodeName=node-1"}))
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].Image).To(Equal("shop:1.0"))
+	})
+})
+
+var _ = Describe("CurrentPod", func() {
+	pod := func(limits ...string) *v1.Pod {
+		isController := true
+		pod := &v1.Pod{ObjectMeta: metaV1.ObjectMeta{
+			Name:      "scanner-28311840-abcde",
+			Namespace: "readiness",
+			OwnerReferences: []metaV1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: "Job", Name: "scanner-28311840", UID: "job-uid", Controller: &isController},
+			},
+		}}
+		for _, limit := range limits {
+			container := v1.Container{Name: "main"}
+			if limit != "" {
+				container.Resources.Limits = v1.ResourceList{v1.ResourceEphemeralStorage: resource.MustParse(limit)}
+			}
+			pod.Spec.Containers = append(pod.Spec.Containers, container)
+		}
+		return pod
+	}
+
+	It("sums the ephemeral storage limits of the containers, 0 when a container has no limit", func() {
+		limited := NewCurrentPodWith(fake.NewSimpleClientset(pod("10Gi", "1Gi")), "readiness", "scanner-28311840-abcde")
+		unlimited := NewCurrentPodWith(fake.NewSimpleClientset(pod("10Gi", "")), "readiness", "scanner-28311840-abcde")
+
+		Expect(limited.EphemeralStorageLimit()).To(Equal(int64(11 << 30)))
+		Expect(unlimited.EphemeralStorageLimit()).To(BeZero())
+	})
+
+	It("records the event on the Job owning the pod", func() {
+		clientset := fake.NewSimpleClientset(pod("10Gi"))
+
+		Expect(NewCurrentPodWith(clientset, "readiness", "scanner-28311840-abcde").RecordJobEvent(v1.EventTypeWarning, "ScanFailed", "unable to list namespaces")).To(Succeed())
+
+		events, err := clientset.CoreV1().Events("readiness").List(context.Background(), metaV1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events.Items).To(HaveLen(1))
+		Expect(events.Items[0].InvolvedObject).To(Equal(v1.ObjectReference{APIVersion: "batch/v1", Kind: "Job", Name: "scanner-28311840", Namespace: "readiness", UID: "job-uid"}))
+		Expect(events.Items[0].Type).To(Equal(v1.EventTypeWarning))
+		Expect(events.Items[0].Reason).To(Equal("ScanFailed"))
+		Expect(events.Items[0].Message).To(Equal("unable to list namespaces"))
+		Expect(events.Items[0].Source.Component).To(Equal(EventSource))
+	})
+
+	It("fails to record the event when the pod is not owned by a Job", func() {
+		standalone := pod()
+		standalone.OwnerReferences = nil
+
+		err := NewCurrentPodWith(fake.NewSimpleClientset(standalone), "readiness", "scanner-28311840-abcde").RecordJobEvent(v1.EventTypeNormal, "ScanCompleted", "")
+
+		Expect(err).To(MatchError("pod readiness/scanner-28311840-abcde is not owned by a Job"))
+	})
+})
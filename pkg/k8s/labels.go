@@ -0,0 +1,44 @@
+package k8s
+
+import "strings"
+
+// LabelValue returns the value of the first of the comma-separated label names set on the pod, for instance
+// "team,app.kubernetes.io/team". The namespace labels are looked up in the same order when none of the labels
+// is set on the pod, so that pods inherit the area or team of their namespace. It is empty when no label is set
+func LabelValue(labelNames string, podLabels, namespaceLabels map[string]string) string {
+	names := LabelNames(labelNames)
+	for _, labels := range []map[string]string{podLabels, namespaceLabels} {
+		for _, name := range names {
+			if value := labels[name]; value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// LabelNames splits the comma-separated label names, ignoring the blank names
+func LabelNames(labelNames string) []string {
+	var names []string
+	for _, name := range strings.Split(labelNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// NamespaceNameLabel is the label Kubernetes sets on every namespace with the name of the namespace
+const NamespaceNameLabel = "kubernetes.io/metadata.name"
+
+// NamespaceSelector restricts the namespace label selector to the namespace, using the name label Kubernetes sets on
+// the namespaces. The label selector is returned unchanged when the namespace is empty
+func NamespaceSelector(labelSelector, namespace string) string {
+	if namespace == "" {
+		return labelSelector
+	}
+	if labelSelector == "" {
+		return NamespaceNameLabel + "=" + namespace
+	}
+	return labelSelector + "," + NamespaceNameLabel + "=" + namespace
+}
@@ -0,0 +1,136 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// serviceAccountNamespaceFile holds the namespace of the pod, mounted with the service account token
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	// EventSource is the component of the events recorded by the scanner
+	EventSource = "production-readiness"
+	// maxEventMessage is the length of the event messages above which they are truncated, the API server rejecting
+	// the longer messages
+	maxEventMessage = 1024
+)
+
+// CurrentPod is the pod the scanner runs in, used to honour the limits of the pod and to record the outcome of the
+// runs on the Job of the pod
+type CurrentPod struct {
+	clientset kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// NewCurrentPod returns the pod the scanner runs in, nil when not running inside a cluster. The namespace is read from
+// the service account of the pod and the name from the POD_NAME environment variable, or else the hostname
+func NewCurrentPod() (*CurrentPod, error) {
+	if !inCluster() {
+		return nil, nil
+	}
+	namespace, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the namespace of the pod: %v", err)
+	}
+	name := os.Getenv("POD_NAME")
+	if name == "" {
+		if name, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("unable to read the name of the pod: %v", err)
+		}
+	}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	applyClientOptions(config, clientOptions)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewCurrentPodWith(clientset, strings.TrimSpace(string(namespace)), name), nil
+}
+
+// NewCurrentPodWith returns the pod of the namespace and name using the provided clientset
+func NewCurrentPodWith(clientset kubernetes.Interface, namespace, name string) *CurrentPod {
+	return &CurrentPod{clientset: clientset, Namespace: namespace, Name: name}
+}
+
+// EphemeralStorageLimit returns the ephemeral storage limit of the pod, the sum of the limits of its containers, 0 when
+// a container has no limit
+func (p *CurrentPod) EphemeralStorageLimit() (int64, error) {
+	pod, err := p.get()
+	if err != nil {
+		return 0, err
+	}
+	var limit int64
+	for _, container := range pod.Spec.Containers {
+		quantity, ok := container.Resources.Limits[v1.ResourceEphemeralStorage]
+		if !ok {
+			return 0, nil
+		}
+		limit += quantity.Value()
+	}
+	return limit, nil
+}
+
+// RecordJobEvent records an event of the type, v1.EventTypeNormal or v1.EventTypeWarning, on the Job owning the pod, so
+// that the outcome of each run of a CronJob is visible with kubectl describe job. The message is truncated to the
+// length the API server accepts
+func (p *CurrentPod) RecordJobEvent(eventType, reason, message string) error {
+	pod, err := p.get()
+	if err != nil {
+		return err
+	}
+	var job *metaV1.OwnerReference
+	for i, owner := range pod.OwnerReferences {
+		if owner.Kind == "Job" {
+			job = &pod.OwnerReferences[i]
+		}
+	}
+	if job == nil {
+		return fmt.Errorf("pod %s/%s is not owned by a Job", p.Namespace, p.Name)
+	}
+	if len(message) > maxEventMessage {
+		message = message[:maxEventMessage-3] + "..."
+	}
+	now := metaV1.NewTime(time.Now())
+	event := &v1.Event{
+		ObjectMeta: metaV1.ObjectMeta{GenerateName: job.Name + ".", Namespace: p.Namespace},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion: job.APIVersion,
+			Kind:       job.Kind,
+			Name:       job.Name,
+			Namespace:  p.Namespace,
+			UID:        job.UID,
+		},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		Source:         v1.EventSource{Component: EventSource},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err = p.clientset.CoreV1().Events(p.Namespace).Create(context.Background(), event, metaV1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to record the event on job %s/%s: %v", p.Namespace, job.Name, err)
+	}
+	return nil
+}
+
+func (p *CurrentPod) get() (*v1.Pod, error) {
+	pod, err := p.clientset.CoreV1().Pods(p.Namespace).Get(context.Background(), p.Name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pod %s/%s: %v", p.Namespace, p.Name, err)
+	}
+	return pod, nil
+}
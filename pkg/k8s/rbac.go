@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	authorizationV1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacV1 "k8s.io/api/rbac/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serviceAccountsGroup is the group of all the service accounts, system:serviceaccounts:<namespace> being the group of
+// the service accounts of a namespace
+const serviceAccountsGroup = "system:serviceaccounts"
+
+// RoleBinding is a RoleBinding or a ClusterRoleBinding with the rules of the role it binds
+type RoleBinding struct {
+	// Kind is RoleBinding or ClusterRoleBinding
+	Kind string
+	Name string
+	// Namespace is empty for the ClusterRoleBindings
+	Namespace string
+	RoleRef   rbacV1.RoleRef
+	Subjects  []rbacV1.Subject
+	// Rules are the rules of the bound role, empty when the role does not exist
+	Rules []rbacV1.PolicyRule
+}
+
+// BindsServiceAccount returns true when the binding grants its role to the service account of the namespace, either
+// directly or through the groups of all the service accounts, system:serviceaccounts, or of the service accounts of
+// the namespace, system:serviceaccounts:<namespace>
+func (b RoleBinding) BindsServiceAccount(namespace, name string) bool {
+	for _, subject := range b.Subjects {
+		if subject.Kind == rbacV1.GroupKind && (subject.Name == serviceAccountsGroup || subject.Name == serviceAccountsGroup+":"+namespace) {
+			return true
+		}
+		if subject.Kind != rbacV1.ServiceAccountKind || subject.Name != name {
+			continue
+		}
+		subjectNamespace := subject.Namespace
+		if subjectNamespace == "" {
+			subjectNamespace = b.Namespace
+		}
+		if subjectNamespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *kubernetesClient) GetRoleBindings(namespace string) ([]RoleBinding, error) {
+	ctx := context.Background()
+	options := metaV1.ListOptions{}
+	clusterRoles, err := k.clientset.RbacV1().ClusterRoles().List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find cluster roles: %v", err)
+	}
+	clusterRoleRules := make(map[string][]rbacV1.PolicyRule)
+	for _, role := range clusterRoles.Items {
+		clusterRoleRules[role.Name] = role.Rules
+	}
+	roles, err := k.clientset.RbacV1().Roles(namespace).List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find roles in namespace %s: %v", namespace, err)
+	}
+	roleRules := make(map[string][]rbacV1.PolicyRule)
+	for _, role := range roles.Items {
+		roleRules[role.Name] = role.Rules
+	}
+	rules := func(roleRef rbacV1.RoleRef) []rbacV1.PolicyRule {
+		if roleRef.Kind == "ClusterRole" {
+			return clusterRoleRules[roleRef.Name]
+		}
+		return roleRules[roleRef.Name]
+	}
+
+	var bindings []RoleBinding
+	roleBindings, err := k.clientset.RbacV1().RoleBindings(namespace).List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find role bindings in namespace %s: %v", namespace, err)
+	}
+	for _, binding := range roleBindings.Items {
+		bindings = append(bindings, RoleBinding{
+			Kind:      "RoleBinding",
+			Name:      binding.Name,
+			Namespace: binding.Namespace,
+			RoleRef:   binding.RoleRef,
+			Subjects:  binding.Subjects,
+			Rules:     rules(binding.RoleRef),
+		})
+	}
+	clusterRoleBindings, err := k.clientset.RbacV1().ClusterRoleBindings().List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find cluster role bindings: %v", err)
+	}
+	for _, binding := range clusterRoleBindings.Items {
+		bindings = append(bindings, RoleBinding{
+			Kind:     "ClusterRoleBinding",
+			Name:     binding.Name,
+			RoleRef:  binding.RoleRef,
+			Subjects: binding.Subjects,
+			Rules:    rules(binding.RoleRef),
+		})
+	}
+	return bindings, nil
+}
+
+// Permission is a verb on a resource of an API group, the group being empty for the core API group
+type Permission struct {
+	Verb     string
+	Group    string
+	Resource string
+}
+
+// String returns the permission as in kubectl auth can-i, for instance list deployments.apps
+func (p Permission) String() string {
+	if p.Group == "" {
+		return p.Verb + " " + p.Resource
+	}
+	return p.Verb + " " + p.Resource + "." + p.Group
+}
+
+func (k *kubernetesClient) IsAllowed(permission Permission) (bool, error) {
+	review := &authorizationV1.SelfSubjectAccessReview{
+		Spec: authorizationV1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationV1.ResourceAttributes{Verb: permission.Verb, Group: permission.Group, Resource: permission.Resource},
+		},
+	}
+	review, err := k.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), review, metaV1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to review the access to %s: %v", permission, err)
+	}
+	return review.Status.Allowed, nil
+}
+
+func (k *kubernetesClient) GetServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
+	serviceAccountList, err := k.clientset.CoreV1().ServiceAccounts(namespace).List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to find service accounts in namespace %s: %v", namespace, err)
+	}
+	return serviceAccountList.Items, nil
+}
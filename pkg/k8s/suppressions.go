@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// SuppressAnnotation suppresses findings in all the workloads of a namespace. Its value lists, separated by commas or
+// new lines, patterns of the vulnerability ids, for instance CVE-2023-4911 or CVE-2023-*, or of the readiness check
+// names, for instance probes, each optionally followed by the date it expires on, for instance CVE-2023-4911@2024-06-30.
+// A suppression applies until the end of its expiry date
+const SuppressAnnotation = "prod-readiness/suppress"
+
+// SuppressReasonAnnotation is the reason of the suppressions of a namespace, listed in the reports with them
+const SuppressReasonAnnotation = "prod-readiness/suppress-reason"
+
+// suppressionDateLayout is the layout of the expiry date of the suppressions
+const suppressionDateLayout = "2006-01-02"
+
+// Suppression is a pattern of findings suppressed in a namespace with the SuppressAnnotation
+type Suppression struct {
+	Namespace string
+	Pattern   string
+	// Expires is the time the suppression stops applying, nil when it does not expire
+	Expires *time.Time `json:",omitempty"`
+	Reason  string     `json:",omitempty"`
+}
+
+// NamespaceSuppressions returns the suppressions declared by the annotations of the namespace, the invalid entries
+// being logged and ignored
+func NamespaceSuppressions(namespace string, annotations map[string]string) []Suppression {
+	value, ok := annotations[SuppressAnnotation]
+	if !ok {
+		return nil
+	}
+	var suppressions []Suppression
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		pattern, expiry, hasExpiry := strings.Cut(strings.TrimSpace(entry), "@")
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			logr.Warnf("Ignoring invalid suppression %q of namespace %s: %v", entry, namespace, err)
+			continue
+		}
+		suppression := Suppression{Namespace: namespace, Pattern: pattern, Reason: strings.TrimSpace(annotations[SuppressReasonAnnotation])}
+		if hasExpiry {
+			date, err := time.Parse(suppressionDateLayout, strings.TrimSpace(expiry))
+			if err != nil {
+				logr.Warnf("Ignoring suppression %q of namespace %s, its expiry date is not formatted as %s", entry, namespace, suppressionDateLayout)
+				continue
+			}
+			expires := date.Add(24 * time.Hour)
+			suppression.Expires = &expires
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	return suppressions
+}
+
+// Expired returns true when the suppression no longer applies at the time
+func (s Suppression) Expired(now time.Time) bool {
+	return s.Expires != nil && !now.Before(*s.Expires)
+}
+
+// ExpiryDate returns the last day the suppression applies, formatted as in the SuppressAnnotation, empty when it does
+// not expire
+func (s Suppression) ExpiryDate() string {
+	if s.Expires == nil {
+		return ""
+	}
+	return s.Expires.Add(-24 * time.Hour).Format(suppressionDateLayout)
+}
+
+// Matches returns true when the suppression applies to the finding id at the time, a vulnerability id or a check name,
+// whatever their case
+func (s Suppression) Matches(id string, now time.Time) bool {
+	if s.Expired(now) {
+		return false
+	}
+	matched, _ := path.Match(strings.ToLower(s.Pattern), strings.ToLower(id))
+	return matched
+}
+
+// MatchingSuppression returns the first of the suppressions applying to the finding id at the time, false when none
+func MatchingSuppression(suppressions []Suppression, id string, now time.Time) (Suppression, bool) {
+	for _, suppression := range suppressions {
+		if suppression.Matches(id, now) {
+			return suppression, true
+		}
+	}
+	return Suppression{}, false
+}
+
+// SuppressionInEffect is a suppression declared by a namespace, listed in the reports with the number of findings it
+// suppressed so that the suppressions remain visible
+type SuppressionInEffect struct {
+	Suppression
+	// Expired is true when the suppression expired and no longer suppresses the findings
+	Expired         bool `json:",omitempty"`
+	SuppressedCount int
+}
+
+// SuppressionsInEffect accumulates the suppressions of the namespaces and the number of findings each suppressed
+type SuppressionsInEffect struct {
+	now          time.Time
+	suppressions map[string]*SuppressionInEffect
+}
+
+// NewSuppressionsInEffect creates SuppressionsInEffect evaluating the expiry of the suppressions at the time
+func NewSuppressionsInEffect(now time.Time) *SuppressionsInEffect {
+	return &SuppressionsInEffect{now: now, suppressions: make(map[string]*SuppressionInEffect)}
+}
+
+// Declare adds the suppressions, so that they are listed even when they suppress no finding
+func (s *SuppressionsInEffect) Declare(suppressions []Suppression) {
+	for _, suppression := range suppressions {
+		key := suppression.Namespace + "/" + suppression.Pattern
+		if _, ok := s.suppressions[key]; !ok {
+			s.suppressions[key] = &SuppressionInEffect{Suppression: suppression, Expired: suppression.Expired(s.now)}
+		}
+	}
+}
+
+// Count counts a finding suppressed by the suppression
+func (s *SuppressionsInEffect) Count(suppression Suppression) {
+	s.Declare([]Suppression{suppression})
+	s.suppressions[suppression.Namespace+"/"+suppression.Pattern].SuppressedCount++
+}
+
+// List returns the suppressions sorted by namespace and pattern
+func (s *SuppressionsInEffect) List() []SuppressionInEffect {
+	var list []SuppressionInEffect
+	for _, suppression := range s.suppressions {
+		list = append(list, *suppression)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return list[i].Pattern < list[j].Pattern
+	})
+	return list
+}
@@ -0,0 +1,305 @@
+package kubebench
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/gammazero/workerpool"
+	logr "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultImage is the kube-bench image used when none is configured
+	DefaultImage = "docker.io/aquasec/kube-bench:v0.6.15"
+
+	controlPlaneLabel       = "node-role.kubernetes.io/control-plane"
+	legacyControlPlaneLabel = "node-role.kubernetes.io/master"
+)
+
+// hostPaths are the node directories kube-bench inspects
+var hostPaths = []struct {
+	name, path, mountPath string
+}{
+	{"var-lib-etcd", "/var/lib/etcd", "/var/lib/etcd"},
+	{"var-lib-kubelet", "/var/lib/kubelet", "/var/lib/kubelet"},
+	{"var-lib-kube-proxy", "/var/lib/kube-proxy", "/var/lib/kube-proxy"},
+	{"etc-systemd", "/etc/systemd", "/etc/systemd"},
+	{"lib-systemd", "/lib/systemd", "/lib/systemd"},
+	{"etc-kubernetes", "/etc/kubernetes", "/etc/kubernetes"},
+	// kube-bench looks up the binaries versions from this directory
+	{"usr-bin", "/usr/bin", "/usr/local/mount-from-host/bin"},
+}
+
+// Config is the config used to run kube-bench
+type Config struct {
+	Image     string
+	Namespace string
+	Workers   int
+	Timeout   time.Duration
+}
+
+// KubeBench runs kube-bench on the cluster nodes to cover the node and control plane checks trivy cannot reach
+type KubeBench struct {
+	config           *Config
+	kubernetesClient k8s.KubernetesClient
+}
+
+// Output is the object representation of the kube-bench json output
+type Output struct {
+	Controls []struct {
+		ID       string `json:"id"`
+		Version  string `json:"version"`
+		Text     string `json:"text"`
+		NodeType string `json:"node_type"`
+		Tests    []struct {
+			Section string   `json:"section"`
+			Desc    string   `json:"desc"`
+			Results []Result `json:"results"`
+		} `json:"tests"`
+	} `json:"Controls"`
+}
+
+// Result is the result of a single kube-bench check
+type Result struct {
+	TestNumber     string `json:"test_number"`
+	TestDesc       string `json:"test_desc"`
+	Remediation    string `json:"remediation"`
+	Status         string `json:"status"`
+	Scored         bool   `json:"scored"`
+	ActualValue    string `json:"actual_value"`
+	ExpectedResult string `json:"expected_result"`
+	Reason         string `json:"reason"`
+}
+
+// New creates a KubeBench
+func New(kubernetesClient k8s.KubernetesClient, config *Config) *KubeBench {
+	return &KubeBench{
+		config:           config,
+		kubernetesClient: kubernetesClient,
+	}
+}
+
+// Run runs kube-bench on every node and converts the results into a compliance report
+func (k *KubeBench) Run() (*scanner.ComplianceReport, error) {
+	nodes, err := k.kubernetesClient.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes found")
+	}
+
+	var lock sync.Mutex
+	outputs := make(map[string]*Output)
+	wp := workerpool.New(k.workers())
+	for _, node := range nodes {
+		// allocate var to allow access inside the worker submission
+		node := node
+
+		wp.Submit(func() {
+			logr.Infof("Running kube-bench on node %s", node.Name)
+			output, err := k.runOnNode(node)
+			if err != nil {
+				logr.Errorf("Error running kube-bench on node %s: %v", node.Name, err)
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			outputs[node.Name] = output
+		})
+	}
+	wp.StopWait()
+
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("kube-bench did not complete on any of the %d node(s)", len(nodes))
+	}
+	return NewComplianceReport(outputs), nil
+}
+
+func (k *KubeBench) workers() int {
+	if k.config.Workers < 1 {
+		return 1
+	}
+	return k.config.Workers
+}
+
+func (k *KubeBench) runOnNode(node v1.Node) (*Output, error) {
+	logs, err := k.kubernetesClient.RunJob(k.job(node), k.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	var output Output
+	if err := json.Unmarshal(logs, &output); err != nil {
+		return nil, fmt.Errorf("error while decoding kube-bench output: %v", err)
+	}
+	return &output, nil
+}
+
+// job returns the kube-bench job pinned to the node. Control plane checks are only run on control plane nodes
+func (k *KubeBench) job(node v1.Node) *batchv1.Job {
+	targets := "node"
+	if isControlPlane(node) {
+		targets = "master,node,etcd,controlplane,policies"
+	}
+	image := k.config.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	backoffLimit := int32(0)
+	ttl := int32(600)
+
+	var volumes []v1.Volume
+	var mounts []v1.VolumeMount
+	for _, hostPath := range hostPaths {
+		volumes = append(volumes, v1.Volume{
+			Name:         hostPath.name,
+			VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: hostPath.path}},
+		})
+		mounts = append(mounts, v1.VolumeMount{Name: hostPath.name, MountPath: hostPath.mountPath, ReadOnly: true})
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName(node.Name),
+			Namespace: k.config.Namespace,
+			Labels:    map[string]string{"app.kubernetes.io/name": "kube-bench"},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					HostPID:       true,
+					NodeName:      node.Name,
+					RestartPolicy: v1.RestartPolicyNever,
+					Tolerations:   []v1.Toleration{{Operator: v1.TolerationOpExists}},
+					Containers: []v1.Container{{
+						Name:         "kube-bench",
+						Image:        image,
+						Command:      []string{"kube-bench", "run", "--targets", targets, "--json"},
+						VolumeMounts: mounts,
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+func isControlPlane(node v1.Node) bool {
+	_, controlPlane := node.Labels[controlPlaneLabel]
+	_, legacyControlPlane := node.Labels[legacyControlPlaneLabel]
+	return controlPlane || legacyControlPlane
+}
+
+// jobName returns a valid job name for the node, job names are limited to 63 characters
+func jobName(nodeName string) string {
+	name := "kube-bench-" + strings.ToLower(nodeName)
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-.")
+	}
+	return name
+}
+
+// NewComplianceReport merges the kube-bench output of each node into a single compliance report.
+// kube-bench does not rate its checks so scored checks are reported as HIGH and the others as LOW
+func NewComplianceReport(outputs map[string]*Output) *scanner.ComplianceReport {
+	var nodeNames []string
+	for nodeName := range outputs {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	var checkIDs []string
+	checks := make(map[string]*check)
+	for _, nodeName := range nodeNames {
+		for _, control := range outputs[nodeName].Controls {
+			for _, test := range control.Tests {
+				for _, result := range test.Results {
+					c, ok := checks[result.TestNumber]
+					if !ok {
+						c = &check{control: scanner.ComplianceControl{
+							ID:       result.TestNumber,
+							Name:     result.TestDesc,
+							Severity: "LOW",
+						}}
+						if result.Scored {
+							c.control.Severity = "HIGH"
+						}
+						checks[result.TestNumber] = c
+						checkIDs = append(checkIDs, result.TestNumber)
+					}
+					c.add(nodeName, result)
+				}
+			}
+		}
+	}
+
+	var controls []scanner.ComplianceControl
+	for _, id := range checkIDs {
+		controls = append(controls, checks[id].complianceControl())
+	}
+
+	report := &scanner.ComplianceReport{
+		ID:               "kube-bench",
+		Title:            "kube-bench node and control plane checks",
+		RelatedResources: []string{"https://github.com/aquasecurity/kube-bench"},
+	}
+	report.AddControls(controls...)
+	return report
+}
+
+type check struct {
+	control     scanner.ComplianceControl
+	manualCount int
+}
+
+func (c *check) add(nodeName string, result Result) {
+	switch result.Status {
+	case "PASS":
+		c.control.PassCount++
+	case "FAIL":
+		c.control.FailCount++
+		message := result.Reason
+		if message == "" && result.ActualValue != "" {
+			message = fmt.Sprintf("expected %s, found %s", result.ExpectedResult, result.ActualValue)
+		}
+		c.control.FailedResources = append(c.control.FailedResources, scanner.ComplianceResource{
+			Target: nodeName,
+			Class:  "node",
+			Misconfigurations: []scanner.ComplianceMisconfiguration{{
+				ID:         result.TestNumber,
+				Title:      result.TestDesc,
+				Message:    message,
+				Resolution: result.Remediation,
+				Severity:   c.control.Severity,
+				Status:     result.Status,
+			}},
+		})
+	default:
+		// WARN and INFO checks cannot be automated and have to be verified manually
+		c.manualCount++
+	}
+}
+
+func (c *check) complianceControl() scanner.ComplianceControl {
+	control := c.control
+	switch {
+	case control.FailCount > 0:
+		control.Status = scanner.ComplianceFail
+	case c.manualCount > 0:
+		control.Status = scanner.ComplianceManual
+	default:
+		control.Status = scanner.CompliancePass
+	}
+	return control
+}
@@ -0,0 +1,121 @@
+package kubebench
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/stretchr/testify/mock"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKubeBench(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KubeBench Suite")
+}
+
+const nodeOutput = `{"Controls": [{"id": "4", "text": "Worker Node Security Configuration", "node_type": "node", "tests": [
+  {"section": "4.1", "desc": "Worker Node Configuration Files", "results": [
+    {"test_number": "4.1.1", "test_desc": "Ensure that the kubelet service file permissions are set to 644", "status": "%s", "scored": true,
+     "remediation": "chmod 644 /etc/systemd/system/kubelet.service.d/kubeadm.conf", "expected_result": "permissions has permissions 644", "actual_value": "permissions=777"},
+    {"test_number": "4.1.2", "test_desc": "Ensure that the kubelet service file ownership is set to root:root", "status": "PASS", "scored": true},
+    {"test_number": "4.2.9", "test_desc": "Ensure that the --event-qps argument is set to 0", "status": "WARN", "scored": false}
+  ]}
+]}]}`
+
+var _ = Describe("KubeBench", func() {
+
+	var (
+		kubeBench            *KubeBench
+		mockKubernetesClient *k8stest.KubernetesClient
+	)
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		kubeBench = New(mockKubernetesClient, &Config{Namespace: "kube-system", Workers: 2, Timeout: time.Minute})
+	})
+
+	It("runs kube-bench on each node and merges the results", func() {
+		// given
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{node("node-a"), node("node-b")}, nil)
+		mockKubernetesClient.
+			On("RunJob", mock.MatchedBy(jobOn("node-a")), time.Minute).Return([]byte(fmt.Sprintf(nodeOutput, "PASS")), nil).
+			On("RunJob", mock.MatchedBy(jobOn("node-b")), time.Minute).Return([]byte(fmt.Sprintf(nodeOutput, "FAIL")), nil)
+
+		// when
+		report, err := kubeBench.Run()
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ID).To(Equal("kube-bench"))
+		Expect(report.Controls).To(HaveLen(3))
+
+		failed := report.Controls[0]
+		Expect(failed.ID).To(Equal("4.1.1"))
+		Expect(failed.Severity).To(Equal("HIGH"))
+		Expect(failed.Status).To(Equal(scanner.ComplianceFail))
+		Expect(failed.PassCount).To(Equal(1))
+		Expect(failed.FailCount).To(Equal(1))
+		Expect(failed.FailedResources).To(HaveLen(1))
+		Expect(failed.FailedResources[0].Target).To(Equal("node-b"))
+		Expect(failed.FailedResources[0].Misconfigurations[0].Message).To(Equal("expected permissions has permissions 644, found permissions=777"))
+
+		Expect(report.Controls[1].Status).To(Equal(scanner.CompliancePass))
+		Expect(report.Controls[2].Severity).To(Equal("LOW"))
+		Expect(report.Controls[2].Status).To(Equal(scanner.ComplianceManual))
+		Expect(report.Summary).To(Equal(scanner.ComplianceSummary{ControlCount: 3, PassCount: 1, FailCount: 1, ManualCount: 1}))
+	})
+
+	It("ignores the nodes where kube-bench failed", func() {
+		// given
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{node("node-a"), node("node-b")}, nil)
+		mockKubernetesClient.
+			On("RunJob", mock.MatchedBy(jobOn("node-a")), time.Minute).Return([]byte(fmt.Sprintf(nodeOutput, "PASS")), nil).
+			On("RunJob", mock.MatchedBy(jobOn("node-b")), time.Minute).Return([]byte{}, fmt.Errorf("job failed"))
+
+		// when
+		report, err := kubeBench.Run()
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Summary.FailCount).To(Equal(0))
+	})
+
+	It("returns an error when kube-bench failed on all the nodes", func() {
+		// given
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{node("node-a")}, nil)
+		mockKubernetesClient.On("RunJob", mock.Anything, time.Minute).Return([]byte("not json"), nil)
+
+		// when
+		_, err := kubeBench.Run()
+
+		// then
+		Expect(err).To(MatchError("kube-bench did not complete on any of the 1 node(s)"))
+	})
+
+	It("runs the control plane checks on control plane nodes only", func() {
+		controlPlane := node("control-plane")
+		controlPlane.Labels = map[string]string{"node-role.kubernetes.io/control-plane": ""}
+
+		Expect(kubeBench.job(controlPlane).Spec.Template.Spec.Containers[0].Command).To(ContainElement("master,node,etcd,controlplane,policies"))
+		Expect(kubeBench.job(node("worker")).Spec.Template.Spec.Containers[0].Command).To(ContainElement("node"))
+		Expect(kubeBench.job(node("worker")).Spec.Template.Spec.NodeName).To(Equal("worker"))
+	})
+})
+
+func node(name string) v1.Node {
+	return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func jobOn(nodeName string) func(*batchv1.Job) bool {
+	return func(job *batchv1.Job) bool {
+		return job.Spec.Template.Spec.NodeName == nodeName
+	}
+}
@@ -0,0 +1,526 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+	logr "github.com/sirupsen/logrus"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Config is the config used to load the manifests
+type Config struct {
+	// Path is a yaml file, a directory of yaml files or a Helm chart
+	Path string
+	// DefaultNamespace is used for the resources without namespace
+	DefaultNamespace string
+	HelmReleaseName  string
+	HelmValues       []string
+}
+
+// Manifests holds the resources defined in Kubernetes manifests. It implements k8s.KubernetesClient
+// so that the image scan and readiness checks can run before anything is deployed
+type Manifests struct {
+	workloads       []k8s.Workload
+	networkPolicies []networkingv1.NetworkPolicy
+	budgets         []policyv1.PodDisruptionBudget
+	services        []v1.Service
+	ingresses       []networkingv1.Ingress
+	autoscalers     []autoscalingv2.HorizontalPodAutoscaler
+	resources       []k8s.ResourceAPIVersions
+	namespaceLabels map[string]map[string]string
+	serviceAccounts []v1.ServiceAccount
+	roleBindings    []k8s.RoleBinding
+	// roleRules are the rules of the Roles and ClusterRoles by kind, namespace and name, see roleKey
+	roleRules map[string][]rbacv1.PolicyRule
+}
+
+// force implementation of k8s.KubernetesClient at compilation time
+var _ k8s.KubernetesClient = &Manifests{}
+
+// object is the generic representation of a manifest, the spec is decoded depending on the kind
+type object struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   metav1.ObjectMeta `json:"metadata"`
+	Spec       json.RawMessage   `json:"spec"`
+}
+
+// Load reads the manifests of the path. Helm charts are rendered with 'helm template'
+func Load(config *Config) (*Manifests, error) {
+	return load(config, execCmd.NewCommandRunner())
+}
+
+func load(config *Config, commandRunner execCmd.CommandRunner) (*Manifests, error) {
+	documents, err := readDocuments(config, commandRunner)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifests{namespaceLabels: make(map[string]map[string]string), roleRules: make(map[string][]rbacv1.PolicyRule)}
+	for _, document := range documents {
+		if err := m.add(document, config.DefaultNamespace); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.workloads {
+		m.workloads[i].NamespaceLabels = m.namespaceLabels[m.workloads[i].Namespace]
+	}
+	logr.Infof("Loaded %d workload(s) from %s", len(m.workloads), config.Path)
+	return m, nil
+}
+
+func readDocuments(config *Config, commandRunner execCmd.CommandRunner) ([][]byte, error) {
+	info, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isHelmChart(config.Path, info) {
+		rendered, err := renderHelmChart(config, commandRunner)
+		if err != nil {
+			return nil, err
+		}
+		return splitDocuments(bytes.NewReader(rendered))
+	}
+
+	var files []string
+	if info.IsDir() {
+		err = filepath.Walk(config.Path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(path)
+			if !info.IsDir() && (ext == ".yaml" || ext == ".yml" || ext == ".json") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		files = []string{config.Path}
+	}
+
+	var documents [][]byte
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileDocuments, err := splitDocuments(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifest %s: %v", file, err)
+		}
+		documents = append(documents, fileDocuments...)
+	}
+	return documents, nil
+}
+
+func isHelmChart(path string, info os.FileInfo) bool {
+	if !info.IsDir() {
+		return strings.HasSuffix(path, ".tgz")
+	}
+	_, err := os.Stat(filepath.Join(path, "Chart.yaml"))
+	return err == nil
+}
+
+func renderHelmChart(config *Config, commandRunner execCmd.CommandRunner) ([]byte, error) {
+	releaseName := config.HelmReleaseName
+	if releaseName == "" {
+		releaseName = "release"
+	}
+	args := []string{"template", releaseName, config.Path}
+	if config.DefaultNamespace != "" {
+		args = append(args, "--namespace", config.DefaultNamespace)
+	}
+	for _, values := range config.HelmValues {
+		args = append(args, "--values", values)
+	}
+
+	logr.Infof("Rendering Helm chart %s", config.Path)
+	output, errOutput, err := commandRunner.Execute("helm", args)
+	if err != nil {
+		return nil, fmt.Errorf("error while rendering Helm chart %s. Error output: %s, Error: %v", config.Path, utils.ConvertByteToString(errOutput), err)
+	}
+	return output, nil
+}
+
+func splitDocuments(r io.Reader) ([][]byte, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	var documents [][]byte
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(document)) > 0 {
+			documents = append(documents, document)
+		}
+	}
+}
+
+func (m *Manifests) add(document []byte, defaultNamespace string) error {
+	var o object
+	if err := yaml.Unmarshal(document, &o); err != nil {
+		return fmt.Errorf("error decoding manifest: %v", err)
+	}
+	if o.Kind == "" {
+		// comments only or empty document
+		return nil
+	}
+	namespace := o.Metadata.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	if o.Kind == "Namespace" {
+		m.namespaceLabels[o.Metadata.Name] = o.Metadata.Labels
+		return nil
+	}
+	m.resources = append(m.resources, k8s.ResourceAPIVersions{
+		Kind:        o.Kind,
+		Name:        o.Metadata.Name,
+		Namespace:   namespace,
+		APIVersions: []string{o.APIVersion},
+	})
+
+	if o.Kind == "NetworkPolicy" {
+		var policy networkingv1.NetworkPolicy
+		if err := yaml.Unmarshal(document, &policy); err != nil {
+			return fmt.Errorf("error decoding NetworkPolicy %s: %v", o.Metadata.Name, err)
+		}
+		policy.Namespace = namespace
+		m.networkPolicies = append(m.networkPolicies, policy)
+		return nil
+	}
+
+	switch o.Kind {
+	case "Service":
+		var service v1.Service
+		if err := yaml.Unmarshal(document, &service); err != nil {
+			return fmt.Errorf("error decoding Service %s: %v", o.Metadata.Name, err)
+		}
+		service.Namespace = namespace
+		m.services = append(m.services, service)
+		return nil
+	case "Ingress":
+		var ingress networkingv1.Ingress
+		if err := yaml.Unmarshal(document, &ingress); err != nil {
+			return fmt.Errorf("error decoding Ingress %s: %v", o.Metadata.Name, err)
+		}
+		ingress.Namespace = namespace
+		m.ingresses = append(m.ingresses, ingress)
+		return nil
+	case "HorizontalPodAutoscaler":
+		var autoscaler autoscalingv2.HorizontalPodAutoscaler
+		if err := yaml.Unmarshal(document, &autoscaler); err != nil {
+			return fmt.Errorf("error decoding HorizontalPodAutoscaler %s: %v", o.Metadata.Name, err)
+		}
+		autoscaler.Namespace = namespace
+		m.autoscalers = append(m.autoscalers, autoscaler)
+		return nil
+	case "PodDisruptionBudget":
+		var budget policyv1.PodDisruptionBudget
+		if err := yaml.Unmarshal(document, &budget); err != nil {
+			return fmt.Errorf("error decoding PodDisruptionBudget %s: %v", o.Metadata.Name, err)
+		}
+		budget.Namespace = namespace
+		m.budgets = append(m.budgets, budget)
+		return nil
+	case "ServiceAccount":
+		var serviceAccount v1.ServiceAccount
+		if err := yaml.Unmarshal(document, &serviceAccount); err != nil {
+			return fmt.Errorf("error decoding ServiceAccount %s: %v", o.Metadata.Name, err)
+		}
+		serviceAccount.Namespace = namespace
+		m.serviceAccounts = append(m.serviceAccounts, serviceAccount)
+		return nil
+	case "Role", "ClusterRole":
+		var role rbacv1.Role
+		if err := yaml.Unmarshal(document, &role); err != nil {
+			return fmt.Errorf("error decoding %s %s: %v", o.Kind, o.Metadata.Name, err)
+		}
+		if o.Kind == "ClusterRole" {
+			namespace = ""
+		}
+		m.roleRules[roleKey(o.Kind, namespace, o.Metadata.Name)] = role.Rules
+		return nil
+	case "RoleBinding", "ClusterRoleBinding":
+		var binding rbacv1.RoleBinding
+		if err := yaml.Unmarshal(document, &binding); err != nil {
+			return fmt.Errorf("error decoding %s %s: %v", o.Kind, o.Metadata.Name, err)
+		}
+		if o.Kind == "ClusterRoleBinding" {
+			namespace = ""
+		}
+		m.roleBindings = append(m.roleBindings, k8s.RoleBinding{
+			Kind:      o.Kind,
+			Name:      o.Metadata.Name,
+			Namespace: namespace,
+			RoleRef:   binding.RoleRef,
+			Subjects:  binding.Subjects,
+		})
+		return nil
+	}
+
+	template, err := podTemplate(o)
+	if err != nil {
+		return fmt.Errorf("error decoding %s %s: %v", o.Kind, o.Metadata.Name, err)
+	}
+	if template == nil {
+		return nil
+	}
+	m.workloads = append(m.workloads, k8s.Workload{
+		Kind:           o.Kind,
+		Name:           o.Metadata.Name,
+		Namespace:      namespace,
+		PodLabels:      template.Labels,
+		PodAnnotations: template.Annotations,
+		PodSpec:        template.Spec,
+		PodCount:       replicas(o),
+	})
+	return nil
+}
+
+// replicas returns the replicas of the Deployments and StatefulSets, 1 when not set and for the other workload kinds
+func replicas(o object) int {
+	if o.Kind != "Deployment" && o.Kind != "StatefulSet" {
+		return 1
+	}
+	var spec struct {
+		Replicas *int `json:"replicas"`
+	}
+	if err := json.Unmarshal(o.Spec, &spec); err != nil || spec.Replicas == nil {
+		return 1
+	}
+	return *spec.Replicas
+}
+
+// podTemplate returns the pod template of the workload kinds, nil for other kinds
+func podTemplate(o object) (*v1.PodTemplateSpec, error) {
+	switch o.Kind {
+	case "Pod":
+		var spec v1.PodSpec
+		if err := json.Unmarshal(o.Spec, &spec); err != nil {
+			return nil, err
+		}
+		return &v1.PodTemplateSpec{ObjectMeta: o.Metadata, Spec: spec}, nil
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		var spec struct {
+			Template v1.PodTemplateSpec `json:"template"`
+		}
+		if err := json.Unmarshal(o.Spec, &spec); err != nil {
+			return nil, err
+		}
+		return &spec.Template, nil
+	case "CronJob":
+		var spec struct {
+			JobTemplate batchv1.JobTemplateSpec `json:"jobTemplate"`
+		}
+		if err := json.Unmarshal(o.Spec, &spec); err != nil {
+			return nil, err
+		}
+		return &spec.JobTemplate.Spec.Template, nil
+	}
+	return nil, nil
+}
+
+// GetContainersInNamespaces returns the containers of all the workloads, label selectors are not supported
+func (m *Manifests) GetContainersInNamespaces(labelSelector string) ([]k8s.ContainerSummary, error) {
+	warnUnsupportedSelector(labelSelector)
+	var containers []k8s.ContainerSummary
+	for _, workload := range m.workloads {
+		workloadName := strings.ToLower(workload.Kind) + "/" + workload.Name
+		for _, container := range workload.PodSpec.Containers {
+			containers = append(containers, k8s.ContainerSummary{
+				Image:           container.Image,
+				ContainerName:   container.Name,
+				PodName:         workload.Name,
+				Namespace:       workload.Namespace,
+				NamespaceLabels: workload.NamespaceLabels,
+				PodLabels:       workload.PodLabels,
+				Workload:        workloadName,
+				SkipScanReason:  k8s.SkipScanReason(workload.PodAnnotations),
+			})
+		}
+		for _, container := range workload.PodSpec.InitContainers {
+			containers = append(containers, k8s.ContainerSummary{
+				Image:           container.Image,
+				ContainerName:   container.Name,
+				PodName:         workload.Name,
+				Namespace:       workload.Namespace,
+				NamespaceLabels: workload.NamespaceLabels,
+				PodLabels:       workload.PodLabels,
+				Workload:        workloadName,
+				SkipScanReason:  k8s.SkipScanReason(workload.PodAnnotations),
+				Type:            k8s.InitContainer,
+			})
+		}
+	}
+	return containers, nil
+}
+
+// GetWorkloadsInNamespaces returns all the workloads, label selectors are not supported
+func (m *Manifests) GetWorkloadsInNamespaces(labelSelector string) ([]k8s.Workload, error) {
+	warnUnsupportedSelector(labelSelector)
+	return m.workloads, nil
+}
+
+// GetNetworkPolicies returns the network policies of the namespace
+func (m *Manifests) GetNetworkPolicies(namespace string) ([]networkingv1.NetworkPolicy, error) {
+	var policies []networkingv1.NetworkPolicy
+	for _, policy := range m.networkPolicies {
+		if policy.Namespace == namespace {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+// GetServiceAccounts returns the service accounts of the namespace
+func (m *Manifests) GetServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
+	var serviceAccounts []v1.ServiceAccount
+	for _, serviceAccount := range m.serviceAccounts {
+		if serviceAccount.Namespace == namespace {
+			serviceAccounts = append(serviceAccounts, serviceAccount)
+		}
+	}
+	return serviceAccounts, nil
+}
+
+// GetRoleBindings returns the role bindings of the namespace and the cluster role bindings, with the rules of the
+// roles of the manifests they bind
+func (m *Manifests) GetRoleBindings(namespace string) ([]k8s.RoleBinding, error) {
+	var bindings []k8s.RoleBinding
+	for _, binding := range m.roleBindings {
+		if binding.Namespace != "" && binding.Namespace != namespace {
+			continue
+		}
+		roleNamespace := binding.Namespace
+		if binding.RoleRef.Kind == "ClusterRole" {
+			roleNamespace = ""
+		}
+		binding.Rules = m.roleRules[roleKey(binding.RoleRef.Kind, roleNamespace, binding.RoleRef.Name)]
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+// IsAllowed is not supported as manifests are not bound to a cluster
+func (m *Manifests) IsAllowed(permission k8s.Permission) (bool, error) {
+	return false, fmt.Errorf("no access to %s for manifests, they are not bound to a cluster", permission)
+}
+
+func roleKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// GetPodDisruptionBudgets returns the pod disruption budgets of the namespace
+func (m *Manifests) GetPodDisruptionBudgets(namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	var budgets []policyv1.PodDisruptionBudget
+	for _, budget := range m.budgets {
+		if budget.Namespace == namespace {
+			budgets = append(budgets, budget)
+		}
+	}
+	return budgets, nil
+}
+
+// GetServices returns the services of the namespace
+func (m *Manifests) GetServices(namespace string) ([]v1.Service, error) {
+	var services []v1.Service
+	for _, service := range m.services {
+		if service.Namespace == namespace {
+			services = append(services, service)
+		}
+	}
+	return services, nil
+}
+
+// GetIngresses returns the ingresses of the namespace
+func (m *Manifests) GetIngresses(namespace string) ([]networkingv1.Ingress, error) {
+	var ingresses []networkingv1.Ingress
+	for _, ingress := range m.ingresses {
+		if ingress.Namespace == namespace {
+			ingresses = append(ingresses, ingress)
+		}
+	}
+	return ingresses, nil
+}
+
+// GetHorizontalPodAutoscalers returns the horizontal pod autoscalers of the namespace
+func (m *Manifests) GetHorizontalPodAutoscalers(namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	var autoscalers []autoscalingv2.HorizontalPodAutoscaler
+	for _, autoscaler := range m.autoscalers {
+		if autoscaler.Namespace == namespace {
+			autoscalers = append(autoscalers, autoscaler)
+		}
+	}
+	return autoscalers, nil
+}
+
+// GetServerVersion is not supported as manifests are not bound to a cluster
+func (m *Manifests) GetServerVersion() (string, error) {
+	return "", fmt.Errorf("no cluster version for manifests, the target Kubernetes version has to be specified")
+}
+
+// GetResourceAPIVersions returns the API version of the resources of the namespace
+func (m *Manifests) GetResourceAPIVersions(namespace string) ([]k8s.ResourceAPIVersions, error) {
+	var resources []k8s.ResourceAPIVersions
+	for _, resource := range m.resources {
+		if resource.Namespace == namespace {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+// GetNodes returns no node as manifests are not bound to a cluster
+func (m *Manifests) GetNodes() ([]v1.Node, error) {
+	return nil, nil
+}
+
+// GetNode is not supported as manifests are not bound to a cluster
+func (m *Manifests) GetNode(name string) (*v1.Node, error) {
+	return nil, fmt.Errorf("unable to get node %s without cluster", name)
+}
+
+// RunJob is not supported as manifests are not bound to a cluster
+func (m *Manifests) RunJob(job *batchv1.Job, _ time.Duration) ([]byte, error) {
+	return nil, fmt.Errorf("unable to run job %s without cluster", job.Name)
+}
+
+// WatchContainers is not supported as manifests are not bound to a cluster
+func (m *Manifests) WatchContainers(_ context.Context, _ string, _ func([]k8s.ContainerSummary)) error {
+	return fmt.Errorf("unable to watch the containers of manifests")
+}
+
+func warnUnsupportedSelector(labelSelector string) {
+	if labelSelector != "" {
+		logr.Warnf("Namespace label selector %q is ignored when scanning manifests", labelSelector)
+	}
+}
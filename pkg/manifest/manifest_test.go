@@ -0,0 +1,306 @@
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestManifest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Manifest Suite")
+}
+
+const manifests = `# workloads
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: payments
+  labels:
+    team: a
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: payments
+spec:
+  template:
+    metadata:
+      labels:
+        app: api
+    spec:
+      initContainers:
+      - name: migrate
+        image: registry.com/api-migrations:1.0
+      containers:
+      - name: api
+        image: registry.com/api:1.0
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: cleanup
+            image: busybox
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: api
+  namespace: payments
+spec:
+  podSelector:
+    matchLabels:
+      app: api
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: payments
+`
+
+var _ = Describe("Load", func() {
+
+	var (
+		dir           string
+		commandRunner *mockCommandRunner
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "manifests")
+		Expect(err).NotTo(HaveOccurred())
+		commandRunner = &mockCommandRunner{}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("extracts the workloads and network policies of the manifests of a directory", func() {
+		Expect(os.MkdirAll(filepath.Join(dir, "nested"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "nested", "all.yaml"), []byte(manifests), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0644)).To(Succeed())
+
+		m, err := load(&Config{Path: dir, DefaultNamespace: "default"}, commandRunner)
+
+		Expect(err).NotTo(HaveOccurred())
+		workloads, _ := m.GetWorkloadsInNamespaces("")
+		Expect(workloads).To(HaveLen(2))
+		Expect(workloads[0].Kind).To(Equal("Deployment"))
+		Expect(workloads[0].NamespaceLabels).To(Equal(map[string]string{"team": "a"}))
+		Expect(workloads[0].PodLabels).To(Equal(map[string]string{"app": "api"}))
+		Expect(workloads[1].Kind).To(Equal("CronJob"))
+		Expect(workloads[1].Namespace).To(Equal("default"))
+
+		containers, _ := m.GetContainersInNamespaces("")
+		Expect(containers).To(HaveLen(3))
+		Expect(containers[0].Image).To(Equal("registry.com/api:1.0"))
+		Expect(containers[0].Workload).To(Equal("deployment/api"))
+		Expect(containers[1].Image).To(Equal("registry.com/api-migrations:1.0"))
+		Expect(containers[1].Type).To(Equal(k8s.InitContainer))
+		Expect(containers[2].Image).To(Equal("busybox"))
+		Expect(containers[2].Workload).To(Equal("cronjob/cleanup"))
+
+		policies, _ := m.GetNetworkPolicies("payments")
+		Expect(policies).To(HaveLen(1))
+		Expect(policies[0].Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"app": "api"}))
+
+		resources, _ := m.GetResourceAPIVersions("default")
+		Expect(resources).To(HaveLen(1))
+		Expect(resources[0].APIVersions).To(Equal([]string{"batch/v1beta1"}))
+		commandRunner.AssertNotCalled(GinkgoT(), "Execute", mock.Anything, mock.Anything)
+	})
+
+	It("extracts the service accounts and the role bindings with the rules of their role", func() {
+		rbac := `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: api
+automountServiceAccountToken: false
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+rules:
+- apiGroups: [""]
+  resources: ["configmaps"]
+  verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: api-reader
+roleRef:
+  kind: Role
+  name: reader
+subjects:
+- kind: ServiceAccount
+  name: api
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: other
+  namespace: other
+roleRef:
+  kind: ClusterRole
+  name: view
+`
+		Expect(os.WriteFile(filepath.Join(dir, "rbac.yaml"), []byte(rbac), 0644)).To(Succeed())
+
+		m, err := load(&Config{Path: dir, DefaultNamespace: "payments"}, commandRunner)
+
+		Expect(err).NotTo(HaveOccurred())
+		serviceAccounts, _ := m.GetServiceAccounts("payments")
+		Expect(serviceAccounts).To(HaveLen(1))
+		Expect(*serviceAccounts[0].AutomountServiceAccountToken).To(BeFalse())
+		bindings, _ := m.GetRoleBindings("payments")
+		Expect(bindings).To(HaveLen(1))
+		Expect(bindings[0].BindsServiceAccount("payments", "api")).To(BeTrue())
+		Expect(bindings[0].Rules[0].Resources).To(Equal([]string{"configmaps"}))
+	})
+
+	It("extracts the replicas of the workloads and the pod disruption budgets", func() {
+		resilience := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: api
+        image: api:1.0
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: api
+spec:
+  maxUnavailable: 1
+  selector:
+    matchLabels:
+      app: api
+`
+		Expect(os.WriteFile(filepath.Join(dir, "resilience.yaml"), []byte(resilience), 0644)).To(Succeed())
+
+		m, err := load(&Config{Path: dir, DefaultNamespace: "payments"}, commandRunner)
+
+		Expect(err).NotTo(HaveOccurred())
+		workloads, _ := m.GetWorkloadsInNamespaces("")
+		Expect(workloads[0].PodCount).To(Equal(3))
+		budgets, _ := m.GetPodDisruptionBudgets("payments")
+		Expect(budgets).To(HaveLen(1))
+		Expect(budgets[0].Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "api"}))
+	})
+
+	It("extracts the pod annotations, the services, the ingresses and the horizontal pod autoscalers", func() {
+		availability := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    metadata:
+      annotations:
+        production-readiness/autoscaling: "true"
+    spec:
+      containers:
+      - name: api
+        image: api:1.0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  selector:
+    app: api
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: api
+spec:
+  defaultBackend:
+    service:
+      name: api
+      port:
+        number: 80
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: api
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: api
+  maxReplicas: 5
+`
+		Expect(os.WriteFile(filepath.Join(dir, "availability.yaml"), []byte(availability), 0644)).To(Succeed())
+
+		m, err := load(&Config{Path: dir, DefaultNamespace: "payments"}, commandRunner)
+
+		Expect(err).NotTo(HaveOccurred())
+		workloads, _ := m.GetWorkloadsInNamespaces("")
+		Expect(workloads[0].PodAnnotations).To(Equal(map[string]string{"production-readiness/autoscaling": "true"}))
+		services, _ := m.GetServices("payments")
+		Expect(services).To(HaveLen(1))
+		Expect(services[0].Spec.Selector).To(Equal(map[string]string{"app": "api"}))
+		ingresses, _ := m.GetIngresses("payments")
+		Expect(ingresses).To(HaveLen(1))
+		Expect(ingresses[0].Spec.DefaultBackend.Service.Name).To(Equal("api"))
+		autoscalers, _ := m.GetHorizontalPodAutoscalers("payments")
+		Expect(autoscalers).To(HaveLen(1))
+		Expect(autoscalers[0].Spec.ScaleTargetRef.Name).To(Equal("api"))
+	})
+
+	It("renders Helm charts with helm template", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("name: api"), 0644)).To(Succeed())
+		commandRunner.On("Execute", "helm", []string{"template", "api", dir, "--namespace", "payments", "--values", "prod.yaml"}).
+			Return([]byte(manifests), []byte{}, nil)
+
+		m, err := load(&Config{Path: dir, DefaultNamespace: "payments", HelmReleaseName: "api", HelmValues: []string{"prod.yaml"}}, commandRunner)
+
+		Expect(err).NotTo(HaveOccurred())
+		workloads, _ := m.GetWorkloadsInNamespaces("")
+		Expect(workloads).To(HaveLen(2))
+		Expect(workloads[1].Namespace).To(Equal("payments"))
+	})
+})
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
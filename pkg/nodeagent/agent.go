@@ -0,0 +1,179 @@
+package nodeagent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// nodeScansPath is the collector endpoint the node agents report their scans to, and the scans are read from
+const nodeScansPath = "/api/v1/node-scans"
+
+// NodeScan holds the scans of the images of the containers running on a node
+type NodeScan struct {
+	NodeName string
+	// TrivyVersion is the version of trivy the images were scanned with, empty when unknown
+	TrivyVersion string
+	ScanTime     time.Time
+	Images       []ImageScan
+}
+
+// ImageScan is the trivy output of an image scanned from the container runtime storage of the node
+type ImageScan struct {
+	ImageName string
+	// ImageDigest is the digest of the image run by the containers, empty when unknown
+	ImageDigest string `json:",omitempty"`
+	Output      *scanner.TrivyOutput
+	// Error is the error of the scan, the output being nil unless the scan timed out
+	Error string `json:",omitempty"`
+}
+
+// Agent scans the images of the containers running on its node with trivy, reading the images from the container
+// runtime storage of the node rather than pulling them, and reports the scans to the collector
+type Agent struct {
+	nodeName         string
+	collectorURL     string
+	collectorToken   string
+	filterLabels     string
+	kubernetesClient k8s.KubernetesClient
+	trivyClient      scanner.TrivyClient
+	httpClient       *http.Client
+	now              func() time.Time
+}
+
+// NewAgent creates the agent of the node, scanning the images of the containers of the namespaces matching the filter
+// labels with the trivy client, whose image source is the container runtime of the node, see Config.TrivyImageSource.
+// The scans are reported with the token of the collector as bearer token. The kubernetes client should only discover
+// the pods of the node, see k8s.DiscoveryOptions.NodeName
+func NewAgent(nodeName, collectorURL, collectorToken, filterLabels string, kubernetesClient k8s.KubernetesClient, trivyClient scanner.TrivyClient) *Agent {
+	return &Agent{
+		nodeName:         nodeName,
+		collectorURL:     strings.TrimSuffix(collectorURL, "/"),
+		collectorToken:   collectorToken,
+		filterLabels:     filterLabels,
+		kubernetesClient: kubernetesClient,
+		trivyClient:      trivyClient,
+		httpClient:       &http.Client{Timeout: time.Minute},
+		now:              time.Now,
+	}
+}
+
+// Run scans the node and reports the scan to the collector every interval until the context is done. A failed scan or
+// report is logged and retried at the next interval
+func (a *Agent) Run(ctx context.Context, interval time.Duration) {
+	for {
+		if err := a.scanAndReport(ctx); err != nil && ctx.Err() == nil {
+			logr.Errorf("Error scanning node %s: %v", a.nodeName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (a *Agent) scanAndReport(ctx context.Context) error {
+	nodeScan, err := a.ScanNode(ctx)
+	if err != nil {
+		return err
+	}
+	return a.Report(ctx, nodeScan)
+}
+
+// ScanNode scans the images of the containers running on the node, once per digest
+func (a *Agent) ScanNode(ctx context.Context) (*NodeScan, error) {
+	if err := a.trivyClient.DownloadDatabase(ctx, "image"); err != nil {
+		return nil, fmt.Errorf("failed to download trivy db: %v", err)
+	}
+	containers, err := a.kubernetesClient.GetContainersInNamespaces(a.filterLabels)
+	if err != nil {
+		return nil, err
+	}
+	nodeScan := &NodeScan{NodeName: a.nodeName, ScanTime: a.now().UTC()}
+	if version, err := a.trivyClient.Version(); err != nil {
+		logr.Warnf("Unable to get the trivy version: %v", err)
+	} else {
+		nodeScan.TrivyVersion = version.Version
+	}
+
+	images := nodeImages(containers, a.nodeName)
+	logr.Infof("Scanning %d images of node %s", len(images), a.nodeName)
+	scanned := make(map[string]*scanner.TrivyOutput)
+	for _, image := range images {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if output, ok := scanned[image.ImageDigest]; ok && image.ImageDigest != "" {
+			image.Output = output
+			nodeScan.Images = append(nodeScan.Images, image)
+			continue
+		}
+		output, err := a.trivyClient.ScanImage(ctx, image.ImageName)
+		if err != nil {
+			logr.Errorf("Error scanning image %s of node %s: %v", image.ImageName, a.nodeName, err)
+			image.Error = err.Error()
+		} else {
+			scanned[image.ImageDigest] = output
+		}
+		image.Output = output
+		nodeScan.Images = append(nodeScan.Images, image)
+	}
+	return nodeScan, nil
+}
+
+// nodeImages returns the images of the containers running on the node sorted by name, with their digest
+func nodeImages(containers []k8s.ContainerSummary, nodeName string) []ImageScan {
+	digests := make(map[string]string)
+	for _, container := range containers {
+		if container.NodeName != nodeName {
+			continue
+		}
+		if digest, ok := digests[container.Image]; !ok || digest == "" {
+			digests[container.Image] = container.ImageDigest
+		}
+	}
+	var images []ImageScan
+	for imageName, digest := range digests {
+		images = append(images, ImageScan{ImageName: imageName, ImageDigest: digest})
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].ImageName < images[j].ImageName
+	})
+	return images
+}
+
+// Report sends the scan of the node to the collector
+func (a *Agent) Report(ctx context.Context, nodeScan *NodeScan) error {
+	body, err := json.Marshal(nodeScan)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.collectorURL+nodeScansPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+a.collectorToken)
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error reporting the scan of node %s to the collector: %v", a.nodeName, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		message, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("collector rejected the scan of node %s with status %d: %s", a.nodeName, response.StatusCode, message)
+	}
+	logr.Infof("Reported the scan of %d images of node %s", len(nodeScan.Images), a.nodeName)
+	return nil
+}
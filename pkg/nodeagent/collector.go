@@ -0,0 +1,137 @@
+package nodeagent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// maxNodeScanSize bounds the size of the node scans the collector accepts
+const maxNodeScanSize = 256 << 20
+
+// Collector keeps the latest scan reported by the agent of each node, so that the scan command reports the cluster
+// images with the scans of the node agents rather than pulling and scanning them
+type Collector struct {
+	token string
+	// guards the scans, reported and read concurrently
+	lock  sync.Mutex
+	scans map[string]NodeScan
+}
+
+// NewCollector creates a Collector without scan, the node scans being reported and read with the token as bearer token
+func NewCollector(token string) *Collector {
+	return &Collector{token: token, scans: make(map[string]NodeScan)}
+}
+
+// Handler returns the handler of the collector endpoints:
+//
+//	/api/v1/node-scans  POST the scan of a node, GET the latest scans of the nodes, 401 without the token
+//	/health             the liveness of the collector
+//
+// No node scan request is authorised with an empty token
+func (c *Collector) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(nodeScansPath, func(w http.ResponseWriter, r *http.Request) {
+		if !authorised(r, c.token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			c.receive(w, r)
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, c.NodeScans())
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+		}
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func (c *Collector) receive(w http.ResponseWriter, r *http.Request) {
+	var nodeScan NodeScan
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxNodeScanSize)).Decode(&nodeScan); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid node scan: "+err.Error())
+		return
+	}
+	if nodeScan.NodeName == "" {
+		writeError(w, http.StatusBadRequest, "invalid node scan: no node name")
+		return
+	}
+	c.lock.Lock()
+	c.scans[nodeScan.NodeName] = nodeScan
+	c.lock.Unlock()
+	logr.Infof("Received the scan of %d images of node %s", len(nodeScan.Images), nodeScan.NodeName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// NodeScans returns the latest scan of each node sorted by node name
+func (c *Collector) NodeScans() []NodeScan {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	scans := make([]NodeScan, 0, len(c.scans))
+	for _, nodeScan := range c.scans {
+		scans = append(scans, nodeScan)
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].NodeName < scans[j].NodeName
+	})
+	return scans
+}
+
+// FetchNodeScans reads the latest node scans of the collector at the url, for instance http://node-collector:8080,
+// with the token of the collector as bearer token
+func FetchNodeScans(ctx context.Context, collectorURL, token string) ([]NodeScan, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(collectorURL, "/")+nodeScansPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the node scans of the collector: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("error reading the node scans of the collector, status %d: %s", response.StatusCode, message)
+	}
+	var scans []NodeScan
+	if err := json.NewDecoder(response.Body).Decode(&scans); err != nil {
+		return nil, fmt.Errorf("error decoding the node scans of the collector: %v", err)
+	}
+	return scans, nil
+}
+
+// authorised returns true when the request holds the token as bearer token, compared in constant time
+func authorised(r *http.Request, token string) bool {
+	value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logr.Errorf("Error writing the response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct{ Error string }{message})
+}
@@ -0,0 +1,94 @@
+package nodeagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+type imageScans struct {
+	byDigest    map[string]nodeImageScan
+	byNodeImage map[string]nodeImageScan
+	byImage     map[string]nodeImageScan
+	version     string
+}
+
+// nodeImageScan is the scan of an image by the agent of a node, the trivy output being kept encoded so that each
+// lookup decodes its own copy for the scanner to enrich
+type nodeImageScan struct {
+	nodeName string
+	scanTime time.Time
+	output   []byte
+	err      string
+}
+
+// NewImageScanSource creates the source of the image scans of the node agents. The scans are matched with the images
+// by the digest of the running containers, or else by the node and name of the image, or else by image name only
+func NewImageScanSource(nodeScans []NodeScan) (scanner.ImageScanSource, error) {
+	scans := &imageScans{
+		byDigest:    make(map[string]nodeImageScan),
+		byNodeImage: make(map[string]nodeImageScan),
+		byImage:     make(map[string]nodeImageScan),
+	}
+	for _, nodeScan := range nodeScans {
+		if scans.version == "" {
+			scans.version = nodeScan.TrivyVersion
+		}
+		for _, image := range nodeScan.Images {
+			output, err := json.Marshal(image.Output)
+			if err != nil {
+				return nil, err
+			}
+			scan := nodeImageScan{nodeName: nodeScan.NodeName, scanTime: nodeScan.ScanTime, output: output, err: image.Error}
+			scans.byNodeImage[nodeScan.NodeName+"/"+image.ImageName] = scan
+			// the successful scans are preferred to the failed ones of the other nodes
+			if _, found := scans.byImage[image.ImageName]; !found || scan.err == "" {
+				scans.byImage[image.ImageName] = scan
+			}
+			if _, found := scans.byDigest[image.ImageDigest]; image.ImageDigest != "" && (!found || scan.err == "") {
+				scans.byDigest[image.ImageDigest] = scan
+			}
+		}
+	}
+	return scans, nil
+}
+
+func (s *imageScans) ImageScan(imageName string, containers []k8s.ContainerSummary) (*scanner.TrivyOutput, error) {
+	for _, container := range containers {
+		if scan, ok := s.byDigest[container.ImageDigest]; ok && container.ImageDigest != "" {
+			return scan.trivyOutput(imageName)
+		}
+	}
+	for _, container := range containers {
+		if scan, ok := s.byNodeImage[container.NodeName+"/"+imageName]; ok {
+			return scan.trivyOutput(imageName)
+		}
+	}
+	if scan, ok := s.byImage[imageName]; ok {
+		return scan.trivyOutput(imageName)
+	}
+	return nil, fmt.Errorf("no node agent scan for image %s", imageName)
+}
+
+func (s *imageScans) Version() string {
+	return s.version
+}
+
+// trivyOutput decodes the trivy output of the scan, with the error of the scan when it failed. The output is dated
+// with the scan of the node when trivy does not date it
+func (s nodeImageScan) trivyOutput(imageName string) (*scanner.TrivyOutput, error) {
+	var output *scanner.TrivyOutput
+	if err := json.Unmarshal(s.output, &output); err != nil {
+		return nil, err
+	}
+	if output != nil && output.CreatedAt.IsZero() {
+		output.CreatedAt = s.scanTime
+	}
+	if s.err != "" {
+		return output, fmt.Errorf("node agent of node %s failed to scan image %s: %s", s.nodeName, imageName, s.err)
+	}
+	return output, nil
+}
@@ -0,0 +1,125 @@
+package nodeagent
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner/scannertest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNodeAgent(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Node Agent Suite")
+}
+
+var _ = Describe("Node agent", func() {
+
+	var (
+		kubernetesClient *k8stest.KubernetesClient
+		trivyClient      *scannertest.TrivyClient
+		collector        *httptest.Server
+		agent            *Agent
+	)
+
+	output := func(vulnerabilityID string) *scanner.TrivyOutput {
+		return &scanner.TrivyOutput{Results: []scanner.TrivyOutputResults{
+			{Target: "debian", Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: vulnerabilityID, Severity: "HIGH"}}},
+		}}
+	}
+
+	BeforeEach(func() {
+		kubernetesClient = &k8stest.KubernetesClient{}
+		trivyClient = &scannertest.TrivyClient{}
+		collector = httptest.NewServer(NewCollector("secret").Handler())
+		DeferCleanup(collector.Close)
+		agent = NewAgent("node-1", collector.URL+"/", "secret", "area", kubernetesClient, trivyClient)
+		agent.now = func() time.Time { return time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC) }
+
+		kubernetesClient.On("GetContainersInNamespaces", "area").Return([]k8s.ContainerSummary{
+			{Image: "api:1.0", NodeName: "node-1", ImageDigest: "sha256:api"},
+			{Image: "registry/api:1.0", NodeName: "node-1", ImageDigest: "sha256:api"},
+			{Image: "web:2.0", NodeName: "node-1"},
+			{Image: "db:3.0", NodeName: "node-2"},
+		}, nil)
+		trivyClient.On("DownloadDatabase", "image").Return(nil)
+		trivyClient.On("Version").Return(&scanner.TrivyVersion{Version: "0.45.1"}, nil)
+	})
+
+	It("scans the images of the node once per digest", func() {
+		trivyClient.On("ScanImage", "api:1.0").Return(output("CVE-1"), nil)
+		trivyClient.On("ScanImage", "web:2.0").Return((*scanner.TrivyOutput)(nil), errors.New("image not found in containerd"))
+
+		nodeScan, err := agent.ScanNode(context.Background())
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodeScan.NodeName).To(Equal("node-1"))
+		Expect(nodeScan.TrivyVersion).To(Equal("0.45.1"))
+		Expect(nodeScan.Images).To(HaveLen(3))
+		Expect(nodeScan.Images[0].ImageName).To(Equal("api:1.0"))
+		Expect(nodeScan.Images[1].ImageName).To(Equal("registry/api:1.0"))
+		Expect(nodeScan.Images[1].Output).To(Equal(output("CVE-1")))
+		Expect(nodeScan.Images[2].Error).To(Equal("image not found in containerd"))
+		trivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 2)
+	})
+
+	It("reports the scans to the collector, the scan command reading them as an image scan source", func() {
+		trivyClient.On("ScanImage", "api:1.0").Return(output("CVE-1"), nil)
+		trivyClient.On("ScanImage", "web:2.0").Return(output("CVE-2"), nil)
+		nodeScan, err := agent.ScanNode(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(agent.Report(context.Background(), nodeScan)).To(Succeed())
+		nodeScans, err := FetchNodeScans(context.Background(), collector.URL, "secret")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodeScans).To(HaveLen(1))
+		Expect(nodeScans[0].ScanTime).To(Equal(agent.now()))
+
+		source, err := NewImageScanSource(nodeScans)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Version()).To(Equal("0.45.1"))
+		scan, err := source.ImageScan("mirror/api:1.0", []k8s.ContainerSummary{{Image: "mirror/api:1.0", ImageDigest: "sha256:api", NodeName: "node-3"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scan.Results[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-1"))
+		Expect(scan.CreatedAt).To(Equal(agent.now()))
+		scan, err = source.ImageScan("web:2.0", []k8s.ContainerSummary{{Image: "web:2.0", NodeName: "node-1"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scan.Results[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2"))
+		_, err = source.ImageScan("db:3.0", []k8s.ContainerSummary{{Image: "db:3.0", NodeName: "node-2"}})
+		Expect(err).To(MatchError("no node agent scan for image db:3.0"))
+	})
+
+	It("reports the failed scans of the node agents", func() {
+		source, err := NewImageScanSource([]NodeScan{{NodeName: "node-1", Images: []ImageScan{{ImageName: "web:2.0", Error: "image not found in containerd"}}}})
+		Expect(err).NotTo(HaveOccurred())
+
+		scan, err := source.ImageScan("web:2.0", []k8s.ContainerSummary{{Image: "web:2.0", NodeName: "node-1"}})
+		Expect(scan).To(BeNil())
+		Expect(err).To(MatchError("node agent of node node-1 failed to scan image web:2.0: image not found in containerd"))
+	})
+
+	It("rejects the node scan requests without the token", func() {
+		agent.collectorToken = "wrong"
+		Expect(agent.Report(context.Background(), &NodeScan{NodeName: "node-1"})).To(MatchError(ContainSubstring("status 401")))
+		_, err := FetchNodeScans(context.Background(), collector.URL, "")
+		Expect(err).To(MatchError(ContainSubstring("status 401")))
+
+		unauthenticated := httptest.NewServer(NewCollector("").Handler())
+		DeferCleanup(unauthenticated.Close)
+		_, err = FetchNodeScans(context.Background(), unauthenticated.URL, "")
+		Expect(err).To(MatchError(ContainSubstring("status 401")))
+	})
+
+	It("rejects the node scans without node name", func() {
+		agent.nodeName = ""
+		Expect(agent.Report(context.Background(), &NodeScan{})).To(MatchError(ContainSubstring("status 400")))
+	})
+})
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig is the config used to send the summaries by email
+type EmailConfig struct {
+	// Host and Port of the SMTP server, STARTTLS being used when the server supports it
+	Host string
+	Port int
+	// Username and Password authenticate with the SMTP server when the username is set
+	Username string
+	Password string
+	From     string
+	// To are the recipients of the summaries
+	To []string
+	// TeamRecipients overrides the recipients per team name when sending one summary per team
+	TeamRecipients map[string]string
+	// AreaRecipients overrides the recipients per area name, for the area summaries and the teams without recipient
+	AreaRecipients map[string]string
+}
+
+type emailNotifier struct {
+	config   *EmailConfig
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now      func() time.Time
+}
+
+// NewEmailNotifier creates a Notifier sending the html summaries by email
+func NewEmailNotifier(config *EmailConfig) Notifier {
+	return &emailNotifier{
+		config:   config,
+		sendMail: smtp.SendMail,
+		now:      time.Now,
+	}
+}
+
+func (e *emailNotifier) Notify(summary *Summary) error {
+	recipients := e.config.To
+	if recipient, ok := routeFor(summary, e.config.TeamRecipients, e.config.AreaRecipients); ok {
+		recipients = strings.Split(recipient, ";")
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+	body, err := htmlSummary(summary)
+	if err != nil {
+		return fmt.Errorf("error rendering the email: %v", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Vulnerability scan summary for "+summary.Title()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", e.now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	msg.WriteString("<html><body>\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg.WriteString("</body></html>\r\n")
+
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+	}
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	if err := e.sendMail(addr, auth, e.config.From, recipients, msg.Bytes()); err != nil {
+		return fmt.Errorf("error sending email to %s: %v", strings.Join(recipients, ", "), err)
+	}
+	return nil
+}
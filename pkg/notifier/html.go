@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// severities are the severities listed in the html summaries, from the most to the least severe
+var severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+var htmlSummaryTemplate = template.Must(template.New("summary").Parse(`<h2>Vulnerability scan summary for {{ .Summary.Title }}</h2>
+<p>{{ .Summary.ImageCount }} images / {{ .Summary.ContainerCount }} containers</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr>{{ range .Severities }}<th>{{ . }}</th>{{ end }}</tr>
+<tr>{{ range .Severities }}<td>{{ index $.Summary.TotalVulnerabilityBySeverity . }}</td>{{ end }}</tr>
+</table>
+{{- if .Summary.TopImages }}
+<h3>Top vulnerable images</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Image</th><th>CRITICAL</th><th>HIGH</th></tr>
+{{- range .Summary.TopImages }}
+<tr><td><code>{{ .ImageName }}</code></td><td>{{ index .TotalVulnerabilityBySeverity "CRITICAL" }}</td><td>{{ index .TotalVulnerabilityBySeverity "HIGH" }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .Summary.NewCriticals }}
+<h3>New critical vulnerabilities</h3>
+<ul>
+{{- range .Summary.NewCriticals }}
+<li><a href="https://nvd.nist.gov/vuln/detail/{{ .Vulnerability.VulnerabilityID }}">{{ .Vulnerability.VulnerabilityID }}</a> in <code>{{ .ImageName }}</code> ({{ .Vulnerability.PkgName }} {{ .Vulnerability.InstalledVersion }})</li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if .Summary.FailedScans }}
+<h3>Failed scans</h3>
+<ul>
+{{- range .Summary.FailedScans }}
+<li><code>{{ .ImageName }}</code>: {{ .Error }}</li>
+{{- end }}
+</ul>
+{{- end }}
+`))
+
+// htmlSummary renders the summary as an html fragment, shared by the notifiers supporting html
+func htmlSummary(summary *Summary) (string, error) {
+	var b bytes.Buffer
+	err := htmlSummaryTemplate.Execute(&b, struct {
+		Summary    *Summary
+		Severities []string
+	}{summary, severities})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MSTeamsConfig is the config used to post the summaries to Microsoft Teams
+type MSTeamsConfig struct {
+	// WebhookURL is the incoming webhook of the channel the summaries are posted to
+	WebhookURL string
+	// TeamWebhookURLs overrides the webhook per team name when sending one summary per team
+	TeamWebhookURLs map[string]string
+	// AreaWebhookURLs overrides the webhook per area name, for the area summaries and the teams without webhook
+	AreaWebhookURLs map[string]string
+}
+
+type msTeamsNotifier struct {
+	config     *MSTeamsConfig
+	httpClient *http.Client
+}
+
+// msTeamsMessage is a Microsoft Teams message card, its text supporting a subset of html
+type msTeamsMessage struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor,omitempty"`
+	Text       string `json:"text"`
+}
+
+// NewMSTeamsNotifier creates a Notifier posting the html summaries to a Microsoft Teams incoming webhook
+func NewMSTeamsNotifier(config *MSTeamsConfig) Notifier {
+	return &msTeamsNotifier{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *msTeamsNotifier) Notify(summary *Summary) error {
+	webhookURL, ok := routeFor(summary, t.config.TeamWebhookURLs, t.config.AreaWebhookURLs)
+	if !ok {
+		webhookURL = t.config.WebhookURL
+	}
+	if webhookURL == "" {
+		return nil
+	}
+	text, err := htmlSummary(summary)
+	if err != nil {
+		return fmt.Errorf("error rendering the ms teams message: %v", err)
+	}
+	message := msTeamsMessage{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: "Vulnerability scan summary for " + summary.Title(),
+		Text:    text,
+	}
+	if summary.TotalVulnerabilityBySeverity["CRITICAL"] > 0 {
+		message.ThemeColor = "D70000"
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error encoding ms teams message: %v", err)
+	}
+	resp, err := t.httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting ms teams message: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ms teams webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
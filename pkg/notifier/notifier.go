@@ -0,0 +1,175 @@
+package notifier
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// Notifier sends the summary of a scan run to an external system
+type Notifier interface {
+	Notify(summary *Summary) error
+}
+
+// Config is the config used to build the run summaries
+type Config struct {
+	TopImages int
+	PerTeam   bool
+	// PerArea builds one summary per area of the teams, ignored when PerTeam is set
+	PerArea bool
+	// FixableOnly restricts the new critical vulnerabilities to the ones with a fixed version
+	FixableOnly bool
+}
+
+// Summary is the summary of a scan run, either for the whole cluster, for an area or for a single team
+type Summary struct {
+	Area                         string
+	Team                         string
+	ImageCount                   int
+	ContainerCount               int
+	TotalVulnerabilityBySeverity map[string]int
+	TopImages                    []ImageSummary
+	NewCriticals                 []scanner.VulnerabilityFinding
+	FailedScans                  []FailedScan
+}
+
+// ImageSummary holds the vulnerability counts of an image
+type ImageSummary struct {
+	ImageName                    string
+	SeverityScore                int
+	TotalVulnerabilityBySeverity map[string]int
+}
+
+// FailedScan holds the details of an image that could not be scanned
+type FailedScan struct {
+	ImageName string
+	Error     string
+}
+
+// Title returns a human readable name for the scope of the summary
+func (s *Summary) Title() string {
+	if s.Team == "" && s.Area == "" {
+		return "all teams"
+	}
+	if s.Team == "" {
+		return s.Area
+	}
+	return fmt.Sprintf("%s - %s", s.Area, s.Team)
+}
+
+// NewSummaries builds the summaries to send for a scan run.
+// A single summary is returned unless the config requests one summary per team or per area.
+func NewSummaries(report, baseline *scanner.VulnerabilityReport, config *Config) []*Summary {
+	newCriticals := report.NewVulnerabilities(baseline, "CRITICAL")
+	if config.FixableOnly {
+		newCriticals = scanner.FixableFindings(newCriticals)
+	}
+	if !config.PerTeam && !config.PerArea {
+		return []*Summary{buildSummary("", "", report.ScannedImages, newCriticals, config.TopImages)}
+	}
+
+	var summaries []*Summary
+	for _, area := range report.AreaSummary {
+		if !config.PerTeam {
+			images := areaImages(area)
+			areaTeam := &scanner.TeamSummary{Images: images}
+			summaries = append(summaries, buildSummary(area.Name, "", images, areaTeam.Findings(newCriticals), config.TopImages))
+			continue
+		}
+		for _, team := range area.Teams {
+			summaries = append(summaries, buildSummary(area.Name, team.Name, team.Images, team.Findings(newCriticals), config.TopImages))
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Title() < summaries[j].Title()
+	})
+	return summaries
+}
+
+// NotifyAll sends the summaries using all the notifiers, logging the failures
+func NotifyAll(notifiers []Notifier, summaries []*Summary) error {
+	var failures int
+	for _, n := range notifiers {
+		for _, summary := range summaries {
+			if err := n.Notify(summary); err != nil {
+				logr.Errorf("Error sending notification for %s: %v", summary.Title(), err)
+				failures++
+			}
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d notification(s) could not be sent", failures)
+	}
+	return nil
+}
+
+// areaImages returns the images of the teams of the area, an image run by several teams being counted once
+func areaImages(area *scanner.AreaSummary) []scanner.ScannedImage {
+	teams := make([]string, 0, len(area.Teams))
+	for name := range area.Teams {
+		teams = append(teams, name)
+	}
+	sort.Strings(teams)
+	var images []scanner.ScannedImage
+	seen := make(map[string]bool)
+	for _, name := range teams {
+		for _, image := range area.Teams[name].Images {
+			if !seen[image.ImageName] {
+				seen[image.ImageName] = true
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// routeFor returns the route of the summary, the route of its team or else of its area
+func routeFor(summary *Summary, teamRoutes, areaRoutes map[string]string) (string, bool) {
+	if route, ok := teamRoutes[summary.Team]; ok && summary.Team != "" {
+		return route, true
+	}
+	if route, ok := areaRoutes[summary.Area]; ok && summary.Area != "" {
+		return route, true
+	}
+	return "", false
+}
+
+func buildSummary(area, team string, images []scanner.ScannedImage, newCriticals []scanner.VulnerabilityFinding, topImages int) *Summary {
+	summary := &Summary{
+		Area:                         area,
+		Team:                         team,
+		ImageCount:                   len(images),
+		TotalVulnerabilityBySeverity: make(map[string]int),
+		NewCriticals:                 newCriticals,
+	}
+
+	var imageSummaries []ImageSummary
+	for _, image := range images {
+		summary.ContainerCount += len(image.Containers)
+		if image.ScanError != nil {
+			summary.FailedScans = append(summary.FailedScans, FailedScan{ImageName: image.ImageName, Error: image.ScanError.Error()})
+			continue
+		}
+		for severity, count := range image.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+			summary.TotalVulnerabilityBySeverity[severity] += count
+		}
+		if image.VulnerabilitySummary.SeverityScore > 0 {
+			imageSummaries = append(imageSummaries, ImageSummary{
+				ImageName:                    image.ImageName,
+				SeverityScore:                image.VulnerabilitySummary.SeverityScore,
+				TotalVulnerabilityBySeverity: image.VulnerabilitySummary.TotalVulnerabilityBySeverity,
+			})
+		}
+	}
+
+	sort.SliceStable(imageSummaries, func(i, j int) bool {
+		return imageSummaries[i].SeverityScore > imageSummaries[j].SeverityScore
+	})
+	if len(imageSummaries) > topImages {
+		imageSummaries = imageSummaries[:topImages]
+	}
+	summary.TopImages = imageSummaries
+	return summary
+}
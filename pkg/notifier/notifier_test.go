@@ -0,0 +1,300 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNotifier(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Notifier Suite")
+}
+
+var _ = Describe("Notifier", func() {
+
+	var (
+		criticalImage, highImage, failedImage scanner.ScannedImage
+		report                                *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		criticalImage = scanner.NewScannedImage("critical:1.0", []k8s.ContainerSummary{{PodName: "pod1"}}, []scanner.TrivyOutputResults{
+			{Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"}}},
+		}, nil)
+		highImage = scanner.NewScannedImage("high:1.0", []k8s.ContainerSummary{{PodName: "pod2"}, {PodName: "pod3"}}, []scanner.TrivyOutputResults{
+			{Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"}}},
+		}, nil)
+		failedImage = scanner.NewScannedImage("failed:1.0", []k8s.ContainerSummary{{PodName: "pod4"}}, nil, fmt.Errorf("some trivy error"))
+		report = &scanner.VulnerabilityReport{
+			ScannedImages: []scanner.ScannedImage{highImage, failedImage, criticalImage},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"area1": {
+					Name: "area1",
+					Teams: map[string]*scanner.TeamSummary{
+						"team1": {Name: "team1", Images: []scanner.ScannedImage{criticalImage}},
+						"team2": {Name: "team2", Images: []scanner.ScannedImage{highImage, failedImage}},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("NewSummaries", func() {
+
+		It("builds a single summary for the whole run", func() {
+			summaries := NewSummaries(report, nil, &Config{TopImages: 1})
+
+			Expect(summaries).To(HaveLen(1))
+			Expect(summaries[0].Title()).To(Equal("all teams"))
+			Expect(summaries[0].ImageCount).To(Equal(3))
+			Expect(summaries[0].ContainerCount).To(Equal(4))
+			Expect(summaries[0].TotalVulnerabilityBySeverity).To(HaveKeyWithValue("CRITICAL", 1))
+			Expect(summaries[0].TotalVulnerabilityBySeverity).To(HaveKeyWithValue("HIGH", 1))
+			Expect(summaries[0].TopImages).To(HaveLen(1))
+			Expect(summaries[0].TopImages[0].ImageName).To(Equal("critical:1.0"))
+			Expect(summaries[0].NewCriticals).To(HaveLen(1))
+			Expect(summaries[0].FailedScans).To(Equal([]FailedScan{{ImageName: "failed:1.0", Error: "some trivy error"}}))
+		})
+
+		It("builds one summary per team", func() {
+			summaries := NewSummaries(report, nil, &Config{TopImages: 5, PerTeam: true})
+
+			Expect(summaries).To(HaveLen(2))
+			Expect(summaries[0].Title()).To(Equal("area1 - team1"))
+			Expect(summaries[0].NewCriticals).To(HaveLen(1))
+			Expect(summaries[0].FailedScans).To(BeEmpty())
+			Expect(summaries[1].Title()).To(Equal("area1 - team2"))
+			Expect(summaries[1].NewCriticals).To(BeEmpty())
+			Expect(summaries[1].FailedScans).To(HaveLen(1))
+		})
+
+		It("builds one summary per area", func() {
+			report.AreaSummary["area2"] = &scanner.AreaSummary{Name: "area2", Teams: map[string]*scanner.TeamSummary{
+				"team3": {Name: "team3", Images: []scanner.ScannedImage{criticalImage}},
+			}}
+			report.AreaSummary["area1"].Teams["team3"] = &scanner.TeamSummary{Name: "team3", Images: []scanner.ScannedImage{criticalImage}}
+
+			summaries := NewSummaries(report, nil, &Config{TopImages: 5, PerArea: true})
+
+			Expect(summaries).To(HaveLen(2))
+			Expect(summaries[0].Title()).To(Equal("area1"))
+			Expect(summaries[0].ImageCount).To(Equal(3))
+			Expect(summaries[0].NewCriticals).To(HaveLen(1))
+			Expect(summaries[1].Title()).To(Equal("area2"))
+			Expect(summaries[1].ImageCount).To(Equal(1))
+		})
+
+		It("only reports critical vulnerabilities absent from the baseline", func() {
+			baseline := &scanner.VulnerabilityReport{ScannedImages: []scanner.ScannedImage{criticalImage}}
+
+			summaries := NewSummaries(report, baseline, &Config{TopImages: 5})
+
+			Expect(summaries[0].NewCriticals).To(BeEmpty())
+		})
+
+		It("only reports critical vulnerabilities with a fixed version when requested", func() {
+			summaries := NewSummaries(report, nil, &Config{TopImages: 5, FixableOnly: true})
+
+			Expect(summaries[0].NewCriticals).To(BeEmpty())
+		})
+	})
+
+	Describe("Slack", func() {
+
+		var (
+			server   *httptest.Server
+			messages []slackMessage
+			status   int
+		)
+
+		BeforeEach(func() {
+			messages = nil
+			status = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+				var message slackMessage
+				Expect(json.Unmarshal(body, &message)).To(Succeed())
+				messages = append(messages, message)
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("posts the summary to each configured channel", func() {
+			n := NewSlackNotifier(&SlackConfig{WebhookURL: server.URL, Channels: []string{"#security", "#platform"}})
+
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5})[0])
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages).To(HaveLen(2))
+			Expect(messages[0].Channel).To(Equal("#security"))
+			Expect(messages[1].Channel).To(Equal("#platform"))
+			Expect(messages[0].Text).To(ContainSubstring("Vulnerability scan summary for all teams"))
+			Expect(messages[0].Text).To(ContainSubstring("CVE-1"))
+			Expect(messages[0].Text).To(ContainSubstring("failed:1.0"))
+		})
+
+		It("posts team summaries to the team channel", func() {
+			n := NewSlackNotifier(&SlackConfig{WebhookURL: server.URL, Channels: []string{"#security"}, TeamChannels: map[string]string{"team1": "#team1"}})
+			summaries := NewSummaries(report, nil, &Config{TopImages: 5, PerTeam: true})
+
+			Expect(NotifyAll([]Notifier{n}, summaries)).To(Succeed())
+
+			Expect(messages).To(HaveLen(2))
+			Expect(messages[0].Channel).To(Equal("#team1"))
+			Expect(messages[1].Channel).To(Equal("#security"))
+		})
+
+		It("returns an error when the webhook rejects the message", func() {
+			status = http.StatusBadRequest
+			n := NewSlackNotifier(&SlackConfig{WebhookURL: server.URL})
+
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5})[0])
+
+			Expect(err).To(MatchError(ContainSubstring("slack webhook returned status 400")))
+		})
+	})
+
+	Describe("MS Teams", func() {
+		var (
+			server   *httptest.Server
+			messages map[string][]msTeamsMessage
+			status   int
+		)
+
+		BeforeEach(func() {
+			messages = make(map[string][]msTeamsMessage)
+			status = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var message msTeamsMessage
+				Expect(json.NewDecoder(r.Body).Decode(&message)).To(Succeed())
+				messages[r.URL.Path] = append(messages[r.URL.Path], message)
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("posts the html summary as a message card", func() {
+			n := NewMSTeamsNotifier(&MSTeamsConfig{WebhookURL: server.URL + "/security"})
+
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5})[0])
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages["/security"]).To(HaveLen(1))
+			Expect(messages["/security"][0].Type).To(Equal("MessageCard"))
+			Expect(messages["/security"][0].Summary).To(Equal("Vulnerability scan summary for all teams"))
+			Expect(messages["/security"][0].ThemeColor).To(Equal("D70000"))
+			Expect(messages["/security"][0].Text).To(ContainSubstring(`<a href="https://nvd.nist.gov/vuln/detail/CVE-1">CVE-1</a> in <code>critical:1.0</code>`))
+			Expect(messages["/security"][0].Text).To(ContainSubstring("<li><code>failed:1.0</code>: some trivy error</li>"))
+		})
+
+		It("routes the summaries to the team webhook, or else to the area one", func() {
+			n := NewMSTeamsNotifier(&MSTeamsConfig{
+				TeamWebhookURLs: map[string]string{"team1": server.URL + "/team1"},
+				AreaWebhookURLs: map[string]string{"area1": server.URL + "/area1"},
+			})
+			summaries := NewSummaries(report, nil, &Config{TopImages: 5, PerTeam: true})
+
+			Expect(NotifyAll([]Notifier{n}, summaries)).To(Succeed())
+
+			Expect(messages["/team1"]).To(HaveLen(1))
+			Expect(messages["/area1"]).To(HaveLen(1))
+			Expect(messages["/area1"][0].Summary).To(Equal("Vulnerability scan summary for area1 - team2"))
+		})
+
+		It("returns an error when the webhook rejects the message", func() {
+			status = http.StatusBadRequest
+			n := NewMSTeamsNotifier(&MSTeamsConfig{WebhookURL: server.URL})
+
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5})[0])
+
+			Expect(err).To(MatchError(ContainSubstring("ms teams webhook returned status 400")))
+		})
+	})
+
+	Describe("Email", func() {
+		type sentMail struct {
+			addr string
+			auth smtp.Auth
+			from string
+			to   []string
+			msg  string
+		}
+
+		var (
+			sent []sentMail
+			n    *emailNotifier
+		)
+
+		BeforeEach(func() {
+			sent = nil
+			n = NewEmailNotifier(&EmailConfig{
+				Host:           "smtp.example.com",
+				Port:           587,
+				From:           "security@example.com",
+				To:             []string{"platform@example.com"},
+				AreaRecipients: map[string]string{"area1": "area1@example.com;leads@example.com"},
+			}).(*emailNotifier)
+			n.now = func() time.Time { return time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC) }
+			n.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+				sent = append(sent, sentMail{addr, auth, from, to, string(msg)})
+				return nil
+			}
+		})
+
+		It("sends the html summary to the recipients", func() {
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5})[0])
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sent).To(HaveLen(1))
+			Expect(sent[0].addr).To(Equal("smtp.example.com:587"))
+			Expect(sent[0].auth).To(BeNil())
+			Expect(sent[0].from).To(Equal("security@example.com"))
+			Expect(sent[0].to).To(Equal([]string{"platform@example.com"}))
+			Expect(sent[0].msg).To(ContainSubstring("Subject: Vulnerability scan summary for all teams\r\n"))
+			Expect(sent[0].msg).To(ContainSubstring("Date: Fri, 01 Sep 2023 10:00:00 +0000\r\n"))
+			Expect(sent[0].msg).To(ContainSubstring("Content-Type: text/html; charset=UTF-8\r\n"))
+			Expect(sent[0].msg).To(ContainSubstring("<h2>Vulnerability scan summary for all teams</h2>"))
+		})
+
+		It("sends the area summaries to the area recipients", func() {
+			n.config.Username = "user"
+
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5, PerArea: true})[0])
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sent[0].auth).NotTo(BeNil())
+			Expect(sent[0].to).To(Equal([]string{"area1@example.com", "leads@example.com"}))
+			Expect(sent[0].msg).To(ContainSubstring("To: area1@example.com, leads@example.com\r\n"))
+		})
+
+		It("returns an error when the email cannot be sent", func() {
+			n.sendMail = func(string, smtp.Auth, string, []string, []byte) error {
+				return fmt.Errorf("connection refused")
+			}
+
+			err := n.Notify(NewSummaries(report, nil, &Config{TopImages: 5})[0])
+
+			Expect(err).To(MatchError("error sending email to platform@example.com: connection refused"))
+		})
+	})
+})
@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackConfig is the config used to post the summaries to Slack
+type SlackConfig struct {
+	WebhookURL string
+	// Channels the summaries are posted to. The webhook default channel is used when empty
+	Channels []string
+	// TeamChannels overrides the channels per team name when sending one summary per team
+	TeamChannels map[string]string
+}
+
+type slackNotifier struct {
+	config     *SlackConfig
+	httpClient *http.Client
+}
+
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+// NewSlackNotifier creates a Notifier posting to a Slack incoming webhook
+func NewSlackNotifier(config *SlackConfig) Notifier {
+	return &slackNotifier{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *slackNotifier) Notify(summary *Summary) error {
+	text := slackText(summary)
+	for _, channel := range s.channelsFor(summary) {
+		if err := s.post(slackMessage{Channel: channel, Text: text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *slackNotifier) channelsFor(summary *Summary) []string {
+	if channel, ok := s.config.TeamChannels[summary.Team]; ok && summary.Team != "" {
+		return []string{channel}
+	}
+	if len(s.config.Channels) == 0 {
+		return []string{""}
+	}
+	return s.config.Channels
+}
+
+func (s *slackNotifier) post(message slackMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error encoding slack message: %v", err)
+	}
+
+	resp, err := s.httpClient.Post(s.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting slack message to channel %q: %v", message.Channel, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("slack webhook returned status %d for channel %q: %s", resp.StatusCode, message.Channel, string(respBody))
+	}
+	return nil
+}
+
+func slackText(summary *Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Vulnerability scan summary for %s*\n", summary.Title())
+	fmt.Fprintf(&b, "%d images / %d containers - Critical: %d, High: %d, Medium: %d, Low: %d, Unknown: %d\n",
+		summary.ImageCount, summary.ContainerCount,
+		summary.TotalVulnerabilityBySeverity["CRITICAL"], summary.TotalVulnerabilityBySeverity["HIGH"],
+		summary.TotalVulnerabilityBySeverity["MEDIUM"], summary.TotalVulnerabilityBySeverity["LOW"],
+		summary.TotalVulnerabilityBySeverity["UNKNOWN"])
+
+	if len(summary.TopImages) > 0 {
+		b.WriteString("\n*Top vulnerable images*\n")
+		for _, image := range summary.TopImages {
+			fmt.Fprintf(&b, "• `%s` - Critical: %d, High: %d\n", image.ImageName,
+				image.TotalVulnerabilityBySeverity["CRITICAL"], image.TotalVulnerabilityBySeverity["HIGH"])
+		}
+	}
+
+	if len(summary.NewCriticals) > 0 {
+		b.WriteString("\n*New critical vulnerabilities*\n")
+		for _, finding := range summary.NewCriticals {
+			fmt.Fprintf(&b, "• <https://nvd.nist.gov/vuln/detail/%s|%s> in `%s` (%s %s)\n",
+				finding.Vulnerability.VulnerabilityID, finding.Vulnerability.VulnerabilityID, finding.ImageName,
+				finding.Vulnerability.PkgName, finding.Vulnerability.InstalledVersion)
+		}
+	}
+
+	if len(summary.FailedScans) > 0 {
+		b.WriteString("\n*Failed scans*\n")
+		for _, failed := range summary.FailedScans {
+			fmt.Fprintf(&b, "• `%s`\n", failed.ImageName)
+		}
+	}
+	return b.String()
+}
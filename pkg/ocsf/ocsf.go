@@ -0,0 +1,298 @@
+package ocsf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+const (
+	// schemaVersion is the version of the OCSF schema the events are generated with
+	schemaVersion = "1.1.0"
+
+	// the Vulnerability Finding class of the Findings category, the events being created on each scan
+	categoryUID       = 2
+	classUID          = 2002
+	activityCreate    = 1
+	vulnerabilityType = classUID*100 + activityCreate
+)
+
+// Statuses of the findings, from the triage of their vulnerability, see scanner.FindingTriage
+const (
+	statusNew        = 1
+	statusInProgress = 2
+	statusSuppressed = 3
+)
+
+// VulnerabilityFinding is an OCSF Vulnerability Finding event, reporting a vulnerability of a package of an image
+type VulnerabilityFinding struct {
+	ActivityID      int                    `json:"activity_id"`
+	ActivityName    string                 `json:"activity_name"`
+	CategoryUID     int                    `json:"category_uid"`
+	CategoryName    string                 `json:"category_name"`
+	ClassUID        int                    `json:"class_uid"`
+	ClassName       string                 `json:"class_name"`
+	TypeUID         int                    `json:"type_uid"`
+	TypeName        string                 `json:"type_name"`
+	SeverityID      int                    `json:"severity_id"`
+	Severity        string                 `json:"severity"`
+	StatusID        int                    `json:"status_id"`
+	Status          string                 `json:"status"`
+	Message         string                 `json:"message"`
+	Time            int64                  `json:"time"`
+	Metadata        Metadata               `json:"metadata"`
+	FindingInfo     FindingInfo            `json:"finding_info"`
+	Vulnerabilities []VulnerabilityDetails `json:"vulnerabilities"`
+	Resources       []Resource             `json:"resources"`
+}
+
+// Metadata describes the product that produced the event
+type Metadata struct {
+	Version string   `json:"version"`
+	Product Product  `json:"product"`
+	Labels  []string `json:"labels,omitempty"`
+}
+
+// Product is the product that produced the event
+type Product struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version,omitempty"`
+}
+
+// FindingInfo identifies the finding across the scans
+type FindingInfo struct {
+	// UID identifies the vulnerability of the package of the image across the scans
+	UID           string `json:"uid"`
+	Title         string `json:"title"`
+	Desc          string `json:"desc,omitempty"`
+	FirstSeenTime int64  `json:"first_seen_time,omitempty"`
+}
+
+// VulnerabilityDetails is the vulnerability of the finding
+type VulnerabilityDetails struct {
+	CVE              *CVE              `json:"cve,omitempty"`
+	Title            string            `json:"title"`
+	Desc             string            `json:"desc,omitempty"`
+	Severity         string            `json:"severity"`
+	References       []string          `json:"references,omitempty"`
+	AffectedPackages []AffectedPackage `json:"affected_packages"`
+	IsFixAvailable   bool              `json:"is_fix_available"`
+	// IsExploitAvailable is true when the vulnerability is exploited in the wild according to the CISA catalog, or when
+	// its advisory references a published exploit
+	IsExploitAvailable bool   `json:"is_exploit_available,omitempty"`
+	VendorName         string `json:"vendor_name"`
+}
+
+// CVE is the CVE of the vulnerability with its scores
+type CVE struct {
+	UID  string `json:"uid"`
+	CVSS []CVSS `json:"cvss,omitempty"`
+	EPSS *EPSS  `json:"epss,omitempty"`
+}
+
+// CVSS is the CVSS score of the vulnerability
+type CVSS struct {
+	Version      string  `json:"version"`
+	BaseScore    float64 `json:"base_score"`
+	VectorString string  `json:"vector_string,omitempty"`
+}
+
+// EPSS is the probability of the vulnerability being exploited
+type EPSS struct {
+	Score      string  `json:"score"`
+	Percentile float64 `json:"percentile"`
+}
+
+// AffectedPackage is the package of the image the vulnerability is found in
+type AffectedPackage struct {
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	FixedInVersion string `json:"fixed_in_version,omitempty"`
+	Path           string `json:"path,omitempty"`
+}
+
+// Resource is the image the vulnerability is found in, labelled with the areas and teams running it
+type Resource struct {
+	UID    string   `json:"uid,omitempty"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// severities are the OCSF severity ids of the trivy severities, the other severities being Unknown
+var severities = map[string]int{"LOW": 2, "MEDIUM": 3, "HIGH": 4, "CRITICAL": 5}
+
+// NewVulnerabilityFindings converts the vulnerabilities of the scanned images of the report to OCSF Vulnerability
+// Finding events. The images whose scan failed have no finding
+func NewVulnerabilityFindings(report *scanner.VulnerabilityReport) []VulnerabilityFinding {
+	scanTime := report.Metadata.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now().UTC()
+	}
+	metadata := Metadata{
+		Version: schemaVersion,
+		Product: Product{Name: "Production Readiness", VendorName: "CECG", Version: report.Metadata.TrivyVersion},
+	}
+	if report.Metadata.ClusterName != "" {
+		metadata.Labels = []string{"cluster:" + report.Metadata.ClusterName}
+	}
+	labels := imageLabels(report)
+
+	findings := []VulnerabilityFinding{}
+	for _, image := range report.ScannedImages {
+		if image.ScanError != nil {
+			continue
+		}
+		resource := Resource{UID: image.Digest(), Name: image.ImageName, Type: "Container Image", Labels: labels[image.ImageName]}
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				finding := convert(resource, target.Target, vulnerability)
+				finding.Time = scanTime.UnixMilli()
+				finding.Metadata = metadata
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+func convert(resource Resource, target string, v scanner.Vulnerabilities) VulnerabilityFinding {
+	title := v.Title
+	if title == "" {
+		title = v.VulnerabilityID
+	}
+	severityID := severities[v.Severity]
+	finding := VulnerabilityFinding{
+		ActivityID:   activityCreate,
+		ActivityName: "Create",
+		CategoryUID:  categoryUID,
+		CategoryName: "Findings",
+		ClassUID:     classUID,
+		ClassName:    "Vulnerability Finding",
+		TypeUID:      vulnerabilityType,
+		TypeName:     "Vulnerability Finding: Create",
+		SeverityID:   severityID,
+		Severity:     severityName(severityID),
+		StatusID:     statusNew,
+		Status:       "New",
+		Message:      fmt.Sprintf("%s in %s %s of image %s", v.VulnerabilityID, v.PkgName, v.InstalledVersion, resource.Name),
+		FindingInfo: FindingInfo{
+			UID:   utils.NameUUID(resource.Name, target, v.VulnerabilityID, v.PkgName, v.InstalledVersion),
+			Title: title,
+			Desc:  v.Description,
+		},
+		Vulnerabilities: []VulnerabilityDetails{{
+			Title:              title,
+			Desc:               v.Description,
+			Severity:           severityName(severityID),
+			References:         v.References,
+			AffectedPackages:   []AffectedPackage{{Name: v.PkgName, Version: v.InstalledVersion, FixedInVersion: v.FixedVersion, Path: target}},
+			IsFixAvailable:     v.Fixable(),
+			IsExploitAvailable: v.KnownExploited != nil || len(v.ExploitReferences) > 0,
+			VendorName:         "Trivy",
+		}},
+		Resources: []Resource{resource},
+	}
+	if v.FirstSeen != nil {
+		finding.FindingInfo.FirstSeenTime = v.FirstSeen.UnixMilli()
+	}
+	switch {
+	case v.Triage.Dismissed():
+		finding.StatusID, finding.Status = statusSuppressed, "Suppressed"
+	case v.Triage != nil && v.Triage.State == scanner.TriageFixInProgress:
+		finding.StatusID, finding.Status = statusInProgress, "In Progress"
+	}
+	if strings.HasPrefix(v.VulnerabilityID, "CVE-") {
+		finding.Vulnerabilities[0].CVE = cve(v)
+	}
+	return finding
+}
+
+// cve returns the CVE of the vulnerability with its CVSS score, see scanner.Vulnerabilities.CVSSScore, and its EPSS
+func cve(v scanner.Vulnerabilities) *CVE {
+	cve := &CVE{UID: v.VulnerabilityID}
+	if score := v.CVSSScore(); score > 0 {
+		vector := v.CVSSVector()
+		version := "2.0"
+		if strings.HasPrefix(vector, "CVSS:") {
+			version, _, _ = strings.Cut(strings.TrimPrefix(vector, "CVSS:"), "/")
+		}
+		cve.CVSS = []CVSS{{Version: version, BaseScore: score, VectorString: vector}}
+	}
+	if v.EPSS != nil {
+		cve.EPSS = &EPSS{Score: fmt.Sprintf("%g", v.EPSS.Score), Percentile: v.EPSS.Percentile}
+	}
+	return cve
+}
+
+// severityName returns the OCSF severity caption of the severity id
+func severityName(severityID int) string {
+	switch severityID {
+	case 2:
+		return "Low"
+	case 3:
+		return "Medium"
+	case 4:
+		return "High"
+	case 5:
+		return "Critical"
+	default:
+		return "Unknown"
+	}
+}
+
+// imageLabels returns the area and team labels of the images by image name, sorted
+func imageLabels(report *scanner.VulnerabilityReport) map[string][]string {
+	labels := make(map[string][]string)
+	for areaName, area := range report.AreaSummary {
+		for teamName, team := range area.Teams {
+			for _, image := range team.Images {
+				labels[image.ImageName] = append(labels[image.ImageName], "area:"+areaName, "team:"+teamName)
+			}
+		}
+	}
+	for imageName, imageLabels := range labels {
+		sort.Strings(imageLabels)
+		labels[imageName] = dedupe(imageLabels)
+	}
+	return labels
+}
+
+// dedupe removes the consecutive duplicates of the sorted values
+func dedupe(values []string) []string {
+	var result []string
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// Save writes the findings to the file as JSON lines, one event per line as expected by the security data lakes
+func Save(findings []VulnerabilityFinding, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not write OCSF findings %s: %v", filename, err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, finding := range findings {
+		if err := encoder.Encode(finding); err != nil {
+			return fmt.Errorf("could not write OCSF findings %s: %v", filename, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("could not write OCSF findings %s: %v", filename, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,126 @@
+package ocsf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOCSF(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OCSF Suite")
+}
+
+var _ = Describe("OCSF vulnerability findings", func() {
+
+	var (
+		scanTime  = time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+		firstSeen = time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC)
+		report    *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		api := scanner.ScannedImage{
+			ImageName:  "registry/api:1",
+			Containers: []k8s.ContainerSummary{{Image: "registry/api:1", ImageDigest: "sha256:api"}},
+			TrivyOutputResults: []scanner.TrivyOutputResults{{Target: "registry/api:1 (debian 11.7)", Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-0001", PkgName: "openssl", InstalledVersion: "3.0.0", FixedVersion: "3.0.1", Severity: "CRITICAL", SeveritySource: "nvd",
+					Title: "openssl: buffer overflow", Description: "A buffer overflow", References: []string{"https://nvd.nist.gov/vuln/detail/CVE-2023-0001"},
+					CVSS:           map[string]scanner.CVSS{"nvd": {V3Score: 9.8, V3Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+					EPSS:           &scanner.EPSS{Score: 0.5, Percentile: 0.97},
+					KnownExploited: &scanner.KnownExploitedVulnerability{CveID: "CVE-2023-0001"},
+					FirstSeen:      &firstSeen},
+			}}},
+		}
+		web := scanner.ScannedImage{
+			ImageName: "registry/web:2",
+			TrivyOutputResults: []scanner.TrivyOutputResults{{Target: "app.jar", Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "GHSA-abcd-efgh-ijkl", PkgName: "log4j", InstalledVersion: "2.14", Severity: "UNKNOWN",
+					Triage: &scanner.FindingTriage{State: scanner.TriageFalsePositive}},
+			}}},
+		}
+		report = &scanner.VulnerabilityReport{
+			Metadata:      scanner.ReportMetadata{ClusterName: "prod", ScanTime: scanTime, TrivyVersion: "0.45.0"},
+			ScannedImages: []scanner.ScannedImage{api, web, {ImageName: "registry/db:3", ScanError: errors.New("timeout")}},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"finance": {Name: "finance", Teams: map[string]*scanner.TeamSummary{"payments": {Name: "payments", Images: []scanner.ScannedImage{api}}}},
+			},
+		}
+	})
+
+	It("converts the vulnerabilities of the images to vulnerability finding events", func() {
+		findings := NewVulnerabilityFindings(report)
+
+		Expect(findings).To(HaveLen(2))
+		finding := findings[0]
+		Expect(finding.ClassUID).To(Equal(2002))
+		Expect(finding.CategoryUID).To(Equal(2))
+		Expect(finding.TypeUID).To(Equal(200201))
+		Expect(finding.SeverityID).To(Equal(5))
+		Expect(finding.Severity).To(Equal("Critical"))
+		Expect(finding.Status).To(Equal("New"))
+		Expect(finding.Time).To(Equal(scanTime.UnixMilli()))
+		Expect(finding.Metadata).To(Equal(Metadata{Version: "1.1.0", Product: Product{Name: "Production Readiness", VendorName: "CECG", Version: "0.45.0"}, Labels: []string{"cluster:prod"}}))
+		Expect(finding.FindingInfo.UID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(finding.FindingInfo.Title).To(Equal("openssl: buffer overflow"))
+		Expect(finding.FindingInfo.FirstSeenTime).To(Equal(firstSeen.UnixMilli()))
+		Expect(finding.Resources).To(Equal([]Resource{{UID: "sha256:api", Name: "registry/api:1", Type: "Container Image", Labels: []string{"area:finance", "team:payments"}}}))
+		Expect(finding.Vulnerabilities).To(Equal([]VulnerabilityDetails{{
+			CVE: &CVE{
+				UID:  "CVE-2023-0001",
+				CVSS: []CVSS{{Version: "3.1", BaseScore: 9.8, VectorString: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+				EPSS: &EPSS{Score: "0.5", Percentile: 0.97},
+			},
+			Title:              "openssl: buffer overflow",
+			Desc:               "A buffer overflow",
+			Severity:           "Critical",
+			References:         []string{"https://nvd.nist.gov/vuln/detail/CVE-2023-0001"},
+			AffectedPackages:   []AffectedPackage{{Name: "openssl", Version: "3.0.0", FixedInVersion: "3.0.1", Path: "registry/api:1 (debian 11.7)"}},
+			IsFixAvailable:     true,
+			IsExploitAvailable: true,
+			VendorName:         "Trivy",
+		}}))
+
+		finding = findings[1]
+		Expect(finding.Severity).To(Equal("Unknown"))
+		Expect(finding.Status).To(Equal("Suppressed"))
+		Expect(finding.Vulnerabilities[0].CVE).To(BeNil())
+		Expect(finding.Resources[0].UID).To(BeEmpty())
+	})
+
+	It("keeps the ids of the findings across the scans", func() {
+		first := NewVulnerabilityFindings(report)
+		report.Metadata.ScanTime = scanTime.Add(24 * time.Hour)
+		second := NewVulnerabilityFindings(report)
+
+		Expect(second[0].FindingInfo.UID).To(Equal(first[0].FindingInfo.UID))
+		Expect(second[1].FindingInfo.UID).NotTo(Equal(first[0].FindingInfo.UID))
+	})
+
+	It("saves the findings as json lines", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "findings.ocsf.jsonl")
+
+		Expect(Save(NewVulnerabilityFindings(report), filename)).To(Succeed())
+
+		file, err := os.Open(filename)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+		var classes []interface{}
+		lines := bufio.NewScanner(file)
+		for lines.Scan() {
+			var event map[string]interface{}
+			Expect(json.Unmarshal(lines.Bytes(), &event)).To(Succeed())
+			classes = append(classes, event["class_name"])
+		}
+		Expect(classes).To(Equal([]interface{}{"Vulnerability Finding", "Vulnerability Finding"}))
+	})
+})
@@ -0,0 +1,129 @@
+package pushgateway
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	logr "github.com/sirupsen/logrus"
+)
+
+// DefaultJob is the job the metrics are grouped by when none is configured
+const DefaultJob = "production-readiness"
+
+// severities are the severities whose counts are pushed, including the ones without vulnerability
+var severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// Config is the config used to push the metrics of a batch run to a Prometheus Pushgateway
+type Config struct {
+	URL string
+	Job string
+	// Grouping are the labels grouping the metrics in addition to the job, for instance the cluster
+	Grouping map[string]string
+	// Username and Password authenticate with basic auth when the username is set
+	Username string
+	Password string
+}
+
+// Push pushes the metrics of the run to the Pushgateway. The metrics of a successful run replace the metrics of the
+// previous runs of the group, whereas a failed run only pushes its duration and failure so that the vulnerability
+// counts and the completion time of the last successful run are kept
+func Push(config *Config, report *scanner.VulnerabilityReport, duration time.Duration, runErr error) error {
+	job := config.Job
+	if job == "" {
+		job = DefaultJob
+	}
+	pusher := push.New(config.URL, job).Gatherer(registry(report, duration, time.Now(), runErr))
+	for name, value := range config.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	if config.Username != "" {
+		pusher = pusher.BasicAuth(config.Username, config.Password)
+	}
+
+	var err error
+	if runErr != nil {
+		err = pusher.Add()
+	} else {
+		err = pusher.Push()
+	}
+	if err != nil {
+		return fmt.Errorf("error pushing the metrics to the pushgateway %s: %v", config.URL, err)
+	}
+	logr.Infof("Pushed the metrics of the run to the pushgateway %s", config.URL)
+	return nil
+}
+
+// registry returns a registry holding the metrics of the run, only the duration and success of the run when it failed
+func registry(report *scanner.VulnerabilityReport, duration time.Duration, now time.Time, runErr error) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	success := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_success",
+		Help: "Whether the last scan succeeded (1) or failed (0)",
+	})
+	lastDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_duration_seconds",
+		Help: "Duration of the last scan in seconds",
+	})
+	reg.MustRegister(success, lastDuration)
+	lastDuration.Set(duration.Seconds())
+	if runErr != nil || report == nil {
+		return reg
+	}
+	success.Set(1)
+
+	lastCompletion := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_last_success_timestamp_seconds",
+		Help: "Time the last successful scan finished at, in seconds since the epoch",
+	})
+	images := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_images",
+		Help: "Number of images of the last scan",
+	})
+	failedImages := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_failed_images",
+		Help: "Number of images whose scan failed in the last scan",
+	})
+	vulnerabilities := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_vulnerabilities",
+		Help: "Number of vulnerabilities of the images of the last scan per severity",
+	}, []string{"severity"})
+	teamVulnerabilities := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "production_readiness_scan_team_vulnerabilities",
+		Help: "Number of vulnerabilities of the images of each team in the last scan per severity",
+	}, []string{"area", "team", "severity"})
+	reg.MustRegister(lastCompletion, images, failedImages, vulnerabilities, teamVulnerabilities)
+
+	lastCompletion.Set(float64(now.Unix()))
+	images.Set(float64(len(report.ScannedImages)))
+	failedImages.Set(float64(countBySeverity(report.ScannedImages, vulnerabilities.WithLabelValues)))
+	for _, area := range report.AreaSummary {
+		for _, team := range area.Teams {
+			countBySeverity(team.Images, func(severity ...string) prometheus.Gauge {
+				return teamVulnerabilities.WithLabelValues(area.Name, team.Name, severity[0])
+			})
+		}
+	}
+	return reg
+}
+
+// countBySeverity adds the vulnerabilities of the images to the gauge of their severity, and returns the number of
+// images whose scan failed
+func countBySeverity(images []scanner.ScannedImage, gauge func(severity ...string) prometheus.Gauge) int {
+	for _, severity := range severities {
+		gauge(severity).Set(0)
+	}
+	var failed int
+	for _, image := range images {
+		if image.ScanError != nil {
+			failed++
+			continue
+		}
+		for severity, count := range image.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+			gauge(severity).Add(float64(count))
+		}
+	}
+	return failed
+}
@@ -0,0 +1,148 @@
+package pushgateway
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPushgateway(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pushgateway Suite")
+}
+
+var _ = Describe("Pushgateway", func() {
+
+	var report *scanner.VulnerabilityReport
+
+	BeforeEach(func() {
+		criticalImage := scanner.NewScannedImage("critical:1.0", []k8s.ContainerSummary{{PodName: "pod1"}}, []scanner.TrivyOutputResults{
+			{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"},
+			}},
+		}, nil)
+		highImage := scanner.NewScannedImage("high:1.0", []k8s.ContainerSummary{{PodName: "pod2"}}, []scanner.TrivyOutputResults{
+			{Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"}}},
+		}, nil)
+		failedImage := scanner.NewScannedImage("failed:1.0", []k8s.ContainerSummary{{PodName: "pod3"}}, nil, errors.New("some trivy error"))
+		report = &scanner.VulnerabilityReport{
+			ScannedImages: []scanner.ScannedImage{criticalImage, highImage, failedImage},
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"area1": {Name: "area1", Teams: map[string]*scanner.TeamSummary{
+					"team1": {Name: "team1", Images: []scanner.ScannedImage{criticalImage, failedImage}},
+				}},
+			},
+		}
+	})
+
+	It("records the counts per severity and team, the duration and the success of the run", func() {
+		reg := registry(report, 90*time.Second, time.Unix(1693562400, 0), nil)
+
+		err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP production_readiness_scan_duration_seconds Duration of the last scan in seconds
+# TYPE production_readiness_scan_duration_seconds gauge
+production_readiness_scan_duration_seconds 90
+# HELP production_readiness_scan_failed_images Number of images whose scan failed in the last scan
+# TYPE production_readiness_scan_failed_images gauge
+production_readiness_scan_failed_images 1
+# HELP production_readiness_scan_images Number of images of the last scan
+# TYPE production_readiness_scan_images gauge
+production_readiness_scan_images 3
+# HELP production_readiness_scan_last_success_timestamp_seconds Time the last successful scan finished at, in seconds since the epoch
+# TYPE production_readiness_scan_last_success_timestamp_seconds gauge
+production_readiness_scan_last_success_timestamp_seconds 1.6935624e+09
+# HELP production_readiness_scan_success Whether the last scan succeeded (1) or failed (0)
+# TYPE production_readiness_scan_success gauge
+production_readiness_scan_success 1
+# HELP production_readiness_scan_team_vulnerabilities Number of vulnerabilities of the images of each team in the last scan per severity
+# TYPE production_readiness_scan_team_vulnerabilities gauge
+production_readiness_scan_team_vulnerabilities{area="area1",severity="CRITICAL",team="team1"} 1
+production_readiness_scan_team_vulnerabilities{area="area1",severity="HIGH",team="team1"} 1
+production_readiness_scan_team_vulnerabilities{area="area1",severity="LOW",team="team1"} 0
+production_readiness_scan_team_vulnerabilities{area="area1",severity="MEDIUM",team="team1"} 0
+production_readiness_scan_team_vulnerabilities{area="area1",severity="UNKNOWN",team="team1"} 0
+# HELP production_readiness_scan_vulnerabilities Number of vulnerabilities of the images of the last scan per severity
+# TYPE production_readiness_scan_vulnerabilities gauge
+production_readiness_scan_vulnerabilities{severity="CRITICAL"} 1
+production_readiness_scan_vulnerabilities{severity="HIGH"} 2
+production_readiness_scan_vulnerabilities{severity="LOW"} 0
+production_readiness_scan_vulnerabilities{severity="MEDIUM"} 0
+production_readiness_scan_vulnerabilities{severity="UNKNOWN"} 0
+`))
+
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("only records the duration and the failure of a failed run", func() {
+		reg := registry(nil, 30*time.Second, time.Now(), errors.New("unable to list the pods"))
+
+		count, err := testutil.GatherAndCount(reg)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(2))
+		Expect(testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP production_readiness_scan_success Whether the last scan succeeded (1) or failed (0)
+# TYPE production_readiness_scan_success gauge
+production_readiness_scan_success 0
+`), "production_readiness_scan_success")).To(Succeed())
+	})
+
+	Describe("Push", func() {
+		var (
+			server   *httptest.Server
+			requests []*http.Request
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				w.WriteHeader(http.StatusOK)
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("replaces the metrics of the group with the metrics of a successful run", func() {
+			config := &Config{URL: server.URL, Grouping: map[string]string{"cluster": "prod"}, Username: "user", Password: "secret"}
+
+			Expect(Push(config, report, time.Minute, nil)).To(Succeed())
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodPut))
+			Expect(requests[0].URL.Path).To(Equal("/metrics/job/production-readiness/cluster/prod"))
+			username, password, ok := requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("user"))
+			Expect(password).To(Equal("secret"))
+		})
+
+		It("adds the metrics of a failed run to the ones of the group", func() {
+			Expect(Push(&Config{URL: server.URL, Job: "nightly-scan"}, nil, time.Minute, errors.New("unable to list the pods"))).To(Succeed())
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodPost))
+			Expect(requests[0].URL.Path).To(Equal("/metrics/job/nightly-scan"))
+		})
+
+		It("returns an error when the pushgateway rejects the metrics", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			})
+
+			err := Push(&Config{URL: server.URL}, report, time.Minute, nil)
+
+			Expect(err).To(MatchError(ContainSubstring("error pushing the metrics to the pushgateway " + server.URL)))
+		})
+	})
+})
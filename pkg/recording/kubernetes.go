@@ -0,0 +1,222 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	autoscalingV2 "k8s.io/api/autoscaling/v2"
+	batchV1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	policyV1 "k8s.io/api/policy/v1"
+)
+
+type recordingKubernetesClient struct {
+	client   k8s.KubernetesClient
+	recorder *Recorder
+}
+
+// KubernetesClient wraps the client to record its responses. The watched containers are not recorded
+func (r *Recorder) KubernetesClient(client k8s.KubernetesClient) k8s.KubernetesClient {
+	return &recordingKubernetesClient{client: client, recorder: r}
+}
+
+func (k *recordingKubernetesClient) GetContainersInNamespaces(labelSelector string) ([]k8s.ContainerSummary, error) {
+	containers, err := k.client.GetContainersInNamespaces(labelSelector)
+	k.recorder.save(&entry{}, containers, err, kubernetesDir, "GetContainersInNamespaces", labelSelector)
+	return containers, err
+}
+
+func (k *recordingKubernetesClient) GetWorkloadsInNamespaces(labelSelector string) ([]k8s.Workload, error) {
+	workloads, err := k.client.GetWorkloadsInNamespaces(labelSelector)
+	k.recorder.save(&entry{}, workloads, err, kubernetesDir, "GetWorkloadsInNamespaces", labelSelector)
+	return workloads, err
+}
+
+func (k *recordingKubernetesClient) GetNetworkPolicies(namespace string) ([]networkingV1.NetworkPolicy, error) {
+	policies, err := k.client.GetNetworkPolicies(namespace)
+	k.recorder.save(&entry{}, policies, err, kubernetesDir, "GetNetworkPolicies", namespace)
+	return policies, err
+}
+
+func (k *recordingKubernetesClient) GetPodDisruptionBudgets(namespace string) ([]policyV1.PodDisruptionBudget, error) {
+	budgets, err := k.client.GetPodDisruptionBudgets(namespace)
+	k.recorder.save(&entry{}, budgets, err, kubernetesDir, "GetPodDisruptionBudgets", namespace)
+	return budgets, err
+}
+
+func (k *recordingKubernetesClient) GetServices(namespace string) ([]v1.Service, error) {
+	services, err := k.client.GetServices(namespace)
+	k.recorder.save(&entry{}, services, err, kubernetesDir, "GetServices", namespace)
+	return services, err
+}
+
+func (k *recordingKubernetesClient) GetIngresses(namespace string) ([]networkingV1.Ingress, error) {
+	ingresses, err := k.client.GetIngresses(namespace)
+	k.recorder.save(&entry{}, ingresses, err, kubernetesDir, "GetIngresses", namespace)
+	return ingresses, err
+}
+
+func (k *recordingKubernetesClient) GetHorizontalPodAutoscalers(namespace string) ([]autoscalingV2.HorizontalPodAutoscaler, error) {
+	autoscalers, err := k.client.GetHorizontalPodAutoscalers(namespace)
+	k.recorder.save(&entry{}, autoscalers, err, kubernetesDir, "GetHorizontalPodAutoscalers", namespace)
+	return autoscalers, err
+}
+
+func (k *recordingKubernetesClient) GetServerVersion() (string, error) {
+	version, err := k.client.GetServerVersion()
+	k.recorder.save(&entry{}, version, err, kubernetesDir, "GetServerVersion")
+	return version, err
+}
+
+func (k *recordingKubernetesClient) GetResourceAPIVersions(namespace string) ([]k8s.ResourceAPIVersions, error) {
+	versions, err := k.client.GetResourceAPIVersions(namespace)
+	k.recorder.save(&entry{}, versions, err, kubernetesDir, "GetResourceAPIVersions", namespace)
+	return versions, err
+}
+
+func (k *recordingKubernetesClient) GetNodes() ([]v1.Node, error) {
+	nodes, err := k.client.GetNodes()
+	k.recorder.save(&entry{}, nodes, err, kubernetesDir, "GetNodes")
+	return nodes, err
+}
+
+func (k *recordingKubernetesClient) GetNode(name string) (*v1.Node, error) {
+	node, err := k.client.GetNode(name)
+	k.recorder.save(&entry{}, node, err, kubernetesDir, "GetNode", name)
+	return node, err
+}
+
+func (k *recordingKubernetesClient) GetServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
+	serviceAccounts, err := k.client.GetServiceAccounts(namespace)
+	k.recorder.save(&entry{}, serviceAccounts, err, kubernetesDir, "GetServiceAccounts", namespace)
+	return serviceAccounts, err
+}
+
+func (k *recordingKubernetesClient) GetRoleBindings(namespace string) ([]k8s.RoleBinding, error) {
+	bindings, err := k.client.GetRoleBindings(namespace)
+	k.recorder.save(&entry{}, bindings, err, kubernetesDir, "GetRoleBindings", namespace)
+	return bindings, err
+}
+
+func (k *recordingKubernetesClient) IsAllowed(permission k8s.Permission) (bool, error) {
+	allowed, err := k.client.IsAllowed(permission)
+	k.recorder.save(&entry{}, allowed, err, kubernetesDir, "IsAllowed", permission.String())
+	return allowed, err
+}
+
+// RunJob records the logs of the job by job name
+func (k *recordingKubernetesClient) RunJob(job *batchV1.Job, timeout time.Duration) ([]byte, error) {
+	logs, err := k.client.RunJob(job, timeout)
+	k.recorder.save(&entry{}, logs, err, kubernetesDir, "RunJob", job.Name)
+	return logs, err
+}
+
+func (k *recordingKubernetesClient) WatchContainers(ctx context.Context, labelSelector string, onContainers func([]k8s.ContainerSummary)) error {
+	return k.client.WatchContainers(ctx, labelSelector, onContainers)
+}
+
+type replayKubernetesClient struct {
+	replayer *Replayer
+}
+
+// KubernetesClient returns a client replaying the recorded responses, the calls not recorded returning an error
+func (r *Replayer) KubernetesClient() k8s.KubernetesClient {
+	return &replayKubernetesClient{replayer: r}
+}
+
+func (k *replayKubernetesClient) GetContainersInNamespaces(labelSelector string) ([]k8s.ContainerSummary, error) {
+	var containers []k8s.ContainerSummary
+	err := k.replayer.load(&containers, kubernetesDir, "GetContainersInNamespaces", labelSelector)
+	return containers, err
+}
+
+func (k *replayKubernetesClient) GetWorkloadsInNamespaces(labelSelector string) ([]k8s.Workload, error) {
+	var workloads []k8s.Workload
+	err := k.replayer.load(&workloads, kubernetesDir, "GetWorkloadsInNamespaces", labelSelector)
+	return workloads, err
+}
+
+func (k *replayKubernetesClient) GetNetworkPolicies(namespace string) ([]networkingV1.NetworkPolicy, error) {
+	var policies []networkingV1.NetworkPolicy
+	err := k.replayer.load(&policies, kubernetesDir, "GetNetworkPolicies", namespace)
+	return policies, err
+}
+
+func (k *replayKubernetesClient) GetPodDisruptionBudgets(namespace string) ([]policyV1.PodDisruptionBudget, error) {
+	var budgets []policyV1.PodDisruptionBudget
+	err := k.replayer.load(&budgets, kubernetesDir, "GetPodDisruptionBudgets", namespace)
+	return budgets, err
+}
+
+func (k *replayKubernetesClient) GetServices(namespace string) ([]v1.Service, error) {
+	var services []v1.Service
+	err := k.replayer.load(&services, kubernetesDir, "GetServices", namespace)
+	return services, err
+}
+
+func (k *replayKubernetesClient) GetIngresses(namespace string) ([]networkingV1.Ingress, error) {
+	var ingresses []networkingV1.Ingress
+	err := k.replayer.load(&ingresses, kubernetesDir, "GetIngresses", namespace)
+	return ingresses, err
+}
+
+func (k *replayKubernetesClient) GetHorizontalPodAutoscalers(namespace string) ([]autoscalingV2.HorizontalPodAutoscaler, error) {
+	var autoscalers []autoscalingV2.HorizontalPodAutoscaler
+	err := k.replayer.load(&autoscalers, kubernetesDir, "GetHorizontalPodAutoscalers", namespace)
+	return autoscalers, err
+}
+
+func (k *replayKubernetesClient) GetServerVersion() (string, error) {
+	var version string
+	err := k.replayer.load(&version, kubernetesDir, "GetServerVersion")
+	return version, err
+}
+
+func (k *replayKubernetesClient) GetResourceAPIVersions(namespace string) ([]k8s.ResourceAPIVersions, error) {
+	var versions []k8s.ResourceAPIVersions
+	err := k.replayer.load(&versions, kubernetesDir, "GetResourceAPIVersions", namespace)
+	return versions, err
+}
+
+func (k *replayKubernetesClient) GetNodes() ([]v1.Node, error) {
+	var nodes []v1.Node
+	err := k.replayer.load(&nodes, kubernetesDir, "GetNodes")
+	return nodes, err
+}
+
+func (k *replayKubernetesClient) GetNode(name string) (*v1.Node, error) {
+	var node *v1.Node
+	err := k.replayer.load(&node, kubernetesDir, "GetNode", name)
+	return node, err
+}
+
+func (k *replayKubernetesClient) GetServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
+	var serviceAccounts []v1.ServiceAccount
+	err := k.replayer.load(&serviceAccounts, kubernetesDir, "GetServiceAccounts", namespace)
+	return serviceAccounts, err
+}
+
+func (k *replayKubernetesClient) GetRoleBindings(namespace string) ([]k8s.RoleBinding, error) {
+	var bindings []k8s.RoleBinding
+	err := k.replayer.load(&bindings, kubernetesDir, "GetRoleBindings", namespace)
+	return bindings, err
+}
+
+func (k *replayKubernetesClient) IsAllowed(permission k8s.Permission) (bool, error) {
+	var allowed bool
+	err := k.replayer.load(&allowed, kubernetesDir, "IsAllowed", permission.String())
+	return allowed, err
+}
+
+func (k *replayKubernetesClient) RunJob(job *batchV1.Job, _ time.Duration) ([]byte, error) {
+	var logs []byte
+	err := k.replayer.load(&logs, kubernetesDir, "RunJob", job.Name)
+	return logs, err
+}
+
+func (k *replayKubernetesClient) WatchContainers(_ context.Context, _ string, _ func([]k8s.ContainerSummary)) error {
+	return errors.New("the cluster containers cannot be watched when replaying a recording")
+}
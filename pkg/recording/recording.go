@@ -0,0 +1,132 @@
+// Package recording records the responses of the Kubernetes, trivy and docker clients to a directory, and replays
+// them later on, so that the reports can be generated without cluster access, trivy nor docker
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// Directories of the recordings of each client
+const (
+	kubernetesDir = "kubernetes"
+	trivyDir      = "trivy"
+	dockerDir     = "docker"
+)
+
+// entry is the recorded response of a call, one json file per call
+type entry struct {
+	// Value is the json of the value returned by the call
+	Value json.RawMessage `json:",omitempty"`
+	// Error is the message of the error returned by the call, empty when the call succeeded
+	Error string `json:",omitempty"`
+	// Timeout is the timeout of the trivy scans that timed out, see scanner.ScanTimeoutError
+	Timeout time.Duration `json:",omitempty"`
+}
+
+// Recorder records the responses of the clients it wraps to a directory
+type Recorder struct {
+	dir string
+}
+
+// NewRecorder creates a Recorder recording the responses to the directory, created if needed
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{dir: dir}
+}
+
+// Replayer replays the responses recorded by a Recorder in a directory
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer creates a Replayer replaying the responses recorded in the directory
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// ClusterName records the name of the cluster and returns it
+func (r *Recorder) ClusterName(name string) string {
+	r.save(&entry{}, name, nil, kubernetesDir, "ClusterName")
+	return name
+}
+
+// ClusterName returns the recorded name of the cluster, empty when not recorded
+func (r *Replayer) ClusterName() string {
+	var name string
+	if err := r.load(&name, kubernetesDir, "ClusterName"); err != nil {
+		logr.Warnf("Unable to replay the cluster name: %v", err)
+	}
+	return name
+}
+
+// save records the value and the error returned by the call of the method of the client with the arguments.
+// Failing to record is only logged so that the recording never fails the scan
+func (r *Recorder) save(e *entry, value any, callErr error, client, method string, args ...string) {
+	file := callFile(r.dir, client, method, args)
+	if callErr != nil {
+		e.Error = callErr.Error()
+	}
+	if err := writeEntry(file, e, value); err != nil {
+		logr.Warnf("Unable to record %s: %v", file, err)
+	}
+}
+
+func writeEntry(file string, e *entry, value any) error {
+	var err error
+	if e.Value, err = json.Marshal(value); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0o644)
+}
+
+// load unmarshals the value recorded for the call of the method of the client with the arguments into value,
+// returning the recorded error if any
+func (r *Replayer) load(value any, client, method string, args ...string) error {
+	_, err := r.loadEntry(value, client, method, args...)
+	return err
+}
+
+func (r *Replayer) loadEntry(value any, client, method string, args ...string) (*entry, error) {
+	file := callFile(r.dir, client, method, args)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("no recording of %s %s %v in %s: %v", client, method, args, r.dir, err)
+	}
+	e := &entry{}
+	if err := json.Unmarshal(content, e); err != nil {
+		return nil, fmt.Errorf("unable to read the recording %s: %v", file, err)
+	}
+	if len(e.Value) > 0 {
+		if err := json.Unmarshal(e.Value, value); err != nil {
+			return nil, fmt.Errorf("unable to read the recording %s: %v", file, err)
+		}
+	}
+	if e.Error != "" {
+		return e, errors.New(e.Error)
+	}
+	return e, nil
+}
+
+// callFile returns the file recording the call of the method with the arguments, for instance
+// trivy/ScanImage_nginx%3A1.25.json
+func callFile(dir, client, method string, args []string) string {
+	name := method
+	for _, arg := range args {
+		name += "_" + url.QueryEscape(arg)
+	}
+	return filepath.Join(dir, client, name+".json")
+}
@@ -0,0 +1,95 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner/scannertest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRecording(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Recording Suite")
+}
+
+var _ = Describe("Recording", func() {
+
+	var (
+		dir      string
+		recorder *Recorder
+		replayer *Replayer
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		recorder = NewRecorder(dir)
+		replayer = NewReplayer(dir)
+	})
+
+	It("replays the recorded cluster responses", func() {
+		// given
+		kubernetesClient := &k8stest.KubernetesClient{}
+		containers := []k8s.ContainerSummary{{Image: "nginx:1.25", PodName: "web-1", Namespace: "payments", NamespaceLabels: map[string]string{"team": "payments"}}}
+		kubernetesClient.On("GetContainersInNamespaces", "team").Return(containers, nil)
+		kubernetesClient.On("GetServerVersion").Return("", errors.New("forbidden"))
+		recording := recorder.KubernetesClient(kubernetesClient)
+		_, _ = recording.GetContainersInNamespaces("team")
+		_, _ = recording.GetServerVersion()
+		recorder.ClusterName("sandbox")
+
+		// when
+		replay := replayer.KubernetesClient()
+		replayedContainers, err := replay.GetContainersInNamespaces("team")
+		_, versionErr := replay.GetServerVersion()
+		_, notRecordedErr := replay.GetContainersInNamespaces("area")
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayedContainers).To(Equal(containers))
+		Expect(versionErr).To(MatchError("forbidden"))
+		Expect(notRecordedErr).To(MatchError(ContainSubstring("no recording of kubernetes GetContainersInNamespaces [area]")))
+		Expect(replayer.ClusterName()).To(Equal("sandbox"))
+	})
+
+	It("replays the recorded trivy and docker responses", func() {
+		// given
+		trivyClient := &scannertest.TrivyClient{}
+		dockerClient := &scannertest.DockerClient{}
+		output := &scanner.TrivyOutput{Results: []scanner.TrivyOutputResults{{Target: "nginx:1.25 (debian 12.1)", Vulnerabilities: []scanner.Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-5363", PkgName: "openssl", Severity: "HIGH"},
+		}}}}
+		trivyClient.On("ScanImage", "nginx:1.25").Return(output, nil)
+		trivyClient.On("ScanImage", "huge:1.0").Return(output, &scanner.ScanTimeoutError{Image: "huge:1.0", Timeout: time.Minute})
+		dockerClient.On("PullImage", "nginx:1.25").Return(nil)
+		dockerClient.On("PullImage", "private:1.0").Return(errors.New("unauthorized"))
+		recordingTrivy := recorder.TrivyClient(trivyClient)
+		recordingDocker := recorder.DockerClient(dockerClient)
+		_, _ = recordingTrivy.ScanImage(context.Background(), "nginx:1.25")
+		_, _ = recordingTrivy.ScanImage(context.Background(), "huge:1.0")
+		_ = recordingDocker.PullImage(context.Background(), "nginx:1.25")
+		_ = recordingDocker.PullImage(context.Background(), "private:1.0")
+
+		// when
+		replayTrivy := replayer.TrivyClient()
+		replayDocker := replayer.DockerClient()
+		replayedOutput, err := replayTrivy.ScanImage(context.Background(), "nginx:1.25")
+		partialOutput, timeoutErr := replayTrivy.ScanImage(context.Background(), "huge:1.0")
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayedOutput).To(Equal(output))
+		Expect(partialOutput).To(Equal(output))
+		Expect(timeoutErr).To(Equal(&scanner.ScanTimeoutError{Image: "huge:1.0", Timeout: time.Minute}))
+		Expect(replayDocker.PullImage(context.Background(), "nginx:1.25")).To(Succeed())
+		Expect(replayDocker.PullImage(context.Background(), "private:1.0")).To(MatchError("unauthorized"))
+		Expect(replayTrivy.DownloadDatabase(context.Background(), "image")).To(Succeed())
+	})
+})
@@ -0,0 +1,179 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+type recordingTrivyClient struct {
+	client   scanner.TrivyClient
+	recorder *Recorder
+}
+
+// TrivyClient wraps the client to record its responses. The database downloads are not recorded
+func (r *Recorder) TrivyClient(client scanner.TrivyClient) scanner.TrivyClient {
+	return &recordingTrivyClient{client: client, recorder: r}
+}
+
+func (t *recordingTrivyClient) DownloadDatabase(ctx context.Context, cmd string) error {
+	return t.client.DownloadDatabase(ctx, cmd)
+}
+
+// ScanImage records the partial output and the timeout of the scans that timed out, replayed as a scanner.ScanTimeoutError
+func (t *recordingTrivyClient) ScanImage(ctx context.Context, image string) (*scanner.TrivyOutput, error) {
+	output, err := t.client.ScanImage(ctx, image)
+	e := &entry{}
+	var timeoutErr *scanner.ScanTimeoutError
+	if errors.As(err, &timeoutErr) {
+		e.Timeout = timeoutErr.Timeout
+	}
+	t.recorder.save(e, output, err, trivyDir, "ScanImage", image)
+	return output, err
+}
+
+func (t *recordingTrivyClient) SBOM(ctx context.Context, image string, withVulnerabilities bool) ([]byte, error) {
+	sbom, err := t.client.SBOM(ctx, image, withVulnerabilities)
+	t.recorder.save(&entry{}, sbom, err, trivyDir, "SBOM", image, strconv.FormatBool(withVulnerabilities))
+	return sbom, err
+}
+
+func (t *recordingTrivyClient) CisScan(benchmark string) (*scanner.CisOutput, error) {
+	output, err := t.client.CisScan(benchmark)
+	t.recorder.save(&entry{}, output, err, trivyDir, "CisScan", benchmark)
+	return output, err
+}
+
+func (t *recordingTrivyClient) Version() (*scanner.TrivyVersion, error) {
+	version, err := t.client.Version()
+	t.recorder.save(&entry{}, version, err, trivyDir, "Version")
+	return version, err
+}
+
+type replayTrivyClient struct {
+	replayer *Replayer
+}
+
+// TrivyClient returns a client replaying the recorded responses, the calls not recorded returning an error
+func (r *Replayer) TrivyClient() scanner.TrivyClient {
+	return &replayTrivyClient{replayer: r}
+}
+
+func (t *replayTrivyClient) DownloadDatabase(_ context.Context, _ string) error {
+	return nil
+}
+
+func (t *replayTrivyClient) ScanImage(_ context.Context, image string) (*scanner.TrivyOutput, error) {
+	var output *scanner.TrivyOutput
+	e, err := t.replayer.loadEntry(&output, trivyDir, "ScanImage", image)
+	if err != nil && e != nil && e.Timeout > 0 {
+		return output, &scanner.ScanTimeoutError{Image: image, Timeout: e.Timeout}
+	}
+	return output, err
+}
+
+func (t *replayTrivyClient) SBOM(_ context.Context, image string, withVulnerabilities bool) ([]byte, error) {
+	var sbom []byte
+	err := t.replayer.load(&sbom, trivyDir, "SBOM", image, strconv.FormatBool(withVulnerabilities))
+	return sbom, err
+}
+
+func (t *replayTrivyClient) CisScan(benchmark string) (*scanner.CisOutput, error) {
+	var output *scanner.CisOutput
+	err := t.replayer.load(&output, trivyDir, "CisScan", benchmark)
+	return output, err
+}
+
+func (t *replayTrivyClient) Version() (*scanner.TrivyVersion, error) {
+	var version *scanner.TrivyVersion
+	err := t.replayer.load(&version, trivyDir, "Version")
+	return version, err
+}
+
+type recordingDockerClient struct {
+	client   scanner.DockerClient
+	recorder *Recorder
+}
+
+// DockerClient wraps the client to record its responses. The image removals and lookups are not recorded, as they
+// depend on the images of the recording host
+func (r *Recorder) DockerClient(client scanner.DockerClient) scanner.DockerClient {
+	return &recordingDockerClient{client: client, recorder: r}
+}
+
+// PullImage records the pull errors, the pulled image itself not being recorded
+func (d *recordingDockerClient) PullImage(ctx context.Context, image string) error {
+	err := d.client.PullImage(ctx, image)
+	d.recorder.save(&entry{}, nil, err, dockerDir, "PullImage", image)
+	return err
+}
+
+func (d *recordingDockerClient) RmiImage(image string) error {
+	return d.client.RmiImage(image)
+}
+
+func (d *recordingDockerClient) ImageExists(ctx context.Context, image string) (bool, error) {
+	return d.client.ImageExists(ctx, image)
+}
+
+func (d *recordingDockerClient) ImageSize(ctx context.Context, image string) (int64, error) {
+	size, err := d.client.ImageSize(ctx, image)
+	d.recorder.save(&entry{}, size, err, dockerDir, "ImageSize", image)
+	return size, err
+}
+
+func (d *recordingDockerClient) ImageCreated(ctx context.Context, image string) (time.Time, error) {
+	created, err := d.client.ImageCreated(ctx, image)
+	d.recorder.save(&entry{}, created, err, dockerDir, "ImageCreated", image)
+	return created, err
+}
+
+func (d *recordingDockerClient) ImagePlatforms(ctx context.Context, image string) ([]scanner.ImagePlatform, error) {
+	platforms, err := d.client.ImagePlatforms(ctx, image)
+	d.recorder.save(&entry{}, platforms, err, dockerDir, "ImagePlatforms", image)
+	return platforms, err
+}
+
+type replayDockerClient struct {
+	replayer *Replayer
+}
+
+// DockerClient returns a client replaying the recorded responses, the calls not recorded returning an error
+func (r *Replayer) DockerClient() scanner.DockerClient {
+	return &replayDockerClient{replayer: r}
+}
+
+func (d *replayDockerClient) PullImage(_ context.Context, image string) error {
+	var unused any
+	return d.replayer.load(&unused, dockerDir, "PullImage", image)
+}
+
+func (d *replayDockerClient) RmiImage(_ string) error {
+	return nil
+}
+
+// ImageExists returns false as the replayed images are not pulled
+func (d *replayDockerClient) ImageExists(_ context.Context, _ string) (bool, error) {
+	return false, nil
+}
+
+func (d *replayDockerClient) ImageSize(_ context.Context, image string) (int64, error) {
+	var size int64
+	err := d.replayer.load(&size, dockerDir, "ImageSize", image)
+	return size, err
+}
+
+func (d *replayDockerClient) ImageCreated(_ context.Context, image string) (time.Time, error) {
+	var created time.Time
+	err := d.replayer.load(&created, dockerDir, "ImageCreated", image)
+	return created, err
+}
+
+func (d *replayDockerClient) ImagePlatforms(_ context.Context, image string) ([]scanner.ImagePlatform, error) {
+	var platforms []scanner.ImagePlatform
+	err := d.replayer.load(&platforms, dockerDir, "ImagePlatforms", image)
+	return platforms, err
+}
@@ -0,0 +1,286 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pageSize is the number of repositories or tags requested per page, the registries returning fewer when they cap it
+const pageSize = 1000
+
+// nextLinkPattern extracts the url of the next page from the Link header of a paginated response
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// challengeParamPattern extracts the parameters of the Bearer challenge of the WWW-Authenticate header
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Client lists the repositories and the tags of a registry with the catalog of the Docker Registry HTTP API v2, for
+// instance a registry:2, Harbor, Nexus, Artifactory or GitLab registry. The registries authenticating with bearer tokens
+// are supported, the token being requested with the username and password
+type Client struct {
+	baseURL    string
+	host       string
+	username   string
+	password   string
+	httpClient *http.Client
+	// tokens are the bearer tokens by scope, requested once per scope
+	tokens map[string]string
+}
+
+// NewClient creates a Client for the registry of the url, for instance https://registry.example.com, authenticating
+// with the username and password when given. The url is read with http for the registries served without TLS
+func NewClient(registryURL, username, password string) (*Client, error) {
+	parsed, err := url.Parse(registryURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid registry url %q, expecting for instance https://registry.example.com", registryURL)
+	}
+	return &Client{
+		baseURL:    parsed.Scheme + "://" + parsed.Host,
+		host:       parsed.Host,
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		tokens:     make(map[string]string),
+	}, nil
+}
+
+// Host returns the host of the registry, the prefix of the names of its images
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Repositories returns the repositories of the catalog of the registry, sorted by name
+func (c *Client) Repositories() ([]string, error) {
+	var repositories []string
+	err := c.list("/v2/_catalog", func(content []byte) error {
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		if err := json.Unmarshal(content, &page); err != nil {
+			return fmt.Errorf("error while decoding the catalog of registry %s: %v", c.host, err)
+		}
+		repositories = append(repositories, page.Repositories...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(repositories)
+	return repositories, nil
+}
+
+// Tags returns the tags of the repository, sorted by name
+func (c *Client) Tags(repository string) ([]string, error) {
+	var tags []string
+	err := c.list("/v2/"+repository+"/tags/list", func(content []byte) error {
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(content, &page); err != nil {
+			return fmt.Errorf("error while decoding the tags of repository %s of registry %s: %v", repository, c.host, err)
+		}
+		tags = append(tags, page.Tags...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Images returns the names of the tagged images of the registry matching one of the include patterns, all of them
+// when no pattern is given. A pattern matches the repository name, for instance 'payments/*', or the repository name
+// and the tag, for instance 'payments/*:v1.*', with the path.Match syntax
+func (c *Client) Images(includes []string) ([]string, error) {
+	repositories, err := c.Repositories()
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, repository := range repositories {
+		if !matchesAny(includes, repository, "") {
+			continue
+		}
+		tags, err := c.Tags(repository)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			if matchesAny(includes, repository, tag) {
+				images = append(images, c.host+"/"+repository+":"+tag)
+			}
+		}
+	}
+	return images, nil
+}
+
+// matchesAny returns true when one of the patterns matches the repository and the tag, any tag matching when the tag
+// is empty. Everything matches when there is no pattern
+func matchesAny(patterns []string, repository, tag string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		repositoryPattern, tagPattern, hasTag := strings.Cut(pattern, ":")
+		if matched, _ := path.Match(repositoryPattern, repository); !matched {
+			continue
+		}
+		if !hasTag || tag == "" {
+			return true
+		}
+		if matched, _ := path.Match(tagPattern, tag); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePatterns returns an error when an include pattern is malformed, see Images
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		repositoryPattern, tagPattern, _ := strings.Cut(pattern, ":")
+		for _, p := range []string{repositoryPattern, tagPattern} {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid include pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// list reads the pages of a paginated listing, following the Link header of each page
+func (c *Client) list(firstPage string, readPage func(content []byte) error) error {
+	next := fmt.Sprintf("%s%s?n=%d", c.baseURL, firstPage, pageSize)
+	for next != "" {
+		content, link, err := c.get(next)
+		if err != nil {
+			return err
+		}
+		if err := readPage(content); err != nil {
+			return err
+		}
+		next = ""
+		if match := nextLinkPattern.FindStringSubmatch(link); match != nil {
+			nextURL, err := url.Parse(c.baseURL)
+			if err != nil {
+				return err
+			}
+			reference, err := url.Parse(match[1])
+			if err != nil {
+				return fmt.Errorf("invalid next page link %q of registry %s: %v", match[1], c.host, err)
+			}
+			next = nextURL.ResolveReference(reference).String()
+		}
+	}
+	return nil
+}
+
+// get reads the url, requesting a bearer token when the registry challenges the request for one. It returns the
+// content and the Link header of the response
+func (c *Client) get(pageURL string) ([]byte, string, error) {
+	resp, err := c.do(pageURL, "")
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer ") {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := c.token(challenge)
+		if err != nil {
+			return nil, "", err
+		}
+		if resp, err = c.do(pageURL, token); err != nil {
+			return nil, "", err
+		}
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s: %v", pageURL, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, "", fmt.Errorf("error reading %s: status code %d: %s", pageURL, resp.StatusCode, string(content))
+	}
+	return content, resp.Header.Get("Link"), nil
+}
+
+// do sends the request, with the bearer token when given and with the username and password otherwise
+func (c *Client) do(pageURL, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", pageURL, err)
+	}
+	return resp, nil
+}
+
+// token requests the bearer token of the challenge from its realm, the token of a scope being reused for the next pages
+func (c *Client) token(challenge string) (string, error) {
+	params := make(map[string]string)
+	for _, match := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	if token, ok := c.tokens[params["scope"]]; ok {
+		return token, nil
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("registry %s requested a bearer token without realm: %s", c.host, challenge)
+	}
+	query := url.Values{}
+	for _, name := range []string{"service", "scope"} {
+		if params[name] != "" {
+			query.Set(name, params[name])
+		}
+	}
+	req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting a token of registry %s: %v", c.host, err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error requesting a token of registry %s: %v", c.host, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("error requesting a token of registry %s: status code %d: %s", c.host, resp.StatusCode, string(content))
+	}
+	var response struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(content, &response); err != nil {
+		return "", fmt.Errorf("error while decoding the token of registry %s: %v", c.host, err)
+	}
+	token := response.Token
+	if token == "" {
+		token = response.AccessToken
+	}
+	c.tokens[params["scope"]] = token
+	return token, nil
+}
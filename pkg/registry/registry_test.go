@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegistry(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Registry Suite")
+}
+
+var _ = Describe("Registry catalog", func() {
+
+	var (
+		server        *httptest.Server
+		client        *Client
+		host          string
+		tokenRequests int
+	)
+
+	BeforeEach(func() {
+		tokenRequests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				tokenRequests++
+				username, password, _ := r.BasicAuth()
+				Expect(username + ":" + password).To(Equal("scanner:secret"))
+				_, _ = w.Write([]byte(`{"token":"token-of-` + r.URL.Query().Get("scope") + `"}`))
+				return
+			}
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-of-") {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry",scope="registry:catalog:*"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.URL.Path {
+			case "/v2/_catalog":
+				if r.URL.Query().Get("last") == "" {
+					w.Header().Set("Link", `</v2/_catalog?last=payments%2Fapi&n=1000>; rel="next"`)
+					_, _ = w.Write([]byte(`{"repositories":["payments/web","payments/api"]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"repositories":["platform/ingress"]}`))
+			case "/v2/payments/api/tags/list":
+				_, _ = w.Write([]byte(`{"name":"payments/api","tags":["v2.0","v1.0","latest"]}`))
+			case "/v2/payments/web/tags/list":
+				_, _ = w.Write([]byte(`{"name":"payments/web","tags":["v1.3"]}`))
+			case "/v2/platform/ingress/tags/list":
+				_, _ = w.Write([]byte(`{"name":"platform/ingress","tags":["1.9"]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		var err error
+		client, err = NewClient(server.URL, "scanner", "secret")
+		Expect(err).NotTo(HaveOccurred())
+		host = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists the repositories of all the catalog pages", func() {
+		repositories, err := client.Repositories()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repositories).To(Equal([]string{"payments/api", "payments/web", "platform/ingress"}))
+		// the token of the scope is reused for the next page
+		Expect(tokenRequests).To(Equal(1))
+	})
+
+	It("lists the tagged images of the repositories", func() {
+		images, err := client.Images(nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(Equal([]string{
+			host + "/payments/api:latest", host + "/payments/api:v1.0", host + "/payments/api:v2.0",
+			host + "/payments/web:v1.3", host + "/platform/ingress:1.9",
+		}))
+	})
+
+	It("only lists the images matching the include patterns", func() {
+		images, err := client.Images([]string{"payments/*:v*", "platform/ingress"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(Equal([]string{
+			host + "/payments/api:v1.0", host + "/payments/api:v2.0", host + "/payments/web:v1.3", host + "/platform/ingress:1.9",
+		}))
+	})
+
+	It("rejects the invalid urls and patterns", func() {
+		_, err := NewClient("registry.example.com", "", "")
+		Expect(err).To(HaveOccurred())
+		Expect(ValidatePatterns([]string{"payments/["})).To(HaveOccurred())
+		Expect(ValidatePatterns([]string{"payments/*:v*"})).To(Succeed())
+	})
+
+	It("fails when the registry denies the catalog", func() {
+		client, err := NewClient(server.URL, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		_, err = client.Repositories()
+		Expect(err).To(MatchError(ContainSubstring("status code 403")))
+	})
+})
@@ -0,0 +1,161 @@
+package releases
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kubernetesReleaseURL = "https://dl.k8s.io/release"
+	gitHubAPIURL         = "https://api.github.com"
+)
+
+// Version is a major.minor.patch release version, the pre-release and build suffixes being ignored
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses a release version, for instance v1.27.3, 1.10.1 or v1.27.3-gke.100
+func ParseVersion(version string) (Version, error) {
+	core := strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("unsupported release version %q, expecting major.minor.patch", version)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("unsupported release version %q, expecting major.minor.patch", version)
+		}
+		numbers[i] = number
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// MinorRelease returns the major.minor release line of the version, for instance 1.27
+func (v Version) MinorRelease() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Before returns true when the version was released before the other version
+func (v Version) Before(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Client looks up the latest patch releases of Kubernetes and of the projects released on GitHub. The lookups are
+// cached for the lifetime of the client
+type Client struct {
+	kubernetesURL string
+	gitHubURL     string
+	gitHubToken   string
+	httpClient    *http.Client
+	cache         map[string]Version
+}
+
+// NewClient creates a Client, the GitHub API being called with the token when set to raise its rate limit
+func NewClient(gitHubToken string) *Client {
+	return &Client{
+		kubernetesURL: kubernetesReleaseURL,
+		gitHubURL:     gitHubAPIURL,
+		gitHubToken:   gitHubToken,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		cache:         make(map[string]Version),
+	}
+}
+
+// LatestKubernetesPatch returns the latest patch release of the Kubernetes release line of the version
+func (c *Client) LatestKubernetesPatch(version Version) (Version, error) {
+	key := "kubernetes/" + version.MinorRelease()
+	if latest, ok := c.cache[key]; ok {
+		return latest, nil
+	}
+	content, err := c.get(fmt.Sprintf("%s/stable-%s.txt", c.kubernetesURL, version.MinorRelease()), "")
+	if err != nil {
+		return Version{}, fmt.Errorf("error looking up the latest Kubernetes %s release: %v", version.MinorRelease(), err)
+	}
+	latest, err := ParseVersion(strings.TrimSpace(string(content)))
+	if err != nil {
+		return Version{}, fmt.Errorf("error looking up the latest Kubernetes %s release: %v", version.MinorRelease(), err)
+	}
+	c.cache[key] = latest
+	return latest, nil
+}
+
+// LatestGitHubPatch returns the latest patch release of the release line of the version among the GitHub releases of
+// the repository, for instance coredns/coredns. Only the release tags starting with the tag prefix are considered,
+// for instance controller-v for the ingress-nginx controller releases, the pre-releases being ignored
+func (c *Client) LatestGitHubPatch(repository, tagPrefix string, version Version) (Version, error) {
+	key := repository + "/" + tagPrefix + version.MinorRelease()
+	if latest, ok := c.cache[key]; ok {
+		return latest, nil
+	}
+	content, err := c.get(fmt.Sprintf("%s/repos/%s/releases?per_page=100", c.gitHubURL, repository), c.gitHubToken)
+	if err != nil {
+		return Version{}, fmt.Errorf("error looking up the releases of %s: %v", repository, err)
+	}
+	var releases []struct {
+		TagName    string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	}
+	if err := json.Unmarshal(content, &releases); err != nil {
+		return Version{}, fmt.Errorf("error while decoding the releases of %s: %v", repository, err)
+	}
+	latest := version
+	for _, release := range releases {
+		if release.Draft || release.Prerelease || !strings.HasPrefix(release.TagName, tagPrefix) {
+			continue
+		}
+		released, err := ParseVersion(strings.TrimPrefix(release.TagName, tagPrefix))
+		if err != nil || released.MinorRelease() != version.MinorRelease() {
+			continue
+		}
+		if released.Patch > latest.Patch {
+			latest = released
+		}
+	}
+	c.cache[key] = latest
+	return latest, nil
+}
+
+func (c *Client) get(url, token string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, string(content))
+	}
+	return content, nil
+}
@@ -0,0 +1,93 @@
+package releases
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReleases(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Releases Suite")
+}
+
+const ingressNginxReleases = `[
+  {"tag_name": "helm-chart-4.7.5", "draft": false, "prerelease": false},
+  {"tag_name": "controller-v1.9.0-beta.0", "draft": false, "prerelease": true},
+  {"tag_name": "controller-v1.8.5", "draft": false, "prerelease": false},
+  {"tag_name": "controller-v1.8.6", "draft": true, "prerelease": false},
+  {"tag_name": "controller-v1.8.2", "draft": false, "prerelease": false},
+  {"tag_name": "controller-v1.7.9", "draft": false, "prerelease": false}
+]`
+
+var _ = Describe("Latest releases", func() {
+
+	var (
+		server        *httptest.Server
+		client        *Client
+		requests      []*http.Request
+		authorization string
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			switch r.URL.Path {
+			case "/release/stable-1.27.txt":
+				_, _ = w.Write([]byte("v1.27.16\n"))
+			case "/repos/kubernetes/ingress-nginx/releases":
+				authorization = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(ingressNginxReleases))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		client = NewClient("gh-token")
+		client.kubernetesURL = server.URL + "/release"
+		client.gitHubURL = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("parses the release versions", func() {
+		version, err := ParseVersion("v1.27.3-gke.100")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal(Version{Major: 1, Minor: 27, Patch: 3}))
+		Expect(version.MinorRelease()).To(Equal("1.27"))
+		Expect(version.String()).To(Equal("v1.27.3"))
+
+		_, err = ParseVersion("latest")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("looks up the latest Kubernetes patch release once per release line", func() {
+		latest, err := client.LatestKubernetesPatch(Version{Major: 1, Minor: 27, Patch: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest).To(Equal(Version{Major: 1, Minor: 27, Patch: 16}))
+
+		_, err = client.LatestKubernetesPatch(Version{Major: 1, Minor: 27, Patch: 8})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("looks up the latest GitHub patch release of the release line, ignoring the drafts and pre-releases", func() {
+		latest, err := client.LatestGitHubPatch("kubernetes/ingress-nginx", "controller-v", Version{Major: 1, Minor: 8, Patch: 1})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest).To(Equal(Version{Major: 1, Minor: 8, Patch: 5}))
+		Expect(authorization).To(Equal("Bearer gh-token"))
+	})
+
+	It("returns an error when the releases cannot be looked up", func() {
+		_, err := client.LatestGitHubPatch("example/unknown", "v", Version{Major: 0, Minor: 1, Patch: 0})
+
+		Expect(err).To(MatchError(ContainSubstring("status code 404")))
+	})
+})
@@ -0,0 +1,127 @@
+package reportschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the schema version of the json reports saved with the report-output-filename-json option.
+// It has to be increased, with an upgrade from the previous version, whenever the json representation changes
+// in a way older readers or newer readers cannot cope with
+const CurrentVersion = 2
+
+// VersionField is the name of the json field holding the schema version of a report
+const VersionField = "schemaVersion"
+
+// upgrades converts a report of the version to the next version
+var upgrades = map[int]func(report map[string]interface{}) error{
+	1: upgradeV1,
+}
+
+// Version returns the schema version of the json report. Reports saved before the schema was versioned are version 1
+func Version(content []byte) (int, error) {
+	report, err := decode(content)
+	if err != nil {
+		return 0, err
+	}
+	return version(report)
+}
+
+// Upgrade converts a json report of any previous schema version to the current version.
+// Reports of the current version are returned unchanged
+func Upgrade(content []byte) ([]byte, error) {
+	report, err := decode(content)
+	if err != nil {
+		return nil, err
+	}
+	v, err := version(report)
+	if err != nil {
+		return nil, err
+	}
+	if v == CurrentVersion {
+		return content, nil
+	}
+	if v > CurrentVersion {
+		return nil, fmt.Errorf("report schema version %d is newer than the supported version %d", v, CurrentVersion)
+	}
+
+	for ; v < CurrentVersion; v++ {
+		upgrade, ok := upgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("no upgrade from report schema version %d", v)
+		}
+		if err := upgrade(report); err != nil {
+			return nil, fmt.Errorf("error upgrading report from schema version %d: %v", v, err)
+		}
+		report[VersionField] = v + 1
+	}
+	return json.Marshal(report)
+}
+
+func decode(content []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep numbers as is as the report is re-encoded
+	decoder.UseNumber()
+	var report map[string]interface{}
+	if err := decoder.Decode(&report); err != nil {
+		return nil, fmt.Errorf("error while decoding report: %v", err)
+	}
+	return report, nil
+}
+
+func version(report map[string]interface{}) (int, error) {
+	value, ok := report[VersionField]
+	if !ok {
+		return 1, nil
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid report schema version %v", value)
+	}
+	v, err := number.Int64()
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid report schema version %v", value)
+	}
+	return int(v), nil
+}
+
+// upgradeV1 converts the scan errors of the image scan. Version 1 reports encoded them as empty objects
+// as error values have no json representation, version 2 reports encode them as their message
+func upgradeV1(report map[string]interface{}) error {
+	imageScan, ok := report["ImageScan"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	scannedImages, _ := imageScan["ScannedImages"].([]interface{})
+	for _, image := range scannedImages {
+		upgradeScanErrorV1(image)
+	}
+	areas, _ := imageScan["AreaSummary"].(map[string]interface{})
+	for _, area := range areas {
+		area, _ := area.(map[string]interface{})
+		teams, _ := area["Teams"].(map[string]interface{})
+		for _, team := range teams {
+			team, _ := team.(map[string]interface{})
+			images, _ := team["Images"].([]interface{})
+			for _, image := range images {
+				upgradeScanErrorV1(image)
+			}
+		}
+	}
+	return nil
+}
+
+func upgradeScanErrorV1(image interface{}) {
+	scannedImage, ok := image.(map[string]interface{})
+	if !ok {
+		return
+	}
+	switch scannedImage["ScanError"].(type) {
+	case nil:
+		delete(scannedImage, "ScanError")
+	case map[string]interface{}:
+		// the error message was lost when the report was saved
+		scannedImage["ScanError"] = "image scan failed"
+	}
+}
@@ -0,0 +1,67 @@
+package reportschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReportSchema(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Report Schema Suite")
+}
+
+var _ = Describe("Report schema", func() {
+
+	Describe("Version", func() {
+		It("considers reports without schema version as version 1", func() {
+			Expect(Version([]byte(`{"ImageScan":{}}`))).To(Equal(1))
+		})
+
+		It("returns the schema version of the report", func() {
+			Expect(Version([]byte(`{"schemaVersion":2}`))).To(Equal(2))
+		})
+
+		It("rejects invalid schema versions", func() {
+			_, err := Version([]byte(`{"schemaVersion":"two"}`))
+			Expect(err).To(MatchError(ContainSubstring("invalid report schema version two")))
+		})
+	})
+
+	Describe("Upgrade", func() {
+		It("converts the version 1 scan errors to their message", func() {
+			v1 := `{"ImageScan":{
+				"ScannedImages":[{"ImageName":"alpine","ScanError":{}},{"ImageName":"debian","ScanError":null,"VulnerabilitySummary":{"SeverityScore":100000000}}],
+				"AreaSummary":{"all":{"Teams":{"all":{"Images":[{"ImageName":"alpine","ScanError":{}}]}}}}
+			}}`
+
+			upgraded, err := Upgrade([]byte(v1))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgraded).To(MatchJSON(`{"schemaVersion":2,"ImageScan":{
+				"ScannedImages":[{"ImageName":"alpine","ScanError":"image scan failed"},{"ImageName":"debian","VulnerabilitySummary":{"SeverityScore":100000000}}],
+				"AreaSummary":{"all":{"Teams":{"all":{"Images":[{"ImageName":"alpine","ScanError":"image scan failed"}]}}}}
+			}}`))
+		})
+
+		It("upgrades reports without image scan", func() {
+			upgraded, err := Upgrade([]byte(`{"CisScan":{"Benchmarks":[]}}`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgraded).To(MatchJSON(`{"schemaVersion":2,"CisScan":{"Benchmarks":[]}}`))
+		})
+
+		It("returns reports of the current version unchanged", func() {
+			current, _ := json.Marshal(map[string]interface{}{"schemaVersion": CurrentVersion, "ImageScan": nil})
+
+			Expect(Upgrade(current)).To(Equal(current))
+		})
+
+		It("rejects reports newer than the supported version", func() {
+			_, err := Upgrade([]byte(`{"schemaVersion":99}`))
+			Expect(err).To(MatchError("report schema version 99 is newer than the supported version 2"))
+		})
+	})
+})
@@ -0,0 +1,464 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultNVDAPIURL is the URL of the CVE API of the National Vulnerability Database
+	DefaultNVDAPIURL = "https://services.nvd.nist.gov/rest/json/cves/2.0"
+	// DefaultGitHubAdvisoriesAPIURL is the URL of the GitHub Security Advisories API
+	DefaultGitHubAdvisoriesAPIURL = "https://api.github.com/advisories"
+
+	// AdvisorySourceNVD and AdvisorySourceGHSA are the sources of the advisories, see Vulnerabilities.DescriptionSource
+	AdvisorySourceNVD  = "nvd"
+	AdvisorySourceGHSA = "ghsa"
+
+	// advisoryRequestAttempts is the number of attempts of a request throttled by the API or failing transiently
+	advisoryRequestAttempts = 3
+	// advisoryLookupAttempts is the number of lookups of an advisory that could not be read, the next batch of images
+	// with the vulnerability looking it up again until then
+	advisoryLookupAttempts = 3
+)
+
+// exploitHosts are the hosts of the references publishing exploits, on top of the references NVD tags as Exploit
+var exploitHosts = []string{"exploit-db.com", "packetstormsecurity.com", "0day.today"}
+
+// Advisory is the advisory of a vulnerability published by NVD or by GitHub, the vulnerabilities being enriched with it
+// since the descriptions embedded in the trivy database are sometimes missing or truncated
+type Advisory struct {
+	Source            string
+	Description       string     `json:",omitempty"`
+	References        []string   `json:",omitempty"`
+	ExploitReferences []string   `json:",omitempty"`
+	PublishedDate     *time.Time `json:",omitempty"`
+	LastModifiedDate  *time.Time `json:",omitempty"`
+}
+
+// AdvisoryConfig locates the advisory APIs and the cache of the advisories they returned
+type AdvisoryConfig struct {
+	NVDAPIURL string
+	// NVDAPIKey raises the NVD rate limit from 5 to 50 requests per 30 seconds
+	NVDAPIKey              string
+	GitHubAdvisoriesAPIURL string
+	// GitHubToken raises the GitHub rate limit from 60 to 5000 requests per hour
+	GitHubToken string
+	// CacheDir is where the advisories are cached, a cached advisory younger than MaxAge being reused rather than
+	// requested again
+	CacheDir string
+	MaxAge   time.Duration
+}
+
+// AdvisoryDatabase enriches the vulnerabilities with their NVD advisory, or their GitHub advisory for the GHSA
+// vulnerabilities. The advisories of the distinct vulnerabilities of the scanned images are looked up in a batch once
+// the images are scanned, each advisory being requested once, the requests of each API being spaced out to stay below
+// its rate limit, and retried after the delay the API asks for when throttled
+type AdvisoryDatabase struct {
+	config     AdvisoryConfig
+	httpClient *http.Client
+	nvd        *advisoryRateLimiter
+	github     *advisoryRateLimiter
+	// retryBackoff is the delay before retrying a request failing transiently, when the API gives no Retry-After
+	retryBackoff time.Duration
+	// lock serialises the batches, which share the advisories read
+	lock       sync.Mutex
+	advisories map[string]*advisoryEntry
+}
+
+// advisoryEntry is the advisory of a vulnerability, read once whatever the number of images the vulnerability is found
+// in. A lookup that failed is not kept, so that the advisory is looked up again with the next batch
+type advisoryEntry struct {
+	read     bool
+	failures int
+	advisory *Advisory
+}
+
+// NewAdvisoryDatabase creates an AdvisoryDatabase requesting the advisories from the APIs of the config
+func NewAdvisoryDatabase(config AdvisoryConfig) *AdvisoryDatabase {
+	nvdInterval, githubInterval := 6*time.Second, time.Minute
+	if config.NVDAPIKey != "" {
+		nvdInterval = 600 * time.Millisecond
+	}
+	if config.GitHubToken != "" {
+		githubInterval = 720 * time.Millisecond
+	}
+	return &AdvisoryDatabase{
+		config:       config,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		nvd:          &advisoryRateLimiter{interval: nvdInterval},
+		github:       &advisoryRateLimiter{interval: githubInterval},
+		retryBackoff: 5 * time.Second,
+		advisories:   make(map[string]*advisoryEntry),
+	}
+}
+
+// enrich completes the vulnerabilities of the images with their advisory: the missing or truncated description, the
+// missing references and dates, and the exploit references. It is called once the images are scanned, the distinct
+// vulnerabilities of the images being looked up in a batch, so that the scan workers never wait on the rate limits of
+// the APIs. A nil database enriches none
+func (d *AdvisoryDatabase) enrich(ctx context.Context, images []ScannedImage) {
+	if d == nil {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var vulnerabilityIDs []string
+	seen := make(map[string]bool)
+	for _, image := range images {
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				if !seen[vulnerability.VulnerabilityID] {
+					seen[vulnerability.VulnerabilityID] = true
+					vulnerabilityIDs = append(vulnerabilityIDs, vulnerability.VulnerabilityID)
+				}
+			}
+		}
+	}
+	advisories := make(map[string]*Advisory)
+	for _, vulnerabilityID := range vulnerabilityIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		if advisory := d.advisory(ctx, vulnerabilityID); advisory != nil {
+			advisories[vulnerabilityID] = advisory
+		}
+	}
+	if len(advisories) == 0 {
+		return
+	}
+	for _, image := range images {
+		image.updateResults(func(results []TrivyOutputResults) {
+			for i := range results {
+				for j := range results[i].Vulnerabilities {
+					vulnerability := &results[i].Vulnerabilities[j]
+					if advisory, ok := advisories[vulnerability.VulnerabilityID]; ok {
+						vulnerability.applyAdvisory(advisory)
+					}
+				}
+			}
+		})
+	}
+}
+
+// advisory returns the advisory of the vulnerability, nil when the vulnerability has none or it cannot be read
+func (d *AdvisoryDatabase) advisory(ctx context.Context, vulnerabilityID string) *Advisory {
+	var source string
+	switch {
+	case strings.HasPrefix(vulnerabilityID, "CVE-"):
+		source = AdvisorySourceNVD
+	case strings.HasPrefix(vulnerabilityID, "GHSA-"):
+		source = AdvisorySourceGHSA
+	default:
+		return nil
+	}
+	entry, ok := d.advisories[vulnerabilityID]
+	if !ok {
+		entry = &advisoryEntry{}
+		d.advisories[vulnerabilityID] = entry
+	}
+	if entry.read || entry.failures >= advisoryLookupAttempts {
+		return entry.advisory
+	}
+	advisory, err := d.readAdvisory(ctx, source, vulnerabilityID)
+	if err != nil {
+		entry.failures++
+		logr.Warnf("Unable to read the advisory of %s: %v", vulnerabilityID, err)
+		return nil
+	}
+	entry.read, entry.advisory = true, advisory
+	return entry.advisory
+}
+
+// readAdvisory reads the advisory from the cache when fresh enough, requests it otherwise. The vulnerabilities the
+// source has no advisory for are cached as an empty advisory, so that they are not requested again
+func (d *AdvisoryDatabase) readAdvisory(ctx context.Context, source, vulnerabilityID string) (*Advisory, error) {
+	cacheFile := filepath.Join(d.config.CacheDir, vulnerabilityID+".json")
+	if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < d.config.MaxAge {
+		var advisory Advisory
+		if content, err := os.ReadFile(cacheFile); err == nil && json.Unmarshal(content, &advisory) == nil {
+			return &advisory, nil
+		}
+	}
+
+	var (
+		advisory *Advisory
+		err      error
+	)
+	if source == AdvisorySourceNVD {
+		advisory, err = d.nvdAdvisory(ctx, vulnerabilityID)
+	} else {
+		advisory, err = d.githubAdvisory(ctx, vulnerabilityID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content, _ := json.Marshal(advisory)
+	if err := os.MkdirAll(d.config.CacheDir, 0755); err != nil {
+		logr.Warnf("Could not create the advisory cache directory %s: %v", d.config.CacheDir, err)
+	} else if err := os.WriteFile(cacheFile, content, 0644); err != nil {
+		logr.Warnf("Could not cache the advisory to %s: %v", cacheFile, err)
+	}
+	return advisory, nil
+}
+
+func (d *AdvisoryDatabase) nvdAdvisory(ctx context.Context, cveID string) (*Advisory, error) {
+	var response struct {
+		Vulnerabilities []struct {
+			CVE struct {
+				Published    string `json:"published"`
+				LastModified string `json:"lastModified"`
+				Descriptions []struct {
+					Lang  string `json:"lang"`
+					Value string `json:"value"`
+				} `json:"descriptions"`
+				References []struct {
+					URL  string   `json:"url"`
+					Tags []string `json:"tags"`
+				} `json:"references"`
+			} `json:"cve"`
+		} `json:"vulnerabilities"`
+	}
+	headers := map[string]string{}
+	if d.config.NVDAPIKey != "" {
+		headers["apiKey"] = d.config.NVDAPIKey
+	}
+	requestURL := d.config.NVDAPIURL + "?cveId=" + url.QueryEscape(cveID)
+	if err := d.get(ctx, d.nvd, requestURL, headers, &response); err != nil {
+		return nil, err
+	}
+	advisory := &Advisory{Source: AdvisorySourceNVD}
+	if len(response.Vulnerabilities) == 0 {
+		return advisory, nil
+	}
+	cve := response.Vulnerabilities[0].CVE
+	for _, description := range cve.Descriptions {
+		if description.Lang == "en" {
+			advisory.Description = description.Value
+		}
+	}
+	for _, reference := range cve.References {
+		advisory.References = append(advisory.References, reference.URL)
+		if containsString(reference.Tags, "Exploit") || exploitReference(reference.URL) {
+			advisory.ExploitReferences = append(advisory.ExploitReferences, reference.URL)
+		}
+	}
+	// the NVD times are UTC times without time zone, for instance 2021-12-10T10:15:09.143
+	advisory.PublishedDate = parseAdvisoryTime("2006-01-02T15:04:05", cve.Published)
+	advisory.LastModifiedDate = parseAdvisoryTime("2006-01-02T15:04:05", cve.LastModified)
+	return advisory, nil
+}
+
+func (d *AdvisoryDatabase) githubAdvisory(ctx context.Context, ghsaID string) (*Advisory, error) {
+	var response struct {
+		Description string   `json:"description"`
+		References  []string `json:"references"`
+		PublishedAt string   `json:"published_at"`
+		UpdatedAt   string   `json:"updated_at"`
+	}
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if d.config.GitHubToken != "" {
+		headers["Authorization"] = "Bearer " + d.config.GitHubToken
+	}
+	if err := d.get(ctx, d.github, d.config.GitHubAdvisoriesAPIURL+"/"+url.PathEscape(ghsaID), headers, &response); err != nil {
+		return nil, err
+	}
+	advisory := &Advisory{
+		Source:           AdvisorySourceGHSA,
+		Description:      response.Description,
+		References:       response.References,
+		PublishedDate:    parseAdvisoryTime(time.RFC3339, response.PublishedAt),
+		LastModifiedDate: parseAdvisoryTime(time.RFC3339, response.UpdatedAt),
+	}
+	for _, reference := range response.References {
+		if exploitReference(reference) {
+			advisory.ExploitReferences = append(advisory.ExploitReferences, reference)
+		}
+	}
+	return advisory, nil
+}
+
+// get decodes the response of the API once its rate limit allows the request, a missing advisory leaving the
+// response empty. The requests throttled by the API or failing transiently are retried, after the Retry-After delay of
+// the API when given, the other requests to the API waiting for it too
+func (d *AdvisoryDatabase) get(ctx context.Context, limiter *advisoryRateLimiter, requestURL string, headers map[string]string, response interface{}) error {
+	for attempt := 1; ; attempt++ {
+		resp, err := d.request(ctx, limiter, requestURL, headers)
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
+		transient := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !transient || attempt == advisoryRequestAttempts {
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decodeAdvisoryResponse(resp, requestURL, response)
+		}
+		delay := d.retryBackoff
+		if err == nil {
+			delay = retryAfter(resp.Header.Get("Retry-After"), delay)
+			resp.Body.Close()
+			logr.Debugf("Retrying %s in %v after status code %d", requestURL, delay, resp.StatusCode)
+		} else {
+			logr.Debugf("Retrying %s in %v after error: %v", requestURL, delay, err)
+		}
+		limiter.pause(delay)
+	}
+}
+
+// request requests the API once its rate limit allows it
+func (d *AdvisoryDatabase) request(ctx context.Context, limiter *advisoryRateLimiter, requestURL string, headers map[string]string) (*http.Response, error) {
+	if err := limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not request %s: %v", requestURL, err)
+	}
+	return resp, nil
+}
+
+// retryAfter returns the delay of the Retry-After header, in seconds or as an HTTP date, the default delay when missing
+// or invalid
+func retryAfter(header string, defaultDelay time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+	return defaultDelay
+}
+
+func decodeAdvisoryResponse(resp *http.Response, requestURL string, response interface{}) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("could not request %s: status code %d", requestURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("error while decoding the response of %s: %v", requestURL, err)
+	}
+	return nil
+}
+
+// applyAdvisory completes the vulnerability with its advisory, the description of the advisory replacing the
+// description of the vulnerability when missing or truncated
+func (v *Vulnerabilities) applyAdvisory(advisory *Advisory) {
+	if advisory.Description != "" && truncatedDescription(v.Description, advisory.Description) {
+		v.Description = advisory.Description
+		v.DescriptionSource = advisory.Source
+	}
+	for _, reference := range advisory.References {
+		if !containsString(v.References, reference) {
+			v.References = append(v.References, reference)
+		}
+	}
+	v.ExploitReferences = advisory.ExploitReferences
+	if v.PublishedDate == nil {
+		v.PublishedDate = advisory.PublishedDate
+	}
+	if v.LastModifiedDate == nil || (advisory.LastModifiedDate != nil && advisory.LastModifiedDate.After(*v.LastModifiedDate)) {
+		v.LastModifiedDate = advisory.LastModifiedDate
+	}
+}
+
+// truncatedDescription returns true when the description is missing, ends with an ellipsis or is the beginning of the
+// full description of the advisory
+func truncatedDescription(description, advisoryDescription string) bool {
+	description = strings.TrimSpace(description)
+	if description == "" || strings.HasSuffix(description, "...") || strings.HasSuffix(description, "…") {
+		return true
+	}
+	return len(advisoryDescription) > len(description) && strings.HasPrefix(advisoryDescription, description)
+}
+
+// exploitReference returns true when the reference is hosted by a site publishing exploits
+func exploitReference(reference string) bool {
+	parsed, err := url.Parse(reference)
+	if err != nil {
+		return false
+	}
+	for _, host := range exploitHosts {
+		if parsed.Hostname() == host || strings.HasSuffix(parsed.Hostname(), "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAdvisoryTime(layout, value string) *time.Time {
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		return nil
+	}
+	parsed = parsed.UTC()
+	return &parsed
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// advisoryRateLimiter spaces out the requests of an advisory API so that they stay below its rate limit
+type advisoryRateLimiter struct {
+	interval time.Duration
+	lock     sync.Mutex
+	next     time.Time
+}
+
+// pause delays the next requests of the API by the delay at least
+func (l *advisoryRateLimiter) pause(delay time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if resume := time.Now().Add(delay); l.next.Before(resume) {
+		l.next = resume
+	}
+}
+
+// wait blocks until the API can be requested or the context is done
+func (l *advisoryRateLimiter) wait(ctx context.Context) error {
+	l.lock.Lock()
+	slot := l.next
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(slot)):
+		return nil
+	}
+}
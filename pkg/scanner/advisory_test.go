@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Advisories", func() {
+
+	const nvdResponse = `{"vulnerabilities":[{"cve":{"id":"CVE-2021-44228","published":"2021-12-10T10:15:09.143","lastModified":"2023-04-03T20:15:08.023",
+		"descriptions":[{"lang":"es","value":"Apache Log4j2 ..."},{"lang":"en","value":"Apache Log4j2 2.0-beta9 through 2.15.0 JNDI features do not protect against attacker controlled LDAP endpoints."}],
+		"references":[{"url":"https://logging.apache.org/log4j/2.x/security.html","tags":["Vendor Advisory"]},
+		{"url":"http://packetstormsecurity.com/files/165225/Apache-Log4j2-2.14.1-Remote-Code-Execution.html"},
+		{"url":"https://github.com/cisagov/log4j-affected-db","tags":["Exploit","Third Party Advisory"]}]}}]}`
+	const githubResponse = `{"ghsa_id":"GHSA-jfh8-c2jp-5v3q","description":"Log4j versions prior to 2.16.0 are subject to a remote code execution vulnerability.",
+		"references":["https://github.com/advisories/GHSA-jfh8-c2jp-5v3q","https://www.exploit-db.com/exploits/50592"],
+		"published_at":"2021-12-10T00:40:56Z","updated_at":"2023-01-27T05:01:30Z"}`
+
+	var (
+		tmpDir    string
+		server    *httptest.Server
+		lock      sync.Mutex
+		requests  []string
+		headers   http.Header
+		database  *AdvisoryDatabase
+		throttled int
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+		requests, headers, throttled = nil, http.Header{}, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			requests = append(requests, r.URL.RequestURI())
+			for _, header := range []string{"apiKey", "Authorization"} {
+				if value := r.Header.Get(header); value != "" {
+					headers.Set(header, value)
+				}
+			}
+			lock.Unlock()
+			switch {
+			case r.URL.Path == "/nvd" && r.URL.Query().Get("cveId") == "CVE-2021-44228":
+				_, _ = w.Write([]byte(nvdResponse))
+			case r.URL.Path == "/nvd":
+				_, _ = w.Write([]byte(`{"vulnerabilities":[]}`))
+			case r.URL.Path == "/advisories/GHSA-jfh8-c2jp-5v3q":
+				_, _ = w.Write([]byte(githubResponse))
+			case r.URL.Path == "/throttled" && throttled == 0:
+				throttled++
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case r.URL.Path == "/throttled":
+				_, _ = w.Write([]byte(nvdResponse))
+			case r.URL.Path == "/unavailable":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		database = NewAdvisoryDatabase(AdvisoryConfig{
+			NVDAPIURL:              server.URL + "/nvd",
+			NVDAPIKey:              "nvd-key",
+			GitHubAdvisoriesAPIURL: server.URL + "/advisories",
+			GitHubToken:            "github-token",
+			CacheDir:               filepath.Join(tmpDir, "cache"),
+			MaxAge:                 time.Hour,
+		})
+		database.nvd.interval, database.github.interval = 0, 0
+		database.retryBackoff = 0
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	batch := func(results []TrivyOutputResults) []ScannedImage {
+		return []ScannedImage{{ImageName: "api:1", TrivyOutputResults: results}}
+	}
+
+	It("completes the vulnerabilities with their NVD or GitHub advisory", func() {
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2021-44228", Description: "Apache Log4j2 2.0-beta9 through 2.15.0 JNDI features do not ...",
+				References: []string{"https://logging.apache.org/log4j/2.x/security.html"}},
+			{VulnerabilityID: "GHSA-jfh8-c2jp-5v3q", Description: "Remote code execution in Log4j"},
+			{VulnerabilityID: "CVE-2023-0001", Description: "Unknown to NVD"},
+			{VulnerabilityID: "DLA-3240-1", Description: "Debian advisory"},
+		}}}
+
+		database.enrich(context.Background(), batch(results))
+
+		log4j := results[0].Vulnerabilities[0]
+		Expect(log4j.Description).To(Equal("Apache Log4j2 2.0-beta9 through 2.15.0 JNDI features do not protect against attacker controlled LDAP endpoints."))
+		Expect(log4j.DescriptionSource).To(Equal(AdvisorySourceNVD))
+		Expect(log4j.References).To(HaveLen(3))
+		Expect(log4j.ExploitReferences).To(ConsistOf(
+			"http://packetstormsecurity.com/files/165225/Apache-Log4j2-2.14.1-Remote-Code-Execution.html",
+			"https://github.com/cisagov/log4j-affected-db"))
+		Expect(*log4j.PublishedDate).To(Equal(time.Date(2021, 12, 10, 10, 15, 9, 143000000, time.UTC)))
+		Expect(*log4j.LastModifiedDate).To(Equal(time.Date(2023, 4, 3, 20, 15, 8, 23000000, time.UTC)))
+
+		ghsa := results[0].Vulnerabilities[1]
+		Expect(ghsa.Description).To(Equal("Remote code execution in Log4j"))
+		Expect(ghsa.DescriptionSource).To(BeEmpty())
+		Expect(ghsa.ExploitReferences).To(Equal([]string{"https://www.exploit-db.com/exploits/50592"}))
+		Expect(*ghsa.PublishedDate).To(Equal(time.Date(2021, 12, 10, 0, 40, 56, 0, time.UTC)))
+
+		Expect(results[0].Vulnerabilities[2].Description).To(Equal("Unknown to NVD"))
+		Expect(results[0].Vulnerabilities[2].PublishedDate).To(BeNil())
+		Expect(results[0].Vulnerabilities[3].References).To(BeEmpty())
+		Expect(requests).To(HaveLen(3))
+		Expect(headers.Get("apiKey")).To(Equal("nvd-key"))
+		Expect(headers.Get("Authorization")).To(Equal("Bearer github-token"))
+	})
+
+	It("requests each advisory once, reusing the cached advisories until they are older than the maximum age", func() {
+		newResults := func() []TrivyOutputResults {
+			return []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228"}, {VulnerabilityID: "CVE-2023-0001"}}}}
+		}
+		database.enrich(context.Background(), batch(newResults()))
+		database.enrich(context.Background(), batch(newResults()))
+		Expect(requests).To(HaveLen(2))
+
+		database.advisories = make(map[string]*advisoryEntry)
+		results := newResults()
+		database.enrich(context.Background(), batch(results))
+		Expect(requests).To(HaveLen(2))
+		Expect(results[0].Vulnerabilities[0].DescriptionSource).To(Equal(AdvisorySourceNVD))
+
+		database.advisories = make(map[string]*advisoryEntry)
+		database.config.MaxAge = 0
+		database.enrich(context.Background(), batch(newResults()))
+		Expect(requests).To(HaveLen(4))
+	})
+
+	It("spaces out the requests of each API to stay below its rate limit", func() {
+		database.nvd.interval = 100 * time.Millisecond
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-0001"}, {VulnerabilityID: "CVE-2023-0002"}, {VulnerabilityID: "CVE-2023-0003"},
+			{VulnerabilityID: "GHSA-jfh8-c2jp-5v3q"},
+		}}}
+
+		start := time.Now()
+		database.enrich(context.Background(), batch(results))
+
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(requests).To(HaveLen(4))
+	})
+
+	It("leaves the vulnerabilities as is when the advisory cannot be read", func() {
+		database.config.NVDAPIURL = server.URL + "/unavailable"
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228", Description: "Log4Shell"}}}}
+
+		database.enrich(context.Background(), batch(results))
+
+		Expect(results[0].Vulnerabilities[0].Description).To(Equal("Log4Shell"))
+		Expect(filepath.Join(tmpDir, "cache", "CVE-2021-44228.json")).NotTo(BeAnExistingFile())
+	})
+
+	It("retries the requests throttled by the API after their Retry-After delay", func() {
+		database.config.NVDAPIURL = server.URL + "/throttled"
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228"}}}}
+
+		start := time.Now()
+		database.enrich(context.Background(), batch(results))
+
+		Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+		Expect(requests).To(HaveLen(2))
+		Expect(results[0].Vulnerabilities[0].Description).To(HavePrefix("Apache Log4j2 2.0-beta9"))
+	})
+
+	It("looks up the distinct vulnerabilities of the images of a batch once", func() {
+		images := []ScannedImage{
+			{ImageName: "api:1", TrivyOutputResults: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228"}}}}},
+			{ImageName: "web:1", TrivyOutputResults: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228"}}}}},
+		}
+
+		database.enrich(context.Background(), images)
+
+		Expect(requests).To(HaveLen(1))
+		for _, image := range images {
+			Expect(image.TrivyOutputResults[0].Vulnerabilities[0].DescriptionSource).To(Equal(AdvisorySourceNVD))
+		}
+	})
+
+	It("looks up the advisories that could not be read again with the next batches, a few times at most", func() {
+		database.config.NVDAPIURL = server.URL + "/unavailable"
+		newResults := func() []TrivyOutputResults {
+			return []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228"}}}}
+		}
+
+		database.enrich(context.Background(), batch(newResults()))
+		Expect(requests).To(HaveLen(advisoryRequestAttempts))
+		for i := 0; i < advisoryLookupAttempts; i++ {
+			database.enrich(context.Background(), batch(newResults()))
+		}
+		Expect(requests).To(HaveLen(advisoryLookupAttempts * advisoryRequestAttempts))
+	})
+})
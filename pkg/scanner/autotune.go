@@ -0,0 +1,196 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// workerMemory is the memory a worker needs to pull and scan an image with trivy
+	workerMemory = 1 << 30
+	// workerDisk is the disk space a worker needs for the pulled image and its unpacked layers
+	workerDisk = 4 << 30
+	// ImageSlotSize is the compressed image size a scan slot holds when the scans are weighted by size, the scans of the
+	// larger images taking several slots, see Config.WeightScansBySize
+	ImageSlotSize = 500 << 20
+)
+
+// Resources are the resources of the scanning host the number of workers is sized from, 0 when unknown
+type Resources struct {
+	CPUs int
+	// Memory is the memory available to the process, the memory limit of its cgroup or else the available memory of the host
+	Memory int64
+	// Disk is the free disk space of the directory the images are pulled to
+	Disk int64
+}
+
+// DetectResources returns the CPUs of the host, limited by the CPU quota of the cgroup of the process, its available
+// memory and the free disk space of the directory. The limits of both the cgroup v2 and the cgroup v1 hierarchies are
+// read, the cgroup v1 files being those of the cgroup of the process when its hierarchy is mounted as in a container
+func DetectResources(dir string) Resources {
+	cpuMax := readFile("/sys/fs/cgroup/cpu.max")
+	if cpuMax == "" {
+		cpuMax = cgroupV1CPUMax(readFile("/sys/fs/cgroup/cpu/cpu.cfs_quota_us"), readFile("/sys/fs/cgroup/cpu/cpu.cfs_period_us"))
+	}
+	memoryMax := readFile("/sys/fs/cgroup/memory.max")
+	if memoryMax == "" {
+		memoryMax = cgroupV1MemoryMax(readFile("/sys/fs/cgroup/memory/memory.limit_in_bytes"))
+	}
+	return Resources{
+		CPUs:   cgroupCPUs(cpuMax, runtime.NumCPU()),
+		Memory: availableMemory(memoryMax, readFile("/proc/meminfo")),
+		Disk:   freeDiskSpace(dir),
+	}
+}
+
+// Workers returns the number of workers the resources allow, a worker per CPU, workerMemory of memory and workerDisk
+// of disk space, the unknown resources being ignored. There is at least 1 worker and at most maxWorkers
+func (r Resources) Workers(maxWorkers int) int {
+	workers := maxWorkers
+	limit := func(allowed int) {
+		if allowed < workers {
+			workers = allowed
+		}
+	}
+	if r.CPUs > 0 {
+		limit(r.CPUs)
+	}
+	if r.Memory > 0 {
+		limit(int(r.Memory / workerMemory))
+	}
+	if r.Disk > 0 {
+		limit(int(r.Disk / workerDisk))
+	}
+	if workers < 1 {
+		return 1
+	}
+	return workers
+}
+
+// cgroupCPUs returns the CPUs of the cgroup v2 CPU quota, such as "200000 100000", rounded up, or the CPUs of the host
+// when there is no quota
+func cgroupCPUs(cpuMax string, hostCPUs int) int {
+	fields := strings.Fields(cpuMax)
+	if len(fields) != 2 || fields[0] == "max" {
+		return hostCPUs
+	}
+	quota, quotaErr := strconv.ParseInt(fields[0], 10, 64)
+	period, periodErr := strconv.ParseInt(fields[1], 10, 64)
+	if quotaErr != nil || periodErr != nil || period <= 0 {
+		return hostCPUs
+	}
+	cpus := int((quota + period - 1) / period)
+	if cpus < hostCPUs {
+		return cpus
+	}
+	return hostCPUs
+}
+
+// cgroupV1CPUMax returns the cgroup v1 CFS quota and period in the cgroup v2 cpu.max format, max when there is no
+// quota, -1 in cgroup v1
+func cgroupV1CPUMax(quota, period string) string {
+	if quota == "" || quota == "-1" || period == "" {
+		return "max"
+	}
+	return quota + " " + period
+}
+
+// cgroupV1MemoryMax returns the cgroup v1 memory limit in the cgroup v2 memory.max format, max when there is no limit,
+// cgroup v1 reporting the largest page aligned int64 rather than no limit
+func cgroupV1MemoryMax(limit string) string {
+	value, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil || value >= 1<<62 {
+		return "max"
+	}
+	return limit
+}
+
+// availableMemory returns the cgroup v2 memory limit, or else the MemAvailable of the meminfo, 0 when unknown
+func availableMemory(memoryMax, meminfo string) int64 {
+	if limit, err := strconv.ParseInt(strings.TrimSpace(memoryMax), 10, 64); err == nil && limit > 0 {
+		return limit
+	}
+	scanner := bufio.NewScanner(strings.NewReader(meminfo))
+	for scanner.Scan() {
+		// MemAvailable:   12345678 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kilobytes, err := strconv.ParseInt(fields[1], 10, 64)
+			if err == nil {
+				return kilobytes << 10
+			}
+		}
+	}
+	return 0
+}
+
+// readFile returns the content of the file, empty when it cannot be read
+func readFile(filename string) string {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+	return string(bytes.TrimSpace(content))
+}
+
+// imageSlots returns the scan slots of an image of the compressed size, a slot per ImageSlotSize, at least 1 and at most
+// all the slots. The images of unknown size take 1 slot
+func imageSlots(size int64, allSlots int) int {
+	slots := int((size + ImageSlotSize - 1) / ImageSlotSize)
+	if slots < 1 {
+		return 1
+	}
+	if slots > allSlots {
+		return allSlots
+	}
+	return slots
+}
+
+// scanSlots is a weighted semaphore admitting the scans in order, so that the scans of the large images are not
+// starved by the scans of the small ones
+type scanSlots struct {
+	lock    sync.Mutex
+	free    int
+	waiters []slotWaiter
+}
+
+type slotWaiter struct {
+	slots int
+	ready chan struct{}
+}
+
+func newScanSlots(slots int) *scanSlots {
+	return &scanSlots{free: slots}
+}
+
+// acquire waits for the slots to be free
+func (s *scanSlots) acquire(slots int) {
+	s.lock.Lock()
+	if len(s.waiters) == 0 && s.free >= slots {
+		s.free -= slots
+		s.lock.Unlock()
+		return
+	}
+	waiter := slotWaiter{slots: slots, ready: make(chan struct{})}
+	s.waiters = append(s.waiters, waiter)
+	s.lock.Unlock()
+	<-waiter.ready
+}
+
+// release frees the slots, admitting the waiting scans in order
+func (s *scanSlots) release(slots int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.free += slots
+	for len(s.waiters) > 0 && s.waiters[0].slots <= s.free {
+		waiter := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		s.free -= waiter.slots
+		close(waiter.ready)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package scanner
+
+// freeDiskSpace returns 0 as the free disk space is unknown on this platform
+func freeDiskSpace(_ string) int64 {
+	return 0
+}
@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Worker auto-tuning", func() {
+
+	It("sizes the workers from the most constrained resource", func() {
+		Expect(Resources{CPUs: 8, Memory: 16 << 30, Disk: 100 << 30}.Workers(10)).To(Equal(8))
+		Expect(Resources{CPUs: 8, Memory: 3 << 30, Disk: 100 << 30}.Workers(10)).To(Equal(3))
+		Expect(Resources{CPUs: 8, Memory: 16 << 30, Disk: 9 << 30}.Workers(10)).To(Equal(2))
+		Expect(Resources{CPUs: 64}.Workers(10)).To(Equal(10))
+		Expect(Resources{CPUs: 2, Memory: 512 << 20}.Workers(10)).To(Equal(1))
+	})
+
+	It("reads the CPU quota and the memory limit of the cgroup", func() {
+		Expect(cgroupCPUs("150000 100000", 8)).To(Equal(2))
+		Expect(cgroupCPUs("max 100000", 8)).To(Equal(8))
+		Expect(cgroupCPUs("", 8)).To(Equal(8))
+		Expect(availableMemory("2147483648", "MemAvailable: 1024 kB")).To(Equal(int64(2 << 30)))
+		Expect(availableMemory("max", "MemTotal: 4096 kB\nMemAvailable:    1024 kB")).To(Equal(int64(1 << 20)))
+		Expect(availableMemory("", "")).To(Equal(int64(0)))
+	})
+
+	It("reads the CPU quota and the memory limit of the cgroup v1 hierarchy", func() {
+		Expect(cgroupCPUs(cgroupV1CPUMax("150000", "100000"), 8)).To(Equal(2))
+		Expect(cgroupCPUs(cgroupV1CPUMax("-1", "100000"), 8)).To(Equal(8))
+		Expect(cgroupCPUs(cgroupV1CPUMax("", ""), 8)).To(Equal(8))
+		Expect(availableMemory(cgroupV1MemoryMax("2147483648"), "MemAvailable: 1024 kB")).To(Equal(int64(2 << 30)))
+		Expect(availableMemory(cgroupV1MemoryMax("9223372036854771712"), "MemAvailable: 1024 kB")).To(Equal(int64(1 << 20)))
+	})
+
+	It("gives the larger images more scan slots", func() {
+		Expect(imageSlots(0, 4)).To(Equal(1))
+		Expect(imageSlots(100<<20, 4)).To(Equal(1))
+		Expect(imageSlots(1200<<20, 4)).To(Equal(3))
+		Expect(imageSlots(10<<30, 4)).To(Equal(4))
+	})
+
+	It("admits the scans waiting for slots in order", func() {
+		slots := newScanSlots(2)
+		slots.acquire(1)
+
+		large := make(chan struct{})
+		go func() {
+			slots.acquire(2)
+			close(large)
+		}()
+		Eventually(func() int {
+			slots.lock.Lock()
+			defer slots.lock.Unlock()
+			return len(slots.waiters)
+		}).Should(Equal(1))
+
+		small := make(chan struct{})
+		go func() {
+			slots.acquire(1)
+			close(small)
+		}()
+		Consistently(small, 50*time.Millisecond).ShouldNot(BeClosed())
+
+		slots.release(1)
+		Eventually(large).Should(BeClosed())
+		Consistently(small, 50*time.Millisecond).ShouldNot(BeClosed())
+		slots.release(2)
+		Eventually(small).Should(BeClosed())
+	})
+})
@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package scanner
+
+import "syscall"
+
+// freeDiskSpace returns the disk space available to unprivileged users in the directory, 0 when unknown
+func freeDiskSpace(dir string) int64 {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize)
+}
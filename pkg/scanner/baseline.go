@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/reportschema"
+)
+
+// VulnerabilityFinding is a single vulnerability found in an image
+type VulnerabilityFinding struct {
+	ImageName     string
+	Vulnerability Vulnerabilities
+	Containers    []k8s.ContainerSummary
+}
+
+type findingKey struct {
+	imageName, vulnerabilityID, pkgName string
+}
+
+// LoadVulnerabilityReport reads the image scan section of a json report previously saved with the report-output-filename-json option
+func LoadVulnerabilityReport(filename string) (*VulnerabilityReport, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read report file %s: %v", filename, err)
+	}
+	content, err = reportschema.Upgrade(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not upgrade report file %s: %v", filename, err)
+	}
+
+	var savedReport struct {
+		ImageScan *VulnerabilityReport
+	}
+	err = json.Unmarshal(content, &savedReport)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding report file %s: %v", filename, err)
+	}
+	if savedReport.ImageScan == nil {
+		return nil, fmt.Errorf("report file %s does not contain an image scan", filename)
+	}
+	return savedReport.ImageScan, nil
+}
+
+// NewVulnerabilities returns the vulnerabilities matching the severity that are present in the report but not in the baseline.
+// All the vulnerabilities matching the severity are returned when no baseline is given. The vulnerabilities triaged as
+// false positives or accepted are never returned.
+func (r *VulnerabilityReport) NewVulnerabilities(baseline *VulnerabilityReport, severity string) []VulnerabilityFinding {
+	known := make(map[findingKey]bool)
+	if baseline != nil {
+		for _, image := range baseline.ScannedImages {
+			for _, target := range image.Results() {
+				for _, vulnerability := range target.Vulnerabilities {
+					known[findingKey{image.ImageName, vulnerability.VulnerabilityID, vulnerability.PkgName}] = true
+				}
+			}
+		}
+	}
+
+	var findings []VulnerabilityFinding
+	seen := make(map[findingKey]bool)
+	for _, image := range r.ScannedImages {
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				key := findingKey{image.ImageName, vulnerability.VulnerabilityID, vulnerability.PkgName}
+				if vulnerability.Severity != severity || vulnerability.Triage.Dismissed() || known[key] || seen[key] {
+					continue
+				}
+				seen[key] = true
+				findings = append(findings, VulnerabilityFinding{
+					ImageName:     image.ImageName,
+					Vulnerability: vulnerability,
+					Containers:    image.Containers,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// FixableFindings returns the findings of the vulnerabilities with a fixed version, so that the teams are only
+// alerted about the vulnerabilities they can act on
+func FixableFindings(findings []VulnerabilityFinding) []VulnerabilityFinding {
+	var fixable []VulnerabilityFinding
+	for _, finding := range findings {
+		if finding.Vulnerability.Fixable() {
+			fixable = append(fixable, finding)
+		}
+	}
+	return fixable
+}
+
+// KnownExploitedVulnerabilities returns the vulnerabilities of the report images exploited in the wild,
+// when the vulnerabilities are cross-referenced with the CISA Known Exploited Vulnerabilities catalog
+func (r *VulnerabilityReport) KnownExploitedVulnerabilities() []VulnerabilityFinding {
+	return matchingFindings(r.ScannedImages, knownExploited)
+}
+
+// KnownExploitedVulnerabilities returns the vulnerabilities of the team images exploited in the wild
+func (t *TeamSummary) KnownExploitedVulnerabilities() []VulnerabilityFinding {
+	return matchingFindings(t.Images, knownExploited)
+}
+
+// SeverityOverrides returns the vulnerabilities of the team images whose severity is overridden
+func (t *TeamSummary) SeverityOverrides() []VulnerabilityFinding {
+	return matchingFindings(t.Images, func(v Vulnerabilities) bool { return v.OverriddenSeverity != nil })
+}
+
+func knownExploited(v Vulnerabilities) bool {
+	return v.KnownExploited != nil
+}
+
+// matchingFindings returns the vulnerabilities of the images matching the predicate, once per image and package
+func matchingFindings(images []ScannedImage, matches func(Vulnerabilities) bool) []VulnerabilityFinding {
+	var findings []VulnerabilityFinding
+	seen := make(map[findingKey]bool)
+	for _, image := range images {
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				key := findingKey{image.ImageName, vulnerability.VulnerabilityID, vulnerability.PkgName}
+				if !matches(vulnerability) || seen[key] {
+					continue
+				}
+				seen[key] = true
+				findings = append(findings, VulnerabilityFinding{
+					ImageName:     image.ImageName,
+					Vulnerability: vulnerability,
+					Containers:    image.Containers,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// Findings returns the findings affecting the team images, restricting their containers to the ones owned by the team
+func (t *TeamSummary) Findings(findings []VulnerabilityFinding) []VulnerabilityFinding {
+	teamImages := make(map[string]ScannedImage)
+	for _, image := range t.Images {
+		teamImages[image.ImageName] = image
+	}
+
+	var result []VulnerabilityFinding
+	for _, finding := range findings {
+		if image, ok := teamImages[finding.ImageName]; ok {
+			finding.Containers = image.Containers
+			result = append(result, finding)
+		}
+	}
+	return result
+}
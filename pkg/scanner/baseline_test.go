@@ -0,0 +1,127 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Baseline", func() {
+
+	var (
+		report *VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		report = &VulnerabilityReport{
+			ScannedImages: []ScannedImage{
+				NewScannedImage("image1", []k8s.ContainerSummary{{PodName: "pod1"}}, []TrivyOutputResults{
+					{Vulnerabilities: []Vulnerabilities{
+						{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"},
+						{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"},
+					}},
+				}, nil),
+				NewScannedImage("image2", []k8s.ContainerSummary{{PodName: "pod2"}}, []TrivyOutputResults{
+					{Vulnerabilities: []Vulnerabilities{
+						{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"},
+						{VulnerabilityID: "CVE-3", PkgName: "zlib", Severity: "CRITICAL"},
+					}},
+				}, nil),
+			},
+		}
+	})
+
+	Describe("NewVulnerabilities", func() {
+
+		It("returns all the vulnerabilities of the severity when there is no baseline", func() {
+			findings := report.NewVulnerabilities(nil, "CRITICAL")
+
+			Expect(findings).To(HaveLen(3))
+			Expect(findings[0].ImageName).To(Equal("image1"))
+			Expect(findings[0].Vulnerability.VulnerabilityID).To(Equal("CVE-1"))
+			Expect(findings[0].Containers).To(Equal([]k8s.ContainerSummary{{PodName: "pod1"}}))
+		})
+
+		It("excludes the vulnerabilities already present in the baseline for the same image", func() {
+			baseline := &VulnerabilityReport{
+				ScannedImages: []ScannedImage{report.ScannedImages[0]},
+			}
+
+			findings := report.NewVulnerabilities(baseline, "CRITICAL")
+
+			Expect(findings).To(HaveLen(2))
+			Expect(findings[0].ImageName).To(Equal("image2"))
+			Expect(findings[0].Vulnerability.VulnerabilityID).To(Equal("CVE-1"))
+			Expect(findings[1].Vulnerability.VulnerabilityID).To(Equal("CVE-3"))
+		})
+	})
+
+	Describe("FixableFindings", func() {
+
+		It("returns the findings of the vulnerabilities with a fixed version", func() {
+			findings := []VulnerabilityFinding{
+				{ImageName: "image1", Vulnerability: Vulnerabilities{VulnerabilityID: "CVE-1", FixedVersion: "3.0.8"}},
+				{ImageName: "image1", Vulnerability: Vulnerabilities{VulnerabilityID: "CVE-2"}},
+			}
+
+			Expect(FixableFindings(findings)).To(Equal(findings[:1]))
+		})
+	})
+
+	Describe("LoadVulnerabilityReport", func() {
+
+		var (
+			tmpDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		})
+
+		It("reads the image scan of a saved report including the scan errors", func() {
+			report.ScannedImages = append(report.ScannedImages, NewScannedImage("image3", nil, nil, fmt.Errorf("some trivy error")))
+			content, err := json.Marshal(map[string]interface{}{"ImageScan": report})
+			Expect(err).NotTo(HaveOccurred())
+			filename := filepath.Join(tmpDir, "report.json")
+			Expect(os.WriteFile(filename, content, 0644)).To(Succeed())
+
+			loaded, err := LoadVulnerabilityReport(filename)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded.ScannedImages).To(HaveLen(3))
+			Expect(loaded.ScannedImages[0].TrivyOutputResults).To(Equal(report.ScannedImages[0].TrivyOutputResults))
+			Expect(loaded.ScannedImages[2].ScanError).To(MatchError("some trivy error"))
+		})
+
+		It("returns an error when the report has no image scan", func() {
+			filename := filepath.Join(tmpDir, "report.json")
+			Expect(os.WriteFile(filename, []byte(`{"LinuxCIS": {}}`), 0644)).To(Succeed())
+
+			_, err := LoadVulnerabilityReport(filename)
+
+			Expect(err).To(MatchError(ContainSubstring("does not contain an image scan")))
+		})
+
+		It("upgrades reports saved before the schema was versioned", func() {
+			filename := filepath.Join(tmpDir, "report.json")
+			Expect(os.WriteFile(filename, []byte(`{"ImageScan": {"ScannedImages": [{"ImageName": "alpine", "ScanError": {}}]}}`), 0644)).To(Succeed())
+
+			loaded, err := LoadVulnerabilityReport(filename)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded.ScannedImages[0].ScanError).To(MatchError("image scan failed"))
+		})
+	})
+})
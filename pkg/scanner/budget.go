@@ -0,0 +1,233 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// SeverityBudget is the maximum number of CRITICAL and HIGH vulnerabilities of a team, or of an area when it has an
+// area but no team, the vulnerabilities of all the teams of the area counting towards it. A budget without area or team
+// applies to each team. A nil maximum does not limit the severity
+type SeverityBudget struct {
+	Area     string `json:"area"`
+	Team     string `json:"team"`
+	Critical *int   `json:"critical"`
+	High     *int   `json:"high"`
+}
+
+// SeverityBudgets are the budgets of the teams and of the areas, the first budget matching the area and team of a team
+// applying to the team, and the first area budget of an area to the area
+type SeverityBudgets struct {
+	Budgets []SeverityBudget `json:"budgets"`
+	// FixableOnly only counts the vulnerabilities with a fixed version towards the budgets, as the teams cannot act on
+	// the other ones
+	FixableOnly bool `json:"-"`
+}
+
+// BudgetConsumption is the consumption of the budget of a team or of an area, one line per limited severity
+type BudgetConsumption struct {
+	Lines []BudgetLine
+	// FixableOnly is true when only the vulnerabilities with a fixed version count towards the budget
+	FixableOnly bool `json:",omitempty"`
+}
+
+// BudgetLine is the number of vulnerabilities of a severity and its maximum
+type BudgetLine struct {
+	Severity string
+	Count    int
+	Budget   int
+}
+
+// LoadSeverityBudgets reads the severity budgets from a yaml or json file such as:
+//
+//	budgets:
+//	- area: finance
+//	  team: payments
+//	  critical: 0
+//	  high: 5
+//	- area: finance
+//	  critical: 0
+//	  high: 20
+//	- critical: 2
+//	  high: 50
+func LoadSeverityBudgets(filename string) (*SeverityBudgets, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read severity budgets file %s: %v", filename, err)
+	}
+	var budgets SeverityBudgets
+	if err := yaml.Unmarshal(content, &budgets); err != nil {
+		return nil, fmt.Errorf("error while decoding severity budgets file %s: %v", filename, err)
+	}
+	for n, budget := range budgets.Budgets {
+		if budget.Critical == nil && budget.High == nil {
+			return nil, fmt.Errorf("severity budget %d of %s limits no severity, a critical or a high maximum is required", n+1, filename)
+		}
+		if (budget.Critical != nil && *budget.Critical < 0) || (budget.High != nil && *budget.High < 0) {
+			return nil, fmt.Errorf("severity budget %d of %s has a negative maximum", n+1, filename)
+		}
+	}
+	return &budgets, nil
+}
+
+// areaBudget returns true when the budget limits an area rather than its teams
+func (b SeverityBudget) areaBudget() bool {
+	return b.Area != "" && b.Team == ""
+}
+
+// consumption returns the consumption of the budget of the team, nil when no budget matches the team.
+// Nil budgets match no team
+func (b *SeverityBudgets) consumption(area string, team *TeamSummary) *BudgetConsumption {
+	if b == nil {
+		return nil
+	}
+	for _, budget := range b.Budgets {
+		if budget.areaBudget() || (budget.Area != "" && budget.Area != area) || (budget.Team != "" && budget.Team != team.Name) {
+			continue
+		}
+		return budget.consumption(team.budgetCounts(b.FixableOnly), b.FixableOnly)
+	}
+	return nil
+}
+
+// areaConsumption returns the consumption of the budget of the area, nil when no area budget matches the area.
+// Nil budgets match no area
+func (b *SeverityBudgets) areaConsumption(area *AreaSummary) *BudgetConsumption {
+	if b == nil {
+		return nil
+	}
+	for _, budget := range b.Budgets {
+		if budget.areaBudget() && budget.Area == area.Name {
+			return budget.consumption(area.budgetCounts(b.FixableOnly), b.FixableOnly)
+		}
+	}
+	return nil
+}
+
+// consumption returns the consumption of the budget by the vulnerability counts by severity
+func (b SeverityBudget) consumption(counts map[string]int, fixableOnly bool) *BudgetConsumption {
+	consumption := &BudgetConsumption{FixableOnly: fixableOnly}
+	for _, limit := range []struct {
+		severity string
+		maximum  *int
+	}{{"CRITICAL", b.Critical}, {"HIGH", b.High}} {
+		if limit.maximum != nil {
+			consumption.Lines = append(consumption.Lines, BudgetLine{Severity: limit.severity, Count: counts[limit.severity], Budget: *limit.maximum})
+		}
+	}
+	return consumption
+}
+
+// Exceeded returns true when the number of vulnerabilities exceeds the budget
+func (l BudgetLine) Exceeded() bool {
+	return l.Count > l.Budget
+}
+
+// Consumption returns the percentage of the budget consumed, for instance 40%, or - when the budget is zero
+func (l BudgetLine) Consumption() string {
+	if l.Budget == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", l.Count*100/l.Budget)
+}
+
+// recount returns the consumption of the same budget by the vulnerability counts by severity, all of them or the fixable
+// ones as the budget counts, nil when the consumption is nil
+func (c *BudgetConsumption) recount(counts budgetCounter) *BudgetConsumption {
+	if c == nil {
+		return nil
+	}
+	recounted := &BudgetConsumption{FixableOnly: c.FixableOnly}
+	for _, line := range c.Lines {
+		recounted.Lines = append(recounted.Lines, BudgetLine{Severity: line.Severity, Count: counts.budgetCounts(c.FixableOnly)[line.Severity], Budget: line.Budget})
+	}
+	return recounted
+}
+
+// budgetCounter is a team or an area whose vulnerabilities count towards a budget
+type budgetCounter interface {
+	// budgetCounts returns the vulnerability counts by severity, only of the vulnerabilities with a fixed version when
+	// fixableOnly is true
+	budgetCounts(fixableOnly bool) map[string]int
+}
+
+// budgetCounts returns the vulnerability counts of the team images by severity, only of the fixable ones when
+// fixableOnly is true
+func (t *TeamSummary) budgetCounts(fixableOnly bool) map[string]int {
+	if !fixableOnly {
+		return t.TotalVulnerabilityBySeverity()
+	}
+	return t.FixableVulnerabilityBySeverity()
+}
+
+// budgetCounts returns the vulnerability counts of the area teams by severity, only of the fixable ones when
+// fixableOnly is true
+func (a *AreaSummary) budgetCounts(fixableOnly bool) map[string]int {
+	if !fixableOnly {
+		return a.TotalVulnerabilityBySeverity
+	}
+	total := make(map[string]int)
+	for _, team := range a.Teams {
+		for severity, count := range team.FixableVulnerabilityBySeverity() {
+			total[severity] += count
+		}
+	}
+	return total
+}
+
+// Exceeded returns the lines of the budget exceeded, none when the consumption is nil
+func (c *BudgetConsumption) Exceeded() []BudgetLine {
+	if c == nil {
+		return nil
+	}
+	var exceeded []BudgetLine
+	for _, line := range c.Lines {
+		if line.Exceeded() {
+			exceeded = append(exceeded, line)
+		}
+	}
+	return exceeded
+}
+
+// ExceededBudget is a severity budget a team exceeds, or an area when Team is empty
+type ExceededBudget struct {
+	Area string
+	Team string
+	BudgetLine
+}
+
+// String describes the exceeded budget, for instance "Team payments of area finance has 6 HIGH vulnerabilities,
+// exceeding its budget of 5"
+func (b ExceededBudget) String() string {
+	owner := fmt.Sprintf("Team %s of area %s", b.Team, b.Area)
+	if b.Team == "" {
+		owner = "Area " + b.Area
+	}
+	return fmt.Sprintf("%s has %d %s vulnerabilities, exceeding its budget of %d", owner, b.Count, b.Severity, b.Budget)
+}
+
+// ExceededBudgets returns the budgets the areas and the teams exceed, sorted by area and team, the budget of an area
+// before the budgets of its teams
+func (r *VulnerabilityReport) ExceededBudgets() []ExceededBudget {
+	var exceeded []ExceededBudget
+	for _, area := range r.AreaSummary {
+		for _, line := range area.Budget.Exceeded() {
+			exceeded = append(exceeded, ExceededBudget{Area: area.Name, BudgetLine: line})
+		}
+		for _, team := range area.Teams {
+			for _, line := range team.Budget.Exceeded() {
+				exceeded = append(exceeded, ExceededBudget{Area: area.Name, Team: team.Name, BudgetLine: line})
+			}
+		}
+	}
+	sort.SliceStable(exceeded, func(i, j int) bool {
+		if exceeded[i].Area != exceeded[j].Area {
+			return exceeded[i].Area < exceeded[j].Area
+		}
+		return exceeded[i].Team < exceeded[j].Team
+	})
+	return exceeded
+}
@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Severity budgets", func() {
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	writeBudgets := func(content string) string {
+		filename := filepath.Join(tmpDir, "budgets.yaml")
+		Expect(os.WriteFile(filename, []byte(content), 0644)).To(Succeed())
+		return filename
+	}
+
+	teamImage := func(name, area, team string, critical, high int) ScannedImage {
+		return ScannedImage{
+			ImageName:  name,
+			Containers: []k8s.ContainerSummary{{Image: name, NamespaceLabels: map[string]string{"area": area, "team": team}}},
+			VulnerabilitySummary: VulnerabilitySummary{
+				TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": critical, "HIGH": high},
+			},
+		}
+	}
+
+	It("reports the consumption of the first budget matching each area and team and the exceeded budgets", func() {
+		budgets, err := LoadSeverityBudgets(writeBudgets(`
+budgets:
+- area: finance
+  team: payments
+  critical: 0
+  high: 5
+- area: finance
+  high: 20
+- critical: 2
+  high: 10
+`))
+		Expect(err).NotTo(HaveOccurred())
+		reportGenerator := &AreaReport{AreaLabelName: "area", TeamLabelName: "team", Budgets: budgets}
+
+		report, err := reportGenerator.GenerateVulnerabilityReport([]ScannedImage{
+			teamImage("api:1", "finance", "payments", 1, 2),
+			teamImage("worker:1", "finance", "payments", 0, 2),
+			teamImage("ledger:1", "finance", "accounting", 4, 12),
+			teamImage("web:1", "commerce", "orders", 1, 11),
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.AreaSummary["finance"].Teams["payments"].Budget).To(Equal(&BudgetConsumption{Lines: []BudgetLine{
+			{Severity: "CRITICAL", Count: 1, Budget: 0}, {Severity: "HIGH", Count: 4, Budget: 5},
+		}}))
+		Expect(report.AreaSummary["finance"].Teams["accounting"].Budget).To(Equal(&BudgetConsumption{Lines: []BudgetLine{
+			{Severity: "CRITICAL", Count: 4, Budget: 2}, {Severity: "HIGH", Count: 12, Budget: 10},
+		}}))
+		Expect(report.AreaSummary["finance"].Budget).To(Equal(&BudgetConsumption{Lines: []BudgetLine{
+			{Severity: "HIGH", Count: 16, Budget: 20},
+		}}))
+		Expect(report.AreaSummary["commerce"].Budget).To(BeNil())
+		Expect(report.AreaSummary["finance"].Teams["payments"].Budget.Lines[1].Consumption()).To(Equal("80%"))
+		Expect(report.ExceededBudgets()).To(Equal([]ExceededBudget{
+			{Area: "commerce", Team: "orders", BudgetLine: BudgetLine{Severity: "HIGH", Count: 11, Budget: 10}},
+			{Area: "finance", Team: "accounting", BudgetLine: BudgetLine{Severity: "CRITICAL", Count: 4, Budget: 2}},
+			{Area: "finance", Team: "accounting", BudgetLine: BudgetLine{Severity: "HIGH", Count: 12, Budget: 10}},
+			{Area: "finance", Team: "payments", BudgetLine: BudgetLine{Severity: "CRITICAL", Count: 1, Budget: 0}},
+		}))
+	})
+
+	It("reports the areas exceeding their budget", func() {
+		budgets, err := LoadSeverityBudgets(writeBudgets(`
+budgets:
+- area: finance
+  critical: 1
+`))
+		Expect(err).NotTo(HaveOccurred())
+		reportGenerator := &AreaReport{AreaLabelName: "area", TeamLabelName: "team", Budgets: budgets}
+
+		report, err := reportGenerator.GenerateVulnerabilityReport([]ScannedImage{
+			teamImage("api:1", "finance", "payments", 1, 0),
+			teamImage("ledger:1", "finance", "accounting", 1, 0),
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.AreaSummary["finance"].Teams["payments"].Budget).To(BeNil())
+		exceeded := report.ExceededBudgets()
+		Expect(exceeded).To(Equal([]ExceededBudget{{Area: "finance", BudgetLine: BudgetLine{Severity: "CRITICAL", Count: 2, Budget: 1}}}))
+		Expect(exceeded[0].String()).To(Equal("Area finance has 2 CRITICAL vulnerabilities, exceeding its budget of 1"))
+	})
+
+	It("only counts the vulnerabilities with a fixed version towards the budgets with fixable only", func() {
+		budgets, err := LoadSeverityBudgets(writeBudgets(`
+budgets:
+- area: finance
+  critical: 1
+- critical: 0
+`))
+		Expect(err).NotTo(HaveOccurred())
+		budgets.FixableOnly = true
+		reportGenerator := &AreaReport{AreaLabelName: "area", TeamLabelName: "team", Budgets: budgets}
+		unfixable := teamImage("api:1", "finance", "payments", 2, 0)
+		fixable := teamImage("ledger:1", "finance", "accounting", 2, 0)
+		fixable.VulnerabilitySummary.FixableVulnerabilityBySeverity = map[string]int{"CRITICAL": 1}
+
+		report, err := reportGenerator.GenerateVulnerabilityReport([]ScannedImage{unfixable, fixable})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.AreaSummary["finance"].Teams["payments"].Budget).To(Equal(&BudgetConsumption{Lines: []BudgetLine{
+			{Severity: "CRITICAL", Count: 0, Budget: 0},
+		}, FixableOnly: true}))
+		Expect(report.AreaSummary["finance"].Budget).To(Equal(&BudgetConsumption{Lines: []BudgetLine{
+			{Severity: "CRITICAL", Count: 1, Budget: 1},
+		}, FixableOnly: true}))
+		Expect(report.ExceededBudgets()).To(Equal([]ExceededBudget{
+			{Area: "finance", Team: "accounting", BudgetLine: BudgetLine{Severity: "CRITICAL", Count: 1, Budget: 0}},
+		}))
+	})
+
+	It("reports no consumption without budgets", func() {
+		report, err := (&AreaReport{AreaLabelName: "area", TeamLabelName: "team"}).GenerateVulnerabilityReport([]ScannedImage{
+			teamImage("api:1", "finance", "payments", 1, 2),
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.AreaSummary["finance"].Teams["payments"].Budget).To(BeNil())
+		Expect(report.ExceededBudgets()).To(BeEmpty())
+	})
+
+	It("rejects the budgets limiting no severity", func() {
+		_, err := LoadSeverityBudgets(writeBudgets(`
+budgets:
+- area: finance
+`))
+
+		Expect(err).To(MatchError(ContainSubstring("limits no severity")))
+	})
+})
@@ -0,0 +1,39 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+// changedSince returns the containers running the images of the pods created or updated since the time, so that only
+// those images are scanned. All the containers of those images are kept for the report to count them, and the
+// containers of unknown change time, such as the containers of the workloads without pod, are left out
+func changedSince(containers []k8s.ContainerSummary, since time.Time) []k8s.ContainerSummary {
+	changedImages := make(map[string]bool)
+	for _, container := range containers {
+		if container.ChangedAt != nil && !container.ChangedAt.Before(since) {
+			changedImages[container.Image] = true
+		}
+	}
+	var changed []k8s.ContainerSummary
+	for _, container := range containers {
+		if changedImages[container.Image] {
+			changed = append(changed, container)
+		}
+	}
+	logr.Infof("%d image(s) run by the pods created or updated since %s", len(changedImages), since.Format(time.RFC3339))
+	return changed
+}
+
+// selectChanged restricts the containers to the images of the pods changed within Config.Since, recording the start
+// of the period in the report metadata. All the containers are kept when Since is 0
+func (s *Scanner) selectChanged(containers []k8s.ContainerSummary, metadata *ReportMetadata) []k8s.ContainerSummary {
+	if s.config.Since <= 0 {
+		return containers
+	}
+	since := time.Now().UTC().Add(-s.config.Since).Truncate(time.Second)
+	metadata.ChangedSince = &since
+	return changedSince(containers, since)
+}
@@ -0,0 +1,128 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+// checkpoint records each scanned image to a file as a json line as soon as its scan finishes, so that a scan that
+// crashed or was killed can be resumed without scanning the recorded images again
+type checkpoint struct {
+	filename string
+	file     *os.File
+	// scannedImages are the images recorded by the interrupted scan, only read by the workers
+	scannedImages map[string]ScannedImage
+	// recorded holds the names of the images recorded by the interrupted scan and by the collector
+	recorded map[string]bool
+}
+
+// openCheckpoint creates the checkpoint file, or reads the images it records when resuming. It is nil when no
+// checkpoint file is configured
+func openCheckpoint(filename string, resume bool) (*checkpoint, error) {
+	if filename == "" {
+		if resume {
+			return nil, fmt.Errorf("the scan cannot be resumed without checkpoint file")
+		}
+		return nil, nil
+	}
+	c := &checkpoint{filename: filename, scannedImages: make(map[string]ScannedImage), recorded: make(map[string]bool)}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := c.read(); err != nil {
+			return nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, fmt.Errorf("could not create the directory of checkpoint file %s: %v", filename, err)
+	}
+	file, err := os.OpenFile(filename, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open checkpoint file %s: %v", filename, err)
+	}
+	c.file = file
+	return c, nil
+}
+
+// read loads the images recorded by the interrupted scan. The last line is ignored when truncated by the interruption
+func (c *checkpoint) read() error {
+	content, err := os.ReadFile(c.filename)
+	if os.IsNotExist(err) {
+		logr.Warnf("No checkpoint file %s to resume from, scanning all the images", c.filename)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read checkpoint file %s: %v", c.filename, err)
+	}
+	lines := bufio.NewScanner(bytes.NewReader(content))
+	lines.Buffer(nil, len(content)+1)
+	for lines.Scan() {
+		var scannedImage ScannedImage
+		if err := json.Unmarshal(lines.Bytes(), &scannedImage); err != nil {
+			logr.Warnf("Ignoring an invalid line of checkpoint file %s: %v", c.filename, err)
+			continue
+		}
+		c.scannedImages[scannedImage.ImageName] = scannedImage
+		c.recorded[scannedImage.ImageName] = true
+	}
+	logr.Infof("Resuming the scan from checkpoint file %s, %d images already scanned", c.filename, len(c.scannedImages))
+	return lines.Err()
+}
+
+// scanned returns the recorded scan of each image, with their current containers. It is false when one of the
+// images was not scanned yet. A nil checkpoint records no image
+func (c *checkpoint) scanned(imageNames []string, imageList map[string][]k8s.ContainerSummary, resolveImageName func(string) string) ([]ScannedImage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	var scannedImages []ScannedImage
+	for _, imageName := range imageNames {
+		recorded, ok := c.scannedImages[resolveImageName(imageName)]
+		if !ok {
+			return nil, false
+		}
+		recorded.Containers = imageList[imageName]
+		recorded.VulnerabilitySummary = recorded.buildVulnerabilitySummary()
+		scannedImages = append(scannedImages, recorded)
+	}
+	return scannedImages, true
+}
+
+// record appends the scanned image to the checkpoint file unless already recorded. Checkpointing is best effort so
+// that errors are logged rather than failing the scan
+func (c *checkpoint) record(scannedImage ScannedImage) {
+	if c == nil {
+		return
+	}
+	if c.recorded[scannedImage.ImageName] {
+		return
+	}
+	c.recorded[scannedImage.ImageName] = true
+	if err := json.NewEncoder(c.file).Encode(scannedImage); err != nil {
+		logr.Errorf("Error recording scan result of image %s to checkpoint file %s: %v", scannedImage.ImageName, c.filename, err)
+	}
+}
+
+// close closes the checkpoint file, which is removed once the scan completes as there is nothing left to resume
+func (c *checkpoint) close(complete bool) {
+	if c == nil {
+		return
+	}
+	if err := c.file.Close(); err != nil {
+		logr.Errorf("Error closing checkpoint file %s: %v", c.filename, err)
+	}
+	if !complete {
+		logr.Infof("The scan can be resumed from checkpoint file %s to scan the remaining images only", c.filename)
+		return
+	}
+	if err := os.Remove(c.filename); err != nil {
+		logr.Errorf("Error removing checkpoint file %s: %v", c.filename, err)
+	}
+}
@@ -0,0 +1,229 @@
+package scanner
+
+import (
+	"sort"
+)
+
+// Compliance control statuses
+const (
+	CompliancePass   = "PASS"
+	ComplianceFail   = "FAIL"
+	ComplianceManual = "MANUAL"
+)
+
+// ComplianceReport is the result of a security compliance benchmark
+type ComplianceReport struct {
+	ID                string
+	Title             string
+	Description       string
+	Version           string
+	RelatedResources  []string
+	Controls          []ComplianceControl
+	Summary           ComplianceSummary
+	SummaryBySeverity map[string]*ComplianceSummary
+}
+
+// CombinedComplianceReport groups the results of several compliance benchmarks
+type CombinedComplianceReport struct {
+	Benchmarks []*ComplianceReport
+	// Summary holds the number of controls per status across all the benchmarks
+	Summary ComplianceSummary
+}
+
+// ComplianceSummary holds the number of controls per status
+type ComplianceSummary struct {
+	ControlCount int
+	PassCount    int
+	FailCount    int
+	ManualCount  int
+}
+
+// ComplianceControl holds the result of a single benchmark control
+type ComplianceControl struct {
+	ID          string
+	Name        string
+	Description string
+	Severity    string
+	Status      string
+	// PassCount is the number of successful checks across the cluster resources
+	PassCount int
+	// FailCount is the number of failed checks across the cluster resources
+	FailCount       int
+	FailedResources []ComplianceResource
+}
+
+// ComplianceResource is a cluster resource failing a control
+type ComplianceResource struct {
+	Target            string
+	Class             string
+	Misconfigurations []ComplianceMisconfiguration
+}
+
+// ComplianceMisconfiguration describes why a resource fails a control
+type ComplianceMisconfiguration struct {
+	ID          string
+	Title       string
+	Description string
+	Message     string
+	Namespace   string
+	Resolution  string
+	Severity    string
+	PrimaryURL  string
+	References  []string
+	Status      string
+}
+
+// NewComplianceReport converts the trivy compliance output into a ComplianceReport
+func NewComplianceReport(output *CisOutput) *ComplianceReport {
+	report := &ComplianceReport{
+		SummaryBySeverity: make(map[string]*ComplianceSummary),
+	}
+	if output == nil {
+		return report
+	}
+	report.ID = output.ID
+	report.Title = output.Title
+	report.Description = output.Description
+	report.Version = output.Version
+	report.RelatedResources = output.RelatedResources
+
+	var controls []ComplianceControl
+	for _, result := range output.Results {
+		control := ComplianceControl{
+			ID:          result.ID,
+			Name:        result.Name,
+			Description: result.Description,
+			Severity:    result.Severity,
+		}
+		for _, resource := range result.Results {
+			control.PassCount += resource.MisconfSummary.Successes
+			control.FailCount += resource.MisconfSummary.Failures
+
+			var misconfigurations []ComplianceMisconfiguration
+			for _, m := range resource.Misconfigurations {
+				if m.Status == CompliancePass {
+					continue
+				}
+				misconfigurations = append(misconfigurations, ComplianceMisconfiguration{
+					ID:          m.ID,
+					Title:       m.Title,
+					Description: m.Description,
+					Message:     m.Message,
+					Namespace:   m.Namespace,
+					Resolution:  m.Resolution,
+					Severity:    m.Severity,
+					PrimaryURL:  m.PrimaryURL,
+					References:  m.References,
+					Status:      m.Status,
+				})
+			}
+			if len(misconfigurations) > 0 {
+				control.FailedResources = append(control.FailedResources, ComplianceResource{
+					Target:            resource.Target,
+					Class:             resource.Class,
+					Misconfigurations: misconfigurations,
+				})
+			}
+		}
+		if control.FailCount < len(control.FailedResources) {
+			control.FailCount = len(control.FailedResources)
+		}
+		control.Status = controlStatus(control, len(result.Results), result.DefaultStatus)
+		controls = append(controls, control)
+	}
+	report.AddControls(controls...)
+	return report
+}
+
+// AddControls adds the controls to the report summaries and keeps the controls sorted by severity
+func (r *ComplianceReport) AddControls(controls ...ComplianceControl) {
+	for _, control := range controls {
+		r.Controls = append(r.Controls, control)
+		r.aggregate(control)
+	}
+	sort.SliceStable(r.Controls, func(i, j int) bool {
+		return severityScores[r.Controls[i].Severity] > severityScores[r.Controls[j].Severity]
+	})
+}
+
+// NewCombinedComplianceReport creates a report combining the results of the benchmarks
+func NewCombinedComplianceReport(reports []*ComplianceReport) *CombinedComplianceReport {
+	combined := &CombinedComplianceReport{}
+	for _, report := range reports {
+		combined.Add(report)
+	}
+	return combined
+}
+
+// Add adds the results of a benchmark to the combined report
+func (c *CombinedComplianceReport) Add(report *ComplianceReport) {
+	c.Benchmarks = append(c.Benchmarks, report)
+	c.Summary.merge(report.Summary)
+}
+
+func controlStatus(control ComplianceControl, resultCount int, defaultStatus string) string {
+	switch {
+	case control.FailCount > 0:
+		return ComplianceFail
+	case resultCount == 0 && defaultStatus == ComplianceFail:
+		// controls without automated checks have to be verified manually
+		return ComplianceManual
+	default:
+		return CompliancePass
+	}
+}
+
+func (r *ComplianceReport) aggregate(control ComplianceControl) {
+	if r.SummaryBySeverity == nil {
+		r.SummaryBySeverity = make(map[string]*ComplianceSummary)
+	}
+	if _, ok := r.SummaryBySeverity[control.Severity]; !ok {
+		r.SummaryBySeverity[control.Severity] = &ComplianceSummary{}
+	}
+	r.Summary.add(control.Status)
+	r.SummaryBySeverity[control.Severity].add(control.Status)
+}
+
+func (s *ComplianceSummary) add(status string) {
+	s.ControlCount++
+	switch status {
+	case CompliancePass:
+		s.PassCount++
+	case ComplianceFail:
+		s.FailCount++
+	case ComplianceManual:
+		s.ManualCount++
+	}
+}
+
+func (s *ComplianceSummary) merge(other ComplianceSummary) {
+	s.ControlCount += other.ControlCount
+	s.PassCount += other.PassCount
+	s.FailCount += other.FailCount
+	s.ManualCount += other.ManualCount
+}
+
+// FailedControls returns the controls failing the benchmark
+func (r *ComplianceReport) FailedControls() []ComplianceControl {
+	var failed []ComplianceControl
+	for _, control := range r.Controls {
+		if control.Status == ComplianceFail {
+			failed = append(failed, control)
+		}
+	}
+	return failed
+}
+
+// FailedControlsWithMinSeverity returns the controls of the benchmarks failing with at least the minimum severity, for
+// instance HIGH for the CRITICAL and HIGH controls
+func (c *CombinedComplianceReport) FailedControlsWithMinSeverity(minSeverity string) []ComplianceControl {
+	var failed []ComplianceControl
+	for _, benchmark := range c.Benchmarks {
+		for _, control := range benchmark.FailedControls() {
+			if severityScores[control.Severity] >= severityScores[minSeverity] {
+				failed = append(failed, control)
+			}
+		}
+	}
+	return failed
+}
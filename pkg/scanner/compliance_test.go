@@ -0,0 +1,109 @@
+package scanner
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const complianceOutput = `{
+  "ID": "k8s-cis",
+  "Title": "CIS Kubernetes Benchmarks v1.23",
+  "Version": "1.23",
+  "RelatedResources": ["https://www.cisecurity.org/benchmark/kubernetes"],
+  "Results": [
+    {"ID": "1.1.1", "Name": "Ensure that the API server pod specification file permissions are set", "Severity": "HIGH"},
+    {"ID": "1.2.1", "Name": "Ensure that the --anonymous-auth argument is set to false", "Severity": "MEDIUM",
+     "Results": [
+       {"Target": "kube-system/kube-apiserver", "Class": "config",
+        "MisconfSummary": {"Successes": 0, "Failures": 1},
+        "Misconfigurations": [{"ID": "KCV0001", "Title": "Anonymous auth", "Message": "anonymous-auth is enabled", "Namespace": "kube-system", "Severity": "MEDIUM", "Status": "FAIL"}]},
+       {"Target": "kube-system/kube-apiserver-2", "Class": "config",
+        "MisconfSummary": {"Successes": 1, "Failures": 0},
+        "Misconfigurations": [{"ID": "KCV0001", "Status": "PASS"}]}
+     ]},
+    {"ID": "5.1.1", "Name": "Ensure that the cluster-admin role is only used where required", "Severity": "CRITICAL",
+     "Results": [
+       {"Target": "ClusterRoleBinding/admin", "MisconfSummary": {"Successes": 3, "Failures": 0}}
+     ]},
+    {"ID": "5.2.1", "Name": "Minimize the admission of privileged containers", "Severity": "HIGH", "DefaultStatus": "FAIL"}
+  ]
+}`
+
+var _ = Describe("Compliance report", func() {
+
+	var (
+		report *ComplianceReport
+	)
+
+	BeforeEach(func() {
+		var output *CisOutput
+		Expect(json.Unmarshal([]byte(complianceOutput), &output)).To(Succeed())
+		report = NewComplianceReport(output)
+	})
+
+	It("copies the benchmark details", func() {
+		Expect(report.ID).To(Equal("k8s-cis"))
+		Expect(report.Title).To(Equal("CIS Kubernetes Benchmarks v1.23"))
+		Expect(report.RelatedResources).To(Equal([]string{"https://www.cisecurity.org/benchmark/kubernetes"}))
+	})
+
+	It("sorts the controls by severity", func() {
+		Expect(report.Controls).To(HaveLen(4))
+		Expect(report.Controls[0].ID).To(Equal("5.1.1"))
+		Expect(report.Controls[1].ID).To(Equal("1.1.1"))
+		Expect(report.Controls[2].ID).To(Equal("5.2.1"))
+		Expect(report.Controls[3].ID).To(Equal("1.2.1"))
+	})
+
+	It("computes the status and check counts of each control", func() {
+		Expect(report.Controls[0].Status).To(Equal(CompliancePass))
+		Expect(report.Controls[0].PassCount).To(Equal(3))
+		Expect(report.Controls[1].Status).To(Equal(CompliancePass))
+		Expect(report.Controls[2].Status).To(Equal(ComplianceManual))
+
+		failed := report.Controls[3]
+		Expect(failed.Status).To(Equal(ComplianceFail))
+		Expect(failed.PassCount).To(Equal(1))
+		Expect(failed.FailCount).To(Equal(1))
+		Expect(failed.FailedResources).To(HaveLen(1))
+		Expect(failed.FailedResources[0].Target).To(Equal("kube-system/kube-apiserver"))
+		Expect(failed.FailedResources[0].Misconfigurations[0].Message).To(Equal("anonymous-auth is enabled"))
+	})
+
+	It("summarises the controls per severity", func() {
+		Expect(report.Summary).To(Equal(ComplianceSummary{ControlCount: 4, PassCount: 2, FailCount: 1, ManualCount: 1}))
+		Expect(report.SummaryBySeverity).To(HaveLen(3))
+		Expect(*report.SummaryBySeverity["HIGH"]).To(Equal(ComplianceSummary{ControlCount: 2, PassCount: 1, ManualCount: 1}))
+		Expect(*report.SummaryBySeverity["MEDIUM"]).To(Equal(ComplianceSummary{ControlCount: 1, FailCount: 1}))
+		Expect(report.FailedControls()).To(HaveLen(1))
+	})
+
+	It("returns an empty report when there is no output", func() {
+		report := NewComplianceReport(nil)
+		Expect(report.Controls).To(BeEmpty())
+		Expect(report.Summary.ControlCount).To(Equal(0))
+	})
+
+	It("combines the results of several benchmarks", func() {
+		other := &ComplianceReport{ID: "k8s-nsa", Summary: ComplianceSummary{ControlCount: 2, PassCount: 1, FailCount: 1}}
+
+		combined := NewCombinedComplianceReport([]*ComplianceReport{report, other})
+
+		Expect(combined.Benchmarks).To(Equal([]*ComplianceReport{report, other}))
+		Expect(combined.Summary).To(Equal(ComplianceSummary{ControlCount: 6, PassCount: 3, FailCount: 2, ManualCount: 1}))
+	})
+
+	It("returns the failed controls of at least the minimum severity of all the benchmarks", func() {
+		other := &ComplianceReport{ID: "k8s-nsa", Controls: []ComplianceControl{
+			{ID: "1.0", Severity: "HIGH", Status: ComplianceFail},
+			{ID: "2.0", Severity: "CRITICAL", Status: CompliancePass},
+		}}
+
+		combined := NewCombinedComplianceReport([]*ComplianceReport{report, other})
+
+		Expect(combined.FailedControlsWithMinSeverity("HIGH")).To(Equal([]ComplianceControl{other.Controls[0]}))
+		Expect(combined.FailedControlsWithMinSeverity("MEDIUM")).To(HaveLen(2))
+	})
+})
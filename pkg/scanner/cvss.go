@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"sort"
+)
+
+// CVSS is the object representation of the CVSS scores and vectors a source, for instance nvd or redhat, assigned to a
+// vulnerability. The scores are 0 when the source did not rate the vulnerability with this CVSS version
+type CVSS struct {
+	V2Vector string
+	V3Vector string
+	V2Score  float64
+	V3Score  float64
+}
+
+// nvdSource is the trivy name of the National Vulnerability Database
+const nvdSource = "nvd"
+
+// CVSSScore returns the CVSS score of the vulnerability, 0 when it has no CVSS score. See CVSSVector for the source used
+func (v Vulnerabilities) CVSSScore() float64 {
+	score, _ := v.cvss()
+	return score
+}
+
+// CVSSVector returns the CVSS vector of the vulnerability, empty when it has no CVSS score.
+// The CVSS v3 score is preferred to the v2 score, and the score of the severity source to the nvd score and then to the
+// scores of the other sources
+func (v Vulnerabilities) CVSSVector() string {
+	_, vector := v.cvss()
+	return vector
+}
+
+func (v Vulnerabilities) cvss() (float64, string) {
+	sources := []string{v.SeveritySource, nvdSource}
+	var otherSources []string
+	for source := range v.CVSS {
+		if source != v.SeveritySource && source != nvdSource {
+			otherSources = append(otherSources, source)
+		}
+	}
+	sort.Strings(otherSources)
+	sources = append(sources, otherSources...)
+
+	for _, source := range sources {
+		if cvss, ok := v.CVSS[source]; ok && cvss.V3Score > 0 {
+			return cvss.V3Score, cvss.V3Vector
+		}
+	}
+	for _, source := range sources {
+		if cvss, ok := v.CVSS[source]; ok && cvss.V2Score > 0 {
+			return cvss.V2Score, cvss.V2Vector
+		}
+	}
+	return 0, ""
+}
+
+// filterByCVSSScore removes the vulnerabilities with a CVSS score below the minimum score.
+// The vulnerabilities without CVSS score are kept as their risk cannot be assessed
+func filterByCVSSScore(trivyOutput []TrivyOutputResults, minScore float64) {
+	for i := range trivyOutput {
+		var vulnerabilities []Vulnerabilities
+		for _, vulnerability := range trivyOutput[i].Vulnerabilities {
+			if score := vulnerability.CVSSScore(); score == 0 || score >= minScore {
+				vulnerabilities = append(vulnerabilities, vulnerability)
+			}
+		}
+		trivyOutput[i].Vulnerabilities = vulnerabilities
+	}
+}
+
+// sortByCVSSScore sorts the vulnerabilities by decreasing CVSS score, the vulnerabilities without CVSS score last
+func sortByCVSSScore(trivyOutput []TrivyOutputResults) {
+	for i := range trivyOutput {
+		vulnerabilities := trivyOutput[i].Vulnerabilities
+		sort.SliceStable(vulnerabilities, func(a, b int) bool {
+			return vulnerabilities[a].CVSSScore() > vulnerabilities[b].CVSSScore()
+		})
+	}
+}
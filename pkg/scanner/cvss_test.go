@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CVSS", func() {
+
+	nvd := CVSS{V2Vector: "AV:N/AC:L/Au:N/C:P/I:P/A:P", V2Score: 7.5, V3Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", V3Score: 9.8}
+	redhat := CVSS{V3Vector: "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:H/A:H", V3Score: 8.1}
+	nvdV2 := CVSS{V2Vector: "AV:N/AC:L/Au:N/C:P/I:N/A:N", V2Score: 5.0}
+
+	DescribeTable("selects the CVSS score and vector of the vulnerability",
+		func(vulnerability Vulnerabilities, score float64, vector string) {
+			Expect(vulnerability.CVSSScore()).To(Equal(score))
+			Expect(vulnerability.CVSSVector()).To(Equal(vector))
+		},
+		Entry("nvd v3 score", Vulnerabilities{CVSS: map[string]CVSS{"nvd": nvd}}, 9.8, nvd.V3Vector),
+		Entry("severity source score", Vulnerabilities{SeveritySource: "redhat", CVSS: map[string]CVSS{"nvd": nvd, "redhat": redhat}}, 8.1, redhat.V3Vector),
+		Entry("nvd score over other sources", Vulnerabilities{SeveritySource: "debian", CVSS: map[string]CVSS{"nvd": nvd, "redhat": redhat}}, 9.8, nvd.V3Vector),
+		Entry("v3 score over v2 score", Vulnerabilities{CVSS: map[string]CVSS{"nvd": nvdV2, "redhat": redhat}}, 8.1, redhat.V3Vector),
+		Entry("v2 score only", Vulnerabilities{CVSS: map[string]CVSS{"nvd": nvdV2}}, 5.0, nvdV2.V2Vector),
+		Entry("no score", Vulnerabilities{}, 0.0, ""),
+	)
+
+	It("filters out the vulnerabilities below the minimum score", func() {
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-1", CVSS: map[string]CVSS{"nvd": nvd}},
+			{VulnerabilityID: "CVE-2", CVSS: map[string]CVSS{"nvd": nvdV2}},
+			{VulnerabilityID: "CVE-3"},
+		}}}
+
+		filterByCVSSScore(results, 7.0)
+
+		Expect(results[0].Vulnerabilities).To(HaveLen(2))
+		Expect(results[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-1"))
+		Expect(results[0].Vulnerabilities[1].VulnerabilityID).To(Equal("CVE-3"))
+	})
+
+	It("sorts the vulnerabilities by decreasing score", func() {
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-1"},
+			{VulnerabilityID: "CVE-2", CVSS: map[string]CVSS{"nvd": nvdV2}},
+			{VulnerabilityID: "CVE-3", CVSS: map[string]CVSS{"redhat": redhat}},
+			{VulnerabilityID: "CVE-4", CVSS: map[string]CVSS{"nvd": nvd}},
+		}}}
+
+		sortByCVSSScore(results)
+
+		var ids []string
+		for _, vulnerability := range results[0].Vulnerabilities {
+			ids = append(ids, vulnerability.VulnerabilityID)
+		}
+		Expect(ids).To(Equal([]string{"CVE-4", "CVE-3", "CVE-2", "CVE-1"}))
+	})
+})
@@ -0,0 +1,198 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ReportDiff is the delta between two image scan reports
+type ReportDiff struct {
+	NewImages     []string `json:"newImages"`
+	RemovedImages []string `json:"removedImages"`
+	// NewVulnerabilities are the vulnerabilities of the new report missing from the old report, by image and package.
+	// The images whose scan failed or timed out in the old report are left out, as their vulnerabilities were unknown
+	// rather than absent
+	NewVulnerabilities []DiffFinding `json:"newVulnerabilities"`
+	// FixedVulnerabilities are the vulnerabilities of the images of both reports missing from the new report. The images
+	// whose scan failed or timed out in the new report are left out, as their vulnerabilities are unknown rather than fixed
+	FixedVulnerabilities []DiffFinding `json:"fixedVulnerabilities"`
+	// WorkloadChanges are the workloads whose images changed between the inventories of the reports, nil unless the
+	// diff is explained, see Explain
+	WorkloadChanges []WorkloadChange `json:"workloadChanges,omitempty"`
+}
+
+// DiffFinding is a vulnerability of an image added or removed between two reports
+type DiffFinding struct {
+	ImageName        string `json:"imageName"`
+	VulnerabilityID  string `json:"vulnerabilityID"`
+	Severity         string `json:"severity"`
+	PkgName          string `json:"pkgName"`
+	InstalledVersion string `json:"installedVersion"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	// Cause is DeploymentCause or VulnerabilityDataCause, empty when unknown or when the diff is not explained
+	Cause string `json:"cause,omitempty"`
+}
+
+// DiffReports returns the images and vulnerabilities added and removed from the old report to the new report. The
+// images missing from a partial report, see ReportMetadata.Partial, are left out as they are not known to be added or
+// removed
+func DiffReports(oldReport, newReport *VulnerabilityReport) *ReportDiff {
+	oldImages := imagesByName(oldReport)
+	newImages := imagesByName(newReport)
+	diff := &ReportDiff{
+		NewImages:     []string{},
+		RemovedImages: []string{},
+	}
+	for name := range newImages {
+		if _, ok := oldImages[name]; !ok {
+			if oldReport.Metadata.Partial() {
+				delete(newImages, name)
+				continue
+			}
+			diff.NewImages = append(diff.NewImages, name)
+		}
+	}
+	for name, image := range oldImages {
+		newImage, ok := newImages[name]
+		if !ok {
+			if !newReport.Metadata.Partial() {
+				diff.RemovedImages = append(diff.RemovedImages, name)
+			}
+			continue
+		}
+		if !completeScan(newImage) {
+			continue
+		}
+		diff.FixedVulnerabilities = append(diff.FixedVulnerabilities, missingFindings(image, newImage)...)
+	}
+	for name, image := range newImages {
+		if oldImage, ok := oldImages[name]; ok && !completeScan(oldImage) {
+			continue
+		}
+		diff.NewVulnerabilities = append(diff.NewVulnerabilities, missingFindings(image, oldImages[name])...)
+	}
+	sort.Strings(diff.NewImages)
+	sort.Strings(diff.RemovedImages)
+	sortDiffFindings(diff.NewVulnerabilities)
+	sortDiffFindings(diff.FixedVulnerabilities)
+	if diff.NewVulnerabilities == nil {
+		diff.NewVulnerabilities = []DiffFinding{}
+	}
+	if diff.FixedVulnerabilities == nil {
+		diff.FixedVulnerabilities = []DiffFinding{}
+	}
+	return diff
+}
+
+// completeScan returns true when the scan of the image neither failed nor timed out, so that its vulnerabilities are
+// all known
+func completeScan(image ScannedImage) bool {
+	return image.ScanError == nil && !image.TimedOut
+}
+
+func imagesByName(report *VulnerabilityReport) map[string]ScannedImage {
+	images := make(map[string]ScannedImage)
+	for _, image := range report.ScannedImages {
+		images[image.ImageName] = image
+	}
+	return images
+}
+
+// missingFindings returns the vulnerabilities of the image missing from the other image, all of them when the other
+// image is not in the other report
+func missingFindings(image, other ScannedImage) []DiffFinding {
+	known := make(map[findingKey]bool)
+	for _, target := range other.Results() {
+		for _, vulnerability := range target.Vulnerabilities {
+			known[findingKey{image.ImageName, vulnerability.VulnerabilityID, vulnerability.PkgName}] = true
+		}
+	}
+	var findings []DiffFinding
+	for _, target := range image.Results() {
+		for _, vulnerability := range target.Vulnerabilities {
+			key := findingKey{image.ImageName, vulnerability.VulnerabilityID, vulnerability.PkgName}
+			if known[key] {
+				continue
+			}
+			known[key] = true
+			findings = append(findings, DiffFinding{
+				ImageName:        image.ImageName,
+				VulnerabilityID:  vulnerability.VulnerabilityID,
+				Severity:         vulnerability.Severity,
+				PkgName:          vulnerability.PkgName,
+				InstalledVersion: vulnerability.InstalledVersion,
+				FixedVersion:     vulnerability.FixedVersion,
+			})
+		}
+	}
+	return findings
+}
+
+// sortDiffFindings sorts the findings by decreasing severity, then by image and vulnerability
+func sortDiffFindings(findings []DiffFinding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return severityScores[findings[i].Severity] > severityScores[findings[j].Severity]
+		}
+		if findings[i].ImageName != findings[j].ImageName {
+			return findings[i].ImageName < findings[j].ImageName
+		}
+		if findings[i].VulnerabilityID != findings[j].VulnerabilityID {
+			return findings[i].VulnerabilityID < findings[j].VulnerabilityID
+		}
+		return findings[i].PkgName < findings[j].PkgName
+	})
+}
+
+// WriteText writes the human-readable delta, the added entries prefixed by + and the removed ones by -
+func (d *ReportDiff) WriteText(w io.Writer) error {
+	var out strings.Builder
+	fmt.Fprintf(&out, "New images (%d)\n", len(d.NewImages))
+	for _, image := range d.NewImages {
+		fmt.Fprintf(&out, "  + %s\n", image)
+	}
+	fmt.Fprintf(&out, "\nRemoved images (%d)\n", len(d.RemovedImages))
+	for _, image := range d.RemovedImages {
+		fmt.Fprintf(&out, "  - %s\n", image)
+	}
+	fmt.Fprintf(&out, "\nNew vulnerabilities (%d)\n", len(d.NewVulnerabilities))
+	for _, finding := range d.NewVulnerabilities {
+		fmt.Fprintf(&out, "  + %s\n", finding)
+	}
+	fmt.Fprintf(&out, "\nFixed vulnerabilities (%d)\n", len(d.FixedVulnerabilities))
+	for _, finding := range d.FixedVulnerabilities {
+		fmt.Fprintf(&out, "  - %s\n", finding)
+	}
+	if d.WorkloadChanges != nil {
+		fmt.Fprintf(&out, "\nWorkload changes (%d)\n", len(d.WorkloadChanges))
+		for _, change := range d.WorkloadChanges {
+			fmt.Fprintf(&out, "  %s %s/%s", change.Change, change.Namespace, change.Workload)
+			if len(change.OldImages) > 0 {
+				fmt.Fprintf(&out, " from %s", strings.Join(change.OldImages, ", "))
+			}
+			if len(change.NewImages) > 0 {
+				fmt.Fprintf(&out, " to %s", strings.Join(change.NewImages, ", "))
+			}
+			out.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func (f DiffFinding) String() string {
+	description := fmt.Sprintf("%s %s %s %s", f.Severity, f.VulnerabilityID, f.PkgName, f.InstalledVersion)
+	if f.FixedVersion != "" {
+		description += fmt.Sprintf(" (fixed in %s)", f.FixedVersion)
+	}
+	description += " in " + f.ImageName
+	switch f.Cause {
+	case DeploymentCause:
+		description += " after a deployment"
+	case VulnerabilityDataCause:
+		description += " after a vulnerability data update"
+	}
+	return description
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Report diff", func() {
+
+	var oldReport, newReport *VulnerabilityReport
+
+	BeforeEach(func() {
+		oldReport = &VulnerabilityReport{ScannedImages: []ScannedImage{
+			NewScannedImage("api:1.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.10", Severity: "CRITICAL"},
+			}}}, nil),
+			NewScannedImage("web:2.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-2", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.1.0", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-3", PkgName: "zlib", InstalledVersion: "1.2.13", Severity: "LOW"},
+			}}}, nil),
+			NewScannedImage("worker:1.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-4", PkgName: "glibc", InstalledVersion: "2.36", Severity: "MEDIUM"},
+			}}}, nil),
+		}}
+		newReport = &VulnerabilityReport{ScannedImages: []ScannedImage{
+			NewScannedImage("api:1.1", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-5", PkgName: "libxml2", InstalledVersion: "2.10.3", Severity: "MEDIUM"},
+			}}}, nil),
+			NewScannedImage("web:2.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-3", PkgName: "zlib", InstalledVersion: "1.2.13", Severity: "LOW"},
+				{VulnerabilityID: "CVE-6", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.2.0", Severity: "CRITICAL"},
+			}}}, nil),
+			NewScannedImage("worker:1.0", nil, nil, errors.New("unable to pull image")),
+		}}
+	})
+
+	It("lists the new and removed images and the new and fixed vulnerabilities", func() {
+		diff := DiffReports(oldReport, newReport)
+
+		Expect(diff).To(Equal(&ReportDiff{
+			NewImages:     []string{"api:1.1"},
+			RemovedImages: []string{"api:1.0"},
+			NewVulnerabilities: []DiffFinding{
+				{ImageName: "web:2.0", VulnerabilityID: "CVE-6", Severity: "CRITICAL", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.2.0"},
+				{ImageName: "api:1.1", VulnerabilityID: "CVE-5", Severity: "MEDIUM", PkgName: "libxml2", InstalledVersion: "2.10.3"},
+			},
+			FixedVulnerabilities: []DiffFinding{
+				{ImageName: "web:2.0", VulnerabilityID: "CVE-2", Severity: "HIGH", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.1.0"},
+			},
+		}))
+	})
+
+	It("does not list as removed the images missing from a partial report", func() {
+		since := time.Now()
+		newReport.Metadata.ChangedSince = &since
+
+		diff := DiffReports(oldReport, newReport)
+
+		Expect(diff.NewImages).To(Equal([]string{"api:1.1"}))
+		Expect(diff.RemovedImages).To(BeEmpty())
+	})
+
+	It("does not list as new the images missing from a partial old report", func() {
+		oldReport.Metadata.Incomplete = true
+
+		diff := DiffReports(oldReport, newReport)
+
+		Expect(diff.NewImages).To(BeEmpty())
+		Expect(diff.RemovedImages).To(Equal([]string{"api:1.0"}))
+		Expect(diff.NewVulnerabilities).To(Equal([]DiffFinding{
+			{ImageName: "web:2.0", VulnerabilityID: "CVE-6", Severity: "CRITICAL", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.2.0"},
+		}))
+	})
+
+	It("leaves out the vulnerabilities of the images whose scan failed or timed out on either side", func() {
+		oldReport, newReport = newReport, oldReport
+		newReport.ScannedImages[1].TimedOut = true
+		newReport.ScannedImages[1].ScanError = errors.New("scan timed out")
+
+		diff := DiffReports(oldReport, newReport)
+
+		Expect(diff.NewVulnerabilities).To(Equal([]DiffFinding{
+			{ImageName: "api:1.0", VulnerabilityID: "CVE-1", Severity: "CRITICAL", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.10"},
+			{ImageName: "web:2.0", VulnerabilityID: "CVE-2", Severity: "HIGH", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.1.0"},
+		}))
+		Expect(diff.FixedVulnerabilities).To(BeEmpty())
+	})
+
+	It("writes the human-readable delta", func() {
+		var out strings.Builder
+
+		Expect(DiffReports(oldReport, newReport).WriteText(&out)).To(Succeed())
+
+		Expect(out.String()).To(Equal(`New images (1)
+  + api:1.1
+
+Removed images (1)
+  - api:1.0
+
+New vulnerabilities (2)
+  + CRITICAL CVE-6 curl 8.0.1 (fixed in 8.2.0) in web:2.0
+  + MEDIUM CVE-5 libxml2 2.10.3 in api:1.1
+
+Fixed vulnerabilities (1)
+  - HIGH CVE-2 curl 8.0.1 (fixed in 8.1.0) in web:2.0
+`))
+	})
+
+	It("reports no change between identical reports", func() {
+		diff := DiffReports(oldReport, oldReport)
+
+		Expect(diff.NewImages).To(BeEmpty())
+		Expect(diff.RemovedImages).To(BeEmpty())
+		Expect(diff.NewVulnerabilities).To(BeEmpty())
+		Expect(diff.FixedVulnerabilities).To(BeEmpty())
+	})
+})
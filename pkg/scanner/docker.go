@@ -1,17 +1,40 @@
 package scanner
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 )
 
-// DockerClient is a thin client for docker
+// DockerClient is a thin client for docker, or for podman with the podman client, see Config.ContainerRuntime
 type DockerClient interface {
-	PullImage(image string) error
+	// PullImage pulls the image, the pull is stopped when the context is done
+	PullImage(ctx context.Context, image string) error
 	RmiImage(image string) error
+	// ImageExists returns whether the image is present on the host, so that the images present before the scan are kept
+	ImageExists(ctx context.Context, image string) (bool, error)
+	// ImageSize returns the compressed size of the image layers from the registry, without pulling the image
+	ImageSize(ctx context.Context, image string) (int64, error)
+	// ImageCreated returns the creation time recorded in the image config blob of the registry, without pulling the image
+	ImageCreated(ctx context.Context, image string) (time.Time, error)
+	// ImagePlatforms returns the platforms of the manifest list of the image from the registry with the digest of their
+	// manifest, a single platform or none for the images built for one platform
+	ImagePlatforms(ctx context.Context, image string) ([]ImagePlatform, error)
 }
 
 type dockerClient struct {
+	// insecureRegistries are the registries the manifests are inspected from without TLS verification. The pulls are
+	// run by the docker daemon, whose insecure-registries setting applies
+	insecureRegistries []string
+	// credentials provides the credentials of the registries written to a temporary docker config for each command
+	// reaching the registry, the docker config of the user being used for the registries without credentials
+	credentials RegistryCredentialSource
 }
 
 // NewDockerClient creates a new DockerClient
@@ -19,13 +42,16 @@ func NewDockerClient() DockerClient {
 	return &dockerClient{}
 }
 
-func (d *dockerClient) PullImage(image string) error {
-	command := exec.Command("docker", "pull", image)
-	output, err := command.CombinedOutput()
-	if err != nil {
-		return dockerError(fmt.Sprintf("error while pulling for image %s", image), output, err)
-	}
-	return nil
+func (d *dockerClient) PullImage(ctx context.Context, image string) error {
+	return withRegistryAuth(ctx, d.credentials, image, func(authFile string) error {
+		command := exec.CommandContext(ctx, "docker", "pull", image)
+		command.Env = dockerConfigEnv(authFile)
+		output, err := command.CombinedOutput()
+		if err != nil {
+			return dockerError(fmt.Sprintf("error while pulling for image %s", image), output, err)
+		}
+		return nil
+	})
 }
 
 func (d *dockerClient) RmiImage(image string) error {
@@ -37,6 +63,219 @@ func (d *dockerClient) RmiImage(image string) error {
 	return nil
 }
 
+func (d *dockerClient) ImageExists(ctx context.Context, image string) (bool, error) {
+	command := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Id}}", image)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		if strings.Contains(strings.ToLower(string(output)), "no such image") {
+			return false, nil
+		}
+		return false, dockerError(fmt.Sprintf("error while inspecting image %s", image), output, err)
+	}
+	return true, nil
+}
+
+func (d *dockerClient) ImageSize(ctx context.Context, image string) (int64, error) {
+	output, err := d.inspectManifest(ctx, image)
+	if err != nil {
+		return 0, err
+	}
+	size, err := manifestSize(output, runtime.GOARCH)
+	if err != nil {
+		return 0, fmt.Errorf("error while decoding the manifest of image %s: %v", image, err)
+	}
+	return size, nil
+}
+
+// inspectManifest returns the docker manifest inspect --verbose output of the image
+func (d *dockerClient) inspectManifest(ctx context.Context, image string) ([]byte, error) {
+	var output []byte
+	err := withRegistryAuth(ctx, d.credentials, image, func(authFile string) error {
+		command := exec.CommandContext(ctx, "docker", d.manifestInspectArgs(image)...)
+		command.Env = dockerConfigEnv(authFile)
+		var err error
+		if output, err = command.Output(); err != nil {
+			return dockerError(fmt.Sprintf("error while inspecting the manifest of image %s", image), output, err)
+		}
+		return nil
+	})
+	return output, err
+}
+
+// manifestInspectArgs returns the arguments of the verbose manifest inspection of the image, which is insecure for
+// the images of the insecure registries
+func (d *dockerClient) manifestInspectArgs(image string) []string {
+	args := []string{"manifest", "inspect", "--verbose"}
+	if insecureRegistry(d.insecureRegistries, image) {
+		args = append(args, "--insecure")
+	}
+	return append(args, image)
+}
+
+// verboseManifest is an object representation of the docker manifest inspect --verbose output for a platform
+type verboseManifest struct {
+	Descriptor struct {
+		Digest   string            `json:"digest"`
+		Platform *manifestPlatform `json:"platform"`
+	}
+	SchemaV2Manifest struct {
+		Config struct {
+			Size int64 `json:"size"`
+		} `json:"config"`
+		Layers []struct {
+			Size int64 `json:"size"`
+		} `json:"layers"`
+	}
+}
+
+// manifestSize sums the size of the layers of the linux manifest of the architecture, the output holding a list of
+// manifests for multi-platform images. The first manifest is used when none matches the architecture
+func manifestSize(output []byte, architecture string) (int64, error) {
+	manifests, err := verboseManifests(output)
+	if err != nil {
+		return 0, err
+	}
+
+	selected := manifests[0]
+	for _, manifest := range manifests {
+		platform := manifest.Descriptor.Platform
+		if platform != nil && platform.OS == "linux" && platform.Architecture == architecture {
+			selected = manifest
+			break
+		}
+	}
+	size := selected.SchemaV2Manifest.Config.Size
+	for _, layer := range selected.SchemaV2Manifest.Layers {
+		size += layer.Size
+	}
+	return size, nil
+}
+
+// manifestPlatform is the object representation of the platform of a manifest of a manifest list
+type manifestPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant"`
+}
+
+// name returns the platform name, for instance linux/arm64 or linux/arm/v7, empty for the attestation manifests of
+// unknown platform
+func (p *manifestPlatform) name() string {
+	if p == nil || p.OS == "unknown" || p.Architecture == "unknown" {
+		return ""
+	}
+	name := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		name += "/" + p.Variant
+	}
+	return name
+}
+
+// verboseManifests decodes the docker manifest inspect --verbose output, a list of manifests for the multi-platform
+// images and a single manifest otherwise
+func verboseManifests(output []byte) ([]verboseManifest, error) {
+	var manifests []verboseManifest
+	if trimmed := bytes.TrimSpace(output); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &manifests); err != nil {
+			return nil, err
+		}
+	} else {
+		var manifest verboseManifest
+		if err := json.Unmarshal(trimmed, &manifest); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no manifest found")
+	}
+	return manifests, nil
+}
+
+func (d *dockerClient) ImagePlatforms(ctx context.Context, image string) ([]ImagePlatform, error) {
+	output, err := d.inspectManifest(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	platforms, err := manifestPlatforms(output)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the manifest of image %s: %v", image, err)
+	}
+	return platforms, nil
+}
+
+// manifestPlatforms returns the platforms of the manifests of the docker manifest inspect --verbose output, for
+// instance linux/arm64 or linux/arm/v7. The attestation manifests of unknown platform are ignored
+func manifestPlatforms(output []byte) ([]ImagePlatform, error) {
+	manifests, err := verboseManifests(output)
+	if err != nil {
+		return nil, err
+	}
+	var platforms []ImagePlatform
+	for _, manifest := range manifests {
+		if name := manifest.Descriptor.Platform.name(); name != "" {
+			platforms = append(platforms, ImagePlatform{Platform: name, Digest: manifest.Descriptor.Digest})
+		}
+	}
+	return platforms, nil
+}
+
+func (d *dockerClient) ImageCreated(ctx context.Context, image string) (time.Time, error) {
+	var output []byte
+	err := withRegistryAuth(ctx, d.credentials, image, func(authFile string) error {
+		command := exec.CommandContext(ctx, "docker", "buildx", "imagetools", "inspect", "--format", "{{json .Image}}", image)
+		command.Env = dockerConfigEnv(authFile)
+		var err error
+		if output, err = command.Output(); err != nil {
+			return dockerError(fmt.Sprintf("error while inspecting the config of image %s", image), output, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	created, err := configCreated(output, runtime.GOARCH)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error while decoding the config of image %s: %v", image, err)
+	}
+	return created, nil
+}
+
+// configCreated returns the creation time of the image config of the docker buildx imagetools inspect output, the
+// output holding a config per platform for multi-platform images. The linux config of the architecture is used, or
+// else the config of the first platform
+func configCreated(output []byte, architecture string) (time.Time, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(output, &fields); err != nil {
+		return time.Time{}, err
+	}
+	config := output
+	if _, found := fields["created"]; !found {
+		var platforms []string
+		for platform := range fields {
+			platforms = append(platforms, platform)
+		}
+		if len(platforms) == 0 {
+			return time.Time{}, fmt.Errorf("no image config found")
+		}
+		sort.Strings(platforms)
+		config = fields[platforms[0]]
+		if platformConfig, found := fields["linux/"+architecture]; found {
+			config = platformConfig
+		}
+	}
+	var imageConfig struct {
+		Created *time.Time `json:"created"`
+	}
+	if err := json.Unmarshal(config, &imageConfig); err != nil {
+		return time.Time{}, err
+	}
+	if imageConfig.Created == nil {
+		return time.Time{}, fmt.Errorf("no creation time in the image config")
+	}
+	return *imageConfig.Created, nil
+}
+
 func dockerError(message string, output []byte, err error) error {
 	var outputAsString string
 	if output != nil {
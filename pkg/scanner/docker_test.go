@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Docker client", func() {
+
+	Describe("manifest size", func() {
+
+		It("sums the config and layers sizes of a single platform image", func() {
+			output := `{"Ref":"docker.io/library/app:1.0","Descriptor":{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","size":528},
+"SchemaV2Manifest":{"config":{"size":1500},"layers":[{"size":3000000},{"size":500}]}}`
+
+			size, err := manifestSize([]byte(output), "amd64")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(3002000)))
+		})
+
+		It("selects the linux manifest of the architecture of a multi-platform image", func() {
+			output := `[
+{"Descriptor":{"platform":{"architecture":"arm64","os":"linux"}},"SchemaV2Manifest":{"config":{"size":10},"layers":[{"size":100}]}},
+{"Descriptor":{"platform":{"architecture":"amd64","os":"linux"}},"SchemaV2Manifest":{"config":{"size":20},"layers":[{"size":200},{"size":300}]}}
+]`
+
+			size, err := manifestSize([]byte(output), "amd64")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(520)))
+
+			size, err = manifestSize([]byte(output), "s390x")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(110)))
+		})
+
+		It("returns an error when the output is not a manifest", func() {
+			_, err := manifestSize([]byte("no such manifest"), "amd64")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("manifest platforms", func() {
+
+		It("lists the platforms of a multi-platform image with the digests of their manifest", func() {
+			output := `[
+{"Descriptor":{"digest":"sha256:aaa","platform":{"architecture":"amd64","os":"linux"}}},
+{"Descriptor":{"digest":"sha256:bbb","platform":{"architecture":"arm","os":"linux","variant":"v7"}}},
+{"Descriptor":{"digest":"sha256:ccc","platform":{"architecture":"unknown","os":"unknown"}}}
+]`
+
+			platforms, err := manifestPlatforms([]byte(output))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platforms).To(Equal([]ImagePlatform{
+				{Platform: "linux/amd64", Digest: "sha256:aaa"},
+				{Platform: "linux/arm/v7", Digest: "sha256:bbb"},
+			}))
+		})
+
+		It("lists the platform of a single platform image", func() {
+			output := `{"Descriptor":{"digest":"sha256:aaa","platform":{"architecture":"arm64","os":"linux"}}}`
+
+			platforms, err := manifestPlatforms([]byte(output))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platforms).To(Equal([]ImagePlatform{{Platform: "linux/arm64", Digest: "sha256:aaa"}}))
+		})
+	})
+
+	Describe("config creation time", func() {
+
+		It("reads the creation time of a single platform image", func() {
+			output := `{"created":"2023-03-01T10:00:00.123Z","architecture":"amd64","os":"linux","config":{}}`
+
+			created, err := configCreated([]byte(output), "amd64")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created).To(BeTemporally("==", time.Date(2023, 3, 1, 10, 0, 0, 123000000, time.UTC)))
+		})
+
+		It("selects the linux config of the architecture of a multi-platform image", func() {
+			output := `{"linux/amd64":{"created":"2023-03-01T10:00:00Z"},"linux/arm64":{"created":"2023-02-01T10:00:00Z"}}`
+
+			created, err := configCreated([]byte(output), "arm64")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created.Month()).To(Equal(time.February))
+
+			created, err = configCreated([]byte(output), "s390x")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created.Month()).To(Equal(time.March))
+		})
+
+		It("returns an error when the config has no creation time", func() {
+			_, err := configCreated([]byte(`{"architecture":"amd64"}`), "amd64")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
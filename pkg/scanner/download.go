@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// DatasetConfig locates a dataset the vulnerabilities are enriched with, such as the CISA KEV catalog
+type DatasetConfig struct {
+	// Source is the URL the dataset is downloaded from, or the path of a local copy for offline scans
+	Source string
+	// CacheFile is where the downloaded dataset is cached, a cached dataset younger than MaxAge being reused
+	// rather than downloaded again
+	CacheFile string
+	MaxAge    time.Duration
+}
+
+// loadDataset returns the content of the dataset, read from the local copy or from the cache file when fresh enough,
+// downloaded otherwise. When the download fails, a stale cached dataset is used rather than failing the scan
+func loadDataset(config *DatasetConfig, httpClient *http.Client) ([]byte, error) {
+	if !strings.HasPrefix(config.Source, "http://") && !strings.HasPrefix(config.Source, "https://") {
+		content, err := os.ReadFile(config.Source)
+		if err != nil {
+			return nil, fmt.Errorf("could not read dataset file %s: %v", config.Source, err)
+		}
+		return content, nil
+	}
+
+	cacheInfo, cacheErr := os.Stat(config.CacheFile)
+	if cacheErr == nil && time.Since(cacheInfo.ModTime()) < config.MaxAge {
+		return os.ReadFile(config.CacheFile)
+	}
+
+	content, err := download(config.Source, httpClient)
+	if err != nil {
+		if cacheErr != nil {
+			return nil, err
+		}
+		logr.Warnf("%v, using the dataset cached on %s", err, cacheInfo.ModTime().Format(time.RFC3339))
+		return os.ReadFile(config.CacheFile)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.CacheFile), 0755); err != nil {
+		logr.Warnf("Could not create the cache directory of %s: %v", config.CacheFile, err)
+	} else if err := os.WriteFile(config.CacheFile, content, 0644); err != nil {
+		logr.Warnf("Could not cache the dataset to %s: %v", config.CacheFile, err)
+	}
+	return content, nil
+}
+
+func download(url string, httpClient *http.Client) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("could not download %s: status code %d", url, resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %v", url, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// DefaultEPSSDatasetURL is the URL of the daily EPSS scores dataset published by FIRST
+const DefaultEPSSDatasetURL = "https://epss.cyentia.com/epss_scores-current.csv.gz"
+
+// EPSS is the Exploit Prediction Scoring System score of a vulnerability
+type EPSS struct {
+	// Score is the probability, between 0 and 1, of the vulnerability being exploited in the next 30 days
+	Score float64
+	// Percentile is the proportion of vulnerabilities with a lower or equal score
+	Percentile float64
+}
+
+// EPSSDataset holds the EPSS scores of the vulnerabilities per CVE
+type EPSSDataset struct {
+	// ScoreDate is the date the scores were computed, as recorded in the dataset
+	ScoreDate string
+	scores    map[string]EPSS
+}
+
+// LoadEPSSDataset downloads the EPSS scores dataset, or reads it from the cache or from a local copy.
+// The dataset is a csv file, optionally gzipped, of cve, epss and percentile columns
+func LoadEPSSDataset(config *DatasetConfig) (*EPSSDataset, error) {
+	content, err := loadDataset(config, &http.Client{Timeout: 5 * time.Minute})
+	if err != nil {
+		return nil, fmt.Errorf("could not load the EPSS dataset: %v", err)
+	}
+	return parseEPSSDataset(content)
+}
+
+func parseEPSSDataset(content []byte) (*EPSSDataset, error) {
+	var reader io.Reader = bytes.NewReader(content)
+	if len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error while decompressing the EPSS dataset: %v", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error while decompressing the EPSS dataset: %v", err)
+	}
+
+	dataset := &EPSSDataset{scores: make(map[string]EPSS)}
+	// the dataset starts with a comment line such as #model_version:v2023.03.01,score_date:2023-09-05T00:00:00+0000
+	if firstLine, rest, found := strings.Cut(string(content), "\n"); found && strings.HasPrefix(firstLine, "#") {
+		for _, field := range strings.Split(strings.TrimPrefix(firstLine, "#"), ",") {
+			if value, ok := strings.CutPrefix(field, "score_date:"); ok {
+				dataset.ScoreDate = value
+			}
+		}
+		content = []byte(rest)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding the EPSS dataset: %v", err)
+	}
+	for n, record := range records {
+		if n == 0 || len(record) < 3 {
+			// header
+			continue
+		}
+		score, scoreErr := strconv.ParseFloat(record[1], 64)
+		percentile, percentileErr := strconv.ParseFloat(record[2], 64)
+		if scoreErr != nil || percentileErr != nil {
+			return nil, fmt.Errorf("error while decoding the EPSS dataset: invalid scores on line %d: %v", n+1, record)
+		}
+		dataset.scores[record[0]] = EPSS{Score: score, Percentile: percentile}
+	}
+	logr.Infof("Loaded the EPSS scores of %d vulnerabilities computed on %s", len(dataset.scores), dataset.ScoreDate)
+	return dataset, nil
+}
+
+// mark sets the EPSS score of the vulnerabilities of the trivy results. A nil dataset scores none
+func (d *EPSSDataset) mark(trivyOutput []TrivyOutputResults) {
+	if d == nil {
+		return
+	}
+	for i := range trivyOutput {
+		for j := range trivyOutput[i].Vulnerabilities {
+			vulnerability := &trivyOutput[i].Vulnerabilities[j]
+			if epss, ok := d.scores[vulnerability.VulnerabilityID]; ok {
+				vulnerability.EPSS = &epss
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EPSS", func() {
+
+	const dataset = "#model_version:v2023.03.01,score_date:2023-09-05T00:00:00+0000\n" +
+		"cve,epss,percentile\n" +
+		"CVE-2021-44228,0.97565,0.99996\n" +
+		"CVE-2021-3326,0.00216,0.59012\n"
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("scores the vulnerabilities of the dataset", func() {
+		filename := filepath.Join(tmpDir, "epss_scores.csv")
+		Expect(os.WriteFile(filename, []byte(dataset), 0644)).To(Succeed())
+		epssDataset, err := LoadEPSSDataset(&DatasetConfig{Source: filename})
+		Expect(err).NotTo(HaveOccurred())
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2021-44228"},
+			{VulnerabilityID: "CVE-2023-0001"},
+		}}}
+
+		epssDataset.mark(results)
+
+		Expect(epssDataset.ScoreDate).To(Equal("2023-09-05T00:00:00+0000"))
+		Expect(results[0].Vulnerabilities[0].EPSS).To(Equal(&EPSS{Score: 0.97565, Percentile: 0.99996}))
+		Expect(results[0].Vulnerabilities[1].EPSS).To(BeNil())
+	})
+
+	It("reads the gzipped dataset", func() {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		_, err := writer.Write([]byte(dataset))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		filename := filepath.Join(tmpDir, "epss_scores.csv.gz")
+		Expect(os.WriteFile(filename, compressed.Bytes(), 0644)).To(Succeed())
+
+		epssDataset, err := LoadEPSSDataset(&DatasetConfig{Source: filename})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(epssDataset.scores).To(HaveLen(2))
+		Expect(epssDataset.scores["CVE-2021-3326"]).To(Equal(EPSS{Score: 0.00216, Percentile: 0.59012}))
+	})
+
+	It("returns an error when the scores are invalid", func() {
+		filename := filepath.Join(tmpDir, "epss_scores.csv")
+		Expect(os.WriteFile(filename, []byte("cve,epss,percentile\nCVE-2021-44228,high,0.99996\n"), 0644)).To(Succeed())
+
+		_, err := LoadEPSSDataset(&DatasetConfig{Source: filename})
+
+		Expect(err).To(MatchError(ContainSubstring("invalid scores on line 2")))
+	})
+})
@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"fmt"
+	"time"
+)
+
+// FailurePolicy selects the vulnerabilities failing a scan, see Config.FailFast
+type FailurePolicy struct {
+	// MinSeverity fails the scan on the vulnerabilities of at least this severity, for instance HIGH. The severity
+	// does not fail the scan when empty
+	MinSeverity string
+	// KnownExploited fails the scan on the vulnerabilities exploited in the wild, see Config.KEVCatalog
+	KnownExploited bool
+	// FindingsState is the triage of the findings, the vulnerabilities triaged as false positives or accepted not
+	// failing the scan. All the vulnerabilities can fail the scan when nil
+	FindingsState *FindingsState
+	// FixableOnly only fails the scan on the vulnerabilities with a fixed version, as the teams cannot act on the other ones
+	FixableOnly bool
+}
+
+// failure returns the first vulnerability of the image failing the policy, described as in the report metadata, false
+// when none does. The vulnerabilities the namespaces running the image suppress, dismissed in the findings state, or
+// without fixed version with FixableOnly, do not fail the policy
+func (p *FailurePolicy) failure(image ScannedImage, now time.Time) (string, bool) {
+	if p == nil || image.ScanError != nil && !image.TimedOut {
+		return "", false
+	}
+	// the suppressions are applied to a copy, the report applying them to the collected images
+	images := []ScannedImage{image}
+	suppressVulnerabilities(images, now)
+	if p.FindingsState != nil {
+		p.FindingsState.annotate(images)
+	}
+	floor, hasFloor := severityScores[p.MinSeverity]
+	for _, target := range images[0].Results() {
+		for _, vulnerability := range target.Vulnerabilities {
+			if vulnerability.Triage.Dismissed() || p.FixableOnly && !vulnerability.Fixable() {
+				continue
+			}
+			severityFailure := p.MinSeverity != "" && hasFloor && severityScores[vulnerability.Severity] >= floor
+			if severityFailure || p.KnownExploited && vulnerability.KnownExploited != nil {
+				return fmt.Sprintf("%s %s (%s) in %s", vulnerability.Severity, vulnerability.VulnerabilityID, vulnerability.PkgName, image.ImageName), true
+			}
+		}
+	}
+	return "", false
+}
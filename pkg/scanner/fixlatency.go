@@ -0,0 +1,217 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// fixRetention is the age of the fixes of the vulnerability history past which they are forgotten
+const fixRetention = 400 * 24 * time.Hour
+
+// FixRecord is a vulnerability no longer found in any image of a repository, either fixed in the images or no longer
+// run by the team, recorded by the vulnerability history with the time it was first and last found. ImageName is the
+// repository of the images, see imageRepository
+type FixRecord struct {
+	Area            string    `json:"area"`
+	Team            string    `json:"team"`
+	ImageName       string    `json:"imageName"`
+	VulnerabilityID string    `json:"vulnerabilityId"`
+	PkgName         string    `json:"pkgName"`
+	Severity        string    `json:"severity"`
+	FirstSeen       time.Time `json:"firstSeen"`
+	FixedAt         time.Time `json:"fixedAt"`
+}
+
+// FixLatency is the time the vulnerabilities of a severity took to be fixed, for a team or all the teams when Area and
+// Team are empty
+type FixLatency struct {
+	Area     string `json:",omitempty"`
+	Team     string `json:",omitempty"`
+	Severity string
+	// Fixed is the number of vulnerabilities fixed, MeanDays and MedianDays the mean and median number of days between
+	// the scan they were first found and the scan they were no longer found
+	Fixed      int
+	MeanDays   float64
+	MedianDays float64
+}
+
+// trackFixes records the fixes of the vulnerabilities of the history no longer found in any image of their repository
+// in the report, so that a vulnerability still found once its image is retagged is not fixed, the fix of a
+// vulnerability being attributed to the teams that ran the images of the repository when the vulnerability was found.
+// It is called before the first seen times of the history are updated with the report. The vulnerabilities tracked
+// before their severity was recorded are not counted, and the vulnerabilities suppressed since are not fixes
+func (h *VulnerabilityHistory) trackFixes(r *VulnerabilityReport, scanTime time.Time) {
+	current := make(map[string]map[string]bool)
+	for _, image := range r.ScannedImages {
+		repository := imageRepository(image.ImageName)
+		keys, tracked := current[repository]
+		if tracked && keys == nil {
+			continue
+		}
+		if image.ScanError != nil || image.Skipped {
+			// the repository keeps its history, the vulnerabilities of the image are not known to be fixed
+			current[repository] = nil
+			continue
+		}
+		if keys == nil {
+			keys = make(map[string]bool)
+			current[repository] = keys
+		}
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				keys[historyKey(vulnerability)] = true
+			}
+		}
+		// the suppressed vulnerabilities are accepted rather than fixed
+		for _, suppressed := range image.SuppressedVulnerabilities {
+			keys[suppressed.VulnerabilityID+" "+suppressed.PkgName] = true
+		}
+	}
+	for repository, firstSeen := range h.FirstSeen {
+		keys, scanned := current[repository]
+		if scanned && keys == nil || !scanned && r.Metadata.Partial() {
+			continue
+		}
+		for key, seen := range firstSeen {
+			severity, ok := h.Severities[repository][key]
+			if keys[key] || !ok {
+				continue
+			}
+			vulnerabilityID, pkgName, _ := strings.Cut(key, " ")
+			for _, team := range h.ImageTeams[repository] {
+				area, teamName, _ := strings.Cut(team, "/")
+				h.Fixes = append(h.Fixes, FixRecord{Area: area, Team: teamName, ImageName: repository, VulnerabilityID: vulnerabilityID,
+					PkgName: pkgName, Severity: severity, FirstSeen: seen, FixedAt: scanTime})
+			}
+		}
+	}
+	kept := h.Fixes[:0]
+	for _, fix := range h.Fixes {
+		if fix.FixedAt.After(scanTime.Add(-fixRetention)) {
+			kept = append(kept, fix)
+		}
+	}
+	h.Fixes = kept
+}
+
+// trackSeverities records the severity of the vulnerabilities of the history and the teams running the images of each
+// repository of the report, so that their fixes can be attributed once the vulnerabilities are no longer found. It is
+// called once the first seen times of the history are updated with the report, the repositories of an image whose scan
+// failed keeping the severities of the vulnerabilities they keep
+func (h *VulnerabilityHistory) trackSeverities(r *VulnerabilityReport) {
+	if h.Severities == nil {
+		h.Severities = make(map[string]map[string]string)
+	}
+	if h.ImageTeams == nil {
+		h.ImageTeams = make(map[string][]string)
+	}
+	severities := make(map[string]map[string]string)
+	for _, image := range r.ScannedImages {
+		repository := imageRepository(image.ImageName)
+		if image.ScanError != nil || image.Skipped {
+			continue
+		}
+		if severities[repository] == nil {
+			severities[repository] = make(map[string]string)
+			for key, severity := range h.Severities[repository] {
+				if _, ok := h.FirstSeen[repository][key]; ok {
+					severities[repository][key] = severity
+				}
+			}
+		}
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				severities[repository][historyKey(vulnerability)] = vulnerability.Severity
+			}
+		}
+	}
+	for repository, repositorySeverities := range severities {
+		h.Severities[repository] = repositorySeverities
+	}
+	teams := make(map[string][]string)
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			for _, image := range team.Images {
+				repository := imageRepository(image.ImageName)
+				if key := area.Name + "/" + team.Name; !containsString(teams[repository], key) {
+					teams[repository] = append(teams[repository], key)
+				}
+			}
+		}
+	}
+	for repository, repositoryTeams := range teams {
+		sort.Strings(repositoryTeams)
+		h.ImageTeams[repository] = repositoryTeams
+	}
+	for repository := range h.Severities {
+		if _, ok := h.FirstSeen[repository]; !ok {
+			delete(h.Severities, repository)
+			delete(h.ImageTeams, repository)
+		}
+	}
+}
+
+// FixLatencies returns the mean and median time to fix the vulnerabilities of each severity fixed since the given
+// time, per team sorted by area, team and decreasing severity, followed by all the teams per severity
+func (h *VulnerabilityHistory) FixLatencies(since time.Time) []FixLatency {
+	byTeam := make(map[FixLatency][]float64)
+	for _, fix := range h.Fixes {
+		if fix.FixedAt.Before(since) {
+			continue
+		}
+		days := fix.FixedAt.Sub(fix.FirstSeen).Hours() / 24
+		byTeam[FixLatency{Area: fix.Area, Team: fix.Team, Severity: fix.Severity}] = append(byTeam[FixLatency{Area: fix.Area, Team: fix.Team, Severity: fix.Severity}], days)
+		byTeam[FixLatency{Severity: fix.Severity}] = append(byTeam[FixLatency{Severity: fix.Severity}], days)
+	}
+	var latencies []FixLatency
+	for key, days := range byTeam {
+		sort.Float64s(days)
+		total := 0.0
+		for _, d := range days {
+			total += d
+		}
+		median := days[len(days)/2]
+		if len(days)%2 == 0 {
+			median = (days[len(days)/2-1] + days[len(days)/2]) / 2
+		}
+		key.Fixed, key.MeanDays, key.MedianDays = len(days), total/float64(len(days)), median
+		latencies = append(latencies, key)
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		a, b := latencies[i], latencies[j]
+		if (a.Team == "") != (b.Team == "") {
+			return b.Team == ""
+		}
+		if a.Area != b.Area {
+			return a.Area < b.Area
+		}
+		if a.Team != b.Team {
+			return a.Team < b.Team
+		}
+		return severityScores[a.Severity] > severityScores[b.Severity]
+	})
+	return latencies
+}
+
+// WriteFixLatencies writes the fix latencies as a table, the latencies of all the teams being listed as team ALL
+func WriteFixLatencies(w io.Writer, latencies []FixLatency) error {
+	var out strings.Builder
+	table := tabwriter.NewWriter(&out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(table, "TEAM\tAREA\tSEVERITY\tFIXED\tMEAN DAYS\tMEDIAN DAYS\t\n")
+	for _, latency := range latencies {
+		team, area := latency.Team, latency.Area
+		if team == "" {
+			team, area = "ALL", "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%.1f\t%.1f\t\n", team, area, latency.Severity, latency.Fixed, latency.MeanDays, latency.MedianDays)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
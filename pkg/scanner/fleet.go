@@ -0,0 +1,90 @@
+package scanner
+
+import "sort"
+
+// FleetVulnerability is a vulnerability listed once across the fleet with the images and the teams it affects, so that
+// the security teams can coordinate its fix, for instance of a new OpenSSL CVE, across the teams
+type FleetVulnerability struct {
+	VulnerabilityID string
+	// Severity is the highest severity of the vulnerability across its packages and images
+	Severity string
+	Title    string
+	// PkgNames are the packages the vulnerability is found in
+	PkgNames []string
+	// Fixable is true when a fixed version is available for one of its packages at least
+	Fixable        bool
+	KnownExploited bool
+	Images         []string
+	// Teams are the teams running the affected images, as <area>/<team>
+	Teams []string
+}
+
+// UniqueVulnerabilities returns each vulnerability found in the images once, with the images and the teams it affects,
+// the most severe vulnerabilities first, then the vulnerabilities affecting the most images
+func (r *VulnerabilityReport) UniqueVulnerabilities() []FleetVulnerability {
+	teamsByImage := make(map[string][]string)
+	for areaName, area := range r.AreaSummary {
+		for teamName, team := range area.Teams {
+			for _, image := range team.Images {
+				teamsByImage[image.ImageName] = append(teamsByImage[image.ImageName], areaName+"/"+teamName)
+			}
+		}
+	}
+
+	vulnerabilities := make(map[string]*FleetVulnerability)
+	packages := make(map[string]map[string]bool)
+	images := make(map[string]map[string]bool)
+	teams := make(map[string]map[string]bool)
+	for _, finding := range matchingFindings(r.ScannedImages, func(Vulnerabilities) bool { return true }) {
+		v := finding.Vulnerability
+		fleetVulnerability, ok := vulnerabilities[v.VulnerabilityID]
+		if !ok {
+			fleetVulnerability = &FleetVulnerability{VulnerabilityID: v.VulnerabilityID, Severity: v.Severity, Title: v.Title}
+			vulnerabilities[v.VulnerabilityID] = fleetVulnerability
+			packages[v.VulnerabilityID] = make(map[string]bool)
+			images[v.VulnerabilityID] = make(map[string]bool)
+			teams[v.VulnerabilityID] = make(map[string]bool)
+		}
+		if severityScores[v.Severity] > severityScores[fleetVulnerability.Severity] {
+			fleetVulnerability.Severity = v.Severity
+		}
+		if fleetVulnerability.Title == "" {
+			fleetVulnerability.Title = v.Title
+		}
+		fleetVulnerability.Fixable = fleetVulnerability.Fixable || v.Fixable()
+		fleetVulnerability.KnownExploited = fleetVulnerability.KnownExploited || v.KnownExploited != nil
+		packages[v.VulnerabilityID][v.PkgName] = true
+		images[v.VulnerabilityID][finding.ImageName] = true
+		for _, team := range teamsByImage[finding.ImageName] {
+			teams[v.VulnerabilityID][team] = true
+		}
+	}
+
+	var result []FleetVulnerability
+	for id, fleetVulnerability := range vulnerabilities {
+		fleetVulnerability.PkgNames = sortedKeys(packages[id])
+		fleetVulnerability.Images = sortedKeys(images[id])
+		fleetVulnerability.Teams = sortedKeys(teams[id])
+		result = append(result, *fleetVulnerability)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if severityScores[result[i].Severity] != severityScores[result[j].Severity] {
+			return severityScores[result[i].Severity] > severityScores[result[j].Severity]
+		}
+		if len(result[i].Images) != len(result[j].Images) {
+			return len(result[i].Images) > len(result[j].Images)
+		}
+		return result[i].VulnerabilityID < result[j].VulnerabilityID
+	})
+	return result
+}
+
+// sortedKeys returns the keys of the set in alphabetical order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
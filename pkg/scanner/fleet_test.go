@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unique vulnerabilities", func() {
+
+	It("lists each vulnerability once with the images and the teams it affects", func() {
+		api := NewScannedImage("api:1.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-1", PkgName: "libssl3", FixedVersion: "3.0.10", Severity: "HIGH", Title: "openssl: buffer overflow"},
+			{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"},
+			{VulnerabilityID: "CVE-3", PkgName: "zlib", Severity: "LOW"},
+		}}}, nil)
+		web := NewScannedImage("web:2.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "HIGH", KnownExploited: &KnownExploitedVulnerability{}},
+			{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"},
+		}}}, nil)
+		report := &VulnerabilityReport{
+			ScannedImages: []ScannedImage{api, web},
+			AreaSummary: map[string]*AreaSummary{
+				"payments": {Teams: map[string]*TeamSummary{"api": {Images: []ScannedImage{api}}, "web": {Images: []ScannedImage{web}}}},
+				"shop":     {Teams: map[string]*TeamSummary{"web": {Images: []ScannedImage{web}}}},
+			},
+		}
+
+		Expect(report.UniqueVulnerabilities()).To(Equal([]FleetVulnerability{
+			{VulnerabilityID: "CVE-1", Severity: "CRITICAL", Title: "openssl: buffer overflow", PkgNames: []string{"libssl3", "openssl"}, Fixable: true, KnownExploited: true,
+				Images: []string{"api:1.0", "web:2.0"}, Teams: []string{"payments/api", "payments/web", "shop/web"}},
+			{VulnerabilityID: "CVE-2", Severity: "HIGH", PkgNames: []string{"curl"}, Images: []string{"web:2.0"}, Teams: []string{"payments/web", "shop/web"}},
+			{VulnerabilityID: "CVE-3", Severity: "LOW", PkgNames: []string{"zlib"}, Images: []string{"api:1.0"}, Teams: []string{"payments/api"}},
+		}))
+	})
+})
@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"sort"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// staleCheckInterval is how often the watched images are checked for results older than Config.MaxResultAge
+var staleCheckInterval = time.Minute
+
+// stale returns true when the results are older than the maximum age, results never expiring when it is 0. The
+// results of unknown scan time are never stale
+func (o *TrivyOutput) stale(maxAge time.Duration, now time.Time) bool {
+	return maxAge > 0 && !o.CreatedAt.IsZero() && now.Sub(o.CreatedAt) > maxAge
+}
+
+// stale returns true when the results of the image are older than the maximum age, results never expiring when it
+// is 0. The images of unknown scan time, for instance the skipped images, are never stale
+func (i ScannedImage) stale(maxAge time.Duration, now time.Time) bool {
+	return maxAge > 0 && i.ScanTime != nil && now.Sub(*i.ScanTime) > maxAge
+}
+
+// markStale flags the images whose results are older than the maximum age
+func markStale(scannedImages []ScannedImage, maxAge time.Duration, now time.Time) {
+	for i := range scannedImages {
+		scannedImages[i].Stale = scannedImages[i].stale(maxAge, now)
+	}
+}
+
+// anyStale returns true when the results of one of the images are older than the maximum age
+func anyStale(scannedImages []ScannedImage, maxAge time.Duration, now time.Time) bool {
+	for _, scannedImage := range scannedImages {
+		if scannedImage.stale(maxAge, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// staleImageList returns the containers of the images whose results are older than the maximum age by image name,
+// so that they are rescanned
+func staleImageList(scannedImages []ScannedImage, maxAge time.Duration, now time.Time) map[string][]k8s.ContainerSummary {
+	imageList := make(map[string][]k8s.ContainerSummary)
+	for _, scannedImage := range scannedImages {
+		if !scannedImage.stale(maxAge, now) {
+			continue
+		}
+		for _, container := range scannedImage.Containers {
+			imageList[container.Image] = append(imageList[container.Image], container)
+		}
+	}
+	return imageList
+}
+
+// replaceScannedImages returns the scanned images with the images of the same name replaced by the rescanned ones,
+// sorted by name
+func replaceScannedImages(scannedImages, rescannedImages []ScannedImage) []ScannedImage {
+	rescanned := scannedImageNames(rescannedImages)
+	var replaced []ScannedImage
+	for _, scannedImage := range scannedImages {
+		if !rescanned[scannedImage.ImageName] {
+			replaced = append(replaced, scannedImage)
+		}
+	}
+	replaced = append(replaced, rescannedImages...)
+	sort.SliceStable(replaced, func(i, j int) bool {
+		return replaced[i].ImageName < replaced[j].ImageName
+	})
+	return replaced
+}
+
+// StaleImages returns the team images whose results are older than the maximum result age, see Config.MaxResultAge
+func (t *TeamSummary) StaleImages() []ScannedImage {
+	var images []ScannedImage
+	for _, i := range t.Images {
+		if i.Stale {
+			images = append(images, i)
+		}
+	}
+	return images
+}
@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Result freshness", func() {
+
+	now := time.Date(2023, 9, 8, 10, 0, 0, 0, time.UTC)
+	lastWeek, yesterday := now.Add(-7*24*time.Hour), now.Add(-24*time.Hour)
+
+	images := func() []ScannedImage {
+		return []ScannedImage{
+			{ImageName: "api:1", ScanTime: &lastWeek, Containers: []k8s.ContainerSummary{{Image: "mirror/api:1", PodName: "api-1"}, {Image: "mirror/api:1", PodName: "api-2"}}},
+			{ImageName: "skipped:1", Skipped: true, Containers: []k8s.ContainerSummary{{Image: "skipped:1", PodName: "skipped-1"}}},
+			{ImageName: "web:1", ScanTime: &yesterday, Containers: []k8s.ContainerSummary{{Image: "web:1", PodName: "web-1"}}},
+		}
+	}
+
+	It("flags the images whose results are older than the maximum age", func() {
+		scannedImages := images()
+
+		markStale(scannedImages, 72*time.Hour, now)
+
+		Expect([]bool{scannedImages[0].Stale, scannedImages[1].Stale, scannedImages[2].Stale}).To(Equal([]bool{true, false, false}))
+		Expect((&TeamSummary{Images: scannedImages}).StaleImages()).To(Equal(scannedImages[:1]))
+
+		markStale(scannedImages, 0, now)
+
+		Expect(scannedImages[0].Stale).To(BeFalse())
+	})
+
+	It("rescans the containers of the stale images and replaces their results", func() {
+		Expect(staleImageList(images(), 72*time.Hour, now)).To(Equal(map[string][]k8s.ContainerSummary{
+			"mirror/api:1": {{Image: "mirror/api:1", PodName: "api-1"}, {Image: "mirror/api:1", PodName: "api-2"}},
+		}))
+
+		rescanned := ScannedImage{ImageName: "api:1", ScanTime: &now}
+		replaced := replaceScannedImages(images(), []ScannedImage{rescanned})
+
+		Expect(replaced).To(HaveLen(3))
+		Expect(replaced[0]).To(Equal(rescanned))
+		Expect(replaced[2].ImageName).To(Equal("web:1"))
+	})
+
+	It("dates the stale trivy outputs", func() {
+		Expect((&TrivyOutput{CreatedAt: lastWeek}).stale(72*time.Hour, now)).To(BeTrue())
+		Expect((&TrivyOutput{CreatedAt: yesterday}).stale(72*time.Hour, now)).To(BeFalse())
+		Expect((&TrivyOutput{}).stale(72*time.Hour, now)).To(BeFalse())
+	})
+})
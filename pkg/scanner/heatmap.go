@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Heatmap dimensions, the rows of the matrices of a Heatmap
+const (
+	HeatmapTeams      = "team"
+	HeatmapNamespaces = "namespace"
+)
+
+// Heatmap holds the vulnerability counts of the report aggregated as matrices of the teams and of the namespaces by
+// severity, shaped for the heatmap visualisations so that they are charted without reprocessing the whole report
+type Heatmap struct {
+	Metadata   ReportMetadata
+	Teams      HeatmapMatrix
+	Namespaces HeatmapMatrix
+}
+
+// HeatmapMatrix is a matrix of vulnerability counts, Values[i][j] being the count of the row Rows[i] and of the
+// severity Columns[j]. The rows are ordered from the most vulnerable, by their counts of the most severe severities
+type HeatmapMatrix struct {
+	// Dimension is the dimension of the rows, HeatmapTeams or HeatmapNamespaces
+	Dimension string
+	Rows      []string
+	Columns   []string
+	Values    [][]int
+}
+
+// NewHeatmap returns the heatmap of the report. The team rows are named area/team, as the teams of different areas may
+// share their name. The vulnerabilities of an image are counted once per team and once per namespace running it
+func NewHeatmap(report *VulnerabilityReport) *Heatmap {
+	teams := make(map[string]map[string]int)
+	for _, area := range report.AreaSummary {
+		for _, team := range area.Teams {
+			teams[area.Name+"/"+team.Name] = team.TotalVulnerabilityBySeverity()
+		}
+	}
+	namespaces := make(map[string]map[string]int)
+	for _, image := range report.ScannedImages {
+		counted := make(map[string]bool)
+		for _, container := range image.Containers {
+			if counted[container.Namespace] {
+				continue
+			}
+			counted[container.Namespace] = true
+			if namespaces[container.Namespace] == nil {
+				namespaces[container.Namespace] = make(map[string]int)
+			}
+			for severity, count := range image.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+				namespaces[container.Namespace][severity] += count
+			}
+		}
+	}
+	return &Heatmap{
+		Metadata:   report.Metadata,
+		Teams:      newHeatmapMatrix(HeatmapTeams, teams),
+		Namespaces: newHeatmapMatrix(HeatmapNamespaces, namespaces),
+	}
+}
+
+func newHeatmapMatrix(dimension string, counts map[string]map[string]int) HeatmapMatrix {
+	matrix := HeatmapMatrix{Dimension: dimension, Rows: []string{}, Columns: summarySeverities, Values: [][]int{}}
+	for row := range counts {
+		matrix.Rows = append(matrix.Rows, row)
+	}
+	sort.Slice(matrix.Rows, func(i, j int) bool {
+		for _, severity := range summarySeverities {
+			if counts[matrix.Rows[i]][severity] != counts[matrix.Rows[j]][severity] {
+				return counts[matrix.Rows[i]][severity] > counts[matrix.Rows[j]][severity]
+			}
+		}
+		return matrix.Rows[i] < matrix.Rows[j]
+	})
+	for _, row := range matrix.Rows {
+		values := make([]int, len(summarySeverities))
+		for i, severity := range summarySeverities {
+			values[i] = counts[row][severity]
+		}
+		matrix.Values = append(matrix.Values, values)
+	}
+	return matrix
+}
+
+// WriteCSV writes the heatmap as csv, a record per dimension, row and severity with its count, the long format the
+// visualisation tools pivot into a heatmap
+func (h *Heatmap) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"dimension", "row", "severity", "count"}); err != nil {
+		return err
+	}
+	for _, matrix := range []HeatmapMatrix{h.Teams, h.Namespaces} {
+		for i, row := range matrix.Rows {
+			for j, severity := range matrix.Columns {
+				if err := writer.Write([]string{matrix.Dimension, row, severity, strconv.Itoa(matrix.Values[i][j])}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Save writes the heatmap to the file, as csv when its extension is .csv and as json otherwise
+func (h *Heatmap) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not create heatmap file %s: %v", filename, err)
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		err = h.WriteCSV(file)
+	} else {
+		err = json.NewEncoder(file).Encode(h)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write heatmap file %s: %v", filename, err)
+	}
+	return nil
+}
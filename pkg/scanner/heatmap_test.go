@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Heatmap", func() {
+
+	image := func(name string, counts map[string]int, namespaces ...string) ScannedImage {
+		var containers []k8s.ContainerSummary
+		for _, namespace := range namespaces {
+			containers = append(containers, k8s.ContainerSummary{Image: name, Namespace: namespace}, k8s.ContainerSummary{Image: name, Namespace: namespace})
+		}
+		return ScannedImage{ImageName: name, Containers: containers, VulnerabilitySummary: VulnerabilitySummary{TotalVulnerabilityBySeverity: counts}}
+	}
+
+	var report *VulnerabilityReport
+
+	BeforeEach(func() {
+		api := image("api:1.0", map[string]int{"HIGH": 3, "LOW": 1}, "payments")
+		nginx := image("nginx:1.25", map[string]int{"CRITICAL": 1, "MEDIUM": 2}, "payments", "orders")
+		report = &VulnerabilityReport{
+			ScannedImages: []ScannedImage{api, nginx},
+			AreaSummary: map[string]*AreaSummary{
+				"finance": {Name: "finance", Teams: map[string]*TeamSummary{"payments": {Name: "payments", Images: []ScannedImage{api, nginx}}}},
+				"retail":  {Name: "retail", Teams: map[string]*TeamSummary{"orders": {Name: "orders", Images: []ScannedImage{nginx}}}},
+			},
+		}
+	})
+
+	It("aggregates the vulnerabilities by team and by namespace, the most vulnerable rows first", func() {
+		heatmap := NewHeatmap(report)
+
+		Expect(heatmap.Teams).To(Equal(HeatmapMatrix{
+			Dimension: HeatmapTeams,
+			Rows:      []string{"finance/payments", "retail/orders"},
+			Columns:   []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"},
+			Values:    [][]int{{1, 3, 2, 1, 0}, {1, 0, 2, 0, 0}},
+		}))
+		Expect(heatmap.Namespaces.Rows).To(Equal([]string{"payments", "orders"}))
+		Expect(heatmap.Namespaces.Values).To(Equal([][]int{{1, 3, 2, 1, 0}, {1, 0, 2, 0, 0}}))
+	})
+
+	It("keeps apart the teams of different areas sharing their name", func() {
+		report.AreaSummary["retail"].Teams = map[string]*TeamSummary{"payments": {Name: "payments", Images: []ScannedImage{report.ScannedImages[1]}}}
+
+		heatmap := NewHeatmap(report)
+
+		Expect(heatmap.Teams.Rows).To(Equal([]string{"finance/payments", "retail/payments"}))
+		Expect(heatmap.Teams.Values).To(Equal([][]int{{1, 3, 2, 1, 0}, {1, 0, 2, 0, 0}}))
+	})
+
+	It("saves the heatmap as csv or json according to the file extension", func() {
+		dir := GinkgoT().TempDir()
+		heatmap := NewHeatmap(&VulnerabilityReport{ScannedImages: []ScannedImage{image("api:1.0", map[string]int{"HIGH": 3}, "payments")}})
+
+		Expect(heatmap.Save(filepath.Join(dir, "heatmap.csv"))).To(Succeed())
+		Expect(heatmap.Save(filepath.Join(dir, "heatmap.json"))).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(dir, "heatmap.csv"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(strings.Join([]string{
+			"dimension,row,severity,count",
+			"namespace,payments,CRITICAL,0",
+			"namespace,payments,HIGH,3",
+			"namespace,payments,MEDIUM,0",
+			"namespace,payments,LOW,0",
+			"namespace,payments,UNKNOWN,0",
+		}, "\n") + "\n"))
+		content, err = os.ReadFile(filepath.Join(dir, "heatmap.json"))
+		Expect(err).NotTo(HaveOccurred())
+		var saved Heatmap
+		Expect(json.Unmarshal(content, &saved)).To(Succeed())
+		Expect(saved.Namespaces).To(Equal(heatmap.Namespaces))
+		Expect(saved.Teams.Rows).To(BeEmpty())
+	})
+})
@@ -0,0 +1,293 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VulnerabilityHistory records the time of the first scan each vulnerability was found in each image repository, so
+// that the age of the vulnerabilities is known across the scans whatever the tags the images are rolled out with. The
+// vulnerabilities no longer found in any image of a repository are forgotten, their age restarting from zero when they
+// reappear
+type VulnerabilityHistory struct {
+	// FirstSeen holds the first scan time of the vulnerabilities per image repository, see imageRepository, keyed by
+	// vulnerability id and package
+	FirstSeen map[string]map[string]time.Time `json:"firstSeen"`
+	// TeamScores holds the severity scores of the teams recorded by the last scans, keyed by area and team, so that
+	// the scoreboard shows the change of the team scores week over week
+	TeamScores map[string][]TeamScoreRecord `json:"teamScores,omitempty"`
+	// Severities holds the severity of the vulnerabilities of FirstSeen and ImageTeams the area/team of the teams
+	// running the images of each repository, so that the fixes can be attributed once the vulnerabilities are no longer found
+	Severities map[string]map[string]string `json:"severities,omitempty"`
+	ImageTeams map[string][]string          `json:"imageTeams,omitempty"`
+	// Fixes are the vulnerabilities no longer found in the images by the last scans, see FixLatencies
+	Fixes []FixRecord `json:"fixes,omitempty"`
+}
+
+// RemediationSLAs are the maximum number of days the vulnerabilities of each severity may remain in an image once found,
+// for instance 7 for CRITICAL. The severities without SLA are not tracked against any
+type RemediationSLAs map[string]int
+
+// SLABreach is a vulnerability found in an image for longer than the remediation SLA of its severity
+type SLABreach struct {
+	VulnerabilityFinding
+	FirstSeen time.Time
+	// AgeDays is the number of days since the vulnerability was first found in the image, SLADays the SLA of its severity
+	AgeDays int
+	SLADays int
+}
+
+// TeamSLABreaches are the SLA breaches of a team
+type TeamSLABreaches struct {
+	Area     string
+	Team     string
+	Breaches []SLABreach
+}
+
+// LoadVulnerabilityHistory reads the vulnerability history saved by a previous scan, an empty history when the file
+// does not exist yet
+func LoadVulnerabilityHistory(filename string) (*VulnerabilityHistory, error) {
+	history := &VulnerabilityHistory{FirstSeen: make(map[string]map[string]time.Time)}
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read vulnerability history file %s: %v", filename, err)
+	}
+	if err := json.Unmarshal(content, history); err != nil {
+		return nil, fmt.Errorf("error while decoding vulnerability history file %s: %v", filename, err)
+	}
+	if history.FirstSeen == nil {
+		history.FirstSeen = make(map[string]map[string]time.Time)
+	}
+	history.byRepository()
+	return history, nil
+}
+
+// byRepository merges the history recorded per image name by the previous versions into the history per image
+// repository, keeping the earliest first scan time of the vulnerabilities found in several tags
+func (h *VulnerabilityHistory) byRepository() {
+	for imageName, firstSeen := range h.FirstSeen {
+		repository := imageRepository(imageName)
+		if repository == imageName {
+			continue
+		}
+		delete(h.FirstSeen, imageName)
+		if h.FirstSeen[repository] == nil {
+			h.FirstSeen[repository] = make(map[string]time.Time)
+		}
+		for key, seen := range firstSeen {
+			if previous, ok := h.FirstSeen[repository][key]; !ok || seen.Before(previous) {
+				h.FirstSeen[repository][key] = seen
+			}
+		}
+		if severities, ok := h.Severities[imageName]; ok {
+			delete(h.Severities, imageName)
+			if h.Severities[repository] == nil {
+				h.Severities[repository] = make(map[string]string)
+			}
+			for key, severity := range severities {
+				h.Severities[repository][key] = severity
+			}
+		}
+		if teams, ok := h.ImageTeams[imageName]; ok {
+			delete(h.ImageTeams, imageName)
+			for _, team := range teams {
+				if !containsString(h.ImageTeams[repository], team) {
+					h.ImageTeams[repository] = append(h.ImageTeams[repository], team)
+				}
+			}
+			sort.Strings(h.ImageTeams[repository])
+		}
+	}
+}
+
+// Save writes the history to the file, replacing it once fully written so that an interrupted save keeps the previous history
+func (h *VulnerabilityHistory) Save(filename string) error {
+	content, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("could not create the directory of vulnerability history file %s: %v", filename, err)
+	}
+	if err := os.WriteFile(filename+".tmp", content, 0644); err != nil {
+		return fmt.Errorf("could not write vulnerability history file %s: %v", filename, err)
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// Track records the vulnerabilities of the report found for the first time at the scan time of the report, and sets
+// the FirstSeen time of the vulnerabilities of the report images. The vulnerabilities are tracked per image repository,
+// a vulnerability found in any image of a repository keeping its first scan time when the image is retagged. The
+// repositories of an image whose scan failed keep their history, and the repositories no longer in the report are only
+// forgotten when the report is complete, see ReportMetadata.Partial. The vulnerabilities forgotten are recorded as
+// fixed, see FixLatencies, and the severity scores of the teams are recorded too, see VulnerabilityReport.Scoreboard
+func (h *VulnerabilityHistory) Track(r *VulnerabilityReport) {
+	scanTime := r.Metadata.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now().UTC()
+	}
+	h.trackFixes(r, scanTime)
+	scanned := make(map[string]bool)
+	current := make(map[string]map[string]time.Time)
+	for _, image := range r.ScannedImages {
+		repository := imageRepository(image.ImageName)
+		scanned[repository] = true
+		if image.ScanError != nil || image.Skipped {
+			continue
+		}
+		previous := h.FirstSeen[repository]
+		if current[repository] == nil {
+			current[repository] = make(map[string]time.Time)
+		}
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				key := historyKey(vulnerability)
+				if firstSeen, ok := previous[key]; ok {
+					current[repository][key] = firstSeen
+				} else if _, ok := current[repository][key]; !ok {
+					current[repository][key] = scanTime
+				}
+			}
+		}
+	}
+	for _, image := range r.ScannedImages {
+		repository := imageRepository(image.ImageName)
+		if (image.ScanError == nil && !image.Skipped) || current[repository] == nil {
+			continue
+		}
+		// the vulnerabilities of the image whose scan failed are not known to be fixed
+		for key, firstSeen := range h.FirstSeen[repository] {
+			if _, ok := current[repository][key]; !ok {
+				current[repository][key] = firstSeen
+			}
+		}
+	}
+	for repository, firstSeen := range current {
+		h.FirstSeen[repository] = firstSeen
+	}
+	if !r.Metadata.Partial() {
+		for repository := range h.FirstSeen {
+			if !scanned[repository] {
+				delete(h.FirstSeen, repository)
+			}
+		}
+	}
+
+	h.trackSeverities(r)
+
+	h.annotate(r.ScannedImages)
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			h.annotate(team.Images)
+		}
+	}
+	h.trackTeamScores(r, scanTime)
+}
+
+// annotate sets the FirstSeen time of the vulnerabilities of the images
+func (h *VulnerabilityHistory) annotate(images []ScannedImage) {
+	for _, image := range images {
+		firstSeen := h.FirstSeen[imageRepository(image.ImageName)]
+		image.updateResults(func(results []TrivyOutputResults) {
+			for i := range results {
+				vulnerabilities := results[i].Vulnerabilities
+				for j := range vulnerabilities {
+					if seen, ok := firstSeen[historyKey(vulnerabilities[j])]; ok {
+						vulnerabilities[j].FirstSeen = &seen
+					}
+				}
+			}
+		})
+	}
+}
+
+func historyKey(v Vulnerabilities) string {
+	return v.VulnerabilityID + " " + v.PkgName
+}
+
+// ParseRemediationSLAs parses remediation SLAs such as 'CRITICAL=7,HIGH=30', in days per severity
+func ParseRemediationSLAs(value string) (RemediationSLAs, error) {
+	slas := make(RemediationSLAs)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		items := strings.SplitN(pair, "=", 2)
+		if len(items) != 2 {
+			return nil, fmt.Errorf("invalid remediation SLA %q, expected format 'SEVERITY=days'", pair)
+		}
+		severity := strings.ToUpper(strings.TrimSpace(items[0]))
+		if _, ok := severityScores[severity]; !ok {
+			return nil, fmt.Errorf("invalid remediation SLA %q, unknown severity %s", pair, severity)
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(items[1]))
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid remediation SLA %q, expected a number of days", pair)
+		}
+		slas[severity] = days
+	}
+	return slas, nil
+}
+
+// ApplyRemediationSLAs sets the SLA breaches of the teams of the report, the vulnerabilities found in the team images for
+// longer than the SLA of their severity at the scan time of the report. The age of a vulnerability is the one of its
+// image repository, so that retagging an image still holding the vulnerability does not restart its SLA. The
+// vulnerabilities without FirstSeen time, not tracked by a VulnerabilityHistory, never breach their SLA
+func (r *VulnerabilityReport) ApplyRemediationSLAs(slas RemediationSLAs) {
+	if len(slas) == 0 {
+		return
+	}
+	now := r.Metadata.ScanTime
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			team.SLABreaches = nil
+			findings := matchingFindings(team.Images, func(v Vulnerabilities) bool {
+				sla, ok := slas[v.Severity]
+				return ok && v.FirstSeen != nil && now.Sub(*v.FirstSeen) > time.Duration(sla)*24*time.Hour
+			})
+			for _, finding := range findings {
+				firstSeen := *finding.Vulnerability.FirstSeen
+				team.SLABreaches = append(team.SLABreaches, SLABreach{
+					VulnerabilityFinding: finding,
+					FirstSeen:            firstSeen,
+					AgeDays:              int(now.Sub(firstSeen).Hours() / 24),
+					SLADays:              slas[finding.Vulnerability.Severity],
+				})
+			}
+			sort.SliceStable(team.SLABreaches, func(i, j int) bool {
+				return team.SLABreaches[i].FirstSeen.Before(team.SLABreaches[j].FirstSeen)
+			})
+		}
+	}
+}
+
+// SLABreaches returns the SLA breaches of the teams having any, sorted by area and team
+func (r *VulnerabilityReport) SLABreaches() []TeamSLABreaches {
+	var breaches []TeamSLABreaches
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			if len(team.SLABreaches) > 0 {
+				breaches = append(breaches, TeamSLABreaches{Area: area.Name, Team: team.Name, Breaches: team.SLABreaches})
+			}
+		}
+	}
+	sort.SliceStable(breaches, func(i, j int) bool {
+		if breaches[i].Area != breaches[j].Area {
+			return breaches[i].Area < breaches[j].Area
+		}
+		return breaches[i].Team < breaches[j].Team
+	})
+	return breaches
+}
@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vulnerability history", func() {
+
+	var (
+		historyFile string
+		day1        = time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		tmpDir, err := os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmpDir)
+		historyFile = filepath.Join(tmpDir, "history", "history.json")
+	})
+
+	teamImage := func(name, team string, vulnerabilities ...Vulnerabilities) ScannedImage {
+		return ScannedImage{
+			ImageName:          name,
+			Containers:         []k8s.ContainerSummary{{Image: name, NamespaceLabels: map[string]string{"area": "finance", "team": team}}},
+			TrivyOutputResults: []TrivyOutputResults{{Target: "debian", Vulnerabilities: vulnerabilities}},
+		}
+	}
+
+	scanReport := func(scanTime time.Time, images ...ScannedImage) *VulnerabilityReport {
+		report, err := (&AreaReport{AreaLabelName: "area", TeamLabelName: "team"}).GenerateVulnerabilityReport(images)
+		Expect(err).NotTo(HaveOccurred())
+		report.Metadata.ScanTime = scanTime
+		return report
+	}
+
+	trackScan := func(report *VulnerabilityReport) {
+		history, err := LoadVulnerabilityHistory(historyFile)
+		Expect(err).NotTo(HaveOccurred())
+		history.Track(report)
+		Expect(history.Save(historyFile)).To(Succeed())
+	}
+
+	critical := Vulnerabilities{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL", FixedVersion: "3.0.1"}
+	high := Vulnerabilities{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"}
+
+	It("keeps the first scan time of the vulnerabilities across the scans", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical)))
+		report := scanReport(day1.Add(10*24*time.Hour), teamImage("api:1", "payments", critical, high))
+
+		trackScan(report)
+
+		Expect(*report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[0].FirstSeen).To(Equal(day1))
+		Expect(*report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[1].FirstSeen).To(Equal(day1.Add(10 * 24 * time.Hour)))
+		Expect(*report.AreaSummary["finance"].Teams["payments"].Images[0].TrivyOutputResults[0].Vulnerabilities[0].FirstSeen).To(Equal(day1))
+	})
+
+	It("forgets the fixed vulnerabilities and the removed images, but not the images whose scan failed", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical, high), teamImage("web:1", "orders", high), teamImage("db:1", "orders", high)))
+		failed := teamImage("db:1", "orders")
+		failed.ScanError = errors.New("timeout")
+		trackScan(scanReport(day1.Add(24*time.Hour), teamImage("api:1", "payments", high), failed))
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.FirstSeen).To(Equal(map[string]map[string]time.Time{
+			"api": {"CVE-2 curl": day1},
+			"db":  {"CVE-2 curl": day1},
+		}))
+	})
+
+	It("keeps the images missing from a report restricted to the changed images", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical), teamImage("web:1", "orders", high)))
+		report := scanReport(day1.Add(24*time.Hour), teamImage("api:1", "payments", critical))
+		since := day1
+		report.Metadata.ChangedSince = &since
+		trackScan(report)
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.FirstSeen).To(HaveKey("web"))
+		Expect(history.Fixes).To(BeEmpty())
+		Expect(history.TeamScores).To(HaveKeyWithValue("finance/orders", HaveLen(1)))
+	})
+
+	It("measures the time to fix the vulnerabilities per team and per severity", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical, high), teamImage("web:1", "orders", critical), teamImage("db:1", "orders", high)))
+		trackScan(scanReport(day1.Add(4*24*time.Hour), teamImage("api:1", "payments", high), teamImage("web:1", "orders", critical), teamImage("db:1", "orders", high)))
+		trackScan(scanReport(day1.Add(10*24*time.Hour), teamImage("api:2", "payments"), teamImage("db:1", "orders", high)))
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Fixes).To(HaveLen(3))
+
+		Expect(history.FixLatencies(day1)).To(Equal([]FixLatency{
+			{Area: "finance", Team: "orders", Severity: "CRITICAL", Fixed: 1, MeanDays: 10, MedianDays: 10},
+			{Area: "finance", Team: "payments", Severity: "CRITICAL", Fixed: 1, MeanDays: 4, MedianDays: 4},
+			{Area: "finance", Team: "payments", Severity: "HIGH", Fixed: 1, MeanDays: 10, MedianDays: 10},
+			{Severity: "CRITICAL", Fixed: 2, MeanDays: 7, MedianDays: 7},
+			{Severity: "HIGH", Fixed: 1, MeanDays: 10, MedianDays: 10},
+		}))
+		Expect(history.FixLatencies(day1.Add(5 * 24 * time.Hour))).To(HaveLen(4))
+	})
+
+	It("does not count the vulnerabilities still found once the image is retagged as fixed", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical, high)))
+		trackScan(scanReport(day1.Add(24*time.Hour), teamImage("api:2", "payments", critical), teamImage("api@sha256:abc", "payments", high)))
+		trackScan(scanReport(day1.Add(2*24*time.Hour), teamImage("api:3", "payments", high)))
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Fixes).To(Equal([]FixRecord{{Area: "finance", Team: "payments", ImageName: "api", VulnerabilityID: "CVE-1",
+			PkgName: "openssl", Severity: "CRITICAL", FirstSeen: day1, FixedAt: day1.Add(2 * 24 * time.Hour)}}))
+		Expect(history.FirstSeen).To(Equal(map[string]map[string]time.Time{"api": {"CVE-2 curl": day1}}))
+	})
+
+	It("merges the history recorded per image name into the history per repository", func() {
+		Expect(os.MkdirAll(filepath.Dir(historyFile), 0755)).To(Succeed())
+		Expect(os.WriteFile(historyFile, []byte(`{
+  "firstSeen": {"api:1": {"CVE-1 openssl": "2023-09-02T10:00:00Z"}, "api:2": {"CVE-1 openssl": "2023-09-01T10:00:00Z"}},
+  "severities": {"api:1": {"CVE-1 openssl": "CRITICAL"}},
+  "imageTeams": {"api:1": ["finance/payments"], "api:2": ["finance/orders"]}
+}`), 0644)).To(Succeed())
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.FirstSeen).To(Equal(map[string]map[string]time.Time{"api": {"CVE-1 openssl": day1}}))
+		Expect(history.Severities).To(Equal(map[string]map[string]string{"api": {"CVE-1 openssl": "CRITICAL"}}))
+		Expect(history.ImageTeams).To(Equal(map[string][]string{"api": {"finance/orders", "finance/payments"}}))
+	})
+
+	It("does not count the suppressed vulnerabilities as fixed", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical, high)))
+		suppressed := teamImage("api:1", "payments", high)
+		suppressed.SuppressedVulnerabilities = []SuppressedVulnerability{{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"}}
+		trackScan(scanReport(day1.Add(24*time.Hour), suppressed))
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Fixes).To(BeEmpty())
+	})
+
+	It("reports the vulnerabilities past the remediation SLA of their severity per team", func() {
+		trackScan(scanReport(day1, teamImage("api:1", "payments", critical, high), teamImage("web:1", "orders", high)))
+		report := scanReport(day1.Add(8*24*time.Hour), teamImage("api:1", "payments", critical, high), teamImage("web:1", "orders", high))
+		trackScan(report)
+		slas, err := ParseRemediationSLAs("critical=7, HIGH=30")
+		Expect(err).NotTo(HaveOccurred())
+
+		report.ApplyRemediationSLAs(slas)
+
+		breaches := report.SLABreaches()
+		Expect(breaches).To(HaveLen(1))
+		Expect(breaches[0].Area).To(Equal("finance"))
+		Expect(breaches[0].Team).To(Equal("payments"))
+		Expect(breaches[0].Breaches).To(HaveLen(1))
+		Expect(breaches[0].Breaches[0].ImageName).To(Equal("api:1"))
+		Expect(breaches[0].Breaches[0].Vulnerability.VulnerabilityID).To(Equal("CVE-1"))
+		Expect(breaches[0].Breaches[0].FirstSeen).To(Equal(day1))
+		Expect(breaches[0].Breaches[0].AgeDays).To(Equal(8))
+		Expect(breaches[0].Breaches[0].SLADays).To(Equal(7))
+		Expect(report.AreaSummary["finance"].Teams["orders"].SLABreaches).To(BeEmpty())
+	})
+
+	It("keeps the age of the vulnerabilities past their SLA once the image is retagged", func() {
+		trackScan(scanReport(day1, teamImage("registry.example.com/api:1", "payments", critical)))
+		report := scanReport(day1.Add(8*24*time.Hour), teamImage("registry.example.com/api:2", "payments", critical))
+		trackScan(report)
+
+		report.ApplyRemediationSLAs(RemediationSLAs{"CRITICAL": 7})
+
+		breaches := report.SLABreaches()
+		Expect(breaches).To(HaveLen(1))
+		Expect(breaches[0].Breaches).To(HaveLen(1))
+		Expect(breaches[0].Breaches[0].ImageName).To(Equal("registry.example.com/api:2"))
+		Expect(breaches[0].Breaches[0].FirstSeen).To(Equal(day1))
+		Expect(breaches[0].Breaches[0].AgeDays).To(Equal(8))
+	})
+
+	It("rejects the remediation SLAs of unknown severities", func() {
+		_, err := ParseRemediationSLAs("URGENT=1")
+		Expect(err).To(MatchError(ContainSubstring("unknown severity URGENT")))
+		_, err = ParseRemediationSLAs("HIGH=-1")
+		Expect(err).To(MatchError(ContainSubstring("expected a number of days")))
+	})
+})
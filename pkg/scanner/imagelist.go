@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// ReadImageList reads an image list file with one image reference per line, optionally followed by
+// space separated key=value annotations, for instance 'registry.com/api:1.0 area=payments team=a'.
+// The 'area' and 'team' annotations are stored under the area and team label names so that the images
+// are grouped as the cluster images, blank lines and lines starting with '#' are ignored
+func ReadImageList(filename, areaLabelName, teamLabelName string) ([]k8s.ContainerSummary, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not open image list %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	var containers []k8s.ContainerSummary
+	lineScanner := bufio.NewScanner(file)
+	for lineNumber := 1; lineScanner.Scan(); lineNumber++ {
+		fields := strings.Fields(lineScanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		labels := make(map[string]string)
+		for _, annotation := range fields[1:] {
+			key, value, found := strings.Cut(annotation, "=")
+			if !found || key == "" {
+				return nil, fmt.Errorf("invalid annotation %q in image list %s line %d, expected format is key=value", annotation, filename, lineNumber)
+			}
+			switch key {
+			case "area":
+				key = areaLabelName
+			case "team":
+				key = teamLabelName
+			}
+			labels[key] = value
+		}
+		containers = append(containers, k8s.ContainerSummary{
+			Image:           fields[0],
+			NamespaceLabels: labels,
+		})
+	}
+	if err := lineScanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read image list %s: %v", filename, err)
+	}
+	return containers, nil
+}
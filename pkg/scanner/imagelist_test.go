@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image list", func() {
+
+	var imageListFile string
+
+	writeImageList := func(content string) {
+		dir, err := os.MkdirTemp("", "image-list")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		imageListFile = filepath.Join(dir, "images.txt")
+		Expect(os.WriteFile(imageListFile, []byte(content), 0644)).To(Succeed())
+	}
+
+	It("reads one image per line with the area and team annotations", func() {
+		writeImageList("# images not deployed yet\nregistry.com/api:1.0 area=payments team=a\n\n  alpine:3.18  \nnginx:1.25 team=b owner=ops\n")
+
+		containers, err := ReadImageList(imageListFile, "area-label", "team-label")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containers).To(Equal([]k8s.ContainerSummary{
+			{Image: "registry.com/api:1.0", NamespaceLabels: map[string]string{"area-label": "payments", "team-label": "a"}},
+			{Image: "alpine:3.18", NamespaceLabels: map[string]string{}},
+			{Image: "nginx:1.25", NamespaceLabels: map[string]string{"team-label": "b", "owner": "ops"}},
+		}))
+	})
+
+	It("rejects invalid annotations", func() {
+		writeImageList("alpine:3.18\nnginx:1.25 team\n")
+
+		_, err := ReadImageList(imageListFile, "area", "team")
+
+		Expect(err).To(MatchError(ContainSubstring("invalid annotation \"team\" in image list " + imageListFile + " line 2")))
+	})
+
+	It("groups the scanned images by the annotations", func() {
+		writeImageList("registry.com/api:1.0 area=payments team=a\nalpine:3.18\n")
+		mockTrivyClient := &mockTrivy{}
+		mockDockerClient := &mockDocker{}
+		scan := &Scanner{
+			config:       &Config{Workers: 1},
+			trivyClient:  mockTrivyClient,
+			dockerClient: mockDockerClient,
+		}
+		mockTrivyClient.On("DownloadDatabase").Return(nil)
+		mockTrivyClient.On("Version").Return(&TrivyVersion{Version: "0.45.0"}, nil)
+		for _, image := range []string{"registry.com/api:1.0", "alpine:3.18"} {
+			mockDockerClient.On("ImageExists", image).Return(false, nil).On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+			mockTrivyClient.On("ScanImage", image).Return(&TrivyOutput{}, nil)
+		}
+
+		report, err := scan.ScanImageList(context.Background(), imageListFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages).To(HaveLen(2))
+		Expect(report.AreaSummary["payments"].Teams["a"].Images[0].ImageName).To(Equal("registry.com/api:1.0"))
+		Expect(report.AreaSummary["all"].Teams["all"].Images[0].ImageName).To(Equal("alpine:3.18"))
+	})
+
+	It("groups the registry images by registry and repository owner", func() {
+		mockTrivyClient := &mockTrivy{}
+		mockDockerClient := &mockDocker{}
+		scan := &Scanner{
+			config:       &Config{Workers: 1},
+			trivyClient:  mockTrivyClient,
+			dockerClient: mockDockerClient,
+		}
+		mockTrivyClient.On("DownloadDatabase").Return(nil)
+		mockTrivyClient.On("Version").Return(&TrivyVersion{Version: "0.45.0"}, nil)
+		images := []string{"registry.com:5000/payments/api:1.0", "registry.com:5000/nginx:1.25"}
+		for _, image := range images {
+			mockDockerClient.On("ImageExists", image).Return(false, nil).On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+			mockTrivyClient.On("ScanImage", image).Return(&TrivyOutput{}, nil)
+		}
+
+		report, err := scan.ScanRegistryImages(context.Background(), images)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages).To(HaveLen(2))
+		Expect(report.AreaSummary["registry.com:5000"].Teams["payments"].Images[0].ImageName).To(Equal("registry.com:5000/payments/api:1.0"))
+		Expect(report.AreaSummary["registry.com:5000"].Teams["nginx"].Images[0].ImageName).To(Equal("registry.com:5000/nginx:1.25"))
+	})
+})
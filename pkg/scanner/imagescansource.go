@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+// ImageScanSource provides the results of the images already scanned by another scanner, for instance Trivy Operator
+type ImageScanSource interface {
+	// ImageScan returns the trivy output of the image run by the containers, or an error when the image was not scanned
+	ImageScan(imageName string, containers []k8s.ContainerSummary) (*TrivyOutput, error)
+	// Version returns the version of trivy the images were scanned with, empty when unknown
+	Version() string
+}
+
+// ReportImageScans reports the images of the cluster with the results of the source rather than pulling and scanning
+// them. The results get the same license classification, severity overrides and enrichments as the scanned images
+func (s *Scanner) ReportImageScans(ctx context.Context, source ImageScanSource) (*VulnerabilityReport, error) {
+	logr.Infof("Reporting image scans from source")
+	_, span := s.config.Tracer.Start(ctx, "ReportImageScans")
+	defer span.Finish()
+	metadata := ReportMetadata{ScanTime: time.Now().UTC(), ClusterName: s.config.ClusterName, TrivyVersion: source.Version()}
+	kubernetesVersion, err := s.kubernetesClient.GetServerVersion()
+	if err != nil {
+		logr.Warnf("Unable to get the Kubernetes version for the report metadata: %v", err)
+	}
+	metadata.KubernetesVersion = kubernetesVersion
+
+	containers, err := s.kubernetesClient.GetContainersInNamespaces(s.config.FilterLabels)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	containers = s.selectChanged(containers, &metadata)
+	containers, optOuts := excludeOptedOut(containers)
+	var scannedImages []ScannedImage
+	for imageName, imageContainers := range s.groupContainersByImageName(containers) {
+		trivyOutput, err := source.ImageScan(imageName, imageContainers)
+		if err != nil {
+			logr.Warn(err)
+		}
+		if trivyOutput != nil {
+			s.enrich(trivyOutput)
+		}
+		scannedImage := newTrivyScannedImage(s.resolveImageName(imageName), imageContainers, trivyOutput, err)
+		scannedImages = append(scannedImages, scannedImage)
+		s.stream(scannedImage)
+	}
+	sort.SliceStable(scannedImages, func(i, j int) bool {
+		return scannedImages[i].ImageName < scannedImages[j].ImageName
+	})
+	s.config.Advisories.enrich(ctx, scannedImages)
+	report, err := s.generateReport(scannedImages, s.config.AreaLabels, s.config.TeamsLabels, metadata)
+	span.RecordError(err)
+	if err != nil {
+		return nil, err
+	}
+	report.ScanOptOuts = optOuts
+	return report, nil
+}
+
+// registryScan returns the results of the image group the registry already scanned, false when the image has to be
+// scanned with trivy. The results get the same enrichments, filtering them by severity as trivy does
+func (s *Scanner) registryScan(ctx context.Context, imageName string, imageNames []string, imageList map[string][]k8s.ContainerSummary) (*TrivyOutput, bool) {
+	if s.config.RegistryScans == nil {
+		return nil, false
+	}
+	var containers []k8s.ContainerSummary
+	for _, name := range imageNames {
+		containers = append(containers, imageList[name]...)
+	}
+	trivyOutput, err := s.config.RegistryScans.ImageScan(imageName, containers)
+	if err != nil {
+		logr.Debugf("%v, scanning image %s with trivy", err, imageName)
+		return nil, false
+	}
+	if trivyOutput.stale(s.config.MaxResultAge, time.Now()) {
+		logr.Infof("The registry scan of image %s is older than %s, scanning the image with trivy", imageName, s.config.MaxResultAge)
+		return nil, false
+	}
+	logr.Infof("Image %s already scanned by its registry, reusing the registry scan", imageName)
+	trivyOutput.Results = sortTrivyVulnerabilities(trivyOutput.Results)
+	s.enrich(trivyOutput)
+	return trivyOutput, true
+}
+
+// filterBySeverity removes the vulnerabilities whose severity is not one of the comma separated severities, for
+// instance CRITICAL,HIGH, so that the severities trivy is asked for also apply to the results of the other image scan
+// sources and to the overridden and normalised severities. No vulnerability is removed when the severities are empty
+func filterBySeverity(trivyOutput []TrivyOutputResults, severities string) {
+	if severities == "" {
+		return
+	}
+	kept := make(map[string]bool)
+	for _, severity := range strings.Split(severities, ",") {
+		kept[strings.TrimSpace(severity)] = true
+	}
+	for i := range trivyOutput {
+		var vulnerabilities []Vulnerabilities
+		for _, vulnerability := range trivyOutput[i].Vulnerabilities {
+			if kept[vulnerability.Severity] {
+				vulnerabilities = append(vulnerabilities, vulnerability)
+			}
+		}
+		trivyOutput[i].Vulnerabilities = vulnerabilities
+	}
+}
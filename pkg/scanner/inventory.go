@@ -0,0 +1,264 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+const (
+	// DeploymentCause and VulnerabilityDataCause explain the vulnerabilities added or removed between two reports, see
+	// ReportDiff.Explain. The former is a change of the images run, a new image or a new digest of an image, the latter
+	// a change of the vulnerability data of an unchanged image, for instance a newly disclosed vulnerability
+	DeploymentCause        = "deployment"
+	VulnerabilityDataCause = "vulnerability-data"
+)
+
+// Inventory is the machine-readable inventory of the cluster captured at scan time: its namespaces, its workloads and
+// the images and digests they run, so that the reports are auditable and their differences can be explained
+type Inventory struct {
+	Metadata   ReportMetadata
+	Namespaces []InventoryNamespace
+	Workloads  []InventoryWorkload
+	Images     []InventoryImage
+}
+
+// InventoryNamespace is a namespace running workloads
+type InventoryNamespace struct {
+	Name   string
+	Labels map[string]string `json:",omitempty"`
+}
+
+// InventoryWorkload is a workload and the images its containers run
+type InventoryWorkload struct {
+	Namespace string
+	// Workload is the kind and name of the workload, for instance deployment/web, or the pod name when unknown
+	Workload string
+	// Labels are the labels of the pods of the workload
+	Labels map[string]string `json:",omitempty"`
+	// SkipScanReason is the reason the workload opted out of the image scans, empty when its images are scanned
+	SkipScanReason string `json:",omitempty"`
+	// Containers are the containers of the workload, one per image and digest they run, several being run during rollouts
+	Containers []InventoryContainer
+}
+
+// InventoryContainer is a container of a workload and the image it runs
+type InventoryContainer struct {
+	Name  string `json:",omitempty"`
+	Image string
+	// Digest is the digest of the image run, empty when unknown
+	Digest string `json:",omitempty"`
+}
+
+// InventoryImage is an image run in the cluster
+type InventoryImage struct {
+	Name string
+	// Digests are the digests of the image run, several being run when the tag moved between the pod starts
+	Digests []string `json:",omitempty"`
+	// Workloads are the namespaces and workloads running the image, for instance payments/deployment/web
+	Workloads []string
+}
+
+// NewInventory returns the inventory of the namespaces, workloads and images of the report, the workloads opted out of
+// the scans included
+func NewInventory(report *VulnerabilityReport) *Inventory {
+	inventory := &Inventory{Metadata: report.Metadata}
+	namespaces := make(map[string]*InventoryNamespace)
+	workloads := make(map[string]*InventoryWorkload)
+	images := make(map[string]*InventoryImage)
+	add := func(namespace, workload string, container InventoryContainer) {
+		key := namespace + "/" + workload
+		if workloads[key] == nil {
+			workloads[key] = &InventoryWorkload{Namespace: namespace, Workload: workload}
+		}
+		if !containsInventoryContainer(workloads[key].Containers, container) {
+			workloads[key].Containers = append(workloads[key].Containers, container)
+		}
+		if images[container.Image] == nil {
+			images[container.Image] = &InventoryImage{Name: container.Image}
+		}
+		image := images[container.Image]
+		if container.Digest != "" && !containsString(image.Digests, container.Digest) {
+			image.Digests = append(image.Digests, container.Digest)
+		}
+		if !containsString(image.Workloads, key) {
+			image.Workloads = append(image.Workloads, key)
+		}
+	}
+
+	for _, image := range report.ScannedImages {
+		for _, container := range image.Containers {
+			if namespaces[container.Namespace] == nil {
+				namespaces[container.Namespace] = &InventoryNamespace{Name: container.Namespace, Labels: container.NamespaceLabels}
+			}
+			workload := container.Workload
+			if workload == "" {
+				workload = container.PodName
+			}
+			add(container.Namespace, workload, InventoryContainer{Name: container.ContainerName, Image: image.ImageName, Digest: imageDigest(image.ImageName, []k8s.ContainerSummary{container})})
+			if workloads[container.Namespace+"/"+workload].Labels == nil {
+				workloads[container.Namespace+"/"+workload].Labels = container.PodLabels
+			}
+		}
+	}
+	for _, optOut := range report.ScanOptOuts {
+		if namespaces[optOut.Namespace] == nil {
+			namespaces[optOut.Namespace] = &InventoryNamespace{Name: optOut.Namespace}
+		}
+		for _, image := range optOut.Images {
+			add(optOut.Namespace, optOut.Workload, InventoryContainer{Image: image})
+		}
+		workloads[optOut.Namespace+"/"+optOut.Workload].SkipScanReason = optOut.Reason
+	}
+
+	for _, namespace := range namespaces {
+		inventory.Namespaces = append(inventory.Namespaces, *namespace)
+	}
+	sort.Slice(inventory.Namespaces, func(i, j int) bool { return inventory.Namespaces[i].Name < inventory.Namespaces[j].Name })
+	for _, workload := range workloads {
+		sort.Slice(workload.Containers, func(i, j int) bool {
+			return workload.Containers[i].reference() < workload.Containers[j].reference()
+		})
+		inventory.Workloads = append(inventory.Workloads, *workload)
+	}
+	sort.Slice(inventory.Workloads, func(i, j int) bool {
+		if inventory.Workloads[i].Namespace != inventory.Workloads[j].Namespace {
+			return inventory.Workloads[i].Namespace < inventory.Workloads[j].Namespace
+		}
+		return inventory.Workloads[i].Workload < inventory.Workloads[j].Workload
+	})
+	for _, image := range images {
+		sort.Strings(image.Digests)
+		sort.Strings(image.Workloads)
+		inventory.Images = append(inventory.Images, *image)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool { return inventory.Images[i].Name < inventory.Images[j].Name })
+	return inventory
+}
+
+// LoadInventory reads an inventory previously saved with the inventory-output option
+func LoadInventory(filename string) (*Inventory, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read inventory file %s: %v", filename, err)
+	}
+	var inventory Inventory
+	if err := json.Unmarshal(content, &inventory); err != nil {
+		return nil, fmt.Errorf("error while decoding inventory file %s: %v", filename, err)
+	}
+	return &inventory, nil
+}
+
+// reference returns the image of the container with its digest, for instance web:1.2@sha256:4ff3..., and its name
+func (c InventoryContainer) reference() string {
+	reference := c.Image
+	if c.Digest != "" && !strings.Contains(reference, "@") {
+		reference += "@" + c.Digest
+	}
+	if c.Name != "" {
+		reference = c.Name + "=" + reference
+	}
+	return reference
+}
+
+func containsInventoryContainer(containers []InventoryContainer, container InventoryContainer) bool {
+	for _, c := range containers {
+		if c == container {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkloadChange is a change of the images run by a workload between two inventories
+type WorkloadChange struct {
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload"`
+	// Change is added or removed for the workloads of a single inventory, updated for the workloads running other images
+	Change string `json:"change"`
+	// OldImages and NewImages are the images the containers of the workload ran in each inventory, with their digest
+	// when known, for instance web=web:1.2@sha256:4ff3...
+	OldImages []string `json:"oldImages,omitempty"`
+	NewImages []string `json:"newImages,omitempty"`
+}
+
+// Explain explains the differences of the reports with the inventories captured with them: it lists the workloads
+// whose images changed and records the cause of each new and fixed vulnerability, DeploymentCause when its image is
+// new or runs another digest, VulnerabilityDataCause when the image runs the same digests in both inventories. The
+// cause is left empty when the digests of the image are unknown
+func (d *ReportDiff) Explain(oldInventory, newInventory *Inventory) {
+	d.WorkloadChanges = diffWorkloads(oldInventory, newInventory)
+	oldDigests, newDigests := oldInventory.imageDigests(), newInventory.imageDigests()
+	cause := func(image string) string {
+		old, ok := oldDigests[image]
+		switch {
+		case !ok:
+			return DeploymentCause
+		case len(old) == 0 || len(newDigests[image]) == 0:
+			return ""
+		case strings.Join(old, ",") != strings.Join(newDigests[image], ","):
+			return DeploymentCause
+		}
+		return VulnerabilityDataCause
+	}
+	for i := range d.NewVulnerabilities {
+		d.NewVulnerabilities[i].Cause = cause(d.NewVulnerabilities[i].ImageName)
+	}
+	for i := range d.FixedVulnerabilities {
+		d.FixedVulnerabilities[i].Cause = cause(d.FixedVulnerabilities[i].ImageName)
+	}
+}
+
+// imageDigests returns the sorted digests of the images of the inventory, by image name
+func (i *Inventory) imageDigests() map[string][]string {
+	digests := make(map[string][]string)
+	for _, image := range i.Images {
+		digests[image.Name] = image.Digests
+	}
+	return digests
+}
+
+// diffWorkloads returns the workloads added, removed or running other images between the inventories, sorted by
+// namespace and workload
+func diffWorkloads(oldInventory, newInventory *Inventory) []WorkloadChange {
+	images := func(inventory *Inventory) map[string][]string {
+		byWorkload := make(map[string][]string)
+		for _, workload := range inventory.Workloads {
+			var references []string
+			for _, container := range workload.Containers {
+				references = append(references, container.reference())
+			}
+			byWorkload[workload.Namespace+"/"+workload.Workload] = references
+		}
+		return byWorkload
+	}
+	oldImages, newImages := images(oldInventory), images(newInventory)
+	changes := []WorkloadChange{}
+	for key, newReferences := range newImages {
+		namespace, workload, _ := strings.Cut(key, "/")
+		oldReferences, ok := oldImages[key]
+		switch {
+		case !ok:
+			changes = append(changes, WorkloadChange{Namespace: namespace, Workload: workload, Change: "added", NewImages: newReferences})
+		case strings.Join(oldReferences, ",") != strings.Join(newReferences, ","):
+			changes = append(changes, WorkloadChange{Namespace: namespace, Workload: workload, Change: "updated", OldImages: oldReferences, NewImages: newReferences})
+		}
+	}
+	for key, oldReferences := range oldImages {
+		if _, ok := newImages[key]; !ok {
+			namespace, workload, _ := strings.Cut(key, "/")
+			changes = append(changes, WorkloadChange{Namespace: namespace, Workload: workload, Change: "removed", OldImages: oldReferences})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Namespace != changes[j].Namespace {
+			return changes[i].Namespace < changes[j].Namespace
+		}
+		return changes[i].Workload < changes[j].Workload
+	})
+	return changes
+}
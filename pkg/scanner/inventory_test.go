@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	r "github.com/coreeng/production-readiness/production-readiness/pkg/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inventory", func() {
+
+	container := func(namespace, workload, image, digest string) k8s.ContainerSummary {
+		return k8s.ContainerSummary{Image: image, ContainerName: "app", Namespace: namespace, Workload: workload, ImageDigest: digest,
+			PodName: strings.TrimPrefix(workload, "deployment/") + "-5d8f7", NamespaceLabels: map[string]string{"team": namespace}, PodLabels: map[string]string{"app": workload}}
+	}
+	image := func(name string, vulnerabilityIDs []string, containers ...k8s.ContainerSummary) ScannedImage {
+		var vulnerabilities []Vulnerabilities
+		for _, id := range vulnerabilityIDs {
+			vulnerabilities = append(vulnerabilities, Vulnerabilities{VulnerabilityID: id, PkgName: "openssl", Severity: "HIGH"})
+		}
+		return NewScannedImage(name, containers, []TrivyOutputResults{{Vulnerabilities: vulnerabilities}}, nil)
+	}
+
+	It("lists the namespaces, workloads and images of the report with their digests", func() {
+		report := &VulnerabilityReport{
+			ScannedImages: []ScannedImage{
+				image("api:1.0", nil, container("payments", "deployment/api", "api:1.0", "sha256:a1"), container("payments", "deployment/api", "api:1.0", "sha256:a2")),
+				image("nginx:1.25", nil, container("payments", "deployment/web", "nginx:1.25", ""), container("orders", "deployment/web", "nginx:1.25", "sha256:n1")),
+			},
+			ScanOptOuts: []ScanOptOut{{Namespace: "vendor", Workload: "statefulset/appliance", Reason: "scanned by the vendor", Images: []string{"vendor/appliance:7"}}},
+		}
+
+		inventory := NewInventory(report)
+
+		Expect(inventory.Namespaces).To(Equal([]InventoryNamespace{
+			{Name: "orders", Labels: map[string]string{"team": "orders"}},
+			{Name: "payments", Labels: map[string]string{"team": "payments"}},
+			{Name: "vendor"},
+		}))
+		Expect(inventory.Workloads).To(HaveLen(4))
+		Expect(inventory.Workloads[1]).To(Equal(InventoryWorkload{Namespace: "payments", Workload: "deployment/api", Labels: map[string]string{"app": "deployment/api"},
+			Containers: []InventoryContainer{{Name: "app", Image: "api:1.0", Digest: "sha256:a1"}, {Name: "app", Image: "api:1.0", Digest: "sha256:a2"}}}))
+		Expect(inventory.Workloads[3].SkipScanReason).To(Equal("scanned by the vendor"))
+		Expect(inventory.Images).To(Equal([]InventoryImage{
+			{Name: "api:1.0", Digests: []string{"sha256:a1", "sha256:a2"}, Workloads: []string{"payments/deployment/api"}},
+			{Name: "nginx:1.25", Digests: []string{"sha256:n1"}, Workloads: []string{"orders/deployment/web", "payments/deployment/web"}},
+			{Name: "vendor/appliance:7", Workloads: []string{"vendor/statefulset/appliance"}},
+		}))
+
+		filename := filepath.Join(GinkgoT().TempDir(), "inventory.json")
+		Expect(r.SaveReport(inventory, filename)).To(Succeed())
+		Expect(LoadInventory(filename)).To(Equal(inventory))
+	})
+
+	It("explains the differences of the reports by deployment or by vulnerability data update", func() {
+		oldReport := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			image("api:1.0", []string{"CVE-1"}, container("payments", "deployment/api", "api:1.0", "sha256:a1")),
+			image("web:2.0", []string{"CVE-2"}, container("payments", "deployment/web", "web:2.0", "sha256:w1")),
+			image("worker:3.0", []string{"CVE-3"}, container("payments", "deployment/worker", "worker:3.0", "sha256:k1")),
+		}}
+		newReport := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			image("api:1.1", []string{"CVE-4"}, container("payments", "deployment/api", "api:1.1", "sha256:a2")),
+			image("web:2.0", []string{"CVE-2", "CVE-5"}, container("payments", "deployment/web", "web:2.0", "sha256:w1")),
+			image("worker:3.0", nil, container("payments", "deployment/worker", "worker:3.0", "sha256:k2")),
+		}}
+		diff := DiffReports(oldReport, newReport)
+
+		diff.Explain(NewInventory(oldReport), NewInventory(newReport))
+
+		Expect(diff.NewVulnerabilities).To(HaveLen(2))
+		Expect(diff.NewVulnerabilities[0].ImageName).To(Equal("api:1.1"))
+		Expect(diff.NewVulnerabilities[0].Cause).To(Equal(DeploymentCause))
+		Expect(diff.NewVulnerabilities[1].ImageName).To(Equal("web:2.0"))
+		Expect(diff.NewVulnerabilities[1].Cause).To(Equal(VulnerabilityDataCause))
+		Expect(diff.FixedVulnerabilities).To(HaveLen(1))
+		Expect(diff.FixedVulnerabilities[0].Cause).To(Equal(DeploymentCause))
+		Expect(diff.WorkloadChanges).To(Equal([]WorkloadChange{
+			{Namespace: "payments", Workload: "deployment/api", Change: "updated", OldImages: []string{"app=api:1.0@sha256:a1"}, NewImages: []string{"app=api:1.1@sha256:a2"}},
+			{Namespace: "payments", Workload: "deployment/worker", Change: "updated", OldImages: []string{"app=worker:3.0@sha256:k1"}, NewImages: []string{"app=worker:3.0@sha256:k2"}},
+		}))
+
+		var out strings.Builder
+		Expect(diff.WriteText(&out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("  + HIGH CVE-5 openssl  in web:2.0 after a vulnerability data update\n"))
+		Expect(out.String()).To(ContainSubstring("Workload changes (2)\n  updated payments/deployment/api from app=api:1.0@sha256:a1 to app=api:1.1@sha256:a2\n"))
+	})
+})
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// DefaultKEVCatalogURL is the URL of the CISA Known Exploited Vulnerabilities catalog
+const DefaultKEVCatalogURL = "https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json"
+
+// KnownExploitedVulnerability is the object representation of a vulnerability of the CISA Known Exploited
+// Vulnerabilities catalog, a vulnerability exploited in the wild
+type KnownExploitedVulnerability struct {
+	CveID             string `json:"cveID"`
+	VendorProject     string `json:"vendorProject"`
+	Product           string `json:"product"`
+	VulnerabilityName string `json:"vulnerabilityName"`
+	DateAdded         string `json:"dateAdded"`
+	RequiredAction    string `json:"requiredAction"`
+	// DueDate is the remediation date required of the US federal agencies
+	DueDate string `json:"dueDate"`
+	// KnownRansomwareCampaignUse is Known when the vulnerability is used by ransomware campaigns, Unknown otherwise
+	KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+}
+
+// KEVCatalog is the CISA Known Exploited Vulnerabilities catalog the vulnerabilities are cross-referenced against
+type KEVCatalog struct {
+	Version         string
+	vulnerabilities map[string]KnownExploitedVulnerability
+}
+
+// LoadKEVCatalog downloads the CISA Known Exploited Vulnerabilities catalog, or reads it from the cache or from a local copy
+func LoadKEVCatalog(config *DatasetConfig) (*KEVCatalog, error) {
+	content, err := loadDataset(config, &http.Client{Timeout: time.Minute})
+	if err != nil {
+		return nil, fmt.Errorf("could not load the known exploited vulnerabilities catalog: %v", err)
+	}
+	return parseKEVCatalog(content)
+}
+
+func parseKEVCatalog(content []byte) (*KEVCatalog, error) {
+	var catalog struct {
+		CatalogVersion  string                        `json:"catalogVersion"`
+		Vulnerabilities []KnownExploitedVulnerability `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return nil, fmt.Errorf("error while decoding the known exploited vulnerabilities catalog: %v", err)
+	}
+	vulnerabilities := make(map[string]KnownExploitedVulnerability, len(catalog.Vulnerabilities))
+	for _, vulnerability := range catalog.Vulnerabilities {
+		vulnerabilities[vulnerability.CveID] = vulnerability
+	}
+	logr.Infof("Loaded %d known exploited vulnerabilities from catalog version %s", len(vulnerabilities), catalog.CatalogVersion)
+	return &KEVCatalog{Version: catalog.CatalogVersion, vulnerabilities: vulnerabilities}, nil
+}
+
+// mark sets the catalog entry of the known exploited vulnerabilities of the trivy results. A nil catalog marks none
+func (c *KEVCatalog) mark(trivyOutput []TrivyOutputResults) {
+	if c == nil {
+		return
+	}
+	for i := range trivyOutput {
+		for j := range trivyOutput[i].Vulnerabilities {
+			vulnerability := &trivyOutput[i].Vulnerabilities[j]
+			if knownExploited, ok := c.vulnerabilities[vulnerability.VulnerabilityID]; ok {
+				vulnerability.KnownExploited = &knownExploited
+			}
+		}
+	}
+}
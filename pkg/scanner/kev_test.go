@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Known exploited vulnerabilities", func() {
+
+	const catalog = `{"catalogVersion":"2023.09.05","vulnerabilities":[{"cveID":"CVE-2021-44228","vendorProject":"Apache","product":"Log4j2","vulnerabilityName":"Apache Log4j2 Remote Code Execution Vulnerability","dateAdded":"2021-12-10","requiredAction":"Apply updates per vendor instructions.","dueDate":"2021-12-24","knownRansomwareCampaignUse":"Known"}]}`
+
+	var (
+		tmpDir   string
+		server   *httptest.Server
+		requests int
+		config   *DatasetConfig
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			_, _ = w.Write([]byte(catalog))
+		}))
+		config = &DatasetConfig{Source: server.URL, CacheFile: filepath.Join(tmpDir, "cache", "kev.json"), MaxAge: time.Hour}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("marks the vulnerabilities of the catalog", func() {
+		kevCatalog, err := LoadKEVCatalog(config)
+		Expect(err).NotTo(HaveOccurred())
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2021-44228", PkgName: "log4j-core"},
+			{VulnerabilityID: "CVE-2021-3326", PkgName: "libc-bin"},
+		}}}
+
+		kevCatalog.mark(results)
+
+		Expect(kevCatalog.Version).To(Equal("2023.09.05"))
+		Expect(results[0].Vulnerabilities[0].KnownExploited).NotTo(BeNil())
+		Expect(results[0].Vulnerabilities[0].KnownExploited.KnownRansomwareCampaignUse).To(Equal("Known"))
+		Expect(results[0].Vulnerabilities[1].KnownExploited).To(BeNil())
+	})
+
+	It("reuses the cached catalog until it is older than the maximum age", func() {
+		_, err := LoadKEVCatalog(config)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = LoadKEVCatalog(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal(1))
+
+		yesterday := time.Now().Add(-24 * time.Hour)
+		Expect(os.Chtimes(config.CacheFile, yesterday, yesterday)).To(Succeed())
+		_, err = LoadKEVCatalog(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal(2))
+	})
+
+	It("uses the stale cached catalog when the download fails", func() {
+		_, err := LoadKEVCatalog(config)
+		Expect(err).NotTo(HaveOccurred())
+		yesterday := time.Now().Add(-24 * time.Hour)
+		Expect(os.Chtimes(config.CacheFile, yesterday, yesterday)).To(Succeed())
+		server.Close()
+
+		kevCatalog, err := LoadKEVCatalog(config)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kevCatalog.Version).To(Equal("2023.09.05"))
+	})
+
+	It("returns an error when the catalog cannot be downloaded nor read from the cache", func() {
+		server.Close()
+
+		_, err := LoadKEVCatalog(config)
+
+		Expect(err).To(MatchError(ContainSubstring("could not load the known exploited vulnerabilities catalog")))
+	})
+
+	It("reads a local copy of the catalog for offline scans", func() {
+		filename := filepath.Join(tmpDir, "known_exploited_vulnerabilities.json")
+		Expect(os.WriteFile(filename, []byte(catalog), 0644)).To(Succeed())
+
+		kevCatalog, err := LoadKEVCatalog(&DatasetConfig{Source: filename})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kevCatalog.Version).To(Equal("2023.09.05"))
+		Expect(requests).To(Equal(0))
+	})
+
+	It("lists the known exploited vulnerabilities of the report once per image and package", func() {
+		knownExploited := &KnownExploitedVulnerability{CveID: "CVE-2021-44228"}
+		image := NewScannedImage("app:1", nil, []TrivyOutputResults{
+			{Target: "app.jar", Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228", PkgName: "log4j-core", KnownExploited: knownExploited}}},
+			{Target: "lib.jar", Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2021-44228", PkgName: "log4j-core", KnownExploited: knownExploited}, {VulnerabilityID: "CVE-2021-3326"}}},
+		}, nil)
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{image}}
+
+		findings := report.KnownExploitedVulnerabilities()
+
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].ImageName).To(Equal("app:1"))
+		Expect(findings[0].Vulnerability.VulnerabilityID).To(Equal("CVE-2021-44228"))
+		Expect(image.VulnerabilitySummary.KnownExploitedCount).To(Equal(2))
+	})
+})
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"strings"
+)
+
+// Origins of the layers the vulnerabilities were found in
+const (
+	// LayerBaseImage is the origin of the vulnerabilities of the layers of the base image, fixed by a base image bump
+	LayerBaseImage = "base-image"
+	// LayerApplication is the origin of the vulnerabilities of the layers added on top of the base image, fixed in
+	// the Dockerfile or the dependencies of the application
+	LayerApplication = "application"
+)
+
+// ImageHistory is the object representation of an entry of the history of the image config, each instruction of
+// the Dockerfile adding an entry, an empty layer for the instructions not changing the filesystem such as CMD
+type ImageHistory struct {
+	CreatedBy  string `json:"created_by"`
+	EmptyLayer bool   `json:"empty_layer"`
+}
+
+// ImageRootFS is the object representation of the layers of the image config
+type ImageRootFS struct {
+	DiffIDs []string `json:"diff_ids"`
+}
+
+// baseImageIndex returns the index of the history entry ending the base image, -1 when the image has no base image.
+// As trivy does, the base image ends with the last CMD instruction followed by a layer, the final CMD of the image
+// itself being ignored
+func baseImageIndex(history []ImageHistory) int {
+	foundLayer := false
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if !foundLayer {
+			foundLayer = !entry.EmptyLayer
+			continue
+		}
+		if !entry.EmptyLayer {
+			continue
+		}
+		createdBy := strings.TrimSpace(entry.CreatedBy)
+		// the docker builder records /bin/sh -c #(nop)  CMD ["bash"], buildkit records CMD ["bash"]
+		if strings.HasPrefix(createdBy, "CMD") || strings.HasPrefix(strings.Join(strings.Fields(createdBy), " "), "/bin/sh -c #(nop) CMD") {
+			return i
+		}
+	}
+	return -1
+}
+
+// layerOrigins returns the origin of the layers of the image config by diff id, nil when the history of the image
+// is unknown or does not match its layers
+func (c *ImageConfig) layerOrigins() map[string]string {
+	if c == nil || len(c.History) == 0 {
+		return nil
+	}
+	var layerCount int
+	for _, entry := range c.History {
+		if !entry.EmptyLayer {
+			layerCount++
+		}
+	}
+	if layerCount != len(c.RootFS.DiffIDs) {
+		return nil
+	}
+
+	baseIndex := baseImageIndex(c.History)
+	origins := make(map[string]string)
+	layer := 0
+	for i, entry := range c.History {
+		if entry.EmptyLayer {
+			continue
+		}
+		origin := LayerApplication
+		if i < baseIndex {
+			origin = LayerBaseImage
+		}
+		origins[c.RootFS.DiffIDs[layer]] = origin
+		layer++
+	}
+	return origins
+}
+
+// attributeLayers records the origin of the layers the vulnerabilities of the results were found in, the
+// vulnerabilities of unknown layer being left unattributed
+func attributeLayers(results []TrivyOutputResults, config *ImageConfig) {
+	origins := config.layerOrigins()
+	if origins == nil {
+		return
+	}
+	for i := range results {
+		for j, vulnerability := range results[i].Vulnerabilities {
+			if vulnerability.Layer != nil {
+				results[i].Vulnerabilities[j].LayerOrigin = origins[vulnerability.Layer.DiffID]
+			}
+		}
+	}
+}
+
+// LayerAttributedImages returns the team images with vulnerabilities attributed to the base image or application
+// layers, so that the teams know whether to fix their Dockerfile or wait for a base image bump
+func (t *TeamSummary) LayerAttributedImages() []ScannedImage {
+	var images []ScannedImage
+	for _, i := range t.Images {
+		if i.VulnerabilitySummary.BaseImageLayerCount > 0 || i.VulnerabilitySummary.ApplicationLayerCount > 0 {
+			images = append(images, i)
+		}
+	}
+	return images
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Layer attribution", func() {
+
+	// trivyOutput is the output of an image built on debian, whose CMD ends the base image, with a buildkit RUN and COPY
+	const trivyOutput = `{
+		"Metadata": {
+			"ImageConfig": {
+				"architecture": "amd64",
+				"os": "linux",
+				"history": [
+					{"created_by": "/bin/sh -c #(nop) ADD file:6f3e0e8a9 in / "},
+					{"created_by": "/bin/sh -c #(nop)  CMD [\"bash\"]", "empty_layer": true},
+					{"created_by": "RUN /bin/sh -c apt-get install -y curl # buildkit"},
+					{"created_by": "COPY app.jar /app.jar # buildkit"},
+					{"created_by": "CMD [\"java\", \"-jar\", \"/app.jar\"]", "empty_layer": true}
+				],
+				"rootfs": {"type": "layers", "diff_ids": ["sha256:base", "sha256:curl", "sha256:app"]}
+			}
+		},
+		"Results": [
+			{"Target": "app (debian 12.1)", "Class": "os-pkgs", "Vulnerabilities": [
+				{"VulnerabilityID": "CVE-2023-4911", "Severity": "HIGH", "PkgName": "libc6", "Layer": {"DiffID": "sha256:base"}},
+				{"VulnerabilityID": "CVE-2023-38545", "Severity": "CRITICAL", "PkgName": "curl", "Layer": {"DiffID": "sha256:curl"}}
+			]},
+			{"Target": "app.jar", "Class": "lang-pkgs", "Vulnerabilities": [
+				{"VulnerabilityID": "CVE-2021-44228", "Severity": "CRITICAL", "PkgName": "log4j-core", "Layer": {"DiffID": "sha256:app"}},
+				{"VulnerabilityID": "CVE-2022-1471", "Severity": "HIGH", "PkgName": "snakeyaml"}
+			]}
+		]
+	}`
+
+	var output TrivyOutput
+
+	BeforeEach(func() {
+		output = TrivyOutput{}
+		Expect(json.Unmarshal([]byte(trivyOutput), &output)).To(Succeed())
+	})
+
+	origins := func(results []TrivyOutputResults) []string {
+		var origins []string
+		for _, result := range results {
+			for _, vulnerability := range result.Vulnerabilities {
+				origins = append(origins, vulnerability.LayerOrigin)
+			}
+		}
+		return origins
+	}
+
+	It("attributes the vulnerabilities to the base image or application layers", func() {
+		attributeLayers(output.Results, output.Metadata.ImageConfig)
+
+		Expect(origins(output.Results)).To(Equal([]string{LayerBaseImage, LayerApplication, LayerApplication, ""}))
+		summary := NewScannedImage("app:1", nil, output.Results, nil).VulnerabilitySummary
+		Expect(summary.BaseImageLayerCount).To(Equal(1))
+		Expect(summary.ApplicationLayerCount).To(Equal(2))
+	})
+
+	It("attributes every layer to the application when the image has no base image", func() {
+		output.Metadata.ImageConfig.History = []ImageHistory{
+			{CreatedBy: "COPY libc /lib # buildkit"}, {CreatedBy: "COPY curl /bin # buildkit"}, {CreatedBy: "COPY app.jar /app.jar # buildkit"},
+		}
+
+		attributeLayers(output.Results, output.Metadata.ImageConfig)
+
+		Expect(origins(output.Results)).To(Equal([]string{LayerApplication, LayerApplication, LayerApplication, ""}))
+	})
+
+	It("leaves the vulnerabilities unattributed when the history of the image is unknown or does not match its layers", func() {
+		output.Metadata.ImageConfig.RootFS.DiffIDs = []string{"sha256:base", "sha256:app"}
+		attributeLayers(output.Results, output.Metadata.ImageConfig)
+		Expect(origins(output.Results)).To(Equal([]string{"", "", "", ""}))
+
+		output.Metadata.ImageConfig.History = nil
+		attributeLayers(output.Results, output.Metadata.ImageConfig)
+		Expect(origins(output.Results)).To(Equal([]string{"", "", "", ""}))
+
+		attributeLayers(output.Results, nil)
+		Expect(origins(output.Results)).To(Equal([]string{"", "", "", ""}))
+	})
+
+	It("lists the team images with attributed vulnerabilities", func() {
+		attributeLayers(output.Results, output.Metadata.ImageConfig)
+		attributed := NewScannedImage("app:1", nil, output.Results, nil)
+		unattributed := NewScannedImage("web:1", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2023-4911"}}}}, nil)
+
+		team := &TeamSummary{Images: []ScannedImage{unattributed, attributed}}
+
+		Expect(team.LayerAttributedImages()).To(Equal([]ScannedImage{attributed}))
+	})
+})
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"path"
+	"strings"
+)
+
+// License is the object representation of a package license found by the trivy license scanner.
+// Violation explains why the license breaks the license policy, it is empty when the license complies with the policy
+type License struct {
+	Severity   string
+	Category   string
+	PkgName    string
+	FilePath   string
+	Name       string
+	Confidence float64
+	Link       string
+	Violation  string
+}
+
+// LicensePolicy classifies the licenses of the image packages. The names are matched case-insensitively and may hold
+// wildcards, for instance AGPL-* matches all the AGPL versions. Without allowed nor denied licenses, the licenses
+// trivy classifies as forbidden are violations
+type LicensePolicy struct {
+	// Allowed lists the allowed licenses, any other license being a violation. All licenses are allowed when empty
+	Allowed []string
+	// Denied lists the forbidden licenses, for instance AGPL-3.0
+	Denied []string
+}
+
+// forbiddenCategory is the trivy category of the licenses such as AGPL which are usually not allowed in products
+const forbiddenCategory = "forbidden"
+
+// apply sets the violation of the licenses of the trivy results. A nil policy has neither allowed nor denied licenses
+func (p *LicensePolicy) apply(trivyOutput []TrivyOutputResults) {
+	for i := range trivyOutput {
+		for j := range trivyOutput[i].Licenses {
+			license := &trivyOutput[i].Licenses[j]
+			license.Violation = p.violation(*license)
+		}
+	}
+}
+
+func (p *LicensePolicy) violation(license License) string {
+	var policy LicensePolicy
+	if p != nil {
+		policy = *p
+	}
+	switch {
+	case matchLicense(policy.Denied, license.Name):
+		return "denied by the license policy"
+	case len(policy.Allowed) > 0 && !matchLicense(policy.Allowed, license.Name):
+		return "not in the allowed licenses"
+	case len(policy.Allowed) == 0 && len(policy.Denied) == 0 && license.Category == forbiddenCategory:
+		return "classified as forbidden by trivy"
+	}
+	return ""
+}
+
+func matchLicense(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name)); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
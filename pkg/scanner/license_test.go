@@ -0,0 +1,24 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("License policy", func() {
+
+	DescribeTable("classifies the package licenses",
+		func(policy *LicensePolicy, license License, violation string) {
+			Expect(policy.violation(license)).To(Equal(violation))
+		},
+		Entry("denied license", &LicensePolicy{Denied: []string{"AGPL-3.0"}}, License{Name: "AGPL-3.0"}, "denied by the license policy"),
+		Entry("denied license family", &LicensePolicy{Denied: []string{"AGPL-*"}}, License{Name: "agpl-3.0-only"}, "denied by the license policy"),
+		Entry("license neither allowed nor denied", &LicensePolicy{Denied: []string{"AGPL-*"}}, License{Name: "GPL-2.0", Category: "forbidden"}, ""),
+		Entry("allowed license", &LicensePolicy{Allowed: []string{"MIT", "BSD-*"}}, License{Name: "BSD-3-Clause"}, ""),
+		Entry("license not allowed", &LicensePolicy{Allowed: []string{"MIT", "BSD-*"}}, License{Name: "GPL-2.0"}, "not in the allowed licenses"),
+		Entry("allowed but denied license", &LicensePolicy{Allowed: []string{"*"}, Denied: []string{"SSPL-1.0"}}, License{Name: "SSPL-1.0"}, "denied by the license policy"),
+		Entry("forbidden license without policy", &LicensePolicy{}, License{Name: "AGPL-3.0", Category: "forbidden"}, "classified as forbidden by trivy"),
+		Entry("forbidden license with nil policy", nil, License{Name: "AGPL-3.0", Category: "forbidden"}, "classified as forbidden by trivy"),
+		Entry("restricted license without policy", nil, License{Name: "GPL-2.0", Category: "restricted"}, ""),
+	)
+})
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// nodePressureCheckInterval is how often the pressure of the node hosting the scanner is checked, and how long the
+// pulls wait before checking again while the node is under pressure
+var nodePressureCheckInterval = 30 * time.Second
+
+// pressureConditions are the node conditions pausing the image pulls, as the pulled images fill the disk of the node
+// and the scans use its memory, the kubelet evicting the pods of the node under such pressure
+var pressureConditions = []v1.NodeConditionType{v1.NodeDiskPressure, v1.NodeMemoryPressure}
+
+// nodePressureMonitor pauses the image pulls while the node hosting the scanner is under pressure, the pressure of the
+// node being checked at most once per nodePressureCheckInterval whatever the number of workers
+type nodePressureMonitor struct {
+	kubernetesClient k8s.KubernetesClient
+	nodeName         string
+	lock             sync.Mutex
+	checkedAt        time.Time
+	pressure         []string
+}
+
+// newNodePressureMonitor creates a monitor of the pressure of the node, nil when the node or the cluster is unknown
+func newNodePressureMonitor(kubernetesClient k8s.KubernetesClient, nodeName string) *nodePressureMonitor {
+	if kubernetesClient == nil || nodeName == "" {
+		return nil
+	}
+	return &nodePressureMonitor{kubernetesClient: kubernetesClient, nodeName: nodeName}
+}
+
+// wait blocks while the node is under pressure, until it recovers or the context is done
+func (m *nodePressureMonitor) wait(ctx context.Context, imageName string) error {
+	if m == nil {
+		return nil
+	}
+	for len(m.nodePressure()) > 0 {
+		logr.Debugf("Waiting for node %s to recover before pulling image %s", m.nodeName, imageName)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(nodePressureCheckInterval):
+		}
+	}
+	return nil
+}
+
+// nodePressure returns the pressure conditions of the node, for instance DiskPressure, checking them again once
+// nodePressureCheckInterval elapsed. The node is not considered under pressure when it cannot be read, so that the
+// scan carries on
+func (m *nodePressureMonitor) nodePressure() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.checkedAt.IsZero() && time.Since(m.checkedAt) < nodePressureCheckInterval {
+		return m.pressure
+	}
+	m.checkedAt = time.Now()
+
+	previous := m.pressure
+	node, err := m.kubernetesClient.GetNode(m.nodeName)
+	if err != nil {
+		logr.Warnf("Unable to check the pressure of node %s, carrying on with the scan: %v", m.nodeName, err)
+		m.pressure = nil
+	} else {
+		m.pressure = nodePressure(*node)
+	}
+	switch {
+	case len(previous) == 0 && len(m.pressure) > 0:
+		logr.Warnf("Node %s is under %s, pausing the image pulls until it recovers", m.nodeName, strings.Join(m.pressure, " and "))
+	case len(previous) > 0 && len(m.pressure) == 0:
+		logr.Infof("Node %s recovered, resuming the image pulls", m.nodeName)
+	}
+	return m.pressure
+}
+
+// nodePressure returns the pressure conditions of the node that are true
+func nodePressure(node v1.Node) []string {
+	var pressure []string
+	for _, condition := range node.Status.Conditions {
+		for _, pressureCondition := range pressureConditions {
+			if condition.Type == pressureCondition && condition.Status == v1.ConditionTrue {
+				pressure = append(pressure, string(condition.Type))
+			}
+		}
+	}
+	return pressure
+}
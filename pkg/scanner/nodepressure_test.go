@@ -0,0 +1,59 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	v1 "k8s.io/api/core/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Node pressure", func() {
+
+	node := func(conditions ...v1.NodeCondition) *v1.Node {
+		return &v1.Node{Status: v1.NodeStatus{Conditions: conditions}}
+	}
+	diskPressure := v1.NodeCondition{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue}
+	noMemoryPressure := v1.NodeCondition{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse}
+
+	var mockKubernetesClient *k8stest.KubernetesClient
+
+	BeforeEach(func() {
+		previousInterval := nodePressureCheckInterval
+		nodePressureCheckInterval = 10 * time.Millisecond
+		DeferCleanup(func() { nodePressureCheckInterval = previousInterval })
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+	})
+
+	It("pauses the pulls while the node is under pressure and resumes once it recovers", func() {
+		mockKubernetesClient.On("GetNode", "node-1").Return(node(diskPressure, noMemoryPressure), nil).Twice()
+		mockKubernetesClient.On("GetNode", "node-1").Return(node(noMemoryPressure), nil)
+		monitor := newNodePressureMonitor(mockKubernetesClient, "node-1")
+
+		Expect(monitor.nodePressure()).To(Equal([]string{"DiskPressure"}))
+		Expect(monitor.wait(context.Background(), "alpine:3.18")).To(Succeed())
+
+		Expect(monitor.nodePressure()).To(BeEmpty())
+		mockKubernetesClient.AssertNumberOfCalls(GinkgoT(), "GetNode", 3)
+	})
+
+	It("stops waiting when the context is done", func() {
+		mockKubernetesClient.On("GetNode", "node-1").Return(node(diskPressure), nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		Expect(newNodePressureMonitor(mockKubernetesClient, "node-1").wait(ctx, "alpine:3.18")).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("carries on with the scan when the node cannot be read or is unknown", func() {
+		mockKubernetesClient.On("GetNode", "node-1").Return((*v1.Node)(nil), errors.New("forbidden"))
+
+		Expect(newNodePressureMonitor(mockKubernetesClient, "node-1").wait(context.Background(), "alpine:3.18")).To(Succeed())
+		Expect(newNodePressureMonitor(mockKubernetesClient, "")).To(BeNil())
+		Expect(newNodePressureMonitor(nil, "node-1").wait(context.Background(), "alpine:3.18")).To(Succeed())
+	})
+})
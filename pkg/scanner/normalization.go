@@ -0,0 +1,105 @@
+package scanner
+
+// Sources of the normalised severity of the vulnerabilities
+const (
+	// NormalizedFromOverride is the source of the severities overridden by Config.SeverityOverrides
+	NormalizedFromOverride = "override"
+	// NormalizedFromCVSS is the source of the severities derived from the CVSS score, see Vulnerabilities.CVSSScore
+	NormalizedFromCVSS = "cvss"
+	// NormalizedFromVendor is the source of the severities derived from the severity the vendor of the severity source
+	// assigned, for the vulnerabilities without CVSS score
+	NormalizedFromVendor = "vendor"
+	// NormalizedFromTrivy is the source of the severities derived from the trivy severity alone
+	NormalizedFromTrivy = "trivy"
+	// NormalizedFromKEV is the source of the severities raised to CRITICAL as the vulnerability is exploited in the wild
+	NormalizedFromKEV = "kev"
+)
+
+// NormalizedSeverity is the severity of a vulnerability on the single internal scale the severities of the sources,
+// trivy severity, CVSS scores, vendor severities and KEV catalog, are mapped to, so that the vulnerabilities rank and
+// count consistently whatever the scanner or image scan source that found them and the enrichments applied
+type NormalizedSeverity struct {
+	// Score is between 0 and 10 as the CVSS scores, the severities without score being mapped to the lowest score of
+	// their CVSS v3 qualitative rating
+	Score float64
+	// Severity is the CVSS v3 qualitative rating of the score: CRITICAL from 9.0, HIGH from 7.0, MEDIUM from 4.0, LOW
+	// above 0 and UNKNOWN otherwise
+	Severity string
+	// Source is the source the score is derived from, for instance NormalizedFromCVSS
+	Source string
+	// SourceSeverity is the severity of the vulnerability before normalisation, the trivy or the overridden severity,
+	// the severity of the vulnerability being replaced by the normalised one
+	SourceSeverity string `json:",omitempty"`
+}
+
+// severityLevelScores are the lowest scores of the CVSS v3 qualitative ratings
+var severityLevelScores = map[string]float64{"CRITICAL": 9.0, "HIGH": 7.0, "MEDIUM": 4.0, "LOW": 0.1, "UNKNOWN": 0}
+
+// vendorSeverityLevels are the severities of the trivy vendor severity values
+var vendorSeverityLevels = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// severityOfScore returns the CVSS v3 qualitative rating of the score
+func severityOfScore(score float64) string {
+	switch {
+	case score >= 9.0:
+		return "CRITICAL"
+	case score >= 7.0:
+		return "HIGH"
+	case score >= 4.0:
+		return "MEDIUM"
+	case score > 0:
+		return "LOW"
+	}
+	return "UNKNOWN"
+}
+
+// NormalizedScore returns the score of the normalised severity of the vulnerability, or the lowest score of the rating
+// of its severity when it is not normalised
+func (v Vulnerabilities) NormalizedScore() float64 {
+	if v.NormalizedSeverity != nil {
+		return v.NormalizedSeverity.Score
+	}
+	return severityLevelScores[v.Severity]
+}
+
+// normalize returns the normalised severity of the vulnerability. The overridden severities reflect an internal risk
+// assessment so that they prevail, then the CVSS score, the vendor severity of the severity source and the trivy
+// severity. The known exploited vulnerabilities are raised to CRITICAL
+func (v Vulnerabilities) normalize() *NormalizedSeverity {
+	var score float64
+	var source string
+	vendorSeverity, vendorRated := v.VendorSeverity[v.SeveritySource]
+	switch {
+	case v.OverriddenSeverity != nil:
+		score, source = severityLevelScores[v.Severity], NormalizedFromOverride
+	case v.CVSSScore() > 0:
+		score, source = v.CVSSScore(), NormalizedFromCVSS
+	case vendorRated && vendorSeverity > 0 && vendorSeverity < len(vendorSeverityLevels):
+		score, source = severityLevelScores[vendorSeverityLevels[vendorSeverity]], NormalizedFromVendor
+	default:
+		score, source = severityLevelScores[v.Severity], NormalizedFromTrivy
+	}
+	if v.KnownExploited != nil && v.OverriddenSeverity == nil && score < severityLevelScores["CRITICAL"] {
+		score, source = severityLevelScores["CRITICAL"], NormalizedFromKEV
+	}
+	return &NormalizedSeverity{Score: score, Severity: severityOfScore(score), Source: source}
+}
+
+// normalizeSeverities records the normalised severity of the vulnerabilities of the trivy results and replaces their
+// severity with the normalised one, so that the severity filter, the summaries, the budgets and the failure thresholds
+// all count the vulnerabilities by their normalised severity. The vulnerabilities already normalised are normalised
+// again from their source severity
+func normalizeSeverities(trivyOutput []TrivyOutputResults) {
+	for i := range trivyOutput {
+		for j := range trivyOutput[i].Vulnerabilities {
+			vulnerability := &trivyOutput[i].Vulnerabilities[j]
+			if vulnerability.NormalizedSeverity != nil && vulnerability.NormalizedSeverity.SourceSeverity != "" {
+				vulnerability.Severity = vulnerability.NormalizedSeverity.SourceSeverity
+			}
+			normalized := vulnerability.normalize()
+			normalized.SourceSeverity = vulnerability.Severity
+			vulnerability.NormalizedSeverity = normalized
+			vulnerability.Severity = normalized.Severity
+		}
+	}
+}
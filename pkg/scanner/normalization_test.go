@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Severity normalisation", func() {
+
+	It("normalises the severities of the sources to a single scale", func() {
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-override", Severity: "LOW", SeveritySource: "debian", OverriddenSeverity: &OverriddenSeverity{Severity: "HIGH"},
+				CVSS: map[string]CVSS{"nvd": {V3Score: 8.1}}, KnownExploited: &KnownExploitedVulnerability{}},
+			{VulnerabilityID: "CVE-cvss", Severity: "MEDIUM", SeveritySource: "debian", CVSS: map[string]CVSS{"nvd": {V3Score: 7.5}}},
+			{VulnerabilityID: "CVE-vendor", Severity: "MEDIUM", SeveritySource: "redhat", VendorSeverity: map[string]int{"redhat": 3}},
+			{VulnerabilityID: "CVE-trivy", Severity: "LOW"},
+			{VulnerabilityID: "CVE-kev", Severity: "MEDIUM", CVSS: map[string]CVSS{"nvd": {V3Score: 5.3}}, KnownExploited: &KnownExploitedVulnerability{}},
+			{VulnerabilityID: "CVE-unknown", Severity: "UNKNOWN"},
+		}}}
+
+		normalizeSeverities(results)
+
+		var normalized []NormalizedSeverity
+		var severities []string
+		for _, vulnerability := range results[0].Vulnerabilities {
+			normalized = append(normalized, *vulnerability.NormalizedSeverity)
+			severities = append(severities, vulnerability.Severity)
+		}
+		Expect(normalized).To(Equal([]NormalizedSeverity{
+			{Score: 0.1, Severity: "LOW", Source: NormalizedFromOverride, SourceSeverity: "LOW"},
+			{Score: 7.5, Severity: "HIGH", Source: NormalizedFromCVSS, SourceSeverity: "MEDIUM"},
+			{Score: 7.0, Severity: "HIGH", Source: NormalizedFromVendor, SourceSeverity: "MEDIUM"},
+			{Score: 0.1, Severity: "LOW", Source: NormalizedFromTrivy, SourceSeverity: "LOW"},
+			{Score: 9.0, Severity: "CRITICAL", Source: NormalizedFromKEV, SourceSeverity: "MEDIUM"},
+			{Score: 0, Severity: "UNKNOWN", Source: NormalizedFromTrivy, SourceSeverity: "UNKNOWN"},
+		}))
+		Expect(severities).To(Equal([]string{"LOW", "HIGH", "HIGH", "LOW", "CRITICAL", "UNKNOWN"}))
+
+		normalizeSeverities(results)
+
+		Expect(results[0].Vulnerabilities[4].NormalizedSeverity).To(Equal(&NormalizedSeverity{Score: 9.0, Severity: "CRITICAL", Source: NormalizedFromKEV, SourceSeverity: "MEDIUM"}))
+	})
+
+	It("filters and counts the vulnerabilities by their normalised severity", func() {
+		scanner := &Scanner{config: &Config{Severity: "CRITICAL,HIGH"}}
+		trivyOutput := &TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-raised", Severity: "LOW", SeveritySource: "debian", CVSS: map[string]CVSS{"nvd": {V3Score: 9.8}}},
+			{VulnerabilityID: "CVE-lowered", Severity: "HIGH", SeveritySource: "debian", CVSS: map[string]CVSS{"nvd": {V3Score: 5.3}}},
+		}}}}
+
+		scanner.enrich(trivyOutput)
+
+		Expect(trivyOutput.Results[0].Vulnerabilities).To(HaveLen(1))
+		Expect(trivyOutput.Results[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-raised"))
+		image := ScannedImage{ImageName: "api:1", TrivyOutputResults: trivyOutput.Results}
+		summary := image.buildVulnerabilitySummary()
+		Expect(summary.TotalVulnerabilityBySeverity).To(HaveKeyWithValue("CRITICAL", 1))
+		Expect(summary.TotalVulnerabilityBySeverity).To(HaveKeyWithValue("LOW", 0))
+		finding, failed := (&FailurePolicy{MinSeverity: "CRITICAL"}).failure(image, time.Now())
+		Expect(failed).To(BeTrue())
+		Expect(finding).To(Equal("CRITICAL CVE-raised () in api:1"))
+	})
+
+	It("sorts the vulnerabilities by normalised severity and decreasing normalised score", func() {
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-1", Severity: "MEDIUM", CVSS: map[string]CVSS{"nvd": {V3Score: 4.3}}},
+			{VulnerabilityID: "CVE-2", Severity: "HIGH"},
+			{VulnerabilityID: "CVE-3", Severity: "MEDIUM", KnownExploited: &KnownExploitedVulnerability{}},
+			{VulnerabilityID: "CVE-4", Severity: "MEDIUM", CVSS: map[string]CVSS{"nvd": {V3Score: 6.5}}},
+		}}}
+
+		normalizeSeverities(results)
+		sortTrivyVulnerabilities(results)
+
+		var ids []string
+		for _, vulnerability := range results[0].Vulnerabilities {
+			ids = append(ids, vulnerability.VulnerabilityID)
+		}
+		Expect(ids).To(Equal([]string{"CVE-3", "CVE-2", "CVE-4", "CVE-1"}))
+	})
+})
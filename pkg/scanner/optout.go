@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"sort"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+// ScanOptOut is a workload whose pods, or whose namespace, opted out of the image scans with the k8s.SkipScanAnnotation
+type ScanOptOut struct {
+	Namespace string
+	// Workload is the kind and name of the workload, for instance deployment/web, or the pod name when unknown
+	Workload string
+	Reason   string
+	// Images are the images of the workload that were not scanned for it, sorted by name
+	Images []string
+}
+
+// excludeOptedOut returns the containers to scan, without the containers opted out of the scans, and the opted-out
+// workloads sorted by namespace and workload. An image run by other containers is still scanned for them
+func excludeOptedOut(containers []k8s.ContainerSummary) ([]k8s.ContainerSummary, []ScanOptOut) {
+	type optOutKey struct {
+		namespace, workload, reason string
+	}
+	var scanned []k8s.ContainerSummary
+	optOuts := make(map[optOutKey]map[string]bool)
+	for _, container := range containers {
+		if container.SkipScanReason == "" {
+			scanned = append(scanned, container)
+			continue
+		}
+		workload := container.Workload
+		if workload == "" {
+			workload = container.PodName
+		}
+		key := optOutKey{container.Namespace, workload, container.SkipScanReason}
+		if optOuts[key] == nil {
+			optOuts[key] = make(map[string]bool)
+		}
+		optOuts[key][container.Image] = true
+	}
+
+	var result []ScanOptOut
+	for key, images := range optOuts {
+		optOut := ScanOptOut{Namespace: key.namespace, Workload: key.workload, Reason: key.reason}
+		for image := range images {
+			optOut.Images = append(optOut.Images, image)
+		}
+		sort.Strings(optOut.Images)
+		logr.Infof("Not scanning the images of %s in namespace %s, opted out of the scans: %s", optOut.Workload, optOut.Namespace, optOut.Reason)
+		result = append(result, optOut)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		if result[i].Workload != result[j].Workload {
+			return result[i].Workload < result[j].Workload
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return scanned, result
+}
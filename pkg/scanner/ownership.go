@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// OwnershipRule attributes the containers of a namespace, the containers running images starting with a prefix,
+// or the containers of a namespace running images starting with a prefix only, to an area and team
+type OwnershipRule struct {
+	Namespace   string `json:"namespace"`
+	ImagePrefix string `json:"imagePrefix"`
+	Area        string `json:"area"`
+	Team        string `json:"team"`
+}
+
+// OwnershipMapping attributes the containers whose area or team labels are missing, the first matching rule applying
+type OwnershipMapping struct {
+	Rules []OwnershipRule `json:"owners"`
+}
+
+// LoadOwnershipMapping reads the ownership mapping from a yaml or json file such as:
+//
+//	owners:
+//	- namespace: payments
+//	  area: finance
+//	  team: payments
+//	- imagePrefix: registry.example.com/platform/
+//	  area: platform
+//	  team: sre
+func LoadOwnershipMapping(filename string) (*OwnershipMapping, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ownership mapping file %s: %v", filename, err)
+	}
+	var mapping OwnershipMapping
+	if err := yaml.Unmarshal(content, &mapping); err != nil {
+		return nil, fmt.Errorf("error while decoding ownership mapping file %s: %v", filename, err)
+	}
+	for n, rule := range mapping.Rules {
+		if rule.Namespace == "" && rule.ImagePrefix == "" {
+			return nil, fmt.Errorf("ownership rule %d of %s matches no container, a namespace or an image prefix is required", n+1, filename)
+		}
+		if rule.Area == "" && rule.Team == "" {
+			return nil, fmt.Errorf("ownership rule %d of %s has no owner, an area or a team is required", n+1, filename)
+		}
+	}
+	return &mapping, nil
+}
+
+// ownerOf returns the area and team of the first rule matching the container, empty when no rule matches.
+// A nil mapping matches no container
+func (m *OwnershipMapping) ownerOf(c k8s.ContainerSummary) (string, string) {
+	if m == nil {
+		return "", ""
+	}
+	for _, rule := range m.Rules {
+		if (rule.Namespace == "" || rule.Namespace == c.Namespace) &&
+			(rule.ImagePrefix == "" || strings.HasPrefix(c.Image, rule.ImagePrefix)) {
+			return rule.Area, rule.Team
+		}
+	}
+	return "", ""
+}
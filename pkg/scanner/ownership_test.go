@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ownership mapping", func() {
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	writeMapping := func(content string) string {
+		filename := filepath.Join(tmpDir, "owners.yaml")
+		Expect(os.WriteFile(filename, []byte(content), 0644)).To(Succeed())
+		return filename
+	}
+
+	It("attributes the images without area or team labels to the first matching rule", func() {
+		mapping, err := LoadOwnershipMapping(writeMapping(`
+owners:
+- namespace: payments
+  imagePrefix: registry.example.com/platform/
+  area: platform
+  team: sre
+- namespace: payments
+  area: finance
+  team: payments
+- imagePrefix: registry.example.com/platform/
+  team: platform
+`))
+		Expect(err).NotTo(HaveOccurred())
+		reportGenerator := &AreaReport{AreaLabelName: "area", TeamLabelName: "team", Ownership: mapping}
+		scannedImages := []ScannedImage{
+			{ImageName: "api:1", Containers: []k8s.ContainerSummary{{Namespace: "payments", Image: "api:1"}}},
+			{ImageName: "registry.example.com/platform/proxy:1", Containers: []k8s.ContainerSummary{{Namespace: "payments", Image: "registry.example.com/platform/proxy:1"}}},
+			{ImageName: "registry.example.com/platform/agent:1", Containers: []k8s.ContainerSummary{{Namespace: "monitoring", Image: "registry.example.com/platform/agent:1",
+				NamespaceLabels: map[string]string{"area": "observability"}}}},
+			{ImageName: "web:1", Containers: []k8s.ContainerSummary{{Namespace: "orders", Image: "web:1",
+				NamespaceLabels: map[string]string{"area": "commerce", "team": "orders"}}}},
+			{ImageName: "debug:1", Containers: []k8s.ContainerSummary{{Namespace: "default", Image: "debug:1"}}},
+		}
+
+		imageByArea, err := reportGenerator.generateAreaGrouping(scannedImages)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imageByArea["finance"].Teams["payments"].Images[0].ImageName).To(Equal("api:1"))
+		Expect(imageByArea["platform"].Teams["sre"].Images[0].ImageName).To(Equal("registry.example.com/platform/proxy:1"))
+		Expect(imageByArea["observability"].Teams["platform"].Images[0].ImageName).To(Equal("registry.example.com/platform/agent:1"))
+		Expect(imageByArea["commerce"].Teams["orders"].Images[0].ImageName).To(Equal("web:1"))
+		Expect(imageByArea["all"].Teams["all"].Images[0].ImageName).To(Equal("debug:1"))
+	})
+
+	It("rejects the rules without matcher or owner", func() {
+		_, err := LoadOwnershipMapping(writeMapping(`
+owners:
+- team: sre
+`))
+		Expect(err).To(MatchError(ContainSubstring("ownership rule 1 of")))
+		Expect(err).To(MatchError(ContainSubstring("a namespace or an image prefix is required")))
+
+		_, err = LoadOwnershipMapping(writeMapping(`
+owners:
+- namespace: payments
+`))
+		Expect(err).To(MatchError(ContainSubstring("an area or a team is required")))
+	})
+
+	It("fails when the mapping file cannot be read", func() {
+		_, err := LoadOwnershipMapping(filepath.Join(tmpDir, "missing.yaml"))
+
+		Expect(err).To(MatchError(ContainSubstring("could not read ownership mapping file")))
+	})
+})
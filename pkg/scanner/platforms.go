@@ -0,0 +1,220 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+const (
+	// PlatformsHost scans the platform docker pulls on the scanning host, the default
+	PlatformsHost = "host"
+	// PlatformsNodes scans the platforms of the nodes running the multi-platform images
+	PlatformsNodes = "nodes"
+	// PlatformsAll scans every platform of the multi-platform images
+	PlatformsAll = "all"
+)
+
+// ImagePlatform is a platform of an image, for instance linux/arm64, with the digest of its manifest
+type ImagePlatform struct {
+	Platform string
+	Digest   string
+}
+
+// ValidatePlatforms returns an error when the platform selection mode is unknown, see Config.Platforms
+func ValidatePlatforms(platforms string) error {
+	switch platforms {
+	case "", PlatformsHost, PlatformsNodes, PlatformsAll:
+		return nil
+	}
+	return fmt.Errorf("unknown platforms %q, must be one of %s, %s or %s", platforms, PlatformsHost, PlatformsNodes, PlatformsAll)
+}
+
+// loadNodePlatforms returns the platforms of the cluster nodes by node name. The nodes are unknown when they cannot be
+// listed, every platform of the images being scanned then
+func (s *Scanner) loadNodePlatforms() map[string]string {
+	if s.kubernetesClient == nil {
+		return nil
+	}
+	nodes, err := s.kubernetesClient.GetNodes()
+	if err != nil {
+		logr.Warnf("Unable to list the nodes, scanning every platform of the multi-platform images: %v", err)
+		return nil
+	}
+	platforms := make(map[string]string)
+	for _, node := range nodes {
+		nodeInfo := node.Status.NodeInfo
+		if nodeInfo.OperatingSystem != "" && nodeInfo.Architecture != "" {
+			platforms[node.Name] = nodeInfo.OperatingSystem + "/" + nodeInfo.Architecture
+		}
+	}
+	return platforms
+}
+
+// scanImagePlatforms scans the platforms of the image selected by Config.Platforms, each platform being pulled and
+// scanned by the digest of its manifest. The results of the platforms are merged, the vulnerabilities found on several
+// platforms being kept once in the results of the first of them, and the SBOM being the one of the first platform. The
+// image is scanned as is when a single platform is selected or when its platforms are unknown
+func (s *Scanner) scanImagePlatforms(ctx context.Context, imageName string, containers []k8s.ContainerSummary) (scan imageScan, interrupted bool) {
+	platforms := s.selectPlatforms(ctx, imageName, containers)
+	if len(platforms) == 0 {
+		return s.scanImage(ctx, imageName)
+	}
+	found := make(map[platformFindingKey]bool)
+	for _, platform := range platforms {
+		logr.Infof("Scanning platform %s of image %s", platform.Platform, imageName)
+		platformScan, platformInterrupted := s.scanImage(ctx, platformReference(imageName, platform.Digest))
+		if platformInterrupted {
+			return imageScan{}, true
+		}
+		if scan.scanError == nil {
+			scan.scanError = platformScan.scanError
+		}
+		if scan.pullError == nil {
+			scan.pullError = platformScan.pullError
+		}
+		if scan.removeError == nil {
+			scan.removeError = platformScan.removeError
+		}
+		if scan.sbomFile == "" {
+			scan.sbomFile = platformScan.sbomFile
+		}
+		scan.rawOutputFiles = append(scan.rawOutputFiles, platformScan.rawOutputFiles...)
+		platformOutput := platformScan.trivyOutput
+		if platformOutput == nil {
+			continue
+		}
+		// the platform of the manifest list holds the variant the image config may lack
+		setPlatform(platformOutput.Results, platform.Platform)
+		dropFoundVulnerabilities(platformOutput.Results, found)
+		if scan.trivyOutput == nil {
+			scan.trivyOutput = platformOutput
+		} else {
+			scan.trivyOutput.Results = append(scan.trivyOutput.Results, platformOutput.Results...)
+		}
+	}
+	return scan, false
+}
+
+// platformFindingKey identifies a vulnerability of a package version across the platforms of an image
+type platformFindingKey struct {
+	vulnerabilityID  string
+	pkgName          string
+	installedVersion string
+}
+
+// dropFoundVulnerabilities removes from the results of a platform the vulnerabilities already found on the previous
+// platforms, so that a vulnerability of every platform is counted once, and records the others as found
+func dropFoundVulnerabilities(results []TrivyOutputResults, found map[platformFindingKey]bool) {
+	for i := range results {
+		var vulnerabilities []Vulnerabilities
+		for _, vulnerability := range results[i].Vulnerabilities {
+			key := platformFindingKey{vulnerability.VulnerabilityID, vulnerability.PkgName, vulnerability.InstalledVersion}
+			if found[key] {
+				continue
+			}
+			found[key] = true
+			vulnerabilities = append(vulnerabilities, vulnerability)
+		}
+		results[i].Vulnerabilities = vulnerabilities
+	}
+}
+
+// selectPlatforms returns the platforms of the multi-platform image to scan according to Config.Platforms, nil to
+// scan the image as is. The platforms of the nodes running the containers are selected with PlatformsNodes, every
+// platform of the image being selected when those nodes are unknown or none of their platforms is found
+func (s *Scanner) selectPlatforms(ctx context.Context, imageName string, containers []k8s.ContainerSummary) []ImagePlatform {
+	if s.config.Platforms != PlatformsNodes && s.config.Platforms != PlatformsAll {
+		return nil
+	}
+	platforms, err := s.dockerClient.ImagePlatforms(ctx, imageName)
+	if err != nil {
+		logr.Warnf("Unable to list the platforms of image %s, scanning the platform of the host: %v", imageName, err)
+		return nil
+	}
+	if len(platforms) <= 1 || s.config.Platforms == PlatformsAll {
+		return multiplePlatforms(platforms)
+	}
+
+	nodePlatforms := make(map[string]bool)
+	for _, container := range containers {
+		if platform, ok := s.nodePlatforms[container.NodeName]; ok {
+			nodePlatforms[platform] = true
+		}
+	}
+	if len(nodePlatforms) == 0 {
+		logr.Debugf("Nodes running image %s unknown, scanning all its platforms", imageName)
+		return platforms
+	}
+	var selected []ImagePlatform
+	for _, platform := range platforms {
+		for nodePlatform := range nodePlatforms {
+			if matchesPlatform(platform.Platform, nodePlatform) {
+				selected = append(selected, platform)
+				break
+			}
+		}
+	}
+	if len(selected) == 0 {
+		logr.Warnf("No platform of image %s matches the nodes running it, scanning all its platforms", imageName)
+		return platforms
+	}
+	return selected
+}
+
+// multiplePlatforms returns the platforms when there are several of them, nil otherwise as a single platform image
+// is scanned as is
+func multiplePlatforms(platforms []ImagePlatform) []ImagePlatform {
+	if len(platforms) <= 1 {
+		return nil
+	}
+	return platforms
+}
+
+// matchesPlatform returns true when the image platform, for instance linux/arm/v7, is the node platform, which
+// has no variant, for instance linux/arm
+func matchesPlatform(imagePlatform, nodePlatform string) bool {
+	return imagePlatform == nodePlatform || strings.HasPrefix(imagePlatform, nodePlatform+"/")
+}
+
+// platformReference returns the reference of the platform manifest of the image, for instance
+// registry/payments/web@sha256:4ff3ca91... for the image registry/payments/web:1.2
+func platformReference(imageName, digest string) string {
+	name, _, _ := strings.Cut(imageName, "@")
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		name = name[:tagIndex]
+	}
+	return name + "@" + digest
+}
+
+// setPlatform records the platform of the results when known
+func setPlatform(results []TrivyOutputResults, platform string) {
+	if platform == "" {
+		return
+	}
+	for i := range results {
+		results[i].Platform = platform
+	}
+}
+
+// resultPlatforms returns the distinct platforms of the results in order, nil when unknown
+func resultPlatforms(results []TrivyOutputResults) []string {
+	var platforms []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.Platform != "" && !seen[result.Platform] {
+			seen[result.Platform] = true
+			platforms = append(platforms, result.Platform)
+		}
+	}
+	return platforms
+}
+
+// PlatformNames returns the platforms the image was scanned for separated by commas, for instance
+// "linux/amd64, linux/arm64", empty when unknown
+func (i ScannedImage) PlatformNames() string {
+	return strings.Join(i.Platforms, ", ")
+}
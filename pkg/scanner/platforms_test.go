@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Platforms", func() {
+
+	const areaLabel = "area-label"
+
+	var (
+		scan                 *Scanner
+		mockKubernetesClient *k8stest.KubernetesClient
+		mockTrivyClient      *mockTrivy
+		mockDockerClient     *mockDocker
+		platforms            []ImagePlatform
+	)
+
+	vulnerableOutput := func(vulnerabilityIDs ...string) *TrivyOutput {
+		var vulnerabilities []Vulnerabilities
+		for _, vulnerabilityID := range vulnerabilityIDs {
+			vulnerabilities = append(vulnerabilities, Vulnerabilities{VulnerabilityID: vulnerabilityID, PkgName: "openssl", InstalledVersion: "3.0.9", Severity: "HIGH"})
+		}
+		return &TrivyOutput{Results: []TrivyOutputResults{{Target: "nginx", Class: "os-pkgs", Vulnerabilities: vulnerabilities}}}
+	}
+
+	expectPlatformScan := func(reference string, vulnerabilityIDs ...string) {
+		mockDockerClient.On("PullImage", reference).Return(nil).On("RmiImage", reference).Return(nil)
+		mockTrivyClient.On("ScanImage", reference).Return(vulnerableOutput(vulnerabilityIDs...), nil)
+	}
+
+	BeforeEach(func() {
+		mockKubernetesClient = &k8stest.KubernetesClient{}
+		mockTrivyClient = &mockTrivy{}
+		mockDockerClient = &mockDocker{}
+		mockDockerClient.On("ImageExists", mock.Anything).Return(false, nil).Maybe()
+		scan = &Scanner{
+			config: &Config{
+				Workers:      1,
+				FilterLabels: areaLabel,
+			},
+			kubernetesClient: mockKubernetesClient,
+			trivyClient:      mockTrivyClient,
+			dockerClient:     mockDockerClient,
+		}
+		platforms = []ImagePlatform{
+			{Platform: "linux/amd64", Digest: "sha256:amd"},
+			{Platform: "linux/arm64", Digest: "sha256:arm"},
+		}
+		mockKubernetesClient.On("GetServerVersion").Return("v1.27.3", nil)
+		mockKubernetesClient.On("GetNodes").Return([]v1.Node{
+			{ObjectMeta: metaV1.ObjectMeta{Name: "arm-node"}, Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "arm64"}}},
+		}, nil)
+		mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{
+			{Image: "nginx:1.25", PodName: "web", NodeName: "arm-node"},
+		}, nil)
+		mockTrivyClient.On("Version").Return(&TrivyVersion{Version: "0.45.0"}, nil)
+		mockTrivyClient.On("DownloadDatabase").Return(nil)
+	})
+
+	It("records the platform of the image config trivy scanned on the host", func() {
+		output := vulnerableOutput("CVE-1")
+		output.Metadata.ImageConfig = &ImageConfig{OS: "linux", Architecture: "amd64"}
+		mockDockerClient.On("PullImage", "nginx:1.25").Return(nil).On("RmiImage", "nginx:1.25").Return(nil)
+		mockTrivyClient.On("ScanImage", "nginx:1.25").Return(output, nil)
+
+		report, err := scan.ScanImages(context.Background())
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages[0].Platforms).To(Equal([]string{"linux/amd64"}))
+		Expect(report.ScannedImages[0].TrivyOutputResults[0].Platform).To(Equal("linux/amd64"))
+		mockDockerClient.AssertNotCalled(GinkgoT(), "ImagePlatforms", "nginx:1.25")
+	})
+
+	It("scans the platform of the nodes running the image by the digest of its manifest", func() {
+		scan.config.Platforms = PlatformsNodes
+		mockDockerClient.On("ImagePlatforms", "nginx:1.25").Return(platforms, nil)
+		expectPlatformScan("nginx@sha256:arm", "CVE-1")
+
+		report, err := scan.ScanImages(context.Background())
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages[0].ImageName).To(Equal("nginx:1.25"))
+		Expect(report.ScannedImages[0].Platforms).To(Equal([]string{"linux/arm64"}))
+		mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "nginx@sha256:amd")
+	})
+
+	It("scans and merges the results of every platform of the image", func() {
+		scan.config.Platforms = PlatformsAll
+		mockDockerClient.On("ImagePlatforms", "nginx:1.25").Return(platforms, nil)
+		expectPlatformScan("nginx@sha256:amd", "CVE-1")
+		expectPlatformScan("nginx@sha256:arm", "CVE-2")
+
+		report, err := scan.ScanImages(context.Background())
+
+		Expect(err).NotTo(HaveOccurred())
+		image := report.ScannedImages[0]
+		Expect(image.Platforms).To(Equal([]string{"linux/amd64", "linux/arm64"}))
+		Expect(image.TrivyOutputResults).To(HaveLen(2))
+		Expect(image.TrivyOutputResults[1].Platform).To(Equal("linux/arm64"))
+		Expect(image.TrivyOutputResults[1].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2"))
+	})
+
+	It("counts once the vulnerabilities found on several platforms", func() {
+		scan.config.Platforms = PlatformsAll
+		mockDockerClient.On("ImagePlatforms", "nginx:1.25").Return(platforms, nil)
+		expectPlatformScan("nginx@sha256:amd", "CVE-1", "CVE-2")
+		expectPlatformScan("nginx@sha256:arm", "CVE-1", "CVE-3")
+
+		report, err := scan.ScanImages(context.Background())
+
+		Expect(err).NotTo(HaveOccurred())
+		image := report.ScannedImages[0]
+		Expect(image.Platforms).To(Equal([]string{"linux/amd64", "linux/arm64"}))
+		Expect(image.VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(3))
+		Expect(image.TrivyOutputResults[1].Vulnerabilities).To(HaveLen(1))
+		Expect(image.TrivyOutputResults[1].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-3"))
+	})
+
+	It("scans the image as is when its platforms cannot be listed", func() {
+		scan.config.Platforms = PlatformsAll
+		mockDockerClient.On("ImagePlatforms", "nginx:1.25").Return([]ImagePlatform(nil), context.DeadlineExceeded)
+		expectPlatformScan("nginx:1.25", "CVE-1")
+
+		report, err := scan.ScanImages(context.Background())
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+	})
+
+	Describe("selection", func() {
+
+		It("matches the variants of the node architecture", func() {
+			Expect(matchesPlatform("linux/arm/v7", "linux/arm")).To(BeTrue())
+			Expect(matchesPlatform("linux/arm64", "linux/arm")).To(BeFalse())
+		})
+
+		It("references the platform manifest by digest whatever the tag and the registry port", func() {
+			Expect(platformReference("registry:5000/payments/web:1.2", "sha256:arm")).To(Equal("registry:5000/payments/web@sha256:arm"))
+			Expect(platformReference("web@sha256:list", "sha256:arm")).To(Equal("web@sha256:arm"))
+		})
+
+		It("rejects the unknown platform selections", func() {
+			Expect(ValidatePlatforms(PlatformsNodes)).To(Succeed())
+			Expect(ValidatePlatforms("arm64")).To(HaveOccurred())
+		})
+	})
+})
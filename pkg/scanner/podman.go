@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+const (
+	// DockerRuntime pulls and removes the images with docker, the default
+	DockerRuntime = "docker"
+	// PodmanRuntime pulls and removes the images with podman, for the hosts where docker is not installed
+	PodmanRuntime = "podman"
+)
+
+// ValidateContainerRuntime returns an error when the container runtime is unknown, see Config.ContainerRuntime
+func ValidateContainerRuntime(containerRuntime string) error {
+	switch containerRuntime {
+	case "", DockerRuntime, PodmanRuntime:
+		return nil
+	}
+	return fmt.Errorf("unknown container runtime %q, must be %s or %s", containerRuntime, DockerRuntime, PodmanRuntime)
+}
+
+// NewDockerClient creates the client pulling and removing the images with the container runtime of the config
+func (c *Config) NewDockerClient() DockerClient {
+	if c.ContainerRuntime == PodmanRuntime {
+		return &podmanClient{insecureRegistries: c.InsecureRegistries, credentials: c.RegistryCredentials}
+	}
+	return &dockerClient{insecureRegistries: c.InsecureRegistries, credentials: c.RegistryCredentials}
+}
+
+type podmanClient struct {
+	// insecureRegistries are the registries the images are pulled from without TLS verification
+	insecureRegistries []string
+	// credentials provides the credentials of the registries passed with --authfile to the commands reaching the
+	// registry, see dockerClient.credentials
+	credentials RegistryCredentialSource
+}
+
+// NewPodmanClient creates a DockerClient pulling and removing the images with the podman CLI
+func NewPodmanClient() DockerClient {
+	return &podmanClient{}
+}
+
+func (p *podmanClient) PullImage(ctx context.Context, image string) error {
+	return withRegistryAuth(ctx, p.credentials, image, func(authFile string) error {
+		command := exec.CommandContext(ctx, "podman", p.registryArgs([]string{"pull"}, image, authFile)...)
+		output, err := command.CombinedOutput()
+		if err != nil {
+			return dockerError(fmt.Sprintf("error while pulling image %s with podman", image), output, err)
+		}
+		return nil
+	})
+}
+
+func (p *podmanClient) RmiImage(image string) error {
+	command := exec.Command("podman", "rmi", image)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		return dockerError(fmt.Sprintf("error while deleting image %s with podman", image), output, err)
+	}
+	return nil
+}
+
+func (p *podmanClient) ImageExists(ctx context.Context, image string) (bool, error) {
+	command := exec.CommandContext(ctx, "podman", "image", "exists", image)
+	output, err := command.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, dockerError(fmt.Sprintf("error while inspecting image %s with podman", image), output, err)
+	}
+	return true, nil
+}
+
+func (p *podmanClient) ImageSize(ctx context.Context, image string) (int64, error) {
+	manifest, err := p.inspectManifest(ctx, image)
+	if err != nil {
+		return 0, err
+	}
+	if len(manifest.Manifests) > 0 {
+		digest := manifest.platformDigest(runtime.GOARCH)
+		if manifest, err = p.inspectManifest(ctx, platformReference(image, digest)); err != nil {
+			return 0, err
+		}
+	}
+	return manifest.size(), nil
+}
+
+// ImageCreated returns an error as podman cannot read the image config blob of the registry without pulling the image
+func (p *podmanClient) ImageCreated(_ context.Context, image string) (time.Time, error) {
+	return time.Time{}, fmt.Errorf("podman cannot read the creation time of image %s without pulling it", image)
+}
+
+func (p *podmanClient) ImagePlatforms(ctx context.Context, image string) ([]ImagePlatform, error) {
+	manifest, err := p.inspectManifest(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.platforms(), nil
+}
+
+func (p *podmanClient) inspectManifest(ctx context.Context, image string) (*podmanManifest, error) {
+	var output []byte
+	err := withRegistryAuth(ctx, p.credentials, image, func(authFile string) error {
+		command := exec.CommandContext(ctx, "podman", p.registryArgs([]string{"manifest", "inspect"}, image, authFile)...)
+		var err error
+		if output, err = command.Output(); err != nil {
+			return dockerError(fmt.Sprintf("error while inspecting the manifest of image %s with podman", image), output, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var manifest podmanManifest
+	if err := json.Unmarshal(output, &manifest); err != nil {
+		return nil, fmt.Errorf("error while decoding the manifest of image %s: %v", image, err)
+	}
+	return &manifest, nil
+}
+
+// registryArgs appends the image to the arguments of the podman command reaching the registry, without TLS
+// verification for the images of the insecure registries and with the credentials of the auth file if any
+func (p *podmanClient) registryArgs(args []string, image, authFile string) []string {
+	if insecureRegistry(p.insecureRegistries, image) {
+		args = append(args, "--tls-verify=false")
+	}
+	if authFile != "" {
+		args = append(args, "--authfile", authFile)
+	}
+	return append(args, image)
+}
+
+// podmanManifest is an object representation of the podman manifest inspect output, the manifest list of the
+// multi-platform images or the manifest of the single platform images
+type podmanManifest struct {
+	Manifests []struct {
+		Digest   string            `json:"digest"`
+		Platform *manifestPlatform `json:"platform"`
+	} `json:"manifests"`
+	Config struct {
+		Size int64 `json:"size"`
+	} `json:"config"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+}
+
+// platforms returns the platforms of the manifest list, none for a single platform manifest which holds no platform
+func (m *podmanManifest) platforms() []ImagePlatform {
+	var platforms []ImagePlatform
+	for _, manifest := range m.Manifests {
+		if name := manifest.Platform.name(); name != "" {
+			platforms = append(platforms, ImagePlatform{Platform: name, Digest: manifest.Digest})
+		}
+	}
+	return platforms
+}
+
+// platformDigest returns the digest of the linux manifest of the architecture of the manifest list, or else of the
+// first manifest as docker does
+func (m *podmanManifest) platformDigest(architecture string) string {
+	for _, manifest := range m.Manifests {
+		if platform := manifest.Platform; platform != nil && platform.OS == "linux" && platform.Architecture == architecture {
+			return manifest.Digest
+		}
+	}
+	return m.Manifests[0].Digest
+}
+
+// size sums the size of the config and layers of a single platform manifest
+func (m *podmanManifest) size() int64 {
+	size := m.Config.Size
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return size
+}
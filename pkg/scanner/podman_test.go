@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Podman client", func() {
+
+	decode := func(output string) *podmanManifest {
+		var manifest podmanManifest
+		Expect(json.Unmarshal([]byte(output), &manifest)).To(Succeed())
+		return &manifest
+	}
+
+	manifestList := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+{"digest":"sha256:aaa","size":528,"platform":{"architecture":"arm64","os":"linux"}},
+{"digest":"sha256:bbb","size":528,"platform":{"architecture":"amd64","os":"linux"}},
+{"digest":"sha256:ccc","size":566,"platform":{"architecture":"unknown","os":"unknown"}}
+]}`
+
+	It("lists the platforms of the manifest list", func() {
+		Expect(decode(manifestList).platforms()).To(Equal([]ImagePlatform{
+			{Platform: "linux/arm64", Digest: "sha256:aaa"},
+			{Platform: "linux/amd64", Digest: "sha256:bbb"},
+		}))
+	})
+
+	It("selects the linux manifest of the architecture, or else the first manifest", func() {
+		Expect(decode(manifestList).platformDigest("amd64")).To(Equal("sha256:bbb"))
+		Expect(decode(manifestList).platformDigest("s390x")).To(Equal("sha256:aaa"))
+	})
+
+	It("sums the config and layers sizes of a single platform manifest, which has no platform", func() {
+		manifest := decode(`{"schemaVersion":2,"config":{"size":1500},"layers":[{"size":3000000},{"size":500}]}`)
+
+		Expect(manifest.size()).To(Equal(int64(3002000)))
+		Expect(manifest.platforms()).To(BeEmpty())
+	})
+
+	It("skips the TLS verification of the insecure registries", func() {
+		docker := (&Config{InsecureRegistries: []string{"registry.internal:5000"}}).NewDockerClient().(*dockerClient)
+		Expect(docker.manifestInspectArgs("registry.internal:5000/api:1.0")).To(Equal([]string{"manifest", "inspect", "--verbose", "--insecure", "registry.internal:5000/api:1.0"}))
+		Expect(docker.manifestInspectArgs("alpine:3.18")).To(Equal([]string{"manifest", "inspect", "--verbose", "alpine:3.18"}))
+
+		podman := (&Config{ContainerRuntime: PodmanRuntime, InsecureRegistries: []string{"registry.internal:5000"}}).NewDockerClient().(*podmanClient)
+		Expect(podman.registryArgs([]string{"pull"}, "registry.internal:5000/api:1.0", "")).To(Equal([]string{"pull", "--tls-verify=false", "registry.internal:5000/api:1.0"}))
+		Expect(podman.registryArgs([]string{"pull"}, "alpine:3.18", "/tmp/auth/config.json")).To(Equal([]string{"pull", "--authfile", "/tmp/auth/config.json", "alpine:3.18"}))
+	})
+
+	It("creates the client of the container runtime of the config", func() {
+		Expect((&Config{ContainerRuntime: PodmanRuntime}).NewDockerClient()).To(BeAssignableToTypeOf(&podmanClient{}))
+		Expect((&Config{}).NewDockerClient()).To(BeAssignableToTypeOf(&dockerClient{}))
+		Expect(ValidateContainerRuntime("containerd")).To(HaveOccurred())
+	})
+})
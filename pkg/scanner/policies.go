@@ -0,0 +1,45 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultPolicyNamespace is the Rego package prefix trivy evaluates the custom policies of by default
+const DefaultPolicyNamespace = "user"
+
+// RegoPolicies are the user-supplied Rego policy bundles trivy evaluates the Kubernetes resources against, in addition
+// to its built-in checks, so that organisations can enforce their own configuration rules
+type RegoPolicies struct {
+	// Dirs are the directories of the policy bundles, no custom policy being evaluated when empty
+	Dirs []string
+	// Namespaces are the Rego package prefixes of the custom policies, for instance user for package user.kubernetes.ID001
+	Namespaces []string
+}
+
+// Validate returns an error when a policy directory does not exist
+func (p RegoPolicies) Validate() error {
+	for _, dir := range p.Dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("could not read policy directory %s: %v", dir, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("policy directory %s is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// TrivyArgs returns the trivy arguments loading the policies, none when there is no policy directory
+func (p RegoPolicies) TrivyArgs() []string {
+	if len(p.Dirs) == 0 {
+		return nil
+	}
+	args := []string{"--config-policy", strings.Join(p.Dirs, ",")}
+	if len(p.Namespaces) > 0 {
+		args = append(args, "--policy-namespaces", strings.Join(p.Namespaces, ","))
+	}
+	return args
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+var criticalityRanks = map[string]int{"critical": 4, "high": 3, "medium": 2, "low": 1}
+
+// ScanPriority orders the scans of the images so that the most important images are scanned, and streamed, first in
+// long runs: the images are scanned by decreasing priority, the weighted sum of their exposure, of the criticality of
+// the workloads running them, see k8s.CriticalityAnnotation, and of their severity score in a previous report, each normalised between 0 and 1
+type ScanPriority struct {
+	ExposureWeight      float64
+	CriticalityWeight   float64
+	PreviousScoreWeight float64
+	// PreviousReport is the report the previous severity score of the images is read from, the images not in the
+	// report or all the images when nil having a previous score of 0
+	PreviousReport *VulnerabilityReport
+}
+
+// ParseScanPriority parses the weights of the scan priority given as exposure=3,criticality=2,previous-score=1, the
+// weights not given being 0
+func ParseScanPriority(weights string) (*ScanPriority, error) {
+	priority := &ScanPriority{}
+	for _, pair := range strings.Split(weights, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid scan priority weight %q, expecting name=weight with a positive weight such as exposure=3", pair)
+		}
+		switch strings.TrimSpace(name) {
+		case "exposure":
+			priority.ExposureWeight = weight
+		case "criticality":
+			priority.CriticalityWeight = weight
+		case "previous-score":
+			priority.PreviousScoreWeight = weight
+		default:
+			return nil, fmt.Errorf("unknown scan priority weight %q, expecting exposure, criticality or previous-score", name)
+		}
+	}
+	return priority, nil
+}
+
+// sort orders the image groups by decreasing priority, the groups of equal priority keeping their order, and returns
+// the priority of the groups by their first image name. A nil priority keeps the order of the groups, their priorities
+// being nil
+func (p *ScanPriority) sort(imageGroups [][]string, imageList map[string][]k8s.ContainerSummary) map[string]float64 {
+	if p == nil {
+		return nil
+	}
+	previousScores := make(map[string]int)
+	if p.PreviousReport != nil {
+		for _, image := range p.PreviousReport.ScannedImages {
+			previousScores[image.ImageName] = image.VulnerabilitySummary.SeverityScore
+		}
+	}
+	priorities := make(map[string]float64, len(imageGroups))
+	for _, imageNames := range imageGroups {
+		priorities[imageNames[0]] = p.priority(imageNames, imageList, previousScores)
+	}
+	sort.SliceStable(imageGroups, func(i, j int) bool {
+		return priorities[imageGroups[i][0]] > priorities[imageGroups[j][0]]
+	})
+	for _, imageNames := range imageGroups {
+		logr.Debugf("Scan priority of image %s: %.2f", imageNames[0], priorities[imageNames[0]])
+	}
+	return priorities
+}
+
+// priority returns the weighted priority of the images of a group, the highest exposure, criticality and previous
+// score of its images being used
+func (p *ScanPriority) priority(imageNames []string, imageList map[string][]k8s.ContainerSummary, previousScores map[string]int) float64 {
+	var exposure, criticality, previousScore float64
+	for _, imageName := range imageNames {
+		exposure = math.Max(exposure, float64(k8s.ExposureRank(k8s.HighestExposure(imageList[imageName])))/float64(k8s.ExposureRank(k8s.ExposureInternet)))
+		for _, container := range imageList[imageName] {
+			rank := criticalityRanks[strings.ToLower(container.Criticality)]
+			criticality = math.Max(criticality, float64(rank)/float64(criticalityRanks["critical"]))
+		}
+		// the severity scores grow a hundredfold per severity, a single critical vulnerability scoring about 1
+		previousScore = math.Max(previousScore, math.Log10(1+float64(previousScores[imageName]))/math.Log10(critical))
+	}
+	return p.ExposureWeight*exposure + p.CriticalityWeight*criticality + p.PreviousScoreWeight*previousScore
+}
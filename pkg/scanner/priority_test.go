@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scan priority", func() {
+
+	imageList := map[string][]k8s.ContainerSummary{
+		"api:1":    {{Image: "api:1", Namespace: "payments", Exposure: k8s.ExposureInternet}},
+		"batch:1":  {{Image: "batch:1", Namespace: "billing", Criticality: "Critical"}},
+		"legacy:1": {{Image: "legacy:1", Namespace: "tools"}},
+		"web:1":    {{Image: "web:1", Namespace: "tools", Exposure: k8s.ExposureCluster}},
+	}
+	previousReport := &VulnerabilityReport{ScannedImages: []ScannedImage{
+		{ImageName: "legacy:1", VulnerabilitySummary: VulnerabilitySummary{SeverityScore: 2*critical + high}},
+	}}
+
+	It("scans the images by decreasing weighted priority", func() {
+		priority, err := ParseScanPriority("exposure=1, criticality=2,previous-score=3")
+		Expect(err).NotTo(HaveOccurred())
+		priority.PreviousReport = previousReport
+		imageGroups := groupImageNamesByDigest(imageList)
+		Expect(imageGroups).To(Equal([][]string{{"api:1"}, {"web:1"}, {"batch:1"}, {"legacy:1"}}))
+
+		priorities := priority.sort(imageGroups, imageList)
+
+		Expect(imageGroups).To(Equal([][]string{{"legacy:1"}, {"batch:1"}, {"api:1"}, {"web:1"}}))
+		Expect(priorities["legacy:1"]).To(BeNumerically(">", priorities["batch:1"]))
+		Expect(priorities["api:1"]).To(BeNumerically(">", priorities["web:1"]))
+	})
+
+	It("keeps the exposure order without priority", func() {
+		imageGroups := groupImageNamesByDigest(imageList)
+
+		Expect((*ScanPriority)(nil).sort(imageGroups, imageList)).To(BeNil())
+
+		Expect(imageGroups).To(Equal([][]string{{"api:1"}, {"web:1"}, {"batch:1"}, {"legacy:1"}}))
+	})
+
+	It("rejects the unknown and negative weights", func() {
+		_, err := ParseScanPriority("exposure=1,age=2")
+		Expect(err).To(MatchError(ContainSubstring("unknown scan priority weight")))
+		_, err = ParseScanPriority("exposure=-1")
+		Expect(err).To(MatchError(ContainSubstring("positive weight")))
+	})
+})
@@ -0,0 +1,236 @@
+package scanner
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// rankingSize is the number of entries of the report rankings
+const rankingSize = 10
+
+// PackageRanking is a package of the images with the number of vulnerabilities found in it across the images. The
+// packages of different target types, for instance debian and jar, or of different installed versions are ranked apart
+type PackageRanking struct {
+	// Type is the type of the trivy target of the package, for instance debian or jar
+	Type             string
+	PkgName          string
+	InstalledVersion string
+	// FindingCount is the number of vulnerabilities of the package, counted once per image
+	FindingCount int
+	// VulnerabilityCount is the number of distinct vulnerabilities of the package
+	VulnerabilityCount           int
+	ImageCount                   int
+	TotalVulnerabilityBySeverity map[string]int
+}
+
+// UpgradeRanking is the upgrade of a package to a fixed version with the number of vulnerabilities it remediates
+type UpgradeRanking struct {
+	PkgName      string
+	FixedVersion string
+	// VulnerabilityCount is the number of distinct vulnerabilities the upgrade fixes
+	VulnerabilityCount int
+	// FindingCount is the number of vulnerabilities the upgrade fixes, counted once per image
+	FindingCount int
+	ImageCount   int
+}
+
+// TopVulnerableImages returns the images with the highest severity score, the images without vulnerability being left out
+func (r *VulnerabilityReport) TopVulnerableImages() []ScannedImage {
+	return topVulnerableImages(r.ScannedImages, rankingSize)
+}
+
+func topVulnerableImages(scannedImages []ScannedImage, count int) []ScannedImage {
+	var images []ScannedImage
+	for _, image := range scannedImages {
+		if image.VulnerabilitySummary.SeverityScore > 0 {
+			images = append(images, image)
+		}
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].VulnerabilitySummary.SeverityScore > images[j].VulnerabilitySummary.SeverityScore
+	})
+	if len(images) > count {
+		images = images[:count]
+	}
+	return images
+}
+
+// packageKey identifies a package of the rankings by its target type, name and installed version
+type packageKey struct {
+	targetType, pkgName, installedVersion string
+}
+
+// packageFinding is a vulnerability of a package of an image, see TopPackages
+type packageFinding struct {
+	packageKey
+	vulnerabilityID string
+}
+
+// TopPackages returns the packages responsible for the most vulnerabilities across the images
+func (r *VulnerabilityReport) TopPackages() []PackageRanking {
+	rankings := make(map[packageKey]*PackageRanking)
+	vulnerabilities := make(map[packageKey]map[string]bool)
+	images := make(map[packageKey]map[string]bool)
+	for _, image := range r.ScannedImages {
+		seen := make(map[packageFinding]bool)
+		for _, target := range image.Results() {
+			for _, vulnerability := range target.Vulnerabilities {
+				key := packageKey{target.Type, vulnerability.PkgName, vulnerability.InstalledVersion}
+				ranking, ok := rankings[key]
+				if !ok {
+					ranking = &PackageRanking{Type: target.Type, PkgName: vulnerability.PkgName, InstalledVersion: vulnerability.InstalledVersion,
+						TotalVulnerabilityBySeverity: make(map[string]int)}
+					rankings[key] = ranking
+					vulnerabilities[key] = make(map[string]bool)
+					images[key] = make(map[string]bool)
+				}
+				// a vulnerability is counted once per image and package, whatever the number of targets it is found in
+				if finding := (packageFinding{key, vulnerability.VulnerabilityID}); !seen[finding] {
+					seen[finding] = true
+					ranking.FindingCount++
+					ranking.TotalVulnerabilityBySeverity[vulnerability.Severity]++
+				}
+				vulnerabilities[key][vulnerability.VulnerabilityID] = true
+				images[key][image.ImageName] = true
+			}
+		}
+	}
+
+	var result []PackageRanking
+	for key, ranking := range rankings {
+		ranking.VulnerabilityCount = len(vulnerabilities[key])
+		ranking.ImageCount = len(images[key])
+		result = append(result, *ranking)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		switch {
+		case result[i].FindingCount != result[j].FindingCount:
+			return result[i].FindingCount > result[j].FindingCount
+		case result[i].PkgName != result[j].PkgName:
+			return result[i].PkgName < result[j].PkgName
+		case result[i].InstalledVersion != result[j].InstalledVersion:
+			return result[i].InstalledVersion < result[j].InstalledVersion
+		}
+		return result[i].Type < result[j].Type
+	})
+	if len(result) > rankingSize {
+		result = result[:rankingSize]
+	}
+	return result
+}
+
+// TopUpgrades returns the package upgrades remediating the most vulnerabilities across the images. The upgrade of a
+// package is to the highest version fixing its vulnerabilities, so that a single upgrade fixes all of them
+func (r *VulnerabilityReport) TopUpgrades() []UpgradeRanking {
+	rankings := make(map[string]*UpgradeRanking)
+	vulnerabilities := make(map[string]map[string]bool)
+	images := make(map[string]map[string]bool)
+	for _, finding := range matchingFindings(r.ScannedImages, Vulnerabilities.Fixable) {
+		name := finding.Vulnerability.PkgName
+		fixedVersion := upgradeVersion(finding.Vulnerability.InstalledVersion, finding.Vulnerability.FixedVersion)
+		ranking, ok := rankings[name]
+		if !ok {
+			ranking = &UpgradeRanking{PkgName: name, FixedVersion: fixedVersion}
+			rankings[name] = ranking
+			vulnerabilities[name] = make(map[string]bool)
+			images[name] = make(map[string]bool)
+		}
+		if compareVersions(fixedVersion, ranking.FixedVersion) > 0 {
+			ranking.FixedVersion = fixedVersion
+		}
+		ranking.FindingCount++
+		vulnerabilities[name][finding.Vulnerability.VulnerabilityID] = true
+		images[name][finding.ImageName] = true
+	}
+
+	var result []UpgradeRanking
+	for name, ranking := range rankings {
+		ranking.VulnerabilityCount = len(vulnerabilities[name])
+		ranking.ImageCount = len(images[name])
+		result = append(result, *ranking)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].VulnerabilityCount != result[j].VulnerabilityCount {
+			return result[i].VulnerabilityCount > result[j].VulnerabilityCount
+		}
+		if result[i].FindingCount != result[j].FindingCount {
+			return result[i].FindingCount > result[j].FindingCount
+		}
+		return result[i].PkgName < result[j].PkgName
+	})
+	if len(result) > rankingSize {
+		result = result[:rankingSize]
+	}
+	return result
+}
+
+// upgradeVersion returns the version to upgrade to among the fixed versions trivy reports, for instance
+// "1.1.1u, 3.0.9" for the fixes of several release lines: the lowest fixed version above the installed version
+func upgradeVersion(installedVersion, fixedVersions string) string {
+	var upgrade string
+	for _, version := range strings.Split(fixedVersions, ",") {
+		version = strings.TrimSpace(version)
+		if compareVersions(version, installedVersion) <= 0 {
+			continue
+		}
+		if upgrade == "" || compareVersions(version, upgrade) < 0 {
+			upgrade = version
+		}
+	}
+	if upgrade == "" {
+		return strings.TrimSpace(fixedVersions)
+	}
+	return upgrade
+}
+
+// compareVersions compares the versions by their numeric and non numeric parts, for instance 1.2.10 > 1.2.9,
+// returning a negative number when a < b, zero when a == b and a positive number when a > b
+func compareVersions(a, b string) int {
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numberA, errA := strconv.Atoi(partsA[i])
+		numberB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil && numberA != numberB:
+			return numberA - numberB
+		case (errA != nil || errB != nil) && partsA[i] != partsB[i]:
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+	return len(partsA) - len(partsB)
+}
+
+// versionParts splits the version into its runs of digits and of letters, the separators being dropped
+func versionParts(version string) []string {
+	var parts []string
+	var current strings.Builder
+	digits := false
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	for _, c := range version {
+		switch {
+		case unicode.IsDigit(c):
+			if !digits {
+				flush()
+			}
+			digits = true
+			current.WriteRune(c)
+		case unicode.IsLetter(c):
+			if digits {
+				flush()
+			}
+			digits = false
+			current.WriteRune(c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Report rankings", func() {
+
+	var report *VulnerabilityReport
+
+	BeforeEach(func() {
+		report = &VulnerabilityReport{ScannedImages: []ScannedImage{
+			NewScannedImage("api:1.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "1.1.1v, 3.0.10", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-2", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.11", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-3", PkgName: "zlib", InstalledVersion: "1.2.13", Severity: "LOW"},
+			}}}, nil),
+			NewScannedImage("web:2.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", InstalledVersion: "3.0.8", FixedVersion: "1.1.1v, 3.0.10", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-4", PkgName: "curl", InstalledVersion: "8.0.1", FixedVersion: "8.1.0", Severity: "MEDIUM"},
+			}}}, nil),
+			NewScannedImage("static:1.0", nil, nil, nil),
+		}}
+	})
+
+	It("ranks the images by severity score, leaving out the images without vulnerability", func() {
+		images := report.TopVulnerableImages()
+
+		Expect(images).To(HaveLen(2))
+		Expect(images[0].ImageName).To(Equal("api:1.0"))
+		Expect(images[1].ImageName).To(Equal("web:2.0"))
+	})
+
+	It("ranks the packages by number of findings across the images", func() {
+		Expect(report.TopPackages()).To(Equal([]PackageRanking{
+			{PkgName: "openssl", InstalledVersion: "3.0.9", FindingCount: 2, VulnerabilityCount: 2, ImageCount: 1, TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": 1, "HIGH": 1}},
+			{PkgName: "curl", InstalledVersion: "8.0.1", FindingCount: 1, VulnerabilityCount: 1, ImageCount: 1, TotalVulnerabilityBySeverity: map[string]int{"MEDIUM": 1}},
+			{PkgName: "openssl", InstalledVersion: "3.0.8", FindingCount: 1, VulnerabilityCount: 1, ImageCount: 1, TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": 1}},
+			{PkgName: "zlib", InstalledVersion: "1.2.13", FindingCount: 1, VulnerabilityCount: 1, ImageCount: 1, TotalVulnerabilityBySeverity: map[string]int{"LOW": 1}},
+		}))
+	})
+
+	It("ranks apart the packages of different target types", func() {
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			NewScannedImage("api:1.0", nil, []TrivyOutputResults{
+				{Type: "debian", Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-1", PkgName: "log4j", InstalledVersion: "2.14.1", Severity: "CRITICAL"}}},
+				{Type: "jar", Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-1", PkgName: "log4j", InstalledVersion: "2.14.1", Severity: "CRITICAL"}}},
+			}, nil),
+			NewScannedImage("web:1.0", nil, []TrivyOutputResults{
+				{Type: "jar", Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-1", PkgName: "log4j", InstalledVersion: "2.14.1", Severity: "CRITICAL"}}},
+			}, nil),
+		}}
+
+		rankings := report.TopPackages()
+
+		Expect(rankings).To(HaveLen(2))
+		Expect(rankings[0].Type).To(Equal("jar"))
+		Expect(rankings[0].ImageCount).To(Equal(2))
+		Expect(rankings[1].Type).To(Equal("debian"))
+		Expect(rankings[1].ImageCount).To(Equal(1))
+	})
+
+	It("ranks the upgrades to the version fixing all the vulnerabilities of the package", func() {
+		Expect(report.TopUpgrades()).To(Equal([]UpgradeRanking{
+			{PkgName: "openssl", FixedVersion: "3.0.11", VulnerabilityCount: 2, FindingCount: 3, ImageCount: 2},
+			{PkgName: "curl", FixedVersion: "8.1.0", VulnerabilityCount: 1, FindingCount: 1, ImageCount: 1},
+		}))
+	})
+
+	It("compares the versions by their numeric parts", func() {
+		Expect(compareVersions("1.2.10", "1.2.9")).To(BeNumerically(">", 0))
+		Expect(compareVersions("3.0.10", "3.0.10")).To(Equal(0))
+		Expect(compareVersions("1.1.1v", "3.0.9")).To(BeNumerically("<", 0))
+		Expect(compareVersions("2.36-9+deb12u3", "2.36-9+deb12u4")).To(BeNumerically("<", 0))
+		Expect(upgradeVersion("3.0.9", "1.1.1v, 3.0.10")).To(Equal("3.0.10"))
+	})
+})
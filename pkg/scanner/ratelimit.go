@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// DockerHubRegistry is the registry of the images referenced without registry host, for instance alpine:3.18
+const DockerHubRegistry = "docker.io"
+
+// registryRateLimiter spaces out the image pulls of each rate limited registry so that the number of pulls per minute
+// stays below the registry limit. Registries without limit are not throttled
+type registryRateLimiter struct {
+	intervals map[string]time.Duration
+	lock      sync.Mutex
+	next      map[string]time.Time
+}
+
+func newRegistryRateLimiter(pullsPerMinute map[string]int) *registryRateLimiter {
+	intervals := make(map[string]time.Duration)
+	for registry, pulls := range pullsPerMinute {
+		if pulls > 0 {
+			intervals[registry] = time.Minute / time.Duration(pulls)
+		}
+	}
+	return &registryRateLimiter{intervals: intervals, next: make(map[string]time.Time)}
+}
+
+// wait blocks until the image can be pulled from its registry or the context is done
+func (l *registryRateLimiter) wait(ctx context.Context, image string) error {
+	if l == nil {
+		return nil
+	}
+	registry := ImageRegistry(image)
+	interval, ok := l.intervals[registry]
+	if !ok {
+		return nil
+	}
+
+	l.lock.Lock()
+	slot := l.next[registry]
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	l.next[registry] = slot.Add(interval)
+	l.lock.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+	logr.Debugf("Waiting %v before pulling image %s to stay below the %s rate limit", delay, image, registry)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// ImageRegistry returns the registry host of the image reference, docker.io for the Docker Hub images
+func ImageRegistry(image string) string {
+	host, _, found := strings.Cut(image, "/")
+	if !found || !(strings.ContainsAny(host, ".:") || host == "localhost") {
+		return DockerHubRegistry
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return DockerHubRegistry
+	}
+	return host
+}
+
+// insecureRegistry returns true when the image is hosted by one of the insecure registries
+func insecureRegistry(registries []string, image string) bool {
+	registry := ImageRegistry(image)
+	for _, insecure := range registries {
+		if insecure == registry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry rate limiter", func() {
+
+	DescribeTable("resolves the registry of the image",
+		func(image, registry string) {
+			Expect(ImageRegistry(image)).To(Equal(registry))
+		},
+		Entry("official image", "alpine:3.18", "docker.io"),
+		Entry("Docker Hub user image", "bitnami/redis:7.0", "docker.io"),
+		Entry("Docker Hub host", "index.docker.io/library/alpine:3.18", "docker.io"),
+		Entry("registry host", "quay.io/prometheus/prometheus:v2.45.0", "quay.io"),
+		Entry("registry host with port", "registry-mirror:5000/api:1.0", "registry-mirror:5000"),
+		Entry("localhost", "localhost/api:1.0", "localhost"),
+	)
+
+	It("matches the images of the insecure registries by registry host", func() {
+		registries := []string{"registry.internal:5000", "docker.io"}
+
+		Expect(insecureRegistry(registries, "registry.internal:5000/api:1.0")).To(BeTrue())
+		Expect(insecureRegistry(registries, "alpine:3.18")).To(BeTrue())
+		Expect(insecureRegistry(registries, "registry.internal/api:1.0")).To(BeFalse())
+	})
+
+	It("spaces out the pulls of the rate limited registries only", func() {
+		limiter := newRegistryRateLimiter(map[string]int{"docker.io": 600})
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			Expect(limiter.wait(context.Background(), "alpine:3.18")).To(Succeed())
+			Expect(limiter.wait(context.Background(), "quay.io/api:1.0")).To(Succeed())
+		}
+
+		// 600 pulls per minute allow a docker.io pull every 100ms, the first one being immediate
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+	})
+
+	It("stops waiting when the context is done", func() {
+		limiter := newRegistryRateLimiter(map[string]int{"docker.io": 1})
+		ctx, cancel := context.WithCancel(context.Background())
+		Expect(limiter.wait(ctx, "alpine:3.18")).To(Succeed())
+
+		cancel()
+
+		Expect(limiter.wait(ctx, "alpine:3.18")).To(MatchError(context.Canceled))
+	})
+})
@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// noDigestDir is the directory of the raw trivy outputs of the images whose digest is unknown
+const noDigestDir = "no-digest"
+
+// saveRawTrivyOutput writes the raw json output of trivy for the image to the raw output directory and returns its
+// path. The output is compressed with gzip and organised by digest, for instance sha256/4ff3...json.gz, so that an
+// image run under several tags is saved once. The raw output is released afterwards. It returns an empty path when
+// nothing is saved or when the write fails, the scan results being reported anyway
+func (s *Scanner) saveRawTrivyOutput(imageName string, output *TrivyOutput) string {
+	if output == nil || len(output.Raw) == 0 {
+		return ""
+	}
+	raw := output.Raw
+	output.Raw = nil
+	if s.config.RawTrivyOutputDir == "" {
+		return ""
+	}
+	filename := filepath.Join(s.config.RawTrivyOutputDir, rawTrivyOutputFilename(imageName, output))
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Name = imageName + ".json"
+	writer.ModTime = output.CreatedAt
+	if _, err := writer.Write(raw); err != nil {
+		logr.Warnf("Could not compress the trivy output of image %s: %v", imageName, err)
+		return ""
+	}
+	if err := writer.Close(); err != nil {
+		logr.Warnf("Could not compress the trivy output of image %s: %v", imageName, err)
+		return ""
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		logr.Warnf("Could not create the raw trivy output directory %s: %v", filepath.Dir(filename), err)
+		return ""
+	}
+	if err := os.WriteFile(filename, compressed.Bytes(), 0644); err != nil {
+		logr.Warnf("Could not write the trivy output of image %s to %s: %v", imageName, filename, err)
+		return ""
+	}
+	return filename
+}
+
+// rawTrivyOutputFilename returns the filename of the raw trivy output of the image relative to the raw output
+// directory. The digest is the one of the image reference when it has one, else the repository digest trivy reports
+// for the repository of the image, for instance sha256/4ff3...json.gz. The images of unknown digest are saved by name,
+// for instance no-digest/registry_payments_web_1.2.json.gz. The platform of the image config is appended when known,
+// for instance sha256/4ff3..._linux_arm64.json.gz, as the platforms of a multi-platform image may resolve to the digest
+// of its manifest list and would overwrite each other
+func rawTrivyOutputFilename(imageName string, output *TrivyOutput) string {
+	digest := ""
+	if _, imageDigest, ok := strings.Cut(imageName, "@"); ok {
+		digest = imageDigest
+	} else {
+		repository := imageRepository(imageName)
+		for _, repoDigest := range output.Metadata.RepoDigests {
+			if name, repoDigest, ok := strings.Cut(repoDigest, "@"); ok && name == repository {
+				digest = repoDigest
+				break
+			}
+		}
+	}
+	suffix := ".json.gz"
+	if platform := output.Metadata.ImageConfig.Platform(); platform != "" {
+		suffix = "_" + unsafeFilenameCharacters.ReplaceAllString(platform, "_") + suffix
+	}
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || hex == "" {
+		return filepath.Join(noDigestDir, unsafeFilenameCharacters.ReplaceAllString(imageName, "_")+suffix)
+	}
+	return filepath.Join(unsafeFilenameCharacters.ReplaceAllString(algorithm, "_"), unsafeFilenameCharacters.ReplaceAllString(hex, "_")+suffix)
+}
+
+// imageRepository returns the repository of the image name, that is the name without its tag or digest
+func imageRepository(imageName string) string {
+	imageName, _, _ = strings.Cut(imageName, "@")
+	if i := strings.LastIndex(imageName, ":"); i > strings.LastIndex(imageName, "/") {
+		return imageName[:i]
+	}
+	return imageName
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RegistryCredentials are the credentials the images of a registry are pulled with
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// RegistryCredentialSource provides the credentials of the registries, for instance from a secret store, so that no
+// docker config holding the credentials is kept on disk
+type RegistryCredentialSource interface {
+	// Credentials returns the credentials of the registry, nil when the registry is pulled from with the credentials
+	// of the container runtime
+	Credentials(ctx context.Context, registry string) (*RegistryCredentials, error)
+}
+
+// withRegistryAuth runs the command reaching the registry of the image with the path of a docker config file holding
+// the credentials of the registry, empty when the source has none. The file is written to a temporary directory only
+// readable by the user and removed once the command completes
+func withRegistryAuth(ctx context.Context, source RegistryCredentialSource, image string, run func(authFile string) error) error {
+	if source == nil {
+		return run("")
+	}
+	registry := ImageRegistry(image)
+	credentials, err := source.Credentials(ctx, registry)
+	if err != nil {
+		return fmt.Errorf("could not read the credentials of registry %s: %v", registry, err)
+	}
+	if credentials == nil {
+		return run("")
+	}
+
+	dir, err := os.MkdirTemp("", "registry-auth-")
+	if err != nil {
+		return fmt.Errorf("could not create the auth file of registry %s: %v", registry, err)
+	}
+	defer os.RemoveAll(dir)
+	authFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(authFile, dockerConfig(registry, credentials), 0600); err != nil {
+		return fmt.Errorf("could not write the auth file of registry %s: %v", registry, err)
+	}
+	return run(authFile)
+}
+
+// dockerConfig returns the docker config holding the credentials of the registry, the format of the docker config.json
+// also read by podman --authfile. The docker.io credentials are recorded for the Docker Hub index docker expects
+func dockerConfig(registry string, credentials *RegistryCredentials) []byte {
+	server := registry
+	if registry == "docker.io" {
+		server = "https://index.docker.io/v1/"
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password))
+	content, _ := json.Marshal(map[string]map[string]map[string]string{"auths": {server: {"auth": auth}}})
+	return content
+}
+
+// dockerConfigEnv returns the environment of the docker commands reading the docker config of the auth file, nil for
+// the environment of the tool when there is no auth file
+func dockerConfigEnv(authFile string) []string {
+	if authFile == "" {
+		return nil
+	}
+	return append(os.Environ(), "DOCKER_CONFIG="+filepath.Dir(authFile))
+}
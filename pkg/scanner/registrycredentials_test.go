@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry credentials", func() {
+
+	source := registryCredentialsFunc(func(_ context.Context, registry string) (*RegistryCredentials, error) {
+		switch registry {
+		case "docker.io":
+			return &RegistryCredentials{Username: "scanner", Password: "s3cret"}, nil
+		case "registry.broken":
+			return nil, errors.New("permission denied")
+		}
+		return nil, nil
+	})
+
+	It("runs the commands with a temporary docker config holding the credentials of the registry", func() {
+		var authFile string
+		var config map[string]map[string]map[string]string
+		err := withRegistryAuth(context.Background(), source, "alpine:3.18", func(file string) error {
+			authFile = file
+			info, err := os.Stat(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			content, err := os.ReadFile(file)
+			Expect(err).NotTo(HaveOccurred())
+			return json.Unmarshal(content, &config)
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config["auths"]).To(Equal(map[string]map[string]string{"https://index.docker.io/v1/": {"auth": "c2Nhbm5lcjpzM2NyZXQ="}}))
+		Expect(filepath.Base(authFile)).To(Equal("config.json"))
+		_, err = os.Stat(filepath.Dir(authFile))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(dockerConfigEnv(authFile)).To(ContainElement("DOCKER_CONFIG=" + filepath.Dir(authFile)))
+	})
+
+	It("runs the commands with the credentials of the container runtime for the registries without credentials", func() {
+		authFile := "unset"
+		Expect(withRegistryAuth(context.Background(), source, "quay.io/prometheus/node-exporter:v1.6.0", func(file string) error {
+			authFile = file
+			return nil
+		})).To(Succeed())
+		Expect(authFile).To(BeEmpty())
+		Expect(dockerConfigEnv(authFile)).To(BeNil())
+	})
+
+	It("does not run the commands when the credentials cannot be read", func() {
+		err := withRegistryAuth(context.Background(), source, "registry.broken/api:1.0", func(string) error {
+			Fail("the command should not run")
+			return nil
+		})
+		Expect(err).To(MatchError("could not read the credentials of registry registry.broken: permission denied"))
+	})
+})
+
+type registryCredentialsFunc func(ctx context.Context, registry string) (*RegistryCredentials, error)
+
+func (f registryCredentialsFunc) Credentials(ctx context.Context, registry string) (*RegistryCredentials, error) {
+	return f(ctx, registry)
+}
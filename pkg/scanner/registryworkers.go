@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/gammazero/workerpool"
+)
+
+// registryPools runs the image scans on a pool of workers, the scans of the images of the registries with a worker
+// limit being first queued on a pool of the registry size. As the registry pools wait for a worker of the main pool,
+// the images waiting for their registry never hold a worker that the images of the other registries could use
+type registryPools struct {
+	pool       *priorityPool
+	registries map[string]*workerpool.WorkerPool
+}
+
+// newRegistryPools creates the pools of the workers and of the registry limits lower than the workers, the
+// registries of higher limits only being limited by the workers
+func newRegistryPools(workers int, registryWorkers map[string]int) *registryPools {
+	pools := &registryPools{pool: newPriorityPool(workers), registries: make(map[string]*workerpool.WorkerPool)}
+	for registry, limit := range registryWorkers {
+		if limit > 0 && limit < workers {
+			pools.registries[registry] = workerpool.New(limit)
+		}
+	}
+	return pools
+}
+
+// submit queues the scan of the image on the pool of its registry if limited, on the pool of the workers otherwise.
+// The workers run the queued scans by decreasing priority, see priorityPool
+func (p *registryPools) submit(imageName string, priority float64, task func()) {
+	registryPool, ok := p.registries[ImageRegistry(imageName)]
+	if !ok {
+		p.pool.submit(priority, task)
+		return
+	}
+	registryPool.Submit(func() {
+		p.pool.submitWait(priority, task)
+	})
+}
+
+// stopWait waits for the queued scans to complete, the registry pools being stopped before the pool they submit to
+func (p *registryPools) stopWait() {
+	for _, registryPool := range p.registries {
+		registryPool.StopWait()
+	}
+	p.pool.stopWait()
+}
+
+// priorityPool is a pool of workers running the queued tasks by decreasing priority, the tasks of equal priority in
+// the order they are queued, so that the tasks queued late, such as the scans waiting for their registry, still run
+// before the queued tasks of lower priority
+type priorityPool struct {
+	lock    sync.Mutex
+	queued  *sync.Cond
+	tasks   priorityTasks
+	count   int
+	stopped bool
+	workers sync.WaitGroup
+}
+
+type priorityTask struct {
+	priority float64
+	// order is the order the task was queued in, among the tasks of the same priority
+	order int
+	run   func()
+}
+
+// priorityTasks is a heap of the tasks, the task of highest priority first
+type priorityTasks []priorityTask
+
+func (t priorityTasks) Len() int { return len(t) }
+
+func (t priorityTasks) Less(i, j int) bool {
+	if t[i].priority != t[j].priority {
+		return t[i].priority > t[j].priority
+	}
+	return t[i].order < t[j].order
+}
+
+func (t priorityTasks) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+
+func (t *priorityTasks) Push(task interface{}) { *t = append(*t, task.(priorityTask)) }
+
+func (t *priorityTasks) Pop() interface{} {
+	old := *t
+	task := old[len(old)-1]
+	*t = old[:len(old)-1]
+	return task
+}
+
+// newPriorityPool starts the workers of the pool, at least one
+func newPriorityPool(workers int) *priorityPool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &priorityPool{}
+	p.queued = sync.NewCond(&p.lock)
+	for i := 0; i < workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// work runs the queued tasks until the pool is stopped and no task is left
+func (p *priorityPool) work() {
+	defer p.workers.Done()
+	for {
+		p.lock.Lock()
+		for len(p.tasks) == 0 && !p.stopped {
+			p.queued.Wait()
+		}
+		if len(p.tasks) == 0 {
+			p.lock.Unlock()
+			return
+		}
+		task := heap.Pop(&p.tasks).(priorityTask)
+		p.lock.Unlock()
+		task.run()
+	}
+}
+
+// submit queues the task with its priority
+func (p *priorityPool) submit(priority float64, task func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	heap.Push(&p.tasks, priorityTask{priority: priority, order: p.count, run: task})
+	p.count++
+	p.queued.Signal()
+}
+
+// submitWait queues the task with its priority and waits for it to complete
+func (p *priorityPool) submitWait(priority float64, task func()) {
+	done := make(chan struct{})
+	p.submit(priority, func() {
+		defer close(done)
+		task()
+	})
+	<-done
+}
+
+// stopWait waits for the queued tasks to complete and stops the workers
+func (p *priorityPool) stopWait() {
+	p.lock.Lock()
+	p.stopped = true
+	p.queued.Broadcast()
+	p.lock.Unlock()
+	p.workers.Wait()
+}
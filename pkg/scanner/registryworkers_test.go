@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry workers", func() {
+
+	It("limits the images of each registry scanned concurrently within the workers", func() {
+		pools := newRegistryPools(4, map[string]int{"docker.io": 1, "registry.internal": 10})
+
+		var (
+			lock                 sync.Mutex
+			running, maxRunning  = make(map[string]int), make(map[string]int)
+			total, maxTotal, ran int
+		)
+		for _, image := range []string{"alpine:3.18", "redis:7", "nginx:1.25", "quay.io/api:1", "quay.io/web:1", "registry.internal/db:1", "registry.internal/job:1"} {
+			registry := ImageRegistry(image)
+			pools.submit(image, 0, func() {
+				lock.Lock()
+				running[registry]++
+				total++
+				if running[registry] > maxRunning[registry] {
+					maxRunning[registry] = running[registry]
+				}
+				if total > maxTotal {
+					maxTotal = total
+				}
+				lock.Unlock()
+
+				time.Sleep(50 * time.Millisecond)
+
+				lock.Lock()
+				running[registry]--
+				total--
+				ran++
+				lock.Unlock()
+			})
+		}
+		pools.stopWait()
+
+		Expect(ran).To(Equal(7))
+		Expect(maxRunning["docker.io"]).To(Equal(1))
+		Expect(maxTotal).To(BeNumerically("<=", 4))
+		Expect(pools.registries).To(HaveKey("docker.io"))
+		Expect(pools.registries).NotTo(HaveKey("registry.internal"))
+	})
+
+	It("runs the queued scans by decreasing priority, those of equal priority in the order they are queued", func() {
+		pool := newPriorityPool(1)
+		var (
+			lock sync.Mutex
+			ran  []string
+		)
+		run := func(name string) func() {
+			return func() {
+				lock.Lock()
+				defer lock.Unlock()
+				ran = append(ran, name)
+			}
+		}
+		blocked := make(chan struct{})
+		pool.submit(0, func() { <-blocked })
+		pool.submit(1, run("low"))
+		pool.submit(3, run("high"))
+		pool.submit(2, run("medium"))
+		pool.submit(3, run("high again"))
+		close(blocked)
+		pool.stopWait()
+
+		Expect(ran).To(Equal([]string{"high", "high again", "medium", "low"}))
+	})
+})
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"sort"
+)
+
+// osPackagesClass is the trivy class of the results of the packages of the operating system of the image
+const osPackagesClass = "os-pkgs"
+
+// RemediationPlan is the minimal set of upgrades clearing the fixable vulnerabilities of an image
+type RemediationPlan struct {
+	// BaseImage suggests a base image bump clearing the vulnerabilities of the operating system packages, nil when
+	// none of them is fixable and the operating system is supported
+	BaseImage *BaseImageBump
+	// Upgrades are the upgrades of the other packages, each package being upgraded to the version fixing all its
+	// vulnerabilities, sorted by decreasing number of vulnerabilities cleared
+	Upgrades []PackageUpgrade
+}
+
+// BaseImageBump is the rebuild of the image on a newer base image, of a supported release when the operating system
+// of the image reached its end of life
+type BaseImageBump struct {
+	OS OS
+	// FindingCount is the number of fixable vulnerabilities of the operating system packages the bump clears
+	FindingCount int
+	PackageCount int
+}
+
+// PackageUpgrade is the upgrade of a package of a target of the image, for instance a jar or a lock file
+type PackageUpgrade struct {
+	Target           string
+	PkgName          string
+	InstalledVersion string
+	FixedVersion     string
+	// FindingCount is the number of vulnerabilities the upgrade clears and Severity the highest of their severities
+	FindingCount int
+	Severity     string
+}
+
+// ImageRemediation is the remediation plan of an image
+type ImageRemediation struct {
+	ImageName string
+	Plan      *RemediationPlan
+}
+
+// RemediationPlan returns the upgrades clearing the fixable vulnerabilities of the image, nil when there is nothing
+// to upgrade. The vulnerabilities of the operating system packages are cleared by a base image bump when the
+// operating system of the image is known, their packages being upgraded individually otherwise
+func (i ScannedImage) RemediationPlan() *RemediationPlan {
+	plan := &RemediationPlan{}
+	if i.EndOfLife() {
+		plan.BaseImage = &BaseImageBump{OS: *i.OS}
+	}
+	upgrades := make(map[[2]string]*PackageUpgrade)
+	osPackages := make(map[string]bool)
+	seen := make(map[findingKey]bool)
+	for _, target := range i.Results() {
+		for _, vulnerability := range target.Vulnerabilities {
+			key := findingKey{target.Target, vulnerability.VulnerabilityID, vulnerability.PkgName}
+			if !vulnerability.Fixable() || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if target.Class == osPackagesClass && i.OS != nil {
+				if plan.BaseImage == nil {
+					plan.BaseImage = &BaseImageBump{OS: *i.OS}
+				}
+				plan.BaseImage.FindingCount++
+				osPackages[vulnerability.PkgName] = true
+				continue
+			}
+			fixedVersion := upgradeVersion(vulnerability.InstalledVersion, vulnerability.FixedVersion)
+			upgrade, ok := upgrades[[2]string{target.Target, vulnerability.PkgName}]
+			if !ok {
+				upgrade = &PackageUpgrade{Target: target.Target, PkgName: vulnerability.PkgName, InstalledVersion: vulnerability.InstalledVersion, FixedVersion: fixedVersion}
+				upgrades[[2]string{target.Target, vulnerability.PkgName}] = upgrade
+			}
+			if compareVersions(fixedVersion, upgrade.FixedVersion) > 0 {
+				upgrade.FixedVersion = fixedVersion
+			}
+			if severityScores[vulnerability.Severity] > severityScores[upgrade.Severity] {
+				upgrade.Severity = vulnerability.Severity
+			}
+			upgrade.FindingCount++
+		}
+	}
+	if plan.BaseImage != nil {
+		plan.BaseImage.PackageCount = len(osPackages)
+	}
+	for _, upgrade := range upgrades {
+		plan.Upgrades = append(plan.Upgrades, *upgrade)
+	}
+	sort.Slice(plan.Upgrades, func(i, j int) bool {
+		a, b := plan.Upgrades[i], plan.Upgrades[j]
+		if a.FindingCount != b.FindingCount {
+			return a.FindingCount > b.FindingCount
+		}
+		if a.Severity != b.Severity {
+			return severityScores[a.Severity] > severityScores[b.Severity]
+		}
+		if a.PkgName != b.PkgName {
+			return a.PkgName < b.PkgName
+		}
+		return a.Target < b.Target
+	})
+	if plan.BaseImage == nil && len(plan.Upgrades) == 0 {
+		return nil
+	}
+	return plan
+}
+
+// RemediationPlans returns the remediation plans of the team images with something to upgrade, in the order of the images
+func (t *TeamSummary) RemediationPlans() []ImageRemediation {
+	var remediations []ImageRemediation
+	for _, image := range t.Images {
+		if plan := image.RemediationPlan(); plan != nil {
+			remediations = append(remediations, ImageRemediation{ImageName: image.ImageName, Plan: plan})
+		}
+	}
+	return remediations
+}
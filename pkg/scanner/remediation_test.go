@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Remediation plan", func() {
+
+	var image ScannedImage
+
+	BeforeEach(func() {
+		image = NewScannedImage("api:1.0", nil, []TrivyOutputResults{
+			{Target: "api:1.0 (debian 12.1)", Class: "os-pkgs", Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-1", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.10", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-2", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.11", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-3", PkgName: "libc6", InstalledVersion: "2.36-9", FixedVersion: "2.36-9+deb12u3", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-4", PkgName: "zlib", InstalledVersion: "1.2.13", Severity: "LOW"},
+			}},
+			{Target: "app/app.jar", Class: "lang-pkgs", Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-5", PkgName: "jackson-databind", InstalledVersion: "2.13.0", FixedVersion: "2.12.7.1, 2.13.4.1", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-6", PkgName: "jackson-databind", InstalledVersion: "2.13.0", FixedVersion: "2.13.4", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-7", PkgName: "snakeyaml", InstalledVersion: "1.33", FixedVersion: "2.0", Severity: "MEDIUM"},
+			}},
+		}, nil)
+		image.OS = &OS{Family: "debian", Name: "12.1"}
+	})
+
+	It("suggests a base image bump for the operating system packages and the upgrades of the other packages", func() {
+		Expect(image.RemediationPlan()).To(Equal(&RemediationPlan{
+			BaseImage: &BaseImageBump{OS: OS{Family: "debian", Name: "12.1"}, FindingCount: 3, PackageCount: 2},
+			Upgrades: []PackageUpgrade{
+				{Target: "app/app.jar", PkgName: "jackson-databind", InstalledVersion: "2.13.0", FixedVersion: "2.13.4.1", FindingCount: 2, Severity: "CRITICAL"},
+				{Target: "app/app.jar", PkgName: "snakeyaml", InstalledVersion: "1.33", FixedVersion: "2.0", FindingCount: 1, Severity: "MEDIUM"},
+			},
+		}))
+	})
+
+	It("upgrades the operating system packages individually when the operating system is unknown", func() {
+		image.OS = nil
+
+		plan := image.RemediationPlan()
+
+		Expect(plan.BaseImage).To(BeNil())
+		Expect(plan.Upgrades).To(HaveLen(4))
+		Expect(plan.Upgrades).To(ContainElement(PackageUpgrade{Target: "api:1.0 (debian 12.1)", PkgName: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.11", FindingCount: 2, Severity: "CRITICAL"}))
+	})
+
+	It("suggests a supported base image when the operating system reached its end of life", func() {
+		image = NewScannedImage("legacy:1.0", nil, []TrivyOutputResults{{Target: "legacy:1.0 (debian 9.13)", Class: "os-pkgs", Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-4", PkgName: "zlib", InstalledVersion: "1.2.8", Severity: "LOW"},
+		}}}, nil)
+		image.OS = &OS{Family: "debian", Name: "9.13", EOSL: true}
+
+		Expect(image.RemediationPlan()).To(Equal(&RemediationPlan{BaseImage: &BaseImageBump{OS: OS{Family: "debian", Name: "9.13", EOSL: true}}}))
+	})
+
+	It("has no plan when nothing is fixable", func() {
+		image = NewScannedImage("static:1.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-4", PkgName: "zlib", InstalledVersion: "1.2.13", Severity: "LOW"},
+		}}}, nil)
+
+		Expect(image.RemediationPlan()).To(BeNil())
+		Expect((&TeamSummary{Images: []ScannedImage{image}}).RemediationPlans()).To(BeEmpty())
+	})
+})
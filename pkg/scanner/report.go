@@ -2,14 +2,49 @@ package scanner
 
 import (
 	"sort"
+	"time"
 
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
 )
 
 // VulnerabilityReport is top level structure holding the results of the image scan
 type VulnerabilityReport struct {
+	Metadata      ReportMetadata
 	ScannedImages []ScannedImage
 	AreaSummary   map[string]*AreaSummary
+	// ScanOptOuts are the workloads opted out of the scans, listed so that the opt-outs remain visible
+	ScanOptOuts []ScanOptOut `json:",omitempty"`
+	// Suppressions are the suppressions declared by the namespaces with the number of vulnerabilities each suppressed,
+	// listed so that the suppressions remain visible
+	Suppressions []k8s.SuppressionInEffect `json:",omitempty"`
+	// FixLatencies are the times the teams took to fix the vulnerabilities of each severity over the last scans, read
+	// from the vulnerability history, see VulnerabilityHistory.FixLatencies
+	FixLatencies []FixLatency `json:",omitempty"`
+}
+
+// ReportMetadata describes where and how the images were scanned so that reports are self-describing and comparable
+type ReportMetadata struct {
+	// ClusterName and KubernetesVersion are empty when the images are not scanned from a cluster
+	ClusterName       string
+	KubernetesVersion string
+	ScanTime          time.Time
+	TrivyVersion      string
+	TrivyDBVersion    int
+	TrivyDBUpdatedAt  time.Time
+	// Incomplete is true when the scan was interrupted, the report only holding the images scanned before
+	Incomplete bool
+	// FailFastFinding is the vulnerability that stopped the scan, for instance CRITICAL CVE-2024-0001 (openssl) in
+	// api:1.2, empty unless the scan failed fast, see Config.FailFast
+	FailFastFinding string `json:",omitempty"`
+	// ChangedSince is the time since which the pods of the scanned images were created or updated, nil when all the
+	// images are scanned, see Config.Since
+	ChangedSince *time.Time `json:",omitempty"`
+}
+
+// Partial returns true when the report does not hold all the images, the scan being interrupted or restricted to the
+// images changed since ChangedSince, so that the images missing from the report are not known to be gone
+func (m ReportMetadata) Partial() bool {
+	return m.Incomplete || m.ChangedSince != nil
 }
 
 // AreaSummary holds the summary of the vulnerabilities of the teams
@@ -19,6 +54,8 @@ type AreaSummary struct {
 	ImageCount                   int
 	ContainerCount               int
 	TotalVulnerabilityBySeverity map[string]int
+	// Budget is the consumption of the severity budget of the area, nil when no area budget applies to the area
+	Budget *BudgetConsumption `json:",omitempty"`
 }
 
 // TeamSummary defines the summary for an team
@@ -28,16 +65,47 @@ type TeamSummary struct {
 	Containers     []k8s.ContainerSummary
 	ImageCount     int
 	ContainerCount int
+	// Budget is the consumption of the severity budget of the team, nil when no budget applies to the team
+	Budget *BudgetConsumption `json:",omitempty"`
+	// SLABreaches are the vulnerabilities of the team images past their remediation SLA, see VulnerabilityReport.ApplyRemediationSLAs
+	SLABreaches []SLABreach `json:",omitempty"`
+	// PreviousSeverityScore is the severity score of the team a week before the scan, nil when the vulnerability history
+	// holds no score of the team that old, see VulnerabilityReport.Scoreboard
+	PreviousSeverityScore *int `json:",omitempty"`
 }
 
+// Grouping modes of the images in the report, see AreaReport.GroupBy
+const (
+	// GroupByLabels groups the images by the area and team namespace labels
+	GroupByLabels = "labels"
+	// GroupByNamespace groups the images by namespace
+	GroupByNamespace = "namespace"
+	// GroupByWorkload groups the images by namespace and by the workload running them, for instance deployment/web
+	GroupByWorkload = "workload"
+)
+
 // AreaReport generates a report grouped by area and team
 type AreaReport struct {
 	AreaLabelName string
 	TeamLabelName string
+	// GroupBy is the grouping mode of the images, GroupByLabels when empty. With GroupByNamespace the namespaces are
+	// both the areas and the teams, with GroupByWorkload the namespaces are the areas and their workloads the teams
+	GroupBy string
+	// Ownership attributes the containers whose area or team labels are missing when grouping by labels
+	Ownership *OwnershipMapping
+	// Budgets are the severity budgets the consumption of each area and team is reported against
+	Budgets *SeverityBudgets
+	// ScoringMode is the weighting of the severity score the images are ranked by, ScoringImages when empty.
+	// ScoringContainers weights the score by the containers running the image and ScoringWorkloads by the
+	// distinct workloads, so that the DaemonSet images are not ranked by their number of nodes
+	ScoringMode string
 }
 
-// GenerateVulnerabilityReport generates a vulnerability report grouping images by
+// GenerateVulnerabilityReport generates a vulnerability report grouping images by area and team
 func (r *AreaReport) GenerateVulnerabilityReport(scannedImages []ScannedImage) (*VulnerabilityReport, error) {
+	if r.ScoringMode != "" {
+		applyScoringMode(scannedImages, r.ScoringMode)
+	}
 	imagesByArea, err := r.generateAreaGrouping(scannedImages)
 	if err != nil {
 		return nil, err
@@ -48,12 +116,50 @@ func (r *AreaReport) GenerateVulnerabilityReport(scannedImages []ScannedImage) (
 	}, nil
 }
 
+// TimedOutImageCount returns the number of images whose scan timed out, so that the scan timeout or the number of
+// workers can be tuned
+func (r *VulnerabilityReport) TimedOutImageCount() int {
+	count := 0
+	for _, i := range r.ScannedImages {
+		if i.TimedOut {
+			count++
+		}
+	}
+	return count
+}
+
+// SplitByTeam returns one report per team name holding only the images of the team, so that each team can be
+// sent its own findings. A team present in several areas gets a single report with one summary per area
+func (r *VulnerabilityReport) SplitByTeam() map[string]*VulnerabilityReport {
+	reports := make(map[string]*VulnerabilityReport)
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			teamReport, ok := reports[team.Name]
+			if !ok {
+				teamReport = &VulnerabilityReport{
+					Metadata:    r.Metadata,
+					AreaSummary: make(map[string]*AreaSummary),
+				}
+				reports[team.Name] = teamReport
+			}
+			areaSummary := &AreaSummary{
+				Name:  area.Name,
+				Teams: map[string]*TeamSummary{team.Name: team},
+			}
+			areaSummary.aggregate(team)
+			teamReport.AreaSummary[area.Name] = areaSummary
+			teamReport.ScannedImages = append(teamReport.ScannedImages, team.Images...)
+		}
+	}
+	return reports
+}
+
 type teamKey struct {
 	area, team string
 }
 
 func (r *AreaReport) generateAreaGrouping(scannedImages []ScannedImage) (map[string]*AreaSummary, error) {
-	imageByTeam := groupImagesByTeam(scannedImages, r.AreaLabelName, r.TeamLabelName)
+	imageByTeam := groupImagesByTeam(scannedImages, r.teamOf)
 	var summaryByArea = make(map[string]*AreaSummary)
 	for teamID, teamImageMap := range imageByTeam {
 		if _, ok := summaryByArea[teamID.area]; !ok {
@@ -64,9 +170,13 @@ func (r *AreaReport) generateAreaGrouping(scannedImages []ScannedImage) (map[str
 		}
 
 		teamSummary := buildTeamSummary(teamImageMap, teamID)
+		teamSummary.Budget = r.Budgets.consumption(teamID.area, teamSummary)
 		summaryByArea[teamID.area].Teams[teamID.team] = teamSummary
 		summaryByArea[teamID.area].aggregate(teamSummary)
 	}
+	for _, area := range summaryByArea {
+		area.Budget = r.Budgets.areaConsumption(area)
+	}
 
 	return summaryByArea, nil
 }
@@ -84,6 +194,37 @@ func (a *AreaSummary) aggregate(teamSummary *TeamSummary) {
 	}
 }
 
+// TotalVulnerabilityBySeverity returns the vulnerability count of the team images by severity
+func (t *TeamSummary) TotalVulnerabilityBySeverity() map[string]int {
+	total := make(map[string]int)
+	for _, i := range t.Images {
+		for severity, count := range i.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+			total[severity] += count
+		}
+	}
+	return total
+}
+
+// FixableVulnerabilityBySeverity returns the count of the team images vulnerabilities with a fixed version by severity
+func (t *TeamSummary) FixableVulnerabilityBySeverity() map[string]int {
+	total := make(map[string]int)
+	for _, i := range t.Images {
+		for severity, count := range i.VulnerabilitySummary.FixableVulnerabilityBySeverity {
+			total[severity] += count
+		}
+	}
+	return total
+}
+
+// FixableCount returns the number of vulnerabilities of the team images with a fixed version
+func (t *TeamSummary) FixableCount() int {
+	var count int
+	for _, i := range t.Images {
+		count += i.VulnerabilitySummary.FixableCount
+	}
+	return count
+}
+
 // HasScanErrors returns true when one or more team images has scan errors
 func (t *TeamSummary) HasScanErrors() bool {
 	for _, i := range t.Images {
@@ -105,33 +246,114 @@ func (t *TeamSummary) ScanErrors() []error {
 	return errors
 }
 
-func groupImagesByTeam(allImages []ScannedImage, areaLabelName, teamLabelName string) map[teamKey]map[string]*ScannedImage {
-	imageByTeam := make(map[teamKey]map[string]*ScannedImage)
-	var areaLabel, teamsLabel string
-	for _, i := range allImages {
-		for _, c := range i.Containers {
-			areaLabel = c.NamespaceLabels[areaLabelName]
-			teamsLabel = c.NamespaceLabels[teamLabelName]
+// SkippedImages returns the team images not scanned as larger than the maximum image size
+func (t *TeamSummary) SkippedImages() []ScannedImage {
+	var skipped []ScannedImage
+	for _, i := range t.Images {
+		if i.Skipped {
+			skipped = append(skipped, i)
+		}
+	}
+	return skipped
+}
+
+// EndOfLifeImages returns the team images whose operating system reached its end of life
+func (t *TeamSummary) EndOfLifeImages() []ScannedImage {
+	var images []ScannedImage
+	for _, i := range t.Images {
+		if i.EndOfLife() {
+			images = append(images, i)
+		}
+	}
+	return images
+}
 
-			if areaLabel == "" {
-				areaLabel = "all"
+// SecretFinding is a secret found in a file of an image
+type SecretFinding struct {
+	ImageName string
+	// Target is the path of the file holding the secret
+	Target string
+	Secret Secret
+}
+
+// Secrets returns the secrets found in the team images, when the secret scanner is enabled
+func (t *TeamSummary) Secrets() []SecretFinding {
+	var findings []SecretFinding
+	for _, i := range t.Images {
+		for _, result := range i.Results() {
+			for _, secret := range result.Secrets {
+				findings = append(findings, SecretFinding{ImageName: i.ImageName, Target: result.Target, Secret: secret})
 			}
-			if teamsLabel == "" {
-				teamsLabel = "all"
+		}
+	}
+	return findings
+}
+
+// LicenseFinding is a package license of an image violating the license policy
+type LicenseFinding struct {
+	ImageName string
+	License   License
+}
+
+// LicenseViolations returns the package licenses of the team images violating the license policy, when the license
+// scanner is enabled
+func (t *TeamSummary) LicenseViolations() []LicenseFinding {
+	var findings []LicenseFinding
+	for _, i := range t.Images {
+		for _, result := range i.Results() {
+			for _, license := range result.Licenses {
+				if license.Violation != "" {
+					findings = append(findings, LicenseFinding{ImageName: i.ImageName, License: license})
+				}
 			}
+		}
+	}
+	return findings
+}
+
+// teamOf returns the area and team of the container according to the grouping mode, all when unknown
+func (r *AreaReport) teamOf(c k8s.ContainerSummary) teamKey {
+	var area, team string
+	switch r.GroupBy {
+	case GroupByNamespace:
+		area, team = c.Namespace, c.Namespace
+	case GroupByWorkload:
+		area, team = c.Namespace, c.Workload
+	default:
+		area = k8s.LabelValue(r.AreaLabelName, c.PodLabels, c.NamespaceLabels)
+		team = k8s.LabelValue(r.TeamLabelName, c.PodLabels, c.NamespaceLabels)
+		if area == "" || team == "" {
+			ownerArea, ownerTeam := r.Ownership.ownerOf(c)
+			if area == "" {
+				area = ownerArea
+			}
+			if team == "" {
+				team = ownerTeam
+			}
+		}
+	}
+	if area == "" {
+		area = "all"
+	}
+	if team == "" {
+		team = "all"
+	}
+	return teamKey{area: area, team: team}
+}
 
-			teamID := teamKey{area: areaLabel, team: teamsLabel}
+func groupImagesByTeam(allImages []ScannedImage, teamOf func(k8s.ContainerSummary) teamKey) map[teamKey]map[string]*ScannedImage {
+	imageByTeam := make(map[teamKey]map[string]*ScannedImage)
+	for _, i := range allImages {
+		for _, c := range i.Containers {
+			teamID := teamOf(c)
 			if _, ok := imageByTeam[teamID]; !ok {
 				imageByTeam[teamID] = make(map[string]*ScannedImage)
 			}
 			if _, ok := imageByTeam[teamID][i.ImageName]; !ok {
-				imageByTeam[teamID][i.ImageName] = &ScannedImage{
-					ImageName:            i.ImageName,
-					TrivyOutputResults:   i.TrivyOutputResults,
-					VulnerabilitySummary: i.VulnerabilitySummary,
-					Containers:           nil,
-					ScanError:            i.ScanError,
-				}
+				// the team image holds all the fields of the image, only its containers being those of the team
+				teamImage := i
+				teamImage.Containers = nil
+				imageByTeam[teamID][i.ImageName] = &teamImage
 			}
 			imageByTeam[teamID][i.ImageName].Containers = append(imageByTeam[teamID][i.ImageName].Containers, c)
 		}
@@ -140,11 +362,17 @@ func groupImagesByTeam(allImages []ScannedImage, areaLabelName, teamLabelName st
 }
 
 func buildTeamSummary(teamImageMap map[string]*ScannedImage, teamID teamKey) *TeamSummary {
+	var imageNames []string
+	for imageName := range teamImageMap {
+		imageNames = append(imageNames, imageName)
+	}
+	sort.Strings(imageNames)
+
 	var teamContainers []k8s.ContainerSummary
 	var teamImages []ScannedImage
-	for _, scannedImage := range teamImageMap {
-		teamImages = append(teamImages, *scannedImage)
-		teamContainers = append(teamContainers, scannedImage.Containers...)
+	for _, imageName := range imageNames {
+		teamImages = append(teamImages, *teamImageMap[imageName])
+		teamContainers = append(teamContainers, teamImageMap[imageName].Containers...)
 	}
 
 	return &TeamSummary{
@@ -156,8 +384,9 @@ func buildTeamSummary(teamImageMap map[string]*ScannedImage, teamID teamKey) *Te
 	}
 }
 
+// sortBySeverity sorts the images by decreasing severity, images with the same severity keeping their order
 func sortBySeverity(scannedImages []ScannedImage) []ScannedImage {
-	sort.Slice(scannedImages, func(i, j int) bool {
+	sort.SliceStable(scannedImages, func(i, j int) bool {
 		return scannedImages[i].VulnerabilitySummary.SeverityScore > scannedImages[j].VulnerabilitySummary.SeverityScore
 	})
 	return scannedImages
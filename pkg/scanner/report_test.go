@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"time"
 
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
 
@@ -144,6 +145,34 @@ var _ = Describe("Vulnerability report", func() {
 			Expect(imageByArea["area1"].Teams["team2"].Containers[0].PodName).Should(Equal("pod3"))
 		})
 
+		It("keeps all the fields of the images in the team images", func() {
+			scanTime := time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+			scannedImages := []ScannedImage{
+				{
+					ImageName: "image1",
+					Containers: []k8s.ContainerSummary{
+						{NamespaceLabels: map[string]string{areaLabel: "area1", teamLabel: "team1"}, PodName: "pod1"},
+						{NamespaceLabels: map[string]string{areaLabel: "area1", teamLabel: "team2"}, PodName: "pod2"},
+					},
+					TimedOut:  true,
+					Exposure:  k8s.ExposureInternet,
+					Platforms: []string{"linux/amd64", "linux/arm64"},
+					ScanTime:  &scanTime,
+					Stale:     true,
+					SBOMFile:  "sbom/image1.cdx.json",
+				},
+			}
+
+			imageByArea, err := reportGenerator.generateAreaGrouping(scannedImages)
+
+			Expect(err).NotTo(HaveOccurred())
+			teamImage := imageByArea["area1"].Teams["team1"].Images[0]
+			expected := scannedImages[0]
+			expected.Containers = scannedImages[0].Containers[:1]
+			Expect(teamImage).To(Equal(expected))
+			Expect(imageByArea["area1"].Teams["team2"].StaleImages()).To(HaveLen(1))
+		})
+
 		It("sort teams images by severity", func() {
 			team1Pod := k8s.ContainerSummary{
 				Namespace:       "namespace1",
@@ -421,6 +450,109 @@ var _ = Describe("Vulnerability report", func() {
 			Expect(images[0].ScanError).Should(Equal(fmt.Errorf("error occurred during scan")))
 			Expect(images[1].ScanError).Should(BeNil())
 		})
+
+		It("uses the first label set, on the pod or else on the namespace", func() {
+			reportGenerator.AreaLabelName = "area," + areaLabel
+			reportGenerator.TeamLabelName = "team, " + teamLabel
+			scannedImages := []ScannedImage{
+				{ImageName: "api:1", Containers: []k8s.ContainerSummary{
+					{Namespace: "payments", PodName: "api", PodLabels: map[string]string{"team": "api-team"},
+						NamespaceLabels: map[string]string{areaLabel: "area1", teamLabel: "team1"}},
+				}},
+				{ImageName: "web:1", Containers: []k8s.ContainerSummary{
+					{Namespace: "payments", PodName: "web", PodLabels: map[string]string{"app": "web"},
+						NamespaceLabels: map[string]string{areaLabel: "area1", teamLabel: "team1"}},
+				}},
+				{ImageName: "debug:1", Containers: []k8s.ContainerSummary{
+					{Namespace: "default", PodName: "debug"},
+				}},
+			}
+
+			imageByArea, err := reportGenerator.generateAreaGrouping(scannedImages)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imageByArea).To(HaveLen(2))
+			Expect(imageByArea["area1"].Teams["api-team"].Images[0].ImageName).To(Equal("api:1"))
+			Expect(imageByArea["area1"].Teams["team1"].Images[0].ImageName).To(Equal("web:1"))
+			Expect(imageByArea["all"].Teams["all"].Images[0].ImageName).To(Equal("debug:1"))
+		})
+
+		Context("grouping by namespace or workload rather than labels", func() {
+			var scannedImages []ScannedImage
+
+			BeforeEach(func() {
+				scannedImages = []ScannedImage{
+					{ImageName: "api:1", Containers: []k8s.ContainerSummary{
+						{Namespace: "payments", PodName: "api-5d8f-x2k9z", Workload: "deployment/api"},
+						{Namespace: "payments", PodName: "api-5d8f-q7x2p", Workload: "deployment/api"},
+					}},
+					{ImageName: "busybox:1", Containers: []k8s.ContainerSummary{
+						{Namespace: "payments", PodName: "cleanup-28100-abcde", Workload: "cronjob/cleanup"},
+						{Namespace: "orders", PodName: "debug", Workload: "pod/debug", NamespaceLabels: map[string]string{areaLabel: "area1", teamLabel: "team1"}},
+					}},
+				}
+			})
+
+			It("groups images per namespace", func() {
+				reportGenerator.GroupBy = GroupByNamespace
+
+				imageByArea, err := reportGenerator.generateAreaGrouping(scannedImages)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imageByArea).To(HaveLen(2))
+				Expect(imageByArea["payments"].Teams).To(HaveLen(1))
+				Expect(imageByArea["payments"].Teams["payments"].ImageCount).To(Equal(2))
+				Expect(imageByArea["orders"].Teams["orders"].Images[0].ImageName).To(Equal("busybox:1"))
+			})
+
+			It("groups images per namespace and workload", func() {
+				reportGenerator.GroupBy = GroupByWorkload
+
+				imageByArea, err := reportGenerator.generateAreaGrouping(scannedImages)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imageByArea["payments"].Teams).To(HaveLen(2))
+				Expect(imageByArea["payments"].Teams["deployment/api"].ContainerCount).To(Equal(2))
+				Expect(imageByArea["payments"].Teams["cronjob/cleanup"].Images[0].ImageName).To(Equal("busybox:1"))
+				Expect(imageByArea["orders"].Teams["pod/debug"].ImageCount).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("SplitByTeam", func() {
+		It("returns a report per team with the team images only", func() {
+			image1 := ScannedImage{ImageName: "image1", VulnerabilitySummary: VulnerabilitySummary{TotalVulnerabilityBySeverity: map[string]int{"HIGH": 1}}}
+			image2 := ScannedImage{ImageName: "image2", VulnerabilitySummary: VulnerabilitySummary{TotalVulnerabilityBySeverity: map[string]int{"HIGH": 2}}}
+			image3 := ScannedImage{ImageName: "image3", VulnerabilitySummary: VulnerabilitySummary{TotalVulnerabilityBySeverity: map[string]int{"LOW": 3}}}
+			report := &VulnerabilityReport{
+				Metadata:      ReportMetadata{ClusterName: "sandbox"},
+				ScannedImages: []ScannedImage{image1, image2, image3},
+				AreaSummary: map[string]*AreaSummary{
+					"area1": {Name: "area1", Teams: map[string]*TeamSummary{
+						"team1": {Name: "team1", Images: []ScannedImage{image1}, ImageCount: 1, ContainerCount: 2},
+						"team2": {Name: "team2", Images: []ScannedImage{image2}, ImageCount: 1, ContainerCount: 1},
+					}},
+					"area2": {Name: "area2", Teams: map[string]*TeamSummary{
+						"team1": {Name: "team1", Images: []ScannedImage{image3}, ImageCount: 1, ContainerCount: 1},
+					}},
+				},
+			}
+
+			// when
+			reports := report.SplitByTeam()
+
+			// then
+			Expect(reports).To(HaveLen(2))
+			Expect(reports["team1"].Metadata.ClusterName).To(Equal("sandbox"))
+			Expect(reports["team1"].ScannedImages).To(ConsistOf(image1, image3))
+			Expect(reports["team1"].AreaSummary).To(HaveLen(2))
+			Expect(reports["team1"].AreaSummary["area1"].Teams).To(HaveKey("team1"))
+			Expect(reports["team1"].AreaSummary["area1"].Teams).NotTo(HaveKey("team2"))
+			Expect(reports["team1"].AreaSummary["area1"].ContainerCount).To(Equal(2))
+			Expect(reports["team1"].AreaSummary["area1"].TotalVulnerabilityBySeverity).To(Equal(map[string]int{"HIGH": 1}))
+			Expect(reports["team2"].ScannedImages).To(ConsistOf(image2))
+			Expect(reports["team2"].AreaSummary["area1"].TotalVulnerabilityBySeverity).To(Equal(map[string]int{"HIGH": 2}))
+		})
 	})
 
 	Describe("Team summary", func() {
@@ -467,6 +599,29 @@ var _ = Describe("Vulnerability report", func() {
 				Expect(summary.HasScanErrors()).To(BeTrue())
 			})
 		})
+
+		Describe("Secrets", func() {
+			It("should return the secrets of all images with the file they were found in", func() {
+				awsKey := Secret{RuleID: "aws-access-key-id", Category: "AWS", Severity: "CRITICAL", Title: "AWS Access Key ID", StartLine: 3, EndLine: 3}
+				privateKey := Secret{RuleID: "private-key", Category: "AsymmetricPrivateKey", Severity: "HIGH", Title: "Asymmetric Private Key", StartLine: 1, EndLine: 27}
+				summary := TeamSummary{
+					Images: []ScannedImage{
+						{ImageName: "app:1", TrivyOutputResults: []TrivyOutputResults{
+							{Target: "alpine", Vulnerabilities: buildVulnerabilities(map[string]int{"HIGH": 1})},
+							{Target: "/app/.env", Class: "secret", Secrets: []Secret{awsKey}},
+						}},
+						{ImageName: "base:1"},
+						{ImageName: "proxy:1", TrivyOutputResults: []TrivyOutputResults{
+							{Target: "/etc/ssl/server.key", Class: "secret", Secrets: []Secret{privateKey}},
+						}},
+					},
+				}
+				Expect(summary.Secrets()).To(Equal([]SecretFinding{
+					{ImageName: "app:1", Target: "/app/.env", Secret: awsKey},
+					{ImageName: "proxy:1", Target: "/etc/ssl/server.key", Secret: privateKey},
+				}))
+			})
+		})
 	})
 })
 
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+// RescanFailures scans again the images whose scan failed in a previous report, rather than the whole cluster, and
+// merges their new results into the report. The other images and all the fields of the report are kept as is, and the
+// images scanned again keep their areas and teams in the report. When the context is cancelled, the images not scanned
+// again keep their scan error and the report is marked as incomplete
+func (s *Scanner) RescanFailures(ctx context.Context, report *VulnerabilityReport) (*VulnerabilityReport, error) {
+	ctx, span := s.config.Tracer.Start(ctx, "RescanFailures")
+	defer span.Finish()
+	failedImages := make(map[string][]k8s.ContainerSummary)
+	for _, image := range report.ScannedImages {
+		if image.ScanError != nil && !image.Skipped {
+			failedImages[image.ImageName] = image.Containers
+		}
+	}
+	if len(failedImages) == 0 {
+		logr.Infof("No failed image to scan again")
+		return report, nil
+	}
+
+	logr.Infof("Scanning again the %d image(s) whose scan failed", len(failedImages))
+	rescannedImages, err := s.scanImages(ctx, failedImages)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	// the report is copied so that the report fields such as the scan opt-outs and the fix latencies are kept
+	merged := *report
+	merged.Metadata.Incomplete = report.Metadata.Incomplete || ctx.Err() != nil
+	merged.ScannedImages = replaceScannedImages(report.ScannedImages, rescannedImages)
+	merged.Suppressions = suppressVulnerabilities(merged.ScannedImages, time.Now())
+	merged.AreaSummary = regroupImages(report.AreaSummary, merged.ScannedImages)
+	if failed := merged.FailedScanCount(); failed > 0 {
+		logr.Warnf("The scan of %d image(s) failed again", failed)
+	}
+	return &merged, nil
+}
+
+// regroupImages returns the areas and teams of the summaries with the images in place of their previous results, so that
+// the images scanned again keep their grouping whatever the labels it was made with. The team images keep their
+// containers of the team, and the budget consumptions are counted again
+func regroupImages(areaSummaries map[string]*AreaSummary, images []ScannedImage) map[string]*AreaSummary {
+	imagesByName := make(map[string]ScannedImage)
+	for _, image := range images {
+		imagesByName[image.ImageName] = image
+	}
+	regrouped := make(map[string]*AreaSummary)
+	for areaName, area := range areaSummaries {
+		areaSummary := &AreaSummary{Name: area.Name, Teams: make(map[string]*TeamSummary)}
+		for teamName, team := range area.Teams {
+			teamSummary := *team
+			teamSummary.Images = nil
+			for _, teamImage := range team.Images {
+				image, ok := imagesByName[teamImage.ImageName]
+				if !ok {
+					image = teamImage
+				}
+				image.Containers = teamImage.Containers
+				teamSummary.Images = append(teamSummary.Images, image)
+			}
+			teamSummary.Images = sortBySeverity(teamSummary.Images)
+			teamSummary.Budget = team.Budget.recount(&teamSummary)
+			areaSummary.Teams[teamName] = &teamSummary
+			areaSummary.aggregate(&teamSummary)
+		}
+		areaSummary.Budget = area.Budget.recount(areaSummary)
+		regrouped[areaName] = areaSummary
+	}
+	return regrouped
+}
@@ -0,0 +1,42 @@
+package scanner
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// maxRetryBackoff caps the exponential backoff between two attempts
+const maxRetryBackoff = 2 * time.Minute
+
+// retry calls the operation until it succeeds or the configured retries are exhausted, returning the last error.
+// The backoff doubles after each attempt and is randomised between half and the whole backoff, so that the workers
+// throttled by a registry do not retry all at once. It gives up as soon as the context is done
+func (s *Scanner) retry(ctx context.Context, description string, operation func() error) error {
+	err := operation()
+	backoff := s.config.RetryBackoff
+	for attempt := 1; err != nil && attempt <= s.config.Retries && ctx.Err() == nil; attempt++ {
+		delay := jitter(backoff)
+		logr.Warnf("Retrying %s in %v (retry %d/%d) after error: %v", description, delay, attempt, s.config.Retries, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		err = operation()
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+	return err
+}
+
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+var unsafeFilenameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// generateSBOM writes the CycloneDX SBOM of the pulled image to the SBOM directory and returns its path. It returns
+// an empty path when no SBOM is generated or when the generation fails, the scan results being reported anyway
+func (s *Scanner) generateSBOM(ctx context.Context, imageName string) string {
+	if s.config.SBOMDir == "" {
+		return ""
+	}
+	_, span := s.config.Tracer.Start(ctx, "trivy sbom")
+	defer span.Finish()
+	span.SetAttribute("image", imageName)
+	sbom, err := s.trivyClient.SBOM(ctx, imageName, s.config.SBOMVulnerabilities)
+	span.RecordError(err)
+	if err != nil {
+		logr.Warn(err)
+		return ""
+	}
+	filename := filepath.Join(s.config.SBOMDir, sbomFilename(imageName))
+	if err := os.MkdirAll(s.config.SBOMDir, 0755); err != nil {
+		logr.Warnf("Could not create the SBOM directory %s: %v", s.config.SBOMDir, err)
+		return ""
+	}
+	if err := os.WriteFile(filename, sbom, 0644); err != nil {
+		logr.Warnf("Could not write the SBOM of image %s to %s: %v", imageName, filename, err)
+		return ""
+	}
+	return filename
+}
+
+// sbomFilename returns the filename of the SBOM of the image, for instance registry_payments_web_1.2.cdx.json
+func sbomFilename(imageName string) string {
+	return unsafeFilenameCharacters.ReplaceAllString(imageName, "_") + ".cdx.json"
+}
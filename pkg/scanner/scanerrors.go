@@ -0,0 +1,52 @@
+package scanner
+
+// Stages of the image scans failing, see ImageScanError
+const (
+	ScanStagePull   = "pull"
+	ScanStageScan   = "scan"
+	ScanStageRemove = "remove"
+)
+
+// ImageScanError is an error of the pull, scan or removal of an image
+type ImageScanError struct {
+	ImageName string
+	Stage     string
+	Error     string
+}
+
+// ScanErrors returns the pull, scan and removal errors of the scanned images, in the order of the images. The images
+// not scanned as larger than the maximum image size are not errors, they are reported as skipped
+func (r *VulnerabilityReport) ScanErrors() []ImageScanError {
+	var scanErrors []ImageScanError
+	for _, i := range r.ScannedImages {
+		if i.PullError != "" {
+			scanErrors = append(scanErrors, ImageScanError{ImageName: i.ImageName, Stage: ScanStagePull, Error: i.PullError})
+		}
+		if i.ScanError != nil && !i.Skipped {
+			scanErrors = append(scanErrors, ImageScanError{ImageName: i.ImageName, Stage: ScanStageScan, Error: i.ScanError.Error()})
+		}
+		if i.RemoveError != "" {
+			scanErrors = append(scanErrors, ImageScanError{ImageName: i.ImageName, Stage: ScanStageRemove, Error: i.RemoveError})
+		}
+	}
+	return scanErrors
+}
+
+// FailedScanCount returns the number of images whose scan failed or timed out, the skipped images excluded
+func (r *VulnerabilityReport) FailedScanCount() int {
+	count := 0
+	for _, i := range r.ScannedImages {
+		if i.ScanError != nil && !i.Skipped {
+			count++
+		}
+	}
+	return count
+}
+
+// ScanErrorRate returns the percentage of the scanned images whose scan failed, 0 when no image is scanned
+func (r *VulnerabilityReport) ScanErrorRate() float64 {
+	if len(r.ScannedImages) == 0 {
+		return 0
+	}
+	return 100 * float64(r.FailedScanCount()) / float64(len(r.ScannedImages))
+}
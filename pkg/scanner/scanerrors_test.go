@@ -0,0 +1,32 @@
+package scanner
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scan errors", func() {
+
+	report := &VulnerabilityReport{ScannedImages: []ScannedImage{
+		{ImageName: "nginx:1.25"},
+		{ImageName: "broken:1.0", PullError: "manifest unknown", ScanError: errors.New("unable to scan image")},
+		{ImageName: "huge:1.0", Skipped: true, ScanError: errors.New("image larger than 1GB")},
+		{ImageName: "redis:7.2", RemoveError: "no such image"},
+	}}
+
+	It("lists the pull, scan and removal errors of the images, the skipped images excluded", func() {
+		Expect(report.ScanErrors()).To(Equal([]ImageScanError{
+			{ImageName: "broken:1.0", Stage: ScanStagePull, Error: "manifest unknown"},
+			{ImageName: "broken:1.0", Stage: ScanStageScan, Error: "unable to scan image"},
+			{ImageName: "redis:7.2", Stage: ScanStageRemove, Error: "no such image"},
+		}))
+	})
+
+	It("computes the percentage of the failed scans", func() {
+		Expect(report.FailedScanCount()).To(Equal(1))
+		Expect(report.ScanErrorRate()).To(Equal(25.0))
+		Expect((&VulnerabilityReport{}).ScanErrorRate()).To(BeZero())
+	})
+})
@@ -1,13 +1,20 @@
 package scanner
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/tracing"
 
-	"github.com/gammazero/workerpool"
 	logr "github.com/sirupsen/logrus"
 )
 
@@ -17,6 +24,17 @@ type Scanner struct {
 	kubernetesClient k8s.KubernetesClient
 	dockerClient     DockerClient
 	trivyClient      TrivyClient
+	rateLimiter      *registryRateLimiter
+	nodePressure     *nodePressureMonitor
+	// scratch is the scratch directory of the scan in progress, nil when none is configured, see Config.ScratchDir
+	scratch *scratchDir
+	// nodePlatforms are the platforms of the cluster nodes by node name, for instance linux/arm64, only loaded when
+	// the platforms of the nodes are scanned, see Config.Platforms
+	nodePlatforms map[string]string
+	// database is the version of the vulnerability database the images are scanned with, nil when unknown
+	database *TrivyVersion
+	// failFastFinding is the finding that stopped the scan in progress, see Config.FailFast
+	failFastFinding string
 }
 
 // ScannedImage define the information of an image
@@ -26,13 +44,100 @@ type ScannedImage struct {
 	ImageName            string
 	ScanError            error
 	VulnerabilitySummary VulnerabilitySummary
+	// Skipped is true when the image was not scanned as larger than the maximum image size
+	Skipped bool
+	// ImageSize is the compressed size of the image layers, only known when a maximum image size is configured
+	ImageSize int64
+	// OS is the operating system trivy detected in the image, nil when unknown
+	OS *OS
+	// TimedOut is true when the scan exceeded the scan timeout, the results being the partial results of trivy if any
+	TimedOut bool
+	// SBOMFile is the CycloneDX SBOM generated for the image, empty when none was generated, see Config.SBOMDir
+	SBOMFile string `json:",omitempty"`
+	// RawTrivyOutputFiles are the raw trivy outputs of the image saved to Config.RawTrivyOutputDir, one per platform scanned
+	RawTrivyOutputFiles []string `json:",omitempty"`
+	// Platforms are the platforms the image was scanned for, for instance linux/amd64, empty when unknown
+	Platforms []string `json:",omitempty"`
+	// PullError and RemoveError are the errors of the pull and the removal of the image, the image being scanned even
+	// when its pull fails, see VulnerabilityReport.ScanErrors
+	PullError   string `json:",omitempty"`
+	RemoveError string `json:",omitempty"`
+	// Exposure is the highest exposure level of the containers running the image, for instance k8s.ExposureInternet,
+	// empty when none is exposed
+	Exposure string `json:",omitempty"`
+	// ScanTime is the time the image was scanned at, by trivy or by the source of its results, nil when unknown.
+	// TrivyDBVersion and TrivyDBUpdatedAt are the vulnerability database of the scan, when scanned with trivy
+	ScanTime         *time.Time `json:",omitempty"`
+	TrivyDBVersion   int        `json:",omitempty"`
+	TrivyDBUpdatedAt *time.Time `json:",omitempty"`
+	// Stale is true when the results are older than Config.MaxResultAge
+	Stale bool `json:",omitempty"`
+	// SuppressedVulnerabilities are the vulnerabilities suppressed by all the namespaces running the image, left out of
+	// its results, see k8s.SuppressAnnotation
+	SuppressedVulnerabilities []SuppressedVulnerability `json:",omitempty"`
+	// spilled are the results of the image spilled to disk, TrivyOutputResults being empty, see Results
+	spilled *spilledResults
+}
+
+// MarshalJSON encodes the scan error as a string as error values have no json representation. The results spilled to
+// disk are encoded with the image
+func (i ScannedImage) MarshalJSON() ([]byte, error) {
+	type scannedImage ScannedImage
+	i.TrivyOutputResults = i.Results()
+	var scanError string
+	if i.ScanError != nil {
+		scanError = i.ScanError.Error()
+	}
+	return json.Marshal(&struct {
+		scannedImage
+		ScanError string `json:",omitempty"`
+	}{scannedImage(i), scanError})
+}
+
+// UnmarshalJSON decodes a ScannedImage saved with MarshalJSON
+func (i *ScannedImage) UnmarshalJSON(data []byte) error {
+	type scannedImage ScannedImage
+	decoded := &struct {
+		*scannedImage
+		ScanError string `json:",omitempty"`
+	}{scannedImage: (*scannedImage)(i)}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		return err
+	}
+	if decoded.ScanError != "" {
+		i.ScanError = errors.New(decoded.ScanError)
+	}
+	return nil
 }
 
 // VulnerabilitySummary provides a summary of the vulnerabilities found for an image
 type VulnerabilitySummary struct {
-	ContainerCount               int
-	SeverityScore                int
+	ContainerCount int
+	// WorkloadCount is the number of distinct workloads running the image, a DaemonSet counting once whatever the
+	// number of nodes it runs on
+	WorkloadCount int
+	// SeverityScore is the score of the vulnerabilities of the image, weighted according to ScoringMode
+	SeverityScore int
+	// ContainerWeightedScore and WorkloadWeightedScore are the score of the vulnerabilities of the image weighted by
+	// ContainerCount and by WorkloadCount respectively
+	ContainerWeightedScore int
+	WorkloadWeightedScore  int
+	// ScoringMode is the weighting of SeverityScore, for instance ScoringWorkloads, the image being scored once when
+	// empty or ScoringImages
+	ScoringMode                  string `json:",omitempty"`
 	TotalVulnerabilityBySeverity map[string]int
+	// FixableCount is the number of vulnerabilities with a fixed version the teams can upgrade to,
+	// UnfixableCount the number of vulnerabilities without fix yet
+	FixableCount   int
+	UnfixableCount int
+	// FixableVulnerabilityBySeverity counts the vulnerabilities with a fixed version by severity
+	FixableVulnerabilityBySeverity map[string]int
+	// KnownExploitedCount is the number of vulnerabilities of the CISA Known Exploited Vulnerabilities catalog
+	KnownExploitedCount int
+	// BaseImageLayerCount and ApplicationLayerCount are the number of vulnerabilities found in the layers of the base
+	// image and in the layers of the application, see Vulnerabilities.LayerOrigin
+	BaseImageLayerCount   int `json:",omitempty"`
+	ApplicationLayerCount int `json:",omitempty"`
 }
 
 // Vulnerabilities is the object representation of the trivy vulnerability table for an image
@@ -44,21 +149,140 @@ type Vulnerabilities struct {
 	InstalledVersion string
 	VulnerabilityID  string
 	PkgName          string
-	Title            string
-	References       []string
-	Layer            *Layer
+	// PkgIdentifier identifies the package across the ecosystems, nil with the trivy versions not reporting it
+	PkgIdentifier *PkgIdentifier `json:",omitempty"`
+	Title         string
+	References    []string
+	Layer         *Layer
+	// LayerOrigin is the origin of the layer the vulnerability was found in, LayerBaseImage or LayerApplication,
+	// empty when the layers of the image are unknown
+	LayerOrigin string `json:",omitempty"`
+	// DescriptionSource is the source of the advisory the description was completed from, nvd or ghsa, empty for the
+	// description of the trivy database, see Config.Advisories
+	DescriptionSource string `json:",omitempty"`
+	// ExploitReferences are the references of the advisory publishing exploits of the vulnerability, see Config.Advisories
+	ExploitReferences []string   `json:",omitempty"`
+	PublishedDate     *time.Time `json:",omitempty"`
+	LastModifiedDate  *time.Time `json:",omitempty"`
+	// CVSS holds the CVSS scores and vectors per source, for instance nvd or redhat
+	CVSS map[string]CVSS
+	// VendorSeverity holds the severity each source assigned, from 0 for UNKNOWN to 4 for CRITICAL
+	VendorSeverity map[string]int `json:",omitempty"`
+	// KnownExploited is the CISA catalog entry of the vulnerabilities exploited in the wild, nil for the other
+	// vulnerabilities or when the vulnerabilities are not cross-referenced with the catalog, see Config.KEVCatalog
+	KnownExploited *KnownExploitedVulnerability `json:",omitempty"`
+	// EPSS is the probability of the vulnerability being exploited, nil when unknown or when the vulnerabilities are
+	// not scored, see Config.EPSSDataset
+	EPSS *EPSS `json:",omitempty"`
+	// OverriddenSeverity is the trivy severity of the vulnerability when Severity is overridden, see Config.SeverityOverrides
+	OverriddenSeverity *OverriddenSeverity `json:",omitempty"`
+	// NormalizedSeverity is the severity of the vulnerability on the internal scale of all the sources, nil when the
+	// vulnerability is not enriched
+	NormalizedSeverity *NormalizedSeverity `json:",omitempty"`
+	// FirstSeen is the time of the first scan the vulnerability was found in the image at, nil when the vulnerabilities
+	// are not tracked, see VulnerabilityHistory
+	FirstSeen *time.Time `json:",omitempty"`
+	// Triage is the triage of the vulnerability in the image digest, nil when the vulnerability is not triaged, see
+	// FindingsState
+	Triage *FindingTriage `json:",omitempty"`
+}
+
+// Fixable returns true when a version of the package fixing the vulnerability is available
+func (v Vulnerabilities) Fixable() bool {
+	return v.FixedVersion != ""
+}
+
+// filterUnfixed removes the vulnerabilities without fixed version
+func filterUnfixed(trivyOutput []TrivyOutputResults) {
+	for i := range trivyOutput {
+		var vulnerabilities []Vulnerabilities
+		for _, vulnerability := range trivyOutput[i].Vulnerabilities {
+			if vulnerability.Fixable() {
+				vulnerabilities = append(vulnerabilities, vulnerability)
+			}
+		}
+		trivyOutput[i].Vulnerabilities = vulnerabilities
+	}
+}
+
+// PkgIdentifier is the object representation of the identifier of the vulnerable package of a trivy vulnerability
+type PkgIdentifier struct {
+	// PURL is the package URL of the package, for instance pkg:deb/debian/openssl@3.0.0?arch=amd64
+	PURL string `json:",omitempty"`
 }
 
 // TrivyOutputResults is an object representation of the trivy image scan summary
 type TrivyOutputResults struct {
 	Vulnerabilities []Vulnerabilities
-	Type            string
-	Target          string
+	// Secrets are only found when the secret scanner is enabled, see Config.ScanSecrets
+	Secrets []Secret
+	// Licenses are only found when the license scanner is enabled, see Config.ScanLicenses
+	Licenses []License
+	Type     string
+	Target   string
+	Class    string
+	// Platform is the platform of the image the results were found in, for instance linux/arm64, empty when unknown
+	Platform string `json:",omitempty"`
+}
+
+// Secret is the object representation of a secret found by the trivy secret scanner, for instance an AWS access key.
+// The matched line is not kept so that the reports never hold any part of the leaked credentials
+type Secret struct {
+	RuleID    string
+	Category  string
+	Severity  string
+	Title     string
+	StartLine int
+	EndLine   int
+	Layer     *Layer
 }
 
 // TrivyOutput is an object representation of the trivy output for an image scan
 type TrivyOutput struct {
+	// CreatedAt is the time of the scan, zero when unknown
+	CreatedAt time.Time
+	Metadata  struct {
+		OS *OS
+		// ImageConfig is the config of the scanned image, nil when unknown
+		ImageConfig *ImageConfig
+		// RepoDigests are the repository digests of the scanned image, for instance registry/payments/web@sha256:4ff3...
+		RepoDigests []string
+	}
 	Results []TrivyOutputResults
+	// Raw is the json output of trivy as is, nil when the output was not read from trivy
+	Raw []byte `json:"-"`
+}
+
+// OS is the object representation of the operating system trivy detected in an image, for instance debian 9.13
+type OS struct {
+	Family string
+	Name   string
+	// EOSL is true when the release reached its end of service life, it receives no security fixes anymore
+	EOSL bool
+}
+
+// ImageConfig is the object representation of the platform and layers of the image config trivy reads from the
+// scanned image
+type ImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant"`
+	// History and RootFS tell the layers of the base image from the layers of the application, see attributeLayers
+	History []ImageHistory `json:"history"`
+	RootFS  ImageRootFS    `json:"rootfs"`
+}
+
+// Platform returns the platform of the image config, for instance linux/arm64 or linux/arm/v7. It is empty when
+// the architecture is unknown
+func (c *ImageConfig) Platform() string {
+	if c == nil || c.OS == "" || c.Architecture == "" {
+		return ""
+	}
+	platform := c.OS + "/" + c.Architecture
+	if c.Variant != "" {
+		platform += "/" + c.Variant
+	}
+	return platform
 }
 
 // CisOutput is an object representation of the trivy security compliance scan
@@ -137,37 +361,420 @@ type Config struct {
 	FilterLabels         string
 	Severity             string
 	ScanImageTimeout     time.Duration
+	// ClusterName is recorded in the report metadata
+	ClusterName string
+	// Stream receives each scanned image as a json line as soon as its scan finishes, when set
+	Stream io.Writer
+	// Tracer records the spans of the scan pipeline, tracing is disabled when nil
+	Tracer *tracing.Tracer
+	// Retries is the number of times a failing image pull or scan is retried, waiting RetryBackoff before the first retry
+	Retries      int
+	RetryBackoff time.Duration
+	// RegistryPullsPerMinute limits the image pulls per minute of the registries, for instance docker.io, see ImageRegistry
+	RegistryPullsPerMinute map[string]int
+	// RegistryWorkers limits the number of images of the registries scanned concurrently, for instance 2 for docker.io,
+	// within the Workers. The images of the other registries are only limited by the Workers
+	RegistryWorkers map[string]int
+	// MaxImageSize is the size in bytes above which images are skipped, or scanned once all the other images are scanned
+	// with OversizedImageWorkers workers when ScanOversizedImages is true. There is no maximum size when 0
+	MaxImageSize          int64
+	ScanOversizedImages   bool
+	OversizedImageWorkers int
+	// WeightScansBySize makes the scans of the images take one of the Workers slots per ImageSlotSize of their
+	// compressed size, read from the registry, so that fewer large images are scanned concurrently
+	WeightScansBySize bool
+	// ScanSecrets also runs the trivy secret scanner on the images, to find the credentials embedded in their files
+	ScanSecrets bool
+	// ScanLicenses also runs the trivy license scanner on the images, their package licenses being classified
+	// against LicensePolicy
+	ScanLicenses  bool
+	LicensePolicy *LicensePolicy
+	// MinCVSSScore removes the vulnerabilities with a CVSS score below it, the vulnerabilities without CVSS score are kept
+	MinCVSSScore float64
+	// IgnoreUnfixed only reports the vulnerabilities with a fixed version. Trivy is run with --ignore-unfixed and the
+	// unfixed vulnerabilities of the other image scan sources are removed before the vulnerabilities are counted
+	IgnoreUnfixed bool
+	// SortByCVSS sorts the vulnerabilities of the images by decreasing CVSS score rather than by severity
+	SortByCVSS bool
+	// KEVCatalog marks the vulnerabilities exploited in the wild, the vulnerabilities are not cross-referenced when nil
+	KEVCatalog *KEVCatalog
+	// EPSSDataset scores the vulnerabilities with their exploit probability, the vulnerabilities are not scored when nil
+	EPSSDataset *EPSSDataset
+	// Advisories complete the descriptions, references and dates of the vulnerabilities with their NVD or GitHub
+	// advisory once the images are scanned, the vulnerabilities are not enriched when nil
+	Advisories *AdvisoryDatabase
+	// SeverityOverrides overrides the severity trivy assigns to vulnerabilities before the vulnerabilities are counted
+	SeverityOverrides *SeverityOverrides
+	// UnknownSeverity is the policy of the vulnerabilities of UNKNOWN severity, one of UnknownSeverityPolicies, applied
+	// after the SeverityOverrides so that the summaries, the scores and the thresholds count them consistently. The
+	// vulnerabilities are kept as UNKNOWN when empty
+	UnknownSeverity string
+	// GroupBy is the grouping mode of the images in the report, see AreaReport.GroupBy
+	GroupBy string
+	// Ownership attributes the images whose area or team labels are missing, see AreaReport.Ownership
+	Ownership *OwnershipMapping
+	// ScoringMode is the weighting of the severity score of the images, see AreaReport.ScoringMode
+	ScoringMode string
+	// CheckpointFile records each scanned image as soon as its scan finishes, so that an interrupted scan can be resumed
+	// with Resume only scanning the remaining images. It is removed once the scan completes, there is no checkpoint when
+	// empty and the scan cannot be resumed
+	CheckpointFile string
+	Resume         bool
+	// SpillDir holds the results of the scanned images on disk rather than in memory, so that the scans of the clusters
+	// with thousands of images complete on the runners with little memory. The results are read from disk one image at
+	// a time when the reports are generated, and removed once the report is closed. The results are kept in memory
+	// when empty
+	SpillDir string
+	// ScratchDir is the directory trivy writes its temporary files and its cache to, for instance a volume mounted in
+	// the scanner pod so that the scans do not fill the root disk of the node. The temporary files and the cache of the
+	// analysed layers are removed once the scan completes, the vulnerability database being kept. The trivy cache is
+	// in the TrivyDB cache directory instead when one is configured
+	ScratchDir string
+	// ScratchDirMaxSize is the size of the scratch directory above which the scans wait for the scans in progress to
+	// complete, no maximum when 0
+	ScratchDirMaxSize int64
+	// FullRescanInterval is the interval the cluster is fully rescanned at while watched, see Scanner.Watch.
+	// The images already scanned are never rescanned when 0
+	FullRescanInterval time.Duration
+	// ScanWindows restricts the scans of the watched cluster to windows, the scans being run at any time when nil
+	ScanWindows ScanWindows
+	// MaxResultAge is the age above which the results of an image are stale: the stale images are flagged in the
+	// report, and rescanned when their results come from the checkpoint file, the watched report or the registry.
+	// Results never expire when 0
+	MaxResultAge time.Duration
+	// Since restricts the scan of the cluster images to the images of the pods created or updated within this
+	// duration, for incremental scans between full scans. All the images are scanned when 0
+	Since time.Duration
+	// NodeName is the node hosting the scanner when it runs in a pod of the cluster. The image pulls are paused while
+	// the node is under disk or memory pressure, so that they do not get the workloads of the node evicted, and resume
+	// once it recovers. The pressure of the node is not monitored when empty
+	NodeName string
+	// RegistryScans provides the results of the images their registry already scanned, for instance Harbor, those
+	// images being neither pulled nor scanned. The other images are scanned with trivy, all of them when nil
+	RegistryScans ImageScanSource
+	// SBOMDir receives the CycloneDX SBOM of each image pulled and scanned, no SBOM being generated when empty.
+	// The SBOMs also hold the vulnerabilities of the components with SBOMVulnerabilities
+	SBOMDir             string
+	SBOMVulnerabilities bool
+	// RawTrivyOutputDir receives the raw json output of trivy for each image scanned, compressed with gzip and named after
+	// the image digest, so that the findings can be looked into without scanning the image again. Nothing is saved when empty
+	RawTrivyOutputDir string
+	// FailFast stops the scan as soon as a scanned image has a vulnerability failing the policy, no new image being
+	// scanned and the scans in progress being interrupted, so that the pipelines gating on the policy fail quickly.
+	// The report only holds the images scanned before, see ReportMetadata.FailFastFinding. All the images are scanned
+	// when nil
+	FailFast *FailurePolicy
+	// ScanPriority orders the scans of the images by weighted priority, the images being scanned by decreasing exposure
+	// when nil
+	ScanPriority *ScanPriority
+	// SeverityBudgets are the maximum CRITICAL and HIGH vulnerabilities of the areas and teams, see AreaReport.Budgets
+	SeverityBudgets *SeverityBudgets
+	// TrivyPath is the trivy binary the images are scanned with, the trivy of the PATH when empty
+	TrivyPath string
+	// TrivyExtraArgs are passed through to the trivy invocations as is, to enable the trivy flags the scanner does not
+	// model such as --ignore-unfixed or --offline-scan. TrivyImageExtraArgs, TrivySBOMExtraArgs and TrivyCisExtraArgs
+	// are only passed to the image scans, the SBOM generations and the compliance scans respectively
+	TrivyExtraArgs      []string
+	TrivyImageExtraArgs []string
+	TrivySBOMExtraArgs  []string
+	TrivyCisExtraArgs   []string
+	// TrivyDB locates the trivy vulnerability database, downloaded from its default repository when empty
+	TrivyDB TrivyDBConfig
+	// TrivySharedCache locks the trivy cache directory the workers share with the other runs using it, exclusively
+	// while the databases are downloaded once before the scans and shared while the images are scanned, so that the
+	// runs neither download the databases concurrently nor update them under the scans of another run. The Java index
+	// database is prefetched with the vulnerability database unless skipped, the workers never downloading it
+	TrivySharedCache bool
+	// Policies are the custom Rego policies the compliance scans evaluate the cluster resources against
+	Policies RegoPolicies
+	// Platforms selects the platforms of the multi-platform images scanned: PlatformsHost the platform docker pulls
+	// on the scanning host, PlatformsNodes the platforms of the nodes running the image and PlatformsAll every platform.
+	// The host platform is scanned when empty
+	Platforms string
+	// KeepImages keeps the pulled images rather than removing them once scanned. The images present on the host before
+	// their pull are kept in any case
+	KeepImages bool
+	// ContainerRuntime pulls and removes the images, DockerRuntime or PodmanRuntime. The images are pulled with docker when empty
+	ContainerRuntime string
+	// TrivyImageSource is the trivy image source the images are read from, for instance containerd for the node agents
+	// scanning the images of the node runtime. It is the container runtime when empty, see ContainerRuntime
+	TrivyImageSource string
+	// InsecureRegistries are the registries the images are read from without TLS verification, or over plain HTTP,
+	// for instance registry.internal:5000, see ImageRegistry
+	InsecureRegistries []string
+	// RegistryCredentials provides the credentials the images of the registries are pulled with, for instance from
+	// a secret store. The credentials of the container runtime are used when nil, or for the registries it has none for
+	RegistryCredentials RegistryCredentialSource
 }
 
 // New creates a Scanner to find vulnerabilities in container images
 func New(kubernetesClient k8s.KubernetesClient, config *Config) *Scanner {
+	return NewWithClients(kubernetesClient, config.NewDockerClient(), config.NewTrivyClient(), config)
+}
+
+// ScanWindows are the windows the scans are allowed in, see schedule.Windows
+type ScanWindows interface {
+	// Allows returns true when the scans are allowed at the time
+	Allows(t time.Time) bool
+	// Next returns the time the scans are next allowed at, the time itself when they are allowed
+	Next(after time.Time) time.Time
+}
+
+// NewTrivyClient creates the trivy client scanning the images with the trivy binary, the severities, the timeout,
+// the scanners and the extra arguments of the config
+func (c *Config) NewTrivyClient() TrivyClient {
+	command := c.TrivyPath
+	if command == "" {
+		command = DefaultTrivyCommand
+	}
+	client := NewTrivyClientWithCommand(command, trivySeverity(c.Severity, c.UnknownSeverity), c.ScanImageTimeout, c.trivyScanners()).(*trivyClient)
+	client.extraArgs = trivyExtraArgs{all: c.TrivyExtraArgs, image: c.TrivyImageExtraArgs, sbom: c.TrivySBOMExtraArgs, cis: c.TrivyCisExtraArgs}
+	client.policies = c.Policies
+	client.imageSource = c.TrivyImageSource
+	client.insecureRegistries = c.InsecureRegistries
+	client.db = c.TrivyDB
+	client.ignoreUnfixed = c.IgnoreUnfixed
+	if c.TrivySharedCache && (client.db.JavaDB == "" || client.db.JavaDB == JavaDBOnDemand) {
+		// the workers sharing the cache would otherwise race to download the Java index database
+		client.db.JavaDB = JavaDBPrefetch
+	}
+	if c.ScratchDir != "" {
+		client.commandRunner = execCmd.NewCommandRunnerWithEnv([]string{"TMPDIR=" + scratchTempDir(c.ScratchDir)})
+		if client.db.CacheDir == "" {
+			client.db.CacheDir = scratchTrivyCacheDir(c.ScratchDir)
+		}
+	}
+	if client.imageSource == "" && c.ContainerRuntime == PodmanRuntime {
+		// the images pulled with podman are not in the docker engine trivy reads the images from first
+		client.imageSource = PodmanRuntime
+	}
+	return client
+}
+
+// NewWithClients creates a Scanner pulling and scanning the images with the given clients, for instance the mocks of
+// the scannertest and k8stest packages to unit test the code embedding the scanner
+func NewWithClients(kubernetesClient k8s.KubernetesClient, dockerClient DockerClient, trivyClient TrivyClient, config *Config) *Scanner {
 	return &Scanner{
 		config:           config,
 		kubernetesClient: kubernetesClient,
-		dockerClient:     NewDockerClient(),
-		trivyClient:      NewTrivyClient(config.Severity, config.ScanImageTimeout),
+		dockerClient:     dockerClient,
+		trivyClient:      trivyClient,
+		rateLimiter:      newRegistryRateLimiter(config.RegistryPullsPerMinute),
+		nodePressure:     newNodePressureMonitor(kubernetesClient, config.NodeName),
 	}
 }
 
-// ScanImages get all the images available in a cluster and scan them
-func (s *Scanner) ScanImages() (*VulnerabilityReport, error) {
+// trivyScanners returns the trivy scanners to run on the images, the vulnerability scanner and the opt-in scanners. The
+// scanners are always passed to trivy, its default scanners including the secret scanner
+func (c *Config) trivyScanners() []string {
+	scanners := []string{"vuln"}
+	if c.ScanSecrets {
+		scanners = append(scanners, "secret")
+	}
+	if c.ScanLicenses {
+		scanners = append(scanners, "license")
+	}
+	return scanners
+}
+
+// ScanImages get all the images available in a cluster and scan them.
+// When the context is cancelled, the scans in progress are stopped and the report of the images scanned
+// so far is returned, marked as incomplete
+func (s *Scanner) ScanImages(ctx context.Context) (*VulnerabilityReport, error) {
 	logr.Infof("Running scanner")
+	ctx, span := s.config.Tracer.Start(ctx, "ScanImages")
+	defer span.Finish()
+	metadata := s.newReportMetadata()
+	metadata.ClusterName = s.config.ClusterName
+	kubernetesVersion, err := s.kubernetesClient.GetServerVersion()
+	if err != nil {
+		logr.Warnf("Unable to get the Kubernetes version for the report metadata: %v", err)
+	}
+	metadata.KubernetesVersion = kubernetesVersion
+
 	containers, err := s.kubernetesClient.GetContainersInNamespaces(s.config.FilterLabels)
 	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	containers = s.selectChanged(containers, &metadata)
+	report, err := s.scanContainers(ctx, containers, s.config.AreaLabels, s.config.TeamsLabels, metadata)
+	span.RecordError(err)
+	return report, err
+}
+
+// ScanImageList scans the images of an image list file rather than the cluster images, see ReadImageList.
+// The area and team annotations are stored under the 'area' and 'team' labels when no label name is configured.
+// Cancelling the context returns an incomplete report as ScanImages does
+func (s *Scanner) ScanImageList(ctx context.Context, filename string) (*VulnerabilityReport, error) {
+	logr.Infof("Running scanner on image list %s", filename)
+	ctx, span := s.config.Tracer.Start(ctx, "ScanImageList")
+	defer span.Finish()
+	span.SetAttribute("image_list", filename)
+	areaLabelName, teamLabelName := s.imageListLabelNames()
+	// the annotations are stored under the first label name of the lists, which the report looks up first
+	containers, err := ReadImageList(filename, firstLabelName(areaLabelName), firstLabelName(teamLabelName))
+	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
+	report, err := s.scanContainers(ctx, containers, areaLabelName, teamLabelName, s.newReportMetadata())
+	span.RecordError(err)
+	return report, err
+}
+
+// ScanRegistryImages scans the images of a registry rather than the cluster images, for instance the tagged images of
+// its catalog, so that the images are vetted before they are deployed. The images are grouped with the registry host
+// as area and the first segment of their repository, such as the project or the organisation, as team. Cancelling the
+// context returns an incomplete report as ScanImages does
+func (s *Scanner) ScanRegistryImages(ctx context.Context, imageNames []string) (*VulnerabilityReport, error) {
+	logr.Infof("Running scanner on %d registry images", len(imageNames))
+	ctx, span := s.config.Tracer.Start(ctx, "ScanRegistryImages")
+	defer span.Finish()
+	areaLabelName, teamLabelName := s.imageListLabelNames()
+	var containers []k8s.ContainerSummary
+	for _, imageName := range imageNames {
+		registry := ImageRegistry(imageName)
+		repository, _, _ := strings.Cut(strings.TrimPrefix(imageName, registry+"/"), "@")
+		if tagIndex := strings.LastIndex(repository, ":"); tagIndex > strings.LastIndex(repository, "/") {
+			repository = repository[:tagIndex]
+		}
+		owner, _, _ := strings.Cut(repository, "/")
+		containers = append(containers, k8s.ContainerSummary{
+			Image:           imageName,
+			NamespaceLabels: map[string]string{firstLabelName(areaLabelName): registry, firstLabelName(teamLabelName): owner},
+		})
+	}
+	report, err := s.scanContainers(ctx, containers, areaLabelName, teamLabelName, s.newReportMetadata())
+	span.RecordError(err)
+	return report, err
+}
+
+// imageListLabelNames returns the area and team label names of the images scanned outside of a cluster, 'area' and
+// 'team' when no label name is configured
+func (s *Scanner) imageListLabelNames() (areaLabelName, teamLabelName string) {
+	areaLabelName, teamLabelName = s.config.AreaLabels, s.config.TeamsLabels
+	if areaLabelName == "" {
+		areaLabelName = "area"
+	}
+	if teamLabelName == "" {
+		teamLabelName = "team"
+	}
+	return areaLabelName, teamLabelName
+}
+
+func firstLabelName(labelNames string) string {
+	if names := k8s.LabelNames(labelNames); len(names) > 0 {
+		return names[0]
+	}
+	return labelNames
+}
+
+func (s *Scanner) scanContainers(ctx context.Context, containers []k8s.ContainerSummary, areaLabelName, teamLabelName string, metadata ReportMetadata) (*VulnerabilityReport, error) {
+	containers, optOuts := excludeOptedOut(containers)
 	containersByImageName := s.groupContainersByImageName(containers)
-	scannedImages, err := s.scanImages(containersByImageName)
+	scannedImages, err := s.scanImages(ctx, containersByImageName)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.generateReport(scannedImages, areaLabelName, teamLabelName, metadata)
 	if err != nil {
 		return nil, err
 	}
+	report.ScanOptOuts = optOuts
+	if timedOut := report.TimedOutImageCount(); timedOut > 0 {
+		logr.Warnf("The scan of %d image(s) timed out, consider increasing the scan timeout or decreasing the number of workers", timedOut)
+	}
+	if ctx.Err() != nil || s.failFastFinding != "" {
+		logr.Warnf("Scan interrupted, the report only holds the %d image(s) scanned out of %d", len(scannedImages), len(containersByImageName))
+		report.Metadata.Incomplete = true
+		report.Metadata.FailFastFinding = s.failFastFinding
+	}
+	return report, nil
+}
 
+func (s *Scanner) generateReport(scannedImages []ScannedImage, areaLabelName, teamLabelName string, metadata ReportMetadata) (*VulnerabilityReport, error) {
 	logr.Infof("Generating vulnerability report")
 	reportGenerator := &AreaReport{
-		AreaLabelName: s.config.AreaLabels,
-		TeamLabelName: s.config.TeamsLabels,
+		AreaLabelName: areaLabelName,
+		TeamLabelName: teamLabelName,
+		GroupBy:       s.config.GroupBy,
+		Ownership:     s.config.Ownership,
+		Budgets:       s.config.SeverityBudgets,
+		ScoringMode:   s.config.ScoringMode,
+	}
+	markStale(scannedImages, s.config.MaxResultAge, time.Now())
+	suppressions := suppressVulnerabilities(scannedImages, time.Now())
+	report, err := reportGenerator.GenerateVulnerabilityReport(scannedImages)
+	if err != nil {
+		return nil, err
 	}
-	return reportGenerator.GenerateVulnerabilityReport(scannedImages)
+	report.Metadata = metadata
+	report.Suppressions = suppressions
+	return report, nil
+}
+
+// newReportMetadata records the scan time and the trivy versions. The metadata is informative so that
+// errors are logged rather than failing the scan
+func (s *Scanner) newReportMetadata() ReportMetadata {
+	metadata := ReportMetadata{ScanTime: time.Now().UTC()}
+	version, err := s.trivyClient.Version()
+	if err != nil {
+		logr.Warnf("Unable to get the trivy version for the report metadata: %v", err)
+		return metadata
+	}
+	metadata.TrivyVersion = version.Version
+	metadata.TrivyDBVersion = version.VulnerabilityDB.Version
+	metadata.TrivyDBUpdatedAt = version.VulnerabilityDB.UpdatedAt
+	return metadata
+}
+
+// databaseVersion returns the version of the vulnerability database the images are scanned with, recorded with each
+// scanned image. The version is informative so that errors are logged rather than failing the scan
+func (s *Scanner) databaseVersion() *TrivyVersion {
+	version, err := s.trivyClient.Version()
+	if err != nil {
+		logr.Warnf("Unable to get the trivy database version of the scanned images: %v", err)
+		return nil
+	}
+	return version
+}
+
+// ScanImage scans a single image outside of any cluster. The image is neither pulled nor removed
+// so that locally built images can be scanned, it is reported under the 'all' area and team
+func (s *Scanner) ScanImage(ctx context.Context, imageName string) (*VulnerabilityReport, error) {
+	ctx, span := s.config.Tracer.Start(ctx, "ScanImage")
+	defer span.Finish()
+	span.SetAttribute("image", imageName)
+	err := s.downloadDatabase(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	logr.Infof("Scanning image %s", imageName)
+	metadata := s.newReportMetadata()
+	trivyOutput, err := s.trivyScan(ctx, imageName)
+	if err != nil {
+		err = fmt.Errorf("error executing trivy for image %s: %v", imageName, err)
+		span.RecordError(err)
+		return nil, err
+	}
+	scannedImage := newTrivyScannedImage(imageName, []k8s.ContainerSummary{{Image: imageName}}, trivyOutput, nil)
+	if rawOutputFile := s.saveRawTrivyOutput(imageName, trivyOutput); rawOutputFile != "" {
+		scannedImage.RawTrivyOutputFiles = []string{rawOutputFile}
+	}
+	s.config.Advisories.enrich(ctx, []ScannedImage{scannedImage})
+	s.stream(scannedImage)
+
+	reportGenerator := &AreaReport{}
+	report, err := reportGenerator.GenerateVulnerabilityReport([]ScannedImage{scannedImage})
+	if err != nil {
+		return nil, err
+	}
+	report.Metadata = metadata
+	return report, nil
 }
 
 func (s *Scanner) groupContainersByImageName(containers []k8s.ContainerSummary) map[string][]k8s.ContainerSummary {
@@ -181,73 +788,505 @@ func (s *Scanner) groupContainersByImageName(containers []k8s.ContainerSummary)
 	return images
 }
 
-func (s *Scanner) scanImages(imageList map[string][]k8s.ContainerSummary) ([]ScannedImage, error) {
-	var scannedImages []ScannedImage
-	wp := workerpool.New(s.config.Workers)
-	err := s.trivyClient.DownloadDatabase("image")
+func (s *Scanner) scanImages(ctx context.Context, imageList map[string][]k8s.ContainerSummary) ([]ScannedImage, error) {
+	scratch, err := openScratchDir(s.config.ScratchDir, s.config.ScratchDirMaxSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download trivy db: %v", err)
+		return nil, err
 	}
-
-	logr.Infof("Scanning %d images with %d workers", len(imageList), s.config.Workers)
-	for imageName, containers := range imageList {
-		// allocate var to allow access inside the worker submission
-		resolvedContainers := containers
-		resolvedImageName, err := s.stringReplacement(imageName, s.config.ImageNameReplacement)
+	defer scratch.close()
+	s.scratch = scratch
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	s.failFastFinding = ""
+	var sharedCache *sharedCache
+	if s.config.TrivySharedCache {
+		sharedCache, err = lockSharedCache(ctx, s.trivyCacheDir())
 		if err != nil {
-			logr.Errorf("Error string replacement failed, image_name : %s, image_replacement_string: %s, error: %s", imageName, s.config.ImageNameReplacement, err)
+			return nil, err
 		}
+		defer sharedCache.close()
+	}
+	err = s.downloadDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := sharedCache.share(ctx); err != nil {
+		return nil, err
+	}
+	if s.config.Platforms == PlatformsNodes {
+		s.nodePlatforms = s.loadNodePlatforms()
+	}
+	s.database = s.databaseVersion()
+	checkpoint, err := openCheckpoint(s.config.CheckpointFile, s.config.Resume)
+	if err != nil {
+		return nil, err
+	}
+	spill, err := openSpillStore(s.config.SpillDir)
+	if err != nil {
+		return nil, err
+	}
+	results := make(chan ScannedImage)
+	collected := make(chan []ScannedImage)
+	go s.collect(results, collected, checkpoint, spill, stop)
 
-		wp.Submit(func() {
-			logr.Infof("Worker processing image: %s", resolvedImageName)
+	imageGroups := groupImageNamesByDigest(imageList)
+	// the pools run the scans by decreasing priority, the scans of equal priority in the order they are submitted
+	priorities := s.config.ScanPriority.sort(imageGroups, imageList)
+	logr.Infof("Scanning %d images (%d unique digests) with %d workers", len(imageList), len(imageGroups), s.config.Workers)
+	tracing.SpanFromContext(ctx).SetAttribute("image_count", fmt.Sprint(len(imageList)))
+	var (
+		oversizedImageGroups [][]string
+		// guards the oversized image groups appended by the workers
+		lock sync.Mutex
+	)
+	pools := newRegistryPools(s.config.Workers, s.config.RegistryWorkers)
+	slots := newScanSlots(s.config.Workers)
+	for _, imageNames := range imageGroups {
+		// allocate var to allow access inside the worker submission
+		resolvedImageNames := imageNames
+		resolvedImageName := s.resolveImageName(imageNames[0])
 
-			// trivy fail to download from quay.io so we need to pull the image first
-			err := s.dockerClient.PullImage(resolvedImageName)
-			if err != nil {
-				logr.Errorf("Error executing docker pull for image %s: %v", resolvedImageName, err)
+		pools.submit(resolvedImageName, priorities[imageNames[0]], func() {
+			if ctx.Err() != nil {
+				logr.Debugf("Scan interrupted, skipping image: %s", resolvedImageName)
+				return
 			}
-
-			trivyOutput, err := s.trivyClient.ScanImage(resolvedImageName)
-			var scanError error
-			if err != nil {
-				scanError = fmt.Errorf("error executing trivy for image %s: %s", resolvedImageName, err)
-				logr.Error(scanError)
+			if scannedImages, ok := checkpoint.scanned(resolvedImageNames, imageList, s.resolveImageName); ok && anyStale(scannedImages, s.config.MaxResultAge, time.Now()) {
+				logr.Infof("Rescanning image %s, its results in the checkpoint file are older than %s", resolvedImageName, s.config.MaxResultAge)
+			} else if ok {
+				logr.Infof("Image %s already scanned according to the checkpoint file", resolvedImageName)
+				for _, scannedImage := range scannedImages {
+					results <- scannedImage
+				}
+				return
 			}
-			scannedImages = append(scannedImages, NewScannedImage(
-				resolvedImageName,
-				resolvedContainers,
-				trivyOutput,
-				scanError,
-			))
-
-			err = s.dockerClient.RmiImage(resolvedImageName)
-			if err != nil {
-				logr.Errorf("Error executing docker rmi for image %s: %v", resolvedImageName, err)
+			if trivyOutput, ok := s.registryScan(ctx, resolvedImageName, resolvedImageNames, imageList); ok {
+				s.fanOut(results, imageList, resolvedImageNames, func(imageName string, containers []k8s.ContainerSummary) ScannedImage {
+					return newTrivyScannedImage(imageName, containers, trivyOutput, nil)
+				})
+				return
+			}
+			size := s.imageSize(ctx, resolvedImageName)
+			oversized := s.config.MaxImageSize > 0 && size > s.config.MaxImageSize
+			switch {
+			case oversized && s.config.ScanOversizedImages:
+				logr.Infof("Deferring the scan of image %s, its size %d exceeds the maximum image size", resolvedImageName, size)
+				lock.Lock()
+				oversizedImageGroups = append(oversizedImageGroups, resolvedImageNames)
+				lock.Unlock()
+			case oversized:
+				logr.Warnf("Skipping image %s, its size %d exceeds the maximum image size", resolvedImageName, size)
+				s.fanOut(results, imageList, resolvedImageNames, func(imageName string, containers []k8s.ContainerSummary) ScannedImage {
+					return NewSkippedImage(imageName, containers, size)
+				})
+			case s.config.WeightScansBySize:
+				imageSlots := imageSlots(size, s.config.Workers)
+				slots.acquire(imageSlots)
+				s.scanImageGroup(ctx, imageList, resolvedImageNames, results)
+				slots.release(imageSlots)
+			default:
+				s.scanImageGroup(ctx, imageList, resolvedImageNames, results)
 			}
 		})
 	}
+	pools.stopWait()
 
-	wp.StopWait()
+	if len(oversizedImageGroups) > 0 {
+		sort.Slice(oversizedImageGroups, func(i, j int) bool {
+			first, second := oversizedImageGroups[i][0], oversizedImageGroups[j][0]
+			if priorities[first] != priorities[second] {
+				return priorities[first] > priorities[second]
+			}
+			return first < second
+		})
+		logr.Infof("Scanning %d oversized images with %d workers", len(oversizedImageGroups), s.config.OversizedImageWorkers)
+		pools = newRegistryPools(s.config.OversizedImageWorkers, s.config.RegistryWorkers)
+		for _, imageNames := range oversizedImageGroups {
+			resolvedImageNames := imageNames
+			pools.submit(s.resolveImageName(resolvedImageNames[0]), priorities[resolvedImageNames[0]], func() {
+				if ctx.Err() != nil {
+					logr.Debugf("Scan interrupted, skipping image: %s", resolvedImageNames[0])
+					return
+				}
+				s.scanImageGroup(ctx, imageList, resolvedImageNames, results)
+			})
+		}
+		pools.stopWait()
+	}
+
+	close(results)
+	scannedImages := <-collected
+	checkpoint.close(ctx.Err() == nil)
+	s.config.Advisories.enrich(ctx, scannedImages)
+	// the spilled results are read from disk until the report is closed, see VulnerabilityReport.Close
+	if spill != nil && spill.empty() {
+		spill.close()
+	}
 	return scannedImages, nil
 }
 
-// CisScan perform trivy compliance scan
-func (s *Scanner) CisScan(benchmark string) (*VulnerabilityReport, error) {
-	logr.Infof("Running %s security benchmark", benchmark)
+// imageSize returns the compressed size of the image when a maximum image size is configured or the scans are weighted
+// by size, 0 otherwise or when unknown. Images of unknown size are scanned
+func (s *Scanner) imageSize(ctx context.Context, imageName string) int64 {
+	if s.config.MaxImageSize <= 0 && !s.config.WeightScansBySize {
+		return 0
+	}
+	size, err := s.dockerClient.ImageSize(ctx, imageName)
+	if err != nil {
+		logr.Warnf("Unable to get the size of image %s, scanning it anyway: %v", imageName, err)
+		return 0
+	}
+	return size
+}
+
+// scanImageGroup scans the first image of a group of images sharing the same digest, and sends a scanned image with
+// the scan results for each image of the group. Nothing is sent when the scan is interrupted
+func (s *Scanner) scanImageGroup(ctx context.Context, imageList map[string][]k8s.ContainerSummary, imageNames []string, results chan<- ScannedImage) {
+	var containers []k8s.ContainerSummary
+	for _, imageName := range imageNames {
+		containers = append(containers, imageList[imageName]...)
+	}
+	scan, interrupted := s.scanImagePlatforms(ctx, s.resolveImageName(imageNames[0]), containers)
+	if interrupted {
+		return
+	}
+	s.fanOut(results, imageList, imageNames, func(imageName string, containers []k8s.ContainerSummary) ScannedImage {
+		scannedImage := newTrivyScannedImage(imageName, containers, scan.trivyOutput, scan.scanError)
+		if scan.trivyOutput != nil && s.database != nil {
+			updatedAt := s.database.VulnerabilityDB.UpdatedAt
+			scannedImage.TrivyDBVersion = s.database.VulnerabilityDB.Version
+			scannedImage.TrivyDBUpdatedAt = &updatedAt
+		}
+		scannedImage.SBOMFile = scan.sbomFile
+		scannedImage.RawTrivyOutputFiles = scan.rawOutputFiles
+		scannedImage.PullError = errorMessage(scan.pullError)
+		scannedImage.RemoveError = errorMessage(scan.removeError)
+		return scannedImage
+	})
+}
+
+// fanOut sends a scanned image for each image name, with its containers and its name after replacement
+func (s *Scanner) fanOut(results chan<- ScannedImage, imageList map[string][]k8s.ContainerSummary, imageNames []string, newScannedImage func(imageName string, containers []k8s.ContainerSummary) ScannedImage) {
+	for _, imageName := range imageNames {
+		results <- newScannedImage(s.resolveImageName(imageName), imageList[imageName])
+	}
+}
 
-	trivyOutput, err := s.trivyClient.CisScan(benchmark)
+// imageScan is the outcome of the pull, scan, SBOM generation and removal of an image
+type imageScan struct {
+	trivyOutput    *TrivyOutput
+	sbomFile       string
+	rawOutputFiles []string
+	scanError      error
+	// pullError and removeError are the errors of the pull and the removal of the image
+	pullError   error
+	removeError error
+}
+
+// errorMessage returns the message of the error, empty when nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// scanImage pulls, scans, generates the SBOM of and removes the image. interrupted is true when the scan is interrupted
+func (s *Scanner) scanImage(ctx context.Context, imageName string) (scan imageScan, interrupted bool) {
+	logr.Infof("Worker processing image: %s", imageName)
+	imageCtx, imageSpan := s.config.Tracer.Start(ctx, "scan image")
+	defer imageSpan.Finish()
+	imageSpan.SetAttribute("image", imageName)
+
+	if err := s.scratch.acquire(imageCtx, imageName); err != nil {
+		logr.Warnf("Scan interrupted, image %s is not reported", imageName)
+		return imageScan{}, true
+	}
+	defer s.scratch.release()
+
+	// the images present before the pull, for instance the local images of a developer, are not removed
+	keepImage := s.config.KeepImages || s.imagePresent(imageCtx, imageName)
+
+	// trivy fail to download from quay.io so we need to pull the image first
+	_, pullSpan := s.config.Tracer.Start(imageCtx, "docker pull")
+	err := s.retry(imageCtx, fmt.Sprintf("docker pull of image %s", imageName), func() error {
+		if err := s.nodePressure.wait(imageCtx, imageName); err != nil {
+			return err
+		}
+		if err := s.rateLimiter.wait(imageCtx, imageName); err != nil {
+			return err
+		}
+		return s.dockerClient.PullImage(imageCtx, imageName)
+	})
+	pullSpan.RecordError(err)
+	pullSpan.Finish()
 	if err != nil {
-		return nil, fmt.Errorf("error executing trivy cluster scan: %v", err)
+		logr.Errorf("Error executing docker pull for image %s: %v", imageName, err)
+		scan.pullError = err
 	}
+	// the image is removed even when the scan is interrupted
+	defer func() {
+		if !keepImage {
+			scan.removeError = s.removeImage(imageCtx, imageName)
+		}
+	}()
 
-	logr.Infof("SUCCESS: %v", trivyOutput)
+	trivyOutput, err := s.trivyScan(imageCtx, imageName)
+	if ctx.Err() != nil {
+		logr.Warnf("Scan interrupted, image %s is not reported", imageName)
+		imageSpan.RecordError(ctx.Err())
+		return imageScan{}, true
+	}
+	scan.trivyOutput = trivyOutput
+	if rawOutputFile := s.saveRawTrivyOutput(imageName, trivyOutput); rawOutputFile != "" {
+		scan.rawOutputFiles = []string{rawOutputFile}
+	}
+	var timeoutErr *ScanTimeoutError
+	switch {
+	case errors.As(err, &timeoutErr):
+		scan.scanError = err
+		logr.Warnf("%v, reporting its partial results", scan.scanError)
+		imageSpan.RecordError(scan.scanError)
+	case err != nil:
+		scan.scanError = fmt.Errorf("error executing trivy for image %s: %s", imageName, err)
+		logr.Error(scan.scanError)
+		imageSpan.RecordError(scan.scanError)
+	}
+	if scan.scanError == nil {
+		scan.sbomFile = s.generateSBOM(imageCtx, imageName)
+	}
+	return scan, false
+}
 
-	logr.Infof("Generating %s security benchmark report", benchmark)
-	reportGenerator := &AreaReport{
-		AreaLabelName: s.config.AreaLabels,
-		TeamLabelName: s.config.TeamsLabels,
+// resolveImageName applies the image name replacement, the image name being unchanged when the replacement is invalid
+func (s *Scanner) resolveImageName(imageName string) string {
+	resolvedImageName, err := s.stringReplacement(imageName, s.config.ImageNameReplacement)
+	if err != nil {
+		logr.Errorf("Error string replacement failed, image_name : %s, image_replacement_string: %s, error: %s", imageName, s.config.ImageNameReplacement, err)
+	}
+	return resolvedImageName
+}
+
+// collect receives the images scanned by the workers until the results channel is closed, so that the scanned images,
+// the stream and the checkpoint are written by a single goroutine. The results of the scanned images are spilled to
+// disk once streamed and recorded when a spill store is configured. The scanned images are sorted by name to be
+// independent of the workers scheduling
+func (s *Scanner) collect(results <-chan ScannedImage, collected chan<- []ScannedImage, checkpoint *checkpoint, spill *spillStore, stop context.CancelFunc) {
+	var scannedImages []ScannedImage
+	for scannedImage := range results {
+		s.stream(scannedImage)
+		if finding, failed := s.config.FailFast.failure(scannedImage, time.Now()); failed && s.failFastFinding == "" {
+			logr.Warnf("Stopping the scan, %s fails the scan", finding)
+			s.failFastFinding = finding
+			stop()
+		}
+		checkpoint.record(scannedImage)
+		spill.spill(&scannedImage)
+		scannedImages = append(scannedImages, scannedImage)
+	}
+	sort.SliceStable(scannedImages, func(i, j int) bool {
+		return scannedImages[i].ImageName < scannedImages[j].ImageName
+	})
+	collected <- scannedImages
+}
+
+// groupImageNamesByDigest groups the names of the images sharing the same digest, for instance several tags of the
+// same image, so that each digest is pulled and scanned once. Images of unknown digest are grouped by name.
+// The groups are sorted by the exposure of their containers, the most exposed first, and then by their first image name
+func groupImageNamesByDigest(imageList map[string][]k8s.ContainerSummary) [][]string {
+	var imageNames []string
+	for imageName := range imageList {
+		imageNames = append(imageNames, imageName)
+	}
+	sort.Strings(imageNames)
+
+	var groups [][]string
+	groupIndexByDigest := make(map[string]int)
+	for _, imageName := range imageNames {
+		digest := imageDigest(imageName, imageList[imageName])
+		if digest != "" {
+			if index, ok := groupIndexByDigest[digest]; ok {
+				groups[index] = append(groups[index], imageName)
+				continue
+			}
+			groupIndexByDigest[digest] = len(groups)
+		}
+		groups = append(groups, []string{imageName})
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groupExposureRank(groups[i], imageList) > groupExposureRank(groups[j], imageList)
+	})
+	return groups
+}
+
+// groupExposureRank returns the highest exposure rank of the containers running the images of the group
+func groupExposureRank(imageNames []string, imageList map[string][]k8s.ContainerSummary) int {
+	rank := 0
+	for _, imageName := range imageNames {
+		if imageRank := k8s.ExposureRank(k8s.HighestExposure(imageList[imageName])); imageRank > rank {
+			rank = imageRank
+		}
+	}
+	return rank
+}
+
+// Digest returns the digest of the image run by its containers, or referenced by its name. It is empty when unknown
+func (i ScannedImage) Digest() string {
+	return imageDigest(i.ImageName, i.Containers)
+}
+
+// imageDigest returns the digest of the image run by the containers, or referenced by the image name. It is empty when unknown
+func imageDigest(imageName string, containers []k8s.ContainerSummary) string {
+	for _, container := range containers {
+		if container.ImageDigest != "" {
+			return container.ImageDigest
+		}
+	}
+	if _, digest, found := strings.Cut(imageName, "@"); found {
+		return digest
+	}
+	return ""
+}
+
+// imagePresent returns whether the image is present on the host before its pull. The image is considered present when
+// its presence cannot be determined, so that it is not removed
+func (s *Scanner) imagePresent(ctx context.Context, imageName string) bool {
+	present, err := s.dockerClient.ImageExists(ctx, imageName)
+	if err != nil {
+		logr.Warnf("Unable to determine whether image %s is present before its pull, it is not removed: %v", imageName, err)
+		return true
+	}
+	if present {
+		logr.Infof("Image %s is present on the host before its pull, it is not removed", imageName)
+	}
+	return present
+}
+
+func (s *Scanner) removeImage(ctx context.Context, imageName string) error {
+	_, span := s.config.Tracer.Start(ctx, "docker rmi")
+	defer span.Finish()
+	err := s.dockerClient.RmiImage(imageName)
+	span.RecordError(err)
+	if err != nil {
+		logr.Errorf("Error executing docker rmi for image %s: %v", imageName, err)
+	}
+	return err
+}
+
+// trivyCacheDir returns the trivy cache directory the database is downloaded to, empty for the trivy default
+func (s *Scanner) trivyCacheDir() string {
+	if s.config.TrivyDB.CacheDir == "" && s.config.ScratchDir != "" {
+		return scratchTrivyCacheDir(s.config.ScratchDir)
+	}
+	return s.config.TrivyDB.CacheDir
+}
+
+func (s *Scanner) downloadDatabase(ctx context.Context) error {
+	_, span := s.config.Tracer.Start(ctx, "trivy download db")
+	defer span.Finish()
+	if err := s.trivyClient.DownloadDatabase(ctx, "image"); err != nil {
+		err = fmt.Errorf("failed to download trivy db: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+func (s *Scanner) trivyScan(ctx context.Context, imageName string) (*TrivyOutput, error) {
+	_, span := s.config.Tracer.Start(ctx, "trivy scan")
+	defer span.Finish()
+	span.SetAttribute("image", imageName)
+	var (
+		trivyOutput *TrivyOutput
+		timeoutErr  *ScanTimeoutError
+	)
+	err := s.retry(ctx, fmt.Sprintf("trivy scan of image %s", imageName), func() error {
+		var err error
+		trivyOutput, err = s.trivyClient.ScanImage(ctx, imageName)
+		if errors.As(err, &timeoutErr) {
+			// the scan would time out again, it is not retried
+			return nil
+		}
+		return err
+	})
+	if err == nil && timeoutErr != nil {
+		err = timeoutErr
+	}
+	span.RecordError(err)
+	if trivyOutput == nil {
+		return nil, err
+	}
+	if trivyOutput.CreatedAt.IsZero() {
+		trivyOutput.CreatedAt = time.Now().UTC()
 	}
-	return reportGenerator.GenerateVulnerabilityReport(nil)
+	setPlatform(trivyOutput.Results, trivyOutput.Metadata.ImageConfig.Platform())
+	attributeLayers(trivyOutput.Results, trivyOutput.Metadata.ImageConfig)
+	s.enrich(trivyOutput)
+	return trivyOutput, err
+}
+
+// enrich classifies the licenses, removes the unfixed vulnerabilities, overrides the severities, marks the known
+// exploited vulnerabilities, applies the unknown severity policy, normalises the severities, removes the
+// vulnerabilities of the severities not reported and below the minimum CVSS score, and scores and sorts the
+// vulnerabilities of the trivy output according to the config. The known exploited vulnerabilities are marked first so
+// that the policy keeps them, the severities are normalised before they are filtered so that the vulnerabilities are
+// reported and counted by their normalised severity, and the vulnerabilities are filtered before their advisories are
+// requested once the images are scanned, see AdvisoryDatabase.enrich
+func (s *Scanner) enrich(trivyOutput *TrivyOutput) {
+	if s.config.ScanLicenses {
+		s.config.LicensePolicy.apply(trivyOutput.Results)
+	}
+	if s.config.IgnoreUnfixed {
+		filterUnfixed(trivyOutput.Results)
+	}
+	s.config.SeverityOverrides.apply(trivyOutput.Results)
+	s.config.KEVCatalog.mark(trivyOutput.Results)
+	applyUnknownSeverityPolicy(trivyOutput.Results, s.config.UnknownSeverity)
+	normalizeSeverities(trivyOutput.Results)
+	filterBySeverity(trivyOutput.Results, s.config.Severity)
+	if s.config.MinCVSSScore > 0 {
+		filterByCVSSScore(trivyOutput.Results, s.config.MinCVSSScore)
+	}
+	s.config.EPSSDataset.mark(trivyOutput.Results)
+	sortTrivyVulnerabilities(trivyOutput.Results)
+	if s.config.SortByCVSS {
+		sortByCVSSScore(trivyOutput.Results)
+	}
+}
+
+// stream writes the scanned image as a json line to the stream when set. Streaming is best effort so that
+// errors are logged rather than failing the scan
+func (s *Scanner) stream(scannedImage ScannedImage) {
+	if s.config.Stream == nil {
+		return
+	}
+	if err := json.NewEncoder(s.config.Stream).Encode(scannedImage); err != nil {
+		logr.Errorf("Error streaming scan result of image %s: %v", scannedImage.ImageName, err)
+	}
+}
+
+// SupportedBenchmarks lists the security compliance benchmarks trivy can run against a cluster
+var SupportedBenchmarks = []string{"k8s-cis", "k8s-nsa", "k8s-pss-baseline", "k8s-pss-restricted"}
+
+// CisScan perform trivy compliance scans and combine the results of all the benchmarks in a single report
+func (s *Scanner) CisScan(benchmarks []string) (*CombinedComplianceReport, error) {
+	var reports []*ComplianceReport
+	for _, benchmark := range benchmarks {
+		logr.Infof("Running %s security benchmark. Please wait...", benchmark)
+
+		trivyOutput, err := s.trivyClient.CisScan(benchmark)
+		if err != nil {
+			return nil, fmt.Errorf("error executing trivy %s cluster scan: %v", benchmark, err)
+		}
+		report := NewComplianceReport(trivyOutput)
+		if report.ID == "" {
+			report.ID = benchmark
+		}
+		reports = append(reports, report)
+	}
+
+	logr.Infof("Generating security benchmarks report")
+	return NewCombinedComplianceReport(reports), nil
 }
 
 const (
@@ -262,6 +1301,79 @@ var severityScores = map[string]int{
 	"CRITICAL": critical, "HIGH": high, "MEDIUM": medium, "LOW": low, "UNKNOWN": unknown,
 }
 
+// ContainerTypes returns the types of the init and ephemeral containers running the image, for instance "init",
+// so that the report flags the images not run by regular containers. It is empty when only regular containers run the image
+func (i ScannedImage) ContainerTypes() string {
+	var types []string
+	for _, containerType := range []k8s.ContainerType{k8s.InitContainer, k8s.EphemeralContainer} {
+		for _, container := range i.Containers {
+			if container.Type == containerType {
+				types = append(types, string(containerType))
+				break
+			}
+		}
+	}
+	return strings.Join(types, ", ")
+}
+
+// WorkloadContext returns the context annotations of the containers running the image, for instance
+// "data-classification=pii, tier=1", so that the report shows the business criticality of the workloads next to their
+// findings. The distinct values of an annotation are joined with a slash, and the annotations are sorted by name
+func (i ScannedImage) WorkloadContext() string {
+	values := make(map[string][]string)
+	for _, container := range i.Containers {
+		for key, value := range container.Annotations {
+			if !containsString(values[key], value) {
+				values[key] = append(values[key], value)
+			}
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var context []string
+	for _, key := range keys {
+		sort.Strings(values[key])
+		context = append(context, key+"="+strings.Join(values[key], "/"))
+	}
+	return strings.Join(context, ", ")
+}
+
+// NewSkippedImage creates a ScannedImage for an image not scanned as larger than the maximum image size
+func NewSkippedImage(imageName string, containers []k8s.ContainerSummary, imageSize int64) ScannedImage {
+	i := NewScannedImage(imageName, containers, nil, nil)
+	i.Skipped = true
+	i.ImageSize = imageSize
+	return i
+}
+
+// newTrivyScannedImage creates a ScannedImage with the results and the operating system of the trivy output,
+// which is nil when the scan failed, or partial when the scan timed out
+func newTrivyScannedImage(imageName string, containers []k8s.ContainerSummary, trivyOutput *TrivyOutput, scanError error) ScannedImage {
+	var timeoutErr *ScanTimeoutError
+	var i ScannedImage
+	if trivyOutput == nil {
+		i = NewScannedImage(imageName, containers, nil, scanError)
+	} else {
+		i = NewScannedImage(imageName, containers, trivyOutput.Results, scanError)
+		i.OS = trivyOutput.Metadata.OS
+		if !trivyOutput.CreatedAt.IsZero() {
+			scanTime := trivyOutput.CreatedAt
+			i.ScanTime = &scanTime
+		}
+	}
+	i.TimedOut = errors.As(scanError, &timeoutErr)
+	return i
+}
+
+// EndOfLife returns true when the operating system of the image reached its end of life, so that the vulnerabilities
+// of its packages are not fixed anymore
+func (i ScannedImage) EndOfLife() bool {
+	return i.OS != nil && i.OS.EOSL
+}
+
 // NewScannedImage created a new ScannedImage with all fields initialised
 func NewScannedImage(imageName string, containers []k8s.ContainerSummary, trivyOutput []TrivyOutputResults, scanError error) ScannedImage {
 	i := ScannedImage{
@@ -269,6 +1381,8 @@ func NewScannedImage(imageName string, containers []k8s.ContainerSummary, trivyO
 		Containers:         containers,
 		TrivyOutputResults: trivyOutput,
 		ScanError:          scanError,
+		Platforms:          resultPlatforms(trivyOutput),
+		Exposure:           k8s.HighestExposure(containers),
 	}
 	i.VulnerabilitySummary = i.buildVulnerabilitySummary()
 	return i
@@ -276,12 +1390,30 @@ func NewScannedImage(imageName string, containers []k8s.ContainerSummary, trivyO
 
 func (i *ScannedImage) buildVulnerabilitySummary() VulnerabilitySummary {
 	severityMap := make(map[string]int)
+	fixableSeverityMap := make(map[string]int)
 	for severity := range severityScores {
 		severityMap[severity] = 0
+		fixableSeverityMap[severity] = 0
 	}
-	for _, target := range i.TrivyOutputResults {
+	var fixableCount, unfixableCount, knownExploitedCount, baseImageLayerCount, applicationLayerCount int
+	for _, target := range i.Results() {
 		for _, vulnerability := range target.Vulnerabilities {
 			severityMap[vulnerability.Severity] = severityMap[vulnerability.Severity] + 1
+			if vulnerability.KnownExploited != nil {
+				knownExploitedCount++
+			}
+			switch vulnerability.LayerOrigin {
+			case LayerBaseImage:
+				baseImageLayerCount++
+			case LayerApplication:
+				applicationLayerCount++
+			}
+			if vulnerability.Fixable() {
+				fixableSeverityMap[vulnerability.Severity]++
+				fixableCount++
+			} else {
+				unfixableCount++
+			}
 		}
 	}
 
@@ -290,11 +1422,22 @@ func (i *ScannedImage) buildVulnerabilitySummary() VulnerabilitySummary {
 		score := severityScores[severity]
 		severityScore = severityScore + count*score
 	}
-	return VulnerabilitySummary{
-		ContainerCount:               len(i.Containers),
-		SeverityScore:                severityScore,
-		TotalVulnerabilityBySeverity: severityMap,
+	summary := VulnerabilitySummary{
+		ContainerCount:                 len(i.Containers),
+		WorkloadCount:                  workloadCount(i.Containers),
+		ScoringMode:                    i.VulnerabilitySummary.ScoringMode,
+		TotalVulnerabilityBySeverity:   severityMap,
+		FixableCount:                   fixableCount,
+		UnfixableCount:                 unfixableCount,
+		FixableVulnerabilityBySeverity: fixableSeverityMap,
+		KnownExploitedCount:            knownExploitedCount,
+		BaseImageLayerCount:            baseImageLayerCount,
+		ApplicationLayerCount:          applicationLayerCount,
 	}
+	summary.ContainerWeightedScore = severityScore * summary.ContainerCount
+	summary.WorkloadWeightedScore = severityScore * summary.WorkloadCount
+	summary.SeverityScore = summary.scoreOfMode(severityScore)
+	return summary
 }
 
 func (s *Scanner) stringReplacement(imageName string, stringReplacement string) (string, error) {
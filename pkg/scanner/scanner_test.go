@@ -1,12 +1,25 @@
 package scanner
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/tracing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -118,6 +131,26 @@ var _ = Describe("Scanner", func() {
 			Expect(image.Containers).To(Equal(containers))
 		})
 
+		It("counts the vulnerabilities with and without fixed version", func() {
+			trivyOutput := []TrivyOutputResults{
+				{
+					Vulnerabilities: []Vulnerabilities{
+						{Severity: "CRITICAL", FixedVersion: "3.0.8"},
+						{Severity: "CRITICAL"},
+						{Severity: "HIGH", FixedVersion: "1.2.13"},
+						{Severity: "LOW"},
+					},
+				},
+			}
+
+			image := NewScannedImage("image", nil, trivyOutput, nil)
+			Expect(image.VulnerabilitySummary.FixableCount).To(Equal(2))
+			Expect(image.VulnerabilitySummary.UnfixableCount).To(Equal(2))
+			Expect(image.VulnerabilitySummary.FixableVulnerabilityBySeverity).To(Equal(
+				map[string]int{"CRITICAL": 1, "HIGH": 1, "MEDIUM": 0, "LOW": 0, "UNKNOWN": 0}),
+			)
+		})
+
 		Context("when an scan error occurs", func() {
 			It("captures the error and the container details", func() {
 				containers := []k8s.ContainerSummary{{Image: "image1"}}
@@ -138,64 +171,1012 @@ var _ = Describe("Scanner", func() {
 			})
 		})
 
+		It("flags the init and ephemeral containers running the image", func() {
+			regular := k8s.ContainerSummary{Image: "busybox"}
+			initContainer := k8s.ContainerSummary{Image: "busybox", Type: k8s.InitContainer}
+			ephemeral := k8s.ContainerSummary{Image: "busybox", Type: k8s.EphemeralContainer}
+
+			Expect(NewScannedImage("busybox", []k8s.ContainerSummary{regular}, nil, nil).ContainerTypes()).To(BeEmpty())
+			Expect(NewScannedImage("busybox", []k8s.ContainerSummary{regular, initContainer}, nil, nil).ContainerTypes()).To(Equal("init"))
+			Expect(NewScannedImage("busybox", []k8s.ContainerSummary{ephemeral, initContainer}, nil, nil).ContainerTypes()).To(Equal("init, ephemeral"))
+		})
+
+		It("summarises the context annotations of the containers running the image", func() {
+			web := k8s.ContainerSummary{Image: "web", Annotations: map[string]string{"tier": "1", "data-classification": "pii"}}
+			admin := k8s.ContainerSummary{Image: "web", Annotations: map[string]string{"tier": "2", "data-classification": "pii"}}
+
+			Expect(NewScannedImage("web", []k8s.ContainerSummary{{Image: "web"}}, nil, nil).WorkloadContext()).To(BeEmpty())
+			Expect(NewScannedImage("web", []k8s.ContainerSummary{web, admin}, nil, nil).WorkloadContext()).To(Equal("data-classification=pii, tier=1/2"))
+		})
 	})
 
-	Describe("scan processing", func() {
-		const (
-			areaLabel = "area-label"
-		)
+	Describe("scan processing", func() {
+		const (
+			areaLabel = "area-label"
+		)
+
+		var (
+			scan                 *Scanner
+			mockKubernetesClient *k8stest.KubernetesClient
+			mockTrivyClient      *mockTrivy
+			mockDockerClient     *mockDocker
+		)
+
+		BeforeEach(func() {
+			mockKubernetesClient = &k8stest.KubernetesClient{}
+			mockTrivyClient = &mockTrivy{}
+			mockDockerClient = &mockDocker{}
+			mockDockerClient.On("ImageExists", mock.Anything).Return(false, nil).Maybe()
+			scan = &Scanner{
+				config: &Config{
+					Workers:              3,
+					FilterLabels:         areaLabel,
+					Severity:             "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL",
+					ImageNameReplacement: "replace-this-registry|registry",
+				},
+				kubernetesClient: mockKubernetesClient,
+				trivyClient:      mockTrivyClient,
+				dockerClient:     mockDockerClient,
+			}
+			mockKubernetesClient.On("GetServerVersion").Return("v1.27.3", nil)
+			mockTrivyClient.On("Version").Return(&TrivyVersion{Version: "0.45.0"}, nil)
+		})
+
+		It("should delete the pulled docker images once the scan is complete", func() {
+			// given
+			containers := []k8s.ContainerSummary{
+				{
+					Image:   "alpine:3.11.0",
+					PodName: "pod1",
+				},
+				{
+					Image:   "replace-this-registry/image:0.1",
+					PodName: "pod1",
+				},
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.
+				On("PullImage", "alpine:3.11.0").Return(nil).
+				On("PullImage", "registry/image:0.1").Return(nil)
+			mockTrivyClient.
+				On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil).
+				On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil)
+			mockDockerClient.
+				On("RmiImage", "alpine:3.11.0").Return(nil).
+				On("RmiImage", "registry/image:0.1").Return(nil)
+
+			// when
+			_, err := scan.ScanImages(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should not delete the images present on the host before the scan", func() {
+			// given
+			dockerClient := &mockDocker{}
+			scan.dockerClient = dockerClient
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{
+				{Image: "alpine:3.11.0", PodName: "pod1"},
+				{Image: "local/dev:latest", PodName: "pod2"},
+				{Image: "unknown:1.0", PodName: "pod3"},
+			}, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockTrivyClient.On("ScanImage", mock.Anything).Return(&TrivyOutput{}, nil)
+			dockerClient.
+				On("ImageExists", "alpine:3.11.0").Return(false, nil).
+				On("ImageExists", "local/dev:latest").Return(true, nil).
+				On("ImageExists", "unknown:1.0").Return(false, fmt.Errorf("cannot connect to the docker daemon")).
+				On("PullImage", mock.Anything).Return(nil).
+				On("RmiImage", "alpine:3.11.0").Return(nil)
+
+			// when
+			_, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			dockerClient.AssertNumberOfCalls(GinkgoT(), "PullImage", 3)
+			dockerClient.AssertNumberOfCalls(GinkgoT(), "RmiImage", 1)
+		})
+
+		It("should keep the pulled images when requested", func() {
+			// given
+			dockerClient := &mockDocker{}
+			scan.dockerClient = dockerClient
+			scan.config.KeepImages = true
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+			dockerClient.On("PullImage", "alpine:3.11.0").Return(nil)
+
+			// when
+			_, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			dockerClient.AssertNotCalled(GinkgoT(), "ImageExists", mock.Anything)
+			dockerClient.AssertNotCalled(GinkgoT(), "RmiImage", mock.Anything)
+		})
+
+		It("should record the cluster and trivy versions in the report metadata", func() {
+			// given
+			scan.config.ClusterName = "sandbox"
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{}, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Metadata.ClusterName).To(Equal("sandbox"))
+			Expect(report.Metadata.KubernetesVersion).To(Equal("v1.27.3"))
+			Expect(report.Metadata.TrivyVersion).To(Equal("0.45.0"))
+			Expect(report.Metadata.ScanTime).NotTo(BeZero())
+		})
+
+		It("should return the scanned images sorted by name whatever the workers scheduling", func() {
+			// given
+			imageNames := []string{"nginx:1.25", "alpine:3.11.0", "redis:7", "busybox:1.36", "debian:12", "ubuntu:22.04"}
+			var containers []k8s.ContainerSummary
+			for i, image := range imageNames {
+				containers = append(containers, k8s.ContainerSummary{Image: image, PodName: fmt.Sprintf("pod%d", i)})
+				mockDockerClient.On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+				mockTrivyClient.On("ScanImage", image).Return(&TrivyOutput{}, nil)
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			var scannedImageNames []string
+			for _, image := range report.ScannedImages {
+				scannedImageNames = append(scannedImageNames, image.ImageName)
+			}
+			Expect(scannedImageNames).To(Equal([]string{"alpine:3.11.0", "busybox:1.36", "debian:12", "nginx:1.25", "redis:7", "ubuntu:22.04"}))
+		})
+
+		It("should scan the images of the most exposed containers first and record their exposure", func() {
+			// given
+			scan.config.Workers = 1
+			containers := []k8s.ContainerSummary{
+				{Image: "alpine:3.11.0", PodName: "pod1"},
+				{Image: "nginx:1.25", PodName: "pod2", Exposure: k8s.ExposureInternet},
+				{Image: "redis:7", PodName: "pod3", Exposure: k8s.ExposureCluster},
+				{Image: "redis:7", PodName: "pod4", Exposure: k8s.ExposureNode},
+			}
+			var scanOrder []string
+			for _, image := range []string{"alpine:3.11.0", "nginx:1.25", "redis:7"} {
+				image := image
+				mockDockerClient.On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+				mockTrivyClient.On("ScanImage", image).Return(&TrivyOutput{}, nil).Run(func(mock.Arguments) {
+					scanOrder = append(scanOrder, image)
+				})
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scanOrder).To(Equal([]string{"nginx:1.25", "redis:7", "alpine:3.11.0"}))
+			Expect(report.ScannedImages[0].Exposure).To(BeEmpty())
+			Expect(report.ScannedImages[1].Exposure).To(Equal(k8s.ExposureInternet))
+			Expect(report.ScannedImages[2].Exposure).To(Equal(k8s.ExposureNode))
+		})
+
+		It("should stream each scanned image as a json line", func() {
+			// given
+			var stream bytes.Buffer
+			scan.config.Stream = &stream
+			containers := []k8s.ContainerSummary{
+				{Image: "alpine:3.11.0", PodName: "pod1"},
+				{Image: "replace-this-registry/image:0.1", PodName: "pod2"},
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			for _, image := range []string{"alpine:3.11.0", "registry/image:0.1"} {
+				mockDockerClient.On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+			}
+			mockTrivyClient.
+				On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil).
+				On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, fmt.Errorf("some trivy error"))
+
+			// when
+			_, err := scan.ScanImages(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+
+			// then
+			lines := strings.Split(strings.TrimSpace(stream.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			var streamed []ScannedImage
+			for _, line := range lines {
+				var image ScannedImage
+				Expect(json.Unmarshal([]byte(line), &image)).To(Succeed())
+				streamed = append(streamed, image)
+			}
+			Expect([]string{streamed[0].ImageName, streamed[1].ImageName}).To(ConsistOf("alpine:3.11.0", "registry/image:0.1"))
+		})
+
+		It("should trace the scan of each image", func() {
+			// given
+			exporter := &spanRecorder{}
+			scan.config.Tracer = tracing.New(exporter)
+			containers := []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(fmt.Errorf("some docker error")).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+			// when
+			_, err := scan.ScanImages(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scan.config.Tracer.Shutdown()).To(Succeed())
+
+			// then
+			spansByName := make(map[string]*tracing.Span)
+			for _, span := range exporter.spans {
+				spansByName[span.Name] = span
+			}
+			Expect(spansByName).To(HaveLen(6))
+			root := spansByName["ScanImages"]
+			Expect(root.Attributes).To(HaveKeyWithValue("image_count", "1"))
+			Expect(spansByName["trivy download db"].ParentSpanID).To(Equal(root.SpanID))
+			Expect(spansByName["scan image"].ParentSpanID).To(Equal(root.SpanID))
+			Expect(spansByName["scan image"].Attributes).To(HaveKeyWithValue("image", "alpine:3.11.0"))
+			for _, name := range []string{"docker pull", "trivy scan", "docker rmi"} {
+				Expect(spansByName[name].TraceID).To(Equal(root.TraceID))
+				Expect(spansByName[name].ParentSpanID).To(Equal(spansByName["scan image"].SpanID))
+			}
+			Expect(spansByName["docker pull"].Error).To(Equal("some docker error"))
+		})
+
+		It("should scan once the images sharing the same digest", func() {
+			// given
+			containers := []k8s.ContainerSummary{
+				{Image: "nginx:1.25", PodName: "pod1", ImageDigest: "sha256:4ff3ca91"},
+				{Image: "nginx:stable", PodName: "pod2", ImageDigest: "sha256:4ff3ca91"},
+				{Image: "registry.com/nginx@sha256:4ff3ca91", PodName: "pod3"},
+				{Image: "alpine:3.11.0", PodName: "pod4"},
+			}
+			vulnerabilities := &TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2023-1", Severity: "HIGH"}}}}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			for _, image := range []string{"nginx:1.25", "alpine:3.11.0"} {
+				mockDockerClient.On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+			}
+			mockTrivyClient.
+				On("ScanImage", "nginx:1.25").Return(vulnerabilities, nil).
+				On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages).To(HaveLen(4))
+			for _, image := range report.ScannedImages[1:] {
+				Expect(image.TrivyOutputResults).To(Equal(vulnerabilities.Results))
+				Expect(image.Containers).To(HaveLen(1))
+			}
+			Expect(report.ScannedImages[3].ImageName).To(Equal("registry.com/nginx@sha256:4ff3ca91"))
+			Expect(report.ScannedImages[3].Containers[0].PodName).To(Equal("pod3"))
+			mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 2)
+			mockDockerClient.AssertNumberOfCalls(GinkgoT(), "PullImage", 2)
+		})
+
+		It("should classify the package licenses against the license policy", func() {
+			// given
+			scan.config.ScanLicenses = true
+			scan.config.LicensePolicy = &LicensePolicy{Denied: []string{"AGPL-*"}}
+			containers := []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{{
+				Target: "OS Packages",
+				Class:  "license",
+				Licenses: []License{
+					{PkgName: "musl", Name: "MIT", Category: "notice"},
+					{PkgName: "ghostscript", Name: "AGPL-3.0", Category: "forbidden"},
+				},
+			}}}, nil)
+			mockDockerClient.On("RmiImage", "alpine:3.11.0").Return(nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.AreaSummary["all"].Teams["all"].LicenseViolations()).To(Equal([]LicenseFinding{{
+				ImageName: "alpine:3.11.0",
+				License:   License{PkgName: "ghostscript", Name: "AGPL-3.0", Category: "forbidden", Violation: "denied by the license policy"},
+			}}))
+		})
+
+		It("should only report the vulnerabilities scoring at least the minimum CVSS score", func() {
+			// given
+			var advisoryRequests []string
+			advisoryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				advisoryRequests = append(advisoryRequests, r.URL.Query().Get("cveId"))
+				_, _ = w.Write([]byte(`{"vulnerabilities":[]}`))
+			}))
+			defer advisoryServer.Close()
+			scan.config.Advisories = NewAdvisoryDatabase(AdvisoryConfig{NVDAPIURL: advisoryServer.URL, CacheDir: GinkgoT().TempDir()})
+			scan.config.Advisories.nvd.interval = 0
+			scan.config.MinCVSSScore = 7.0
+			containers := []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-1", Severity: "HIGH", CVSS: map[string]CVSS{"nvd": {V3Score: 8.8}}},
+				{VulnerabilityID: "CVE-2023-2", Severity: "HIGH", CVSS: map[string]CVSS{"nvd": {V3Score: 6.5}}},
+			}}}}, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(1))
+			Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+			Expect(advisoryRequests).To(Equal([]string{"CVE-2023-1"}))
+		})
+
+		It("should not scan the images of the containers opted out of the scans and list their workloads", func() {
+			// given
+			containers := []k8s.ContainerSummary{
+				{Image: "alpine:3.11.0", PodName: "pod1", Namespace: "shop", Workload: "deployment/web"},
+				{Image: "alpine:3.11.0", PodName: "pod2", Namespace: "vendor", Workload: "deployment/appliance", SkipScanReason: "scanned by the vendor"},
+				{Image: "appliance:2.0", PodName: "pod2", Namespace: "vendor", Workload: "deployment/appliance", SkipScanReason: "scanned by the vendor"},
+				{Image: "appliance:2.0", PodName: "pod3", Namespace: "vendor", Workload: "deployment/appliance", SkipScanReason: "scanned by the vendor"},
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages).To(HaveLen(1))
+			Expect(report.ScannedImages[0].Containers).To(HaveLen(1))
+			Expect(report.ScanOptOuts).To(Equal([]ScanOptOut{
+				{Namespace: "vendor", Workload: "deployment/appliance", Reason: "scanned by the vendor", Images: []string{"alpine:3.11.0", "appliance:2.0"}},
+			}))
+			mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 1)
+		})
+
+		It("should only scan the images of the pods created or updated since the requested duration", func() {
+			// given
+			scan.config.Since = 24 * time.Hour
+			lastWeek, lastHour := time.Now().Add(-7*24*time.Hour), time.Now().Add(-time.Hour)
+			containers := []k8s.ContainerSummary{
+				{Image: "alpine:3.11.0", PodName: "pod1", ChangedAt: &lastWeek},
+				{Image: "alpine:3.11.0", PodName: "pod2", ChangedAt: &lastHour},
+				{Image: "appliance:2.0", PodName: "pod3", ChangedAt: &lastWeek},
+				{Image: "legacy:1.0", PodName: "deployment/legacy"},
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages).To(HaveLen(1))
+			Expect(report.ScannedImages[0].Containers).To(HaveLen(2))
+			Expect(report.Metadata.ChangedSince).NotTo(BeNil())
+			Expect(*report.Metadata.ChangedSince).To(BeTemporally("~", time.Now().Add(-24*time.Hour), time.Minute))
+			mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 1)
+		})
+
+		It("should neither report nor count the unfixed vulnerabilities when ignored", func() {
+			// given
+			scan.config.IgnoreUnfixed = true
+			containers := []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-1", Severity: "CRITICAL", FixedVersion: "1.2.3"},
+				{VulnerabilityID: "CVE-2023-2", Severity: "CRITICAL"},
+			}}}}, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(1))
+			Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2023-1"))
+			Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["CRITICAL"]).To(Equal(1))
+		})
+
+		It("should only report and count the vulnerabilities of the reported severities once overridden", func() {
+			// given
+			scan.config.Severity = "CRITICAL,HIGH"
+			scan.config.SeverityOverrides = &SeverityOverrides{Overrides: []SeverityOverride{{VulnerabilityID: "CVE-2023-2", Severity: "LOW", Justification: "not reachable"}}}
+			containers := []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-1", Severity: "CRITICAL"},
+				{VulnerabilityID: "CVE-2023-2", Severity: "HIGH"},
+			}}}}, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(1))
+			Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["LOW"]).To(Equal(0))
+			Expect(report.ScannedImages[0].VulnerabilitySummary.SeverityScore).To(Equal(critical))
+		})
+
+		It("should flag the images running an end-of-life operating system", func() {
+			// given
+			containers := []k8s.ContainerSummary{{Image: "debian:9", PodName: "pod1"}, {Image: "debian:12", PodName: "pod2"}}
+			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockDockerClient.On("PullImage", mock.Anything).Return(nil).On("RmiImage", mock.Anything).Return(nil)
+			stretch := &TrivyOutput{}
+			stretch.Metadata.OS = &OS{Family: "debian", Name: "9.13", EOSL: true}
+			bookworm := &TrivyOutput{}
+			bookworm.Metadata.OS = &OS{Family: "debian", Name: "12.1"}
+			mockTrivyClient.On("ScanImage", "debian:9").Return(stretch, nil).On("ScanImage", "debian:12").Return(bookworm, nil)
+
+			// when
+			report, err := scan.ScanImages(context.Background())
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			endOfLifeImages := report.AreaSummary["all"].Teams["all"].EndOfLifeImages()
+			Expect(endOfLifeImages).To(HaveLen(1))
+			Expect(endOfLifeImages[0].ImageName).To(Equal("debian:9"))
+			Expect(endOfLifeImages[0].OS).To(Equal(&OS{Family: "debian", Name: "9.13", EOSL: true}))
+		})
+
+		Context("an image pull or scan fails temporarily", func() {
+			BeforeEach(func() {
+				scan.config.RetryBackoff = time.Millisecond
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", "alpine:3.11.0").Return(fmt.Errorf("toomanyrequests")).Once().
+					On("PullImage", "alpine:3.11.0").Return(nil).
+					On("RmiImage", "alpine:3.11.0").Return(nil)
+			})
+
+			It("should retry until the scan succeeds", func() {
+				// given
+				scan.config.Retries = 2
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, fmt.Errorf("connection reset")).Twice().
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages[0].ScanError).To(BeNil())
+				mockDockerClient.AssertNumberOfCalls(GinkgoT(), "PullImage", 2)
+				mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 3)
+			})
+
+			It("should report the scan error once the retries are exhausted", func() {
+				// given
+				scan.config.Retries = 1
+				mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, fmt.Errorf("connection reset"))
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages[0].ScanError).To(MatchError(ContainSubstring("connection reset")))
+				mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 2)
+			})
+
+			It("should report the partial results of a timed out scan without retrying it", func() {
+				// given
+				scan.config.Retries = 2
+				partialOutput := &TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2023-0001", Severity: "HIGH"}}}}}
+				mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(partialOutput, &ScanTimeoutError{Image: "alpine:3.11.0", Timeout: time.Minute})
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages[0].TimedOut).To(BeTrue())
+				Expect(report.ScannedImages[0].ScanError).To(MatchError("trivy scan of image alpine:3.11.0 timed out after 1m0s"))
+				Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+				Expect(report.TimedOutImageCount()).To(Equal(1))
+				mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 1)
+			})
+		})
+
+		Context("an image is larger than the maximum image size", func() {
+			BeforeEach(func() {
+				scan.config.MaxImageSize = 100
+				containers := []k8s.ContainerSummary{
+					{Image: "alpine:3.11.0", PodName: "pod1"},
+					{Image: "replace-this-registry/image:0.1", PodName: "pod2"},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("ImageSize", "alpine:3.11.0").Return(int64(10), nil).
+					On("ImageSize", "registry/image:0.1").Return(int64(1000), nil)
+				for _, image := range []string{"alpine:3.11.0", "registry/image:0.1"} {
+					mockDockerClient.On("PullImage", image).Return(nil).On("RmiImage", image).Return(nil)
+					mockTrivyClient.On("ScanImage", image).Return(&TrivyOutput{}, nil)
+				}
+			})
+
+			It("should record the image as skipped with its size without pulling it", func() {
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(2))
+				Expect(report.ScannedImages[0].Skipped).To(BeFalse())
+				Expect(report.ScannedImages[1].ImageName).To(Equal("registry/image:0.1"))
+				Expect(report.ScannedImages[1].Skipped).To(BeTrue())
+				Expect(report.ScannedImages[1].ImageSize).To(Equal(int64(1000)))
+				Expect(report.AreaSummary["all"].Teams["all"].SkippedImages()).To(HaveLen(1))
+				mockDockerClient.AssertNotCalled(GinkgoT(), "PullImage", "registry/image:0.1")
+				mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "registry/image:0.1")
+			})
+
+			It("should scan the image after the other images when requested", func() {
+				// given
+				scan.config.ScanOversizedImages = true
+				scan.config.OversizedImageWorkers = 1
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(2))
+				Expect(report.ScannedImages[1].Skipped).To(BeFalse())
+				mockTrivyClient.AssertCalled(GinkgoT(), "ScanImage", "registry/image:0.1")
+			})
+
+			It("should scan the images weighted by their size when requested", func() {
+				// given
+				scan.config.MaxImageSize = 0
+				scan.config.WeightScansBySize = true
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(2))
+				Expect(report.ScannedImages[1].Skipped).To(BeFalse())
+				mockDockerClient.AssertCalled(GinkgoT(), "ImageSize", "registry/image:0.1")
+				mockTrivyClient.AssertNumberOfCalls(GinkgoT(), "ScanImage", 2)
+			})
+		})
+
+		Context("the registry already scanned an image", func() {
+			It("should reuse the registry scan without pulling the image and scan the other images", func() {
+				// given
+				scan.config.Severity = "HIGH,CRITICAL"
+				scan.config.RegistryScans = &fakeImageScanSource{outputs: map[string]*TrivyOutput{
+					"registry/image:0.1": {Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+						{VulnerabilityID: "CVE-2023-0002", Severity: "LOW"},
+						{VulnerabilityID: "CVE-2023-0003", Severity: "HIGH"},
+						{VulnerabilityID: "CVE-2023-0001", Severity: "CRITICAL"},
+					}}}},
+				}}
+				containers := []k8s.ContainerSummary{
+					{Image: "alpine:3.11.0", PodName: "pod1"},
+					{Image: "replace-this-registry/image:0.1", PodName: "pod2"},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+				mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(2))
+				Expect(report.ScannedImages[1].ImageName).To(Equal("registry/image:0.1"))
+				vulnerabilities := report.ScannedImages[1].TrivyOutputResults[0].Vulnerabilities
+				Expect(vulnerabilities).To(HaveLen(2))
+				Expect(vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2023-0001"))
+				Expect(vulnerabilities[1].VulnerabilityID).To(Equal("CVE-2023-0003"))
+				mockDockerClient.AssertNotCalled(GinkgoT(), "PullImage", "registry/image:0.1")
+				mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "registry/image:0.1")
+				mockTrivyClient.AssertCalled(GinkgoT(), "ScanImage", "alpine:3.11.0")
+			})
+
+			It("should scan the image with trivy when the registry scan is older than the maximum result age", func() {
+				// given
+				scan.config.MaxResultAge = 24 * time.Hour
+				scan.config.RegistryScans = &fakeImageScanSource{outputs: map[string]*TrivyOutput{
+					"alpine:3.11.0": {CreatedAt: time.Now().Add(-48 * time.Hour)},
+				}}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+				mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(1))
+				Expect(report.ScannedImages[0].TrivyDBVersion).To(BeZero())
+				Expect(report.ScannedImages[0].TrivyDBUpdatedAt).NotTo(BeNil())
+				mockTrivyClient.AssertCalled(GinkgoT(), "ScanImage", "alpine:3.11.0")
+			})
+		})
+
+		Context("SBOMs are generated", func() {
+			It("should write the SBOM of the pulled image and record it on the scanned images", func() {
+				// given
+				scan.config.SBOMDir = GinkgoT().TempDir()
+				scan.config.SBOMVulnerabilities = true
+				containers := []k8s.ContainerSummary{{Image: "replace-this-registry/image:0.1", PodName: "pod1"}}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.On("PullImage", "registry/image:0.1").Return(nil).On("RmiImage", "registry/image:0.1").Return(nil)
+				mockTrivyClient.On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil)
+				mockTrivyClient.On("SBOM", "registry/image:0.1", true).Return([]byte(`{"bomFormat":"CycloneDX"}`), nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				sbomFile := report.ScannedImages[0].SBOMFile
+				Expect(sbomFile).To(Equal(filepath.Join(scan.config.SBOMDir, "registry_image_0.1.cdx.json")))
+				Expect(os.ReadFile(sbomFile)).To(Equal([]byte(`{"bomFormat":"CycloneDX"}`)))
+			})
+		})
+
+		Context("raw trivy outputs are saved", func() {
+			It("should write the compressed trivy output by digest and record it on the scanned images", func() {
+				// given
+				scan.config.RawTrivyOutputDir = GinkgoT().TempDir()
+				containers := []k8s.ContainerSummary{
+					{Image: "replace-this-registry/image:0.1", PodName: "pod1"},
+					{Image: "alpine:3.11.0", PodName: "pod2"},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.On("PullImage", mock.Anything).Return(nil).On("RmiImage", mock.Anything).Return(nil)
+				withDigest := &TrivyOutput{Raw: []byte(`{"ArtifactName":"registry/image:0.1"}`)}
+				withDigest.Metadata.RepoDigests = []string{"registry/image@sha256:4ff3"}
+				mockTrivyClient.
+					On("ScanImage", "registry/image:0.1").Return(withDigest, nil).
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Raw: []byte(`{"ArtifactName":"alpine:3.11.0"}`)}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				rawOutputFiles := make(map[string][]string)
+				for _, image := range report.ScannedImages {
+					rawOutputFiles[image.ImageName] = image.RawTrivyOutputFiles
+				}
+				Expect(rawOutputFiles["registry/image:0.1"]).To(Equal([]string{filepath.Join(scan.config.RawTrivyOutputDir, "sha256", "4ff3.json.gz")}))
+				Expect(rawOutputFiles["alpine:3.11.0"]).To(Equal([]string{filepath.Join(scan.config.RawTrivyOutputDir, "no-digest", "alpine_3.11.0.json.gz")}))
+				file, err := os.Open(rawOutputFiles["registry/image:0.1"][0])
+				Expect(err).NotTo(HaveOccurred())
+				defer file.Close()
+				reader, err := gzip.NewReader(file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(io.ReadAll(reader)).To(Equal([]byte(`{"ArtifactName":"registry/image:0.1"}`)))
+			})
+
+			It("should name the raw trivy outputs of the platforms of an image after their platform", func() {
+				output := func(architecture string) *TrivyOutput {
+					output := &TrivyOutput{}
+					output.Metadata.RepoDigests = []string{"registry/image@sha256:4ff3"}
+					output.Metadata.ImageConfig = &ImageConfig{OS: "linux", Architecture: architecture}
+					return output
+				}
+
+				Expect(rawTrivyOutputFilename("registry/image:0.1", output("amd64"))).To(Equal(filepath.Join("sha256", "4ff3_linux_amd64.json.gz")))
+				Expect(rawTrivyOutputFilename("registry/image:0.1", output("arm64"))).To(Equal(filepath.Join("sha256", "4ff3_linux_arm64.json.gz")))
+			})
+		})
+
+		Context("the scan is interrupted", func() {
+			It("should remove the pulled image and report the images scanned so far as incomplete", func() {
+				// given
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				scan.config.Workers = 1
+				containers := []k8s.ContainerSummary{
+					{Image: "alpine:3.11.0", PodName: "pod1"},
+					{Image: "replace-this-registry/image:0.1", PodName: "pod2"},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, fmt.Errorf("signal: killed")).Run(func(_ mock.Arguments) { cancel() }).
+					On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil).Maybe()
+
+				// when
+				report, err := scan.ScanImages(ctx)
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Metadata.Incomplete).To(BeTrue())
+				for _, image := range report.ScannedImages {
+					Expect(image.ImageName).NotTo(Equal("alpine:3.11.0"))
+				}
+				mockDockerClient.AssertCalled(GinkgoT(), "RmiImage", "alpine:3.11.0")
+			})
+		})
+
+		Context("the scan fails fast", func() {
+			It("should stop on the first vulnerability failing the policy and report the images scanned so far as incomplete", func() {
+				// given
+				scan.config.Workers = 1
+				scan.config.FailFast = &FailurePolicy{MinSeverity: "HIGH"}
+				containers := []k8s.ContainerSummary{
+					{Image: "alpine:3.11.0", PodName: "pod1"},
+					{Image: "replace-this-registry/image:0.1", PodName: "pod2"},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+				vulnerable := &TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+					{VulnerabilityID: "CVE-2024-0002", PkgName: "zlib", Severity: "MEDIUM"},
+					{VulnerabilityID: "CVE-2024-0001", PkgName: "openssl", Severity: "CRITICAL"},
+				}}}}
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(vulnerable, nil).
+					On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil).Maybe()
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Metadata.Incomplete).To(BeTrue())
+				Expect(report.Metadata.FailFastFinding).To(Equal("CRITICAL CVE-2024-0001 (openssl) in alpine:3.11.0"))
+			})
+
+			It("should not stop on the vulnerabilities dismissed in the findings state", func() {
+				// given
+				scan.config.FailFast = &FailurePolicy{MinSeverity: "HIGH", FindingsState: &FindingsState{Findings: []FindingTriage{
+					{VulnerabilityID: "CVE-2024-0001", Digest: "sha256:abc", State: TriageFalsePositive},
+				}}}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{{Image: "alpine:3.11.0", ImageDigest: "sha256:abc", PodName: "pod1"}}, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+				mockTrivyClient.On("ScanImage", mock.Anything).Return(&TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+					{VulnerabilityID: "CVE-2024-0001", PkgName: "openssl", Severity: "CRITICAL"},
+				}}}}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Metadata.FailFastFinding).To(BeEmpty())
+				Expect(report.FailingVulnerabilities("HIGH")).To(BeEmpty())
+				Expect(report.CountWithMinSeverity("HIGH")).To(Equal(1))
+			})
+
+			It("should not stop on the vulnerabilities without fixed version with fixable only", func() {
+				// given
+				scan.config.FailFast = &FailurePolicy{MinSeverity: "HIGH", FixableOnly: true}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+				mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+					{VulnerabilityID: "CVE-2024-0001", PkgName: "openssl", Severity: "CRITICAL"},
+				}}}}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Metadata.FailFastFinding).To(BeEmpty())
+				Expect(report.CountWithMinSeverity("HIGH")).To(Equal(1))
+			})
+
+			It("should not stop on the vulnerabilities below the policy", func() {
+				// given
+				scan.config.FailFast = &FailurePolicy{MinSeverity: "CRITICAL", KnownExploited: true}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+				mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+					{VulnerabilityID: "CVE-2024-0001", PkgName: "openssl", Severity: "HIGH"},
+				}}}}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Metadata.Incomplete).To(BeFalse())
+				Expect(report.Metadata.FailFastFinding).To(BeEmpty())
+			})
+		})
+
+		Context("the scan is checkpointed", func() {
+			var checkpointFile string
+
+			BeforeEach(func() {
+				checkpointFile = filepath.Join(GinkgoT().TempDir(), "checkpoint", "checkpoint.jsonl")
+				scan.config.CheckpointFile = checkpointFile
+				scan.config.Workers = 1
+				containers := []k8s.ContainerSummary{
+					{Image: "alpine:3.11.0", PodName: "pod1"},
+					{Image: "replace-this-registry/image:0.1", PodName: "pod2"},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+			})
+
+			It("should record the images scanned before the interruption", func() {
+				// given
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil).
+					On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, fmt.Errorf("signal: killed")).Run(func(_ mock.Arguments) { cancel() })
+
+				// when
+				_, err := scan.ScanImages(ctx)
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				content, err := os.ReadFile(checkpointFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(strings.Split(strings.TrimSpace(string(content)), "\n")).To(HaveLen(1))
+				Expect(string(content)).To(ContainSubstring(`"ImageName":"alpine:3.11.0"`))
+			})
+
+			It("should fail to resume without checkpoint file", func() {
+				scan.config.CheckpointFile = ""
+				scan.config.Resume = true
+
+				_, err := scan.ScanImages(context.Background())
+
+				Expect(err).To(MatchError("the scan cannot be resumed without checkpoint file"))
+			})
+
+			It("should only scan the images the checkpoint does not record when resuming, and remove it once complete", func() {
+				// given
+				scan.config.Resume = true
+				Expect(os.MkdirAll(filepath.Dir(checkpointFile), 0755)).To(Succeed())
+				recorded := NewScannedImage("alpine:3.11.0", nil, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2023-0001", Severity: "HIGH"}}}}, nil)
+				line, err := json.Marshal(recorded)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(checkpointFile, append(line, []byte("\n{\"ImageName\":\"trunc")...), 0644)).To(Succeed())
+				mockTrivyClient.On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil)
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(2))
+				Expect(report.ScannedImages[0].ImageName).To(Equal("alpine:3.11.0"))
+				Expect(report.ScannedImages[0].VulnerabilitySummary.ContainerCount).To(Equal(1))
+				Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+				mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "alpine:3.11.0")
+				Expect(checkpointFile).NotTo(BeAnExistingFile())
+			})
+
+			It("should rescan the images whose checkpointed results are older than the maximum result age", func() {
+				// given
+				scan.config.Resume = true
+				scan.config.MaxResultAge = 24 * time.Hour
+				Expect(os.MkdirAll(filepath.Dir(checkpointFile), 0755)).To(Succeed())
+				recorded := NewScannedImage("alpine:3.11.0", nil, nil, nil)
+				lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+				recorded.ScanTime = &lastWeek
+				line, err := json.Marshal(recorded)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(checkpointFile, line, 0644)).To(Succeed())
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, nil).
+					On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil)
 
-		var (
-			scan                 *Scanner
-			mockKubernetesClient *mockKubernetes
-			mockTrivyClient      *mockTrivy
-			mockDockerClient     *mockDocker
-		)
+				// when
+				report, err := scan.ScanImages(context.Background())
 
-		BeforeEach(func() {
-			mockKubernetesClient = &mockKubernetes{}
-			mockTrivyClient = &mockTrivy{}
-			mockDockerClient = &mockDocker{}
-			scan = &Scanner{
-				config: &Config{
-					Workers:              3,
-					FilterLabels:         areaLabel,
-					Severity:             "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL",
-					ImageNameReplacement: "replace-this-registry|registry",
-				},
-				kubernetesClient: mockKubernetesClient,
-				trivyClient:      mockTrivyClient,
-				dockerClient:     mockDockerClient,
-			}
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages).To(HaveLen(2))
+				Expect(report.ScannedImages[0].ScanTime.After(lastWeek)).To(BeTrue())
+				Expect(report.ScannedImages[0].Stale).To(BeFalse())
+				mockTrivyClient.AssertCalled(GinkgoT(), "ScanImage", "alpine:3.11.0")
+			})
 		})
 
-		It("should delete the pulled docker images once the scan is complete", func() {
-			// given
-			containers := []k8s.ContainerSummary{
-				{
-					Image:   "alpine:3.11.0",
-					PodName: "pod1",
-				},
-				{
-					Image:   "replace-this-registry/image:0.1",
-					PodName: "pod1",
-				},
-			}
-			mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
-			mockTrivyClient.On("DownloadDatabase").Return(nil)
-			mockDockerClient.
-				On("PullImage", "alpine:3.11.0").Return(nil).
-				On("PullImage", "registry/image:0.1").Return(nil)
-			mockTrivyClient.
-				On("ScanImage", "alpine:3.11.0").Return([]TrivyOutputResults{}, nil).
-				On("ScanImage", "registry/image:0.1").Return([]TrivyOutputResults{}, nil)
-			mockDockerClient.
-				On("RmiImage", "alpine:3.11.0").Return(nil).
-				On("RmiImage", "registry/image:0.1").Return(nil)
+		Context("the failed images of a previous report are scanned again", func() {
+			It("should only scan the failed images and merge their results into the report", func() {
+				// given
+				container := func(image, area string) []k8s.ContainerSummary {
+					return []k8s.ContainerSummary{{Image: image, PodName: "pod1", PodLabels: map[string]string{areaLabel: area}}}
+				}
+				vulnerable := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-2023-0001", Severity: "HIGH"}}}}
+				previous, err := (&AreaReport{AreaLabelName: areaLabel}).GenerateVulnerabilityReport([]ScannedImage{
+					NewScannedImage("alpine:3.11.0", container("alpine:3.11.0", "payments"), nil, fmt.Errorf("error executing trivy for image alpine:3.11.0: signal: killed")),
+					NewScannedImage("nginx:1.25", container("nginx:1.25", "payments"), vulnerable, nil),
+					NewScannedImage("redis:7", container("redis:7", "orders"), nil, fmt.Errorf("error executing trivy for image redis:7: timeout")),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				previous.Metadata.ClusterName = "sandbox"
+				previous.FixLatencies = []FixLatency{{Severity: "HIGH", Fixed: 2, MeanDays: 3, MedianDays: 3}}
+				previous.ScanOptOuts = []ScanOptOut{{Namespace: "batch", Workload: "job/migrate", Reason: "opted out"}}
+				previous.AreaSummary["payments"].Budget = &BudgetConsumption{Lines: []BudgetLine{{Severity: "HIGH", Count: 1, Budget: 1}}}
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", mock.Anything).Return(nil).
+					On("RmiImage", mock.Anything).Return(nil)
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: vulnerable}, nil).
+					On("ScanImage", "redis:7").Return(&TrivyOutput{}, fmt.Errorf("timeout"))
 
-			// when
-			_, err := scan.ScanImages()
-			Expect(err).NotTo(HaveOccurred())
+				// when
+				report, err := scan.RescanFailures(context.Background(), previous)
+
+				// then
+				Expect(err).NotTo(HaveOccurred())
+				mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "nginx:1.25")
+				Expect(report.Metadata.ClusterName).To(Equal("sandbox"))
+				Expect(report.ScannedImages).To(HaveLen(3))
+				Expect(report.ScannedImages[0].ScanError).NotTo(HaveOccurred())
+				Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+				Expect(report.ScannedImages[2].ScanError).To(HaveOccurred())
+				Expect(report.FailedScanCount()).To(Equal(1))
+				Expect(report.AreaSummary["payments"].TotalVulnerabilityBySeverity["HIGH"]).To(Equal(2))
+				Expect(report.AreaSummary["payments"].Budget.Exceeded()).To(Equal([]BudgetLine{{Severity: "HIGH", Count: 2, Budget: 1}}))
+				Expect(report.AreaSummary["orders"].Teams["all"].HasScanErrors()).To(BeTrue())
+				Expect(report.FixLatencies).To(Equal(previous.FixLatencies))
+				Expect(report.ScanOptOuts).To(Equal(previous.ScanOptOuts))
+				Expect(previous.AreaSummary["payments"].TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+			})
 		})
 
 		Context("an error occurs when communicating with the Kubernetes cluster", func() {
@@ -205,7 +1186,7 @@ var _ = Describe("Scanner", func() {
 				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return([]k8s.ContainerSummary{}, k8Error)
 
 				// when
-				_, err := scan.ScanImages()
+				_, err := scan.ScanImages(context.Background())
 				Expect(err).To(HaveOccurred())
 				Expect(err).To(MatchError(k8Error))
 			})
@@ -225,7 +1206,7 @@ var _ = Describe("Scanner", func() {
 				mockTrivyClient.On("DownloadDatabase").Return(trivyError)
 
 				// when
-				_, err := scan.ScanImages()
+				_, err := scan.ScanImages(context.Background())
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to download trivy db: a trivy error"))
 			})
@@ -250,14 +1231,14 @@ var _ = Describe("Scanner", func() {
 					On("PullImage", "alpine:3.11.0").Return(fmt.Errorf("some docker error")).
 					On("PullImage", "registry/image:0.1").Return(nil)
 				mockTrivyClient.
-					On("ScanImage", "alpine:3.11.0").Return([]TrivyOutputResults{}, fmt.Errorf("some trivy error")).
-					On("ScanImage", "registry/image:0.1").Return([]TrivyOutputResults{}, nil)
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, fmt.Errorf("some trivy error")).
+					On("ScanImage", "registry/image:0.1").Return(&TrivyOutput{}, nil)
 				mockDockerClient.
 					On("RmiImage", "alpine:3.11.0").Return(fmt.Errorf("some docker error")).
 					On("RmiImage", "registry/image:0.1").Return(nil)
 
 				// when
-				_, err := scan.ScanImages()
+				_, err := scan.ScanImages(context.Background())
 				Expect(err).NotTo(HaveOccurred())
 			})
 
@@ -274,31 +1255,350 @@ var _ = Describe("Scanner", func() {
 				mockDockerClient.
 					On("PullImage", "alpine:3.11.0").Return(nil)
 				mockTrivyClient.
-					On("ScanImage", "alpine:3.11.0").Return([]TrivyOutputResults{}, fmt.Errorf("some trivy error"))
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, fmt.Errorf("some trivy error"))
 				mockDockerClient.
 					On("RmiImage", "alpine:3.11.0").Return(fmt.Errorf("some docker error"))
 
 				// when
-				report, err := scan.ScanImages()
+				report, err := scan.ScanImages(context.Background())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(report.ScannedImages[0].ScanError.Error()).To(ContainSubstring("error executing trivy for image alpine:3.11.0: some trivy error"))
 			})
+
+			It("should report the pull and removal errors with the scan errors", func() {
+				// given
+				containers := []k8s.ContainerSummary{
+					{
+						Image:   "alpine:3.11.0",
+						PodName: "pod1",
+					},
+				}
+				mockKubernetesClient.On("GetContainersInNamespaces", areaLabel).Return(containers, nil)
+				mockTrivyClient.On("DownloadDatabase").Return(nil)
+				mockDockerClient.
+					On("PullImage", "alpine:3.11.0").Return(fmt.Errorf("manifest unknown"))
+				mockTrivyClient.
+					On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{}, fmt.Errorf("some trivy error"))
+				mockDockerClient.
+					On("RmiImage", "alpine:3.11.0").Return(fmt.Errorf("no such image"))
+
+				// when
+				report, err := scan.ScanImages(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.ScannedImages[0].PullError).To(Equal("manifest unknown"))
+				Expect(report.ScannedImages[0].RemoveError).To(Equal("no such image"))
+				Expect(report.ScanErrors()).To(Equal([]ImageScanError{
+					{ImageName: "alpine:3.11.0", Stage: ScanStagePull, Error: "manifest unknown"},
+					{ImageName: "alpine:3.11.0", Stage: ScanStageScan, Error: "error executing trivy for image alpine:3.11.0: some trivy error"},
+					{ImageName: "alpine:3.11.0", Stage: ScanStageRemove, Error: "no such image"},
+				}))
+				Expect(report.ScanErrorRate()).To(Equal(100.0))
+			})
 		})
 	})
 
-})
+	Describe("image scan source", func() {
+		var (
+			scan                 *Scanner
+			mockKubernetesClient *k8stest.KubernetesClient
+		)
 
-type mockKubernetes struct {
-	mock.Mock
-}
+		BeforeEach(func() {
+			mockKubernetesClient = &k8stest.KubernetesClient{}
+			scan = &Scanner{
+				config: &Config{
+					FilterLabels: "area-label",
+					AreaLabels:   "area-label",
+					TeamsLabels:  "team-label",
+					Severity:     "UNKNOWN,LOW,MEDIUM,HIGH,CRITICAL",
+					SeverityOverrides: &SeverityOverrides{Overrides: []SeverityOverride{
+						{VulnerabilityID: "CVE-1", Severity: "LOW", Justification: "not reachable"},
+					}},
+				},
+				kubernetesClient: mockKubernetesClient,
+			}
+			mockKubernetesClient.On("GetServerVersion").Return("v1.27.3", nil)
+		})
+
+		It("should report the images of the cluster with the results of the source", func() {
+			// given
+			containers := []k8s.ContainerSummary{
+				{Image: "app:1.0", PodName: "app-1", Namespace: "payments", NamespaceLabels: map[string]string{"area-label": "payments", "team-label": "api"}},
+				{Image: "debug:latest", PodName: "debug", Namespace: "payments", NamespaceLabels: map[string]string{"area-label": "payments", "team-label": "api"}},
+			}
+			mockKubernetesClient.On("GetContainersInNamespaces", "area-label").Return(containers, nil)
+			source := &fakeImageScanSource{outputs: map[string]*TrivyOutput{
+				"app:1.0": {Results: []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+					{VulnerabilityID: "CVE-1", Severity: "CRITICAL"},
+					{VulnerabilityID: "CVE-2", Severity: "HIGH"},
+				}}}},
+			}}
 
-// force implementation of k8s.KubernetesClient at compilation time
-var _ k8s.KubernetesClient = &mockKubernetes{}
+			// when
+			report, err := scan.ReportImageScans(context.Background(), source)
 
-func (k *mockKubernetes) GetContainersInNamespaces(labelSelector string) ([]k8s.ContainerSummary, error) {
-	args := k.Called(labelSelector)
-	return args.Get(0).([]k8s.ContainerSummary), args.Error(1)
-}
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Metadata.TrivyVersion).To(Equal("0.45.1"))
+			Expect(report.Metadata.KubernetesVersion).To(Equal("v1.27.3"))
+			Expect(report.ScannedImages).To(HaveLen(2))
+			Expect(report.ScannedImages[0].ImageName).To(Equal("app:1.0"))
+			Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2"))
+			Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[1].Severity).To(Equal("LOW"))
+			Expect(report.ScannedImages[1].ImageName).To(Equal("debug:latest"))
+			Expect(report.ScannedImages[1].ScanError).To(MatchError("no scan of debug:latest"))
+			Expect(report.AreaSummary["payments"].Teams["api"].Images).To(HaveLen(2))
+		})
+
+		It("should flag the images whose results are older than the maximum result age", func() {
+			// given
+			scan.config.MaxResultAge = 24 * time.Hour
+			mockKubernetesClient.On("GetContainersInNamespaces", "area-label").Return([]k8s.ContainerSummary{
+				{Image: "app:1.0", PodName: "app-1"}, {Image: "web:1.0", PodName: "web-1"},
+			}, nil)
+			lastWeek, lastHour := time.Now().Add(-7*24*time.Hour).UTC(), time.Now().Add(-time.Hour).UTC()
+			source := &fakeImageScanSource{outputs: map[string]*TrivyOutput{
+				"app:1.0": {CreatedAt: lastWeek},
+				"web:1.0": {CreatedAt: lastHour},
+			}}
+
+			// when
+			report, err := scan.ReportImageScans(context.Background(), source)
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages[0].ScanTime).To(Equal(&lastWeek))
+			Expect(report.ScannedImages[0].Stale).To(BeTrue())
+			Expect(report.ScannedImages[1].ScanTime).To(Equal(&lastHour))
+			Expect(report.ScannedImages[1].Stale).To(BeFalse())
+		})
+	})
+
+	Describe("watch", func() {
+		var (
+			scan                 *Scanner
+			mockKubernetesClient *k8stest.KubernetesClient
+			mockTrivyClient      *mockTrivy
+			mockDockerClient     *mockDocker
+		)
+
+		BeforeEach(func() {
+			mockKubernetesClient = &k8stest.KubernetesClient{}
+			mockTrivyClient = &mockTrivy{}
+			mockDockerClient = &mockDocker{}
+			mockDockerClient.On("ImageExists", mock.Anything).Return(false, nil).Maybe()
+			scan = &Scanner{
+				config:           &Config{Workers: 2, FilterLabels: "area-label"},
+				kubernetesClient: mockKubernetesClient,
+				trivyClient:      mockTrivyClient,
+				dockerClient:     mockDockerClient,
+			}
+			watchBatchDelay = 10 * time.Millisecond
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockTrivyClient.On("Version").Return(&TrivyVersion{Version: "0.45.0"}, nil)
+		})
+
+		It("should only scan the images of the watched pods the report does not hold", func() {
+			// given
+			report := &VulnerabilityReport{
+				ScannedImages: []ScannedImage{{ImageName: "alpine:3.11.0", Containers: []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}}},
+				Metadata:      ReportMetadata{ClusterName: "sandbox"},
+			}
+			mockKubernetesClient.On("WatchContainers", "area-label").Return([][]k8s.ContainerSummary{
+				{{Image: "alpine:3.11.0", PodName: "pod2"}},
+				{{Image: "nginx:1.25", PodName: "web-1"}, {Image: "alpine:3.11.0", PodName: "web-1"}},
+			}, nil)
+			mockDockerClient.On("PullImage", "nginx:1.25").Return(nil).On("RmiImage", "nginx:1.25").Return(nil)
+			mockTrivyClient.On("ScanImage", "nginx:1.25").Return(&TrivyOutput{Results: []TrivyOutputResults{
+				{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-1", Severity: "HIGH"}}},
+			}}, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var updated *VulnerabilityReport
+
+			// when
+			err := scan.Watch(ctx, report, func(report *VulnerabilityReport) {
+				updated = report
+				cancel()
+			})
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.ScannedImages).To(HaveLen(2))
+			Expect(updated.ScannedImages[0].ImageName).To(Equal("alpine:3.11.0"))
+			Expect(updated.ScannedImages[1].ImageName).To(Equal("nginx:1.25"))
+			Expect(updated.Metadata.ClusterName).To(Equal("sandbox"))
+			mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "alpine:3.11.0")
+			mockDockerClient.AssertNotCalled(GinkgoT(), "PullImage", "alpine:3.11.0")
+		})
+
+		It("should rescan the images whose results are older than the maximum result age", func() {
+			// given
+			staleCheckInterval = 10 * time.Millisecond
+			scan.config.MaxResultAge = time.Hour
+			lastWeek, lastMinute := time.Now().Add(-7*24*time.Hour), time.Now().Add(-time.Minute)
+			report := &VulnerabilityReport{
+				ScannedImages: []ScannedImage{
+					{ImageName: "alpine:3.11.0", ScanTime: &lastWeek, Containers: []k8s.ContainerSummary{{Image: "alpine:3.11.0", PodName: "pod1"}}},
+					{ImageName: "nginx:1.25", ScanTime: &lastMinute, Containers: []k8s.ContainerSummary{{Image: "nginx:1.25", PodName: "web-1"}}},
+				},
+			}
+			mockKubernetesClient.On("WatchContainers", "area-label").Return(nil, nil)
+			mockDockerClient.On("PullImage", "alpine:3.11.0").Return(nil).On("RmiImage", "alpine:3.11.0").Return(nil)
+			mockTrivyClient.On("ScanImage", "alpine:3.11.0").Return(&TrivyOutput{Results: []TrivyOutputResults{
+				{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-1", Severity: "HIGH"}}},
+			}}, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var updated *VulnerabilityReport
+
+			// when
+			err := scan.Watch(ctx, report, func(report *VulnerabilityReport) {
+				updated = report
+				cancel()
+			})
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.ScannedImages).To(HaveLen(2))
+			Expect(updated.ScannedImages[0].ImageName).To(Equal("alpine:3.11.0"))
+			Expect(updated.ScannedImages[0].ScanTime.After(lastMinute)).To(BeTrue())
+			Expect(updated.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+			Expect(updated.ScannedImages[0].Stale).To(BeFalse())
+			Expect(updated.ScannedImages[1].ScanTime).To(Equal(&lastMinute))
+			mockTrivyClient.AssertNotCalled(GinkgoT(), "ScanImage", "nginx:1.25")
+		})
+
+		It("should postpone the scans of the new images to the next scan window", func() {
+			// given
+			opening := time.Now().Add(200 * time.Millisecond)
+			scan.config.ScanWindows = fakeScanWindows{opening: opening}
+			mockKubernetesClient.On("WatchContainers", "area-label").Return([][]k8s.ContainerSummary{
+				{{Image: "nginx:1.25", PodName: "web-1"}},
+			}, nil)
+			mockDockerClient.On("PullImage", "nginx:1.25").Return(nil).On("RmiImage", "nginx:1.25").Return(nil)
+			mockTrivyClient.On("ScanImage", "nginx:1.25").Return(&TrivyOutput{}, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var scannedAt time.Time
+
+			// when
+			err := scan.Watch(ctx, &VulnerabilityReport{}, func(report *VulnerabilityReport) {
+				scannedAt = time.Now()
+				cancel()
+			})
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scannedAt).NotTo(BeTemporally("<", opening))
+		})
+
+		It("should return the error of the watch", func() {
+			// given
+			mockKubernetesClient.On("WatchContainers", "area-label").Return(nil, fmt.Errorf("pods is forbidden"))
+
+			// when
+			err := scan.Watch(context.Background(), &VulnerabilityReport{}, func(*VulnerabilityReport) {})
+
+			// then
+			Expect(err).To(MatchError("pods is forbidden"))
+		})
+	})
+
+	Describe("single image scan", func() {
+		var (
+			scan             *Scanner
+			mockTrivyClient  *mockTrivy
+			mockDockerClient *mockDocker
+		)
+
+		BeforeEach(func() {
+			mockTrivyClient = &mockTrivy{}
+			mockDockerClient = &mockDocker{}
+			mockDockerClient.On("ImageExists", mock.Anything).Return(false, nil).Maybe()
+			scan = &Scanner{
+				config:       &Config{},
+				trivyClient:  mockTrivyClient,
+				dockerClient: mockDockerClient,
+			}
+			mockTrivyClient.On("DownloadDatabase").Return(nil)
+			mockTrivyClient.On("Version").Return(&TrivyVersion{}, fmt.Errorf("trivy version error"))
+		})
+
+		It("should report the image vulnerabilities without pulling the image", func() {
+			// given
+			mockTrivyClient.On("ScanImage", "app:local").Return(&TrivyOutput{Results: []TrivyOutputResults{
+				{Vulnerabilities: []Vulnerabilities{{VulnerabilityID: "CVE-1", Severity: "HIGH"}}},
+			}}, nil)
+
+			// when
+			report, err := scan.ScanImage(context.Background(), "app:local")
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.ScannedImages).To(HaveLen(1))
+			Expect(report.AreaSummary["all"].TotalVulnerabilityBySeverity["HIGH"]).To(Equal(1))
+			Expect(report.AreaSummary["all"].Teams["all"].Images[0].ImageName).To(Equal("app:local"))
+			mockDockerClient.AssertNotCalled(GinkgoT(), "PullImage", mock.Anything)
+			mockDockerClient.AssertNotCalled(GinkgoT(), "RmiImage", mock.Anything)
+		})
+
+		It("should return the scan error", func() {
+			// given
+			mockTrivyClient.On("ScanImage", "app:local").Return(&TrivyOutput{}, fmt.Errorf("some trivy error"))
+
+			// when
+			_, err := scan.ScanImage(context.Background(), "app:local")
+
+			// then
+			Expect(err).To(MatchError(ContainSubstring("error executing trivy for image app:local: some trivy error")))
+		})
+	})
+
+	Describe("compliance scan", func() {
+		var (
+			scan            *Scanner
+			mockTrivyClient *mockTrivy
+		)
+
+		BeforeEach(func() {
+			mockTrivyClient = &mockTrivy{}
+			scan = &Scanner{
+				config:      &Config{},
+				trivyClient: mockTrivyClient,
+			}
+		})
+
+		It("should run each benchmark and combine the results", func() {
+			// given
+			mockTrivyClient.
+				On("CisScan", "k8s-cis").Return(&CisOutput{ID: "k8s-cis", Title: "CIS"}, nil).
+				On("CisScan", "k8s-nsa").Return(&CisOutput{}, nil)
+
+			// when
+			report, err := scan.CisScan([]string{"k8s-cis", "k8s-nsa"})
+
+			// then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Benchmarks).To(HaveLen(2))
+			Expect(report.Benchmarks[0].Title).To(Equal("CIS"))
+			Expect(report.Benchmarks[1].ID).To(Equal("k8s-nsa"))
+		})
+
+		It("should return the error of a failing benchmark", func() {
+			// given
+			mockTrivyClient.On("CisScan", "k8s-cis").Return(&CisOutput{}, fmt.Errorf("some trivy error"))
+
+			// when
+			_, err := scan.CisScan([]string{"k8s-cis", "k8s-nsa"})
+
+			// then
+			Expect(err).To(MatchError(ContainSubstring("error executing trivy k8s-cis cluster scan: some trivy error")))
+			mockTrivyClient.AssertNotCalled(GinkgoT(), "CisScan", "k8s-nsa")
+		})
+	})
+
+})
 
 type mockTrivy struct {
 	mock.Mock
@@ -307,28 +1607,38 @@ type mockTrivy struct {
 // force implementation of TrivyClient at compilation time
 var _ TrivyClient = &mockTrivy{}
 
-func (t *mockTrivy) DownloadDatabase(_ string) error {
+func (t *mockTrivy) DownloadDatabase(_ context.Context, _ string) error {
 	args := t.Called()
 	return args.Error(0)
 
 }
-func (t *mockTrivy) ScanImage(image string) ([]TrivyOutputResults, error) {
+func (t *mockTrivy) ScanImage(_ context.Context, image string) (*TrivyOutput, error) {
 	args := t.Called(image)
-	return args.Get(0).([]TrivyOutputResults), args.Error(1)
+	return args.Get(0).(*TrivyOutput), args.Error(1)
+}
+
+func (t *mockTrivy) SBOM(_ context.Context, image string, withVulnerabilities bool) ([]byte, error) {
+	args := t.Called(image, withVulnerabilities)
+	return args.Get(0).([]byte), args.Error(1)
 }
 
 func (t *mockTrivy) CisScan(benchmark string) (*CisOutput, error) {
-	args := t.Called()
+	args := t.Called(benchmark)
 	return args.Get(0).(*CisOutput), args.Error(1)
 }
 
+func (t *mockTrivy) Version() (*TrivyVersion, error) {
+	args := t.Called()
+	return args.Get(0).(*TrivyVersion), args.Error(1)
+}
+
 type mockDocker struct {
 	mock.Mock
 }
 
 var _ DockerClient = &mockDocker{}
 
-func (d *mockDocker) PullImage(image string) error {
+func (d *mockDocker) PullImage(_ context.Context, image string) error {
 	args := d.Called(image)
 	return args.Error(0)
 }
@@ -337,3 +1647,63 @@ func (d *mockDocker) RmiImage(image string) error {
 	args := d.Called(image)
 	return args.Error(0)
 }
+
+func (d *mockDocker) ImageExists(_ context.Context, image string) (bool, error) {
+	args := d.Called(image)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *mockDocker) ImageCreated(_ context.Context, image string) (time.Time, error) {
+	args := d.Called(image)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (d *mockDocker) ImageSize(_ context.Context, image string) (int64, error) {
+	args := d.Called(image)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *mockDocker) ImagePlatforms(_ context.Context, image string) ([]ImagePlatform, error) {
+	args := d.Called(image)
+	return args.Get(0).([]ImagePlatform), args.Error(1)
+}
+
+type spanRecorder struct {
+	spans []*tracing.Span
+}
+
+func (r *spanRecorder) Export(spans []*tracing.Span) error {
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+type fakeImageScanSource struct {
+	outputs map[string]*TrivyOutput
+}
+
+func (f *fakeImageScanSource) ImageScan(imageName string, _ []k8s.ContainerSummary) (*TrivyOutput, error) {
+	if output, ok := f.outputs[imageName]; ok {
+		return output, nil
+	}
+	return nil, fmt.Errorf("no scan of %s", imageName)
+}
+
+func (f *fakeImageScanSource) Version() string {
+	return "0.45.1"
+}
+
+// fakeScanWindows allows the scans from the opening time
+type fakeScanWindows struct {
+	opening time.Time
+}
+
+func (w fakeScanWindows) Allows(t time.Time) bool {
+	return !t.Before(w.opening)
+}
+
+func (w fakeScanWindows) Next(after time.Time) time.Time {
+	if w.Allows(after) {
+		return after
+	}
+	return w.opening
+}
@@ -0,0 +1,84 @@
+// Package scannertest provides mocks of the trivy and docker clients of the scanner, to unit test the code embedding
+// the scanner without a trivy binary nor a docker daemon, see scanner.NewWithClients
+package scannertest
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/stretchr/testify/mock"
+)
+
+// TrivyClient is a testify mock of scanner.TrivyClient, the expectations being set on the method names with the
+// arguments other than the context
+type TrivyClient struct {
+	mock.Mock
+}
+
+// force implementation of scanner.TrivyClient at compilation time
+var _ scanner.TrivyClient = &TrivyClient{}
+
+func (t *TrivyClient) DownloadDatabase(_ context.Context, cmd string) error {
+	args := t.Called(cmd)
+	return args.Error(0)
+}
+
+func (t *TrivyClient) ScanImage(_ context.Context, image string) (*scanner.TrivyOutput, error) {
+	args := t.Called(image)
+	return args.Get(0).(*scanner.TrivyOutput), args.Error(1)
+}
+
+func (t *TrivyClient) SBOM(_ context.Context, image string, withVulnerabilities bool) ([]byte, error) {
+	args := t.Called(image, withVulnerabilities)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (t *TrivyClient) CisScan(benchmark string) (*scanner.CisOutput, error) {
+	args := t.Called(benchmark)
+	return args.Get(0).(*scanner.CisOutput), args.Error(1)
+}
+
+func (t *TrivyClient) Version() (*scanner.TrivyVersion, error) {
+	args := t.Called()
+	return args.Get(0).(*scanner.TrivyVersion), args.Error(1)
+}
+
+// DockerClient is a testify mock of scanner.DockerClient, the expectations being set on the method names with the
+// arguments other than the context
+type DockerClient struct {
+	mock.Mock
+}
+
+// force implementation of scanner.DockerClient at compilation time
+var _ scanner.DockerClient = &DockerClient{}
+
+func (d *DockerClient) PullImage(_ context.Context, image string) error {
+	args := d.Called(image)
+	return args.Error(0)
+}
+
+func (d *DockerClient) RmiImage(image string) error {
+	args := d.Called(image)
+	return args.Error(0)
+}
+
+func (d *DockerClient) ImageExists(_ context.Context, image string) (bool, error) {
+	args := d.Called(image)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *DockerClient) ImageSize(_ context.Context, image string) (int64, error) {
+	args := d.Called(image)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *DockerClient) ImageCreated(_ context.Context, image string) (time.Time, error) {
+	args := d.Called(image)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (d *DockerClient) ImagePlatforms(_ context.Context, image string) ([]scanner.ImagePlatform, error) {
+	args := d.Called(image)
+	return args.Get(0).([]scanner.ImagePlatform), args.Error(1)
+}
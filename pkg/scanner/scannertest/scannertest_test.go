@@ -0,0 +1,51 @@
+package scannertest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s/k8stest"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner/scannertest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestScannertest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Scannertest Suite")
+}
+
+var _ = Describe("Scanner test clients", func() {
+
+	It("scans the images of the cluster without a cluster, a trivy binary nor a docker daemon", func() {
+		// given
+		kubernetesClient := &k8stest.KubernetesClient{}
+		dockerClient := &scannertest.DockerClient{}
+		trivyClient := &scannertest.TrivyClient{}
+		kubernetesClient.On("GetServerVersion").Return("v1.27.3", nil)
+		kubernetesClient.On("GetContainersInNamespaces", "").Return([]k8s.ContainerSummary{{Image: "alpine:3.18.0", PodName: "pod1"}}, nil)
+		trivyClient.On("Version").Return(&scanner.TrivyVersion{Version: "0.45.0"}, nil)
+		trivyClient.On("DownloadDatabase", "image").Return(nil)
+		trivyClient.On("ScanImage", "alpine:3.18.0").Return(&scanner.TrivyOutput{Results: []scanner.TrivyOutputResults{{
+			Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: "CVE-2023-5363", PkgName: "openssl", Severity: "HIGH"}},
+		}}}, nil)
+		dockerClient.On("ImageExists", "alpine:3.18.0").Return(false, nil)
+		dockerClient.On("PullImage", "alpine:3.18.0").Return(nil)
+		dockerClient.On("RmiImage", "alpine:3.18.0").Return(nil)
+		scan := scanner.NewWithClients(kubernetesClient, dockerClient, trivyClient, &scanner.Config{Workers: 1, Severity: "HIGH,CRITICAL"})
+
+		// when
+		report, err := scan.ScanImages(context.Background())
+
+		// then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages).To(HaveLen(1))
+		Expect(report.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity).To(HaveKeyWithValue("HIGH", 1))
+		Expect(report.Metadata.TrivyVersion).To(Equal("0.45.0"))
+		dockerClient.AssertExpectations(GinkgoT())
+		trivyClient.AssertExpectations(GinkgoT())
+	})
+})
@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// scoreboardWeek is the period of the change of the team scores of the scoreboard
+	scoreboardWeek = 7 * 24 * time.Hour
+	// teamScoreRetention is the age of the team scores of the vulnerability history past which they are forgotten
+	teamScoreRetention = 5 * scoreboardWeek
+)
+
+// TeamScoreRecord is the severity score of a team at the time of a scan, recorded by the vulnerability history
+type TeamScoreRecord struct {
+	ScanTime      time.Time `json:"scanTime"`
+	SeverityScore int       `json:"severityScore"`
+}
+
+// TeamScore is the entry of a team in the scoreboard, see VulnerabilityReport.Scoreboard
+type TeamScore struct {
+	// Rank is the rank of the team, the teams with the same score sharing their rank
+	Rank          int
+	Area          string
+	Team          string
+	SeverityScore int
+	// WeekOverWeekChange is the change of the severity score of the team since the scan a week before, nil when the
+	// vulnerability history holds no score of the team that old
+	WeekOverWeekChange           *int
+	TotalVulnerabilityBySeverity map[string]int
+	// ImageCount is the number of images of the team successfully scanned, ZeroCriticalPercentage the percentage of
+	// them without CRITICAL vulnerability
+	ImageCount             int
+	ZeroCriticalPercentage float64
+	// FailedImageCount is the number of images of the team whose scan failed, left out of the severity score
+	FailedImageCount int
+}
+
+// Scoreboard ranks the teams by the severity score of their images, the lowest score first, so that the teams compete
+// on remediating their vulnerabilities. The teams with the same score are ranked by their percentage of images without
+// CRITICAL vulnerability. The teams without any image successfully scanned are ranked last, their score of 0 telling
+// nothing about their vulnerabilities
+func (r *VulnerabilityReport) Scoreboard() []TeamScore {
+	var scores []TeamScore
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			score := TeamScore{
+				Area:                         area.Name,
+				Team:                         team.Name,
+				SeverityScore:                team.SeverityScore(),
+				TotalVulnerabilityBySeverity: team.TotalVulnerabilityBySeverity(),
+			}
+			if team.PreviousSeverityScore != nil {
+				change := score.SeverityScore - *team.PreviousSeverityScore
+				score.WeekOverWeekChange = &change
+			}
+			zeroCritical := 0
+			for _, image := range team.Images {
+				if image.ScanError != nil {
+					score.FailedImageCount++
+					continue
+				}
+				if image.Skipped {
+					continue
+				}
+				score.ImageCount++
+				if image.VulnerabilitySummary.TotalVulnerabilityBySeverity["CRITICAL"] == 0 {
+					zeroCritical++
+				}
+			}
+			if score.ImageCount > 0 {
+				score.ZeroCriticalPercentage = 100 * float64(zeroCritical) / float64(score.ImageCount)
+			}
+			scores = append(scores, score)
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		switch {
+		case (scores[i].ImageCount == 0) != (scores[j].ImageCount == 0):
+			return scores[j].ImageCount == 0
+		case scores[i].SeverityScore != scores[j].SeverityScore:
+			return scores[i].SeverityScore < scores[j].SeverityScore
+		case scores[i].ZeroCriticalPercentage != scores[j].ZeroCriticalPercentage:
+			return scores[i].ZeroCriticalPercentage > scores[j].ZeroCriticalPercentage
+		case scores[i].Area != scores[j].Area:
+			return scores[i].Area < scores[j].Area
+		}
+		return scores[i].Team < scores[j].Team
+	})
+	for i := range scores {
+		scores[i].Rank = i + 1
+		if i > 0 && (scores[i].ImageCount == 0) == (scores[i-1].ImageCount == 0) && scores[i].SeverityScore == scores[i-1].SeverityScore && scores[i].ZeroCriticalPercentage == scores[i-1].ZeroCriticalPercentage {
+			scores[i].Rank = scores[i-1].Rank
+		}
+	}
+	return scores
+}
+
+// SeverityScore returns the severity score of the team images, weighted according to the scoring mode of the report.
+// The images whose scan failed are left out
+func (t *TeamSummary) SeverityScore() int {
+	score := 0
+	for _, image := range t.Images {
+		if image.ScanError != nil {
+			continue
+		}
+		score += image.VulnerabilitySummary.SeverityScore
+	}
+	return score
+}
+
+// WriteScoreboard writes the scoreboard of the report as a table, see Scoreboard
+func (r *VulnerabilityReport) WriteScoreboard(w io.Writer) error {
+	var out strings.Builder
+	table := tabwriter.NewWriter(&out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(table, "RANK\tTEAM\tAREA\tSCORE\tWEEK CHANGE\t%s\tZERO CRITICAL\tFAILED\t\n", strings.Join(summarySeverities, "\t"))
+	for _, score := range r.Scoreboard() {
+		change := "-"
+		if score.WeekOverWeekChange != nil {
+			change = fmt.Sprintf("%+d", *score.WeekOverWeekChange)
+		}
+		var counts []string
+		for _, severity := range summarySeverities {
+			counts = append(counts, fmt.Sprint(score.TotalVulnerabilityBySeverity[severity]))
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%d\t%s\t%s\t%.0f%%\t%d\t\n", score.Rank, score.Team, score.Area, score.SeverityScore, change,
+			strings.Join(counts, "\t"), score.ZeroCriticalPercentage, score.FailedImageCount)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// trackTeamScores records the severity score of the teams of a complete report at its scan time, and sets the score of
+// each team a week before from the scores recorded by the previous scans. The scores older than a few weeks are forgotten
+func (h *VulnerabilityHistory) trackTeamScores(r *VulnerabilityReport, scanTime time.Time) {
+	if h.TeamScores == nil {
+		h.TeamScores = make(map[string][]TeamScoreRecord)
+	}
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			key := area.Name + "/" + team.Name
+			records := h.TeamScores[key]
+			team.PreviousSeverityScore = nil
+			for i := len(records) - 1; i >= 0; i-- {
+				if !records[i].ScanTime.After(scanTime.Add(-scoreboardWeek)) {
+					score := records[i].SeverityScore
+					team.PreviousSeverityScore = &score
+					break
+				}
+			}
+			if !r.Metadata.Partial() && (len(records) == 0 || scanTime.After(records[len(records)-1].ScanTime)) {
+				h.TeamScores[key] = append(records, TeamScoreRecord{ScanTime: scanTime, SeverityScore: team.SeverityScore()})
+			}
+		}
+	}
+	for key, records := range h.TeamScores {
+		kept := records[:0]
+		for _, record := range records {
+			if record.ScanTime.After(scanTime.Add(-teamScoreRetention)) {
+				kept = append(kept, record)
+			}
+		}
+		if len(kept) == 0 {
+			delete(h.TeamScores, key)
+		} else {
+			h.TeamScores[key] = kept
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scoreboard", func() {
+
+	var (
+		historyFile string
+		week1       = time.Date(2023, 9, 4, 10, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		historyFile = filepath.Join(GinkgoT().TempDir(), "history.json")
+	})
+
+	teamImage := func(name, team string, severities ...string) ScannedImage {
+		var vulnerabilities []Vulnerabilities
+		for i, severity := range severities {
+			vulnerabilities = append(vulnerabilities, Vulnerabilities{VulnerabilityID: "CVE-" + string(rune('1'+i)), PkgName: "openssl", Severity: severity})
+		}
+		return NewScannedImage(name, []k8s.ContainerSummary{{Image: name, NamespaceLabels: map[string]string{"area": "finance", "team": team}}},
+			[]TrivyOutputResults{{Vulnerabilities: vulnerabilities}}, nil)
+	}
+
+	scanReport := func(scanTime time.Time, images ...ScannedImage) *VulnerabilityReport {
+		report, err := (&AreaReport{AreaLabelName: "area", TeamLabelName: "team"}).GenerateVulnerabilityReport(images)
+		Expect(err).NotTo(HaveOccurred())
+		report.Metadata.ScanTime = scanTime
+		history, err := LoadVulnerabilityHistory(historyFile)
+		Expect(err).NotTo(HaveOccurred())
+		history.Track(report)
+		Expect(history.Save(historyFile)).To(Succeed())
+		return report
+	}
+
+	It("ranks the teams by severity score, the lowest first", func() {
+		failed := teamImage("db:1", "orders")
+		failed.ScanError = errors.New("timeout")
+		report := scanReport(week1,
+			teamImage("api:1", "payments", "CRITICAL", "HIGH"), teamImage("web:1", "payments"),
+			teamImage("cart:1", "orders", "HIGH"), failed,
+			teamImage("batch:1", "reporting", "HIGH"))
+
+		scoreboard := report.Scoreboard()
+
+		Expect(scoreboard).To(HaveLen(3))
+		Expect(scoreboard[0].Rank).To(Equal(1))
+		Expect(scoreboard[0].Team).To(Equal("orders"))
+		Expect(scoreboard[0].ImageCount).To(Equal(1))
+		Expect(scoreboard[0].ZeroCriticalPercentage).To(Equal(100.0))
+		Expect(scoreboard[0].FailedImageCount).To(Equal(1))
+		Expect(scoreboard[1].Rank).To(Equal(1))
+		Expect(scoreboard[1].Team).To(Equal("reporting"))
+		Expect(scoreboard[2].Rank).To(Equal(3))
+		Expect(scoreboard[2].Team).To(Equal("payments"))
+		Expect(scoreboard[2].SeverityScore).To(Equal(critical + high))
+		Expect(scoreboard[2].TotalVulnerabilityBySeverity).To(HaveKeyWithValue("CRITICAL", 1))
+		Expect(scoreboard[2].ZeroCriticalPercentage).To(Equal(50.0))
+		Expect(scoreboard[2].WeekOverWeekChange).To(BeNil())
+	})
+
+	It("ranks the teams without any image successfully scanned last", func() {
+		failed := teamImage("db:1", "orders", "CRITICAL")
+		failed.ScanError = errors.New("timeout")
+		report := scanReport(week1, failed, teamImage("api:1", "payments", "CRITICAL", "HIGH"))
+
+		scoreboard := report.Scoreboard()
+
+		Expect(scoreboard).To(HaveLen(2))
+		Expect(scoreboard[0].Team).To(Equal("payments"))
+		Expect(scoreboard[1].Rank).To(Equal(2))
+		Expect(scoreboard[1].Team).To(Equal("orders"))
+		Expect(scoreboard[1].SeverityScore).To(Equal(0))
+		Expect(scoreboard[1].ImageCount).To(Equal(0))
+		Expect(scoreboard[1].FailedImageCount).To(Equal(1))
+	})
+
+	It("shows the change of the team scores since the scan a week before", func() {
+		scanReport(week1, teamImage("api:1", "payments", "CRITICAL", "HIGH"))
+		scanReport(week1.Add(3*24*time.Hour), teamImage("api:1", "payments", "CRITICAL"))
+		report := scanReport(week1.Add(8*24*time.Hour), teamImage("api:1", "payments", "HIGH"), teamImage("cart:1", "orders", "HIGH"))
+
+		scoreboard := report.Scoreboard()
+
+		Expect(scoreboard[1].Team).To(Equal("payments"))
+		Expect(*scoreboard[1].WeekOverWeekChange).To(Equal(-critical))
+		Expect(scoreboard[0].WeekOverWeekChange).To(BeNil())
+
+		var table strings.Builder
+		Expect(report.WriteScoreboard(&table)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+		Expect(lines).To(HaveLen(3))
+		Expect(strings.Fields(lines[0])).To(Equal([]string{"RANK", "TEAM", "AREA", "SCORE", "WEEK", "CHANGE", "CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN", "ZERO", "CRITICAL", "FAILED"}))
+		Expect(strings.Fields(lines[2])).To(Equal([]string{"1", "payments", "finance", "1000000", "-100000000", "0", "1", "0", "0", "0", "100%", "0"}))
+	})
+
+	It("forgets the team scores older than a few weeks", func() {
+		scanReport(week1, teamImage("api:1", "payments", "HIGH"))
+		scanReport(week1.Add(6*scoreboardWeek), teamImage("api:1", "payments", "HIGH"))
+
+		history, err := LoadVulnerabilityHistory(historyFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.TeamScores["finance/payments"]).To(HaveLen(1))
+	})
+})
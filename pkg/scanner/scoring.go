@@ -0,0 +1,48 @@
+package scanner
+
+import "github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+// Scoring modes of the severity score of the images, see AreaReport.ScoringMode
+const (
+	// ScoringImages scores each image once, whatever the number of containers running it
+	ScoringImages = "images"
+	// ScoringContainers weights the score of the images by the number of containers running them, so that a
+	// DaemonSet image running on every node outscores an image run by a single workload
+	ScoringContainers = "containers"
+	// ScoringWorkloads weights the score of the images by the number of distinct workloads running them, a DaemonSet
+	// counting once whatever the number of nodes it runs on
+	ScoringWorkloads = "workloads"
+)
+
+// applyScoringMode sets the scoring mode of the images and computes their severity score accordingly
+func applyScoringMode(scannedImages []ScannedImage, mode string) {
+	for i := range scannedImages {
+		scannedImages[i].VulnerabilitySummary.ScoringMode = mode
+		scannedImages[i].VulnerabilitySummary = scannedImages[i].buildVulnerabilitySummary()
+	}
+}
+
+// scoreOfMode returns the severity score of the scoring mode, the score of the image when the mode is empty
+func (s VulnerabilitySummary) scoreOfMode(imageScore int) int {
+	switch s.ScoringMode {
+	case ScoringContainers:
+		return s.ContainerWeightedScore
+	case ScoringWorkloads:
+		return s.WorkloadWeightedScore
+	}
+	return imageScore
+}
+
+// workloadCount returns the number of distinct workloads running the containers, the containers without workload
+// counting per pod
+func workloadCount(containers []k8s.ContainerSummary) int {
+	workloads := make(map[string]bool)
+	for _, container := range containers {
+		workload := container.Workload
+		if workload == "" {
+			workload = "pod/" + container.PodName
+		}
+		workloads[container.Namespace+"/"+workload] = true
+	}
+	return len(workloads)
+}
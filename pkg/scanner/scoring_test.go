@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scoring modes", func() {
+
+	var scannedImages []ScannedImage
+
+	BeforeEach(func() {
+		var agentContainers []k8s.ContainerSummary
+		for _, node := range []string{"node-1", "node-2", "node-3", "node-4"} {
+			agentContainers = append(agentContainers, k8s.ContainerSummary{Namespace: "monitoring", Workload: "daemonset/agent", PodName: "agent-" + node, NodeName: node})
+		}
+		scannedImages = []ScannedImage{
+			NewScannedImage("agent:1.0", agentContainers, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-1", Severity: "HIGH"},
+			}}}, nil),
+			NewScannedImage("api:1.0", []k8s.ContainerSummary{
+				{Namespace: "shop", Workload: "deployment/api", PodName: "api-1"},
+				{Namespace: "payments", Workload: "deployment/api", PodName: "api-1"},
+				{Namespace: "payments", PodName: "debug"},
+			}, []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+				{VulnerabilityID: "CVE-2", Severity: "HIGH"},
+				{VulnerabilityID: "CVE-3", Severity: "MEDIUM"},
+			}}}, nil),
+		}
+	})
+
+	It("records the container and the workload weighted scores of the images", func() {
+		agent := scannedImages[0].VulnerabilitySummary
+		Expect(agent.ContainerCount).To(Equal(4))
+		Expect(agent.WorkloadCount).To(Equal(1))
+		Expect(agent.SeverityScore).To(Equal(high))
+		Expect(agent.ContainerWeightedScore).To(Equal(4 * high))
+		Expect(agent.WorkloadWeightedScore).To(Equal(high))
+
+		api := scannedImages[1].VulnerabilitySummary
+		Expect(api.ContainerCount).To(Equal(3))
+		Expect(api.WorkloadCount).To(Equal(3))
+		Expect(api.ContainerWeightedScore).To(Equal(3 * (high + medium)))
+		Expect(api.WorkloadWeightedScore).To(Equal(3 * (high + medium)))
+	})
+
+	DescribeTable("ranks the images by the score of the scoring mode", func(mode string, expectedOrder []string, expectedScores []int) {
+		report, err := (&AreaReport{ScoringMode: mode}).GenerateVulnerabilityReport(scannedImages)
+		Expect(err).NotTo(HaveOccurred())
+
+		var order []string
+		var scores []int
+		for _, image := range report.TopVulnerableImages() {
+			Expect(image.VulnerabilitySummary.ScoringMode).To(Equal(mode))
+			order = append(order, image.ImageName)
+			scores = append(scores, image.VulnerabilitySummary.SeverityScore)
+		}
+		Expect(order).To(Equal(expectedOrder))
+		Expect(scores).To(Equal(expectedScores))
+	},
+		Entry("per image", ScoringImages, []string{"api:1.0", "agent:1.0"}, []int{high + medium, high}),
+		Entry("per container", ScoringContainers, []string{"agent:1.0", "api:1.0"}, []int{4 * high, 3 * (high + medium)}),
+		Entry("per workload", ScoringWorkloads, []string{"api:1.0", "agent:1.0"}, []int{3 * (high + medium), high}),
+	)
+
+	It("keeps the scoring mode when the vulnerability summary is rebuilt", func() {
+		report, err := (&AreaReport{ScoringMode: ScoringContainers}).GenerateVulnerabilityReport(scannedImages)
+		Expect(err).NotTo(HaveOccurred())
+
+		filtered := imagesWithMinSeverity(report.ScannedImages, severityScores["HIGH"])
+
+		Expect(filtered[1].VulnerabilitySummary.SeverityScore).To(Equal(3 * high))
+	})
+})
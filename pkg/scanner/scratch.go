@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// scratchCheckInterval is how long the scans wait before measuring the usage of the scratch directory again while it
+// exceeds its maximum size
+var scratchCheckInterval = 10 * time.Second
+
+// scratchTempDir returns the directory of the scratch directory trivy writes its temporary files to, for instance the
+// image archives exported from the container runtime and the extracted layers
+func scratchTempDir(dir string) string {
+	return filepath.Join(dir, "tmp")
+}
+
+// scratchTrivyCacheDir returns the trivy cache directory of the scratch directory, holding the vulnerability database
+// in its db subdirectory and the cache of the analysed layers in its fanal subdirectory
+func scratchTrivyCacheDir(dir string) string {
+	return filepath.Join(dir, "trivy-cache")
+}
+
+// scratchDir is the directory the image scans write their temporary files and the trivy cache to, for instance a
+// volume mounted in the scanner pod rather than the root disk of the node. The scans wait while the directory exceeds
+// its maximum size, until the scans in progress complete and remove their temporary files
+type scratchDir struct {
+	dir     string
+	maxSize int64
+	lock    sync.Mutex
+	// inProgress is the number of scans in progress writing to the directory
+	inProgress int
+}
+
+// openScratchDir creates the temporary and the trivy cache directories of the scratch directory, nil when no scratch
+// directory is configured. The directory has no maximum size when maxSize is 0
+func openScratchDir(dir string, maxSize int64) (*scratchDir, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	for _, subdir := range []string{scratchTempDir(dir), scratchTrivyCacheDir(dir)} {
+		if err := os.MkdirAll(subdir, 0755); err != nil {
+			return nil, fmt.Errorf("could not create scratch directory %s: %v", subdir, err)
+		}
+	}
+	return &scratchDir{dir: dir, maxSize: maxSize}, nil
+}
+
+// acquire waits until the directory is below its maximum size before a scan, or the context is done. When the directory
+// still exceeds its maximum size once no scan is in progress, the cache of the analysed layers is removed, and the scan
+// carries on whatever the usage left so that the scan never stalls on the vulnerability database alone
+func (s *scratchDir) acquire(ctx context.Context, imageName string) error {
+	if s == nil {
+		return nil
+	}
+	for {
+		s.lock.Lock()
+		usage := s.measure()
+		if s.maxSize <= 0 || usage <= s.maxSize {
+			s.inProgress++
+			s.lock.Unlock()
+			return nil
+		}
+		if s.inProgress == 0 {
+			logr.Warnf("Scratch directory %s uses %d bytes above its maximum size of %d bytes, removing the trivy layer cache", s.dir, usage, s.maxSize)
+			s.clearLayerCache()
+			s.inProgress++
+			s.lock.Unlock()
+			return nil
+		}
+		s.lock.Unlock()
+		logr.Debugf("Waiting for scratch directory %s to free up before scanning image %s", s.dir, imageName)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(scratchCheckInterval):
+		}
+	}
+}
+
+// release records the end of a scan
+func (s *scratchDir) release() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.inProgress--
+}
+
+// measure returns the size of the files of the directory, 0 when it has no maximum size
+func (s *scratchDir) measure() int64 {
+	var usage int64
+	if s.maxSize <= 0 {
+		return usage
+	}
+	_ = filepath.WalkDir(s.dir, func(_ string, entry fs.DirEntry, err error) error {
+		// the files removed by the scans in progress while walking the directory are skipped
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			usage += info.Size()
+		}
+		return nil
+	})
+	return usage
+}
+
+// clearLayerCache removes the cache of the layers trivy analysed, the vulnerability database being kept
+func (s *scratchDir) clearLayerCache() {
+	if err := os.RemoveAll(filepath.Join(scratchTrivyCacheDir(s.dir), "fanal")); err != nil {
+		logr.Warnf("Unable to remove the trivy layer cache of scratch directory %s: %v", s.dir, err)
+	}
+}
+
+// close removes the temporary files and the layer cache once the scan completes, the vulnerability database being kept
+// for the next scans
+func (s *scratchDir) close() {
+	if s == nil {
+		return
+	}
+	if err := os.RemoveAll(scratchTempDir(s.dir)); err != nil {
+		logr.Warnf("Unable to remove the temporary files of scratch directory %s: %v", s.dir, err)
+	}
+	s.clearLayerCache()
+}
@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scratch directory", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir = filepath.Join(GinkgoT().TempDir(), "scratch")
+		previousInterval := scratchCheckInterval
+		scratchCheckInterval = 10 * time.Millisecond
+		DeferCleanup(func() { scratchCheckInterval = previousInterval })
+	})
+
+	write := func(filename string, size int) {
+		Expect(os.MkdirAll(filepath.Dir(filename), 0755)).To(Succeed())
+		Expect(os.WriteFile(filename, make([]byte, size), 0644)).To(Succeed())
+	}
+
+	It("points trivy to the scratch directory", func() {
+		client := (&Config{ScratchDir: dir}).NewTrivyClient().(*trivyClient)
+
+		Expect(client.db.CacheDir).To(Equal(filepath.Join(dir, "trivy-cache")))
+		Expect(client.commandRunner).NotTo(BeNil())
+		client = (&Config{ScratchDir: dir, TrivyDB: TrivyDBConfig{CacheDir: "/cache"}}).NewTrivyClient().(*trivyClient)
+		Expect(client.db.CacheDir).To(Equal("/cache"))
+	})
+
+	It("waits for the scans in progress while the directory exceeds its maximum size", func() {
+		scratch, err := openScratchDir(dir, 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scratch.acquire(context.Background(), "nginx:1.25")).To(Succeed())
+		write(filepath.Join(scratchTempDir(dir), "nginx.tar"), 2000)
+
+		acquired := make(chan error)
+		go func() { acquired <- scratch.acquire(context.Background(), "redis:7") }()
+		Consistently(acquired, 50*time.Millisecond).ShouldNot(Receive())
+
+		Expect(os.Remove(filepath.Join(scratchTempDir(dir), "nginx.tar"))).To(Succeed())
+		scratch.release()
+		Eventually(acquired).Should(Receive(BeNil()))
+	})
+
+	It("removes the layer cache when it alone exceeds the maximum size", func() {
+		scratch, err := openScratchDir(dir, 1000)
+		Expect(err).NotTo(HaveOccurred())
+		write(filepath.Join(scratchTrivyCacheDir(dir), "fanal", "fanal.db"), 2000)
+		write(filepath.Join(scratchTrivyCacheDir(dir), "db", "trivy.db"), 100)
+
+		Expect(scratch.acquire(context.Background(), "nginx:1.25")).To(Succeed())
+
+		Expect(filepath.Join(scratchTrivyCacheDir(dir), "fanal")).NotTo(BeADirectory())
+		Expect(filepath.Join(scratchTrivyCacheDir(dir), "db", "trivy.db")).To(BeARegularFile())
+	})
+
+	It("stops waiting when the scan is interrupted", func() {
+		scratch, err := openScratchDir(dir, 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scratch.acquire(context.Background(), "nginx:1.25")).To(Succeed())
+		write(filepath.Join(scratchTempDir(dir), "nginx.tar"), 2000)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		Expect(scratch.acquire(ctx, "redis:7")).To(MatchError(context.Canceled))
+	})
+
+	It("removes the temporary files and the layer cache once the scan completes", func() {
+		scratch, err := openScratchDir(dir, 0)
+		Expect(err).NotTo(HaveOccurred())
+		write(filepath.Join(scratchTempDir(dir), "nginx.tar"), 10)
+		write(filepath.Join(scratchTrivyCacheDir(dir), "fanal", "fanal.db"), 10)
+		write(filepath.Join(scratchTrivyCacheDir(dir), "db", "trivy.db"), 10)
+
+		scratch.close()
+
+		Expect(scratchTempDir(dir)).NotTo(BeADirectory())
+		Expect(filepath.Join(scratchTrivyCacheDir(dir), "fanal")).NotTo(BeADirectory())
+		Expect(filepath.Join(scratchTrivyCacheDir(dir), "db", "trivy.db")).To(BeARegularFile())
+	})
+})
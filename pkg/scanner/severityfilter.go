@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"fmt"
+)
+
+// ValidateMinSeverity checks that the minimum severity of an output is a trivy severity, empty for all the severities
+func ValidateMinSeverity(minSeverity string) error {
+	if _, ok := severityScores[minSeverity]; minSeverity != "" && !ok {
+		return fmt.Errorf("unknown severity %q, must be one of UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL", minSeverity)
+	}
+	return nil
+}
+
+// WithMinSeverity returns a copy of the report without the vulnerabilities below the minimum severity, the summaries
+// and scores of the images, teams and areas being recomputed, so that each output can show its own severities.
+// The report itself is returned when the minimum severity is empty. The budget consumptions are the ones of the report
+func (r *VulnerabilityReport) WithMinSeverity(minSeverity string) *VulnerabilityReport {
+	if r == nil || minSeverity == "" {
+		return r
+	}
+	floor := severityScores[minSeverity]
+	// the report is copied so that the report fields such as the fix latencies are kept
+	filtered := *r
+	filtered.ScannedImages = imagesWithMinSeverity(r.ScannedImages, floor)
+	filtered.AreaSummary = make(map[string]*AreaSummary)
+	for areaName, area := range r.AreaSummary {
+		areaSummary := &AreaSummary{Name: area.Name, Teams: make(map[string]*TeamSummary), Budget: area.Budget}
+		for teamName, team := range area.Teams {
+			teamSummary := *team
+			teamSummary.Images = sortBySeverity(imagesWithMinSeverity(team.Images, floor))
+			teamSummary.SLABreaches = nil
+			for _, breach := range team.SLABreaches {
+				if severityScores[breach.Vulnerability.Severity] >= floor {
+					teamSummary.SLABreaches = append(teamSummary.SLABreaches, breach)
+				}
+			}
+			areaSummary.Teams[teamName] = &teamSummary
+			areaSummary.aggregate(&teamSummary)
+		}
+		filtered.AreaSummary[areaName] = areaSummary
+	}
+	return &filtered
+}
+
+// imagesWithMinSeverity returns copies of the images without the vulnerabilities scoring below the floor
+func imagesWithMinSeverity(images []ScannedImage, floor int) []ScannedImage {
+	var filtered []ScannedImage
+	for _, image := range images {
+		imageResults := image.Results()
+		results := make([]TrivyOutputResults, len(imageResults))
+		for i, result := range imageResults {
+			results[i] = result
+			results[i].Vulnerabilities = nil
+			for _, vulnerability := range result.Vulnerabilities {
+				if severityScores[vulnerability.Severity] >= floor {
+					results[i].Vulnerabilities = append(results[i].Vulnerabilities, vulnerability)
+				}
+			}
+		}
+		image.setResults(results)
+		image.VulnerabilitySummary = image.buildVulnerabilitySummary()
+		filtered = append(filtered, image)
+	}
+	return filtered
+}
+
+// FailingVulnerabilities returns the vulnerabilities of the report images of at least the minimum severity, except those
+// triaged as false positives or accepted, so that the same vulnerabilities fail a scan whether or not it fails fast
+func (r *VulnerabilityReport) FailingVulnerabilities(minSeverity string) []VulnerabilityFinding {
+	floor := severityScores[minSeverity]
+	return matchingFindings(r.ScannedImages, func(v Vulnerabilities) bool {
+		return severityScores[v.Severity] >= floor && !v.Triage.Dismissed()
+	})
+}
+
+// CountWithMinSeverity returns the number of vulnerabilities of the report images of at least the minimum severity
+func (r *VulnerabilityReport) CountWithMinSeverity(minSeverity string) int {
+	count := 0
+	for _, image := range r.ScannedImages {
+		count += image.CountWithMinSeverity(minSeverity)
+	}
+	return count
+}
+
+// CountWithMinSeverity returns the number of vulnerabilities of the image of at least the minimum severity
+func (i ScannedImage) CountWithMinSeverity(minSeverity string) int {
+	floor := severityScores[minSeverity]
+	count := 0
+	for severity, severityCount := range i.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+		if severityScores[severity] >= floor {
+			count += severityCount
+		}
+	}
+	return count
+}
@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Severity floors", func() {
+
+	var report *VulnerabilityReport
+
+	BeforeEach(func() {
+		image := func(name, team string, severities ...string) ScannedImage {
+			var vulnerabilities []Vulnerabilities
+			for _, severity := range severities {
+				vulnerabilities = append(vulnerabilities, Vulnerabilities{VulnerabilityID: "CVE-" + severity, Severity: severity})
+			}
+			return NewScannedImage(name, []k8s.ContainerSummary{{Image: name, NamespaceLabels: map[string]string{"area": "finance", "team": team}}},
+				[]TrivyOutputResults{{Target: "debian", Vulnerabilities: vulnerabilities}}, nil)
+		}
+		var err error
+		report, err = (&AreaReport{AreaLabelName: "area", TeamLabelName: "team"}).GenerateVulnerabilityReport([]ScannedImage{
+			image("api:1", "payments", "LOW", "LOW", "MEDIUM"),
+			image("web:1", "orders", "CRITICAL", "LOW"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("removes the vulnerabilities below the minimum severity from a copy of the report, recomputing the summaries and scores", func() {
+		filtered := report.WithMinSeverity("MEDIUM")
+
+		Expect(filtered.ScannedImages).To(HaveLen(2))
+		Expect(filtered.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(1))
+		Expect(filtered.ScannedImages[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["LOW"]).To(Equal(0))
+		Expect(filtered.ScannedImages[0].VulnerabilitySummary.SeverityScore).To(Equal(medium))
+		Expect(filtered.AreaSummary["finance"].TotalVulnerabilityBySeverity).To(HaveKeyWithValue("LOW", 0))
+		Expect(filtered.AreaSummary["finance"].TotalVulnerabilityBySeverity).To(HaveKeyWithValue("CRITICAL", 1))
+		Expect(filtered.AreaSummary["finance"].Teams["orders"].Images[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(1))
+		Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(3))
+		Expect(report.AreaSummary["finance"].TotalVulnerabilityBySeverity).To(HaveKeyWithValue("LOW", 3))
+	})
+
+	It("keeps the other fields of the report", func() {
+		report.FixLatencies = []FixLatency{{Severity: "LOW", Fixed: 2, MeanDays: 3, MedianDays: 3}}
+		report.ScanOptOuts = []ScanOptOut{{Namespace: "payments", Workload: "Deployment/api"}}
+
+		filtered := report.WithMinSeverity("MEDIUM")
+
+		Expect(filtered.FixLatencies).To(Equal(report.FixLatencies))
+		Expect(filtered.ScanOptOuts).To(Equal(report.ScanOptOuts))
+		Expect(filtered.Metadata).To(Equal(report.Metadata))
+	})
+
+	It("returns the report itself without minimum severity", func() {
+		Expect(report.WithMinSeverity("")).To(BeIdenticalTo(report))
+	})
+
+	It("counts the vulnerabilities of at least the severity", func() {
+		Expect(report.CountWithMinSeverity("HIGH")).To(Equal(1))
+		Expect(report.CountWithMinSeverity("MEDIUM")).To(Equal(2))
+		Expect(report.CountWithMinSeverity("CRITICAL")).To(Equal(1))
+	})
+
+	It("rejects the unknown severities", func() {
+		Expect(ValidateMinSeverity("HIGH")).To(Succeed())
+		Expect(ValidateMinSeverity("")).To(Succeed())
+		Expect(ValidateMinSeverity("SEVERE")).To(MatchError(ContainSubstring(`unknown severity "SEVERE"`)))
+	})
+})
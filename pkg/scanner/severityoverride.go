@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// SeverityOverride overrides the severity of a vulnerability, of all the vulnerabilities of a package, or of a
+// vulnerability of a package only, to reflect an internal risk assessment
+type SeverityOverride struct {
+	VulnerabilityID string `json:"vulnerability"`
+	PkgName         string `json:"package"`
+	Severity        string `json:"severity"`
+	// Justification explains the risk assessment, it is shown in the report
+	Justification string `json:"justification"`
+}
+
+// SeverityOverrides are the severity overrides applied to the vulnerabilities, the first matching override applying
+type SeverityOverrides struct {
+	Overrides []SeverityOverride `json:"overrides"`
+}
+
+// OverriddenSeverity is the severity trivy assigned to a vulnerability whose severity is overridden
+type OverriddenSeverity struct {
+	Severity      string
+	Justification string
+}
+
+// LoadSeverityOverrides reads the severity overrides from a yaml or json file such as:
+//
+//	overrides:
+//	- vulnerability: CVE-2021-3326
+//	  package: libc-bin
+//	  severity: LOW
+//	  justification: the services never convert ISO-2022-JP-3 input
+func LoadSeverityOverrides(filename string) (*SeverityOverrides, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read severity overrides file %s: %v", filename, err)
+	}
+	var overrides SeverityOverrides
+	if err := yaml.Unmarshal(content, &overrides); err != nil {
+		return nil, fmt.Errorf("error while decoding severity overrides file %s: %v", filename, err)
+	}
+	for n, override := range overrides.Overrides {
+		if override.VulnerabilityID == "" && override.PkgName == "" {
+			return nil, fmt.Errorf("severity override %d of %s matches no vulnerability, a vulnerability or a package is required", n+1, filename)
+		}
+		if _, ok := severityScores[override.Severity]; !ok {
+			return nil, fmt.Errorf("severity override %d of %s has an invalid severity %q, permitted values: CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN", n+1, filename, override.Severity)
+		}
+	}
+	return &overrides, nil
+}
+
+// apply overrides the severity of the vulnerabilities of the trivy results, recording the trivy severity.
+// Nil overrides override nothing
+func (o *SeverityOverrides) apply(trivyOutput []TrivyOutputResults) {
+	if o == nil {
+		return
+	}
+	for i := range trivyOutput {
+		for j := range trivyOutput[i].Vulnerabilities {
+			vulnerability := &trivyOutput[i].Vulnerabilities[j]
+			override, ok := o.match(*vulnerability)
+			if !ok || override.Severity == vulnerability.Severity {
+				continue
+			}
+			vulnerability.OverriddenSeverity = &OverriddenSeverity{Severity: vulnerability.Severity, Justification: override.Justification}
+			vulnerability.Severity = override.Severity
+		}
+	}
+	sortTrivyVulnerabilities(trivyOutput)
+}
+
+func (o *SeverityOverrides) match(vulnerability Vulnerabilities) (SeverityOverride, bool) {
+	for _, override := range o.Overrides {
+		if (override.VulnerabilityID == "" || override.VulnerabilityID == vulnerability.VulnerabilityID) &&
+			(override.PkgName == "" || override.PkgName == vulnerability.PkgName) {
+			return override, true
+		}
+	}
+	return SeverityOverride{}, false
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Severity overrides", func() {
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	writeOverrides := func(content string) string {
+		filename := filepath.Join(tmpDir, "overrides.yaml")
+		Expect(os.WriteFile(filename, []byte(content), 0644)).To(Succeed())
+		return filename
+	}
+
+	It("overrides the severity of the matching vulnerabilities before they are counted", func() {
+		overrides, err := LoadSeverityOverrides(writeOverrides(`
+overrides:
+- vulnerability: CVE-2021-3326
+  package: libc-bin
+  severity: LOW
+  justification: the services never convert ISO-2022-JP-3 input
+- package: openssl
+  severity: CRITICAL
+  justification: TLS termination
+`))
+		Expect(err).NotTo(HaveOccurred())
+		results := []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2021-3326", PkgName: "libc-bin", Severity: "HIGH"},
+			{VulnerabilityID: "CVE-2021-3326", PkgName: "libc6", Severity: "HIGH"},
+			{VulnerabilityID: "CVE-2023-0286", PkgName: "openssl", Severity: "MEDIUM"},
+		}}}
+
+		overrides.apply(results)
+		image := NewScannedImage("debian:10", nil, results, nil)
+
+		Expect(results[0].Vulnerabilities).To(Equal([]Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-0286", PkgName: "openssl", Severity: "CRITICAL", OverriddenSeverity: &OverriddenSeverity{Severity: "MEDIUM", Justification: "TLS termination"}},
+			{VulnerabilityID: "CVE-2021-3326", PkgName: "libc6", Severity: "HIGH"},
+			{VulnerabilityID: "CVE-2021-3326", PkgName: "libc-bin", Severity: "LOW", OverriddenSeverity: &OverriddenSeverity{Severity: "HIGH", Justification: "the services never convert ISO-2022-JP-3 input"}},
+		}))
+		Expect(image.VulnerabilitySummary.TotalVulnerabilityBySeverity).To(Equal(map[string]int{"CRITICAL": 1, "HIGH": 1, "MEDIUM": 0, "LOW": 1, "UNKNOWN": 0}))
+		Expect((&TeamSummary{Images: []ScannedImage{image}}).SeverityOverrides()).To(HaveLen(2))
+	})
+
+	It("rejects the overrides without vulnerability nor package", func() {
+		_, err := LoadSeverityOverrides(writeOverrides(`{"overrides": [{"severity": "LOW"}]}`))
+
+		Expect(err).To(MatchError(ContainSubstring("severity override 1 of")))
+		Expect(err).To(MatchError(ContainSubstring("a vulnerability or a package is required")))
+	})
+
+	It("rejects the invalid severities", func() {
+		_, err := LoadSeverityOverrides(writeOverrides(`
+overrides:
+- vulnerability: CVE-2021-3326
+  severity: low
+`))
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid severity "low"`)))
+	})
+})
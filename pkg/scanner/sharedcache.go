@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// sharedCacheLockFile is the file of the trivy cache directory the runs sharing it lock, see Config.TrivySharedCache
+const sharedCacheLockFile = "production-readiness.lock"
+
+// sharedCacheRetry is the interval at which the lock of the shared cache is tried again while another run holds it
+var sharedCacheRetry = time.Second
+
+// sharedCache is the lock of the trivy cache directory shared by the workers of the scan and by the concurrent runs,
+// for instance the runs of a CronJob mounting the same volume. It is exclusive while the databases are updated and
+// shared while the images are scanned, so that a run never updates the databases the scans of another run read
+type sharedCache struct {
+	dir  string
+	file *os.File
+}
+
+// lockSharedCache takes the exclusive lock of the trivy cache directory, waiting for the other runs sharing it to
+// release their lock or for the context to be done. The trivy default cache directory is used when cacheDir is empty
+func lockSharedCache(ctx context.Context, cacheDir string) (*sharedCache, error) {
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("could not locate the trivy cache directory: %v", err)
+		}
+		cacheDir = filepath.Join(userCacheDir, "trivy")
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create the trivy cache directory %s: %v", cacheDir, err)
+	}
+	file, err := os.OpenFile(filepath.Join(cacheDir, sharedCacheLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the lock of the trivy cache directory %s: %v", cacheDir, err)
+	}
+	cache := &sharedCache{dir: cacheDir, file: file}
+	if err := cache.lock(ctx, true); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return cache, nil
+}
+
+func (c *sharedCache) lock(ctx context.Context, exclusive bool) error {
+	waiting := false
+	for {
+		locked, err := tryLockFile(c.file, exclusive)
+		if err != nil {
+			return fmt.Errorf("could not lock the trivy cache directory %s: %v", c.dir, err)
+		}
+		if locked {
+			return nil
+		}
+		if !waiting {
+			logr.Infof("Waiting for the other scans sharing the trivy cache directory %s", c.dir)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sharedCacheRetry):
+		}
+	}
+}
+
+// share turns the exclusive lock into a shared lock once the databases are up to date, the runs sharing the cache
+// then scanning concurrently. It does nothing when the cache is not shared
+func (c *sharedCache) share(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	return c.lock(ctx, false)
+}
+
+// close releases the lock of the cache
+func (c *sharedCache) close() {
+	if c == nil {
+		return
+	}
+	if err := c.file.Close(); err != nil {
+		logr.Warnf("Unable to release the lock of the trivy cache directory %s: %v", c.dir, err)
+	}
+}
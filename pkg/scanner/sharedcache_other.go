@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package scanner
+
+import "os"
+
+// tryLockFile does not lock the file as the file locks are not supported on this platform, the runs sharing the trivy
+// cache directory not waiting for each other
+func tryLockFile(_ *os.File, _ bool) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,55 @@
+//go:build linux || darwin
+
+package scanner
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared cache", func() {
+
+	var cacheDir string
+
+	BeforeEach(func() {
+		cacheDir = GinkgoT().TempDir()
+		retry := sharedCacheRetry
+		sharedCacheRetry = 10 * time.Millisecond
+		DeferCleanup(func() { sharedCacheRetry = retry })
+	})
+
+	lockWithin := func(timeout time.Duration) (*sharedCache, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return lockSharedCache(ctx, cacheDir)
+	}
+
+	It("waits for the run updating the databases or scanning with them", func() {
+		first, err := lockWithin(time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(cacheDir, sharedCacheLockFile)).To(BeARegularFile())
+
+		_, err = lockWithin(50 * time.Millisecond)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		Expect(first.share(context.Background())).To(Succeed())
+		_, err = lockWithin(50 * time.Millisecond)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		first.close()
+		second, err := lockWithin(time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		second.close()
+	})
+
+	It("does nothing when the cache is not shared", func() {
+		var cache *sharedCache
+
+		Expect(cache.share(context.Background())).To(Succeed())
+		cache.close()
+	})
+})
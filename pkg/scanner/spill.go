@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// spillStore keeps the trivy results of the scanned images on disk, so that the results of the thousands of images
+// scanned do not hold the memory the trivy scans of the workers and the report generation need. The images only keep
+// their summary in memory, their results being read back one image at a time when the reports are rendered, see
+// ScannedImage.Results.
+// The results of an image are written once and read whole, so a directory of files named after their content is
+// enough and the scanner does not depend on an embedded database
+type spillStore struct {
+	dir string
+	// lock guards the files, the results being spilled while collected and written again while the report is generated
+	lock sync.Mutex
+	// files are the files holding the spilled results by the sha256 of their content, the identical results of the
+	// images of the same digest being written once
+	files map[[sha256.Size]byte]string
+}
+
+// spilledResults are the trivy results of an image spilled to disk, shared by the copies of the image such as the
+// images of its team
+type spilledResults struct {
+	store *spillStore
+	file  string
+}
+
+// openSpillStore creates the store in a temporary directory of the spill directory, nil when no spill directory is
+// configured
+func openSpillStore(spillDir string) (*spillStore, error) {
+	if spillDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(spillDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create spill directory %s: %v", spillDir, err)
+	}
+	dir, err := os.MkdirTemp(spillDir, "scan-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create the spill store in %s: %v", spillDir, err)
+	}
+	return &spillStore{dir: dir, files: make(map[[sha256.Size]byte]string)}, nil
+}
+
+// spill writes the trivy results of the image to disk and releases them, the image being left as is when they cannot
+// be written. A nil store keeps the results in memory
+func (s *spillStore) spill(scannedImage *ScannedImage) {
+	if s == nil || len(scannedImage.TrivyOutputResults) == 0 {
+		return
+	}
+	file, err := s.write(scannedImage.TrivyOutputResults)
+	if err != nil {
+		logr.Warnf("Unable to spill the results of image %s to disk, keeping them in memory: %v", scannedImage.ImageName, err)
+		return
+	}
+	scannedImage.spilled = &spilledResults{store: s, file: file}
+	scannedImage.TrivyOutputResults = nil
+}
+
+// write writes the results to a file of the store unless identical results were already written, and returns the file
+func (s *spillStore) write(results []TrivyOutputResults) (string, error) {
+	content, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if file, ok := s.files[sum]; ok {
+		return file, nil
+	}
+	file := filepath.Join(s.dir, strconv.Itoa(len(s.files))+".json")
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		return "", err
+	}
+	s.files[sum] = file
+	return file, nil
+}
+
+// read reads the results of the file back
+func (s *spillStore) read(file string) ([]TrivyOutputResults, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var results []TrivyOutputResults
+	if err := json.Unmarshal(content, &results); err != nil {
+		return nil, fmt.Errorf("error while decoding %s: %v", file, err)
+	}
+	return results, nil
+}
+
+// empty returns true when no result was spilled
+func (s *spillStore) empty() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.files) == 0
+}
+
+// close removes the results spilled to disk
+func (s *spillStore) close() {
+	if s == nil {
+		return
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		logr.Warnf("Unable to remove spill store %s: %v", s.dir, err)
+	}
+}
+
+// Results returns the trivy results of the image, read from disk when they were spilled, see Config.SpillDir. The
+// results read from disk are not kept, each call reading them again
+func (i ScannedImage) Results() []TrivyOutputResults {
+	if i.spilled == nil {
+		return i.TrivyOutputResults
+	}
+	results, err := i.spilled.store.read(i.spilled.file)
+	if err != nil {
+		logr.Errorf("Unable to read the spilled results of image %s: %v", i.ImageName, err)
+	}
+	return results
+}
+
+// setResults replaces the trivy results of the image, its copies keeping their results. The results of an image
+// spilled to disk are spilled again
+func (i *ScannedImage) setResults(results []TrivyOutputResults) {
+	if i.spilled == nil {
+		i.TrivyOutputResults = results
+		return
+	}
+	file, err := i.spilled.store.write(results)
+	if err != nil {
+		logr.Warnf("Unable to spill the results of image %s to disk, keeping them in memory: %v", i.ImageName, err)
+		i.spilled, i.TrivyOutputResults = nil, results
+		return
+	}
+	i.spilled = &spilledResults{store: i.spilled.store, file: file}
+}
+
+// updateResults updates the trivy results of the image in place, the copies of the image sharing the update. The
+// results of an image spilled to disk are read, updated and spilled again
+func (i ScannedImage) updateResults(update func(results []TrivyOutputResults)) {
+	if i.spilled == nil {
+		update(i.TrivyOutputResults)
+		return
+	}
+	results := i.Results()
+	update(results)
+	file, err := i.spilled.store.write(results)
+	if err != nil {
+		logr.Warnf("Unable to spill the updated results of image %s to disk: %v", i.ImageName, err)
+		return
+	}
+	i.spilled.file = file
+}
+
+// Close removes the results of the report images spilled to disk, see Config.SpillDir. The results of the images are
+// no longer readable once the report is closed
+func (r *VulnerabilityReport) Close() {
+	if r == nil {
+		return
+	}
+	closed := make(map[*spillStore]bool)
+	for _, image := range r.ScannedImages {
+		if image.spilled != nil && !closed[image.spilled.store] {
+			closed[image.spilled.store] = true
+			image.spilled.store.close()
+		}
+	}
+}
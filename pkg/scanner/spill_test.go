@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spill store", func() {
+
+	var spillDir string
+
+	BeforeEach(func() {
+		spillDir = filepath.Join(GinkgoT().TempDir(), "spill")
+	})
+
+	results := func(packages ...string) []TrivyOutputResults {
+		var vulnerabilities []Vulnerabilities
+		for _, pkg := range packages {
+			vulnerabilities = append(vulnerabilities, Vulnerabilities{VulnerabilityID: "CVE-2023-0001", PkgName: pkg, Severity: "HIGH",
+				Description: "A buffer overflow in the parser", References: []string{"https://nvd.nist.gov/vuln/detail/CVE-2023-0001"}})
+		}
+		return []TrivyOutputResults{{Target: "debian (debian 12.1)", Type: "debian", Vulnerabilities: vulnerabilities}}
+	}
+
+	It("keeps the results of the images on disk until the report is closed", func() {
+		spill, err := openSpillStore(spillDir)
+		Expect(err).NotTo(HaveOccurred())
+		scannedImages := []ScannedImage{
+			NewScannedImage("nginx:1.25", nil, results("openssl"), nil),
+			NewScannedImage("nginx:stable", nil, results("openssl"), nil),
+			NewScannedImage("redis:7", nil, results("openssl", "libc6"), nil),
+			NewScannedImage("broken:1.0", nil, nil, nil),
+		}
+
+		for i := range scannedImages {
+			spill.spill(&scannedImages[i])
+		}
+
+		for _, image := range scannedImages {
+			Expect(image.TrivyOutputResults).To(BeNil())
+		}
+		Expect(scannedImages[2].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(2))
+		// the identical results of the images of the same digest are spilled once
+		files, err := os.ReadDir(spill.dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(2))
+		Expect(scannedImages[0].Results()).To(Equal(results("openssl")))
+		Expect(scannedImages[2].Results()).To(Equal(results("openssl", "libc6")))
+		Expect(scannedImages[3].Results()).To(BeEmpty())
+		encoded, err := json.Marshal(scannedImages[2])
+		Expect(err).NotTo(HaveOccurred())
+		var decoded ScannedImage
+		Expect(json.Unmarshal(encoded, &decoded)).To(Succeed())
+		Expect(decoded.TrivyOutputResults).To(Equal(results("openssl", "libc6")))
+
+		report := &VulnerabilityReport{ScannedImages: scannedImages}
+		report.Close()
+		Expect(spill.dir).NotTo(BeADirectory())
+	})
+
+	It("updates the spilled results of the copies of an image in place and replaces those of a single copy", func() {
+		spill, err := openSpillStore(spillDir)
+		Expect(err).NotTo(HaveOccurred())
+		scannedImage := NewScannedImage("nginx:1.25", nil, results("openssl", "libc6"), nil)
+		spill.spill(&scannedImage)
+		teamImage := scannedImage
+
+		scannedImage.updateResults(func(results []TrivyOutputResults) {
+			results[0].Vulnerabilities[0].Severity = "CRITICAL"
+		})
+		filtered := teamImage
+		filtered.setResults([]TrivyOutputResults{{Target: "debian (debian 12.1)", Type: "debian"}})
+
+		Expect(teamImage.Results()[0].Vulnerabilities[0].Severity).To(Equal("CRITICAL"))
+		Expect(scannedImage.Results()[0].Vulnerabilities).To(HaveLen(2))
+		Expect(filtered.Results()[0].Vulnerabilities).To(BeEmpty())
+		spill.close()
+	})
+
+	It("keeps the results in memory without spill directory", func() {
+		spill, err := openSpillStore("")
+		Expect(err).NotTo(HaveOccurred())
+		scannedImage := NewScannedImage("nginx:1.25", nil, results("openssl"), nil)
+
+		spill.spill(&scannedImage)
+
+		Expect(scannedImage.TrivyOutputResults).To(HaveLen(1))
+		Expect(scannedImage.Results()).To(HaveLen(1))
+		spill.close()
+	})
+})
@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// summaryImageCount is the number of images listed in the summary table
+const summaryImageCount = 10
+
+// summarySeverities are the severity columns of the summary table
+var summarySeverities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// ansiColors are the ANSI escape sequences of the severity counts when colored
+var ansiColors = map[string]string{
+	"CRITICAL": "\033[1;31m", "HIGH": "\033[31m", "MEDIUM": "\033[33m", "LOW": "\033[36m",
+}
+
+const ansiBold, ansiReset = "\033[1m", "\033[0m"
+
+// WriteSummaryTable writes a concise table of the report: the top vulnerable images by severity score, the vulnerability totals
+// per severity and per target type, the failed scans and the pull and removal errors, so that the operators get feedback
+// without opening the report. The severity counts are colored with ANSI escape sequences when colored is true, for terminals
+func (r *VulnerabilityReport) WriteSummaryTable(w io.Writer, colored bool) error {
+	var failed []ScannedImage
+	totals := make(map[string]int)
+	for _, image := range r.ScannedImages {
+		if image.ScanError != nil {
+			failed = append(failed, image)
+		}
+		for _, severity := range summarySeverities {
+			totals[severity] += image.VulnerabilitySummary.TotalVulnerabilityBySeverity[severity]
+		}
+	}
+	images := topVulnerableImages(r.ScannedImages, summaryImageCount)
+
+	table := summaryTable{colored: colored}
+	table.add("", append([]string{"IMAGE"}, summarySeverities...)...)
+	for _, image := range images {
+		table.addCounts(image.ImageName, image.VulnerabilitySummary.TotalVulnerabilityBySeverity)
+	}
+	table.addCounts(fmt.Sprintf("TOTAL (%d images)", len(r.ScannedImages)), totals)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s\n", table.style(ansiBold, fmt.Sprintf("Top %d images by severity", len(images))))
+	table.write(&out)
+	if breakdown := r.TargetTypeBreakdown(); len(breakdown) > 0 {
+		targetTypes := summaryTable{colored: colored}
+		targetTypes.add("", append([]string{"TARGET TYPE"}, summarySeverities...)...)
+		for _, summary := range breakdown {
+			targetTypes.addCounts(summary.TargetType, summary.TotalVulnerabilityBySeverity)
+		}
+		fmt.Fprintf(&out, "\n%s\n", table.style(ansiBold, "Vulnerabilities by target type"))
+		targetTypes.write(&out)
+	}
+	fmt.Fprintf(&out, "\n%s\n", table.style(ansiBold, fmt.Sprintf("Failed scans: %d", len(failed))))
+	for _, image := range failed {
+		message, _, _ := strings.Cut(image.ScanError.Error(), "\n")
+		fmt.Fprintf(&out, "  %s: %s\n", image.ImageName, message)
+	}
+	var imageErrors []ImageScanError
+	for _, scanError := range r.ScanErrors() {
+		if scanError.Stage != ScanStageScan {
+			imageErrors = append(imageErrors, scanError)
+		}
+	}
+	if len(imageErrors) > 0 {
+		fmt.Fprintf(&out, "\n%s\n", table.style(ansiBold, fmt.Sprintf("Pull and removal errors: %d", len(imageErrors))))
+		for _, imageError := range imageErrors {
+			message, _, _ := strings.Cut(imageError.Error, "\n")
+			fmt.Fprintf(&out, "  %s %s: %s\n", imageError.Stage, imageError.ImageName, message)
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// OutcomeSummary returns a one-line summary of the report, the number of images scanned and failed and the vulnerability
+// totals per severity, such as "42 images scanned, 1 failed, CRITICAL 2, HIGH 5, MEDIUM 10, LOW 3, UNKNOWN 0"
+func (r *VulnerabilityReport) OutcomeSummary() string {
+	var failed int
+	totals := make(map[string]int)
+	for _, image := range r.ScannedImages {
+		if image.ScanError != nil {
+			failed++
+		}
+		for _, severity := range summarySeverities {
+			totals[severity] += image.VulnerabilitySummary.TotalVulnerabilityBySeverity[severity]
+		}
+	}
+	summary := fmt.Sprintf("%d images scanned, %d failed", len(r.ScannedImages), failed)
+	for _, severity := range summarySeverities {
+		summary += fmt.Sprintf(", %s %d", severity, totals[severity])
+	}
+	return summary
+}
+
+// summaryTable aligns the cells of the rows, the escape sequences being applied once the cells are padded
+type summaryTable struct {
+	colored bool
+	rows    [][]string
+	colors  [][]string
+}
+
+func (t *summaryTable) add(color string, cells ...string) {
+	colors := make([]string, len(cells))
+	for i := range colors {
+		colors[i] = color
+	}
+	t.rows = append(t.rows, cells)
+	t.colors = append(t.colors, colors)
+}
+
+func (t *summaryTable) addCounts(name string, counts map[string]int) {
+	cells := []string{name}
+	colors := []string{""}
+	for _, severity := range summarySeverities {
+		cells = append(cells, fmt.Sprint(counts[severity]))
+		if counts[severity] > 0 {
+			colors = append(colors, ansiColors[severity])
+		} else {
+			colors = append(colors, "")
+		}
+	}
+	t.rows = append(t.rows, cells)
+	t.colors = append(t.colors, colors)
+}
+
+func (t *summaryTable) write(out *strings.Builder) {
+	widths := make([]int, len(summarySeverities)+1)
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for r, row := range t.rows {
+		var cells []string
+		for i, cell := range row {
+			if i == 0 {
+				cell = fmt.Sprintf("%-*s", widths[i], cell)
+			} else {
+				cell = fmt.Sprintf("%*s", widths[i], cell)
+			}
+			cells = append(cells, t.style(t.colors[r][i], cell))
+		}
+		fmt.Fprintf(out, "%s\n", strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+}
+
+// style wraps the text with the escape sequence when colored
+func (t *summaryTable) style(color, text string) string {
+	if !t.colored || color == "" {
+		return text
+	}
+	return color + text + ansiReset
+}
@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Summary table", func() {
+
+	summaryImage := func(name string, critical, high, medium int) ScannedImage {
+		return ScannedImage{ImageName: name, VulnerabilitySummary: VulnerabilitySummary{
+			SeverityScore:                critical*severityScores["CRITICAL"] + high*severityScores["HIGH"] + medium*severityScores["MEDIUM"],
+			TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": critical, "HIGH": high, "MEDIUM": medium},
+		}}
+	}
+
+	It("lists the top images by severity score, the totals and the failed scans", func() {
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			summaryImage("nginx:1.25", 0, 3, 10),
+			summaryImage("redis:7.2", 2, 1, 0),
+			{ImageName: "broken:1.0", ScanError: errors.New("unable to pull image\nmanifest unknown")},
+			{ImageName: "huge:1.0", Skipped: true, ScanError: errors.New("image larger than 1GB")},
+		}}
+		var out strings.Builder
+
+		Expect(report.WriteSummaryTable(&out, false)).To(Succeed())
+
+		Expect(out.String()).To(Equal(`Top 2 images by severity
+IMAGE             CRITICAL  HIGH  MEDIUM  LOW  UNKNOWN
+redis:7.2                2     1       0    0        0
+nginx:1.25               0     3      10    0        0
+TOTAL (4 images)         2     4      10    0        0
+
+Failed scans: 2
+  broken:1.0: unable to pull image
+  huge:1.0: image larger than 1GB
+`))
+	})
+
+	It("breaks the vulnerabilities down by target type", func() {
+		image := summaryImage("api:1.0", 1, 2, 0)
+		image.TrivyOutputResults = []TrivyOutputResults{
+			{Class: "os-pkgs", Type: "debian", Vulnerabilities: []Vulnerabilities{{Severity: "HIGH"}, {Severity: "HIGH"}}},
+			{Class: "lang-pkgs", Type: "gobinary", Vulnerabilities: []Vulnerabilities{{Severity: "CRITICAL"}}},
+		}
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{image}}
+		var out strings.Builder
+
+		Expect(report.WriteSummaryTable(&out, false)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`
+Vulnerabilities by target type
+TARGET TYPE  CRITICAL  HIGH  MEDIUM  LOW  UNKNOWN
+os-pkgs             0     2       0    0        0
+gobinary            1     0       0    0        0
+`))
+	})
+
+	It("lists the pull and removal errors", func() {
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			{ImageName: "nginx:1.25", PullError: "toomanyrequests\nretry later", RemoveError: "no such image"},
+		}}
+		var out strings.Builder
+
+		Expect(report.WriteSummaryTable(&out, false)).To(Succeed())
+
+		Expect(out.String()).To(HaveSuffix(`Failed scans: 0
+
+Pull and removal errors: 2
+  pull nginx:1.25: toomanyrequests
+  remove nginx:1.25: no such image
+`))
+	})
+
+	It("limits the table to the top 10 images and colors the severity counts", func() {
+		report := &VulnerabilityReport{}
+		for i := 0; i < 12; i++ {
+			report.ScannedImages = append(report.ScannedImages, summaryImage(fmt.Sprintf("app-%02d:1.0", i), 0, i, 0))
+		}
+		var out strings.Builder
+
+		Expect(report.WriteSummaryTable(&out, true)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring("Top 10 images by severity"))
+		Expect(out.String()).To(ContainSubstring("app-11:1.0"))
+		Expect(out.String()).NotTo(ContainSubstring("app-01:1.0"))
+		Expect(out.String()).To(ContainSubstring("\033[31m  11\033[0m"))
+	})
+
+	It("summarises the outcome of the scan in one line", func() {
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			summaryImage("nginx:1.25", 0, 3, 10),
+			summaryImage("redis:7.2", 2, 1, 0),
+			{ImageName: "broken:1.0", ScanError: errors.New("unable to pull image")},
+		}}
+
+		Expect(report.OutcomeSummary()).To(Equal("3 images scanned, 1 failed, CRITICAL 2, HIGH 4, MEDIUM 10, LOW 0, UNKNOWN 0"))
+	})
+})
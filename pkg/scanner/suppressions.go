@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"sort"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+)
+
+// SuppressedVulnerability is a vulnerability of an image suppressed by the namespaces running the image
+type SuppressedVulnerability struct {
+	VulnerabilityID string
+	PkgName         string
+	Severity        string
+	// Suppressions are the suppressions of the namespaces running the image matching the vulnerability, one per namespace
+	Suppressions []k8s.Suppression
+}
+
+// suppressVulnerabilities moves the vulnerabilities the namespaces running the images suppress out of the results of
+// the images, their summaries being recomputed, and returns the suppressions of the namespaces with the number of
+// vulnerabilities each suppressed. A vulnerability is only suppressed when all the namespaces running the image suppress
+// it, the images of other namespaces still reporting it. The images already suppressed are counted again, so that the
+// suppressions are the same whenever the report is generated
+func suppressVulnerabilities(images []ScannedImage, now time.Time) []k8s.SuppressionInEffect {
+	inEffect := k8s.NewSuppressionsInEffect(now)
+	for i := range images {
+		image := &images[i]
+		suppressionsByNamespace := make(map[string][]k8s.Suppression)
+		for _, container := range image.Containers {
+			inEffect.Declare(container.Suppressions)
+			suppressionsByNamespace[container.Namespace] = container.Suppressions
+		}
+		if suppressible(suppressionsByNamespace) {
+			suppressImageVulnerabilities(image, suppressionsByNamespace, now)
+		}
+		for _, suppressed := range image.SuppressedVulnerabilities {
+			for _, suppression := range suppressed.Suppressions {
+				inEffect.Count(suppression)
+			}
+		}
+	}
+	return inEffect.List()
+}
+
+// suppressible returns true when all the namespaces declare suppressions
+func suppressible(suppressionsByNamespace map[string][]k8s.Suppression) bool {
+	for _, suppressions := range suppressionsByNamespace {
+		if len(suppressions) == 0 {
+			return false
+		}
+	}
+	return len(suppressionsByNamespace) > 0
+}
+
+// suppressImageVulnerabilities moves the vulnerabilities suppressed by all the namespaces out of the image results. The
+// results are copied as they may be shared with other copies of the image
+func suppressImageVulnerabilities(image *ScannedImage, suppressionsByNamespace map[string][]k8s.Suppression, now time.Time) {
+	imageResults := image.Results()
+	results := make([]TrivyOutputResults, len(imageResults))
+	suppressedCount := len(image.SuppressedVulnerabilities)
+	for i, result := range imageResults {
+		results[i] = result
+		results[i].Vulnerabilities = nil
+		for _, vulnerability := range result.Vulnerabilities {
+			var matching []k8s.Suppression
+			for _, suppressions := range suppressionsByNamespace {
+				suppression, ok := k8s.MatchingSuppression(suppressions, vulnerability.VulnerabilityID, now)
+				if !ok {
+					matching = nil
+					break
+				}
+				matching = append(matching, suppression)
+			}
+			if matching == nil {
+				results[i].Vulnerabilities = append(results[i].Vulnerabilities, vulnerability)
+				continue
+			}
+			sort.Slice(matching, func(a, b int) bool { return matching[a].Namespace < matching[b].Namespace })
+			image.SuppressedVulnerabilities = append(image.SuppressedVulnerabilities, SuppressedVulnerability{
+				VulnerabilityID: vulnerability.VulnerabilityID,
+				PkgName:         vulnerability.PkgName,
+				Severity:        vulnerability.Severity,
+				Suppressions:    matching,
+			})
+		}
+	}
+	if len(image.SuppressedVulnerabilities) > suppressedCount {
+		image.setResults(results)
+		image.VulnerabilitySummary = image.buildVulnerabilitySummary()
+	}
+}
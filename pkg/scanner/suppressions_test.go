@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespace suppressions", func() {
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	suppressions := func(namespace, value string) []k8s.Suppression {
+		return k8s.NamespaceSuppressions(namespace, map[string]string{k8s.SuppressAnnotation: value, k8s.SuppressReasonAnnotation: "waiting for the vendor fix"})
+	}
+	image := func(containers ...k8s.ContainerSummary) ScannedImage {
+		return NewScannedImage("api:1", containers, []TrivyOutputResults{{Target: "debian", Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-4911", PkgName: "libc6", Severity: "HIGH"},
+			{VulnerabilityID: "CVE-2024-0001", PkgName: "openssl", Severity: "CRITICAL"},
+		}}}, nil)
+	}
+
+	It("moves the vulnerabilities the namespace suppresses out of the image results", func() {
+		images := []ScannedImage{image(k8s.ContainerSummary{Namespace: "payments", Suppressions: suppressions("payments", "CVE-2023-*@2024-03-31, CVE-2022-1234")})}
+
+		inEffect := suppressVulnerabilities(images, now)
+
+		Expect(images[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(1))
+		Expect(images[0].VulnerabilitySummary.TotalVulnerabilityBySeverity["HIGH"]).To(Equal(0))
+		Expect(images[0].SuppressedVulnerabilities).To(HaveLen(1))
+		Expect(images[0].SuppressedVulnerabilities[0].VulnerabilityID).To(Equal("CVE-2023-4911"))
+		Expect(inEffect).To(HaveLen(2))
+		Expect(inEffect[0].Pattern).To(Equal("CVE-2022-1234"))
+		Expect(inEffect[0].SuppressedCount).To(Equal(0))
+		Expect(inEffect[1].Pattern).To(Equal("CVE-2023-*"))
+		Expect(inEffect[1].SuppressedCount).To(Equal(1))
+		Expect(inEffect[1].ExpiryDate()).To(Equal("2024-03-31"))
+		Expect(inEffect[1].Reason).To(Equal("waiting for the vendor fix"))
+
+		// the suppressions are the same when the report is generated again
+		Expect(suppressVulnerabilities(images, now)).To(Equal(inEffect))
+	})
+
+	It("keeps the vulnerabilities of the images run by a namespace not suppressing them", func() {
+		images := []ScannedImage{image(
+			k8s.ContainerSummary{Namespace: "payments", Suppressions: suppressions("payments", "CVE-2023-4911")},
+			k8s.ContainerSummary{Namespace: "orders"},
+		)}
+
+		inEffect := suppressVulnerabilities(images, now)
+
+		Expect(images[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(2))
+		Expect(images[0].SuppressedVulnerabilities).To(BeEmpty())
+		Expect(inEffect).To(HaveLen(1))
+		Expect(inEffect[0].SuppressedCount).To(Equal(0))
+	})
+
+	It("lists the expired suppressions without applying them", func() {
+		images := []ScannedImage{image(k8s.ContainerSummary{Namespace: "payments", Suppressions: suppressions("payments", "CVE-2023-4911@2024-02-29")})}
+
+		inEffect := suppressVulnerabilities(images, now)
+
+		Expect(images[0].TrivyOutputResults[0].Vulnerabilities).To(HaveLen(2))
+		Expect(inEffect).To(HaveLen(1))
+		Expect(inEffect[0].Expired).To(BeTrue())
+	})
+})
@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"sort"
+)
+
+const (
+	// TargetTypeOSPackages is the target type of the packages of the OS layer, whatever the distribution
+	TargetTypeOSPackages = "os-pkgs"
+	// TargetTypeUnknown is the target type of the results without type
+	TargetTypeUnknown = "unknown"
+)
+
+// osPackageTypes are the trivy types of the OS package results, recorded without class by the older trivy versions
+// and by some image scan sources
+var osPackageTypes = map[string]bool{
+	"alma": true, "alpine": true, "amazon": true, "bottlerocket": true, "cbl-mariner": true, "centos": true,
+	"chainguard": true, "debian": true, "fedora": true, "opensuse": true, "opensuse.leap": true,
+	"opensuse.tumbleweed": true, "oracle": true, "photon": true, "redhat": true, "rocky": true, "sles": true,
+	"suse": true, "ubuntu": true, "wolfi": true,
+}
+
+// TargetType returns the type of the target the results were found in: os-pkgs for the packages of the OS layer,
+// the language ecosystem otherwise, for instance gobinary, npm or pip
+func (r TrivyOutputResults) TargetType() string {
+	switch {
+	case r.Class == TargetTypeOSPackages || osPackageTypes[r.Type]:
+		return TargetTypeOSPackages
+	case r.Type == "":
+		return TargetTypeUnknown
+	}
+	return r.Type
+}
+
+// TargetTypeSummary counts the vulnerabilities found in the targets of a type, so that the teams can tell the
+// vulnerabilities of the OS layer from those of their application dependencies
+type TargetTypeSummary struct {
+	TargetType                   string
+	TotalVulnerabilityBySeverity map[string]int
+	FixableCount                 int
+}
+
+// TargetTypeBreakdown counts the vulnerabilities of the report images by target type, see TargetTypeSummary
+func (r *VulnerabilityReport) TargetTypeBreakdown() []TargetTypeSummary {
+	return targetTypeBreakdown(r.ScannedImages)
+}
+
+// TargetTypeBreakdown counts the vulnerabilities of the team images by target type, see TargetTypeSummary
+func (t *TeamSummary) TargetTypeBreakdown() []TargetTypeSummary {
+	return targetTypeBreakdown(t.Images)
+}
+
+// targetTypeBreakdown counts the vulnerabilities of the images by target type, os-pkgs first and then the language
+// ecosystems by name. The target types without vulnerability are left out
+func targetTypeBreakdown(images []ScannedImage) []TargetTypeSummary {
+	summaries := make(map[string]*TargetTypeSummary)
+	for _, image := range images {
+		for _, result := range image.Results() {
+			if len(result.Vulnerabilities) == 0 {
+				continue
+			}
+			targetType := result.TargetType()
+			summary, ok := summaries[targetType]
+			if !ok {
+				summary = &TargetTypeSummary{TargetType: targetType, TotalVulnerabilityBySeverity: make(map[string]int)}
+				summaries[targetType] = summary
+			}
+			for _, vulnerability := range result.Vulnerabilities {
+				summary.TotalVulnerabilityBySeverity[vulnerability.Severity]++
+				if vulnerability.Fixable() {
+					summary.FixableCount++
+				}
+			}
+		}
+	}
+
+	var breakdown []TargetTypeSummary
+	for _, summary := range summaries {
+		breakdown = append(breakdown, *summary)
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		if (breakdown[i].TargetType == TargetTypeOSPackages) != (breakdown[j].TargetType == TargetTypeOSPackages) {
+			return breakdown[i].TargetType == TargetTypeOSPackages
+		}
+		return breakdown[i].TargetType < breakdown[j].TargetType
+	})
+	return breakdown
+}
@@ -0,0 +1,36 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Target type breakdown", func() {
+
+	It("tells the OS packages from the language ecosystems", func() {
+		Expect(TrivyOutputResults{Class: "os-pkgs", Type: "debian"}.TargetType()).To(Equal(TargetTypeOSPackages))
+		Expect(TrivyOutputResults{Type: "alpine"}.TargetType()).To(Equal(TargetTypeOSPackages))
+		Expect(TrivyOutputResults{Class: "lang-pkgs", Type: "gobinary"}.TargetType()).To(Equal("gobinary"))
+		Expect(TrivyOutputResults{}.TargetType()).To(Equal(TargetTypeUnknown))
+	})
+
+	It("counts the vulnerabilities of the images by target type, os-pkgs first", func() {
+		report := &VulnerabilityReport{ScannedImages: []ScannedImage{
+			{ImageName: "api:1.0", TrivyOutputResults: []TrivyOutputResults{
+				{Class: "os-pkgs", Type: "debian", Vulnerabilities: []Vulnerabilities{{Severity: "HIGH", FixedVersion: "1.1"}, {Severity: "LOW"}}},
+				{Class: "lang-pkgs", Type: "pip", Vulnerabilities: []Vulnerabilities{{Severity: "CRITICAL", FixedVersion: "2.0"}}},
+				{Class: "lang-pkgs", Type: "npm"},
+			}},
+			{ImageName: "web:1.0", TrivyOutputResults: []TrivyOutputResults{
+				{Type: "alpine", Vulnerabilities: []Vulnerabilities{{Severity: "HIGH"}}},
+				{Class: "lang-pkgs", Type: "gobinary", Vulnerabilities: []Vulnerabilities{{Severity: "MEDIUM"}}},
+			}},
+		}}
+
+		Expect(report.TargetTypeBreakdown()).To(Equal([]TargetTypeSummary{
+			{TargetType: TargetTypeOSPackages, TotalVulnerabilityBySeverity: map[string]int{"HIGH": 2, "LOW": 1}, FixableCount: 1},
+			{TargetType: "gobinary", TotalVulnerabilityBySeverity: map[string]int{"MEDIUM": 1}},
+			{TargetType: "pip", TotalVulnerabilityBySeverity: map[string]int{"CRITICAL": 1}, FixableCount: 1},
+		}))
+	})
+})
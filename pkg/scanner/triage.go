@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Triage states of the findings
+const (
+	// TriageFalsePositive is the state of the findings the package or the image is not actually affected by
+	TriageFalsePositive = "false-positive"
+	// TriageAccepted is the state of the findings whose risk is accepted
+	TriageAccepted = "accepted"
+	// TriageFixInProgress is the state of the findings being fixed
+	TriageFixInProgress = "fix-in-progress"
+)
+
+// TriageStates are the triage states a finding can be marked with
+var TriageStates = []string{TriageFalsePositive, TriageAccepted, TriageFixInProgress}
+
+// FindingTriage is the triage of a vulnerability found in an image digest, carried forward to the reports of all
+// the images running the digest whatever their tag
+type FindingTriage struct {
+	VulnerabilityID string `json:"vulnerability"`
+	Digest          string `json:"digest"`
+	State           string `json:"state"`
+	// Reason explains the triage, it is shown in the report
+	Reason    string    `json:"reason,omitempty"`
+	TriagedAt time.Time `json:"triagedAt"`
+}
+
+// FindingsState records the triage of the findings, so that the users' feedback on the findings is kept across the
+// scans. The triage of a finding applies until the finding is triaged again or cleared
+type FindingsState struct {
+	Findings []FindingTriage `json:"findings"`
+}
+
+// LoadFindingsState reads the findings state saved by the triage command, an empty state when the file does not exist yet
+func LoadFindingsState(filename string) (*FindingsState, error) {
+	state := &FindingsState{}
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read findings state file %s: %v", filename, err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("error while decoding findings state file %s: %v", filename, err)
+	}
+	return state, nil
+}
+
+// Save writes the findings state to the file, replacing it once fully written so that an interrupted save keeps the
+// previous state
+func (s *FindingsState) Save(filename string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("could not create the directory of findings state file %s: %v", filename, err)
+	}
+	if err := os.WriteFile(filename+".tmp", content, 0644); err != nil {
+		return fmt.Errorf("could not write findings state file %s: %v", filename, err)
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// Triage records the triage of the finding, replacing its previous triage if any
+func (s *FindingsState) Triage(triage FindingTriage) error {
+	if triage.VulnerabilityID == "" || triage.Digest == "" {
+		return fmt.Errorf("a vulnerability and an image digest are required to triage a finding")
+	}
+	if !containsState(triage.State) {
+		return fmt.Errorf("invalid triage state %q, permitted values: %s", triage.State, strings.Join(TriageStates, ", "))
+	}
+	s.Clear(triage.VulnerabilityID, triage.Digest)
+	s.Findings = append(s.Findings, triage)
+	sort.SliceStable(s.Findings, func(i, j int) bool {
+		if s.Findings[i].VulnerabilityID != s.Findings[j].VulnerabilityID {
+			return s.Findings[i].VulnerabilityID < s.Findings[j].VulnerabilityID
+		}
+		return s.Findings[i].Digest < s.Findings[j].Digest
+	})
+	return nil
+}
+
+// Clear removes the triage of the finding, returning false when the finding was not triaged
+func (s *FindingsState) Clear(vulnerabilityID, digest string) bool {
+	var findings []FindingTriage
+	for _, finding := range s.Findings {
+		if finding.VulnerabilityID != vulnerabilityID || finding.Digest != digest {
+			findings = append(findings, finding)
+		}
+	}
+	cleared := len(findings) != len(s.Findings)
+	s.Findings = findings
+	return cleared
+}
+
+// WriteText writes the triaged findings as text, one finding per line
+func (s *FindingsState) WriteText(w io.Writer) error {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Triaged findings (%d)\n", len(s.Findings))
+	for _, finding := range s.Findings {
+		fmt.Fprintf(&out, "  %s %s in %s, triaged %s", finding.State, finding.VulnerabilityID, finding.Digest, finding.TriagedAt.Format("2006-01-02"))
+		if finding.Reason != "" {
+			fmt.Fprintf(&out, ": %s", finding.Reason)
+		}
+		out.WriteString("\n")
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// Apply sets the triage of the vulnerabilities of the report images, matched by vulnerability id and image digest.
+// The vulnerabilities of the images of unknown digest are never triaged
+func (s *FindingsState) Apply(r *VulnerabilityReport) {
+	s.annotate(r.ScannedImages)
+	for _, area := range r.AreaSummary {
+		for _, team := range area.Teams {
+			s.annotate(team.Images)
+		}
+	}
+}
+
+// annotate sets the triage of the vulnerabilities of the images
+func (s *FindingsState) annotate(images []ScannedImage) {
+	triages := make(map[string]FindingTriage)
+	for _, finding := range s.Findings {
+		triages[finding.VulnerabilityID+"@"+finding.Digest] = finding
+	}
+	for _, image := range images {
+		digest := image.Digest()
+		if digest == "" {
+			continue
+		}
+		image.updateResults(func(results []TrivyOutputResults) {
+			for i := range results {
+				vulnerabilities := results[i].Vulnerabilities
+				for j := range vulnerabilities {
+					vulnerabilities[j].Triage = nil
+					if triage, ok := triages[vulnerabilities[j].VulnerabilityID+"@"+digest]; ok {
+						vulnerabilities[j].Triage = &triage
+					}
+				}
+			}
+		})
+	}
+}
+
+// Dismissed returns true when the finding is triaged as a false positive or accepted, so that it is not alerted on
+func (t *FindingTriage) Dismissed() bool {
+	return t != nil && (t.State == TriageFalsePositive || t.State == TriageAccepted)
+}
+
+// ImageDigest returns the digest of the report image, empty when the image is not in the report or its digest is unknown
+func (r *VulnerabilityReport) ImageDigest(imageName string) string {
+	for _, image := range r.ScannedImages {
+		if image.ImageName == imageName {
+			return image.Digest()
+		}
+	}
+	return ""
+}
+
+// TriagedFindings returns the vulnerabilities of the team images triaged in the findings state
+func (t *TeamSummary) TriagedFindings() []VulnerabilityFinding {
+	return matchingFindings(t.Images, func(v Vulnerabilities) bool { return v.Triage != nil })
+}
+
+func containsState(state string) bool {
+	for _, s := range TriageStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Findings triage", func() {
+
+	var (
+		stateFile string
+		triagedAt = time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		tmpDir, err := os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmpDir)
+		stateFile = filepath.Join(tmpDir, "triage", "findings.json")
+	})
+
+	teamImage := func(name, digest string, vulnerabilities ...Vulnerabilities) ScannedImage {
+		return ScannedImage{
+			ImageName:          name,
+			Containers:         []k8s.ContainerSummary{{Image: name, ImageDigest: digest, NamespaceLabels: map[string]string{"area": "finance", "team": "payments"}}},
+			TrivyOutputResults: []TrivyOutputResults{{Target: "debian", Vulnerabilities: vulnerabilities}},
+		}
+	}
+
+	scanReport := func(images ...ScannedImage) *VulnerabilityReport {
+		report, err := (&AreaReport{AreaLabelName: "area", TeamLabelName: "team"}).GenerateVulnerabilityReport(images)
+		Expect(err).NotTo(HaveOccurred())
+		return report
+	}
+
+	critical := Vulnerabilities{VulnerabilityID: "CVE-1", PkgName: "openssl", Severity: "CRITICAL"}
+	high := Vulnerabilities{VulnerabilityID: "CVE-2", PkgName: "curl", Severity: "HIGH"}
+
+	It("saves the triage of the findings, replacing their previous triage", func() {
+		state, err := LoadFindingsState(stateFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Findings).To(BeEmpty())
+
+		Expect(state.Triage(FindingTriage{VulnerabilityID: "CVE-2", Digest: "sha256:api", State: TriageFixInProgress, TriagedAt: triagedAt})).To(Succeed())
+		Expect(state.Triage(FindingTriage{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: TriageFixInProgress, TriagedAt: triagedAt})).To(Succeed())
+		Expect(state.Triage(FindingTriage{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: TriageFalsePositive, Reason: "not reachable", TriagedAt: triagedAt})).To(Succeed())
+		Expect(state.Save(stateFile)).To(Succeed())
+
+		saved, err := LoadFindingsState(stateFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(saved.Findings).To(Equal([]FindingTriage{
+			{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: TriageFalsePositive, Reason: "not reachable", TriagedAt: triagedAt},
+			{VulnerabilityID: "CVE-2", Digest: "sha256:api", State: TriageFixInProgress, TriagedAt: triagedAt},
+		}))
+
+		var text bytes.Buffer
+		Expect(saved.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(Equal("Triaged findings (2)\n" +
+			"  false-positive CVE-1 in sha256:api, triaged 2023-09-01: not reachable\n" +
+			"  fix-in-progress CVE-2 in sha256:api, triaged 2023-09-01\n"))
+	})
+
+	It("clears the triage of the findings", func() {
+		state := &FindingsState{Findings: []FindingTriage{{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: TriageAccepted}}}
+
+		Expect(state.Clear("CVE-1", "sha256:web")).To(BeFalse())
+		Expect(state.Clear("CVE-1", "sha256:api")).To(BeTrue())
+		Expect(state.Findings).To(BeEmpty())
+	})
+
+	It("rejects the invalid triages", func() {
+		state := &FindingsState{}
+
+		Expect(state.Triage(FindingTriage{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: "wontfix"})).To(MatchError(ContainSubstring(`invalid triage state "wontfix"`)))
+		Expect(state.Triage(FindingTriage{VulnerabilityID: "CVE-1", State: TriageAccepted})).To(HaveOccurred())
+	})
+
+	It("carries the triage forward to the vulnerabilities of the images running the digest", func() {
+		state := &FindingsState{Findings: []FindingTriage{
+			{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: TriageFalsePositive, TriagedAt: triagedAt},
+			{VulnerabilityID: "CVE-2", Digest: "sha256:web", State: TriageFixInProgress, TriagedAt: triagedAt},
+		}}
+		report := scanReport(teamImage("api:1", "sha256:api", critical, high), teamImage("api:latest", "sha256:api", critical), teamImage("web:1", "", high))
+
+		state.Apply(report)
+
+		Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[0].Triage.State).To(Equal(TriageFalsePositive))
+		Expect(report.ScannedImages[0].TrivyOutputResults[0].Vulnerabilities[1].Triage).To(BeNil())
+		Expect(report.ScannedImages[1].TrivyOutputResults[0].Vulnerabilities[0].Triage.State).To(Equal(TriageFalsePositive))
+		Expect(report.ScannedImages[2].TrivyOutputResults[0].Vulnerabilities[0].Triage).To(BeNil())
+		team := report.AreaSummary["finance"].Teams["payments"]
+		Expect(team.TriagedFindings()).To(HaveLen(2))
+		Expect(report.ImageDigest("api:latest")).To(Equal("sha256:api"))
+		Expect(report.ImageDigest("web:1")).To(BeEmpty())
+	})
+
+	It("does not report the false positives and the accepted vulnerabilities as new", func() {
+		state := &FindingsState{Findings: []FindingTriage{
+			{VulnerabilityID: "CVE-1", Digest: "sha256:api", State: TriageFalsePositive},
+			{VulnerabilityID: "CVE-1", Digest: "sha256:web", State: TriageFixInProgress},
+		}}
+		report := scanReport(teamImage("api:1", "sha256:api", critical), teamImage("web:1", "sha256:web", critical))
+
+		state.Apply(report)
+
+		findings := report.NewVulnerabilities(nil, "CRITICAL")
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].ImageName).To(Equal("web:1"))
+	})
+})
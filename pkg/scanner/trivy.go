@@ -1,10 +1,13 @@
 package scanner
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
@@ -14,38 +17,211 @@ import (
 
 // TrivyClient is a thin client for trivy
 type TrivyClient interface {
-	DownloadDatabase(cmd string) error
-	ScanImage(image string) ([]TrivyOutputResults, error)
+	// DownloadDatabase and ScanImage stop trivy when the context is done
+	DownloadDatabase(ctx context.Context, cmd string) error
+	// ScanImage returns a ScanTimeoutError when the scan times out, with the partial output of trivy when there is one
+	ScanImage(ctx context.Context, image string) (*TrivyOutput, error)
+	// SBOM returns the CycloneDX SBOM of the image, with the vulnerabilities of its components when requested
+	SBOM(ctx context.Context, image string, withVulnerabilities bool) ([]byte, error)
 	CisScan(benchmark string) (*CisOutput, error)
+	Version() (*TrivyVersion, error)
+}
+
+// TrivyVersion is an object representation of the trivy version and of its vulnerability database
+type TrivyVersion struct {
+	Version         string
+	VulnerabilityDB struct {
+		Version   int
+		UpdatedAt time.Time
+	}
+}
+
+// ScanTimeoutError is the error of a trivy scan exceeding the scan timeout
+type ScanTimeoutError struct {
+	Image   string
+	Timeout time.Duration
+}
+
+func (e *ScanTimeoutError) Error() string {
+	return fmt.Sprintf("trivy scan of image %s timed out after %v", e.Image, e.Timeout)
+}
+
+// DefaultTrivyCommand is the trivy binary run when no path is specified, looked up on the PATH
+const DefaultTrivyCommand = "trivy"
+
+// TrivyDBConfig locates the trivy vulnerability database, for the air-gapped clusters where it cannot be downloaded
+// from ghcr.io
+type TrivyDBConfig struct {
+	// SkipUpdate scans with the database of the cache directory as is, without downloading or updating it
+	SkipUpdate bool
+	// Repository is the OCI repository the database is downloaded from, for instance a mirror of ghcr.io/aquasecurity/trivy-db
+	Repository string
+	// CacheDir is the trivy cache directory holding the database in its db subdirectory, the trivy default when empty
+	CacheDir string
+	// JavaDB is when the Java index database of the jar files is downloaded, JavaDBOnDemand when empty. It is the only
+	// language database trivy downloads
+	JavaDB string
+	// JavaDBRepository is the OCI repository the Java index database is downloaded from, for instance a mirror of
+	// ghcr.io/aquasecurity/trivy-java-db
+	JavaDBRepository string
+}
+
+const (
+	// JavaDBOnDemand lets trivy download the Java index database during the first scan of an image holding jar files,
+	// the concurrent scans possibly racing to download it
+	JavaDBOnDemand = "on-demand"
+	// JavaDBPrefetch downloads the Java index database with the vulnerability database before the scans, which do not update it
+	JavaDBPrefetch = "prefetch"
+	// JavaDBSkip never downloads the Java index database, the scans using the one of the cache directory if any
+	JavaDBSkip = "skip"
+)
+
+// ValidateJavaDB returns an error when the Java index database mode is unknown, see TrivyDBConfig.JavaDB
+func ValidateJavaDB(javaDB string) error {
+	switch javaDB {
+	case "", JavaDBOnDemand, JavaDBPrefetch, JavaDBSkip:
+		return nil
+	}
+	return fmt.Errorf("unknown java db mode %q, must be %s, %s or %s", javaDB, JavaDBOnDemand, JavaDBPrefetch, JavaDBSkip)
+}
+
+// cacheDirArgs returns the trivy arguments of the cache directory, none for the trivy default
+func (c TrivyDBConfig) cacheDirArgs() []string {
+	if c.CacheDir == "" {
+		return nil
+	}
+	return []string{"--cache-dir", c.CacheDir}
+}
+
+// repositoryArgs returns the trivy arguments of the database repository, none for the trivy default
+func (c TrivyDBConfig) repositoryArgs() []string {
+	if c.Repository == "" {
+		return nil
+	}
+	return []string{"--db-repository", c.Repository}
+}
+
+// javaDBRepositoryArgs returns the trivy arguments of the Java index database repository, none for the trivy default
+func (c TrivyDBConfig) javaDBRepositoryArgs() []string {
+	if c.JavaDBRepository == "" {
+		return nil
+	}
+	return []string{"--java-db-repository", c.JavaDBRepository}
+}
+
+// javaDBScanArgs returns the trivy arguments of the Java index database of the image scans, which only download it on demand
+func (c TrivyDBConfig) javaDBScanArgs() []string {
+	if c.JavaDB == JavaDBPrefetch || c.JavaDB == JavaDBSkip {
+		return []string{"--skip-java-db-update"}
+	}
+	return c.javaDBRepositoryArgs()
+}
+
+// KubernetesArgs returns the arguments of a trivy kubernetes scan, such as the compliance scans and the misconfiguration
+// check, with the cache directory and the vulnerability db of the image scans. The kubernetes scans never download the
+// Java index db, and do not update the checks bundle either when the vulnerability db is not updated
+func (c TrivyDBConfig) KubernetesArgs(args ...string) []string {
+	args = append(append(c.cacheDirArgs(), args...), "--skip-java-db-update")
+	if c.SkipUpdate {
+		args = append(args, "--skip-db-update", "--skip-policy-update")
+	}
+	return append(args, c.repositoryArgs()...)
 }
 
 type trivyClient struct {
-	severity      string
-	timeout       time.Duration
+	command   string
+	severity  string
+	timeout   time.Duration
+	scanners  []string
+	extraArgs trivyExtraArgs
+	policies  RegoPolicies
+	// imageSource is the trivy image source the pulled images are read from, for instance podman, trivy trying each
+	// of its sources when empty
+	imageSource string
+	// insecureRegistries are the registries trivy reads the images from without TLS verification
+	insecureRegistries []string
+	db                 TrivyDBConfig
+	// ignoreUnfixed only reports the vulnerabilities with a fixed version
+	ignoreUnfixed bool
 	commandRunner execCmd.CommandRunner
 }
 
-// NewTrivyClient creates a new TrivyClient
-func NewTrivyClient(severity string, timeout time.Duration) TrivyClient {
-	return &trivyClient{severity: severity, timeout: timeout, commandRunner: execCmd.NewCommandRunner()}
+// trivyExtraArgs are the arguments passed through to the trivy invocations, see Config.TrivyExtraArgs
+type trivyExtraArgs struct {
+	all, image, sbom, cis []string
+}
+
+// NewTrivyClient creates a new TrivyClient. The images are scanned with the given trivy scanners, for instance
+// vuln and secret, or with the vulnerability scanner only when none is given
+func NewTrivyClient(severity string, timeout time.Duration, scanners []string) TrivyClient {
+	return NewTrivyClientWithCommand(DefaultTrivyCommand, severity, timeout, scanners)
 }
 
-func (t *trivyClient) DownloadDatabase(cmd string) error {
+// NewTrivyClientWithCommand creates a new TrivyClient running the trivy binary at the path
+func NewTrivyClientWithCommand(command, severity string, timeout time.Duration, scanners []string) TrivyClient {
+	return &trivyClient{command: command, severity: severity, timeout: timeout, scanners: scanners, commandRunner: execCmd.NewCommandRunner()}
+}
+
+// DownloadDatabase downloads or updates the database, unless the update is skipped in which case the database is
+// only checked to be present in the cache directory, when one is configured. The Java index database is then
+// downloaded when prefetched, so that the concurrent scans do not race to download it
+func (t *trivyClient) DownloadDatabase(ctx context.Context, cmd string) error {
+	if err := t.downloadVulnerabilityDB(ctx, cmd); err != nil {
+		return err
+	}
+	if t.db.JavaDB != JavaDBPrefetch {
+		return nil
+	}
+	logr.Infof("Trivy downloading/updating java db")
+	args := append(append(append([]string{"-q", cmd, "--download-java-db-only"}, t.db.cacheDirArgs()...), t.db.javaDBRepositoryArgs()...), t.extraArgs.all...)
+	_, errOutput, err := t.commandRunner.ExecuteContext(ctx, t.command, args)
+	if err != nil {
+		return fmt.Errorf("error while downloading trivy java db. Error output: %s, Error: %v", utils.ConvertByteToString(errOutput), err)
+	}
+	return nil
+}
+
+func (t *trivyClient) downloadVulnerabilityDB(ctx context.Context, cmd string) error {
+	if t.db.SkipUpdate {
+		if t.db.CacheDir == "" {
+			logr.Infof("Skipping the trivy db update, using the db of the trivy cache directory")
+			return nil
+		}
+		dbFile := filepath.Join(t.db.CacheDir, "db", "trivy.db")
+		if _, err := os.Stat(dbFile); err != nil {
+			return fmt.Errorf("the trivy db update is skipped but no trivy db is found: %v", err)
+		}
+		logr.Infof("Skipping the trivy db update, using the db of %s", dbFile)
+		return nil
+	}
 	logr.Infof("Trivy downloading/updating db")
-	command := exec.Command("trivy", "-q", cmd, "--download-db-only")
-	_, err := command.CombinedOutput()
+	args := append(append(append([]string{"-q", cmd, "--download-db-only"}, t.db.cacheDirArgs()...), t.db.repositoryArgs()...), t.extraArgs.all...)
+	_, errOutput, err := t.commandRunner.ExecuteContext(ctx, t.command, args)
 	if err != nil {
-		return fmt.Errorf("error while downloading trivy db: %v", err)
+		return fmt.Errorf("error while downloading trivy db. Error output: %s, Error: %v", utils.ConvertByteToString(errOutput), err)
 	}
 	return nil
 }
 
-func (t *trivyClient) ScanImage(image string) ([]TrivyOutputResults, error) {
-	cmd := "trivy"
-	args := []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", t.severity, "--timeout", t.timeout.String(), image}
-	output, errOutput, err := t.commandRunner.Execute(cmd, args)
+func (t *trivyClient) ScanImage(ctx context.Context, image string) (*TrivyOutput, error) {
+	args := []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", t.severity, "--timeout", t.timeout.String()}
+	args = append(append(args, t.db.cacheDirArgs()...), t.db.javaDBScanArgs()...)
+	scanners := t.scanners
+	if len(scanners) == 0 {
+		// trivy runs its secret scanner too by default, which is slow and only opted in with Config.ScanSecrets
+		scanners = []string{"vuln"}
+	}
+	args = append(args, "--scanners", strings.Join(scanners, ","))
+	if t.ignoreUnfixed {
+		args = append(args, "--ignore-unfixed")
+	}
+	args = append(append(append(t.imageArgs(args, image), t.extraArgs.all...), t.extraArgs.image...), image)
+	output, errOutput, err := t.commandRunner.ExecuteContext(ctx, t.command, args)
 
 	errOutputAsString := utils.ConvertByteToString(errOutput)
+	if err != nil && strings.Contains(errOutputAsString, "context deadline exceeded") && ctx.Err() == nil {
+		return t.partialOutput(output), &ScanTimeoutError{Image: image, Timeout: t.timeout}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error while executing trivy for image %s. Output: %s, Error output: %s, Error: %v", image, utils.ConvertByteToString(output), errOutputAsString, err)
 	}
@@ -55,13 +231,51 @@ func (t *trivyClient) ScanImage(image string) ([]TrivyOutputResults, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error while decoding trivy output for image %s: %v", image, err)
 	}
-	return sortTrivyVulnerabilities(trivyOutput.Results), nil
+	trivyOutput.Results = sortTrivyVulnerabilities(trivyOutput.Results)
+	trivyOutput.Raw = output
+	return &trivyOutput, nil
+}
+
+func (t *trivyClient) SBOM(ctx context.Context, image string, withVulnerabilities bool) ([]byte, error) {
+	args := append(append([]string{"-q", "image", "-f", "cyclonedx", "--skip-update", "--no-progress", "--timeout", t.timeout.String()}, t.db.cacheDirArgs()...), t.db.javaDBScanArgs()...)
+	if withVulnerabilities {
+		args = append(args, "--scanners", "vuln", "--severity", t.severity)
+	}
+	args = append(append(append(t.imageArgs(args, image), t.extraArgs.all...), t.extraArgs.sbom...), image)
+	output, errOutput, err := t.commandRunner.ExecuteContext(ctx, t.command, args)
+	if err != nil {
+		return nil, fmt.Errorf("error while generating the SBOM of image %s. Error output: %s, Error: %v", image, utils.ConvertByteToString(errOutput), err)
+	}
+	return output, nil
+}
+
+// imageArgs appends the image source when set to the image scan arguments, and the insecure flag when the image is
+// hosted by an insecure registry
+func (t *trivyClient) imageArgs(args []string, image string) []string {
+	if t.imageSource != "" {
+		args = append(args, "--image-src", t.imageSource)
+	}
+	if insecureRegistry(t.insecureRegistries, image) {
+		args = append(args, "--insecure")
+	}
+	return args
+}
+
+// partialOutput decodes the output trivy wrote before timing out, nil when it wrote no complete output
+func (t *trivyClient) partialOutput(output []byte) *TrivyOutput {
+	var trivyOutput TrivyOutput
+	if len(output) == 0 || json.Unmarshal(output, &trivyOutput) != nil {
+		return nil
+	}
+	trivyOutput.Results = sortTrivyVulnerabilities(trivyOutput.Results)
+	trivyOutput.Raw = output
+	return &trivyOutput
 }
 
 func (t *trivyClient) CisScan(benchmark string) (*CisOutput, error) {
-	cmd := "trivy"
-	args := []string{"--cache-dir", ".trivycache/", "--timeout", t.timeout.String(), "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", benchmark, "--slow", "cluster", "--severity", t.severity}
-	output, errOutput, err := t.commandRunner.Execute(cmd, args)
+	args := t.db.KubernetesArgs("--timeout", t.timeout.String(), "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", benchmark, "--slow", "cluster", "--severity", t.severity)
+	args = append(append(append(args, t.policies.TrivyArgs()...), t.extraArgs.all...), t.extraArgs.cis...)
+	output, errOutput, err := t.commandRunner.Execute(t.command, args)
 
 	errOutputAsString := utils.ConvertByteToString(errOutput)
 	if err != nil {
@@ -76,15 +290,35 @@ func (t *trivyClient) CisScan(benchmark string) (*CisOutput, error) {
 	return cisOutput, nil
 }
 
+func (t *trivyClient) Version() (*TrivyVersion, error) {
+	output, errOutput, err := t.commandRunner.Execute(t.command, append([]string{"version", "-f", "json"}, t.db.cacheDirArgs()...))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting trivy version. Error output: %s, Error: %v", utils.ConvertByteToString(errOutput), err)
+	}
+
+	var version TrivyVersion
+	err = json.Unmarshal(output, &version)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding trivy version output: %v", err)
+	}
+	return &version, nil
+}
+
+// sortTrivyVulnerabilities sorts the vulnerabilities by decreasing severity, the vulnerabilities with the same severity
+// by decreasing normalised score
 func sortTrivyVulnerabilities(trivyOuput []TrivyOutputResults) []TrivyOutputResults {
 	severityScores := map[string]int{
 		"CRITICAL": 100000000, "HIGH": 1000000, "MEDIUM": 10000, "LOW": 100, "UNKNOWN": 1,
 	}
 
 	for z := 0; z < len(trivyOuput); z++ {
-		sort.Slice(trivyOuput[z].Vulnerabilities, func(i, j int) bool {
-			firstItemScore := severityScores[trivyOuput[z].Vulnerabilities[i].Severity]
-			secondItemScore := severityScores[trivyOuput[z].Vulnerabilities[j].Severity]
+		vulnerabilities := trivyOuput[z].Vulnerabilities
+		sort.SliceStable(vulnerabilities, func(i, j int) bool {
+			firstItemScore := severityScores[vulnerabilities[i].Severity]
+			secondItemScore := severityScores[vulnerabilities[j].Severity]
+			if firstItemScore == secondItemScore {
+				return vulnerabilities[i].NormalizedScore() > vulnerabilities[j].NormalizedScore()
+			}
 			return firstItemScore > secondItemScore
 		})
 	}
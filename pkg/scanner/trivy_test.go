@@ -1,7 +1,11 @@
 package scanner
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -60,7 +64,7 @@ var _ = Describe("Trivy client", func() {
 
 		BeforeEach(func() {
 			mockRunner = &mockCommanderRunner{}
-			trivy = &trivyClient{severity: severity, timeout: 7 * time.Minute, commandRunner: mockRunner}
+			trivy = &trivyClient{command: "trivy", severity: severity, timeout: 7 * time.Minute, commandRunner: mockRunner}
 		})
 
 		Describe("Scan", func() {
@@ -70,28 +74,183 @@ var _ = Describe("Trivy client", func() {
 					Results: []TrivyOutputResults{},
 				})
 				Expect(jsonerr).NotTo(HaveOccurred())
-				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "alpine:3.11.0"}).
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "alpine:3.11.0"}).
 					Return(output, []byte{}, nil)
 
-				scanOutput, err := trivy.ScanImage("alpine:3.11.0")
+				scanOutput, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
 				Expect(err).NotTo(HaveOccurred())
-				Expect(scanOutput).Should(Equal([]TrivyOutputResults{}))
+				Expect(scanOutput).Should(Equal(&TrivyOutput{Results: []TrivyOutputResults{}, Raw: output}))
+			})
+
+			It("does not update the prefetched java db", func() {
+				trivy.db = TrivyDBConfig{CacheDir: "/var/lib/trivy", JavaDB: JavaDBPrefetch, JavaDBRepository: "mirror.example.com/aquasecurity/trivy-java-db"}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--cache-dir", "/var/lib/trivy", "--skip-java-db-update", "--scanners", "vuln", "alpine:3.11.0"}).
+					Return([]byte(`{}`), []byte{}, nil)
+
+				_, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("prefetches the java db when the workers share the cache", func() {
+				trivy = (&Config{Severity: severity, ScanImageTimeout: 7 * time.Minute, TrivySharedCache: true}).NewTrivyClient().(*trivyClient)
+				trivy.commandRunner = mockRunner
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--skip-java-db-update", "--scanners", "vuln", "alpine:3.11.0"}).
+					Return([]byte(`{}`), []byte{}, nil)
+
+				_, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("downloads the java db on demand from the configured repository", func() {
+				trivy.db = TrivyDBConfig{JavaDBRepository: "mirror.example.com/aquasecurity/trivy-java-db"}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--java-db-repository", "mirror.example.com/aquasecurity/trivy-java-db", "--scanners", "vuln", "alpine:3.11.0"}).
+					Return([]byte(`{}`), []byte{}, nil)
+
+				_, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("runs the configured trivy scanners", func() {
+				trivy.scanners = []string{"vuln", "secret"}
+				output := []byte(`{"Results":[{"Target":"/app/.env","Class":"secret","Secrets":[{"RuleID":"aws-access-key-id","Category":"AWS","Severity":"CRITICAL","Title":"AWS Access Key ID","StartLine":3,"EndLine":3,"Match":"AWS_ACCESS_KEY_ID=********************"}]}]}`)
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln,secret", "alpine:3.11.0"}).
+					Return(output, []byte{}, nil)
+
+				scanOutput, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(scanOutput).Should(Equal(&TrivyOutput{Results: []TrivyOutputResults{{
+					Target:  "/app/.env",
+					Class:   "secret",
+					Secrets: []Secret{{RuleID: "aws-access-key-id", Category: "AWS", Severity: "CRITICAL", Title: "AWS Access Key ID", StartLine: 3, EndLine: 3}},
+				}}, Raw: output}))
+			})
+
+			It("ignores the unfixed vulnerabilities", func() {
+				trivy = (&Config{Severity: severity, ScanImageTimeout: 7 * time.Minute, IgnoreUnfixed: true}).NewTrivyClient().(*trivyClient)
+				trivy.commandRunner = mockRunner
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "--ignore-unfixed", "alpine:3.11.0"}).
+					Return([]byte(`{"Results":[]}`), []byte{}, nil)
+
+				_, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("passes the extra arguments through to trivy before the image", func() {
+				trivy = (&Config{Severity: severity, ScanImageTimeout: 7 * time.Minute, TrivyExtraArgs: []string{"--offline-scan"}, TrivyImageExtraArgs: []string{"--ignore-unfixed"}, TrivySBOMExtraArgs: []string{"--sbom-sources", "oci"}}).NewTrivyClient().(*trivyClient)
+				trivy.commandRunner = mockRunner
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "--offline-scan", "--ignore-unfixed", "alpine:3.11.0"}).
+					Return([]byte(`{"Results":[]}`), []byte{}, nil)
+
+				_, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("reads the operating system detected in the image", func() {
+				output := []byte(`{"Metadata":{"OS":{"Family":"debian","Name":"9.13","EOSL":true}},"Results":[]}`)
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "debian:9"}).
+					Return(output, []byte{}, nil)
+
+				scanOutput, err := trivy.ScanImage(context.Background(), "debian:9")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(scanOutput.Metadata.OS).To(Equal(&OS{Family: "debian", Name: "9.13", EOSL: true}))
+			})
+
+			It("reads the CVSS scores and vectors of the vulnerabilities", func() {
+				output := []byte(`{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2021-3326","Severity":"HIGH","CVSS":{"nvd":{"V2Vector":"AV:N/AC:L/Au:N/C:N/I:N/A:P","V3Vector":"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H","V2Score":5,"V3Score":7.5}}}]}]}`)
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "debian:10"}).
+					Return(output, []byte{}, nil)
+
+				scanOutput, err := trivy.ScanImage(context.Background(), "debian:10")
+				Expect(err).NotTo(HaveOccurred())
+				vulnerability := scanOutput.Results[0].Vulnerabilities[0]
+				Expect(vulnerability.CVSSScore()).To(Equal(7.5))
+				Expect(vulnerability.CVSSVector()).To(Equal("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H"))
+			})
+
+			It("returns a timeout error with the partial output when the scan times out", func() {
+				output, jsonerr := json.Marshal(TrivyOutput{Results: []TrivyOutputResults{{Target: "alpine:3.11.0 (alpine 3.11.0)"}}})
+				Expect(jsonerr).NotTo(HaveOccurred())
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "alpine:3.11.0"}).
+					Return(output, []byte("FATAL image scan error: scan error: scan failed: failed analysis: analyze error: timeout: context deadline exceeded"), fmt.Errorf("exit status 1"))
+
+				scanOutput, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+
+				Expect(err).To(Equal(&ScanTimeoutError{Image: "alpine:3.11.0", Timeout: 7 * time.Minute}))
+				Expect(scanOutput.Results[0].Target).To(Equal("alpine:3.11.0 (alpine 3.11.0)"))
+			})
+
+			It("returns a timeout error without output when trivy wrote no output", func() {
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "alpine:3.11.0"}).
+					Return([]byte{}, []byte("FATAL analyze error: timeout: context deadline exceeded"), fmt.Errorf("exit status 1"))
+
+				scanOutput, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
+
+				Expect(err).To(MatchError("trivy scan of image alpine:3.11.0 timed out after 7m0s"))
+				Expect(scanOutput).To(BeNil())
 			})
 
 			It("return the error when unable to parse the scan output", func() {
-				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "alpine:3.11.0"}).
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "json", "--skip-update", "--no-progress", "--severity", severity, "--timeout", "7m0s", "--scanners", "vuln", "alpine:3.11.0"}).
 					Return([]byte("not json"), []byte{}, nil)
-				_, err := trivy.ScanImage("alpine:3.11.0")
+				_, err := trivy.ScanImage(context.Background(), "alpine:3.11.0")
 				Expect(err).Should(MatchError(ContainSubstring("error while decoding trivy output for image alpine:3.11.0")))
 			})
 		})
 
+		Describe("SBOM", func() {
+
+			It("invokes trivy CLI to generate the CycloneDX SBOM of the image", func() {
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "cyclonedx", "--skip-update", "--no-progress", "--timeout", "7m0s", "alpine:3.11.0"}).
+					Return([]byte(`{"bomFormat":"CycloneDX"}`), []byte{}, nil)
+
+				sbom, err := trivy.SBOM(context.Background(), "alpine:3.11.0", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(sbom)).To(Equal(`{"bomFormat":"CycloneDX"}`))
+			})
+
+			It("records the vulnerabilities of the components when requested", func() {
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "cyclonedx", "--skip-update", "--no-progress", "--timeout", "7m0s", "--scanners", "vuln", "--severity", severity, "alpine:3.11.0"}).
+					Return([]byte(`{"bomFormat":"CycloneDX","vulnerabilities":[]}`), []byte{}, nil)
+
+				_, err := trivy.SBOM(context.Background(), "alpine:3.11.0", true)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("passes the extra arguments of the SBOM generations through to trivy", func() {
+				trivy.extraArgs = trivyExtraArgs{all: []string{"--offline-scan"}, image: []string{"--ignore-unfixed"}, sbom: []string{"--sbom-sources", "oci"}}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "cyclonedx", "--skip-update", "--no-progress", "--timeout", "7m0s", "--offline-scan", "--sbom-sources", "oci", "alpine:3.11.0"}).
+					Return([]byte(`{"bomFormat":"CycloneDX"}`), []byte{}, nil)
+
+				_, err := trivy.SBOM(context.Background(), "alpine:3.11.0", false)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("skips the TLS verification of the insecure registries", func() {
+				trivy.insecureRegistries = []string{"registry.internal:5000"}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "cyclonedx", "--skip-update", "--no-progress", "--timeout", "7m0s", "--insecure", "registry.internal:5000/api:1.0"}).
+					Return([]byte(`{"bomFormat":"CycloneDX"}`), []byte{}, nil)
+
+				_, err := trivy.SBOM(context.Background(), "registry.internal:5000/api:1.0", false)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("reads the image from the image source when set", func() {
+				trivy.imageSource = "podman"
+				trivy.extraArgs = trivyExtraArgs{all: []string{"--offline-scan"}}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "-f", "cyclonedx", "--skip-update", "--no-progress", "--timeout", "7m0s", "--image-src", "podman", "--offline-scan", "alpine:3.11.0"}).
+					Return([]byte(`{"bomFormat":"CycloneDX"}`), []byte{}, nil)
+
+				_, err := trivy.SBOM(context.Background(), "alpine:3.11.0", false)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
 		Describe("CisScan", func() {
 
 			It("invokes trivy CLI to scan the Kubernetes cluster", func() {
 				output, jsonerr := json.Marshal(CisOutput{})
 				Expect(jsonerr).NotTo(HaveOccurred())
-				mockRunner.On("Execute", "trivy", []string{"--cache-dir", ".trivycache/", "--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL"}).
+				mockRunner.On("Execute", "trivy", []string{"--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL", "--skip-java-db-update"}).
 					Return(output, []byte{}, nil)
 
 				scanOutput, err := trivy.CisScan("mybenchmark")
@@ -100,11 +259,104 @@ var _ = Describe("Trivy client", func() {
 			})
 
 			It("return the error when unable to parse the trivy output", func() {
-				mockRunner.On("Execute", "trivy", []string{"--cache-dir", ".trivycache/", "--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL"}).
+				mockRunner.On("Execute", "trivy", []string{"--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL", "--skip-java-db-update"}).
 					Return([]byte("not json"), []byte{}, nil)
 				_, err := trivy.CisScan("mybenchmark")
 				Expect(err).Should(MatchError(ContainSubstring("error while decoding CisOutput scan output")))
 			})
+
+			It("evaluates the cluster against the custom Rego policies", func() {
+				trivy.policies = RegoPolicies{Dirs: []string{"policies/kubernetes", "policies/shared"}, Namespaces: []string{"user", "acme"}}
+				mockRunner.On("Execute", "trivy", []string{"--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL", "--skip-java-db-update", "--config-policy", "policies/kubernetes,policies/shared", "--policy-namespaces", "user,acme"}).
+					Return([]byte(`{}`), []byte{}, nil)
+
+				_, err := trivy.CisScan("mybenchmark")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("passes the extra arguments of the compliance scans through to trivy", func() {
+				trivy.extraArgs = trivyExtraArgs{all: []string{"--offline-scan"}, cis: []string{"--skip-images"}}
+				mockRunner.On("Execute", "trivy", []string{"--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL", "--skip-java-db-update", "--offline-scan", "--skip-images"}).
+					Return([]byte(`{}`), []byte{}, nil)
+
+				_, err := trivy.CisScan("mybenchmark")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("scans with the database of the cache directory without updating it", func() {
+				trivy.db = TrivyDBConfig{SkipUpdate: true, CacheDir: "/var/lib/trivy"}
+				mockRunner.On("Execute", "trivy", []string{"--cache-dir", "/var/lib/trivy", "--timeout", "7m0s", "--format", "json", "kubernetes", "--exit-code", "0", "--no-progress", "--compliance", "mybenchmark", "--slow", "cluster", "--severity", "CRITICAL", "--skip-java-db-update", "--skip-db-update", "--skip-policy-update"}).
+					Return([]byte(`{}`), []byte{}, nil)
+
+				_, err := trivy.CisScan("mybenchmark")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Describe("DownloadDatabase", func() {
+
+			It("downloads the database from the configured repository to the cache directory", func() {
+				trivy.db = TrivyDBConfig{Repository: "mirror.example.com/aquasecurity/trivy-db", CacheDir: "/var/lib/trivy"}
+				trivy.extraArgs = trivyExtraArgs{all: []string{"--insecure"}}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "--download-db-only", "--cache-dir", "/var/lib/trivy", "--db-repository", "mirror.example.com/aquasecurity/trivy-db", "--insecure"}).
+					Return([]byte{}, []byte{}, nil)
+
+				Expect(trivy.DownloadDatabase(context.Background(), "image")).To(Succeed())
+			})
+
+			It("prefetches the java db after the vulnerability db", func() {
+				trivy.db = TrivyDBConfig{JavaDB: JavaDBPrefetch, JavaDBRepository: "mirror.example.com/aquasecurity/trivy-java-db"}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "--download-db-only"}).Return([]byte{}, []byte{}, nil).Once()
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "--download-java-db-only", "--java-db-repository", "mirror.example.com/aquasecurity/trivy-java-db"}).
+					Return([]byte{}, []byte("failed to download from ghcr.io"), fmt.Errorf("exit status 1")).Once()
+
+				err := trivy.DownloadDatabase(context.Background(), "image")
+				Expect(err).To(MatchError("error while downloading trivy java db. Error output: failed to download from ghcr.io, Error: exit status 1"))
+				mockRunner.AssertExpectations(GinkgoT())
+			})
+
+			It("does not download the skipped java db", func() {
+				trivy.db = TrivyDBConfig{JavaDB: JavaDBSkip}
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "--download-db-only"}).Return([]byte{}, []byte{}, nil)
+
+				Expect(trivy.DownloadDatabase(context.Background(), "image")).To(Succeed())
+				mockRunner.AssertNumberOfCalls(GinkgoT(), "Execute", 1)
+			})
+
+			It("returns the error output of trivy when the download fails", func() {
+				mockRunner.On("Execute", "trivy", []string{"-q", "image", "--download-db-only"}).
+					Return([]byte{}, []byte("failed to download from ghcr.io"), fmt.Errorf("exit status 1"))
+
+				err := trivy.DownloadDatabase(context.Background(), "image")
+				Expect(err).To(MatchError("error while downloading trivy db. Error output: failed to download from ghcr.io, Error: exit status 1"))
+			})
+
+			It("checks the database is present in the cache directory when the update is skipped", func() {
+				cacheDir := GinkgoT().TempDir()
+				trivy.db = TrivyDBConfig{SkipUpdate: true, CacheDir: cacheDir}
+
+				err := trivy.DownloadDatabase(context.Background(), "image")
+				Expect(err).To(MatchError(ContainSubstring("the trivy db update is skipped but no trivy db is found")))
+
+				Expect(os.MkdirAll(filepath.Join(cacheDir, "db"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cacheDir, "db", "trivy.db"), []byte("db"), 0644)).To(Succeed())
+				Expect(trivy.DownloadDatabase(context.Background(), "image")).To(Succeed())
+				mockRunner.AssertNotCalled(GinkgoT(), "Execute", mock.Anything, mock.Anything)
+			})
+		})
+
+		Describe("Version", func() {
+
+			It("invokes trivy CLI to get the trivy and database versions", func() {
+				mockRunner.On("Execute", "trivy", []string{"version", "-f", "json"}).
+					Return([]byte(`{"Version":"0.45.0","VulnerabilityDB":{"Version":2,"UpdatedAt":"2023-09-01T06:13:21Z"}}`), []byte{}, nil)
+
+				version, err := trivy.Version()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version.Version).To(Equal("0.45.0"))
+				Expect(version.VulnerabilityDB.Version).To(Equal(2))
+				Expect(version.VulnerabilityDB.UpdatedAt).To(Equal(time.Date(2023, 9, 1, 6, 13, 21, 0, time.UTC)))
+			})
 		})
 	})
 })
@@ -117,3 +369,7 @@ func (r *mockCommanderRunner) Execute(cmd string, arg []string) (output []byte,
 	args := r.Called(cmd, arg)
 	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
 }
+
+func (r *mockCommanderRunner) ExecuteContext(_ context.Context, cmd string, arg []string) (output []byte, erroutput []byte, err error) {
+	return r.Execute(cmd, arg)
+}
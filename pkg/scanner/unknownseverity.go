@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policies of the vulnerabilities of UNKNOWN severity, the unscored vulnerabilities, see Config.UnknownSeverity
+const (
+	// UnknownSeverityKeep reports the vulnerabilities as UNKNOWN
+	UnknownSeverityKeep = "keep"
+	// UnknownSeverityLow and UnknownSeverityMedium count the vulnerabilities as LOW and as MEDIUM respectively
+	UnknownSeverityLow    = "low"
+	UnknownSeverityMedium = "medium"
+	// UnknownSeverityExclude removes the vulnerabilities from the results, except the known exploited vulnerabilities
+	UnknownSeverityExclude = "exclude"
+)
+
+// UnknownSeverityPolicies are the policies of the vulnerabilities of UNKNOWN severity
+var UnknownSeverityPolicies = []string{UnknownSeverityKeep, UnknownSeverityLow, UnknownSeverityMedium, UnknownSeverityExclude}
+
+// ValidateUnknownSeverityPolicy returns an error when the policy is not one of UnknownSeverityPolicies
+func ValidateUnknownSeverityPolicy(policy string) error {
+	for _, valid := range UnknownSeverityPolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid unknown severity policy %q, permitted values: %s", policy, strings.Join(UnknownSeverityPolicies, ", "))
+}
+
+// unknownSeverityOf returns the severity the vulnerabilities of UNKNOWN severity are counted as with the policy, empty
+// when they are kept as UNKNOWN or excluded
+func unknownSeverityOf(policy string) string {
+	switch policy {
+	case UnknownSeverityLow, UnknownSeverityMedium:
+		return strings.ToUpper(policy)
+	}
+	return ""
+}
+
+// applyUnknownSeverityPolicy counts the vulnerabilities of UNKNOWN severity of the trivy results as LOW or MEDIUM,
+// recording their severity as overridden so that the report shows it, or removes them, according to the policy.
+// The vulnerabilities are kept as is when the policy is empty or UnknownSeverityKeep. The known exploited
+// vulnerabilities, already marked from the KEV catalog, are never removed as they fail --fail-on-known-exploited
+// whatever their score
+func applyUnknownSeverityPolicy(trivyOutput []TrivyOutputResults, policy string) {
+	if policy == "" || policy == UnknownSeverityKeep {
+		return
+	}
+	severity := unknownSeverityOf(policy)
+	for i := range trivyOutput {
+		var vulnerabilities []Vulnerabilities
+		for _, vulnerability := range trivyOutput[i].Vulnerabilities {
+			if vulnerability.Severity == "UNKNOWN" {
+				if policy != UnknownSeverityExclude {
+					vulnerability.OverriddenSeverity = &OverriddenSeverity{Severity: "UNKNOWN", Justification: "unscored vulnerability counted as " + severity + " by the unknown severity policy"}
+					vulnerability.Severity = severity
+				} else if vulnerability.KnownExploited == nil {
+					continue
+				}
+			}
+			vulnerabilities = append(vulnerabilities, vulnerability)
+		}
+		trivyOutput[i].Vulnerabilities = vulnerabilities
+	}
+}
+
+// trivySeverity returns the severities trivy reports, UNKNOWN included when the policy counts the vulnerabilities of
+// UNKNOWN severity as one of the severities so that they are not left out by trivy
+func trivySeverity(severities, policy string) string {
+	severity := unknownSeverityOf(policy)
+	if severities == "" || severity == "" {
+		return severities
+	}
+	reported := false
+	for _, value := range strings.Split(severities, ",") {
+		switch strings.TrimSpace(value) {
+		case "UNKNOWN":
+			return severities
+		case severity:
+			reported = true
+		}
+	}
+	if !reported {
+		return severities
+	}
+	return severities + ",UNKNOWN"
+}
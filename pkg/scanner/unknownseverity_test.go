@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unknown severity policy", func() {
+
+	results := func() []TrivyOutputResults {
+		return []TrivyOutputResults{{Vulnerabilities: []Vulnerabilities{
+			{VulnerabilityID: "CVE-2023-0286", PkgName: "openssl", Severity: "HIGH"},
+			{VulnerabilityID: "CVE-2024-9999", PkgName: "zlib", Severity: "UNKNOWN"},
+		}}}
+	}
+
+	It("counts the vulnerabilities of unknown severity as the severity of the policy, recording their severity", func() {
+		medium := results()
+
+		applyUnknownSeverityPolicy(medium, UnknownSeverityMedium)
+		image := NewScannedImage("debian:10", nil, medium, nil)
+
+		Expect(medium[0].Vulnerabilities[1]).To(Equal(Vulnerabilities{VulnerabilityID: "CVE-2024-9999", PkgName: "zlib", Severity: "MEDIUM",
+			OverriddenSeverity: &OverriddenSeverity{Severity: "UNKNOWN", Justification: "unscored vulnerability counted as MEDIUM by the unknown severity policy"}}))
+		Expect(image.VulnerabilitySummary.TotalVulnerabilityBySeverity).To(Equal(map[string]int{"CRITICAL": 0, "HIGH": 1, "MEDIUM": 1, "LOW": 0, "UNKNOWN": 0}))
+		Expect(image.VulnerabilitySummary.SeverityScore).To(Equal(severityScores["HIGH"] + severityScores["MEDIUM"]))
+	})
+
+	It("excludes or keeps the vulnerabilities of unknown severity", func() {
+		excluded, kept := results(), results()
+
+		applyUnknownSeverityPolicy(excluded, UnknownSeverityExclude)
+		applyUnknownSeverityPolicy(kept, UnknownSeverityKeep)
+
+		Expect(excluded[0].Vulnerabilities).To(Equal(results()[0].Vulnerabilities[:1]))
+		Expect(kept).To(Equal(results()))
+	})
+
+	It("keeps the known exploited vulnerabilities of unknown severity when they are excluded", func() {
+		scanner := &Scanner{config: &Config{
+			UnknownSeverity: UnknownSeverityExclude,
+			KEVCatalog: &KEVCatalog{vulnerabilities: map[string]KnownExploitedVulnerability{
+				"CVE-2024-9999": {CveID: "CVE-2024-9999", VendorProject: "zlib"},
+			}},
+		}}
+		trivyOutput := &TrivyOutput{Results: results()}
+		trivyOutput.Results[0].Vulnerabilities = append(trivyOutput.Results[0].Vulnerabilities,
+			Vulnerabilities{VulnerabilityID: "CVE-2024-8888", PkgName: "libxml2", Severity: "UNKNOWN"})
+
+		scanner.enrich(trivyOutput)
+
+		Expect(trivyOutput.Results[0].Vulnerabilities).To(HaveLen(2))
+		// the known exploited vulnerability is raised to CRITICAL once normalised, sorting first
+		Expect(trivyOutput.Results[0].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2024-9999"))
+		Expect(trivyOutput.Results[0].Vulnerabilities[0].Severity).To(Equal("CRITICAL"))
+		Expect(trivyOutput.Results[0].Vulnerabilities[0].NormalizedSeverity.SourceSeverity).To(Equal("UNKNOWN"))
+		Expect(trivyOutput.Results[0].Vulnerabilities[0].KnownExploited).NotTo(BeNil())
+	})
+
+	It("asks trivy for the vulnerabilities of unknown severity when they are counted as a reported severity", func() {
+		Expect(trivySeverity("CRITICAL,HIGH,LOW", UnknownSeverityLow)).To(Equal("CRITICAL,HIGH,LOW,UNKNOWN"))
+		Expect(trivySeverity("CRITICAL,HIGH", UnknownSeverityLow)).To(Equal("CRITICAL,HIGH"))
+		Expect(trivySeverity("MEDIUM,UNKNOWN", UnknownSeverityMedium)).To(Equal("MEDIUM,UNKNOWN"))
+		Expect(trivySeverity("CRITICAL,LOW", UnknownSeverityExclude)).To(Equal("CRITICAL,LOW"))
+	})
+
+	It("rejects the unknown policies", func() {
+		Expect(ValidateUnknownSeverityPolicy(UnknownSeverityMedium)).To(Succeed())
+		Expect(ValidateUnknownSeverityPolicy("high")).To(MatchError(`invalid unknown severity policy "high", permitted values: keep, low, medium, exclude`))
+	})
+})
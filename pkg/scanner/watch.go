@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	logr "github.com/sirupsen/logrus"
+)
+
+// watchBatchDelay is how long the new images are collected before being scanned together, as the pods of a rollout
+// appear within a few seconds of each other
+var watchBatchDelay = 10 * time.Second
+
+// Watch keeps the report of a cluster scan fresh: the pods of the cluster are watched and the images of their containers
+// the report does not hold are scanned, the images already in the report not being scanned again. onReport is called
+// with the updated report after each scan of new images. When FullRescanInterval is set, the cluster is fully rescanned
+// at this interval to refresh the vulnerabilities of the images already scanned and drop the images no longer running.
+// When MaxResultAge is set, the images whose results get older than it are rescanned. With ScanWindows, the scans due
+// outside of the windows are postponed to the opening of the next window, the scans started in a window running to
+// completion. Watch returns once the context is cancelled
+func (s *Scanner) Watch(ctx context.Context, report *VulnerabilityReport, onReport func(*VulnerabilityReport)) error {
+	scannedImages := report.ScannedImages
+	metadata := report.Metadata
+	// the pods opting out of the scans while watched are only listed once the cluster is fully rescanned
+	optOuts := report.ScanOptOuts
+	scanned := scannedImageNames(scannedImages)
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+	appeared := make(chan []k8s.ContainerSummary, 100)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- s.kubernetesClient.WatchContainers(watchCtx, s.config.FilterLabels, func(containers []k8s.ContainerSummary) {
+			select {
+			case appeared <- containers:
+			case <-watchCtx.Done():
+			}
+		})
+	}()
+
+	var fullRescan, fullRescanTicks <-chan time.Time
+	if s.config.FullRescanInterval > 0 {
+		ticker := time.NewTicker(s.config.FullRescanInterval)
+		defer ticker.Stop()
+		fullRescan, fullRescanTicks = ticker.C, ticker.C
+	}
+	var staleCheck <-chan time.Time
+	if s.config.MaxResultAge > 0 {
+		ticker := time.NewTicker(staleCheckInterval)
+		defer ticker.Stop()
+		staleCheck = ticker.C
+	}
+	pending := make(map[string][]k8s.ContainerSummary)
+	var batch <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watchErr:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		case containers := <-appeared:
+			for _, container := range containers {
+				if container.SkipScanReason == "" && !scanned[s.resolveImageName(container.Image)] {
+					pending[container.Image] = append(pending[container.Image], container)
+				}
+			}
+			if len(pending) > 0 && batch == nil {
+				batch = time.After(watchBatchDelay)
+			}
+		case <-batch:
+			if next, open := s.scanWindowOpen(time.Now()); !open {
+				logr.Infof("Postponing the scan of %d new image(s) to the next scan window at %s", len(pending), next.Format(time.RFC3339))
+				batch = time.After(time.Until(next))
+				continue
+			}
+			batch = nil
+			newImages := pending
+			pending = make(map[string][]k8s.ContainerSummary)
+			logr.Infof("Scanning %d new image(s) of the watched pods", len(newImages))
+			newScannedImages, err := s.scanImages(ctx, newImages)
+			if err != nil {
+				// the images are scanned again when their pods are next updated
+				logr.Warnf("Unable to scan the new images: %v", err)
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			for _, scannedImage := range newScannedImages {
+				scanned[scannedImage.ImageName] = true
+			}
+			scannedImages = append(append([]ScannedImage(nil), scannedImages...), newScannedImages...)
+			sort.SliceStable(scannedImages, func(i, j int) bool {
+				return scannedImages[i].ImageName < scannedImages[j].ImageName
+			})
+			metadata.ScanTime = time.Now().UTC()
+			updated, err := s.generateReport(scannedImages, s.config.AreaLabels, s.config.TeamsLabels, metadata)
+			if err != nil {
+				return err
+			}
+			updated.ScanOptOuts = optOuts
+			onReport(updated)
+		case <-staleCheck:
+			// the stale images are looked for again on the next check
+			if _, open := s.scanWindowOpen(time.Now()); !open {
+				continue
+			}
+			staleImages := staleImageList(scannedImages, s.config.MaxResultAge, time.Now())
+			if len(staleImages) == 0 {
+				continue
+			}
+			logr.Infof("Rescanning %d image(s) whose results are older than %s", len(staleImages), s.config.MaxResultAge)
+			rescannedImages, err := s.scanImages(ctx, staleImages)
+			if err != nil {
+				logr.Warnf("Unable to rescan the stale images: %v", err)
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			scannedImages = replaceScannedImages(scannedImages, rescannedImages)
+			metadata.ScanTime = time.Now().UTC()
+			updated, err := s.generateReport(scannedImages, s.config.AreaLabels, s.config.TeamsLabels, metadata)
+			if err != nil {
+				return err
+			}
+			updated.ScanOptOuts = optOuts
+			onReport(updated)
+		case <-fullRescan:
+			if next, open := s.scanWindowOpen(time.Now()); !open {
+				logr.Infof("Postponing the full rescan to the next scan window at %s", next.Format(time.RFC3339))
+				fullRescan = time.After(time.Until(next))
+				continue
+			}
+			fullRescan = fullRescanTicks
+			logr.Infof("Rescanning all the images of the cluster")
+			updated, err := s.ScanImages(ctx)
+			if err != nil {
+				logr.Warnf("Unable to rescan the cluster images: %v", err)
+				continue
+			}
+			if updated.Metadata.Incomplete {
+				return nil
+			}
+			scannedImages, metadata, optOuts = updated.ScannedImages, updated.Metadata, updated.ScanOptOuts
+			scanned = scannedImageNames(scannedImages)
+			for imageName := range pending {
+				if scanned[s.resolveImageName(imageName)] {
+					delete(pending, imageName)
+				}
+			}
+			onReport(updated)
+		}
+	}
+}
+
+func scannedImageNames(scannedImages []ScannedImage) map[string]bool {
+	names := make(map[string]bool, len(scannedImages))
+	for _, scannedImage := range scannedImages {
+		names[scannedImage.ImageName] = true
+	}
+	return names
+}
+
+// scanWindowOpen returns true when the watch scans may start at the time, see Config.ScanWindows, or else the opening
+// of the next scan window
+func (s *Scanner) scanWindowOpen(now time.Time) (time.Time, bool) {
+	if s.config.ScanWindows == nil || s.config.ScanWindows.Allows(now) {
+		return now, true
+	}
+	return s.config.ScanWindows.Next(now), false
+}
@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron schedule, the times matching all of its fields
+type Schedule struct {
+	expression  string
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// the days match the days of month or the days of week when both are restricted, as cron does
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+// macros are the cron shorthands of the common schedules
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearchYears bounds the search of the next time of the schedules that can never match, such as 0 0 30 2 *
+const maxSearchYears = 5
+
+// Parse parses a standard 5-field cron expression, minute hour day-of-month month day-of-week, for instance
+// "0 2 * * *" for every day at 02:00. The fields accept *, values, ranges, lists and steps such as */15 or 1-5,
+// the days of week going from 0 (Sunday) to 7 (Sunday too). The @hourly, @daily, @weekly, @monthly and @yearly
+// shorthands are supported as well
+func Parse(expression string) (*Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) == 1 {
+		if macro, ok := macros[fields[0]]; ok {
+			fields = strings.Fields(macro)
+		}
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q, 5 fields are expected: minute hour day-of-month month day-of-week", expression)
+	}
+	schedule := &Schedule{expression: expression}
+	var err error
+	for _, field := range []struct {
+		value    string
+		min, max int
+		bits     *uint64
+	}{
+		{fields[0], 0, 59, &schedule.minutes},
+		{fields[1], 0, 23, &schedule.hours},
+		{fields[2], 1, 31, &schedule.daysOfMonth},
+		{fields[3], 1, 12, &schedule.months},
+		{fields[4], 0, 7, &schedule.daysOfWeek},
+	} {
+		if *field.bits, err = parseField(field.value, field.min, field.max); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expression, err)
+		}
+	}
+	// 7 is Sunday as 0
+	if schedule.daysOfWeek&(1<<7) != 0 {
+		schedule.daysOfWeek |= 1
+	}
+	schedule.daysOfMonthRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.daysOfWeekRestricted = !strings.HasPrefix(fields[4], "*")
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid cron expression %q: it never matches", expression)
+	}
+	return schedule, nil
+}
+
+// parseField returns the bits of the values of a comma-separated list of *, values, ranges and steps
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+		start, end := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+			} else if hasStep {
+				// a/n goes from a to the maximum value
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time of the schedule strictly after the given time, in the location of the given time.
+// It is zero when the schedule never matches
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.daysOfMonthRestricted && s.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// String returns the cron expression of the schedule
+func (s *Schedule) String() string {
+	return s.expression
+}
@@ -0,0 +1,51 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RotateFiles keeps the previous versions of the files before they are written again, as logrotate does: the file
+// is renamed with the .1 suffix before its extension, for instance report.1.html, the .1 version to .2 and so on,
+// the versions beyond keep being removed. The missing files are ignored, and no version is kept when keep is 0
+func RotateFiles(keep int, filenames ...string) error {
+	if keep <= 0 {
+		return nil
+	}
+	for _, filename := range filenames {
+		if filename == "" {
+			continue
+		}
+		if err := removeIfExists(rotatedFilename(filename, keep)); err != nil {
+			return err
+		}
+		for version := keep - 1; version >= 0; version-- {
+			from := rotatedFilename(filename, version)
+			if _, err := os.Stat(from); os.IsNotExist(err) {
+				continue
+			}
+			if err := os.Rename(from, rotatedFilename(filename, version+1)); err != nil {
+				return fmt.Errorf("could not rotate %s: %v", from, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rotatedFilename returns the name of a previous version of the file, the file itself for version 0
+func rotatedFilename(filename string, version int) string {
+	if version == 0 {
+		return filename
+	}
+	extension := filepath.Ext(filename)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(filename, extension), version, extension)
+}
+
+func removeIfExists(filename string) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove %s: %v", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSchedule(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Schedule Suite")
+}
+
+var _ = Describe("Cron schedule", func() {
+	at := func(value string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", value)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+	next := func(expression, after string) string {
+		schedule, err := Parse(expression)
+		Expect(err).NotTo(HaveOccurred())
+		return schedule.Next(at(after)).Format("2006-01-02 15:04 Mon")
+	}
+
+	It("returns the next time matching the schedule", func() {
+		Expect(next("0 2 * * *", "2023-10-16 01:30")).To(Equal("2023-10-16 02:00 Mon"))
+		Expect(next("0 2 * * *", "2023-10-16 02:00")).To(Equal("2023-10-17 02:00 Tue"))
+		Expect(next("*/15 * * * *", "2023-10-16 10:07")).To(Equal("2023-10-16 10:15 Mon"))
+		Expect(next("30 9-17/4 * * 1-5", "2023-10-13 18:00")).To(Equal("2023-10-16 09:30 Mon"))
+		Expect(next("0 0 1,15 * *", "2023-10-16 00:00")).To(Equal("2023-11-01 00:00 Wed"))
+		Expect(next("0 0 29 2 *", "2023-03-01 00:00")).To(Equal("2024-02-29 00:00 Thu"))
+		Expect(next("0 6 * * 7", "2023-10-16 00:00")).To(Equal("2023-10-22 06:00 Sun"))
+		Expect(next("@weekly", "2023-10-16 00:00")).To(Equal("2023-10-22 00:00 Sun"))
+	})
+
+	It("matches the days of month or the days of week when both are restricted", func() {
+		Expect(next("0 0 13 * 5", "2023-10-01 00:00")).To(Equal("2023-10-06 00:00 Fri"))
+		Expect(next("0 0 13 * 5", "2023-10-11 00:00")).To(Equal("2023-10-13 00:00 Fri"))
+	})
+
+	It("rejects the invalid expressions", func() {
+		for _, expression := range []string{"0 2 * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "0 0 30 2 *"} {
+			_, err := Parse(expression)
+			Expect(err).To(HaveOccurred(), expression)
+		}
+	})
+})
+
+var _ = Describe("Scheduler", func() {
+	It("runs the job on the schedule and records the status of the last run", func() {
+		schedule, err := Parse("0 2 * * *")
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var calls int
+		scheduler := NewScheduler(schedule, func(context.Context) error {
+			calls++
+			if calls == 2 {
+				cancel()
+				return fmt.Errorf("scan failed")
+			}
+			return nil
+		})
+		elapsed := make(chan time.Time)
+		close(elapsed)
+		scheduler.after = func(time.Duration) <-chan time.Time { return elapsed }
+
+		scheduler.Run(ctx)
+
+		Expect(calls).To(Equal(2))
+		status := scheduler.Status()
+		Expect(status.Schedule).To(Equal("0 2 * * *"))
+		Expect(status.Runs).To(Equal(2))
+		Expect(status.Failures).To(Equal(1))
+		Expect(status.LastRunSuccess).To(BeFalse())
+		Expect(status.LastError).To(Equal("scan failed"))
+		Expect(status.LastRunEnd).NotTo(BeNil())
+		Expect(status.NextRun.Hour()).To(Equal(2))
+
+		recorder := httptest.NewRecorder()
+		scheduler.StatusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var served Status
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &served)).To(Succeed())
+		Expect(served.LastError).To(Equal("scan failed"))
+	})
+
+	It("runs the job when triggered, once at a time", func() {
+		schedule, err := Parse("0 2 * * *")
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		started, release := make(chan struct{}), make(chan struct{})
+		scheduler := NewScheduler(schedule, func(context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+		scheduler.after = func(time.Duration) <-chan time.Time { return nil }
+		done := make(chan struct{})
+		go func() {
+			scheduler.Run(ctx)
+			close(done)
+		}()
+		trigger := func(token string) int {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			scheduler.TriggerHandler("secret").ServeHTTP(recorder, req)
+			return recorder.Code
+		}
+
+		Expect(trigger("wrong")).To(Equal(http.StatusUnauthorized))
+		Expect(trigger("")).To(Equal(http.StatusUnauthorized))
+		Consistently(scheduler.Status, 50*time.Millisecond).Should(HaveField("Running", BeFalse()))
+		Expect(trigger("secret")).To(Equal(http.StatusAccepted))
+		Eventually(started).Should(Receive())
+		Eventually(scheduler.Status).Should(HaveField("Running", BeTrue()))
+		Expect(trigger("secret")).To(Equal(http.StatusConflict))
+		close(release)
+		Eventually(scheduler.Status).Should(HaveField("Runs", 1))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("Scan windows", func() {
+	// 2023-10-16 is a Monday
+	at := func(value string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", value)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+	parse := func(allowed, blackouts string) *Windows {
+		windows, err := ParseWindows(allowed, blackouts, time.UTC)
+		Expect(err).NotTo(HaveOccurred())
+		return windows
+	}
+
+	It("allows the scans in the windows only", func() {
+		windows := parse("01:00-05:00, Sat,Sun 22:00-06:00", "")
+
+		Expect(windows.Allows(at("2023-10-16 01:00"))).To(BeTrue())
+		Expect(windows.Allows(at("2023-10-17 05:00"))).To(BeFalse())
+		Expect(windows.Allows(at("2023-10-16 23:00"))).To(BeFalse())
+		Expect(windows.Allows(at("2023-10-21 23:00"))).To(BeTrue())
+		Expect(windows.Allows(at("2023-10-23 05:30"))).To(BeTrue())
+		Expect(windows.Allows(at("2023-10-24 05:30"))).To(BeFalse())
+		Expect(windows.Next(at("2023-10-16 12:00"))).To(Equal(at("2023-10-17 01:00")))
+		Expect(windows.Next(at("2023-10-16 02:00"))).To(Equal(at("2023-10-16 02:00")))
+		Expect(windows.Close(at("2023-10-21 23:00"))).To(Equal(at("2023-10-22 06:00")))
+		Expect(windows.Close(at("2023-10-17 02:00"))).To(Equal(at("2023-10-17 05:00")))
+	})
+
+	It("never allows the scans in the blackouts", func() {
+		windows := parse("", "Mon-Fri 08:00-20:00")
+
+		Expect(windows.Allows(at("2023-10-16 12:00"))).To(BeFalse())
+		Expect(windows.Allows(at("2023-10-21 12:00"))).To(BeTrue())
+		Expect(windows.Next(at("2023-10-20 12:00"))).To(Equal(at("2023-10-20 20:00")))
+		Expect(windows.Close(at("2023-10-21 12:00"))).To(Equal(at("2023-10-23 08:00")))
+		Expect(parse("", "").Close(at("2023-10-21 12:00")).IsZero()).To(BeTrue())
+		Expect(windows.String()).To(Equal("except Mon-Fri 08:00-20:00"))
+	})
+
+	It("rejects the invalid windows", func() {
+		for _, windows := range [][2]string{{"1-5", ""}, {"01:00-25:00", ""}, {"Mon", ""}, {"Someday 01:00-02:00", ""}, {"01:00-01:00", ""}, {"01:00-05:00", "00:00-24:00"}} {
+			_, err := ParseWindows(windows[0], windows[1], time.UTC)
+			Expect(err).To(HaveOccurred(), windows[0])
+		}
+	})
+
+	It("postpones the scheduled runs to the next window and cancels them when it closes", func() {
+		schedule, err := Parse("* * * * *")
+		Expect(err).NotTo(HaveOccurred())
+		now := time.Now().UTC()
+		windowOpening := now.Add(2 * time.Hour).Truncate(time.Minute)
+		windows, err := ParseWindows("", now.Add(-time.Hour).Format("15:04")+"-"+windowOpening.Format("15:04"), time.UTC)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var deadline time.Time
+		scheduler := NewSchedulerWithWindows(schedule, windows, func(ctx context.Context) error {
+			deadline, _ = ctx.Deadline()
+			cancel()
+			return nil
+		})
+		elapsed := make(chan time.Time)
+		close(elapsed)
+		scheduler.after = func(time.Duration) <-chan time.Time { return elapsed }
+
+		scheduler.Run(ctx)
+
+		Expect(scheduler.Status().NextRun).To(BeTemporally("==", windowOpening))
+		Expect(deadline).NotTo(BeZero())
+	})
+})
+
+var _ = Describe("Rotating files", func() {
+	It("keeps the previous versions of the files", func() {
+		dir := GinkgoT().TempDir()
+		report := filepath.Join(dir, "report.html")
+		write := func(filename, content string) {
+			Expect(os.WriteFile(filename, []byte(content), 0644)).To(Succeed())
+		}
+		read := func(filename string) string {
+			content, err := os.ReadFile(filename)
+			Expect(err).NotTo(HaveOccurred())
+			return string(content)
+		}
+
+		for _, run := range []string{"first", "second", "third"} {
+			Expect(RotateFiles(2, report, filepath.Join(dir, "missing.json"), "")).To(Succeed())
+			write(report, run)
+		}
+
+		Expect(read(report)).To(Equal("third"))
+		Expect(read(filepath.Join(dir, "report.1.html"))).To(Equal("second"))
+		Expect(read(filepath.Join(dir, "report.2.html"))).To(Equal("first"))
+		Expect(filepath.Join(dir, "report.3.html")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(dir, "missing.1.json")).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,243 @@
+package schedule
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logr "github.com/sirupsen/logrus"
+)
+
+var (
+	lastRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scheduled_scan_last_run_timestamp_seconds",
+		Help: "Time the last scheduled scan finished at, in seconds since the epoch",
+	})
+	lastRunDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scheduled_scan_last_run_duration_seconds",
+		Help: "Duration of the last scheduled scan in seconds",
+	})
+	lastRunSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scheduled_scan_last_run_success",
+		Help: "Whether the last scheduled scan succeeded (1) or failed (0)",
+	})
+	nextRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "production_readiness_scheduled_scan_next_run_timestamp_seconds",
+		Help: "Time the next scheduled scan starts at, in seconds since the epoch",
+	})
+	runs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "production_readiness_scheduled_scan_runs_total",
+		Help: "Number of scheduled scans per result, success or failure",
+	}, []string{"result"})
+)
+
+var (
+	// ErrRunning is the error of the runs triggered while a run is in progress
+	ErrRunning = errors.New("a run is already in progress")
+	// ErrOutsideWindow is the error of the runs triggered outside of the windows
+	ErrOutsideWindow = errors.New("outside of the scan windows")
+)
+
+func init() {
+	prometheus.MustRegister(lastRunTimestamp, lastRunDuration, lastRunSuccess, nextRunTimestamp, runs)
+}
+
+// Status is the status of the scheduled runs, exposed by the status endpoint
+type Status struct {
+	Schedule string
+	// Running is true while a run is in progress
+	Running bool
+	// LastRunStart and LastRunEnd are the start and end times of the last finished run, nil before the first run
+	LastRunStart *time.Time `json:",omitempty"`
+	LastRunEnd   *time.Time `json:",omitempty"`
+	// LastRunSuccess is true when the last run succeeded, LastError holding the error of a failed run
+	LastRunSuccess bool
+	LastError      string `json:",omitempty"`
+	NextRun        time.Time
+	Runs           int
+	Failures       int
+}
+
+// Scheduler runs a job on a cron schedule, a run never overlapping the previous one: the times of the schedule
+// passed while a run is in progress are skipped
+type Scheduler struct {
+	schedule *Schedule
+	job      func(ctx context.Context) error
+	lock     sync.Mutex
+	status   Status
+	// after waits for the duration, see time.After
+	after func(d time.Duration) <-chan time.Time
+	// windows are the periods the job may run in, nil when it may run at any time
+	windows *Windows
+	// trigger holds the run triggered out of the schedule, see Trigger
+	trigger chan struct{}
+}
+
+// NewScheduler creates a Scheduler running the job on the schedule
+func NewScheduler(schedule *Schedule, job func(ctx context.Context) error) *Scheduler {
+	return &Scheduler{
+		schedule: schedule,
+		job:      job,
+		status:   Status{Schedule: schedule.String()},
+		after:    time.After,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// NewSchedulerWithWindows creates a Scheduler running the job on the schedule within the windows: the runs scheduled
+// outside of the windows are postponed to the opening of the next window, and the runs still in progress when their
+// window closes are cancelled
+func NewSchedulerWithWindows(schedule *Schedule, windows *Windows, job func(ctx context.Context) error) *Scheduler {
+	scheduler := NewScheduler(schedule, job)
+	scheduler.windows = windows
+	return scheduler
+}
+
+// Run runs the job at each time of the schedule, and whenever triggered, until the context is done. A run in progress is
+// cancelled with the context
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := s.schedule.Next(time.Now())
+		if s.windows != nil && !s.windows.Allows(next) {
+			logr.Infof("Postponing the run scheduled at %s to the next scan window", next.Format(time.RFC3339))
+			next = s.windows.Next(next)
+		}
+		s.update(func(status *Status) { status.NextRun = next })
+		nextRunTimestamp.Set(float64(next.Unix()))
+		logr.Infof("Next scheduled run at %s", next.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.after(time.Until(next)):
+		case <-s.trigger:
+			logr.Info("Running the triggered run")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		s.run(ctx)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	start := time.Now()
+	if s.windows != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = s.windows.Context(ctx, start)
+		defer cancel()
+	}
+	s.update(func(status *Status) { status.Running = true })
+	err := s.job(ctx)
+	end := time.Now()
+	s.update(func(status *Status) {
+		status.Running = false
+		status.LastRunStart, status.LastRunEnd = &start, &end
+		status.LastRunSuccess = err == nil
+		status.LastError = ""
+		status.Runs++
+		if err != nil {
+			status.LastError = err.Error()
+			status.Failures++
+		}
+	})
+
+	lastRunTimestamp.Set(float64(end.Unix()))
+	lastRunDuration.Set(end.Sub(start).Seconds())
+	if err != nil {
+		logr.Errorf("Scheduled run failed: %v", err)
+		lastRunSuccess.Set(0)
+		runs.WithLabelValues("failure").Inc()
+		return
+	}
+	logr.Infof("Scheduled run completed in %v", end.Sub(start).Round(time.Second))
+	lastRunSuccess.Set(1)
+	runs.WithLabelValues("success").Inc()
+}
+
+// Trigger starts a run out of the schedule, failing with ErrRunning while a run is in progress or already triggered
+// and with ErrOutsideWindow outside of the windows
+func (s *Scheduler) Trigger() error {
+	if s.windows != nil && !s.windows.Allows(time.Now()) {
+		return ErrOutsideWindow
+	}
+	if s.Status().Running {
+		return ErrRunning
+	}
+	select {
+	case s.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrRunning
+	}
+}
+
+func (s *Scheduler) update(change func(status *Status)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	change(&s.status)
+}
+
+// Status returns the status of the scheduled runs
+func (s *Scheduler) Status() Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.status
+}
+
+// StatusHandler serves the status of the scheduled runs as json
+func (s *Scheduler) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.Status()); err != nil {
+			logr.Warnf("Unable to write the scheduler status: %v", err)
+		}
+	})
+}
+
+// TriggerHandler starts a run on the POST requests holding the token as bearer token, answering 202 with the status of
+// the scheduled runs once the run is triggered, 401 without the token, 409 while a run is in progress and 503 outside
+// of the windows. No request is authorised with an empty token
+func (s *Scheduler) TriggerHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorised(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		status, value := http.StatusAccepted, interface{}(s.Status())
+		switch err := s.Trigger(); err {
+		case nil:
+		case ErrRunning:
+			status, value = http.StatusConflict, struct{ Error string }{err.Error()}
+		default:
+			status, value = http.StatusServiceUnavailable, struct{ Error string }{err.Error()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(value); err != nil {
+			logr.Warnf("Unable to write the scheduler status: %v", err)
+		}
+	})
+}
+
+// authorised returns true when the request holds the token as bearer token, compared in constant time
+func authorised(r *http.Request, token string) bool {
+	value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
@@ -0,0 +1,226 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// maxWindowSearch bounds the search of the opening and the closing of the windows, the windows repeating every week
+const maxWindowSearch = 8 * 24 * time.Hour
+
+// dayNames are the day names of the windows, see ParseWindows
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// allDays are the days of the windows without days
+const allDays = 1<<7 - 1
+
+// Windows are the daily periods the scans are allowed in, for instance 01:00-05:00 when the cluster traffic is the lowest,
+// and the blackout periods the scans are never run in, the times being those of the location of the windows
+type Windows struct {
+	allowed   []window
+	blackouts []window
+	location  *time.Location
+	value     string
+}
+
+// window is a period of the days of the week, from the start minute of the day to the end minute. The window ends the
+// next day when the end is before the start, for instance 22:00-02:00
+type window struct {
+	days       uint8
+	start, end int
+}
+
+// ParseWindows parses the comma-separated allowed windows and blackouts such as '01:00-05:00' or 'Mon-Fri 22:00-02:00',
+// the days being a range or a list of day names such as 'Sat,Sun'. The scans are allowed at any time outside of the
+// blackouts when no allowed window is given
+func ParseWindows(allowed, blackouts string, location *time.Location) (*Windows, error) {
+	windows := &Windows{location: location, value: allowed}
+	var err error
+	if windows.allowed, err = parseWindowList(allowed); err != nil {
+		return nil, fmt.Errorf("invalid scan windows %q: %v", allowed, err)
+	}
+	if windows.blackouts, err = parseWindowList(blackouts); err != nil {
+		return nil, fmt.Errorf("invalid scan blackouts %q: %v", blackouts, err)
+	}
+	if blackouts != "" {
+		windows.value = strings.TrimSpace(windows.value + " except " + blackouts)
+	}
+	if windows.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("the scan windows %q never allow a scan outside of the blackouts %q", allowed, blackouts)
+	}
+	return windows, nil
+}
+
+// parseWindowList parses the windows of a comma-separated list, the commas of the day lists being told from the
+// separators of the windows as the windows end with their times
+func parseWindowList(value string) ([]window, error) {
+	var windows []window
+	var days string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, ":") {
+			// a day of a list of days, for instance Sat of 'Sat,Sun 00:00-06:00'
+			days += part + ","
+			continue
+		}
+		w, err := parseWindow(days + part)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+		days = ""
+	}
+	if days != "" {
+		return nil, fmt.Errorf("days %q without times", strings.TrimSuffix(days, ","))
+	}
+	return windows, nil
+}
+
+// parseWindow parses a window such as '01:00-05:00' or 'Mon-Fri 22:00-02:00'
+func parseWindow(value string) (window, error) {
+	w := window{days: allDays}
+	fields := strings.Fields(value)
+	if len(fields) == 2 {
+		days, err := parseDays(fields[0])
+		if err != nil {
+			return w, err
+		}
+		w.days = days
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return w, fmt.Errorf("invalid window %q, expected format '[days ]HH:MM-HH:MM'", value)
+	}
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return w, fmt.Errorf("invalid window %q, expected format '[days ]HH:MM-HH:MM'", value)
+	}
+	var err error
+	if w.start, err = parseMinuteOfDay(start); err != nil {
+		return w, err
+	}
+	if w.end, err = parseMinuteOfDay(end); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("invalid window %q, the window is empty", value)
+	}
+	return w, nil
+}
+
+// parseDays parses a range or a list of day names, for instance Mon-Fri or Sat,Sun
+func parseDays(value string) (uint8, error) {
+	var days uint8
+	for _, part := range strings.Split(value, ",") {
+		first, last, isRange := strings.Cut(strings.ToLower(part), "-")
+		start, ok := dayNames[first]
+		if !ok {
+			return 0, fmt.Errorf("invalid day %q, expected a day name such as Mon", first)
+		}
+		end := start
+		if isRange {
+			if end, ok = dayNames[last]; !ok {
+				return 0, fmt.Errorf("invalid day %q, expected a day name such as Fri", last)
+			}
+		}
+		// the ranges such as Fri-Mon wrap around the end of the week
+		for day := start; ; day = (day + 1) % 7 {
+			days |= 1 << uint(day)
+			if day == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseMinuteOfDay parses a time of the day such as 01:30 into its minute of the day
+func parseMinuteOfDay(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	hour, hourErr := strconv.Atoi(hours)
+	minute, minuteErr := strconv.Atoi(minutes)
+	if !ok || hourErr != nil || minuteErr != nil || hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected format HH:MM", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// contains returns true when the window contains the minute of the day of the weekday
+func (w window) contains(day time.Weekday, minute int) bool {
+	previousDay := (day + 6) % 7
+	if w.start < w.end {
+		return w.days&(1<<uint(day)) != 0 && minute >= w.start && minute < w.end
+	}
+	return (w.days&(1<<uint(day)) != 0 && minute >= w.start) || (w.days&(1<<uint(previousDay)) != 0 && minute < w.end)
+}
+
+// Allows returns true when a scan may run at the time
+func (w *Windows) Allows(t time.Time) bool {
+	t = t.In(w.location)
+	day, minute := t.Weekday(), t.Hour()*60+t.Minute()
+	for _, blackout := range w.blackouts {
+		if blackout.contains(day, minute) {
+			return false
+		}
+	}
+	if len(w.allowed) == 0 {
+		return true
+	}
+	for _, allowed := range w.allowed {
+		if allowed.contains(day, minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the given time when a scan may run at the time, or else the time the next window opens at.
+// It is zero when the windows never allow a scan
+func (w *Windows) Next(after time.Time) time.Time {
+	if w.Allows(after) {
+		return after
+	}
+	for t := after.Truncate(time.Minute).Add(time.Minute); t.Sub(after) < maxWindowSearch; t = t.Add(time.Minute) {
+		if w.Allows(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Close returns the time the window the given time is in closes at, zero when the scans are always allowed from then on
+func (w *Windows) Close(after time.Time) time.Time {
+	for t := after.Truncate(time.Minute).Add(time.Minute); t.Sub(after) < maxWindowSearch; t = t.Add(time.Minute) {
+		if !w.Allows(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Context returns a context cancelled when the window the time is in closes, so that a run in progress stops once
+// its window closes. The context is only cancelled with the given context when the window never closes
+func (w *Windows) Context(ctx context.Context, now time.Time) (context.Context, context.CancelFunc) {
+	closing := w.Close(now)
+	if closing.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	logr.Infof("The scan window closes at %s, the run being cancelled if still in progress", closing.Format(time.RFC3339))
+	return context.WithDeadline(ctx, closing)
+}
+
+// String returns the windows and the blackouts as configured
+func (w *Windows) String() string {
+	return w.value
+}
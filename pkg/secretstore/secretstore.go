@@ -0,0 +1,253 @@
+package secretstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+// Schemes of the secret URIs, see NewStore
+const (
+	// VaultScheme reads the secrets of the Vault KV secrets engine, for instance vault://secret/registry
+	VaultScheme = "vault"
+	// AWSSecretsManagerScheme reads the secrets of AWS Secrets Manager by name or ARN, for instance awssm://registry
+	AWSSecretsManagerScheme = "awssm"
+	// GCPSecretManagerScheme reads the secrets of GCP Secret Manager by name or resource name, for instance
+	// gcpsm://projects/platform/secrets/registry/versions/latest
+	GCPSecretManagerScheme = "gcpsm"
+)
+
+// credentialsCacheTTL is how long the credentials read from a secret store are reused, so that the images of a registry
+// do not each read its secret while the rotated secrets are still picked up by the watched scans
+var credentialsCacheTTL = 15 * time.Minute
+
+// Provider reads the secrets of a secret store
+type Provider interface {
+	// Secret returns the value of the secret, the reference being the secret URI without its scheme
+	Secret(ctx context.Context, reference string) ([]byte, error)
+}
+
+// Store provides the credentials of the registries read from the secret stores, see scanner.RegistryCredentialSource
+type Store struct {
+	secrets   map[string]string
+	providers map[string]Provider
+	lock      sync.Mutex
+	cache     map[string]cachedCredentials
+}
+
+type cachedCredentials struct {
+	credentials *scanner.RegistryCredentials
+	readAt      time.Time
+}
+
+// NewStore creates a Store reading the credentials of each registry from its secret URI, for instance
+// vault://secret/registry, awssm://registry or gcpsm://projects/platform/secrets/registry. The secrets are read with
+// the vault, aws and gcloud CLIs, authenticated by their usual environment. A secret holds either a JSON object with
+// the username and password of the registry, or a docker config with the credentials of the registry, as is or under
+// the .dockerconfigjson key of the Kubernetes pull secrets
+func NewStore(secrets map[string]string) (*Store, error) {
+	commandRunner := execCmd.NewCommandRunner()
+	store := &Store{
+		secrets: make(map[string]string),
+		providers: map[string]Provider{
+			VaultScheme:             &vault{commandRunner: commandRunner},
+			AWSSecretsManagerScheme: &awsSecretsManager{commandRunner: commandRunner},
+			GCPSecretManagerScheme:  &gcpSecretManager{commandRunner: commandRunner},
+		},
+		cache: make(map[string]cachedCredentials),
+	}
+	for registry, secret := range secrets {
+		scheme, reference, found := strings.Cut(secret, "://")
+		if _, ok := store.providers[scheme]; !found || !ok || reference == "" {
+			return nil, fmt.Errorf("invalid secret %q of registry %s, expecting %s", secret, registry, strings.Join(store.schemes(), ", "))
+		}
+		store.secrets[registry] = secret
+	}
+	return store, nil
+}
+
+// Credentials returns the credentials of the registry read from its secret, nil when the registry has no secret
+func (s *Store) Credentials(ctx context.Context, registry string) (*scanner.RegistryCredentials, error) {
+	secret, ok := s.secrets[registry]
+	if !ok {
+		return nil, nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cached, ok := s.cache[registry]; ok && time.Since(cached.readAt) < credentialsCacheTTL {
+		return cached.credentials, nil
+	}
+
+	scheme, reference, _ := strings.Cut(secret, "://")
+	value, err := s.providers[scheme].Secret(ctx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("could not read secret %s: %v", secret, err)
+	}
+	credentials, err := parseCredentials(value, registry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret %s: %v", secret, err)
+	}
+	s.cache[registry] = cachedCredentials{credentials: credentials, readAt: time.Now()}
+	return credentials, nil
+}
+
+func (s *Store) schemes() []string {
+	var schemes []string
+	for scheme := range s.providers {
+		schemes = append(schemes, scheme+"://")
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// parseCredentials returns the credentials of the registry of the secret, a JSON object with a username and a
+// password, a docker config or a Kubernetes pull secret holding the docker config under the .dockerconfigjson key
+func parseCredentials(secret []byte, registry string) (*scanner.RegistryCredentials, error) {
+	var fields struct {
+		Username         string                      `json:"username"`
+		Password         string                      `json:"password"`
+		DockerConfigJSON string                      `json:".dockerconfigjson"`
+		Auths            map[string]dockerConfigAuth `json:"auths"`
+	}
+	if err := json.Unmarshal(secret, &fields); err != nil {
+		return nil, fmt.Errorf("the secret is not a JSON object")
+	}
+	switch {
+	case fields.DockerConfigJSON != "":
+		return parseCredentials([]byte(fields.DockerConfigJSON), registry)
+	case fields.Auths != nil:
+		for server, auth := range fields.Auths {
+			if serverRegistry(server) == registry {
+				return auth.credentials()
+			}
+		}
+		return nil, fmt.Errorf("the docker config has no credentials for registry %s", registry)
+	case fields.Username != "" && fields.Password != "":
+		return &scanner.RegistryCredentials{Username: fields.Username, Password: fields.Password}, nil
+	}
+	return nil, fmt.Errorf("the secret holds neither a username and a password nor a docker config")
+}
+
+// dockerConfigAuth is the object representation of the credentials of a registry of a docker config
+type dockerConfigAuth struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// credentials returns the credentials, the auth field holding the base64 encoded username:password if set
+func (a dockerConfigAuth) credentials() (*scanner.RegistryCredentials, error) {
+	if a.Auth == "" {
+		return &scanner.RegistryCredentials{Username: a.Username, Password: a.Password}, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth of the docker config: %v", err)
+	}
+	username, password, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return nil, fmt.Errorf("invalid auth of the docker config, expecting username:password")
+	}
+	return &scanner.RegistryCredentials{Username: username, Password: password}, nil
+}
+
+// serverRegistry returns the registry of a docker config server, for instance docker.io for the legacy Docker Hub
+// index https://index.docker.io/v1/
+func serverRegistry(server string) string {
+	if parsed, err := url.Parse(server); err == nil && parsed.Host != "" {
+		server = parsed.Host
+	}
+	switch server {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return server
+}
+
+// vault reads the secrets of the Vault KV secrets engine with vault kv get, for instance secret/registry, the
+// address and the token of Vault being read by the CLI from the VAULT_ADDR and VAULT_TOKEN environment variables
+type vault struct {
+	commandRunner execCmd.CommandRunner
+}
+
+func (v *vault) Secret(ctx context.Context, reference string) ([]byte, error) {
+	output, errOutput, err := v.commandRunner.ExecuteContext(ctx, "vault", []string{"kv", "get", "-format=json", reference})
+	if err != nil {
+		return nil, commandError(errOutput, err)
+	}
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(output, &secret); err != nil {
+		return nil, fmt.Errorf("error while decoding the vault output: %v", err)
+	}
+	// the KV version 2 engine nests the secret data along with its metadata
+	if data, ok := secret.Data["data"]; ok && secret.Data["metadata"] != nil {
+		return data, nil
+	}
+	return json.Marshal(secret.Data)
+}
+
+// awsSecretsManager reads the string secrets of AWS Secrets Manager with aws secretsmanager get-secret-value, in the
+// region of the ARN or else in the region of the AWS environment
+type awsSecretsManager struct {
+	commandRunner execCmd.CommandRunner
+}
+
+func (a *awsSecretsManager) Secret(ctx context.Context, reference string) ([]byte, error) {
+	args := []string{"secretsmanager", "get-secret-value", "--secret-id", reference, "--query", "SecretString", "--output", "text"}
+	if arn := strings.Split(reference, ":"); len(arn) > 3 && arn[0] == "arn" {
+		args = append(args, "--region", arn[3])
+	}
+	output, errOutput, err := a.commandRunner.ExecuteContext(ctx, "aws", args)
+	if err != nil {
+		return nil, commandError(errOutput, err)
+	}
+	return []byte(strings.TrimSpace(string(output))), nil
+}
+
+// gcpSecretManager reads the secrets of GCP Secret Manager with gcloud secrets versions access, the latest version of
+// the secrets of the gcloud project unless the reference is a resource name, for instance
+// projects/platform/secrets/registry/versions/3
+type gcpSecretManager struct {
+	commandRunner execCmd.CommandRunner
+}
+
+func (g *gcpSecretManager) Secret(ctx context.Context, reference string) ([]byte, error) {
+	project, secret, version := "", reference, "latest"
+	if parts := strings.Split(reference, "/"); len(parts) >= 4 && parts[0] == "projects" && parts[2] == "secrets" {
+		project, secret = parts[1], parts[3]
+		if len(parts) == 6 && parts[4] == "versions" {
+			version = parts[5]
+		}
+	}
+	args := []string{"secrets", "versions", "access", version, "--secret", secret}
+	if project != "" {
+		args = append(args, "--project", project)
+	}
+	output, errOutput, err := g.commandRunner.ExecuteContext(ctx, "gcloud", args)
+	if err != nil {
+		return nil, commandError(errOutput, err)
+	}
+	return output, nil
+}
+
+// commandError returns the last line of the error output of the CLI, the line stating the cause of the failure
+func commandError(errOutput []byte, err error) error {
+	lines := strings.Split(strings.TrimSpace(utils.ConvertByteToString(errOutput)), "\n")
+	if reason := strings.TrimSpace(lines[len(lines)-1]); reason != "" {
+		return errors.New(reason)
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package secretstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSecretStore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Secret Store Suite")
+}
+
+var _ = Describe("Secret store", func() {
+
+	var commandRunner *mockCommandRunner
+
+	BeforeEach(func() {
+		commandRunner = &mockCommandRunner{}
+	})
+
+	newStore := func(secrets map[string]string) *Store {
+		store, err := NewStore(secrets)
+		Expect(err).NotTo(HaveOccurred())
+		store.providers = map[string]Provider{
+			VaultScheme:             &vault{commandRunner: commandRunner},
+			AWSSecretsManagerScheme: &awsSecretsManager{commandRunner: commandRunner},
+			GCPSecretManagerScheme:  &gcpSecretManager{commandRunner: commandRunner},
+		}
+		return store
+	}
+
+	It("reads the username and password of the registry from the Vault KV secret", func() {
+		commandRunner.On("Execute", "vault", []string{"kv", "get", "-format=json", "secret/ghcr"}).
+			Return([]byte(`{"data":{"data":{"username":"scanner","password":"s3cret"},"metadata":{"version":3}}}`), []byte{}, nil).Once()
+		store := newStore(map[string]string{"ghcr.io": "vault://secret/ghcr"})
+
+		credentials, err := store.Credentials(context.Background(), "ghcr.io")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(&scanner.RegistryCredentials{Username: "scanner", Password: "s3cret"}))
+
+		By("reusing the credentials read")
+		credentials, err = store.Credentials(context.Background(), "ghcr.io")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.Username).To(Equal("scanner"))
+		commandRunner.AssertNumberOfCalls(GinkgoT(), "Execute", 1)
+	})
+
+	It("reads the secrets of the Vault KV version 1 engine", func() {
+		commandRunner.On("Execute", "vault", []string{"kv", "get", "-format=json", "kv/ghcr"}).
+			Return([]byte(`{"data":{"username":"scanner","password":"s3cret"}}`), []byte{}, nil)
+
+		credentials, err := newStore(map[string]string{"ghcr.io": "vault://kv/ghcr"}).Credentials(context.Background(), "ghcr.io")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(&scanner.RegistryCredentials{Username: "scanner", Password: "s3cret"}))
+	})
+
+	It("reads the docker config of the Kubernetes pull secret from AWS Secrets Manager in the region of the ARN", func() {
+		arn := "arn:aws:secretsmanager:eu-west-2:123456789012:secret:registry-AbCdEf"
+		commandRunner.On("Execute", "aws", []string{"secretsmanager", "get-secret-value", "--secret-id", arn, "--query", "SecretString", "--output", "text", "--region", "eu-west-2"}).
+			Return([]byte(`{".dockerconfigjson":"{\"auths\":{\"https://index.docker.io/v1/\":{\"auth\":\"c2Nhbm5lcjpzM2NyZXQ=\"}}}"}`+"\n"), []byte{}, nil)
+
+		credentials, err := newStore(map[string]string{"docker.io": "awssm://" + arn}).Credentials(context.Background(), "docker.io")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(&scanner.RegistryCredentials{Username: "scanner", Password: "s3cret"}))
+	})
+
+	It("reads the version of the secret of the project from GCP Secret Manager", func() {
+		commandRunner.On("Execute", "gcloud", []string{"secrets", "versions", "access", "3", "--secret", "registry", "--project", "platform"}).
+			Return([]byte(`{"auths":{"europe-docker.pkg.dev":{"username":"_json_key","password":"{}"}}}`), []byte{}, nil)
+		commandRunner.On("Execute", "gcloud", []string{"secrets", "versions", "access", "latest", "--secret", "registry"}).
+			Return([]byte(`{"auths":{"europe-docker.pkg.dev":{"username":"_json_key","password":"{}"}}}`), []byte{}, nil)
+		store := newStore(map[string]string{
+			"europe-docker.pkg.dev": "gcpsm://projects/platform/secrets/registry/versions/3",
+			"gcr.io":                "gcpsm://registry",
+		})
+
+		credentials, err := store.Credentials(context.Background(), "europe-docker.pkg.dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(Equal(&scanner.RegistryCredentials{Username: "_json_key", Password: "{}"}))
+
+		_, err = store.Credentials(context.Background(), "gcr.io")
+		Expect(err).To(MatchError("invalid secret gcpsm://registry: the docker config has no credentials for registry gcr.io"))
+	})
+
+	It("returns no credentials for the registries without secret", func() {
+		credentials, err := newStore(map[string]string{"ghcr.io": "vault://secret/ghcr"}).Credentials(context.Background(), "quay.io")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials).To(BeNil())
+	})
+
+	It("returns the error of the CLI", func() {
+		commandRunner.On("Execute", "vault", []string{"kv", "get", "-format=json", "secret/ghcr"}).
+			Return([]byte{}, []byte("Error making API request.\n\nCode: 403. Errors:\n* permission denied\n"), errors.New("exit status 2"))
+
+		_, err := newStore(map[string]string{"ghcr.io": "vault://secret/ghcr"}).Credentials(context.Background(), "ghcr.io")
+
+		Expect(err).To(MatchError("could not read secret vault://secret/ghcr: * permission denied"))
+	})
+
+	It("reads the secret again once the credentials expired", func() {
+		defer func(ttl time.Duration) { credentialsCacheTTL = ttl }(credentialsCacheTTL)
+		credentialsCacheTTL = 0
+		commandRunner.On("Execute", "aws", []string{"secretsmanager", "get-secret-value", "--secret-id", "registry", "--query", "SecretString", "--output", "text"}).
+			Return([]byte(`{"username":"scanner","password":"s3cret"}`), []byte{}, nil)
+		store := newStore(map[string]string{"registry.internal": "awssm://registry"})
+
+		for i := 0; i < 2; i++ {
+			_, err := store.Credentials(context.Background(), "registry.internal")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		commandRunner.AssertNumberOfCalls(GinkgoT(), "Execute", 2)
+	})
+
+	It("rejects the secrets of unknown secret stores", func() {
+		_, err := NewStore(map[string]string{"ghcr.io": "secret/ghcr"})
+
+		Expect(err).To(MatchError(`invalid secret "secret/ghcr" of registry ghcr.io, expecting awssm://, gcpsm://, vault://`))
+	})
+
+	It("rejects the secrets without credentials", func() {
+		_, err := parseCredentials([]byte(`{"token":"abc"}`), "ghcr.io")
+		Expect(err).To(MatchError("the secret holds neither a username and a password nor a docker config"))
+
+		_, err = parseCredentials([]byte(`s3cret`), "ghcr.io")
+		Expect(err).To(MatchError("the secret is not a JSON object"))
+	})
+})
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
@@ -0,0 +1,167 @@
+// Package apiclient is the Go client of the HTTP API of the serve command and of the scheduled scans of
+// scan --schedule, so that internal portals can retrieve the reports and trigger the scans programmatically. The API
+// is described by the OpenAPI definition of pkg/server/openapi.yaml, served on /api/v1/openapi.yaml. The client is
+// written by hand and only covers the report and scan operations, its tests checking it calls the operations of the
+// definition
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/schedule"
+)
+
+// Client calls the API of a server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	// token is sent as bearer token, see NewWithToken
+	token string
+}
+
+// Error is the error answered by the API, for instance 404 when the image is not in the report, 409 when a scan is
+// triggered while another is in progress or 503 when no report is loaded yet
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("unexpected status %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// New creates a Client of the server of the base url, for instance http://production-readiness:8080
+func New(baseURL string) *Client {
+	return NewWithHTTPClient(baseURL, http.DefaultClient)
+}
+
+// NewWithHTTPClient creates a Client of the server of the base url sending the requests with the http client, for
+// instance to authenticate them or to set a timeout
+func NewWithHTTPClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// NewWithToken creates a Client of the server of the base url sending the token as bearer token, the token of
+// scan --schedule authorising the scans triggered with TriggerScan
+func NewWithToken(baseURL, token string) *Client {
+	client := New(baseURL)
+	client.token = token
+	return client
+}
+
+// Report returns the latest image scan report
+func (c *Client) Report(ctx context.Context) (*scanner.VulnerabilityReport, error) {
+	report := &scanner.VulnerabilityReport{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/report", http.StatusOK, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Image returns the scan of the image of the latest report, for instance docker.io/nginx:1.25
+func (c *Client) Image(ctx context.Context, imageName string) (*scanner.ScannedImage, error) {
+	image := &scanner.ScannedImage{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/images/"+escapePath(imageName), http.StatusOK, image); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// TeamReport returns the report of the team of the latest report, holding its images only
+func (c *Client) TeamReport(ctx context.Context, team string) (*scanner.VulnerabilityReport, error) {
+	report := &scanner.VulnerabilityReport{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/teams/"+url.PathEscape(team), http.StatusOK, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Heatmap returns the vulnerability counts of the teams and of the namespaces of the latest report by severity
+func (c *Client) Heatmap(ctx context.Context) (*scanner.Heatmap, error) {
+	heatmap := &scanner.Heatmap{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/heatmap", http.StatusOK, heatmap); err != nil {
+		return nil, err
+	}
+	return heatmap, nil
+}
+
+// Ready returns true once the server has loaded a report
+func (c *Client) Ready(ctx context.Context) (bool, error) {
+	err := c.do(ctx, http.MethodGet, "/ready", http.StatusNoContent, nil)
+	if apiErr, ok := err.(*Error); ok && apiErr.StatusCode == http.StatusServiceUnavailable {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ScanStatus returns the status of the scheduled scans of scan --schedule
+func (c *Client) ScanStatus(ctx context.Context) (*schedule.Status, error) {
+	status := &schedule.Status{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/status", http.StatusOK, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// TriggerScan starts a scan of scan --schedule out of its schedule and returns the status of the scheduled scans. It
+// fails with a 401 Error when the client was not created with the token of the server, see NewWithToken, and with a
+// 409 Error while a scan is in progress
+func (c *Client) TriggerScan(ctx context.Context) (*schedule.Status, error) {
+	status := &schedule.Status{}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/scans", http.StatusAccepted, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// do sends the request and decodes the json response into the value, failing with an Error when the status is not the
+// expected one. The response body is ignored when the value is nil
+func (c *Client) do(ctx context.Context, method, path string, expectedStatus int, value interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expectedStatus {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var body struct{ Error string }
+		if content, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(content, &body) == nil {
+			apiErr.Message = body.Error
+		}
+		return apiErr
+	}
+	if value == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+		return fmt.Errorf("unable to decode the response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// escapePath escapes the segments of the path, keeping its slashes
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,161 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/schedule"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/server"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "API Client Suite")
+}
+
+var _ = Describe("API client", func() {
+
+	var (
+		reportFile string
+		client     *Client
+		scans      chan struct{}
+	)
+
+	BeforeEach(func() {
+		reportFile = filepath.Join(GinkgoT().TempDir(), "report.json")
+		cronSchedule, err := schedule.Parse("0 2 * * *")
+		Expect(err).NotTo(HaveOccurred())
+		scans = make(chan struct{})
+		scheduler := schedule.NewScheduler(cronSchedule, func(ctx context.Context) error {
+			<-scans
+			return nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		go scheduler.Run(ctx)
+		mux := http.NewServeMux()
+		mux.Handle("/", server.New(reportFile).Handler())
+		mux.Handle("/api/v1/status", scheduler.StatusHandler())
+		mux.Handle("/api/v1/scans", scheduler.TriggerHandler("secret"))
+		httpServer := httptest.NewServer(mux)
+		client = NewWithToken(httpServer.URL+"/", "secret")
+		DeferCleanup(func() {
+			close(scans)
+			cancel()
+			httpServer.Close()
+		})
+	})
+
+	saveReport := func(imageNames ...string) {
+		var images []scanner.ScannedImage
+		for _, imageName := range imageNames {
+			images = append(images, scanner.NewScannedImage(imageName, []k8s.ContainerSummary{{Image: imageName, Namespace: "payments"}}, nil, nil))
+		}
+		report := &scanner.VulnerabilityReport{
+			ScannedImages: images,
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"finance": {Name: "finance", Teams: map[string]*scanner.TeamSummary{"payments": {Name: "payments", Images: images}}},
+			},
+		}
+		content, err := json.Marshal(map[string]interface{}{"schemaVersion": 2, "ImageScan": report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(reportFile, content, 0644)).To(Succeed())
+	}
+
+	It("retrieves the report, the images and the teams once the report is loaded", func() {
+		ready, err := client.Ready(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ready).To(BeFalse())
+		_, err = client.Report(context.Background())
+		Expect(err).To(MatchError(&Error{StatusCode: http.StatusServiceUnavailable, Message: "no report loaded yet"}))
+
+		saveReport("docker.io/nginx:1.25", "redis:7.2")
+
+		Expect(client.Ready(context.Background())).To(BeTrue())
+		report, err := client.Report(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.ScannedImages).To(HaveLen(2))
+		image, err := client.Image(context.Background(), "docker.io/nginx:1.25")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image.ImageName).To(Equal("docker.io/nginx:1.25"))
+		teamReport, err := client.TeamReport(context.Background(), "payments")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(teamReport.ScannedImages).To(HaveLen(2))
+		heatmap, err := client.Heatmap(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(heatmap.Teams.Rows).To(Equal([]string{"finance/payments"}))
+		Expect(heatmap.Namespaces.Rows).To(Equal([]string{"payments"}))
+		_, err = client.TeamReport(context.Background(), "unknown")
+		Expect(err).To(MatchError("unexpected status 404 Not Found: team unknown not found in the report"))
+	})
+
+	It("triggers a scan, once at a time", func() {
+		status, err := client.TriggerScan(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Schedule).To(Equal("0 2 * * *"))
+
+		Eventually(func() (bool, error) {
+			status, err := client.ScanStatus(context.Background())
+			if err != nil {
+				return false, err
+			}
+			return status.Running, nil
+		}).Should(BeTrue())
+		_, err = client.TriggerScan(context.Background())
+		Expect(err).To(MatchError(&Error{StatusCode: http.StatusConflict, Message: "a run is already in progress"}))
+		_, err = New(client.baseURL).TriggerScan(context.Background())
+		Expect(err).To(MatchError(&Error{StatusCode: http.StatusUnauthorized}))
+	})
+
+	It("only calls the operations of the OpenAPI definition", func() {
+		var spec struct {
+			Paths map[string]map[string]interface{} `json:"paths"`
+		}
+		Expect(yaml.Unmarshal(server.OpenAPISpec, &spec)).To(Succeed())
+		var requests []string
+		recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		defer recorder.Close()
+
+		client := New(recorder.URL)
+		_, _ = client.Report(context.Background())
+		_, _ = client.Image(context.Background(), "docker.io/nginx:1.25")
+		_, _ = client.TeamReport(context.Background(), "payments")
+		_, _ = client.Heatmap(context.Background())
+		_, _ = client.Ready(context.Background())
+		_, _ = client.ScanStatus(context.Background())
+		_, _ = client.TriggerScan(context.Background())
+
+		Expect(requests).To(HaveLen(7))
+		for _, request := range requests {
+			method, path, _ := strings.Cut(request, " ")
+			Expect(specOperation(spec.Paths, strings.ToLower(method), path)).To(BeTrue(), "%s is not in the OpenAPI definition", request)
+		}
+	})
+})
+
+// specOperation returns true when the OpenAPI paths define the operation of the method on the path, the path parameters
+// matching the rest of the path, as the image names keep their slashes
+func specOperation(paths map[string]map[string]interface{}, method, path string) bool {
+	for template, operations := range paths {
+		pattern := "^" + regexp.MustCompile(`\\\{[^/]+\\\}`).ReplaceAllString(regexp.QuoteMeta(template), ".+") + "$"
+		if _, ok := operations[method]; ok && regexp.MustCompile(pattern).MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
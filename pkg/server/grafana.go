@@ -0,0 +1,297 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxSeverityPoints is the number of reports whose severity counts are kept, older counts being dropped
+	maxSeverityPoints = 5000
+	// defaultTopImages is the number of images of the top images table unless the limit parameter is set
+	defaultTopImages = 20
+
+	// the metrics of the Grafana JSON datasource, see grafanaQuery
+	severityCountsMetric = "severity_counts"
+	topImagesMetric      = "top_images"
+)
+
+// severities are the columns of the severity counts, ordered from the most severe
+var severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// severityPoint is the vulnerability count by severity of a report, in total and per team
+type severityPoint struct {
+	Time   time.Time                 `json:"time"`
+	Counts map[string]int            `json:"counts"`
+	Teams  map[string]map[string]int `json:"teams,omitempty"`
+}
+
+// loadSeries reads the severity counts recorded by a previous run of the server, none when the file does not exist yet
+func loadSeries(filename string) ([]severityPoint, error) {
+	var series []severityPoint
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read severity series file %s: %v", filename, err)
+	}
+	if err := json.Unmarshal(content, &series); err != nil {
+		return nil, fmt.Errorf("error while decoding severity series file %s: %v", filename, err)
+	}
+	return series, nil
+}
+
+// record adds the severity counts of the report to the series, at the scan time of the report or else at the time
+// it was saved. The report is not recorded again when it is reloaded
+func (s *Server) record(report *scanner.VulnerabilityReport, savedAt time.Time) {
+	point := severityPoint{Time: report.Metadata.ScanTime, Counts: make(map[string]int), Teams: make(map[string]map[string]int)}
+	if point.Time.IsZero() {
+		point.Time = savedAt
+	}
+	if len(s.series) > 0 && !point.Time.After(s.series[len(s.series)-1].Time) {
+		return
+	}
+	for _, image := range report.ScannedImages {
+		for severity, count := range image.VulnerabilitySummary.TotalVulnerabilityBySeverity {
+			point.Counts[severity] += count
+		}
+	}
+	for _, area := range report.AreaSummary {
+		for _, team := range area.Teams {
+			if point.Teams[team.Name] == nil {
+				point.Teams[team.Name] = make(map[string]int)
+			}
+			for severity, count := range team.TotalVulnerabilityBySeverity() {
+				point.Teams[team.Name][severity] += count
+			}
+		}
+	}
+	s.series = append(s.series, point)
+	if len(s.series) > maxSeverityPoints {
+		s.series = s.series[len(s.series)-maxSeverityPoints:]
+	}
+	if s.seriesFile == "" {
+		return
+	}
+	if err := saveSeries(s.series, s.seriesFile); err != nil {
+		logr.Warnf("Unable to save the severity series: %v", err)
+	}
+}
+
+// saveSeries writes the severity counts to the file, replacing it once fully written
+func saveSeries(series []severityPoint, filename string) error {
+	content, err := json.Marshal(series)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("could not create the directory of severity series file %s: %v", filename, err)
+	}
+	if err := os.WriteFile(filename+".tmp", content, 0644); err != nil {
+		return fmt.Errorf("could not write severity series file %s: %v", filename, err)
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// severitySeries returns the severity counts of the reports within the time range, of the team only if set
+func (s *Server) severitySeries(team string, from, to time.Time) []severityPoint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var series []severityPoint
+	for _, point := range s.series {
+		if (!from.IsZero() && point.Time.Before(from)) || (!to.IsZero() && point.Time.After(to)) {
+			continue
+		}
+		if team != "" {
+			point = severityPoint{Time: point.Time, Counts: point.Teams[team]}
+		}
+		series = append(series, point)
+	}
+	return series
+}
+
+// topImages returns the images with the highest severity score, of the team only if set
+func topImages(report *scanner.VulnerabilityReport, team string, limit int) []scanner.ScannedImage {
+	if team != "" {
+		teamReport, ok := report.SplitByTeam()[team]
+		if !ok {
+			return nil
+		}
+		report = teamReport
+	}
+	images := report.TopVulnerableImages()
+	if limit < len(images) {
+		images = images[:limit]
+	}
+	return images
+}
+
+// timeseriesRows returns a row per report with its time and its count of each severity, the shape the Grafana
+// Infinity datasource reads as a time series
+func timeseriesRows(series []severityPoint) []map[string]interface{} {
+	rows := []map[string]interface{}{}
+	for _, point := range series {
+		row := map[string]interface{}{"time": point.Time.UTC().Format(time.RFC3339)}
+		for _, severity := range severities {
+			row[severity] = point.Counts[severity]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// topImageRows returns a row per image with its containers, its count of each severity and its fixable count
+func topImageRows(images []scanner.ScannedImage) []map[string]interface{} {
+	rows := []map[string]interface{}{}
+	for _, image := range images {
+		summary := image.VulnerabilitySummary
+		row := map[string]interface{}{"image": image.ImageName, "containers": summary.ContainerCount, "fixable": summary.FixableCount}
+		for _, severity := range severities {
+			row[severity] = summary.TotalVulnerabilityBySeverity[severity]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// handleGrafana serves the endpoints of the Grafana datasources:
+//
+//	GET  /api/v1/grafana/timeseries  the severity counts of the reports for the Infinity datasource, team, from and to
+//	                                 RFC3339 parameters filtering them
+//	GET  /api/v1/grafana/top-images  the most vulnerable images of the latest report for the Infinity datasource, team
+//	                                 and limit parameters filtering them
+//	GET  /api/v1/grafana             the connection test of the JSON datasource
+//	POST /api/v1/grafana/metrics     the metrics of the JSON datasource, severity_counts and top_images
+//	POST /api/v1/grafana/query       the query of the JSON datasource, the team and limit filters being read from the
+//	                                 payload of the targets
+func (s *Server) handleGrafana(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/grafana/timeseries", s.withReport(func(w http.ResponseWriter, r *http.Request, _ *scanner.VulnerabilityReport) {
+		from, to, err := timeRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, timeseriesRows(s.severitySeries(r.URL.Query().Get("team"), from, to)))
+	}))
+	mux.HandleFunc("/api/v1/grafana/top-images", s.withReport(func(w http.ResponseWriter, r *http.Request, report *scanner.VulnerabilityReport) {
+		limit := defaultTopImages
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid limit "+value+", expected a positive number of images")
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, topImageRows(topImages(report, r.URL.Query().Get("team"), limit)))
+	}))
+	mux.HandleFunc("/api/v1/grafana", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, struct{ Status string }{"ok"})
+	})
+	mux.HandleFunc("/api/v1/grafana/metrics", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []map[string]string{
+			{"label": "Vulnerabilities by severity", "value": severityCountsMetric},
+			{"label": "Top vulnerable images", "value": topImagesMetric},
+		})
+	})
+	mux.HandleFunc("/api/v1/grafana/query", s.grafanaQuery)
+}
+
+// grafanaQuery answers the queries of the Grafana JSON datasource, a time series per severity for the severity_counts
+// targets and a table for the top_images targets
+func (s *Server) grafanaQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+		return
+	}
+	var query struct {
+		Range struct {
+			From time.Time `json:"from"`
+			To   time.Time `json:"to"`
+		} `json:"range"`
+		Targets []struct {
+			Target  string `json:"target"`
+			Payload struct {
+				Team  string `json:"team"`
+				Limit int    `json:"limit"`
+			} `json:"payload"`
+		} `json:"targets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid query: "+err.Error())
+		return
+	}
+	report := s.latestReport()
+	if report == nil {
+		writeError(w, http.StatusServiceUnavailable, "no report loaded yet")
+		return
+	}
+
+	results := []interface{}{}
+	for _, target := range query.Targets {
+		switch target.Target {
+		case severityCountsMetric:
+			series := s.severitySeries(target.Payload.Team, query.Range.From, query.Range.To)
+			for _, severity := range severities {
+				datapoints := [][]int64{}
+				for _, point := range series {
+					datapoints = append(datapoints, []int64{int64(point.Counts[severity]), point.Time.UnixMilli()})
+				}
+				results = append(results, map[string]interface{}{"target": severity, "datapoints": datapoints})
+			}
+		case topImagesMetric:
+			limit := target.Payload.Limit
+			if limit <= 0 {
+				limit = defaultTopImages
+			}
+			results = append(results, topImagesTable(topImages(report, target.Payload.Team, limit)))
+		default:
+			writeError(w, http.StatusBadRequest, "unknown metric "+target.Target)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// topImagesTable returns the images as a table of the JSON datasource
+func topImagesTable(images []scanner.ScannedImage) map[string]interface{} {
+	columns := []map[string]string{{"text": "Image", "type": "string"}, {"text": "Containers", "type": "number"}}
+	for _, severity := range severities {
+		columns = append(columns, map[string]string{"text": severity, "type": "number"})
+	}
+	columns = append(columns, map[string]string{"text": "Fixable", "type": "number"})
+	rows := [][]interface{}{}
+	for _, image := range images {
+		summary := image.VulnerabilitySummary
+		row := []interface{}{image.ImageName, summary.ContainerCount}
+		for _, severity := range severities {
+			row = append(row, summary.TotalVulnerabilityBySeverity[severity])
+		}
+		rows = append(rows, append(row, summary.FixableCount))
+	}
+	return map[string]interface{}{"type": "table", "columns": columns, "rows": rows}
+}
+
+// timeRange parses the RFC3339 bounds of the time range, unbounded when empty
+func timeRange(from, to string) (time.Time, time.Time, error) {
+	var bounds [2]time.Time
+	for i, value := range []string{from, to} {
+		if value == "" {
+			continue
+		}
+		bound, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid time %s, expected an RFC3339 time such as 2023-09-01T10:00:00Z", value)
+		}
+		bounds[i] = bound
+	}
+	return bounds[0], bounds[1], nil
+}
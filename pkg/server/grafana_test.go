@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Grafana datasources", func() {
+
+	var (
+		reportFile string
+		seriesFile string
+		server     *httptest.Server
+		scanTime   = time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+	)
+
+	image := func(imageName, team string, severities ...string) scanner.ScannedImage {
+		var vulnerabilities []scanner.Vulnerabilities
+		for i, severity := range severities {
+			vulnerabilities = append(vulnerabilities, scanner.Vulnerabilities{VulnerabilityID: "CVE-2023-" + string(rune('0'+i)), Severity: severity, PkgName: "openssl"})
+		}
+		return scanner.NewScannedImage(imageName, []k8s.ContainerSummary{{Image: imageName, Namespace: team}},
+			[]scanner.TrivyOutputResults{{Vulnerabilities: vulnerabilities}}, nil)
+	}
+
+	saveReport := func(scanTime time.Time, images ...scanner.ScannedImage) {
+		teams := make(map[string]*scanner.TeamSummary)
+		for _, image := range images {
+			team := image.Containers[0].Namespace
+			if teams[team] == nil {
+				teams[team] = &scanner.TeamSummary{Name: team}
+			}
+			teams[team].Images = append(teams[team].Images, image)
+		}
+		report := &scanner.VulnerabilityReport{
+			Metadata:      scanner.ReportMetadata{ScanTime: scanTime},
+			ScannedImages: images,
+			AreaSummary:   map[string]*scanner.AreaSummary{"finance": {Name: "finance", Teams: teams}},
+		}
+		content, err := json.Marshal(map[string]interface{}{"schemaVersion": 2, "ImageScan": report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(reportFile, content, 0644)).To(Succeed())
+		later := time.Now().Add(scanTime.Sub(time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(os.Chtimes(reportFile, later, later)).To(Succeed())
+	}
+
+	get := func(path string, body interface{}) int {
+		resp, err := http.Get(server.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		if body != nil {
+			Expect(json.NewDecoder(resp.Body).Decode(body)).To(Succeed())
+		}
+		return resp.StatusCode
+	}
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		reportFile = filepath.Join(dir, "report.json")
+		seriesFile = filepath.Join(dir, "series", "series.json")
+		reportServer, err := NewWithSeriesFile(reportFile, seriesFile)
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(reportServer.Handler())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("serves the severity counts of each report as a time series", func() {
+		saveReport(scanTime, image("nginx:1.25", "payments", "CRITICAL", "HIGH"), image("redis:7", "orders", "HIGH"))
+		var rows []map[string]interface{}
+		Expect(get("/api/v1/grafana/timeseries", &rows)).To(Equal(http.StatusOK))
+		Expect(rows).To(HaveLen(1))
+
+		saveReport(scanTime.Add(time.Hour), image("nginx:1.26", "payments", "LOW"), image("redis:7", "orders", "HIGH"))
+		Expect(get("/api/v1/grafana/timeseries", &rows)).To(Equal(http.StatusOK))
+		Expect(rows).To(Equal([]map[string]interface{}{
+			{"time": "2023-09-01T10:00:00Z", "CRITICAL": 1.0, "HIGH": 2.0, "MEDIUM": 0.0, "LOW": 0.0, "UNKNOWN": 0.0},
+			{"time": "2023-09-01T11:00:00Z", "CRITICAL": 0.0, "HIGH": 1.0, "MEDIUM": 0.0, "LOW": 1.0, "UNKNOWN": 0.0},
+		}))
+
+		Expect(get("/api/v1/grafana/timeseries?team=payments&from=2023-09-01T10:30:00Z", &rows)).To(Equal(http.StatusOK))
+		Expect(rows).To(Equal([]map[string]interface{}{
+			{"time": "2023-09-01T11:00:00Z", "CRITICAL": 0.0, "HIGH": 0.0, "MEDIUM": 0.0, "LOW": 1.0, "UNKNOWN": 0.0},
+		}))
+
+		var badRequest struct{ Error string }
+		Expect(get("/api/v1/grafana/timeseries?to=yesterday", &badRequest)).To(Equal(http.StatusBadRequest))
+		Expect(badRequest.Error).To(ContainSubstring("invalid time yesterday"))
+	})
+
+	It("keeps the time series in the series file across restarts", func() {
+		saveReport(scanTime, image("nginx:1.25", "payments", "CRITICAL"))
+		Expect(get("/api/v1/grafana/timeseries", nil)).To(Equal(http.StatusOK))
+		server.Close()
+
+		reportServer, err := NewWithSeriesFile(reportFile, seriesFile)
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(reportServer.Handler())
+
+		var rows []map[string]interface{}
+		Expect(get("/api/v1/grafana/timeseries", &rows)).To(Equal(http.StatusOK))
+		Expect(rows).To(HaveLen(1))
+		Expect(rows[0]["CRITICAL"]).To(Equal(1.0))
+	})
+
+	It("serves the most vulnerable images of the latest report", func() {
+		saveReport(scanTime, image("nginx:1.25", "payments", "HIGH"), image("redis:7", "orders", "CRITICAL", "CRITICAL"),
+			image("api:1.0", "payments", "CRITICAL"))
+
+		var rows []map[string]interface{}
+		Expect(get("/api/v1/grafana/top-images?limit=2", &rows)).To(Equal(http.StatusOK))
+		Expect(rows).To(HaveLen(2))
+		Expect(rows[0]["image"]).To(Equal("redis:7"))
+		Expect(rows[0]["CRITICAL"]).To(Equal(2.0))
+		Expect(rows[1]["image"]).To(Equal("api:1.0"))
+
+		Expect(get("/api/v1/grafana/top-images?team=payments", &rows)).To(Equal(http.StatusOK))
+		Expect(rows).To(HaveLen(2))
+		Expect(rows[0]["image"]).To(Equal("api:1.0"))
+		Expect(rows[1]["image"]).To(Equal("nginx:1.25"))
+
+		Expect(get("/api/v1/grafana/top-images?limit=none", nil)).To(Equal(http.StatusBadRequest))
+	})
+
+	It("answers the queries of the JSON datasource", func() {
+		saveReport(scanTime, image("nginx:1.25", "payments", "HIGH"), image("redis:7", "orders", "CRITICAL"))
+		Expect(get("/api/v1/grafana", nil)).To(Equal(http.StatusOK))
+
+		query := `{"range": {"from": "2023-09-01T00:00:00Z", "to": "2023-09-02T00:00:00Z"},
+			"targets": [{"target": "severity_counts", "payload": {"team": "orders"}}, {"target": "top_images", "payload": {"limit": 1}}]}`
+		resp, err := http.Post(server.URL+"/api/v1/grafana/query", "application/json", bytes.NewBufferString(query))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		var results []map[string]interface{}
+		Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+
+		Expect(results).To(HaveLen(len(severities) + 1))
+		Expect(results[0]).To(Equal(map[string]interface{}{
+			"target":     "CRITICAL",
+			"datapoints": []interface{}{[]interface{}{1.0, float64(scanTime.UnixMilli())}},
+		}))
+		Expect(results[1]["datapoints"]).To(Equal([]interface{}{[]interface{}{0.0, float64(scanTime.UnixMilli())}}))
+		table := results[len(severities)]
+		Expect(table["type"]).To(Equal("table"))
+		Expect(table["rows"]).To(Equal([]interface{}{[]interface{}{"redis:7", 1.0, 1.0, 0.0, 0.0, 0.0, 0.0, 0.0}}))
+
+		resp, err = http.Post(server.URL+"/api/v1/grafana/query", "application/json", bytes.NewBufferString(`{"targets": [{"target": "cves"}]}`))
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
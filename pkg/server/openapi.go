@@ -0,0 +1,26 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// OpenAPISpec is the OpenAPI definition of the API, of the endpoints of Handler and of the scheduled scans served by
+// scan --schedule
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
+
+// serveOpenAPISpec serves the OpenAPI definition of the API
+func serveOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(OpenAPISpec); err != nil {
+		logr.Errorf("Error writing the response: %v", err)
+	}
+}
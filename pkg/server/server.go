@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+// Server exposes the latest vulnerability report over a REST API. The report is the json report saved by a scan with
+// the report-output-filename-json option, reloaded whenever the scan saves a new one
+type Server struct {
+	reportFile string
+	// guards the report and its modification time, reloaded by the requests, and the severity counts of the reports
+	lock    sync.Mutex
+	report  *scanner.VulnerabilityReport
+	modTime time.Time
+	series  []severityPoint
+	// seriesFile keeps the severity counts of the reports across the restarts of the server, not kept when empty
+	seriesFile string
+}
+
+// New creates a Server serving the report saved to the report file
+func New(reportFile string) *Server {
+	return &Server{reportFile: reportFile}
+}
+
+// NewWithSeriesFile creates a Server serving the report saved to the report file, the severity counts of the reports
+// served to Grafana being saved to the series file so that they are kept when the server restarts
+func NewWithSeriesFile(reportFile, seriesFile string) (*Server, error) {
+	series, err := loadSeries(seriesFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{reportFile: reportFile, seriesFile: seriesFile, series: series}, nil
+}
+
+// Handler returns the handler of the API endpoints:
+//
+//	/api/v1/report         the whole report
+//	/api/v1/images/<name>  the scan of an image, for instance /api/v1/images/docker.io/nginx:1.25
+//	/api/v1/teams/<team>   the report of a team holding its images only
+//	/api/v1/heatmap        the vulnerability counts of the teams and of the namespaces by severity, see scanner.Heatmap
+//	/api/v1/grafana/...    the endpoints of the Grafana datasources, see handleGrafana
+//	/api/v1/openapi.yaml   the OpenAPI definition of the API, see OpenAPISpec
+//	/health                the liveness of the server
+//	/ready                 the readiness of the server, ready once a report is loaded
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/report", s.withReport(func(w http.ResponseWriter, r *http.Request, report *scanner.VulnerabilityReport) {
+		writeJSON(w, http.StatusOK, report)
+	}))
+	mux.HandleFunc("/api/v1/images/", s.withReport(func(w http.ResponseWriter, r *http.Request, report *scanner.VulnerabilityReport) {
+		imageName := strings.TrimPrefix(r.URL.Path, "/api/v1/images/")
+		for _, image := range report.ScannedImages {
+			if image.ImageName == imageName {
+				writeJSON(w, http.StatusOK, image)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "image "+imageName+" not found in the report")
+	}))
+	mux.HandleFunc("/api/v1/teams/", s.withReport(func(w http.ResponseWriter, r *http.Request, report *scanner.VulnerabilityReport) {
+		team := strings.TrimPrefix(r.URL.Path, "/api/v1/teams/")
+		teamReport, ok := report.SplitByTeam()[team]
+		if !ok {
+			writeError(w, http.StatusNotFound, "team "+team+" not found in the report")
+			return
+		}
+		writeJSON(w, http.StatusOK, teamReport)
+	}))
+	mux.HandleFunc("/api/v1/heatmap", s.withReport(func(w http.ResponseWriter, r *http.Request, report *scanner.VulnerabilityReport) {
+		writeJSON(w, http.StatusOK, scanner.NewHeatmap(report))
+	}))
+	s.handleGrafana(mux)
+	mux.HandleFunc("/api/v1/openapi.yaml", serveOpenAPISpec)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if s.latestReport() == nil {
+			writeError(w, http.StatusServiceUnavailable, "no report loaded yet")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// withReport serves the GET requests with the latest report, failing when no report is loaded yet
+func (s *Server) withReport(handle func(w http.ResponseWriter, r *http.Request, report *scanner.VulnerabilityReport)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+			return
+		}
+		report := s.latestReport()
+		if report == nil {
+			writeError(w, http.StatusServiceUnavailable, "no report loaded yet")
+			return
+		}
+		handle(w, r, report)
+	}
+}
+
+// latestReport reloads the report when the report file changed since last loaded. The previous report is kept when
+// the new one cannot be loaded, for instance while the scan is writing it
+func (s *Server) latestReport() *scanner.VulnerabilityReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	info, err := os.Stat(s.reportFile)
+	if err != nil {
+		if s.report == nil {
+			logr.Debugf("No report to serve yet: %v", err)
+		}
+		return s.report
+	}
+	if s.report != nil && !info.ModTime().After(s.modTime) {
+		return s.report
+	}
+	report, err := scanner.LoadVulnerabilityReport(s.reportFile)
+	if err != nil {
+		logr.Warnf("Could not load the latest report, serving the previous one: %v", err)
+		return s.report
+	}
+	logr.Infof("Loaded report %s of %d images", s.reportFile, len(report.ScannedImages))
+	s.report, s.modTime = report, info.ModTime()
+	s.record(report, info.ModTime())
+	return s.report
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logr.Errorf("Error writing the response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct{ Error string }{message})
+}
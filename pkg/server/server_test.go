@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Server Suite")
+}
+
+var _ = Describe("Report API", func() {
+
+	var (
+		reportFile string
+		server     *httptest.Server
+	)
+
+	saveReport := func(imageNames ...string) {
+		var images []scanner.ScannedImage
+		for _, imageName := range imageNames {
+			images = append(images, scanner.NewScannedImage(imageName, []k8s.ContainerSummary{{Image: imageName, Namespace: "payments"}}, nil, nil))
+		}
+		report := &scanner.VulnerabilityReport{
+			ScannedImages: images,
+			AreaSummary: map[string]*scanner.AreaSummary{
+				"finance": {Name: "finance", Teams: map[string]*scanner.TeamSummary{"payments": {Name: "payments", Images: images}}},
+			},
+		}
+		content, err := json.Marshal(map[string]interface{}{"schemaVersion": 2, "ImageScan": report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(reportFile, content, 0644)).To(Succeed())
+	}
+
+	get := func(path string, body interface{}) int {
+		resp, err := http.Get(server.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		if body != nil {
+			Expect(json.NewDecoder(resp.Body).Decode(body)).To(Succeed())
+		}
+		return resp.StatusCode
+	}
+
+	BeforeEach(func() {
+		reportFile = filepath.Join(GinkgoT().TempDir(), "report.json")
+		server = httptest.NewServer(New(reportFile).Handler())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("is not ready until a report is saved", func() {
+		Expect(get("/health", nil)).To(Equal(http.StatusNoContent))
+		Expect(get("/ready", nil)).To(Equal(http.StatusServiceUnavailable))
+		Expect(get("/api/v1/report", nil)).To(Equal(http.StatusServiceUnavailable))
+
+		saveReport("nginx:1.25")
+
+		Expect(get("/ready", nil)).To(Equal(http.StatusNoContent))
+	})
+
+	It("serves the report, its images and its teams", func() {
+		saveReport("nginx:1.25", "registry.example.com/payments/api:1.0")
+
+		var report scanner.VulnerabilityReport
+		Expect(get("/api/v1/report", &report)).To(Equal(http.StatusOK))
+		Expect(report.ScannedImages).To(HaveLen(2))
+
+		var image scanner.ScannedImage
+		Expect(get("/api/v1/images/registry.example.com/payments/api:1.0", &image)).To(Equal(http.StatusOK))
+		Expect(image.ImageName).To(Equal("registry.example.com/payments/api:1.0"))
+
+		var teamReport scanner.VulnerabilityReport
+		Expect(get("/api/v1/teams/payments", &teamReport)).To(Equal(http.StatusOK))
+		Expect(teamReport.AreaSummary["finance"].Teams["payments"].Images).To(HaveLen(2))
+
+		var notFound struct{ Error string }
+		Expect(get("/api/v1/images/redis:7", &notFound)).To(Equal(http.StatusNotFound))
+		Expect(notFound.Error).To(Equal("image redis:7 not found in the report"))
+		Expect(get("/api/v1/teams/orders", nil)).To(Equal(http.StatusNotFound))
+	})
+
+	It("reloads the report when the scan saves a new one", func() {
+		saveReport("nginx:1.25")
+		Expect(get("/api/v1/images/nginx:1.25", nil)).To(Equal(http.StatusOK))
+
+		saveReport("nginx:1.26")
+		later := time.Now().Add(time.Minute)
+		Expect(os.Chtimes(reportFile, later, later)).To(Succeed())
+
+		Expect(get("/api/v1/images/nginx:1.25", nil)).To(Equal(http.StatusNotFound))
+		Expect(get("/api/v1/images/nginx:1.26", nil)).To(Equal(http.StatusOK))
+	})
+
+	It("keeps serving the previous report when the new one cannot be loaded", func() {
+		saveReport("nginx:1.25")
+		Expect(get("/api/v1/images/nginx:1.25", nil)).To(Equal(http.StatusOK))
+
+		Expect(os.WriteFile(reportFile, []byte(`{"ImageScan": `), 0644)).To(Succeed())
+		later := time.Now().Add(time.Minute)
+		Expect(os.Chtimes(reportFile, later, later)).To(Succeed())
+
+		Expect(get("/api/v1/images/nginx:1.25", nil)).To(Equal(http.StatusOK))
+	})
+
+	It("only allows GET requests", func() {
+		saveReport("nginx:1.25")
+
+		resp, err := http.Post(server.URL+"/api/v1/report", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("serves the OpenAPI definition documenting the endpoints", func() {
+		resp, err := http.Get(server.URL + "/api/v1/openapi.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/yaml"))
+		var spec struct {
+			OpenAPI string                 `json:"openapi"`
+			Paths   map[string]interface{} `json:"paths"`
+		}
+		Expect(yaml.NewYAMLOrJSONDecoder(resp.Body, 4096).Decode(&spec)).To(Succeed())
+
+		Expect(spec.OpenAPI).To(Equal("3.0.3"))
+		Expect(spec.Paths).To(HaveKey("/api/v1/openapi.yaml"))
+		for _, path := range []string{"/api/v1/report", "/api/v1/images/{name}", "/api/v1/teams/{team}", "/api/v1/heatmap", "/api/v1/grafana",
+			"/api/v1/grafana/timeseries", "/api/v1/grafana/top-images", "/api/v1/grafana/metrics", "/api/v1/grafana/query",
+			"/api/v1/status", "/api/v1/scans", "/health", "/ready"} {
+			Expect(spec.Paths).To(HaveKey(path))
+		}
+	})
+})
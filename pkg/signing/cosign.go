@@ -0,0 +1,52 @@
+package signing
+
+import (
+	"fmt"
+	"strings"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+)
+
+// cosign signs and verifies the files with cosign sign-blob and verify-blob, with a cosign key or a KMS key, the
+// password of an encrypted cosign key being read by cosign from the COSIGN_PASSWORD environment variable
+type cosign struct {
+	key           string
+	commandRunner execCmd.CommandRunner
+}
+
+// NewCosignSigner creates a Signer signing with cosign and the key, a path or a KMS URI such as awskms:///alias/reports
+func NewCosignSigner(key string) Signer {
+	return &cosign{key: key, commandRunner: execCmd.NewCommandRunner()}
+}
+
+// NewCosignVerifier creates a Verifier verifying with cosign and the public key, a path, url or KMS URI
+func NewCosignVerifier(key string) Verifier {
+	return &cosign{key: key, commandRunner: execCmd.NewCommandRunner()}
+}
+
+func (c *cosign) Sign(filename string) (string, error) {
+	signatureFilename := SignatureFilename(filename)
+	_, errOutput, err := c.commandRunner.Execute("cosign", []string{"sign-blob", "--yes", "--tlog-upload=false", "--key", c.key, "--output-signature", signatureFilename, filename})
+	if err != nil {
+		return "", fmt.Errorf("could not sign report %s with cosign: %s", filename, cosignError(errOutput, err))
+	}
+	return signatureFilename, nil
+}
+
+func (c *cosign) Verify(filename, signatureFilename string) error {
+	_, errOutput, err := c.commandRunner.Execute("cosign", []string{"verify-blob", "--insecure-ignore-tlog", "--key", c.key, "--signature", signatureFilename, filename})
+	if err != nil {
+		return fmt.Errorf("invalid signature: %s", cosignError(errOutput, err))
+	}
+	return nil
+}
+
+// cosignError returns the last line of the cosign error output, cosign printing the cause of the failure last
+func cosignError(errOutput []byte, err error) string {
+	lines := strings.Split(strings.TrimSpace(utils.ConvertByteToString(errOutput)), "\n")
+	if reason := strings.TrimSpace(lines[len(lines)-1]); reason != "" {
+		return reason
+	}
+	return err.Error()
+}
@@ -0,0 +1,157 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignatureExtension is the extension of the signature files written next to the signed reports
+const SignatureExtension = ".sig"
+
+// Signer signs the report files, writing the base64 signature of each file next to it
+type Signer interface {
+	// Sign signs the file and returns the name of its signature file
+	Sign(filename string) (string, error)
+}
+
+// Verifier verifies the signature of the report files, so that the consumers of the reports can trust they were not
+// modified since they were generated
+type Verifier interface {
+	Verify(filename, signatureFilename string) error
+}
+
+// SignatureFilename returns the name of the signature file of a report file
+func SignatureFilename(filename string) string {
+	return filename + SignatureExtension
+}
+
+// keySigner signs the files with an ECDSA, Ed25519 or RSA private key, the ECDSA signatures being the ones of
+// cosign sign-blob so that they can also be verified with cosign verify-blob
+type keySigner struct {
+	key crypto.Signer
+}
+
+// keyVerifier verifies the signatures of the files with the public key of a keySigner, or a cosign public key
+type keyVerifier struct {
+	key crypto.PublicKey
+}
+
+// NewKeySigner creates a Signer signing with the unencrypted PEM private key of the file
+func NewKeySigner(privateKeyFile string) (Signer, error) {
+	block, err := readPEM(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key %s in %s, expecting an unencrypted PKCS#8, EC or RSA private key", block.Type, privateKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key %s: %v", privateKeyFile, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T in %s", key, privateKeyFile)
+	}
+	return &keySigner{key: signer}, nil
+}
+
+// NewKeyVerifier creates a Verifier verifying with the PEM public key of the file
+func NewKeyVerifier(publicKeyFile string) (Verifier, error) {
+	block, err := readPEM(publicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported public key %s in %s, expecting a PKIX public key", block.Type, publicKeyFile)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key %s: %v", publicKeyFile, err)
+	}
+	return &keyVerifier{key: key}, nil
+}
+
+func readPEM(filename string) (*pem.Block, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file %s: %v", filename, err)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded key found in %s", filename)
+	}
+	return block, nil
+}
+
+func (s *keySigner) Sign(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("could not read report %s to sign: %v", filename, err)
+	}
+	var signature []byte
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		signature, err = s.key.Sign(rand.Reader, content, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(content)
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not sign report %s: %v", filename, err)
+	}
+	signatureFilename := SignatureFilename(filename)
+	if err := os.WriteFile(signatureFilename, []byte(base64.StdEncoding.EncodeToString(signature)), 0644); err != nil {
+		return "", fmt.Errorf("could not write signature file %s: %v", signatureFilename, err)
+	}
+	return signatureFilename, nil
+}
+
+func (v *keyVerifier) Verify(filename, signatureFilename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("could not read report %s to verify: %v", filename, err)
+	}
+	encodedSignature, err := os.ReadFile(signatureFilename)
+	if err != nil {
+		return fmt.Errorf("could not read signature file %s: %v", signatureFilename, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return fmt.Errorf("invalid signature file %s, expecting a base64 signature: %v", signatureFilename, err)
+	}
+
+	digest := sha256.Sum256(content)
+	var verified bool
+	switch key := v.key.(type) {
+	case *ecdsa.PublicKey:
+		verified = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		verified = ed25519.Verify(key, content, signature)
+	case *rsa.PublicKey:
+		verified = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", v.key)
+	}
+	if !verified {
+		return errors.New("invalid signature, the report was modified or signed with another key")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSigning(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Signing Suite")
+}
+
+var _ = Describe("Report signing", func() {
+
+	var (
+		tmpDir     string
+		reportFile string
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		reportFile = filepath.Join(tmpDir, "report.json")
+		Expect(os.WriteFile(reportFile, []byte(`{"ImageScan":{}}`), 0644)).To(Succeed())
+	})
+
+	writeKeys := func(privateKey crypto.Signer) (string, string) {
+		privateBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		Expect(err).NotTo(HaveOccurred())
+		publicBytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+		Expect(err).NotTo(HaveOccurred())
+		privateKeyFile := filepath.Join(tmpDir, "report.key")
+		publicKeyFile := filepath.Join(tmpDir, "report.pub")
+		Expect(os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes}), 0600)).To(Succeed())
+		Expect(os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes}), 0644)).To(Succeed())
+		return privateKeyFile, publicKeyFile
+	}
+
+	ecdsaKey := func() crypto.Signer {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		return key
+	}
+
+	ed25519Key := func() crypto.Signer {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		return key
+	}
+
+	DescribeTable("signs the reports and verifies their signatures", func(generateKey func() crypto.Signer) {
+		privateKeyFile, publicKeyFile := writeKeys(generateKey())
+		signer, err := NewKeySigner(privateKeyFile)
+		Expect(err).NotTo(HaveOccurred())
+		verifier, err := NewKeyVerifier(publicKeyFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		signatureFile, err := signer.Sign(reportFile)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signatureFile).To(Equal(reportFile + ".sig"))
+		Expect(verifier.Verify(reportFile, signatureFile)).To(Succeed())
+	},
+		Entry("with an ECDSA key", ecdsaKey),
+		Entry("with an Ed25519 key", ed25519Key),
+	)
+
+	It("rejects the modified reports and the signatures of other keys", func() {
+		privateKeyFile, publicKeyFile := writeKeys(ecdsaKey())
+		signer, err := NewKeySigner(privateKeyFile)
+		Expect(err).NotTo(HaveOccurred())
+		signatureFile, err := signer.Sign(reportFile)
+		Expect(err).NotTo(HaveOccurred())
+		verifier, err := NewKeyVerifier(publicKeyFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(reportFile, []byte(`{"ImageScan":null}`), 0644)).To(Succeed())
+		Expect(verifier.Verify(reportFile, signatureFile)).To(MatchError(ContainSubstring("invalid signature")))
+
+		_, otherPublicKeyFile := writeKeys(ecdsaKey())
+		otherVerifier, err := NewKeyVerifier(otherPublicKeyFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherVerifier.Verify(reportFile, signatureFile)).To(MatchError(ContainSubstring("invalid signature")))
+	})
+
+	It("rejects the encrypted private keys", func() {
+		keyFile := filepath.Join(tmpDir, "cosign.key")
+		Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: []byte("key")}), 0600)).To(Succeed())
+
+		_, err := NewKeySigner(keyFile)
+
+		Expect(err).To(MatchError(ContainSubstring("unsupported private key ENCRYPTED SIGSTORE PRIVATE KEY")))
+	})
+
+	Context("with cosign", func() {
+
+		var commandRunner *mockCommandRunner
+
+		BeforeEach(func() {
+			commandRunner = &mockCommandRunner{}
+		})
+
+		It("signs the reports with cosign sign-blob", func() {
+			commandRunner.On("Execute", "cosign", []string{"sign-blob", "--yes", "--tlog-upload=false", "--key", "awskms:///alias/reports", "--output-signature", "report.json.sig", "report.json"}).
+				Return([]byte{}, []byte{}, nil)
+			signer := &cosign{key: "awskms:///alias/reports", commandRunner: commandRunner}
+
+			signatureFile, err := signer.Sign("report.json")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signatureFile).To(Equal("report.json.sig"))
+		})
+
+		It("returns the cosign error when the signature is not verified", func() {
+			commandRunner.On("Execute", "cosign", []string{"verify-blob", "--insecure-ignore-tlog", "--key", "cosign.pub", "--signature", "report.json.sig", "report.json"}).
+				Return([]byte{}, []byte("Error: verifying blob\nmain.go:74: error during command execution: invalid signature when validating ASN.1 encoded signature\n"), errors.New("exit status 1"))
+			verifier := &cosign{key: "cosign.pub", commandRunner: commandRunner}
+
+			err := verifier.Verify("report.json", "report.json.sig")
+
+			Expect(err).To(MatchError("invalid signature: main.go:74: error during command execution: invalid signature when validating ASN.1 encoded signature"))
+		})
+	})
+})
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
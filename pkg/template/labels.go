@@ -0,0 +1,92 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// severityNames are the severities the labels can rename
+var severityNames = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// ReportLabels customise the headings and the severity names of the reports, for instance to localise them or to name the
+// severities with an internal risk nomenclature. The headings and severities without label are rendered as is
+type ReportLabels struct {
+	// Headings are the labels of the report headings by their English heading, for instance
+	// "Top vulnerable images": "Images les plus vulnérables"
+	Headings map[string]string `json:"headings"`
+	// Severities are the labels of the severities, for instance CRITICAL: P1
+	Severities map[string]string `json:"severities"`
+}
+
+// labels are the labels of the reports rendered by GenerateReport, see SetReportLabels
+var labels *ReportLabels
+
+// SetReportLabels sets the labels of the reports rendered afterwards, the reports being rendered in English when nil
+func SetReportLabels(reportLabels *ReportLabels) {
+	labels = reportLabels
+}
+
+// LoadReportLabels reads the report labels from a yaml or json file such as:
+//
+//	headings:
+//	  Top vulnerable images: Images les plus vulnérables
+//	severities:
+//	  CRITICAL: P1
+//	  HIGH: P2
+func LoadReportLabels(filename string) (*ReportLabels, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read labels file %s: %v", filename, err)
+	}
+	var reportLabels ReportLabels
+	if err := yaml.Unmarshal(content, &reportLabels); err != nil {
+		return nil, fmt.Errorf("error while decoding labels file %s: %v", filename, err)
+	}
+	for severity := range reportLabels.Severities {
+		if !validSeverity(severity) {
+			return nil, fmt.Errorf("labels file %s has an invalid severity %q, permitted values: %s", filename, severity, strings.Join(severityNames, ", "))
+		}
+	}
+	return &reportLabels, nil
+}
+
+// heading returns the label of the heading, the heading itself when it has no label
+func (l *ReportLabels) heading(heading string) string {
+	if l != nil {
+		if label, ok := l.Headings[heading]; ok {
+			return label
+		}
+	}
+	return heading
+}
+
+// severity returns the label of the severity, the severity itself when it has no label
+func (l *ReportLabels) severity(severity string) string {
+	if l != nil {
+		if label, ok := l.Severities[severity]; ok {
+			return label
+		}
+	}
+	return severity
+}
+
+// severityTitle returns the label of the severity for the table headers, the capitalised severity when it has no
+// label, for instance Critical
+func (l *ReportLabels) severityTitle(severity string) string {
+	if label := l.severity(severity); label != severity || severity == "" {
+		return label
+	}
+	return severity[:1] + strings.ToLower(severity[1:])
+}
+
+func validSeverity(severity string) bool {
+	for _, name := range severityNames {
+		if name == severity {
+			return true
+		}
+	}
+	return false
+}
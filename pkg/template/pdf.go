@@ -0,0 +1,37 @@
+package template
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/utils"
+	logr "github.com/sirupsen/logrus"
+)
+
+// DefaultPDFConverterCommand is the HTML to PDF converter used when none is specified, see https://wkhtmltopdf.org
+const DefaultPDFConverterCommand = "wkhtmltopdf"
+
+// PDFConverter renders HTML reports as PDF documents, for audits requiring immutable artifacts
+type PDFConverter struct {
+	command       string
+	commandRunner execCmd.CommandRunner
+}
+
+// NewPDFConverter creates a PDFConverter running the wkhtmltopdf compatible command
+func NewPDFConverter(command string) *PDFConverter {
+	return &PDFConverter{command: command, commandRunner: execCmd.NewCommandRunner()}
+}
+
+// Convert renders the HTML file as a PDF file with the same name and the .pdf extension, and returns the PDF filename
+func (c *PDFConverter) Convert(htmlFilename string) (string, error) {
+	pdfFilename := strings.TrimSuffix(htmlFilename, filepath.Ext(htmlFilename)) + ".pdf"
+	logr.Infof("Converting report %s to PDF", htmlFilename)
+	_, errOutput, err := c.commandRunner.Execute(c.command, []string{"--quiet", htmlFilename, pdfFilename})
+	if err != nil {
+		return "", fmt.Errorf("error while converting report %s to PDF with %s. Error output: %s, Error: %v", htmlFilename, c.command, utils.ConvertByteToString(errOutput), err)
+	}
+	logr.Infof("Generated report file: %s", pdfFilename)
+	return pdfFilename, nil
+}
@@ -0,0 +1,56 @@
+package template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PDF converter", func() {
+
+	var (
+		commandRunner *mockCommandRunner
+		converter     *PDFConverter
+	)
+
+	BeforeEach(func() {
+		commandRunner = &mockCommandRunner{}
+		converter = &PDFConverter{command: DefaultPDFConverterCommand, commandRunner: commandRunner}
+	})
+
+	It("converts the HTML report to a PDF file with the same name", func() {
+		commandRunner.On("Execute", "wkhtmltopdf", []string{"--quiet", "reports/report-CIS.html", "reports/report-CIS.pdf"}).
+			Return([]byte{}, []byte{}, nil)
+
+		pdfFilename, err := converter.Convert("reports/report-CIS.html")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pdfFilename).To(Equal("reports/report-CIS.pdf"))
+	})
+
+	It("returns the converter error output", func() {
+		commandRunner.On("Execute", "wkhtmltopdf", []string{"--quiet", "report.html", "report.pdf"}).
+			Return([]byte{}, []byte("cannot open report.html"), fmt.Errorf("exit status 1"))
+
+		_, err := converter.Convert("report.html")
+
+		Expect(err).To(MatchError(ContainSubstring("error while converting report report.html to PDF with wkhtmltopdf. Error output: cannot open report.html")))
+	})
+})
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
@@ -0,0 +1,119 @@
+package template
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ReportRunbooks link the checks and the vulnerabilities of the reports to the internal runbooks remediating them, so
+// that the teams reading the reports get direct remediation guidance
+type ReportRunbooks struct {
+	// Checks are the runbook URLs by readiness check name or compliance control id, for instance probes or 1.2.1
+	Checks map[string]string `json:"checks"`
+	// Vulnerabilities are the runbooks of classes of vulnerabilities, the runbook of a vulnerability being the first one
+	// matching it
+	Vulnerabilities []VulnerabilityRunbook `json:"vulnerabilities"`
+}
+
+// VulnerabilityRunbook is the runbook of the vulnerabilities matching all its non empty lists of path.Match patterns
+type VulnerabilityRunbook struct {
+	// IDs are the patterns of the vulnerability ids, for instance CVE-2021-44228 or GHSA-*
+	IDs []string `json:"ids"`
+	// Packages are the patterns of the vulnerable package names, for instance openssl or libssl*
+	Packages []string `json:"packages"`
+	// TargetTypes are the patterns of the types of the targets the vulnerabilities are found in, os-pkgs for the
+	// packages of the OS layer or the language ecosystem, for instance gobinary or npm
+	TargetTypes []string `json:"targetTypes"`
+	URL         string   `json:"url"`
+}
+
+// runbooks are the runbooks of the reports rendered by GenerateReport, see SetReportRunbooks
+var runbooks *ReportRunbooks
+
+// SetReportRunbooks sets the runbooks of the reports rendered afterwards, the reports linking no runbook when nil
+func SetReportRunbooks(reportRunbooks *ReportRunbooks) {
+	runbooks = reportRunbooks
+}
+
+// LoadReportRunbooks reads the report runbooks from a yaml or json file such as:
+//
+//	checks:
+//	  probes: https://wiki.example.com/runbooks/probes
+//	vulnerabilities:
+//	  - packages: [openssl, libssl*]
+//	    url: https://wiki.example.com/runbooks/openssl
+//	  - targetTypes: [os-pkgs]
+//	    url: https://wiki.example.com/runbooks/base-images
+func LoadReportRunbooks(filename string) (*ReportRunbooks, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read runbooks file %s: %v", filename, err)
+	}
+	var reportRunbooks ReportRunbooks
+	if err := yaml.Unmarshal(content, &reportRunbooks); err != nil {
+		return nil, fmt.Errorf("error while decoding runbooks file %s: %v", filename, err)
+	}
+	for check, runbookURL := range reportRunbooks.Checks {
+		if err := validRunbookURL(runbookURL); err != nil {
+			return nil, fmt.Errorf("runbooks file %s has an invalid runbook for check %q: %v", filename, check, err)
+		}
+	}
+	for i, runbook := range reportRunbooks.Vulnerabilities {
+		if err := validRunbookURL(runbook.URL); err != nil {
+			return nil, fmt.Errorf("runbooks file %s has an invalid vulnerability runbook #%d: %v", filename, i+1, err)
+		}
+		for _, pattern := range append(append(append([]string{}, runbook.IDs...), runbook.Packages...), runbook.TargetTypes...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("runbooks file %s has an invalid pattern %q in vulnerability runbook #%d: %v", filename, pattern, i+1, err)
+			}
+		}
+	}
+	return &reportRunbooks, nil
+}
+
+// check returns the runbook URL of the check, empty when the check has no runbook
+func (r *ReportRunbooks) check(check string) string {
+	if r == nil {
+		return ""
+	}
+	return r.Checks[check]
+}
+
+// vulnerability returns the URL of the first runbook matching the vulnerability, empty when none does. The target
+// type is empty when unknown, only the runbooks without target types matching the vulnerability then
+func (r *ReportRunbooks) vulnerability(vulnerabilityID, pkgName, targetType string) string {
+	if r == nil {
+		return ""
+	}
+	for _, runbook := range r.Vulnerabilities {
+		if matchesAny(runbook.IDs, vulnerabilityID) && matchesAny(runbook.Packages, pkgName) && matchesAny(runbook.TargetTypes, targetType) {
+			return runbook.URL
+		}
+	}
+	return ""
+}
+
+// matchesAny returns true when the value matches one of the patterns or when there is no pattern
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return len(patterns) == 0
+}
+
+func validRunbookURL(runbookURL string) error {
+	parsed, err := url.Parse(runbookURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", runbookURL)
+	}
+	return nil
+}
@@ -3,56 +3,54 @@ package template
 import (
 	"encoding/json"
 	"fmt"
-	"html/template"
+	htmltemplate "html/template"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	texttemplate "text/template"
 
 	logr "github.com/sirupsen/logrus"
 )
 
-// GenerateReportFromTemplate - Generate the report based on the given template file
-func GenerateReportFromTemplate(report interface{}, templateFilename string, reportDir string, reportOutputFilename string) error {
-	logr.Infof("Generating report based on template %s", templateFilename)
-	tmp := template.New(filepath.Base(templateFilename))
-	tmp.Funcs(template.FuncMap{
-		"safe": func(s string) template.HTML { return template.HTML(s) },
-	})
-	tmp.Funcs(template.FuncMap{"inc": func(i int) int { return i + 1 }})
-	tmp.Funcs(template.FuncMap{"replace": func(str string, from string, to string) string { return strings.Replace(str, from, to, -1) }})
-	tmp.Funcs(template.FuncMap{"mod": func(i, j int) bool { return i%j == 0 }})
-	tmp.Funcs(template.FuncMap{"truncate": func(s string, i int) string {
-		runes := []rune(s)
-		if len(runes) > i {
-			return fmt.Sprintf("%s...", string(runes[:i]))
-		}
-		return s
-	}})
-	tmp.Funcs(template.FuncMap{"modsub": func(index, tabSize, additional, modulo int) bool {
+// Engine is the Go template package used to render a report
+type Engine string
 
-		if (index+additional)%modulo == 0 {
-			return true
-		}
-		if additional == 1 {
-			if (tabSize-1)-index == 0 {
-				return true
-			}
-		}
-
-		return false
-
-	}})
-	tmp.Funcs(template.FuncMap{"mods": func(index, additional, modulo int) bool {
-
-		if (index+additional)%modulo == 0 {
-			return true
-		}
+const (
+	// HTMLEngine renders reports with html/template, escaping the report data for HTML
+	HTMLEngine Engine = "html"
+	// TextEngine renders reports with text/template, the report data is written as is
+	TextEngine Engine = "text"
+)
 
-		return false
+// Engines lists the supported template engines
+var Engines = []Engine{HTMLEngine, TextEngine}
 
-	}})
-	tmpl, err := tmp.ParseFiles(templateFilename)
+// GenerateReportFromTemplate - Generate the report based on the given template file
+func GenerateReportFromTemplate(report interface{}, templateFilename string, reportDir string, reportOutputFilename string) error {
+	return GenerateReport(report, templateFilename, HTMLEngine, reportDir, reportOutputFilename)
+}
 
+// GenerateReport generates the report based on the given template file, executed with the given template engine
+func GenerateReport(report interface{}, templateFilename string, engine Engine, reportDir string, reportOutputFilename string) error {
+	templateFilename = templateFile(templateFilename)
+	logr.Infof("Generating report based on %s template %s", engine, templateFilename)
+	var tmpl interface {
+		Execute(w io.Writer, data interface{}) error
+	}
+	var err error
+	switch engine {
+	case HTMLEngine:
+		funcs := templateFuncs()
+		funcs["safe"] = func(s string) htmltemplate.HTML { return htmltemplate.HTML(s) }
+		tmpl, err = htmltemplate.New(filepath.Base(templateFilename)).Funcs(funcs).ParseFiles(templateFilename)
+	case TextEngine:
+		funcs := templateFuncs()
+		funcs["safe"] = func(s string) string { return s }
+		tmpl, err = texttemplate.New(filepath.Base(templateFilename)).Funcs(funcs).ParseFiles(templateFilename)
+	default:
+		return fmt.Errorf("unsupported template engine %q, supported engines are %v", engine, Engines)
+	}
 	if err != nil {
 		return err
 	}
@@ -61,6 +59,7 @@ func GenerateReportFromTemplate(report interface{}, templateFilename string, rep
 	if err != nil {
 		return fmt.Errorf("could not create report file %s: %v", reportDir+reportOutputFilename, err)
 	}
+	defer reportFile.Close()
 
 	err = tmpl.Execute(reportFile, report)
 	if err != nil {
@@ -70,6 +69,70 @@ func GenerateReportFromTemplate(report interface{}, templateFilename string, rep
 	return nil
 }
 
+// templateFuncs returns the functions available to both template engines
+func templateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"inc":        func(i int) int { return i + 1 },
+		"replace":    func(str string, from string, to string) string { return strings.Replace(str, from, to, -1) },
+		"mod":        func(i, j int) bool { return i%j == 0 },
+		"severities": func() []string { return []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"} },
+		// label, severity and severityTitle render the headings and the severities with the labels, see SetReportLabels
+		"label":         labels.heading,
+		"severity":      labels.severity,
+		"severityTitle": labels.severityTitle,
+		// checkRunbook and vulnerabilityRunbook return the URLs of the runbooks, empty without runbook, see SetReportRunbooks
+		"checkRunbook":         runbooks.check,
+		"vulnerabilityRunbook": runbooks.vulnerability,
+		"join":                 func(values []string) string { return strings.Join(values, ", ") },
+		"bytes":                formatBytes,
+		"percent":              func(ratio float64) float64 { return ratio * 100 },
+		"truncate": func(s string, i int) string {
+			runes := []rune(s)
+			if len(runes) > i {
+				return fmt.Sprintf("%s...", string(runes[:i]))
+			}
+			return s
+		},
+		"modsub": func(index, tabSize, additional, modulo int) bool {
+
+			if (index+additional)%modulo == 0 {
+				return true
+			}
+			if additional == 1 {
+				if (tabSize-1)-index == 0 {
+					return true
+				}
+			}
+
+			return false
+
+		},
+		"mods": func(index, additional, modulo int) bool {
+
+			if (index+additional)%modulo == 0 {
+				return true
+			}
+
+			return false
+
+		},
+	}
+}
+
+// formatBytes formats a size in bytes with a binary unit, for instance 1.5 GiB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exponent := float64(size)/unit, 0
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exponent])
+}
+
 // SaveReport - SaveReport
 func SaveReport(report interface{}, filename string) error {
 	logr.Infof("Saving report to: %s", filename)
@@ -91,3 +154,24 @@ func SaveReport(report interface{}, filename string) error {
 
 	return nil
 }
+
+// templateFile returns the path of the template file, looked up next to the executable when it is not found in the
+// working directory, as when run as a kubectl plugin installed by krew with the templates alongside the binary
+func templateFile(templateFilename string) string {
+	if _, err := os.Stat(templateFilename); err == nil || filepath.IsAbs(templateFilename) {
+		return templateFilename
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return templateFilename
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	if candidate := filepath.Join(filepath.Dir(executable), templateFilename); candidate != templateFilename {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return templateFilename
+}
@@ -8,7 +8,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/coreeng/production-readiness/production-readiness/pkg/checks"
 	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
 
 	logr "github.com/sirupsen/logrus"
@@ -60,6 +62,403 @@ var _ = Describe("Generating vulnerability report", func() {
 		Expect(fileContentEqual("expected-test-report-imageScan.html", actualReportFile, "-B", "-w")).To(BeTrue())
 	})
 
+	Context("custom template", func() {
+		var customTemplate string
+
+		BeforeEach(func() {
+			customTemplate = filepath.Join(tmpDir, "custom.tmpl")
+			content := `{{ range .ImageScan.ScannedImages }}{{ .ImageName }} {{ printf "<%d>" (index .VulnerabilitySummary.TotalVulnerabilityBySeverity "HIGH") }}{{ "\n" }}{{ end }}`
+			Expect(os.WriteFile(customTemplate, []byte(content), 0644)).To(Succeed())
+		})
+
+		It("should not escape the report data with the text engine", func() {
+			actualReportFile := filepath.Join(tmpDir, "actual-report.txt")
+			err := GenerateReport(aReport(), customTemplate, TextEngine, "", actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(actualReportFile)).To(Equal([]byte("debian:latest <10>\nalpine:latest <0>\nubuntu:18.04 <2>\n")))
+		})
+
+		It("should escape the report data with the html engine", func() {
+			actualReportFile := filepath.Join(tmpDir, "actual-report.html")
+			err := GenerateReport(aReport(), customTemplate, HTMLEngine, "", actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(actualReportFile)).To(ContainSubstring("debian:latest &lt;10&gt;"))
+		})
+
+		It("should reject unknown engines", func() {
+			err := GenerateReport(aReport(), customTemplate, Engine("pdf"), "", filepath.Join(tmpDir, "actual-report"))
+			Expect(err).To(MatchError(ContainSubstring("unsupported template engine \"pdf\"")))
+		})
+	})
+
+	Context("images skipped as larger than the maximum image size", func() {
+		It("should list the skipped images with their size", func() {
+			skippedImage := scanner.NewSkippedImage("big:1.0", nil, 1610612736)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{skippedImage},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{skippedImage}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### Skipped images"))
+			Expect(string(content)).To(ContainSubstring("- big:1.0 (1.5 GiB)"))
+			Expect(string(content)).NotTo(ContainSubstring("| big:1.0 |"))
+		})
+	})
+
+	Context("images whose scan timed out", func() {
+		It("should report the timed out scans with their partial results", func() {
+			timedOutImage := scanner.NewScannedImage("big:1.0", nil, []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-0001", Severity: "HIGH"},
+			}}}, &scanner.ScanTimeoutError{Image: "big:1.0", Timeout: 5 * time.Minute})
+			timedOutImage.TimedOut = true
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{timedOutImage},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{timedOutImage}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("**Timed out scans:** the scan of 1 image(s) timed out"))
+			Expect(string(content)).To(ContainSubstring("- trivy scan of image big:1.0 timed out after 5m0s"))
+			Expect(string(content)).To(ContainSubstring("| big:1.0 (timed out, partial results) | 0 | 0 | 1 |"))
+		})
+	})
+
+	Context("images running an end-of-life operating system", func() {
+		It("should list the images with their operating system", func() {
+			endOfLifeImage := scanner.ScannedImage{ImageName: "debian:9", OS: &scanner.OS{Family: "debian", Name: "9.13", EOSL: true}}
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{endOfLifeImage},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{endOfLifeImage}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### End-of-life operating systems"))
+			Expect(string(content)).To(ContainSubstring("- debian:9 (debian 9.13)"))
+		})
+	})
+
+	Context("images with stale results", func() {
+		It("should list the images with their scan time and database version", func() {
+			scanTime, dbUpdatedAt := time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), time.Date(2023, 8, 31, 6, 0, 0, 0, time.UTC)
+			staleImage := scanner.NewScannedImage("app:1", nil, nil, nil)
+			staleImage.ScanTime, staleImage.TrivyDBVersion, staleImage.TrivyDBUpdatedAt, staleImage.Stale = &scanTime, 2, &dbUpdatedAt, true
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{staleImage},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{staleImage}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### Stale scan results"))
+			Expect(string(content)).To(ContainSubstring("- app:1 (scanned 2023-09-01 10:00 UTC, trivy DB version 2 updated 2023-08-31 06:00 UTC)"))
+			Expect(string(content)).To(ContainSubstring("| app:1 (stale results) |"))
+		})
+	})
+
+	Context("incremental report", func() {
+		It("should state the time since which the pods of the reported images changed", func() {
+			changedSince := time.Date(2023, 9, 7, 10, 0, 0, 0, time.UTC)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					Metadata:    scanner.ReportMetadata{ChangedSince: &changedSince},
+					AreaSummary: map[string]*scanner.AreaSummary{},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("**Incremental report:** only the images of the pods created or updated since 2023-09-07 10:00 UTC are reported."))
+		})
+	})
+
+	Context("triaged findings", func() {
+		It("should list the triaged vulnerabilities with their state and flag them in the details", func() {
+			triagedAt := time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+			image := scanner.NewScannedImage("app:1", nil, []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-4911", Severity: "HIGH", PkgName: "libc6",
+					Triage: &scanner.FindingTriage{VulnerabilityID: "CVE-2023-4911", Digest: "sha256:app", State: scanner.TriageFalsePositive, Reason: "no setuid binary", TriagedAt: triagedAt}},
+			}}}, nil)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{image},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{image}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### Triaged findings"))
+			Expect(string(content)).To(ContainSubstring("| app:1 | CVE-2023-4911 | libc6 | HIGH | false-positive | no setuid binary | 2023-09-01 |"))
+			Expect(string(content)).To(ContainSubstring("(https://nvd.nist.gov/vuln/detail/CVE-2023-4911) (false-positive) |"))
+		})
+	})
+
+	Context("markdown report for pull requests", func() {
+		It("should summarise the teams in a table and collapse the details of each team", func() {
+			image := scanner.NewScannedImage("app:1", nil, []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-4911", Severity: "HIGH", PkgName: "libc6", InstalledVersion: "2.36-9", FixedVersion: "2.36-9+deb12u3", Title: "glibc: buffer overflow | Looney Tunables"},
+			}}}, nil)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					Metadata:      scanner.ReportMetadata{ClusterName: "prod"},
+					ScannedImages: []scanner.ScannedImage{image},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"payments": {Name: "payments", Teams: map[string]*scanner.TeamSummary{"checkout": {Name: "checkout", ImageCount: 1, Images: []scanner.ScannedImage{image}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-pr.md.tmpl")
+
+			err := GenerateReport(report, reportTemplate, TextEngine, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("## Vulnerability Report - prod\n"))
+			Expect(string(content)).To(ContainSubstring("| payments | checkout | 1 | 0 | 1 | 0 | 0 | 0 | 1 |"))
+			Expect(string(content)).To(ContainSubstring("<details>\n<summary><b>payments - checkout</b>: 0 CRITICAL, 1 HIGH</summary>\n\n"))
+			Expect(string(content)).To(ContainSubstring("| `app:1` | [CVE-2023-4911](https://nvd.nist.gov/vuln/detail/CVE-2023-4911) | HIGH | libc6 | 2.36-9 | 2.36-9+deb12u3 | glibc: buffer overflow \\| Looney Tunables |"))
+			Expect(string(content)).To(HaveSuffix("</details>\n"))
+		})
+	})
+
+	Context("report labels", func() {
+		AfterEach(func() {
+			SetReportLabels(nil)
+		})
+
+		It("should render the headings and the severities with their labels in the md and html reports", func() {
+			labelsFile := filepath.Join(tmpDir, "labels.yaml")
+			Expect(os.WriteFile(labelsFile, []byte("headings:\n  Top vulnerable images: Images les plus vulnérables\nseverities:\n  CRITICAL: P1\n  HIGH: P2\n"), 0644)).To(Succeed())
+			labels, err := LoadReportLabels(labelsFile)
+			Expect(err).NotTo(HaveOccurred())
+			SetReportLabels(labels)
+
+			for _, templateFile := range []string{"report-imageScan.md.tmpl", "report-imageScan.html.tmpl"} {
+				actualReportFile := filepath.Join(tmpDir, "actual-"+templateFile)
+				err = GenerateReportFromTemplate(aReport(), filepath.Join(findProjectDir(), "templates", templateFile), "", actualReportFile)
+
+				Expect(err).NotTo(HaveOccurred())
+				content, err := os.ReadFile(actualReportFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("Images les plus vulnérables"))
+				Expect(string(content)).NotTo(ContainSubstring("Top vulnerable images"))
+				Expect(string(content)).To(ContainSubstring("P1"))
+				Expect(string(content)).To(ContainSubstring("Medium"))
+				Expect(string(content)).NotTo(ContainSubstring("CRITICAL"))
+			}
+		})
+
+		It("should reject the labels of unknown severities", func() {
+			labelsFile := filepath.Join(tmpDir, "labels.yaml")
+			Expect(os.WriteFile(labelsFile, []byte("severities:\n  BLOCKER: P0\n"), 0644)).To(Succeed())
+
+			_, err := LoadReportLabels(labelsFile)
+
+			Expect(err).To(MatchError(ContainSubstring(`has an invalid severity "BLOCKER", permitted values: CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN`)))
+		})
+	})
+
+	Context("report runbooks", func() {
+		AfterEach(func() {
+			SetReportRunbooks(nil)
+		})
+
+		It("should link the checks and the vulnerabilities to the first runbook matching them", func() {
+			runbooksFile := filepath.Join(tmpDir, "runbooks.yaml")
+			Expect(os.WriteFile(runbooksFile, []byte(`checks:
+  network-policy: https://wiki.example.com/runbooks/network-policy
+vulnerabilities:
+  - packages: [log4j-*]
+    url: https://wiki.example.com/runbooks/log4j
+  - targetTypes: [os-pkgs]
+    url: https://wiki.example.com/runbooks/base-images
+`), 0644)).To(Succeed())
+			runbooks, err := LoadReportRunbooks(runbooksFile)
+			Expect(err).NotTo(HaveOccurred())
+			SetReportRunbooks(runbooks)
+			image := scanner.NewScannedImage("app:1", nil, []scanner.TrivyOutputResults{
+				{Class: "os-pkgs", Type: "debian", Vulnerabilities: []scanner.Vulnerabilities{{VulnerabilityID: "CVE-2023-4911", Severity: "HIGH", PkgName: "libc6"}}},
+				{Class: "lang-pkgs", Type: "jar", Vulnerabilities: []scanner.Vulnerabilities{
+					{VulnerabilityID: "CVE-2021-44228", Severity: "CRITICAL", PkgName: "log4j-core"},
+					{VulnerabilityID: "CVE-2022-1471", Severity: "HIGH", PkgName: "snakeyaml"},
+				}},
+			}, nil)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{image},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{image}}}},
+					},
+				},
+			}
+
+			Expect(GenerateReportFromTemplate(report, filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl"), "", filepath.Join(tmpDir, "report.md"))).To(Succeed())
+			Expect(GenerateReportFromTemplate(report, filepath.Join(findProjectDir(), "templates/report-imageScan.html.tmpl"), "", filepath.Join(tmpDir, "report.html"))).To(Succeed())
+			Expect(GenerateReportFromTemplate(aChecksReport(), filepath.Join(findProjectDir(), "templates/report-checks.md.tmpl"), "", filepath.Join(tmpDir, "checks.md"))).To(Succeed())
+
+			markdown, err := os.ReadFile(filepath.Join(tmpDir, "report.md"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(markdown)).To(ContainSubstring("[CVE-2023-4911](https://nvd.nist.gov/vuln/detail/CVE-2023-4911) ([runbook](https://wiki.example.com/runbooks/base-images)) |"))
+			Expect(string(markdown)).To(ContainSubstring("[CVE-2021-44228](https://nvd.nist.gov/vuln/detail/CVE-2021-44228) ([runbook](https://wiki.example.com/runbooks/log4j)) |"))
+			Expect(string(markdown)).To(ContainSubstring("[CVE-2022-1471](https://nvd.nist.gov/vuln/detail/CVE-2022-1471) |"))
+			html, err := os.ReadFile(filepath.Join(tmpDir, "report.html"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(html)).To(ContainSubstring(`CVE-2021-44228</a> <a href="https://wiki.example.com/runbooks/log4j">runbook</a>`))
+			checksMarkdown, err := os.ReadFile(filepath.Join(tmpDir, "checks.md"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(checksMarkdown)).To(ContainSubstring("#### network-policy ([runbook](https://wiki.example.com/runbooks/network-policy))\n"))
+		})
+
+		It("should reject the runbooks without http URL", func() {
+			runbooksFile := filepath.Join(tmpDir, "runbooks.yaml")
+			Expect(os.WriteFile(runbooksFile, []byte("checks:\n  probes: wiki/probes\n"), 0644)).To(Succeed())
+
+			_, err := LoadReportRunbooks(runbooksFile)
+
+			Expect(err).To(MatchError(ContainSubstring(`has an invalid runbook for check "probes": "wiki/probes" is not an http or https URL`)))
+		})
+	})
+
+	Context("vulnerabilities attributed to the image layers", func() {
+		It("should count the vulnerabilities of the base image and application layers and flag them in the details", func() {
+			image := scanner.NewScannedImage("app:1", nil, []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-4911", Severity: "HIGH", PkgName: "libc6", LayerOrigin: scanner.LayerBaseImage},
+				{VulnerabilityID: "CVE-2021-44228", Severity: "CRITICAL", PkgName: "log4j-core", LayerOrigin: scanner.LayerApplication},
+				{VulnerabilityID: "CVE-2022-1471", Severity: "HIGH", PkgName: "snakeyaml", LayerOrigin: scanner.LayerApplication},
+			}}}, nil)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{image},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{image}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### Base image and application layers"))
+			Expect(string(content)).To(ContainSubstring("- app:1: 1 vulnerabilities in the base image layers, 2 in the application layers"))
+			Expect(string(content)).To(ContainSubstring("| libc6 (base-image layer) |"))
+			Expect(string(content)).To(ContainSubstring("| log4j-core (application layer) |"))
+		})
+	})
+
+	Context("known exploited vulnerabilities", func() {
+		It("should list the known exploited vulnerabilities first and flag them in the details", func() {
+			image := scanner.NewScannedImage("app:1", nil, []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{{
+				VulnerabilityID: "CVE-2021-44228", Severity: "CRITICAL", PkgName: "log4j-core", FixedVersion: "2.15.0",
+				KnownExploited: &scanner.KnownExploitedVulnerability{CveID: "CVE-2021-44228", KnownRansomwareCampaignUse: "Known", RequiredAction: "Apply updates per vendor instructions."},
+			}}}}, nil)
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{image},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{image}}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### Known exploited vulnerabilities"))
+			Expect(string(content)).To(ContainSubstring("| CRITICAL | log4j-core | 2.15.0 | Known | Apply updates per vendor instructions. |"))
+			Expect(string(content)).To(ContainSubstring("(https://nvd.nist.gov/vuln/detail/CVE-2021-44228) **known exploited** |"))
+		})
+	})
+
+	Context("severity budgets", func() {
+		It("should show the budget consumption of the teams", func() {
+			image := scanner.NewScannedImage("app:1", nil, []scanner.TrivyOutputResults{{Vulnerabilities: []scanner.Vulnerabilities{
+				{VulnerabilityID: "CVE-2023-0001", Severity: "CRITICAL"}, {VulnerabilityID: "CVE-2023-0002", Severity: "HIGH"},
+			}}}, nil)
+			budget := &scanner.BudgetConsumption{Lines: []scanner.BudgetLine{{Severity: "CRITICAL", Count: 1, Budget: 0}, {Severity: "HIGH", Count: 1, Budget: 4}}}
+			report := &TestReport{
+				ImageScan: &scanner.VulnerabilityReport{
+					ScannedImages: []scanner.ScannedImage{image},
+					AreaSummary: map[string]*scanner.AreaSummary{
+						"all": {Name: "all", Teams: map[string]*scanner.TeamSummary{"all": {Name: "all", Images: []scanner.ScannedImage{image}, Budget: budget}}},
+					},
+				},
+			}
+			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+			reportTemplate := filepath.Join(findProjectDir(), "templates/report-imageScan.md.tmpl")
+
+			err := GenerateReportFromTemplate(report, reportTemplate, "", actualReportFile)
+
+			Expect(err).NotTo(HaveOccurred())
+			content, err := os.ReadFile(actualReportFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("#### Severity budget"))
+			Expect(string(content)).To(ContainSubstring("| CRITICAL | 1 | 0 | - **exceeded** |"))
+			Expect(string(content)).To(ContainSubstring("| HIGH | 1 | 4 | 25% |"))
+		})
+	})
+
 	Context("error occurred during image scanning", func() {
 		It("should report the errors according to the md template file", func() {
 			actualReportFile := filepath.Join(tmpDir, "actual-report.md")
@@ -85,6 +484,14 @@ func aReport() *TestReport {
 	alpineImageScan := anAlpineImageScan(map[string]int{})
 	return &TestReport{
 		ImageScan: &scanner.VulnerabilityReport{
+			Metadata: scanner.ReportMetadata{
+				ClusterName:       "sandbox",
+				KubernetesVersion: "v1.27.3",
+				ScanTime:          time.Date(2023, 9, 4, 10, 30, 0, 0, time.UTC),
+				TrivyVersion:      "0.45.0",
+				TrivyDBVersion:    2,
+				TrivyDBUpdatedAt:  time.Date(2023, 9, 4, 6, 12, 0, 0, time.UTC),
+			},
 			ScannedImages: []scanner.ScannedImage{debianImageScan, alpineImageScan, ubuntuImageScan},
 			AreaSummary: map[string]*scanner.AreaSummary{
 				"area-1": {
@@ -156,6 +563,158 @@ func aReportWithErrors() *TestReport {
 	}
 }
 
+type TestComplianceReport struct {
+	CisScan *scanner.CombinedComplianceReport
+}
+
+var _ = Describe("Generating compliance report", func() {
+	var (
+		tmpDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should generate the report according to the md template file", func() {
+		actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+		reportTemplate := filepath.Join(findProjectDir(), "templates/report-cisScan.md.tmpl")
+		err := GenerateReportFromTemplate(aComplianceReport(), reportTemplate, "", actualReportFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileContentEqual("expected-test-report-cisScan.md", actualReportFile)).To(BeTrue())
+	})
+
+	It("should generate the report according to the html template file", func() {
+		actualReportFile := filepath.Join(tmpDir, "actual-report.html")
+		reportTemplate := filepath.Join(findProjectDir(), "templates/report-cisScan.html.tmpl")
+		err := GenerateReportFromTemplate(aComplianceReport(), reportTemplate, "", actualReportFile)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+func aComplianceReport() *TestComplianceReport {
+	failedControl := scanner.ComplianceControl{
+		ID:        "1.2.1",
+		Name:      "Ensure that the --anonymous-auth argument is set to false",
+		Severity:  "MEDIUM",
+		Status:    scanner.ComplianceFail,
+		PassCount: 1,
+		FailCount: 1,
+		FailedResources: []scanner.ComplianceResource{
+			{
+				Target: "kube-system/kube-apiserver",
+				Misconfigurations: []scanner.ComplianceMisconfiguration{
+					{Namespace: "kube-system", Message: "anonymous-auth is enabled", Resolution: "Set --anonymous-auth=false"},
+				},
+			},
+		},
+	}
+	cis := &scanner.ComplianceReport{
+		Title:            "CIS Kubernetes Benchmarks v1.23",
+		RelatedResources: []string{"https://www.cisecurity.org/benchmark/kubernetes"},
+		Controls: []scanner.ComplianceControl{
+			{ID: "5.1.1", Name: "Ensure that the cluster-admin role is only used where required", Severity: "CRITICAL", Status: scanner.CompliancePass, PassCount: 3},
+			{ID: "5.2.1", Name: "Minimize the admission of privileged containers", Severity: "HIGH", Status: scanner.ComplianceManual},
+			failedControl,
+		},
+		Summary: scanner.ComplianceSummary{ControlCount: 3, PassCount: 1, FailCount: 1, ManualCount: 1},
+		SummaryBySeverity: map[string]*scanner.ComplianceSummary{
+			"CRITICAL": {ControlCount: 1, PassCount: 1},
+			"HIGH":     {ControlCount: 1, ManualCount: 1},
+			"MEDIUM":   {ControlCount: 1, FailCount: 1},
+		},
+	}
+	nsa := &scanner.ComplianceReport{
+		Title: "National Security Agency - Kubernetes Hardening Guidance v1.0",
+		Controls: []scanner.ComplianceControl{
+			{ID: "1.0", Name: "Non-root containers", Severity: "MEDIUM", Status: scanner.CompliancePass, PassCount: 2},
+		},
+		Summary: scanner.ComplianceSummary{ControlCount: 1, PassCount: 1},
+		SummaryBySeverity: map[string]*scanner.ComplianceSummary{
+			"MEDIUM": {ControlCount: 1, PassCount: 1},
+		},
+	}
+	return &TestComplianceReport{
+		CisScan: scanner.NewCombinedComplianceReport([]*scanner.ComplianceReport{cis, nsa}),
+	}
+}
+
+type TestChecksReport struct {
+	Checks *checks.ReadinessReport
+}
+
+var _ = Describe("Generating readiness checks report", func() {
+	var (
+		tmpDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should generate the report according to the md template file", func() {
+		actualReportFile := filepath.Join(tmpDir, "actual-report.md")
+		reportTemplate := filepath.Join(findProjectDir(), "templates/report-checks.md.tmpl")
+		err := GenerateReportFromTemplate(aChecksReport(), reportTemplate, "", actualReportFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileContentEqual("expected-test-report-checks.md", actualReportFile)).To(BeTrue())
+	})
+
+	It("should generate the report according to the html template file", func() {
+		actualReportFile := filepath.Join(tmpDir, "actual-report.html")
+		reportTemplate := filepath.Join(findProjectDir(), "templates/report-checks.html.tmpl")
+		err := GenerateReportFromTemplate(aChecksReport(), reportTemplate, "", actualReportFile)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+func aChecksReport() *TestChecksReport {
+	finding := checks.Finding{
+		Check:     checks.NetworkPolicyCheckName,
+		Severity:  "HIGH",
+		Area:      "area-1",
+		Team:      "team-1",
+		Namespace: "ns1",
+		Kind:      "Deployment",
+		Workload:  "api",
+		Message:   "no NetworkPolicy in the namespace, the workload accepts traffic from any source",
+	}
+	return &TestChecksReport{
+		Checks: &checks.ReadinessReport{
+			Checks:        []string{checks.NetworkPolicyCheckName},
+			WorkloadCount: 3,
+			Findings:      []checks.Finding{finding},
+			AreaSummary: map[string]*checks.AreaSummary{
+				"area-1": {
+					Name:                "area-1",
+					FindingCountByCheck: map[string]int{checks.NetworkPolicyCheckName: 1},
+					Teams: map[string]*checks.TeamSummary{
+						"team-1": {
+							Name:                "team-1",
+							Findings:            []checks.Finding{finding},
+							FindingCountByCheck: map[string]int{checks.NetworkPolicyCheckName: 1},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 var _ = Describe("Saving json report", func() {
 	var (
 		tmpDir string
@@ -197,6 +756,23 @@ var _ = Describe("Saving json report", func() {
 	})
 })
 
+var _ = Describe("Locating the report templates", func() {
+	It("should look up the templates missing from the working directory next to the executable", func() {
+		executable, err := os.Executable()
+		Expect(err).NotTo(HaveOccurred())
+		executable, err = filepath.EvalSymlinks(executable)
+		Expect(err).NotTo(HaveOccurred())
+		templateDir, err := ioutil.TempDir(filepath.Dir(executable), "templates")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(templateDir)
+		Expect(os.WriteFile(filepath.Join(templateDir, "report.md.tmpl"), []byte("{{ .Name }}"), 0644)).To(Succeed())
+		relativeTemplate := filepath.Join(filepath.Base(templateDir), "report.md.tmpl")
+
+		Expect(templateFile(relativeTemplate)).To(Equal(filepath.Join(templateDir, "report.md.tmpl")))
+		Expect(templateFile("missing/report.md.tmpl")).To(Equal("missing/report.md.tmpl"))
+	})
+})
+
 func fileContentEqual(filename1, filename2 string, diffOptions ...string) (bool, error) {
 	var args []string
 	if diffOptions != nil {
@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceName identifies the spans of the tool in the tracing backend
+const ServiceName = "production-readiness"
+
+// OTLPConfig is the config used to export spans with the OTLP/HTTP protocol
+type OTLPConfig struct {
+	// Endpoint is the base url of the collector, for instance http://localhost:4318. Spans are posted to /v1/traces
+	Endpoint string
+	// Headers are added to the export requests, for instance for authentication
+	Headers map[string]string
+	Timeout time.Duration
+}
+
+// OTLPExporter exports spans to an OpenTelemetry collector with the OTLP/HTTP protocol and its json encoding
+type OTLPExporter struct {
+	config     *OTLPConfig
+	httpClient *http.Client
+}
+
+// NewOTLPExporter creates an OTLPExporter
+func NewOTLPExporter(config *OTLPConfig) *OTLPExporter {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &OTLPExporter{
+		config:     config,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// see https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+// Export posts the spans to the collector
+func (e *OTLPExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(newOTLPTraces(spans))
+	if err != nil {
+		return fmt.Errorf("error encoding trace spans: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(e.config.Endpoint, "/")+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error exporting trace spans: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("trace collector returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func newOTLPTraces(spans []*Span) *otlpTraces {
+	var otlpSpans []otlpSpan
+	for _, span := range spans {
+		status := otlpStatus{Code: statusCodeOk}
+		if span.Error != "" {
+			status = otlpStatus{Code: statusCodeError, Message: span.Error}
+		}
+		otlpSpans = append(otlpSpans, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            status,
+		})
+	}
+	return &otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource{Attributes: otlpAttributes(map[string]string{"service.name": ServiceName})},
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: ServiceName}, Spans: otlpSpans}},
+		}},
+	}
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	var keys []string
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result []otlpAttribute
+	for _, key := range keys {
+		result = append(result, otlpAttribute{Key: key, Value: map[string]string{"stringValue": attributes[key]}})
+	}
+	return result
+}
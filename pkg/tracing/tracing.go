@@ -0,0 +1,181 @@
+// Package tracing records the spans of the scan pipeline and exports them with OTLP/HTTP. It is a minimal exporter
+// rather than the OpenTelemetry SDK, which is not a dependency of the module: the spans are neither sampled nor
+// propagated to trivy or docker, and a failed export is logged and dropped rather than retried
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	logr "github.com/sirupsen/logrus"
+)
+
+// exportBatchSize is the number of finished spans buffered before being exported
+const exportBatchSize = 512
+
+// exportInterval is the interval the buffered spans are exported at, so that the spans of long scans are not held
+// until the batch is full
+const exportInterval = 5 * time.Second
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer creates the spans of the scan pipeline. A nil Tracer is valid and creates no span,
+// so that the instrumented code does not depend on tracing being enabled
+type Tracer struct {
+	exporter Exporter
+	lock     sync.Mutex
+	finished []*Span
+	// stop ends the periodic export once the tracer is shut down
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Span is a timed operation, identified and related to its parent span as OpenTelemetry spans
+type Span struct {
+	tracer       *Tracer
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+	// Error is the error message of a failed operation, empty when successful
+	Error string
+}
+
+type spanKey struct{}
+
+// New creates a Tracer exporting the spans with the exporter, every 5 seconds and whenever 512 spans are buffered,
+// until it is shut down
+func New(exporter Exporter) *Tracer {
+	return newTracer(exporter, exportInterval)
+}
+
+func newTracer(exporter Exporter, interval time.Duration) *Tracer {
+	t := &Tracer{exporter: exporter, stop: make(chan struct{})}
+	go t.exportPeriodically(interval)
+	return t
+}
+
+func (t *Tracer) exportPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				logr.Warnf("Unable to export trace spans: %v", err)
+			}
+		}
+	}
+}
+
+// Start starts a span, child of the span of the context if any, and returns a context holding the new span
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer:     t,
+		SpanID:     randomID(8),
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: make(map[string]string),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.TraceID = randomID(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext returns the span held by the context, nil when there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Shutdown stops the periodic export and exports the spans not exported yet. The spans finished afterwards are
+// exported by the next Shutdown or Flush, or once 512 spans are buffered
+func (t *Tracer) Shutdown() error {
+	if t == nil {
+		return nil
+	}
+	t.stopOnce.Do(func() { close(t.stop) })
+	return t.Flush()
+}
+
+// Flush exports the spans not exported yet
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	spans := t.finished
+	t.finished = nil
+	t.lock.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	return t.exporter.Export(spans)
+}
+
+func (t *Tracer) finish(span *Span) {
+	t.lock.Lock()
+	t.finished = append(t.finished, span)
+	var spans []*Span
+	if len(t.finished) >= exportBatchSize {
+		spans = t.finished
+		t.finished = nil
+	}
+	t.lock.Unlock()
+
+	if spans != nil {
+		if err := t.exporter.Export(spans); err != nil {
+			logr.Warnf("Unable to export %d trace span(s): %v", len(spans), err)
+		}
+	}
+}
+
+// SetAttribute records a key value describing the operation
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.Attributes[key] = value
+}
+
+// RecordError marks the operation as failed when the error is not nil
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Error = err.Error()
+}
+
+// Finish ends the span, which is exported with the next batch
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.End = time.Now()
+	s.tracer.finish(s)
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
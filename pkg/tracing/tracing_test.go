@@ -0,0 +1,176 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTracing(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tracing Suite")
+}
+
+type recordingExporter struct {
+	exported [][]*Span
+}
+
+func (e *recordingExporter) Export(spans []*Span) error {
+	e.exported = append(e.exported, spans)
+	return nil
+}
+
+type exporterFunc func(spans []*Span) error
+
+func (f exporterFunc) Export(spans []*Span) error {
+	return f(spans)
+}
+
+var _ = Describe("Tracer", func() {
+
+	It("relates the child spans to their parent", func() {
+		exporter := &recordingExporter{}
+		tracer := New(exporter)
+
+		ctx, parent := tracer.Start(context.Background(), "ScanImages")
+		_, child := tracer.Start(ctx, "scan image")
+		child.SetAttribute("image", "alpine:3.18")
+		child.RecordError(errors.New("scan failed"))
+		child.Finish()
+		parent.Finish()
+
+		Expect(exporter.exported).To(BeEmpty())
+		Expect(tracer.Shutdown()).To(Succeed())
+		Expect(exporter.exported).To(HaveLen(1))
+		Expect(exporter.exported[0]).To(Equal([]*Span{child, parent}))
+		Expect(parent.TraceID).To(HaveLen(32))
+		Expect(parent.ParentSpanID).To(BeEmpty())
+		Expect(child.TraceID).To(Equal(parent.TraceID))
+		Expect(child.ParentSpanID).To(Equal(parent.SpanID))
+		Expect(child.SpanID).To(HaveLen(16))
+		Expect(child.Attributes).To(Equal(map[string]string{"image": "alpine:3.18"}))
+		Expect(child.Error).To(Equal("scan failed"))
+		Expect(child.End).NotTo(BeTemporally("<", child.Start))
+	})
+
+	It("exports the spans in batches", func() {
+		exporter := &recordingExporter{}
+		tracer := New(exporter)
+
+		for i := 0; i < exportBatchSize+1; i++ {
+			_, span := tracer.Start(context.Background(), "span")
+			span.Finish()
+		}
+
+		Expect(exporter.exported).To(HaveLen(1))
+		Expect(exporter.exported[0]).To(HaveLen(exportBatchSize))
+		Expect(tracer.Shutdown()).To(Succeed())
+		Expect(exporter.exported).To(HaveLen(2))
+		Expect(exporter.exported[1]).To(HaveLen(1))
+	})
+
+	It("exports the buffered spans periodically until shut down", func() {
+		exported := make(chan []*Span, 2)
+		tracer := newTracer(exporterFunc(func(spans []*Span) error {
+			exported <- spans
+			return nil
+		}), 10*time.Millisecond)
+
+		_, span := tracer.Start(context.Background(), "ScanImages")
+		span.Finish()
+
+		Eventually(exported).Should(Receive(Equal([]*Span{span})))
+		Expect(tracer.Shutdown()).To(Succeed())
+		Expect(tracer.Shutdown()).To(Succeed())
+		Consistently(exported, 50*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("creates no span when nil", func() {
+		var tracer *Tracer
+
+		ctx, span := tracer.Start(context.Background(), "ScanImages")
+		span.SetAttribute("image", "alpine:3.18")
+		span.RecordError(errors.New("scan failed"))
+		span.Finish()
+
+		Expect(span).To(BeNil())
+		Expect(SpanFromContext(ctx)).To(BeNil())
+		Expect(tracer.Shutdown()).To(Succeed())
+	})
+})
+
+var _ = Describe("OTLP exporter", func() {
+
+	var (
+		server        *httptest.Server
+		statusCode    int
+		receivedPath  string
+		receivedAuth  string
+		receivedTrace map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		statusCode = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedPath = r.URL.Path
+			receivedAuth = r.Header.Get("Authorization")
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(body, &receivedTrace)).To(Succeed())
+			w.WriteHeader(statusCode)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts the spans with the OTLP json encoding", func() {
+		exporter := NewOTLPExporter(&OTLPConfig{Endpoint: server.URL + "/", Headers: map[string]string{"Authorization": "Bearer token"}})
+		tracer := New(exporter)
+		ctx, parent := tracer.Start(context.Background(), "ScanImages")
+		_, child := tracer.Start(ctx, "trivy scan")
+		child.SetAttribute("image", "alpine:3.18")
+		child.RecordError(errors.New("scan failed"))
+		child.Finish()
+		parent.Finish()
+
+		Expect(tracer.Shutdown()).To(Succeed())
+
+		Expect(receivedPath).To(Equal("/v1/traces"))
+		Expect(receivedAuth).To(Equal("Bearer token"))
+		resourceSpans := receivedTrace["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		Expect(resourceSpans["resource"]).To(Equal(map[string]interface{}{
+			"attributes": []interface{}{map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": ServiceName}}},
+		}))
+		spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0]).To(SatisfyAll(
+			HaveKeyWithValue("traceId", parent.TraceID),
+			HaveKeyWithValue("spanId", child.SpanID),
+			HaveKeyWithValue("parentSpanId", parent.SpanID),
+			HaveKeyWithValue("name", "trivy scan"),
+			HaveKeyWithValue("attributes", []interface{}{map[string]interface{}{"key": "image", "value": map[string]interface{}{"stringValue": "alpine:3.18"}}}),
+			HaveKeyWithValue("status", map[string]interface{}{"code": float64(2), "message": "scan failed"}),
+		))
+		Expect(spans[1]).NotTo(HaveKey("parentSpanId"))
+		Expect(spans[1]).To(HaveKeyWithValue("status", map[string]interface{}{"code": float64(1)}))
+	})
+
+	It("returns an error when the collector rejects the spans", func() {
+		statusCode = http.StatusBadRequest
+		tracer := New(NewOTLPExporter(&OTLPConfig{Endpoint: server.URL}))
+		_, span := tracer.Start(context.Background(), "ScanImages")
+		span.Finish()
+
+		Expect(tracer.Shutdown()).To(MatchError(ContainSubstring("trace collector returned status 400")))
+	})
+})
@@ -0,0 +1,237 @@
+// Package trivybinary locates the trivy binary the scans are run with, downloading a pinned trivy release when no
+// compatible trivy is installed so that the scans are reproducible across environments
+package trivybinary
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	execCmd "github.com/coreeng/production-readiness/production-readiness/pkg/cmd"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/releases"
+	logr "github.com/sirupsen/logrus"
+)
+
+const (
+	// PinnedVersion is the trivy release downloaded when no compatible trivy is installed
+	PinnedVersion = "0.45.1"
+	// MinimumVersion is the oldest trivy release supporting the flags the scans run trivy with
+	MinimumVersion  = "0.37.0"
+	trivyReleaseURL = "https://github.com/aquasecurity/trivy/releases/download"
+	binaryName      = "trivy"
+)
+
+// Installer locates a compatible trivy binary on the PATH, or downloads the pinned trivy release to its cache
+// directory, the release archive being verified against the checksums published with the release
+type Installer struct {
+	version       string
+	cacheDir      string
+	releaseURL    string
+	goos, goarch  string
+	httpClient    *http.Client
+	commandRunner execCmd.CommandRunner
+	lookPath      func(file string) (string, error)
+}
+
+// NewInstaller creates an Installer downloading the trivy version to the cache directory, for instance
+// ~/.config/production-readiness/trivy, each version being cached in its own sub-directory
+func NewInstaller(version, cacheDir string) *Installer {
+	return &Installer{
+		version:       strings.TrimPrefix(version, "v"),
+		cacheDir:      cacheDir,
+		releaseURL:    trivyReleaseURL,
+		goos:          runtime.GOOS,
+		goarch:        runtime.GOARCH,
+		httpClient:    &http.Client{Timeout: 5 * time.Minute},
+		commandRunner: execCmd.NewCommandRunner(),
+		lookPath:      exec.LookPath,
+	}
+}
+
+// DefaultCacheDir returns the directory the trivy releases are cached to by default, in the user config directory
+func DefaultCacheDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".trivycache", "bin")
+	}
+	return filepath.Join(dir, "production-readiness", "trivy")
+}
+
+// Resolve returns the path of the trivy binary on the PATH when its version is compatible, the path of the cached
+// pinned trivy release otherwise, downloading it first when not cached yet
+func (i *Installer) Resolve() (string, error) {
+	if path, err := i.lookPath(binaryName); err == nil {
+		version, err := i.installedVersion(path)
+		if err == nil {
+			logr.Infof("Using trivy %s installed at %s", version, path)
+			return path, nil
+		}
+		logr.Warnf("Not using the trivy binary at %s: %v", path, err)
+	}
+	return i.install()
+}
+
+// Validate returns an error when the trivy binary at the path can't be run or its version is not compatible
+func (i *Installer) Validate(path string) error {
+	version, err := i.installedVersion(path)
+	if err != nil {
+		return err
+	}
+	logr.Infof("Using trivy %s installed at %s", version, path)
+	return nil
+}
+
+// installedVersion returns the version of the trivy binary, an error when it is older than MinimumVersion
+func (i *Installer) installedVersion(path string) (string, error) {
+	output, errOutput, err := i.commandRunner.Execute(path, []string{"version", "-f", "json"})
+	if err != nil {
+		return "", fmt.Errorf("error while getting the trivy version. Error output: %s, Error: %v", string(errOutput), err)
+	}
+	var trivyVersion struct {
+		Version string
+	}
+	if err := json.Unmarshal(output, &trivyVersion); err != nil {
+		return "", fmt.Errorf("error while decoding the trivy version output: %v", err)
+	}
+	version, err := releases.ParseVersion(trivyVersion.Version)
+	if err != nil {
+		return "", err
+	}
+	minimum, _ := releases.ParseVersion(MinimumVersion)
+	if version.Before(minimum) {
+		return "", fmt.Errorf("trivy %s is older than the minimum supported version %s", trivyVersion.Version, MinimumVersion)
+	}
+	return trivyVersion.Version, nil
+}
+
+// install returns the cached trivy binary of the version, downloading and verifying the release first when not cached
+func (i *Installer) install() (string, error) {
+	binary := filepath.Join(i.cacheDir, i.version, binaryName)
+	if _, err := os.Stat(binary); err == nil {
+		logr.Infof("Using trivy %s cached at %s", i.version, binary)
+		return binary, nil
+	}
+	asset, err := i.assetName()
+	if err != nil {
+		return "", err
+	}
+	logr.Infof("No compatible trivy installed, downloading trivy %s to %s", i.version, binary)
+	archive, err := i.download(asset)
+	if err != nil {
+		return "", err
+	}
+	checksums, err := i.download(fmt.Sprintf("trivy_%s_checksums.txt", i.version))
+	if err != nil {
+		return "", err
+	}
+	if err := verifyChecksum(asset, archive, checksums); err != nil {
+		return "", err
+	}
+	if err := extractBinary(archive, binary); err != nil {
+		return "", fmt.Errorf("error while extracting trivy from %s: %v", asset, err)
+	}
+	return binary, nil
+}
+
+// assetName returns the name of the release archive of the platform, for instance trivy_0.45.1_Linux-64bit.tar.gz
+func (i *Installer) assetName() (string, error) {
+	systems := map[string]string{"linux": "Linux", "darwin": "macOS"}
+	architectures := map[string]string{"amd64": "64bit", "arm64": "ARM64"}
+	system, systemOK := systems[i.goos]
+	architecture, architectureOK := architectures[i.goarch]
+	if !systemOK || !architectureOK {
+		return "", fmt.Errorf("no trivy release for %s/%s, install trivy or set its path", i.goos, i.goarch)
+	}
+	return fmt.Sprintf("trivy_%s_%s-%s.tar.gz", i.version, system, architecture), nil
+}
+
+func (i *Installer) download(asset string) ([]byte, error) {
+	url := fmt.Sprintf("%s/v%s/%s", i.releaseURL, i.version, asset)
+	resp, err := i.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("could not download %s: status code %d", url, resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %v", url, err)
+	}
+	return content, nil
+}
+
+// verifyChecksum returns an error when the sha256 of the archive differs from its checksum in the checksums file,
+// which lists one '<sha256>  <asset>' per line
+func verifyChecksum(asset string, archive, checksums []byte) error {
+	sum := sha256.Sum256(archive)
+	actual := hex.EncodeToString(sum[:])
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[1] != asset {
+			continue
+		}
+		if !strings.EqualFold(fields[0], actual) {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset, fields[0], actual)
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum published for %s", asset)
+}
+
+// extractBinary writes the trivy binary of the gzipped tar archive to the file, through a temporary file so that
+// an interrupted extraction never leaves a truncated binary in the cache
+func extractBinary(archive []byte, file string) error {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("no %s binary in the archive", binaryName)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != binaryName {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		temp, err := os.CreateTemp(filepath.Dir(file), binaryName+"-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(temp.Name())
+		if _, err := io.Copy(temp, tarReader); err != nil {
+			temp.Close()
+			return err
+		}
+		if err := temp.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(temp.Name(), 0o755); err != nil {
+			return err
+		}
+		return os.Rename(temp.Name(), file)
+	}
+}
@@ -0,0 +1,163 @@
+package trivybinary
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTrivyBinary(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Trivy Binary Suite")
+}
+
+var _ = Describe("Trivy installer", func() {
+
+	const asset = "trivy_0.45.1_Linux-64bit.tar.gz"
+
+	var (
+		server     *httptest.Server
+		installer  *Installer
+		mockRunner *mockCommandRunner
+		cacheDir   string
+		archive    []byte
+		checksums  string
+		downloads  int
+	)
+
+	BeforeEach(func() {
+		archive = trivyArchive("#!/bin/sh\necho trivy\n")
+		sum := sha256.Sum256(archive)
+		checksums = fmt.Sprintf("0123  trivy_0.45.1_macOS-64bit.tar.gz\n%s  %s\n", hex.EncodeToString(sum[:]), asset)
+		downloads = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v0.45.1/" + asset:
+				downloads++
+				_, _ = w.Write(archive)
+			case "/v0.45.1/trivy_0.45.1_checksums.txt":
+				_, _ = w.Write([]byte(checksums))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		cacheDir = GinkgoT().TempDir()
+		mockRunner = &mockCommandRunner{}
+		installer = NewInstaller("v0.45.1", cacheDir)
+		installer.releaseURL = server.URL
+		installer.goos, installer.goarch = "linux", "amd64"
+		installer.commandRunner = mockRunner
+		installer.lookPath = func(_ string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("uses the trivy of the PATH when compatible", func() {
+		installer.lookPath = func(_ string) (string, error) { return "/usr/local/bin/trivy", nil }
+		mockRunner.On("Execute", "/usr/local/bin/trivy", []string{"version", "-f", "json"}).Return([]byte(`{"Version":"0.48.3"}`), []byte{}, nil)
+
+		path, err := installer.Resolve()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal("/usr/local/bin/trivy"))
+		Expect(downloads).To(Equal(0))
+	})
+
+	It("downloads the pinned release when the trivy of the PATH is too old, and reuses it once cached", func() {
+		installer.lookPath = func(_ string) (string, error) { return "/usr/local/bin/trivy", nil }
+		mockRunner.On("Execute", "/usr/local/bin/trivy", []string{"version", "-f", "json"}).Return([]byte(`{"Version":"0.30.4"}`), []byte{}, nil)
+
+		path, err := installer.Resolve()
+		Expect(err).NotTo(HaveOccurred())
+		cachedPath, err := installer.Resolve()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(path).To(Equal(filepath.Join(cacheDir, "0.45.1", "trivy")))
+		Expect(cachedPath).To(Equal(path))
+		Expect(downloads).To(Equal(1))
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("#!/bin/sh\necho trivy\n"))
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm() & 0o100).NotTo(BeZero())
+	})
+
+	It("refuses the downloaded release when its checksum does not match", func() {
+		checksums = fmt.Sprintf("%064d  %s\n", 0, asset)
+
+		_, err := installer.Resolve()
+
+		Expect(err).To(MatchError(ContainSubstring("checksum mismatch for " + asset)))
+		Expect(filepath.Join(cacheDir, "0.45.1", "trivy")).NotTo(BeAnExistingFile())
+	})
+
+	It("refuses the downloaded release without published checksum", func() {
+		checksums = "0123  trivy_0.45.1_macOS-64bit.tar.gz\n"
+
+		_, err := installer.Resolve()
+
+		Expect(err).To(MatchError("no checksum published for " + asset))
+	})
+
+	It("validates the version of the trivy path", func() {
+		mockRunner.On("Execute", "/opt/trivy", []string{"version", "-f", "json"}).Return([]byte(`{"Version":"0.36.1"}`), []byte{}, nil)
+
+		Expect(installer.Validate("/opt/trivy")).To(MatchError("trivy 0.36.1 is older than the minimum supported version 0.37.0"))
+	})
+
+	It("names the release archives after the platform", func() {
+		installer.goos, installer.goarch = "darwin", "arm64"
+		Expect(installer.assetName()).To(Equal("trivy_0.45.1_macOS-ARM64.tar.gz"))
+
+		installer.goos = "windows"
+		_, err := installer.assetName()
+		Expect(err).To(MatchError(ContainSubstring("no trivy release for windows/arm64")))
+	})
+})
+
+// trivyArchive returns a gzipped tar archive holding the trivy binary with the content, next to the files of the
+// trivy releases
+func trivyArchive(content string) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, fileContent := range map[string]string{"LICENSE": "Apache License", "trivy": content} {
+		Expect(tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(fileContent)), Typeflag: tar.TypeReg})).To(Succeed())
+		_, err := tarWriter.Write([]byte(fileContent))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(tarWriter.Close()).To(Succeed())
+	Expect(gzipWriter.Close()).To(Succeed())
+	return buffer.Bytes()
+}
+
+type mockCommandRunner struct {
+	mock.Mock
+}
+
+func (c *mockCommandRunner) Execute(cmd string, arg []string) ([]byte, []byte, error) {
+	args := c.Called(cmd, arg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (c *mockCommandRunner) ExecuteContext(_ context.Context, cmd string, arg []string) ([]byte, []byte, error) {
+	return c.Execute(cmd, arg)
+}
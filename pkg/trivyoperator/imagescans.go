@@ -0,0 +1,125 @@
+package trivyoperator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+)
+
+// cvssSource is the source the CVSS scores of the vulnerability reports are recorded under, as Trivy Operator does
+// not report the source of its scores
+const cvssSource = "trivy-operator"
+
+type imageScans struct {
+	byDigest map[string]*VulnerabilityReport
+	byImage  map[string]*VulnerabilityReport
+	version  string
+}
+
+// NewImageScanSource creates the source of the image scans of the vulnerability reports. The reports are matched
+// with the images by the digest of the running containers, or else by image name
+func NewImageScanSource(reports []VulnerabilityReport) scanner.ImageScanSource {
+	scans := &imageScans{byDigest: make(map[string]*VulnerabilityReport), byImage: make(map[string]*VulnerabilityReport)}
+	for i := range reports {
+		report := &reports[i]
+		artifact := report.Report.Artifact
+		if artifact.Digest != "" {
+			scans.byDigest[artifact.Digest] = report
+		}
+		imageName := report.Report.Registry.Server + "/" + artifact.Repository
+		if artifact.Tag != "" {
+			imageName += ":" + artifact.Tag
+		} else if artifact.Digest != "" {
+			imageName += "@" + artifact.Digest
+		}
+		scans.byImage[normalizeImageName(imageName)] = report
+		if scans.version == "" {
+			scans.version = report.Report.Scanner.Version
+		}
+	}
+	return scans
+}
+
+func (s *imageScans) ImageScan(imageName string, containers []k8s.ContainerSummary) (*scanner.TrivyOutput, error) {
+	digests := []string{k8s.ImageDigest(imageName)}
+	for _, container := range containers {
+		digests = append(digests, container.ImageDigest)
+	}
+	for _, digest := range digests {
+		if report, ok := s.byDigest[digest]; ok && digest != "" {
+			return trivyOutput(report), nil
+		}
+	}
+	if report, ok := s.byImage[normalizeImageName(imageName)]; ok {
+		return trivyOutput(report), nil
+	}
+	return nil, fmt.Errorf("no Trivy Operator vulnerability report for image %s", imageName)
+}
+
+func (s *imageScans) Version() string {
+	return s.version
+}
+
+// trivyOutput converts the vulnerability report to the trivy output of an image scan, with one result per target
+func trivyOutput(report *VulnerabilityReport) *scanner.TrivyOutput {
+	output := &scanner.TrivyOutput{CreatedAt: report.Report.UpdateTimestamp.Time}
+	if output.CreatedAt.IsZero() {
+		output.CreatedAt = report.CreationTimestamp.Time
+	}
+	if reportOS := report.Report.OS; reportOS.Family != "" {
+		output.Metadata.OS = &scanner.OS{Family: reportOS.Family, Name: reportOS.Name, EOSL: reportOS.EOSL}
+	}
+	resultIndexByTarget := make(map[string]int)
+	for _, vulnerability := range report.Report.Vulnerabilities {
+		index, ok := resultIndexByTarget[vulnerability.Target]
+		if !ok {
+			index = len(output.Results)
+			resultIndexByTarget[vulnerability.Target] = index
+			output.Results = append(output.Results, scanner.TrivyOutputResults{Target: vulnerability.Target})
+		}
+		converted := scanner.Vulnerabilities{
+			VulnerabilityID:  vulnerability.VulnerabilityID,
+			PkgName:          vulnerability.Resource,
+			InstalledVersion: vulnerability.InstalledVersion,
+			FixedVersion:     vulnerability.FixedVersion,
+			Severity:         vulnerability.Severity,
+			Title:            vulnerability.Title,
+			Description:      vulnerability.Description,
+			References:       vulnerability.Links,
+		}
+		if vulnerability.Score != nil {
+			converted.CVSS = map[string]scanner.CVSS{cvssSource: {V3Score: *vulnerability.Score}}
+		}
+		output.Results[index].Vulnerabilities = append(output.Results[index].Vulnerabilities, converted)
+	}
+	return output
+}
+
+// normalizeImageName returns the fully qualified name of the image, for instance docker.io/library/nginx:latest for
+// nginx, so that the images referenced differently by the containers and by Trivy Operator can be matched
+func normalizeImageName(imageName string) string {
+	name, digest, hasDigest := strings.Cut(imageName, "@")
+	registry, repository := "docker.io", name
+	if host, path, found := strings.Cut(name, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		registry, repository = host, path
+	}
+	if registry == "index.docker.io" {
+		registry = "docker.io"
+	}
+	if registry == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	tagIndex := strings.LastIndex(repository, ":")
+	hasTag := tagIndex > strings.LastIndex(repository, "/")
+	switch {
+	case hasDigest && hasTag:
+		return registry + "/" + repository[:tagIndex] + "@" + digest
+	case hasDigest:
+		return registry + "/" + repository + "@" + digest
+	case !hasTag:
+		return registry + "/" + repository + ":latest"
+	}
+	return registry + "/" + repository
+}
@@ -0,0 +1,149 @@
+package trivyoperator
+
+import (
+	"context"
+	"fmt"
+
+	logr "github.com/sirupsen/logrus"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// Labels Trivy Operator sets on its reports to identify the scanned resource
+const (
+	ResourceKindLabel      = "trivy-operator.resource.kind"
+	ResourceNameLabel      = "trivy-operator.resource.name"
+	ResourceNamespaceLabel = "trivy-operator.resource.namespace"
+	ContainerNameLabel     = "trivy-operator.container.name"
+)
+
+var (
+	vulnerabilityReportsResource = schema.GroupVersionResource{Group: "aquasecurity.github.io", Version: "v1alpha1", Resource: "vulnerabilityreports"}
+	configAuditReportsResource   = schema.GroupVersionResource{Group: "aquasecurity.github.io", Version: "v1alpha1", Resource: "configauditreports"}
+)
+
+// VulnerabilityReport is the object representation of the Trivy Operator VulnerabilityReport resource, holding the
+// vulnerabilities of the image of a container
+type VulnerabilityReport struct {
+	metaV1.ObjectMeta `json:"metadata"`
+	Report            struct {
+		// UpdateTimestamp is the time of the scan of the image, the report being updated by each rescan
+		UpdateTimestamp metaV1.Time `json:"updateTimestamp"`
+		Scanner         struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"scanner"`
+		Registry struct {
+			Server string `json:"server"`
+		} `json:"registry"`
+		Artifact struct {
+			Repository string `json:"repository"`
+			Tag        string `json:"tag"`
+			Digest     string `json:"digest"`
+		} `json:"artifact"`
+		OS struct {
+			Family string `json:"family"`
+			Name   string `json:"name"`
+			EOSL   bool   `json:"eosl"`
+		} `json:"os"`
+		Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	} `json:"report"`
+}
+
+// Vulnerability is a vulnerability of a VulnerabilityReport
+type Vulnerability struct {
+	VulnerabilityID string `json:"vulnerabilityID"`
+	// Resource is the name of the vulnerable package
+	Resource         string   `json:"resource"`
+	InstalledVersion string   `json:"installedVersion"`
+	FixedVersion     string   `json:"fixedVersion"`
+	Severity         string   `json:"severity"`
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	PrimaryLink      string   `json:"primaryLink"`
+	Links            []string `json:"links"`
+	Target           string   `json:"target"`
+	// Score is the CVSS score of the vulnerability, nil when unknown
+	Score *float64 `json:"score"`
+}
+
+// ConfigAuditReport is the object representation of the Trivy Operator ConfigAuditReport resource, holding the
+// configuration checks of a resource
+type ConfigAuditReport struct {
+	metaV1.ObjectMeta `json:"metadata"`
+	Report            struct {
+		Checks []ConfigAuditCheck `json:"checks"`
+	} `json:"report"`
+}
+
+// ConfigAuditCheck is a configuration check of a ConfigAuditReport, Success being false when the resource fails it
+type ConfigAuditCheck struct {
+	CheckID  string   `json:"checkID"`
+	Title    string   `json:"title"`
+	Severity string   `json:"severity"`
+	Success  bool     `json:"success"`
+	Messages []string `json:"messages"`
+}
+
+// Client reads the reports Trivy Operator produced in the cluster
+type Client struct {
+	dynamicClient dynamic.Interface
+}
+
+// NewClient creates a Client for the cluster of the config
+func NewClient(config *rest.Config) *Client {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logr.Fatalf("Unable to obtain dynamic client: %v", err)
+	}
+	return NewClientWith(dynamicClient)
+}
+
+// NewClientWith creates a Client using the provided dynamic client
+func NewClientWith(dynamicClient dynamic.Interface) *Client {
+	return &Client{dynamicClient: dynamicClient}
+}
+
+// VulnerabilityReports returns the vulnerability reports of the namespace, of all the namespaces when empty
+func (c *Client) VulnerabilityReports(namespace string) ([]VulnerabilityReport, error) {
+	var reports []VulnerabilityReport
+	err := c.list(vulnerabilityReportsResource, namespace, func(object map[string]interface{}) error {
+		var report VulnerabilityReport
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, &report); err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	return reports, err
+}
+
+// ConfigAuditReports returns the config audit reports of the namespace, of all the namespaces when empty
+func (c *Client) ConfigAuditReports(namespace string) ([]ConfigAuditReport, error) {
+	var reports []ConfigAuditReport
+	err := c.list(configAuditReportsResource, namespace, func(object map[string]interface{}) error {
+		var report ConfigAuditReport
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, &report); err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	return reports, err
+}
+
+func (c *Client) list(resource schema.GroupVersionResource, namespace string, decode func(object map[string]interface{}) error) error {
+	list, err := c.dynamicClient.Resource(resource).Namespace(namespace).List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to list the Trivy Operator %s, is Trivy Operator installed? %v", resource.Resource, err)
+	}
+	for _, item := range list.Items {
+		if err := decode(item.Object); err != nil {
+			return fmt.Errorf("error while decoding Trivy Operator %s %s/%s: %v", resource.Resource, item.GetNamespace(), item.GetName(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package trivyoperator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/k8s"
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTrivyOperator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Trivy Operator Suite")
+}
+
+var _ = Describe("Trivy Operator reports", func() {
+
+	vulnerabilityReport := func(namespace, name string, report map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "aquasecurity.github.io/v1alpha1",
+			"kind":       "VulnerabilityReport",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					ResourceKindLabel:  "ReplicaSet",
+					ResourceNameLabel:  "web-5d8f",
+					ContainerNameLabel: "nginx",
+				},
+			},
+			"report": report,
+		}}
+	}
+
+	newClient := func(objects ...runtime.Object) *Client {
+		return NewClientWith(fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			vulnerabilityReportsResource: "VulnerabilityReportList",
+			configAuditReportsResource:   "ConfigAuditReportList",
+		}, objects...))
+	}
+
+	It("lists the vulnerability reports of all the namespaces", func() {
+		client := newClient(
+			vulnerabilityReport("payments", "replicaset-web-5d8f-nginx", map[string]interface{}{
+				"scanner":  map[string]interface{}{"name": "Trivy", "version": "0.45.1"},
+				"registry": map[string]interface{}{"server": "index.docker.io"},
+				"artifact": map[string]interface{}{"repository": "library/nginx", "tag": "1.25"},
+				"vulnerabilities": []interface{}{
+					map[string]interface{}{"vulnerabilityID": "CVE-2023-0001", "resource": "openssl", "severity": "HIGH", "score": 7.5},
+				},
+			}),
+			vulnerabilityReport("orders", "pod-debug-busybox", map[string]interface{}{}),
+		)
+
+		reports, err := client.VulnerabilityReports("")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(2))
+		var payments VulnerabilityReport
+		for _, report := range reports {
+			if report.Namespace == "payments" {
+				payments = report
+			}
+		}
+		Expect(payments.Labels[ContainerNameLabel]).To(Equal("nginx"))
+		Expect(payments.Report.Scanner.Version).To(Equal("0.45.1"))
+		Expect(payments.Report.Artifact.Repository).To(Equal("library/nginx"))
+		Expect(payments.Report.Vulnerabilities[0].Resource).To(Equal("openssl"))
+		Expect(*payments.Report.Vulnerabilities[0].Score).To(Equal(7.5))
+	})
+
+	It("lists the config audit reports of a namespace", func() {
+		client := newClient(&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "aquasecurity.github.io/v1alpha1",
+			"kind":       "ConfigAuditReport",
+			"metadata":   map[string]interface{}{"name": "replicaset-web-5d8f", "namespace": "payments"},
+			"report": map[string]interface{}{"checks": []interface{}{
+				map[string]interface{}{"checkID": "KSV001", "title": "Process can elevate its own privileges", "severity": "MEDIUM", "success": false},
+			}},
+		}})
+
+		reports, err := client.ConfigAuditReports("payments")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1))
+		Expect(reports[0].Report.Checks[0].CheckID).To(Equal("KSV001"))
+		Expect(reports[0].Report.Checks[0].Success).To(BeFalse())
+	})
+})
+
+var _ = Describe("Image scan source", func() {
+
+	var source scanner.ImageScanSource
+
+	BeforeEach(func() {
+		score := 9.8
+		var nginx, app VulnerabilityReport
+		nginx.Report.Scanner.Version = "0.45.1"
+		nginx.Report.UpdateTimestamp = metaV1.NewTime(time.Date(2023, 9, 5, 10, 0, 0, 0, time.UTC))
+		nginx.Report.Registry.Server = "index.docker.io"
+		nginx.Report.Artifact.Repository = "library/nginx"
+		nginx.Report.Artifact.Tag = "1.25"
+		nginx.Report.OS.Family = "debian"
+		nginx.Report.OS.Name = "12.1"
+		nginx.Report.Vulnerabilities = []Vulnerability{
+			{VulnerabilityID: "CVE-2023-0001", Resource: "openssl", InstalledVersion: "3.0.9", FixedVersion: "3.0.11", Severity: "CRITICAL", Target: "nginx:1.25 (debian 12.1)", Score: &score},
+			{VulnerabilityID: "CVE-2023-0002", Resource: "golang.org/x/net", Severity: "HIGH", Target: "usr/local/bin/app"},
+			{VulnerabilityID: "CVE-2023-0003", Resource: "libc6", Severity: "LOW", Target: "nginx:1.25 (debian 12.1)"},
+		}
+		app.Report.Registry.Server = "registry.example.com"
+		app.Report.Artifact.Repository = "payments/api"
+		app.Report.Artifact.Digest = "sha256:4ff3ca91"
+		source = NewImageScanSource([]VulnerabilityReport{nginx, app})
+	})
+
+	It("converts the report of the image to a trivy output", func() {
+		output, err := source.ImageScan("nginx:1.25", nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.CreatedAt).To(Equal(time.Date(2023, 9, 5, 10, 0, 0, 0, time.UTC)))
+		Expect(output.Metadata.OS).To(Equal(&scanner.OS{Family: "debian", Name: "12.1"}))
+		Expect(output.Results).To(HaveLen(2))
+		Expect(output.Results[0].Target).To(Equal("nginx:1.25 (debian 12.1)"))
+		Expect(output.Results[0].Vulnerabilities).To(HaveLen(2))
+		Expect(output.Results[0].Vulnerabilities[0].PkgName).To(Equal("openssl"))
+		Expect(output.Results[0].Vulnerabilities[0].FixedVersion).To(Equal("3.0.11"))
+		Expect(output.Results[0].Vulnerabilities[0].CVSSScore()).To(Equal(9.8))
+		Expect(output.Results[1].Vulnerabilities[0].VulnerabilityID).To(Equal("CVE-2023-0002"))
+		Expect(source.Version()).To(Equal("0.45.1"))
+	})
+
+	It("matches the images by the digest of their containers, or else by name", func() {
+		_, err := source.ImageScan("registry.example.com/payments/api:1.0", []k8s.ContainerSummary{{ImageDigest: "sha256:4ff3ca91"}})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = source.ImageScan("registry.example.com/payments/api@sha256:4ff3ca91", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = source.ImageScan("docker.io/library/nginx:1.25", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		output, err := source.ImageScan("nginx:1.26", nil)
+		Expect(output).To(BeNil())
+		Expect(err).To(MatchError("no Trivy Operator vulnerability report for image nginx:1.26"))
+	})
+
+	It("normalizes the image names", func() {
+		Expect(normalizeImageName("nginx")).To(Equal("docker.io/library/nginx:latest"))
+		Expect(normalizeImageName("index.docker.io/bitnami/redis:7")).To(Equal("docker.io/bitnami/redis:7"))
+		Expect(normalizeImageName("localhost:5000/app")).To(Equal("localhost:5000/app:latest"))
+		Expect(normalizeImageName("quay.io/prometheus/node-exporter:v1.6.1@sha256:4ff3ca91")).To(Equal("quay.io/prometheus/node-exporter@sha256:4ff3ca91"))
+	})
+})
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/sha1"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	logr "github.com/sirupsen/logrus"
 )
@@ -30,3 +33,12 @@ func ConvertByteToStruct(data []byte) []map[string]interface{} {
 
 	return jsonMap
 }
+
+// NameUUID returns a name-based UUID of the values, the same values always giving the same UUID, so that the ids of
+// the findings and of the reports are stable across the scans
+func NameUUID(values ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(values, "\x00")))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
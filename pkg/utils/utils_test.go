@@ -36,4 +36,12 @@ var _ = Describe("Utils tests", func() {
 		Expect(valueAsString).To(Equal("sample"))
 	})
 
+	It("Can derive a stable name-based UUID from values", func() {
+		uuid := NameUUID("prod", "2023-09-01T10:00:00Z")
+
+		Expect(uuid).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(NameUUID("prod", "2023-09-01T10:00:00Z")).To(Equal(uuid))
+		Expect(NameUUID("prod2023", "-09-01T10:00:00Z")).NotTo(Equal(uuid))
+	})
+
 })
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+	logr "github.com/sirupsen/logrus"
+)
+
+const (
+	// ModeReport posts the whole report in a single request
+	ModeReport = "report"
+	// ModeImage posts one event per scanned image
+	ModeImage = "image"
+
+	// SignatureHeader holds the hex encoded HMAC-SHA256 of the request body, prefixed with 'sha256='
+	SignatureHeader = "X-Prod-Readiness-Signature-256"
+	// EventHeader holds the type of the event posted
+	EventHeader = "X-Prod-Readiness-Event"
+)
+
+// Config is the config used to deliver the report to a webhook
+type Config struct {
+	URL string
+	// Secret used to sign the requests. Requests are not signed when empty
+	Secret  string
+	Mode    string
+	Timeout time.Duration
+}
+
+// ImageEvent is the payload posted for each scanned image
+type ImageEvent struct {
+	ImageName string
+	Image     scanner.ScannedImage
+}
+
+// Sink delivers reports to an HTTP endpoint
+type Sink struct {
+	config     *Config
+	httpClient *http.Client
+}
+
+// New creates a Sink
+func New(config *Config) (*Sink, error) {
+	if config.Mode != ModeReport && config.Mode != ModeImage {
+		return nil, fmt.Errorf("unsupported webhook mode %q, permitted values: %s, %s", config.Mode, ModeReport, ModeImage)
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	return &Sink{
+		config:     config,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Deliver posts the report, or one event per scanned image depending on the mode
+func (s *Sink) Deliver(report interface{}, imageScan *scanner.VulnerabilityReport) error {
+	if s.config.Mode == ModeReport {
+		return s.post("report", report)
+	}
+
+	if imageScan == nil {
+		return nil
+	}
+	var failures int
+	for _, image := range imageScan.ScannedImages {
+		if err := s.post("image", &ImageEvent{ImageName: image.ImageName, Image: image}); err != nil {
+			logr.Errorf("Error delivering webhook event for image %s: %v", image.ImageName, err)
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d webhook event(s) could not be delivered", failures)
+	}
+	return nil
+}
+
+func (s *Sink) post(event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	if s.config.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(body, s.config.Secret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting to webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the body, allowing receivers to verify the payload
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
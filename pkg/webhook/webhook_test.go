@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreeng/production-readiness/production-readiness/pkg/scanner"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhook(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Webhook Suite")
+}
+
+type receivedRequest struct {
+	event, signature string
+	body             []byte
+}
+
+var _ = Describe("Webhook sink", func() {
+
+	var (
+		server   *httptest.Server
+		received []receivedRequest
+		report   *scanner.VulnerabilityReport
+	)
+
+	BeforeEach(func() {
+		received = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			received = append(received, receivedRequest{event: r.Header.Get(EventHeader), signature: r.Header.Get(SignatureHeader), body: body})
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		report = &scanner.VulnerabilityReport{
+			ScannedImages: []scanner.ScannedImage{
+				scanner.NewScannedImage("image1", nil, nil, nil),
+				scanner.NewScannedImage("image2", nil, nil, nil),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts the whole report signed with the secret", func() {
+		sink, err := New(&Config{URL: server.URL, Secret: "s3cr3t", Mode: ModeReport})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = sink.Deliver(map[string]interface{}{"ImageScan": report}, report)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(HaveLen(1))
+		Expect(received[0].event).To(Equal("report"))
+		Expect(received[0].signature).To(Equal("sha256=" + Sign(received[0].body, "s3cr3t")))
+	})
+
+	It("posts one event per image", func() {
+		sink, err := New(&Config{URL: server.URL, Mode: ModeImage})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = sink.Deliver(nil, report)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(HaveLen(2))
+		Expect(received[0].event).To(Equal("image"))
+		Expect(received[0].signature).To(BeEmpty())
+		var event ImageEvent
+		Expect(json.Unmarshal(received[1].body, &event)).To(Succeed())
+		Expect(event.ImageName).To(Equal("image2"))
+	})
+
+	It("computes a HMAC-SHA256 signature", func() {
+		Expect(Sign([]byte("payload"), "key")).To(Equal("5d98b45c90a207fa998ce639fea6f02ecc8cc3f36fef81d694fb856b4d0a28ca"))
+		Expect(Sign([]byte("payload"), "key")).NotTo(Equal(Sign([]byte("payload"), "other-key")))
+	})
+
+	It("rejects unknown modes", func() {
+		_, err := New(&Config{URL: server.URL, Mode: "unknown"})
+		Expect(err).To(MatchError(ContainSubstring("unsupported webhook mode")))
+	})
+})
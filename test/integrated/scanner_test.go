@@ -103,7 +103,7 @@ var _ = Describe("Scan Images", func() {
 		Eventually(f.PodIsReady(types.NamespacedName{Namespace: team3Pod.Namespace, Name: team3Pod.Name}))
 
 		// when
-		report, err := scan.ScanImages()
+		report, err := scan.ScanImages(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 
 		// then
@@ -159,7 +159,7 @@ var _ = Describe("Scan Images", func() {
 		Eventually(f.PodIsReady(types.NamespacedName{Namespace: teamPod.Namespace, Name: teamPod.Name}))
 
 		// when
-		report, err := scan.ScanImages()
+		report, err := scan.ScanImages(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 
 		// then
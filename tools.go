@@ -7,5 +7,7 @@ import (
 	_ "github.com/onsi/ginkgo/v2"
 	_ "golang.org/x/lint/golint"
 	_ "golang.org/x/tools/cmd/goimports"
+	_ "google.golang.org/grpc/cmd/protoc-gen-go-grpc"
+	_ "google.golang.org/protobuf/cmd/protoc-gen-go"
 	_ "sigs.k8s.io/kind"
 )